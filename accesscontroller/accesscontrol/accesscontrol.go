@@ -1,56 +1,340 @@
 package accesscontrol
 
 import (
-	"errors"
+	"os"
+	"strconv"
 
 	"github.com/neilZon/workout-logger-api/accesscontroller"
+	"github.com/neilZon/workout-logger-api/cache"
+	"github.com/neilZon/workout-logger-api/clock"
+	"github.com/neilZon/workout-logger-api/common"
+	"github.com/neilZon/workout-logger-api/config"
 	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
 	"github.com/neilZon/workout-logger-api/utils"
 	"gorm.io/gorm"
 )
 
 type AccessController struct {
 	DB *gorm.DB
+	// sessions that started more than this many days ago are read only until
+	// they're unlocked, 0 turns the lock off
+	EditLockDays int
+	// who owns each routine, session and exercise, so checks in a row don't
+	// all read them. nil reads them every time
+	Cache cache.Cache
 }
 
-// CanAccessExercise implements accesscontroller.AccessControllerService
-func (*AccessController) CanAccessExercise(userId string, exerciseId string) error {
-	panic("unimplemented")
+// CanAccessExercise checks the user logged the exercise or coaches whoever
+// did with read write access, co-participants in a session can only change
+// their own exercises and sets
+func (ac *AccessController) CanAccessExercise(userId string, exerciseId string) error {
+	exerciseIdUint, err := strconv.ParseUint(exerciseId, 10, 64)
+	if err != nil {
+		return err
+	}
+	ownerId, err := ac.owner("exercises", exerciseId, func() (uint, error) {
+		exercise := database.Exercise{
+			Model: gorm.Model{
+				ID: uint(exerciseIdUint),
+			},
+		}
+		err := database.GetExercise(ac.DB, &exercise, false)
+		return exercise.UserID, err
+	})
+	if err != nil {
+		return err
+	}
+
+	if utils.UIntToString(ownerId) == userId {
+		return nil
+	}
+	return ac.canCoachWith(userId, ownerId, database.CoachAccessReadWrite)
 }
 
+// CanAccessWorkoutRoutine checks the user owns the routine or coaches its
+// owner with read write access
 func (ac *AccessController) CanAccessWorkoutRoutine(userId string, workoutRoutineId string) error {
-	workoutRoutine, err := database.GetWorkoutRoutine(ac.DB, workoutRoutineId)
+	return ac.canAccessWorkoutRoutine(userId, workoutRoutineId, database.CoachAccessReadWrite)
+}
+
+// CanReadWorkoutRoutine is CanAccessWorkoutRoutine for coaches with read only
+// access too
+func (ac *AccessController) CanReadWorkoutRoutine(userId string, workoutRoutineId string) error {
+	return ac.canAccessWorkoutRoutine(userId, workoutRoutineId, database.CoachAccessRead)
+}
+
+// IsWorkoutRoutineOwner leaves coaches out, for things that would tie the
+// routine to the caller's own account
+func (ac *AccessController) IsWorkoutRoutineOwner(userId string, workoutRoutineId string) error {
+	return ac.canAccessWorkoutRoutine(userId, workoutRoutineId, "")
+}
+
+func (ac *AccessController) canAccessWorkoutRoutine(userId string, workoutRoutineId string, coachAccess string) error {
+	ownerId, err := ac.owner("workout_routines", workoutRoutineId, func() (uint, error) {
+		workoutRoutine, err := database.GetWorkoutRoutine(ac.DB, workoutRoutineId)
+		return workoutRoutine.UserID, err
+	})
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return err
 	}
+	if err != nil {
+		return errors.Forbidden("Access Denied")
+	}
 
-	if utils.UIntToString(workoutRoutine.UserID) != userId {
-		return errors.New("Access Denied")
+	if utils.UIntToString(ownerId) == userId {
+		return nil
 	}
-	return nil
+	return ac.canCoachWith(userId, ownerId, coachAccess)
 }
 
+// CanAccessWorkoutSession checks the user owns the session or coaches its
+// owner with read write access
 func (ac *AccessController) CanAccessWorkoutSession(userId string, workoutSessionId string) error {
-	workoutSession, err := database.GetWorkoutSession(ac.DB, workoutSessionId)
+	return ac.canAccessWorkoutSession(userId, workoutSessionId, database.CoachAccessReadWrite)
+}
+
+// CanReadWorkoutSession is CanAccessWorkoutSession for coaches with read only
+// access too
+func (ac *AccessController) CanReadWorkoutSession(userId string, workoutSessionId string) error {
+	return ac.canAccessWorkoutSession(userId, workoutSessionId, database.CoachAccessRead)
+}
+
+// IsWorkoutSessionOwner leaves coaches out, for things only the person who
+// lifted gets to do
+func (ac *AccessController) IsWorkoutSessionOwner(userId string, workoutSessionId string) error {
+	return ac.canAccessWorkoutSession(userId, workoutSessionId, "")
+}
+
+func (ac *AccessController) canAccessWorkoutSession(userId string, workoutSessionId string, coachAccess string) error {
+	ownerId, err := ac.workoutSessionOwner(workoutSessionId)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return err
 	}
-	if utils.UIntToString(workoutSession.UserID) != userId {
-		return errors.New("Access Denied")
+	if err != nil {
+		return errors.Forbidden("Access Denied")
+	}
+
+	if utils.UIntToString(ownerId) == userId {
+		return nil
+	}
+	return ac.canCoachWith(userId, ownerId, coachAccess)
+}
+
+func (ac *AccessController) workoutSessionOwner(workoutSessionId string) (uint, error) {
+	return ac.owner("workout_sessions", workoutSessionId, func() (uint, error) {
+		workoutSession, err := database.GetWorkoutSession(ac.DB, workoutSessionId)
+		return workoutSession.UserID, err
+	})
+}
+
+// owner is who owns the row, from the cache when it's there. Owners only
+// change when accounts are merged, but a cached row can also have been
+// deleted since, so for up to ACCESS_CACHE_TTL this says it's there. Adding or
+// restoring under a routine or session checks it isn't deleted in the same
+// transaction. Rows that aren't found aren't cached
+func (ac *AccessController) owner(table string, id string, read func() (uint, error)) (uint, error) {
+	if ownerId, ok := ac.cachedOwner(table, id); ok {
+		return ownerId, nil
+	}
+
+	ownerId, err := read()
+	if err != nil {
+		return 0, err
+	}
+	ac.cacheOwner(table, id, ownerId)
+	return ownerId, nil
+}
+
+// owners is owner for many rows, the ones that aren't cached are read in one
+// go. It's gorm.ErrRecordNotFound when any of them don't exist
+func (ac *AccessController) owners(table string, ids []string, read func([]string) ([]database.Owner, error)) (map[string]uint, error) {
+	owners := make(map[string]uint, len(ids))
+	uncached := make([]string, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if ownerId, ok := ac.cachedOwner(table, id); ok {
+			owners[id] = ownerId
+		} else {
+			uncached = append(uncached, id)
+		}
+	}
+	if len(uncached) == 0 {
+		return owners, nil
+	}
+
+	found, err := read(uncached)
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range found {
+		id := utils.UIntToString(o.ID)
+		owners[id] = o.UserID
+		ac.cacheOwner(table, id, o.UserID)
+	}
+	if len(owners) != len(seen) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return owners, nil
+}
+
+func (ac *AccessController) cachedOwner(table string, id string) (uint, bool) {
+	if ac.Cache == nil {
+		return 0, false
+	}
+	b, ok := ac.Cache.Get("owner:" + table + ":" + id)
+	if !ok {
+		return 0, false
+	}
+	ownerId, err := strconv.ParseUint(string(b), 10, strconv.IntSize)
+	if err != nil {
+		return 0, false
+	}
+	return uint(ownerId), true
+}
+
+func (ac *AccessController) cacheOwner(table string, id string, ownerId uint) {
+	if ac.Cache != nil {
+		ac.Cache.Set("owner:"+table+":"+id, []byte(utils.UIntToString(ownerId)), config.ACCESS_CACHE_TTL)
+	}
+}
+
+// canAccessAll checks the user owns every row, or coaches the owners with
+// read write access. Each owner other than the user is only checked once
+func (ac *AccessController) canAccessAll(userId string, table string, ids []string, read func([]string) ([]database.Owner, error)) error {
+	owners, err := ac.owners(table, ids, read)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err != nil {
+		return errors.Forbidden("Access Denied")
+	}
+
+	checked := make(map[uint]bool)
+	for _, ownerId := range owners {
+		if utils.UIntToString(ownerId) == userId || checked[ownerId] {
+			continue
+		}
+		err := ac.canCoachWith(userId, ownerId, database.CoachAccessReadWrite)
+		if err != nil {
+			return err
+		}
+		checked[ownerId] = true
 	}
 	return nil
 }
 
-func (ac *AccessController) CanAccessExerciseRoutine(userId string, exerciseId string) error {
-	panic("unimplemented")
+// CanAccessWorkoutSessions is CanAccessWorkoutSession for many sessions
+// with one query for their owners
+func (ac *AccessController) CanAccessWorkoutSessions(userId string, workoutSessionIds []string) error {
+	return ac.canAccessAll(userId, "workout_sessions", workoutSessionIds, func(ids []string) ([]database.Owner, error) {
+		return database.GetWorkoutSessionOwners(ac.DB, ids)
+	})
+}
+
+// CanAccessExerciseRoutine checks the user can change the workout routine
+// the exercise routine is in
+func (ac *AccessController) CanAccessExerciseRoutine(userId string, exerciseRoutineId string) error {
+	return ac.CanAccessExerciseRoutines(userId, []string{exerciseRoutineId})
+}
+
+// CanAccessExerciseRoutines is CanAccessExerciseRoutine for many exercise
+// routines with one query for their owners
+func (ac *AccessController) CanAccessExerciseRoutines(userId string, exerciseRoutineIds []string) error {
+	return ac.canAccessAll(userId, "exercise_routines", exerciseRoutineIds, func(ids []string) ([]database.Owner, error) {
+		return database.GetExerciseRoutineOwners(ac.DB, ids)
+	})
+}
+
+// no access asked for means coaches don't get in at all
+func (ac *AccessController) canCoachWith(coachId string, userId uint, access string) error {
+	if access == "" {
+		return errors.Forbidden("Access Denied")
+	}
+	coach, err := database.GetCoach(ac.DB, utils.UIntToString(userId), coachId)
+	if err != nil || !database.CoachAccessAllows(coach.Access, access) {
+		return errors.Forbidden("Access Denied")
+	}
+	return nil
+}
+
+// CanParticipateInWorkoutSession checks the user owns the session or joined
+// it as a training partner
+func (ac *AccessController) CanParticipateInWorkoutSession(userId string, workoutSessionId string) error {
+	ownerId, err := ac.workoutSessionOwner(workoutSessionId)
+	if err != nil {
+		return err
+	}
+	if utils.UIntToString(ownerId) == userId {
+		return nil
+	}
+
+	_, err = database.GetWorkoutSessionParticipant(ac.DB, workoutSessionId, userId)
+	if err != nil {
+		return errors.Forbidden("Access Denied")
+	}
+	return nil
+}
+
+// CanEditWorkoutSession keeps old sessions from being changed by accident so
+// the stats built on them stay put. Sessions past the lock can be changed
+// for a while after the owner unlocks them
+func (ac *AccessController) CanEditWorkoutSession(workoutSessionId string) error {
+	if ac.EditLockDays <= 0 {
+		return nil
+	}
+	lock, err := database.GetWorkoutSessionEditLock(ac.DB, workoutSessionId)
+	if err != nil {
+		return err
+	}
+
+	now := clock.Now()
+	if lock.Start.After(now.AddDate(0, 0, -ac.EditLockDays)) {
+		return nil
+	}
+	if lock.UnlockedUntil != nil && lock.UnlockedUntil.After(now) {
+		return nil
+	}
+	return &common.WorkoutSessionLockedError{LockAfterDays: ac.EditLockDays}
 }
 
 func (ac *AccessController) CanAccessSetEntry(userId string, exerciseId string) error {
 	panic("unimplemented")
 }
 
+// CanCoach checks the user linked coachId as one of their coaches
+func (ac *AccessController) CanCoach(coachId string, userId string) error {
+	_, err := database.GetCoach(ac.DB, userId, coachId)
+	if err != nil {
+		return errors.Forbidden("Access Denied")
+	}
+	return nil
+}
+
 func NewAccessControllerService(db *gorm.DB) accesscontroller.AccessControllerService {
 	return &AccessController{
-		DB: db,
+		DB:           db,
+		EditLockDays: editLockDays(),
+	}
+}
+
+// NewSharedAccessControllerService keeps who owns what in store
+func NewSharedAccessControllerService(db *gorm.DB, store cache.Cache) accesscontroller.AccessControllerService {
+	return &AccessController{
+		DB:           db,
+		EditLockDays: editLockDays(),
+		Cache:        store,
+	}
+}
+
+func editLockDays() int {
+	days, err := strconv.Atoi(os.Getenv(config.SESSION_EDIT_LOCK_DAYS))
+	if err != nil {
+		return config.DEFAULT_SESSION_EDIT_LOCK_DAYS
 	}
+	return days
 }
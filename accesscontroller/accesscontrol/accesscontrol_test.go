@@ -4,11 +4,15 @@ import (
 	"fmt"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/neilZon/workout-logger-api/cache"
+	"github.com/neilZon/workout-logger-api/common"
 	"github.com/neilZon/workout-logger-api/helpers"
 	"github.com/neilZon/workout-logger-api/tests/testdata"
 	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
 )
 
 func TestAccessControl(t *testing.T) {
@@ -37,6 +41,33 @@ func TestAccessControl(t *testing.T) {
 		}
 	})
 
+	t.Run("Test Can Access Workout Routine Cached Owner", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+
+		userId := fmt.Sprintf("%d", wr.UserID)
+		workoutRoutineId := fmt.Sprintf("%d", wr.ID)
+
+		workoutRoutineRow := sqlmock.
+			NewRows([]string{"id", "name", "user_id", "created_at", "deleted_at", "updated_at"}).
+			AddRow(wr.ID, wr.Name, wr.UserID, wr.CreatedAt, wr.DeletedAt, wr.UpdatedAt)
+
+		// only read once, the second check uses the cached owner
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).WithArgs(workoutRoutineId).WillReturnRows(workoutRoutineRow)
+
+		ac := &AccessController{DB: gormDB, Cache: cache.NewMemory(time.Minute)}
+		err := ac.CanAccessWorkoutRoutine(userId, workoutRoutineId)
+		require.Nil(t, err, "Should be no error for accessing workout routine")
+		err = ac.CanAccessWorkoutRoutine(userId, workoutRoutineId)
+		require.Nil(t, err, "Should be no error for accessing workout routine again")
+		err = ac.CanAccessWorkoutRoutine("43", workoutRoutineId)
+		require.Equal(t, "Access Denied", err.Error())
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
 	t.Run("Test Can Access Workout Routine Denied", func(t *testing.T) {
 		mock, gormDB := helpers.SetupMockDB()
 
@@ -103,4 +134,290 @@ func TestAccessControl(t *testing.T) {
 			panic(err)
 		}
 	})
+
+	t.Run("Test Can Participate In Workout Session As Partner", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+
+		partnerId := "55"
+		workoutSessionId := fmt.Sprintf("%d", ws.ID)
+
+		workoutSessionRow := sqlmock.
+			NewRows([]string{"id", "user_id", "start", "end", "workout_routine_id", "created_at", "deleted_at", "updated_at"}).
+			AddRow(ws.ID, ws.UserID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.CreatedAt, ws.DeletedAt, ws.UpdatedAt)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(workoutSessionId).WillReturnRows(workoutSessionRow)
+
+		const participantQuery = `SELECT * FROM "workout_session_participants" WHERE (workout_session_id = $1 AND user_id = $2) AND "workout_session_participants"."deleted_at" IS NULL ORDER BY "workout_session_participants"."id" LIMIT 1`
+		participantRow := sqlmock.NewRows([]string{"id", "workout_session_id", "user_id"}).AddRow(1, ws.ID, partnerId)
+		mock.ExpectQuery(regexp.QuoteMeta(participantQuery)).WithArgs(workoutSessionId, partnerId).WillReturnRows(participantRow)
+
+		ac := &AccessController{DB: gormDB}
+		err := ac.CanParticipateInWorkoutSession(partnerId, workoutSessionId)
+		require.Nil(t, err, "Should be no error for participating in workout session")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Test Can Participate In Workout Session Denied", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+
+		strangerId := "299"
+		workoutSessionId := fmt.Sprintf("%d", ws.ID)
+
+		workoutSessionRow := sqlmock.
+			NewRows([]string{"id", "user_id", "start", "end", "workout_routine_id", "created_at", "deleted_at", "updated_at"}).
+			AddRow(ws.ID, ws.UserID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.CreatedAt, ws.DeletedAt, ws.UpdatedAt)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(workoutSessionId).WillReturnRows(workoutSessionRow)
+
+		const participantQuery = `SELECT * FROM "workout_session_participants" WHERE (workout_session_id = $1 AND user_id = $2) AND "workout_session_participants"."deleted_at" IS NULL ORDER BY "workout_session_participants"."id" LIMIT 1`
+		mock.ExpectQuery(regexp.QuoteMeta(participantQuery)).WithArgs(workoutSessionId, strangerId).WillReturnError(gorm.ErrRecordNotFound)
+
+		ac := &AccessController{DB: gormDB}
+		err := ac.CanParticipateInWorkoutSession(strangerId, workoutSessionId)
+		require.Equal(t, err.Error(), "Access Denied")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	const coachQuery = `SELECT * FROM "coaches" WHERE (user_id = $1 AND coach_id = $2) AND "coaches"."deleted_at" IS NULL ORDER BY "coaches"."id" LIMIT 1`
+
+	t.Run("Test Can Read Workout Routine As Read Only Coach", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+
+		coachId := "77"
+		workoutRoutineId := fmt.Sprintf("%d", wr.ID)
+		workoutRoutineRow := sqlmock.
+			NewRows([]string{"id", "name", "user_id", "created_at", "deleted_at", "updated_at"}).
+			AddRow(wr.ID, wr.Name, wr.UserID, wr.CreatedAt, wr.DeletedAt, wr.UpdatedAt)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).WithArgs(workoutRoutineId).WillReturnRows(workoutRoutineRow)
+
+		coachRow := sqlmock.NewRows([]string{"id", "user_id", "coach_id", "access"}).AddRow(1, wr.UserID, coachId, "READ")
+		mock.ExpectQuery(regexp.QuoteMeta(coachQuery)).WithArgs(fmt.Sprintf("%d", wr.UserID), coachId).WillReturnRows(coachRow)
+
+		ac := &AccessController{DB: gormDB}
+		err := ac.CanReadWorkoutRoutine(coachId, workoutRoutineId)
+		require.Nil(t, err, "Should be no error for reading workout routine")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Test Can Access Workout Routine As Read Only Coach Denied", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+
+		coachId := "77"
+		workoutRoutineId := fmt.Sprintf("%d", wr.ID)
+		workoutRoutineRow := sqlmock.
+			NewRows([]string{"id", "name", "user_id", "created_at", "deleted_at", "updated_at"}).
+			AddRow(wr.ID, wr.Name, wr.UserID, wr.CreatedAt, wr.DeletedAt, wr.UpdatedAt)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).WithArgs(workoutRoutineId).WillReturnRows(workoutRoutineRow)
+
+		coachRow := sqlmock.NewRows([]string{"id", "user_id", "coach_id", "access"}).AddRow(1, wr.UserID, coachId, "READ")
+		mock.ExpectQuery(regexp.QuoteMeta(coachQuery)).WithArgs(fmt.Sprintf("%d", wr.UserID), coachId).WillReturnRows(coachRow)
+
+		ac := &AccessController{DB: gormDB}
+		err := ac.CanAccessWorkoutRoutine(coachId, workoutRoutineId)
+		require.Equal(t, err.Error(), "Access Denied")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Test Can Access Workout Session As Read Write Coach", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+
+		coachId := "77"
+		workoutSessionId := fmt.Sprintf("%d", ws.ID)
+		workoutSessionRow := sqlmock.
+			NewRows([]string{"id", "user_id", "start", "end", "workout_routine_id", "created_at", "deleted_at", "updated_at"}).
+			AddRow(ws.ID, ws.UserID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.CreatedAt, ws.DeletedAt, ws.UpdatedAt)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(workoutSessionId).WillReturnRows(workoutSessionRow)
+
+		coachRow := sqlmock.NewRows([]string{"id", "user_id", "coach_id", "access"}).AddRow(1, ws.UserID, coachId, "READ_WRITE")
+		mock.ExpectQuery(regexp.QuoteMeta(coachQuery)).WithArgs(fmt.Sprintf("%d", ws.UserID), coachId).WillReturnRows(coachRow)
+
+		ac := &AccessController{DB: gormDB}
+		err := ac.CanAccessWorkoutSession(coachId, workoutSessionId)
+		require.Nil(t, err, "Should be no error for accessing workout session")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Test Is Workout Session Owner Coach Denied", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+
+		coachId := "77"
+		workoutSessionId := fmt.Sprintf("%d", ws.ID)
+		workoutSessionRow := sqlmock.
+			NewRows([]string{"id", "user_id", "start", "end", "workout_routine_id", "created_at", "deleted_at", "updated_at"}).
+			AddRow(ws.ID, ws.UserID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.CreatedAt, ws.DeletedAt, ws.UpdatedAt)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(workoutSessionId).WillReturnRows(workoutSessionRow)
+
+		ac := &AccessController{DB: gormDB}
+		err := ac.IsWorkoutSessionOwner(coachId, workoutSessionId)
+		require.Equal(t, err.Error(), "Access Denied")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	const workoutSessionOwnersQuery = `SELECT id, user_id FROM "workout_sessions" WHERE id IN ($1,$2,$3) AND "workout_sessions"."deleted_at" IS NULL`
+
+	t.Run("Test Can Access Workout Sessions In One Query", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+
+		userId := fmt.Sprintf("%d", ws.UserID)
+		ownerRows := sqlmock.NewRows([]string{"id", "user_id"}).AddRow(1, ws.UserID).AddRow(2, ws.UserID).AddRow(3, ws.UserID)
+		mock.ExpectQuery(regexp.QuoteMeta(workoutSessionOwnersQuery)).WithArgs("1", "2", "3").WillReturnRows(ownerRows)
+
+		ac := &AccessController{DB: gormDB}
+		// repeated ids are only looked up once
+		err := ac.CanAccessWorkoutSessions(userId, []string{"1", "2", "3", "2"})
+		require.Nil(t, err, "Should be no error for accessing workout sessions")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Test Can Access Workout Sessions Missing One", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+
+		userId := fmt.Sprintf("%d", ws.UserID)
+		ownerRows := sqlmock.NewRows([]string{"id", "user_id"}).AddRow(1, ws.UserID).AddRow(3, ws.UserID)
+		mock.ExpectQuery(regexp.QuoteMeta(workoutSessionOwnersQuery)).WithArgs("1", "2", "3").WillReturnRows(ownerRows)
+
+		ac := &AccessController{DB: gormDB}
+		err := ac.CanAccessWorkoutSessions(userId, []string{"1", "2", "3"})
+		require.ErrorIs(t, err, gorm.ErrRecordNotFound)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Test Can Access Workout Sessions Someone Else's Denied", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+
+		userId := fmt.Sprintf("%d", ws.UserID)
+		ownerRows := sqlmock.NewRows([]string{"id", "user_id"}).AddRow(1, ws.UserID).AddRow(2, 43).AddRow(3, 43)
+		mock.ExpectQuery(regexp.QuoteMeta(workoutSessionOwnersQuery)).WithArgs("1", "2", "3").WillReturnRows(ownerRows)
+		// the other owner is only checked once for both of their sessions
+		mock.ExpectQuery(regexp.QuoteMeta(coachQuery)).WithArgs("43", userId).WillReturnError(gorm.ErrRecordNotFound)
+
+		ac := &AccessController{DB: gormDB}
+		err := ac.CanAccessWorkoutSessions(userId, []string{"1", "2", "3"})
+		require.Equal(t, "Access Denied", err.Error())
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Test Can Access Exercise Routines Cached Owners", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+
+		userId := fmt.Sprintf("%d", wr.UserID)
+		ownerRows := sqlmock.NewRows([]string{"id", "user_id"}).AddRow(5, wr.UserID).AddRow(6, wr.UserID)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT exercise_routines.id, workout_routines.user_id FROM "exercise_routines" JOIN workout_routines ON workout_routines.id = exercise_routines.workout_routine_id AND workout_routines.deleted_at IS NULL WHERE exercise_routines.id IN ($1,$2) AND "exercise_routines"."deleted_at" IS NULL`)).
+			WithArgs("5", "6").
+			WillReturnRows(ownerRows)
+		// only 7 isn't cached after the first check
+		ownerRows = sqlmock.NewRows([]string{"id", "user_id"}).AddRow(7, wr.UserID)
+		mock.ExpectQuery(regexp.QuoteMeta(`WHERE exercise_routines.id IN ($1)`)).
+			WithArgs("7").
+			WillReturnRows(ownerRows)
+
+		ac := &AccessController{DB: gormDB, Cache: cache.NewMemory(time.Minute)}
+		err := ac.CanAccessExerciseRoutines(userId, []string{"5", "6"})
+		require.Nil(t, err, "Should be no error for accessing exercise routines")
+		err = ac.CanAccessExerciseRoutines(userId, []string{"5", "6", "7"})
+		require.Nil(t, err, "Should be no error for accessing exercise routines again")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Test Can Edit Recent Workout Session", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+
+		lockRow := sqlmock.NewRows([]string{"start", "unlocked_until"}).AddRow(time.Now().AddDate(0, 0, -89), nil)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionEditLockQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(lockRow)
+
+		ac := &AccessController{DB: gormDB, EditLockDays: 90}
+		err := ac.CanEditWorkoutSession(fmt.Sprintf("%d", ws.ID))
+		require.Nil(t, err, "Should be no error for editing a recent workout session")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Test Can Edit Workout Session Locked", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+
+		// unlocked once, but that ran out
+		lockRow := sqlmock.NewRows([]string{"start", "unlocked_until"}).AddRow(ws.Start, time.Now().Add(-time.Minute))
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionEditLockQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(lockRow)
+
+		ac := &AccessController{DB: gormDB, EditLockDays: 90}
+		err := ac.CanEditWorkoutSession(fmt.Sprintf("%d", ws.ID))
+		var lockedError *common.WorkoutSessionLockedError
+		require.ErrorAs(t, err, &lockedError)
+		require.Equal(t, 90, lockedError.LockAfterDays)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Test Can Edit Unlocked Workout Session", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+
+		lockRow := sqlmock.NewRows([]string{"start", "unlocked_until"}).AddRow(ws.Start, time.Now().Add(time.Minute))
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionEditLockQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(lockRow)
+
+		ac := &AccessController{DB: gormDB, EditLockDays: 90}
+		err := ac.CanEditWorkoutSession(fmt.Sprintf("%d", ws.ID))
+		require.Nil(t, err, "Should be no error for editing an unlocked workout session")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Test Can Edit Workout Session Lock Off", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+
+		ac := &AccessController{DB: gormDB}
+		err := ac.CanEditWorkoutSession(fmt.Sprintf("%d", ws.ID))
+		require.Nil(t, err, "Should be no error when the edit lock is off")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
 }
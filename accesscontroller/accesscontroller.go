@@ -3,8 +3,17 @@ package accesscontroller
 // need to put this in a separate package from accesscontrol to prevent circular import
 type AccessControllerService interface {
 	CanAccessWorkoutRoutine(userId string, workoutRoutineId string) error
+	CanReadWorkoutRoutine(userId string, workoutRoutineId string) error
+	IsWorkoutRoutineOwner(userId string, workoutRoutineId string) error
 	CanAccessWorkoutSession(userId string, workoutSessionId string) error
-	CanAccessExerciseRoutine(userId string, exerciseId string) error
+	CanReadWorkoutSession(userId string, workoutSessionId string) error
+	IsWorkoutSessionOwner(userId string, workoutSessionId string) error
+	CanParticipateInWorkoutSession(userId string, workoutSessionId string) error
+	CanEditWorkoutSession(workoutSessionId string) error
+	CanAccessWorkoutSessions(userId string, workoutSessionIds []string) error
+	CanAccessExerciseRoutine(userId string, exerciseRoutineId string) error
+	CanAccessExerciseRoutines(userId string, exerciseRoutineIds []string) error
 	CanAccessExercise(userId string, exerciseId string) error
 	CanAccessSetEntry(userId string, exerciseId string) error
+	CanCoach(coachId string, userId string) error
 }
@@ -0,0 +1,176 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/clock"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/media"
+	"gorm.io/gorm"
+)
+
+// Archiver moves old workout sessions out of postgres into compressed json
+// blobs in the media store, leaving a summary row behind so they can still be
+// listed and restored
+type Archiver struct {
+	DB        *gorm.DB
+	Store     media.Store
+	MaxAge    time.Duration
+	BatchSize int
+}
+
+func NewArchiver(db *gorm.DB, store media.Store, maxAge time.Duration, batchSize int) *Archiver {
+	return &Archiver{
+		DB:        db,
+		Store:     store,
+		MaxAge:    maxAge,
+		BatchSize: batchSize,
+	}
+}
+
+// Start archives a batch every interval until stop is closed
+func (a *Archiver) Start(interval time.Duration, stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				archived, err := a.ArchiveBatch(context.Background(), clock.Now())
+				if err != nil {
+					log.Printf("error archiving workout sessions: %v", err)
+				} else if archived > 0 {
+					log.Printf("archived %d workout sessions", archived)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// ArchiveBatch archives up to BatchSize sessions that started more than
+// MaxAge before now
+func (a *Archiver) ArchiveBatch(ctx context.Context, now time.Time) (int, error) {
+	workoutSessions, err := database.GetArchivableWorkoutSessions(a.DB, now.Add(-a.MaxAge), a.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range workoutSessions {
+		if err := a.archive(ctx, &workoutSessions[i]); err != nil {
+			return i, err
+		}
+	}
+	return len(workoutSessions), nil
+}
+
+func (a *Archiver) archive(ctx context.Context, workoutSession *database.WorkoutSession) error {
+	blob, err := encode(workoutSession)
+	if err != nil {
+		return err
+	}
+
+	key := StorageKey(workoutSession.UserID, workoutSession.ID)
+	if err := a.Store.Save(ctx, key, bytes.NewReader(blob)); err != nil {
+		return err
+	}
+
+	archived := Summarize(workoutSession)
+	archived.StorageKey = key
+	if err := database.ArchiveWorkoutSession(a.DB, workoutSession, archived); err != nil {
+		// the session is still in postgres so the blob isn't needed
+		a.Store.Delete(ctx, key)
+		return err
+	}
+	return nil
+}
+
+// Load reads an archived session back out of its blob without touching
+// postgres
+func Load(ctx context.Context, store media.Store, archived *database.ArchivedWorkoutSession) (*database.WorkoutSession, error) {
+	r, err := store.Open(ctx, archived.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	var workoutSession database.WorkoutSession
+	if err := json.NewDecoder(gz).Decode(&workoutSession); err != nil {
+		return nil, err
+	}
+	return &workoutSession, nil
+}
+
+// Restore brings an archived session back into postgres and removes its blob
+func Restore(ctx context.Context, db *gorm.DB, store media.Store, archived *database.ArchivedWorkoutSession) (*database.WorkoutSession, error) {
+	workoutSession, err := Load(ctx, store, archived)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := database.RestoreArchivedWorkoutSession(db, archived, workoutSession); err != nil {
+		return nil, err
+	}
+
+	// the session is back in postgres, a leftover blob is only wasted space
+	if err := store.Delete(ctx, archived.StorageKey); err != nil {
+		log.Printf("error deleting archive %s: %v", archived.StorageKey, err)
+	}
+	return workoutSession, nil
+}
+
+// Summarize adds the session's volume up in the unit its first set was
+// logged in, lifetime stats convert it from there
+func Summarize(workoutSession *database.WorkoutSession) *database.ArchivedWorkoutSession {
+	archived := &database.ArchivedWorkoutSession{
+		WorkoutSessionID: workoutSession.ID,
+		UserID:           workoutSession.UserID,
+		WorkoutRoutineID: workoutSession.WorkoutRoutineID,
+		Start:            workoutSession.Start,
+		End:              workoutSession.End,
+		ExerciseCount:    len(workoutSession.Exercises),
+	}
+	for _, e := range workoutSession.Exercises {
+		for _, s := range e.Sets {
+			// same as the stats queries, warm ups don't count
+			if s.Type == database.SetTypeWarmup {
+				continue
+			}
+			if archived.VolumeUnit == "" {
+				archived.VolumeUnit = s.Unit
+			}
+			archived.SetCount++
+			archived.TotalVolume += database.ConvertWeight(float64(s.Weight), s.Unit, archived.VolumeUnit) * float64(s.Reps)
+		}
+	}
+	return archived
+}
+
+func StorageKey(userId uint, workoutSessionId uint) string {
+	return fmt.Sprintf("archive/workout-sessions/%d/%d.json.gz", userId, workoutSessionId)
+}
+
+func encode(workoutSession *database.WorkoutSession) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(gz).Encode(workoutSession); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,114 @@
+package autofinish
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/clock"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/live"
+	"gorm.io/gorm"
+)
+
+// OpenFor is how long a session can stay open before it's finished for the
+// user, 0 when auto finishing is off
+func OpenFor() time.Duration {
+	hours, err := strconv.Atoi(os.Getenv(config.AUTO_FINISH_HOURS))
+	if err != nil {
+		hours = config.DEFAULT_AUTO_FINISH_HOURS
+	}
+	if hours < 0 {
+		hours = 0
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// Finisher finishes sessions users forgot to, so they don't hold up the one
+// open session per user or count hours of rest as workout time
+type Finisher struct {
+	DB        *gorm.DB
+	OpenFor   time.Duration
+	BatchSize int
+	// where spectators of the sessions finished are watching from
+	Live *live.Broker
+}
+
+func NewFinisher(db *gorm.DB, openFor time.Duration, batchSize int, broker *live.Broker) *Finisher {
+	return &Finisher{
+		DB:        db,
+		OpenFor:   openFor,
+		BatchSize: batchSize,
+		Live:      broker,
+	}
+}
+
+// Start finishes stale sessions every interval until stop is closed
+func (f *Finisher) Start(interval time.Duration, stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				finished, err := f.FinishStaleSessions(clock.Now())
+				if err != nil {
+					log.Printf("error auto finishing workout sessions: %v", err)
+				} else if finished > 0 {
+					log.Printf("auto finished %d workout sessions", finished)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// FinishStaleSessions finishes sessions that started more than OpenFor
+// before now in batches and returns how many were finished. each one ends at
+// its last set, or where it started when nothing was logged, and anyone
+// watching it live is let go
+func (f *Finisher) FinishStaleSessions(now time.Time) (int, error) {
+	finished := 0
+	openSince := now.Add(-f.OpenFor)
+	var lastId uint
+	for {
+		workoutSessions, err := database.GetStaleWorkoutSessions(f.DB.Where("id > ?", lastId), openSince, f.BatchSize)
+		if err != nil {
+			return finished, err
+		}
+
+		for i := range workoutSessions {
+			ws := &workoutSessions[i]
+			lastId = ws.ID
+
+			end := ws.Start
+			last, err := database.GetLastSetTime(f.DB, ws.ID)
+			if err != nil {
+				return finished, err
+			}
+			if last != nil && last.After(end) {
+				end = *last
+			}
+
+			err = database.AutoFinishWorkoutSession(f.DB, ws, end)
+			if errors.Is(err, database.ErrWorkoutSessionFinished) {
+				// the user got to it first
+				continue
+			}
+			if err != nil {
+				return finished, err
+			}
+			f.Live.Close(fmt.Sprintf("%d", ws.ID))
+			finished++
+		}
+
+		if len(workoutSessions) < f.BatchSize {
+			return finished, nil
+		}
+	}
+}
@@ -0,0 +1,31 @@
+// Package cache is where instances keep what's expensive to read again. In
+// memory is enough for one instance, with more than one they need to share
+// Redis so a change made through one instance isn't missed by the others
+package cache
+
+import (
+	"time"
+)
+
+// Cache is a key value store with expiry. Stores that can fail, like Redis,
+// log the error and carry on as if the key wasn't there, the database is
+// always the source of truth
+type Cache interface {
+	Get(key string) ([]byte, bool)
+	// a ttl of 0 keeps the value until it's overwritten or deleted
+	Set(key string, value []byte, ttl time.Duration)
+	Delete(key string)
+}
+
+var (
+	_ Cache = &Memory{}
+	_ Cache = &Redis{}
+)
+
+// New is Redis when redisURL is set and Memory otherwise
+func New(redisURL string, sweepEvery time.Duration) (Cache, error) {
+	if redisURL == "" {
+		return NewMemory(sweepEvery), nil
+	}
+	return NewRedis(redisURL)
+}
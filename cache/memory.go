@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"sync"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/clock"
+)
+
+type memoryEntry struct {
+	value []byte
+	// zero never expires
+	expiresAt time.Time
+}
+
+// Memory keeps entries in the process, expired ones are dropped when they're
+// read or on the next sweep. Other instances don't see them
+type Memory struct {
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+	// how often Set sweeps out expired entries
+	sweepEvery time.Duration
+}
+
+func NewMemory(sweepEvery time.Duration) *Memory {
+	return &Memory{
+		entries:    map[string]memoryEntry{},
+		lastSweep:  clock.Now(),
+		sweepEvery: sweepEvery,
+	}
+}
+
+func (m *Memory) Get(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if entry.expired(clock.Now()) {
+		delete(m.entries, key)
+		return nil, false
+	}
+	return entry.value, true
+}
+
+func (m *Memory) Set(key string, value []byte, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := clock.Now()
+	entry := memoryEntry{value: value}
+	if ttl > 0 {
+		entry.expiresAt = now.Add(ttl)
+	}
+	m.entries[key] = entry
+
+	// entries that are never read again would otherwise stay forever
+	if now.Sub(m.lastSweep) >= m.sweepEvery {
+		for k, e := range m.entries {
+			if e.expired(now) {
+				delete(m.entries, k)
+			}
+		}
+		m.lastSweep = now
+	}
+}
+
+func (m *Memory) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+}
+
+func (e memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && !now.Before(e.expiresAt)
+}
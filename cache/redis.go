@@ -0,0 +1,247 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/config"
+)
+
+// Redis is a Cache every instance shares. It speaks just enough of the redis
+// protocol for GET, SET and DEL over a small pool of connections
+type Redis struct {
+	addr     string
+	username string
+	password string
+	db       int
+	tls      *tls.Config
+	timeout  time.Duration
+
+	idle chan *redisConn
+}
+
+type redisConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// an error reply, the connection is still fine after one
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+// NewRedis connects lazily to a url like redis://:password@host:6379/0, or
+// rediss:// for tls
+func NewRedis(redisURL string) (*Redis, error) {
+	u, err := url.Parse(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("%s isn't a url: %w", config.REDIS_URL, err)
+	}
+
+	r := &Redis{
+		addr:    u.Host,
+		timeout: config.REDIS_TIMEOUT,
+		idle:    make(chan *redisConn, config.REDIS_MAX_IDLE_CONNS),
+	}
+	switch u.Scheme {
+	case "redis":
+	case "rediss":
+		r.tls = &tls.Config{ServerName: u.Hostname()}
+	default:
+		return nil, fmt.Errorf("%s needs to start with redis:// or rediss://, got %q", config.REDIS_URL, u.Scheme)
+	}
+	if u.Port() == "" {
+		r.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		// redis://:password@ is a password without a user
+		if password, ok := u.User.Password(); ok {
+			r.username = u.User.Username()
+			r.password = password
+		} else {
+			r.password = u.User.Username()
+		}
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		r.db, err = strconv.Atoi(db)
+		if err != nil {
+			return nil, fmt.Errorf("%s needs a database number after the host, got %q", config.REDIS_URL, db)
+		}
+	}
+	return r, nil
+}
+
+func (r *Redis) Get(key string) ([]byte, bool) {
+	reply, err := r.do("GET", key)
+	if err != nil {
+		log.Printf("error getting %s from redis: %s", key, err)
+		return nil, false
+	}
+	value, ok := reply.([]byte)
+	return value, ok
+}
+
+func (r *Redis) Set(key string, value []byte, ttl time.Duration) {
+	args := []string{"SET", key, string(value)}
+	if ttl > 0 {
+		ms := ttl.Milliseconds()
+		if ms < 1 {
+			ms = 1
+		}
+		args = append(args, "PX", strconv.FormatInt(ms, 10))
+	}
+	if _, err := r.do(args...); err != nil {
+		log.Printf("error setting %s in redis: %s", key, err)
+	}
+}
+
+func (r *Redis) Delete(key string) {
+	if _, err := r.do("DEL", key); err != nil {
+		log.Printf("error deleting %s from redis: %s", key, err)
+	}
+}
+
+// do runs a command on an idle connection, or a new one when none are idle.
+// Connections that fail are closed rather than put back
+func (r *Redis) do(args ...string) (interface{}, error) {
+	var c *redisConn
+	select {
+	case c = <-r.idle:
+	default:
+		var err error
+		c, err = r.dial()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	reply, err := c.do(r.timeout, args...)
+	if err != nil {
+		c.conn.Close()
+		return nil, err
+	}
+	select {
+	case r.idle <- c:
+	default:
+		c.conn.Close()
+	}
+
+	if e, ok := reply.(redisError); ok {
+		return nil, e
+	}
+	return reply, nil
+}
+
+func (r *Redis) dial() (*redisConn, error) {
+	dialer := &net.Dialer{Timeout: r.timeout}
+	var conn net.Conn
+	var err error
+	if r.tls != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", r.addr, r.tls)
+	} else {
+		conn, err = dialer.Dial("tcp", r.addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{conn: conn, r: bufio.NewReader(conn)}
+
+	var setup [][]string
+	if r.username != "" {
+		setup = append(setup, []string{"AUTH", r.username, r.password})
+	} else if r.password != "" {
+		setup = append(setup, []string{"AUTH", r.password})
+	}
+	if r.db != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(r.db)})
+	}
+	for _, args := range setup {
+		reply, err := c.do(r.timeout, args...)
+		if e, ok := reply.(redisError); ok && err == nil {
+			err = e
+		}
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("redis %s: %w", args[0], err)
+		}
+	}
+	return c, nil
+}
+
+func (c *redisConn) do(timeout time.Duration, args ...string) (interface{}, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+
+	var cmd bytes.Buffer
+	fmt.Fprintf(&cmd, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&cmd, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := c.conn.Write(cmd.Bytes()); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+// read is one reply. Bulk strings are []byte and a missing key is nil
+func (c *redisConn) read() (interface{}, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redis: bad reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return redisError(body), nil
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad reply %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		b := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, b); err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("redis: bad reply %q", line)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			items[i], err = c.read()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redis: bad reply %q", line)
+}
@@ -0,0 +1,50 @@
+// Package chaos slows down or fails a share of calls on purpose, so client
+// retries and timeouts can be tried against the api before a real outage
+package chaos
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/config"
+)
+
+// ErrInjected is the error a call fails with when it was picked to fail
+var ErrInjected = errors.New("chaos: injected fault")
+
+// Injector decides which calls get a fault
+type Injector struct {
+	Fault config.Fault
+	// returns a number in [0, 100), rand by default. tests swap it out
+	Percent func() float64
+}
+
+func NewInjector(fault config.Fault) *Injector {
+	return &Injector{
+		Fault: fault,
+		Percent: func() float64 {
+			return rand.Float64() * 100
+		},
+	}
+}
+
+// Inject waits out the latency if the call was picked for it, and returns
+// ErrInjected if it was picked to fail. Latency and errors are picked
+// separately so a call can get both. Giving up on ctx ends the wait early
+func (i *Injector) Inject(ctx context.Context) error {
+	if i.Fault.Latency > 0 && i.Percent() < i.Fault.LatencyPercent {
+		t := time.NewTimer(i.Fault.Latency)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+	if i.Percent() < i.Fault.ErrorPercent {
+		return ErrInjected
+	}
+	return nil
+}
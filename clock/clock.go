@@ -0,0 +1,58 @@
+// Package clock is the server's idea of now. It's the real time unless e2e
+// test mode has frozen or moved it, so anything with a ttl or a deadline
+// should ask here instead of the time package
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+var (
+	mu sync.RWMutex
+	// added to the real time while running
+	offset time.Duration
+	// set while frozen, now stays put until it's advanced
+	frozen *time.Time
+)
+
+func Now() time.Time {
+	mu.RLock()
+	defer mu.RUnlock()
+	if frozen != nil {
+		return *frozen
+	}
+	return time.Now().Add(offset)
+}
+
+func Since(t time.Time) time.Duration {
+	return Now().Sub(t)
+}
+
+// Freeze stops the clock at t
+func Freeze(t time.Time) {
+	mu.Lock()
+	defer mu.Unlock()
+	frozen = &t
+}
+
+// Advance moves the clock forward by d, frozen or not, and returns the new now
+func Advance(d time.Duration) time.Time {
+	mu.Lock()
+	defer mu.Unlock()
+	if frozen != nil {
+		t := frozen.Add(d)
+		frozen = &t
+		return t
+	}
+	offset += d
+	return time.Now().Add(offset)
+}
+
+// Reset goes back to the real time
+func Reset() {
+	mu.Lock()
+	defer mu.Unlock()
+	offset = 0
+	frozen = nil
+}
@@ -0,0 +1,92 @@
+// Command loadtest seeds the database in .env with load test users and their
+// history, then writes the hot path requests for them as k6 or vegeta input.
+// Run it against a dev or staging database, never production.
+//
+//	go run ./cmd/loadtest -format k6 -out loadtest/scenarios.json
+//	k6 run loadtest/k6.js
+//
+//	go run ./cmd/loadtest -format vegeta -scenario add_set -out targets.json
+//	vegeta attack -format=json -targets=targets.json -rate=50 -duration=30s | vegeta report
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/testsupport"
+)
+
+func main() {
+	log.SetFlags(0)
+	opts := testsupport.DefaultSeedOptions
+	flag.IntVar(&opts.Users, "users", opts.Users, "users to seed")
+	flag.IntVar(&opts.SessionsPerUser, "sessions", opts.SessionsPerUser, "finished sessions per user")
+	flag.IntVar(&opts.Exercises, "exercises", opts.Exercises, "exercises per session")
+	flag.IntVar(&opts.SetsPerExercise, "sets", opts.SetsPerExercise, "sets per exercise")
+	flag.StringVar(&opts.EmailPrefix, "prefix", fmt.Sprintf("lifter%d-", time.Now().Unix()), "seeded emails start with this")
+	url := flag.String("url", "http://localhost:8080/query", "graphql endpoint the requests go to")
+	format := flag.String("format", "k6", "k6 or vegeta")
+	scenario := flag.String("scenario", "workout_sessions", "with vegeta, the one to write: workout_sessions, add_set or login")
+	out := flag.String("out", "", "file to write to, stdout when empty")
+	flag.Parse()
+
+	if *format != "k6" && *format != "vegeta" {
+		log.Fatalf("format %q isn't k6 or vegeta", *format)
+	}
+
+	// ci passes the settings in the environment instead
+	if err := godotenv.Load(); err != nil && !os.IsNotExist(err) {
+		log.Fatalf("error loading .env file: %v", err)
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		log.Fatal(err)
+	}
+	started := time.Now()
+	users, err := testsupport.Seed(db, opts)
+	if err != nil {
+		log.Fatalf("error seeding: %v", err)
+	}
+	log.Printf("seeded %d users in %s", len(users), time.Since(started).Round(time.Millisecond))
+
+	scenarios, err := testsupport.Scenarios(*url, users, []byte(os.Getenv(config.ACCESS_SECRET)))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if *format == "k6" {
+		err = testsupport.WriteK6(w, scenarios)
+	} else {
+		err = writeVegeta(w, scenarios, *scenario)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func writeVegeta(w io.Writer, scenarios []testsupport.Scenario, name string) error {
+	for _, s := range scenarios {
+		if s.Name == name {
+			return testsupport.WriteVegeta(w, s.Requests)
+		}
+	}
+	return fmt.Errorf("there's no scenario %q", name)
+}
@@ -0,0 +1,147 @@
+// Command migrate applies and inspects the schema migrations in migrate/sql,
+// with the same .env settings as the server.
+//
+//	go run ./cmd/migrate up            apply every pending migration
+//	go run ./cmd/migrate down [n]      undo the latest n migrations, 1 by default
+//	go run ./cmd/migrate status        list migrations, exits 1 when any are pending
+//	go run ./cmd/migrate force v       mark the database at version v after fixing a failed migration
+//	go run ./cmd/migrate create name   add an empty up and down for the next version
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/migrate"
+)
+
+const usage = "usage: migrate up | down [n] | status | force version | create name"
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		log.Fatal(usage)
+	}
+
+	// ci passes the settings in the environment instead
+	if err := godotenv.Load(); err != nil && !os.IsNotExist(err) {
+		log.Fatalf("error loading .env file: %v", err)
+	}
+
+	if os.Args[1] == "create" {
+		if len(os.Args) != 3 {
+			log.Fatal(usage)
+		}
+		if err := create(os.Args[2]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	db, err := database.Connect()
+	if err != nil {
+		log.Fatal(err)
+	}
+	migrator, err := migrate.New(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch os.Args[1] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, m := range applied {
+			fmt.Printf("applied %s\n", m)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("nothing to apply")
+		}
+	case "down":
+		steps := 1
+		if len(os.Args) > 2 {
+			steps, err = strconv.Atoi(os.Args[2])
+			if err != nil || steps < 1 {
+				log.Fatal(usage)
+			}
+		}
+		undone, err := migrator.Down(steps)
+		for _, m := range undone {
+			fmt.Printf("undid %s\n", m)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatal(err)
+		}
+		pending := 0
+		for _, s := range statuses {
+			switch {
+			case s.Dirty:
+				pending++
+				fmt.Printf("%s  dirty, it failed partway\n", s.Migration)
+			case s.Applied:
+				fmt.Printf("%s  applied\n", s.Migration)
+			default:
+				pending++
+				fmt.Printf("%s  pending\n", s.Migration)
+			}
+		}
+		if pending > 0 {
+			fmt.Printf("%d pending\n", pending)
+			os.Exit(1)
+		}
+	case "force":
+		// -1 is before the first migration
+		if len(os.Args) != 3 {
+			log.Fatal(usage)
+		}
+		version, err := strconv.Atoi(os.Args[2])
+		if err != nil || version < -1 {
+			log.Fatal(usage)
+		}
+		if err := migrator.Force(version); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("marked the database at version %d\n", version)
+	default:
+		log.Fatal(usage)
+	}
+}
+
+var migrationName = regexp.MustCompile(`^\w+$`)
+
+// create writes the next version's files, it's run from the repo root
+func create(name string) error {
+	if !migrationName.MatchString(name) {
+		return fmt.Errorf("migration names are letters, digits and underscores, like add_set_indexes")
+	}
+	migrator, err := migrate.NewFromFS(nil, os.DirFS(migrate.Dir), ".")
+	if err != nil {
+		return err
+	}
+	var version uint = 1
+	if migrations := migrator.Migrations(); len(migrations) > 0 {
+		version = migrations[len(migrations)-1].Version + 1
+	}
+
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(migrate.Dir, fmt.Sprintf("%04d_%s.%s.sql", version, name, direction))
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			return err
+		}
+		fmt.Printf("created %s\n", path)
+	}
+	return nil
+}
@@ -1,7 +1,43 @@
 package common
 
+import (
+	"fmt"
+	"time"
+)
+
 type UnauthorizedError struct{}
 
 func (u *UnauthorizedError) Error() string {
 	return "Unauthorized"
 }
+
+// WorkoutSessionLockedError is returned for changes to a session past the
+// edit lock, the app can offer to unlock it with unlockWorkoutSession
+type WorkoutSessionLockedError struct {
+	LockAfterDays int
+}
+
+func (e *WorkoutSessionLockedError) Error() string {
+	return fmt.Sprintf("Workout sessions older than %d days are locked, unlock the session to edit it", e.LockAfterDays)
+}
+
+// LoginLockedError is returned for logins to an email or from an ip that
+// failed too many times in a row, they're refused until RetryAfter passes
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return "Too many failed logins, try again later"
+}
+
+// ConflictError is returned for updates to a row that changed since the
+// client read it, Current is the row as the server has it now
+type ConflictError struct {
+	Action  string
+	Current interface{}
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s: Changed Since It Was Read", e.Action)
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// names of the .env settings for fault injection, all off when unset
+const (
+	CHAOS_LATENCY                  = "CHAOS_LATENCY"                  // a go duration like "2s"
+	CHAOS_DB_LATENCY_PERCENT       = "CHAOS_DB_LATENCY_PERCENT"       // 0 - 100
+	CHAOS_DB_ERROR_PERCENT         = "CHAOS_DB_ERROR_PERCENT"         // 0 - 100
+	CHAOS_RESOLVER_LATENCY_PERCENT = "CHAOS_RESOLVER_LATENCY_PERCENT" // 0 - 100
+	CHAOS_RESOLVER_ERROR_PERCENT   = "CHAOS_RESOLVER_ERROR_PERCENT"   // 0 - 100
+	CHAOS_RESOLVERS                = "CHAOS_RESOLVERS"                // like "Mutation.addWorkoutSession,Query.workoutRoutines"
+)
+
+// Fault is how often calls get slowed down or fail, as percents of calls
+type Fault struct {
+	Latency        time.Duration
+	LatencyPercent float64
+	ErrorPercent   float64
+}
+
+func (f Fault) Enabled() bool {
+	return (f.Latency > 0 && f.LatencyPercent > 0) || f.ErrorPercent > 0
+}
+
+// Chaos is the faults injected into database calls and resolvers so client
+// retries can be tried out before a real outage
+type Chaos struct {
+	DB        Fault
+	Resolvers Fault
+	// "Type.field" like "Mutation.addWorkoutSession", empty is every resolver
+	ResolverFields []string
+}
+
+// DefaultChaosLatency is what slowed down calls wait when CHAOS_LATENCY isn't set
+const DefaultChaosLatency = 2 * time.Second
+
+// ChaosFromEnv reads the fault injection settings. Like the pool settings, a
+// value that doesn't parse is an error, faults that silently don't happen
+// would pass for resilience
+func ChaosFromEnv() (Chaos, error) {
+	latency := DefaultChaosLatency
+	if v := os.Getenv(CHAOS_LATENCY); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return Chaos{}, fmt.Errorf("%s needs to be a duration like 2s, got %q", CHAOS_LATENCY, v)
+		}
+		latency = d
+	}
+
+	c := Chaos{
+		DB:        Fault{Latency: latency},
+		Resolvers: Fault{Latency: latency},
+	}
+	percents := []struct {
+		name  string
+		value *float64
+	}{
+		{CHAOS_DB_LATENCY_PERCENT, &c.DB.LatencyPercent},
+		{CHAOS_DB_ERROR_PERCENT, &c.DB.ErrorPercent},
+		{CHAOS_RESOLVER_LATENCY_PERCENT, &c.Resolvers.LatencyPercent},
+		{CHAOS_RESOLVER_ERROR_PERCENT, &c.Resolvers.ErrorPercent},
+	}
+	for _, p := range percents {
+		v := os.Getenv(p.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil || n < 0 || n > 100 {
+			return Chaos{}, fmt.Errorf("%s needs to be a percent between 0 and 100, got %q", p.name, v)
+		}
+		*p.value = n
+	}
+
+	for _, field := range strings.Split(os.Getenv(CHAOS_RESOLVERS), ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if typeName, fieldName, ok := strings.Cut(field, "."); !ok || typeName == "" || fieldName == "" {
+			return Chaos{}, fmt.Errorf("%s needs to be resolvers like Mutation.addWorkoutSession, got %q", CHAOS_RESOLVERS, field)
+		}
+		c.ResolverFields = append(c.ResolverFields, field)
+	}
+	return c, nil
+}
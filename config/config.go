@@ -6,6 +6,226 @@ const (
 	ACCESS_TTL  time.Duration = 720 // hours
 	REFRESH_TTL time.Duration = 24  // hours
 
+	// how long an admin's token for acting as a user lasts
+	IMPERSONATION_TTL time.Duration = 1 // hours
+
+	// how long a soft deleted routine/session can still be restored
+	RESTORE_GRACE_PERIOD time.Duration = 72 // hours
+
+	// soft quota on a single graphql response
+	MAX_RESPONSE_BYTES = 2 << 20 // 2MB
+	MAX_RESPONSE_NODES = 10000
+
+	// bodies past these are turned away before they're read. uploads are
+	// multipart, the largest is a video
+	MAX_REQUEST_BYTES        = 2 << 20                 // 2MB
+	MAX_UPLOAD_REQUEST_BYTES = MAX_VIDEO_BYTES + 1<<20 // the video and the rest of the form
+
+	// operation documents kept for automatic persisted queries, the app only
+	// has a few hundred so they all fit with room for old app versions
+	APQ_CACHE_SIZE = 1000
+	// parsed and validated documents kept, keyed on the query text
+	QUERY_CACHE_SIZE = 1000
+
+	// workoutRoutines and exerciseRoutines results are kept this long unless
+	// a mutation changes them first
+	RESPONSE_CACHE_TTL = 30 * time.Second
+	// how often expired entries are swept out of the in-memory cache
+	CACHE_SWEEP_INTERVAL = time.Minute
+	// dataloader results shared through the cache are kept this long, they're
+	// deleted sooner by the mutations that change them
+	LOADER_CACHE_TTL = 5 * time.Minute
+	// who owns a routine or session, for access checks. Ownership only changes
+	// when accounts are merged
+	ACCESS_CACHE_TTL = time.Minute
+	// when a user's request recording ends, so the recorder doesn't read
+	// the user for every operation. startRequestRecording reads it again
+	RECORDING_CACHE_TTL = time.Minute
+
+	// with more than one instance they share redis instead of each keeping
+	// its own cache, REDIS_URL is like redis://:password@host:6379/0
+	REDIS_URL            = "REDIS_URL"
+	REDIS_TIMEOUT        = 500 * time.Millisecond
+	REDIS_MAX_IDLE_CONNS = 10
+
+	// token buckets per user, or per ip without a token, and for the auth
+	// mutations per ip. bursts are how many can go at once, the rate is how
+	// fast they come back
+	RATE_LIMIT_PER_MINUTE      = 300
+	RATE_LIMIT_BURST           = 60
+	AUTH_RATE_LIMIT_PER_MINUTE = 10
+	AUTH_RATE_LIMIT_BURST      = 5
+
+	// biggest page clients in low bandwidth mode get, whatever they ask for
+	LOW_BANDWIDTH_PAGE_SIZE = 10
+
+	// pages of a session's exercises and of an exercise's sets, clients that
+	// don't send a limit get the default one
+	DEFAULT_EXERCISES_PAGE_SIZE = 50
+	MAX_EXERCISES_PAGE_SIZE     = 100
+	DEFAULT_SETS_PAGE_SIZE      = 100
+	MAX_SETS_PAGE_SIZE          = 200
+
+	// form check videos are meant to be a single set. they're private like
+	// progress photos, but their urls last long enough to watch one through
+	MAX_VIDEO_BYTES = 50 << 20 // 50MB
+	VIDEO_URL_TTL   = time.Hour
+
+	// tags are short labels like "deload", not a second place for notes
+	MAX_WORKOUT_SESSION_TAGS = 10
+	MAX_TAG_LEN              = 32
+	MAX_SESSION_NOTES_LEN    = 2048
+
+	// bulk deletes check access for every id in one query, this keeps the
+	// query and the transaction after it small
+	MAX_BULK_IDS = 100
+
+	// progress photos go straight to storage, the signed urls for them only
+	// work for a little while
+	MAX_PHOTO_BYTES = 15 << 20 // 15MB
+	PHOTO_URL_TTL   = 15 * time.Minute
+
+	// clientMetadata on sessions and sets is for small bits of device info,
+	// not a place to keep a second copy of the workout
+	MAX_CLIENT_METADATA_BYTES   = 2 << 10 // 2KB of json
+	MAX_CLIENT_METADATA_DEPTH   = 3
+	MAX_CLIENT_METADATA_KEY_LEN = 64
+
+	// sandbox mode runs against its own postgres schema filled with synthetic
+	// data so third party devs can't touch real users
+	SANDBOX_SCHEMA           = "sandbox"
+	SANDBOX_QUOTA_MULTIPLIER = 5
+	SANDBOX_USER_EMAIL       = "lifter@sandbox.untilfailure.app"
+	SANDBOX_USER_PASSWORD    = "sandbox-password"
+
+	// queries slower than this get logged, along with their query plan when
+	// EXPLAIN_SLOW_QUERIES is on
+	SLOW_QUERY_THRESHOLD time.Duration = 200 // milliseconds
+
+	// service.name on exported traces unless OTEL_SERVICE_NAME says otherwise
+	SERVICE_NAME = "workout-logger-api"
+
+	// set_entries is partitioned by month, keep this many months of empty
+	// partitions ready ahead of time
+	SET_ENTRY_PARTITION_MONTHS_AHEAD = 3
+	SET_ENTRY_PARTITION_INTERVAL     = 24 * time.Hour
+
+	// workout sessions this old get moved out of postgres into cold storage
+	ARCHIVE_AFTER      = 2 * 365 * 24 * time.Hour
+	ARCHIVE_BATCH_SIZE = 100
+	ARCHIVE_INTERVAL   = 24 * time.Hour
+
+	// how often open goals are checked for completion
+	GOAL_CHECK_INTERVAL   = 15 * time.Minute
+	GOAL_CHECK_BATCH_SIZE = 100
+
+	// sessions left open longer than this get finished at their last set.
+	// AUTO_FINISH_HOURS in .env overrides the default, 0 turns it off
+	DEFAULT_AUTO_FINISH_HOURS = 6
+	AUTO_FINISH_INTERVAL      = 15 * time.Minute
+	AUTO_FINISH_BATCH_SIZE    = 100
+
+	// account data exports are built in the background and can be downloaded
+	// for a week before they're deleted
+	ACCOUNT_EXPORT_INTERVAL   = time.Minute
+	ACCOUNT_EXPORT_BATCH_SIZE = 10
+	ACCOUNT_EXPORT_TTL        = 7 * 24 * time.Hour
+
+	// finished sessions are posted to webhooks in the background. failed
+	// posts are tried again after WEBHOOK_BACKOFF, doubling every time, until
+	// WEBHOOK_MAX_ATTEMPTS
+	WEBHOOK_INTERVAL     = 30 * time.Second
+	WEBHOOK_BATCH_SIZE   = 50
+	WEBHOOK_TIMEOUT      = 10 * time.Second
+	WEBHOOK_MAX_ATTEMPTS = 8
+	WEBHOOK_BACKOFF      = time.Minute
+
+	// finished sessions of users connected to Strava are uploaded in the
+	// background, with the same kind of backoff as webhooks. access tokens
+	// are refreshed when they have less than STRAVA_REFRESH_BEFORE left
+	STRAVA_INTERVAL       = 30 * time.Second
+	STRAVA_BATCH_SIZE     = 50
+	STRAVA_TIMEOUT        = 10 * time.Second
+	STRAVA_MAX_ATTEMPTS   = 8
+	STRAVA_BACKOFF        = time.Minute
+	STRAVA_REFRESH_BEFORE = 5 * time.Minute
+
+	// weekly digests go out on DIGEST_SCHEDULE, a cron spec in server time,
+	// and cover the week before. the default is monday at 8
+	DEFAULT_DIGEST_SCHEDULE = "0 8 * * 1"
+	DIGEST_BATCH_SIZE       = 100
+
+	SENDGRID_TIMEOUT = 10 * time.Second
+	FCM_TIMEOUT      = 10 * time.Second
+
+	// how long the link sent to a new email address can confirm the change
+	EMAIL_CHANGE_TTL = 24 * time.Hour
+
+	// password reset links work once within PASSWORD_RESET_TTL, and an account
+	// is sent at most MAX_PASSWORD_RESETS_PER_HOUR of them
+	PASSWORD_RESET_TTL           = time.Hour
+	MAX_PASSWORD_RESETS_PER_HOUR = 3
+
+	// logins to an email lock out after LOGIN_FREE_FAILURES failed ones in a
+	// row, and from an ip after LOGIN_FREE_FAILURES_PER_IP since many people
+	// can share one. The lockout starts at LOGIN_LOCKOUT and doubles with
+	// each failure after, up to LOGIN_MAX_LOCKOUT. Failures are forgotten
+	// after LOGIN_FAILURE_WINDOW without one
+	LOGIN_FREE_FAILURES        = 5
+	LOGIN_FREE_FAILURES_PER_IP = 20
+	LOGIN_LOCKOUT              = 30 * time.Second
+	LOGIN_MAX_LOCKOUT          = time.Hour
+	LOGIN_FAILURE_WINDOW       = 24 * time.Hour
+
+	// sessions that started longer ago than this are read only unless the
+	// owner unlocks them, which lasts for SESSION_UNLOCK_TTL.
+	// SESSION_EDIT_LOCK_DAYS in .env overrides the default, 0 turns it off
+	DEFAULT_SESSION_EDIT_LOCK_DAYS = 90
+	SESSION_UNLOCK_TTL             = time.Hour
+
+	// on SIGTERM in-flight requests and subscriptions get this long to finish,
+	// Cloud Run kills the instance 10 seconds after sending it
+	SHUTDOWN_TIMEOUT = 8 * time.Second
+
+	// /readyz gives up on the database after this long so a hung connection
+	// reads as not ready instead of hanging the probe
+	READINESS_TIMEOUT = 2 * time.Second
+
+	// most exercises a session can be logged with in one go
+	MAX_EXERCISES_PER_SESSION = 30
+
+	// most quick phrases a user can keep
+	MAX_QUICK_PHRASES = 50
+
+	// most snapshots a user can keep
+	MAX_SNAPSHOTS = 10
+
+	// biggest Strong or Hevy export importWorkoutHistory reads
+	MAX_IMPORT_BYTES = 20 << 20 // 20MB
+
+	// sets with more reps than this are left out of strengthProfile, one rep
+	// max estimates from them are too far off
+	MAX_ONE_REP_MAX_REPS = 12
+
+	// most webhooks a user can register
+	MAX_WEBHOOKS = 5
+
+	// most suggestions autocomplete hands back at once
+	MAX_AUTOCOMPLETE_SUGGESTIONS = 25
+
+	// most results search hands back at once
+	MAX_SEARCH_RESULTS = 50
+
+	// most changes syncWorkoutData takes at once, clients that have been
+	// offline longer send them in batches
+	MAX_SYNC_CHANGES = 500
+
+	// most entries auditTrail hands back at once
+	MAX_AUDIT_TRAIL_ENTRIES = 200
+
+	// longest window a user can turn on request recording for
+	MAX_RECORDING_MINUTES = 24 * 60
+
 	// these are not the actual secrets, but are the keys to get the secrets
 	// from the .env file
 	ACCESS_SECRET  = "ACCESS_SECRET"
@@ -13,4 +233,76 @@ const (
 	EMAIL          = "EMAIL"
 	APP_PASSWORD   = "APP_PASSWORD"
 	HOST           = "HOST"
+
+	// archived sessions, exports and snapshots, never served. the server
+	// won't start without it, an empty one would be the working directory
+	ARCHIVE_DIR = "ARCHIVE_DIR"
+
+	// form check videos, only reachable through urls signed with
+	// MEDIA_SIGNING_SECRET. the server won't start without MEDIA_DIR, an
+	// empty one would be the working directory
+	MEDIA_DIR            = "MEDIA_DIR"
+	MEDIA_SIGNING_SECRET = "MEDIA_SIGNING_SECRET"
+
+	// progress photos are private, they're only reachable through urls
+	// signed with PHOTO_SIGNING_SECRET
+	PHOTO_DIR            = "PHOTO_DIR"
+	PHOTO_SIGNING_SECRET = "PHOTO_SIGNING_SECRET"
+
+	// the Strava api app, connecting to Strava is turned off without them
+	STRAVA_CLIENT_ID     = "STRAVA_CLIENT_ID"
+	STRAVA_CLIENT_SECRET = "STRAVA_CLIENT_SECRET"
+
+	// emails go through SendGrid when SENDGRID_API_KEY is set, the EMAIL
+	// account's smtp server otherwise. push notifications are off without
+	// FCM_SERVER_KEY
+	SENDGRID_API_KEY = "SENDGRID_API_KEY"
+	FCM_SERVER_KEY   = "FCM_SERVER_KEY"
+
+	// cron spec the weekly digest goes out on, "off" turns it off
+	DIGEST_SCHEDULE = "DIGEST_SCHEDULE"
+
+	// set to "true" to record every authenticated request, not just opted in users
+	RECORD_ALL_REQUESTS = "RECORD_ALL_REQUESTS"
+
+	// set to "true" to run the server in sandbox mode
+	SANDBOX = "SANDBOX"
+
+	// set to "true" to open the /e2e endpoints that move the clock and mint
+	// tokens, it's ignored on Cloud Run
+	E2E_TEST_MODE = "E2E_TEST_MODE"
+	// comma separated emails of the users seeded for e2e tests, /e2e/token
+	// only signs tokens for them and the sandbox user
+	E2E_USER_EMAILS = "E2E_USER_EMAILS"
+
+	// set to "true" to log the EXPLAIN output of slow queries
+	EXPLAIN_SLOW_QUERIES = "EXPLAIN_SLOW_QUERIES"
+
+	// oldest app version the api still answers, empty lets every version
+	// through. the store links are sent back so the app can send users to
+	// the update
+	MIN_CLIENT_VERSION = "MIN_CLIENT_VERSION"
+	IOS_STORE_URL      = "IOS_STORE_URL"
+	ANDROID_STORE_URL  = "ANDROID_STORE_URL"
+
+	// set to "true" or "false" to force the startup check of the live schema
+	// against the models on or off, it's on everywhere but Cloud Run by default
+	SCHEMA_DRIFT_CHECK = "SCHEMA_DRIFT_CHECK"
+
+	// set to "false" to leave applying migrations to `go run ./cmd/migrate up`,
+	// the server applies whatever is pending when it starts otherwise
+	MIGRATE_ON_START = "MIGRATE_ON_START"
+
+	// days after which sessions can't be edited without unlocking them
+	SESSION_EDIT_LOCK_DAYS = "SESSION_EDIT_LOCK_DAYS"
+
+	// hours after which open sessions are finished for the user
+	AUTO_FINISH_HOURS = "AUTO_FINISH_HOURS"
+
+	// which Authenticator checks access tokens, jwt when it's empty
+	AUTH_PROVIDER = "AUTH_PROVIDER"
+
+	// where to send traces over OTLP/HTTP, tracing is off when it's empty. the
+	// rest of the standard OTEL_* vars are read by the sdk
+	OTEL_EXPORTER_OTLP_ENDPOINT = "OTEL_EXPORTER_OTLP_ENDPOINT"
 )
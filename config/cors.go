@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// CORS_ALLOWED_ORIGINS is the .env setting for the origins browsers can call
+// the api from, comma separated like
+// "https://app.untilfailure.app,https://*.untilfailure.app"
+const CORS_ALLOWED_ORIGINS = "CORS_ALLOWED_ORIGINS"
+
+// DefaultAllowedOrigins are for local development and trying out queries
+var DefaultAllowedOrigins = []string{"http://127.0.0.1", "http://localhost:8080", "https://hoppscotch.io"}
+
+// AllowedOriginsFromEnv is CORS_ALLOWED_ORIGINS, or DefaultAllowedOrigins when
+// it isn't set and the server isn't on Cloud Run. There it has to be set so
+// production never answers localhost or hoppscotch by accident. Browsers send
+// origins without a path, so one with a path would never match and is an
+// error, as is "*" since requests carry credentials. A single "*" in the host
+// allows any subdomain
+func AllowedOriginsFromEnv() ([]string, error) {
+	v := os.Getenv(CORS_ALLOWED_ORIGINS)
+	if strings.TrimSpace(v) == "" {
+		// Cloud Run sets K_SERVICE
+		if os.Getenv("K_SERVICE") != "" {
+			return nil, fmt.Errorf("%s needs to be set on Cloud Run", CORS_ALLOWED_ORIGINS)
+		}
+		return DefaultAllowedOrigins, nil
+	}
+
+	origins := []string{}
+	for _, origin := range strings.Split(v, ",") {
+		origin = strings.TrimSpace(origin)
+		if origin == "" {
+			continue
+		}
+		u, err := url.Parse(strings.Replace(origin, "*", "wildcard", 1))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" || u.RawQuery != "" || strings.Contains(u.Host, "*") {
+			return nil, fmt.Errorf("%s needs to be origins like https://app.untilfailure.app, got %q", CORS_ALLOWED_ORIGINS, origin)
+		}
+		origins = append(origins, strings.ToLower(origin))
+	}
+	if len(origins) == 0 {
+		return nil, fmt.Errorf("%s needs at least one origin", CORS_ALLOWED_ORIGINS)
+	}
+	return origins, nil
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// names of the .env settings for the postgres connection pool
+const (
+	DB_MAX_OPEN_CONNS     = "DB_MAX_OPEN_CONNS"
+	DB_MAX_IDLE_CONNS     = "DB_MAX_IDLE_CONNS"
+	DB_CONN_MAX_LIFETIME  = "DB_CONN_MAX_LIFETIME"  // a go duration like "30m"
+	DB_CONN_MAX_IDLE_TIME = "DB_CONN_MAX_IDLE_TIME" // a go duration like "5m"
+	DB_STATEMENT_TIMEOUT  = "DB_STATEMENT_TIMEOUT"  // a go duration like "30s"
+
+	// read replica for the read only endpoint, it's off when the host isn't
+	// set. the port defaults to the primary's, everything else is shared
+	DB_REPLICA_HOST = "DB_REPLICA_HOST"
+	DB_REPLICA_PORT = "DB_REPLICA_PORT"
+)
+
+// DBPool is how many connections each instance keeps to postgres and for how
+// long. Every instance opens up to MaxOpenConns, so that times the number of
+// instances has to stay under postgres' max_connections
+type DBPool struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// 0 leaves it to postgres. off by default since the csv export streams a
+	// single query for as long as the download takes
+	StatementTimeout time.Duration
+}
+
+// DefaultDBPool leaves room for 4 instances on postgres' default of 100
+// connections
+var DefaultDBPool = DBPool{
+	MaxOpenConns:    20,
+	MaxIdleConns:    10,
+	ConnMaxLifetime: 30 * time.Minute,
+	ConnMaxIdleTime: 5 * time.Minute,
+}
+
+// DBPoolFromEnv starts from DefaultDBPool and applies whatever is set in the
+// environment. A value that doesn't parse is an error rather than a silent
+// default, a pool that's off by an order of magnitude is hard to spot
+func DBPoolFromEnv() (DBPool, error) {
+	pool := DefaultDBPool
+
+	ints := []struct {
+		name  string
+		value *int
+	}{
+		{DB_MAX_OPEN_CONNS, &pool.MaxOpenConns},
+		{DB_MAX_IDLE_CONNS, &pool.MaxIdleConns},
+	}
+	for _, i := range ints {
+		v := os.Getenv(i.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return DBPool{}, fmt.Errorf("%s needs to be a whole number of connections, got %q", i.name, v)
+		}
+		*i.value = n
+	}
+	// migrating holds a connection for its lock and another to run on
+	if pool.MaxOpenConns == 1 {
+		return DBPool{}, fmt.Errorf("%s needs to be at least 2, migrations use two connections at once", DB_MAX_OPEN_CONNS)
+	}
+
+	durations := []struct {
+		name  string
+		value *time.Duration
+	}{
+		{DB_CONN_MAX_LIFETIME, &pool.ConnMaxLifetime},
+		{DB_CONN_MAX_IDLE_TIME, &pool.ConnMaxIdleTime},
+		{DB_STATEMENT_TIMEOUT, &pool.StatementTimeout},
+	}
+	for _, d := range durations {
+		v := os.Getenv(d.name)
+		if v == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(v)
+		if err != nil || duration < 0 {
+			return DBPool{}, fmt.Errorf("%s needs to be a duration like 30s, got %q", d.name, v)
+		}
+		*d.value = duration
+	}
+
+	// database/sql caps idle at open anyway, this keeps what's logged honest
+	if pool.MaxOpenConns > 0 && pool.MaxIdleConns > pool.MaxOpenConns {
+		pool.MaxIdleConns = pool.MaxOpenConns
+	}
+	return pool, nil
+}
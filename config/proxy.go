@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// TRUSTED_PROXIES is the .env setting for the proxies in front of the api
+// whose X-Forwarded-For is believed, comma separated addresses or ranges like
+// "10.0.0.0/8,169.254.1.1"
+const TRUSTED_PROXIES = "TRUSTED_PROXIES"
+
+// TrustedProxiesFromEnv is TRUSTED_PROXIES as ranges, none when it isn't set
+// so every caller is taken to be who connected
+func TrustedProxiesFromEnv() ([]*net.IPNet, error) {
+	proxies := []*net.IPNet{}
+	for _, proxy := range strings.Split(os.Getenv(TRUSTED_PROXIES), ",") {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("%s needs to be addresses or ranges like 10.0.0.0/8, got %q", TRUSTED_PROXIES, proxy)
+			}
+			if v4 := ip.To4(); v4 != nil {
+				ip = v4
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("%s needs to be addresses or ranges like 10.0.0.0/8, got %q", TRUSTED_PROXIES, proxy)
+		}
+		proxies = append(proxies, ipNet)
+	}
+	return proxies, nil
+}
@@ -0,0 +1,226 @@
+package database
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PurgedBlobs are storage keys of files that belonged to a purged user. they
+// live outside postgres so the caller removes them once the purge commits
+type PurgedBlobs struct {
+	Media   []string // exercise videos
+	Archive []string // archived workout sessions, account exports and snapshots
+	Photos  []string // progress photos
+}
+
+// sessions the user owns, other users' exercises in them go with them since
+// they hang off the user's routine
+const ownedWorkoutSessions = "SELECT id FROM workout_sessions WHERE user_id = @user"
+
+// exercises the user logged anywhere plus everything logged in their sessions
+const purgedExercises = "SELECT id FROM exercises WHERE user_id = @user OR workout_session_id IN (" + ownedWorkoutSessions + ")"
+
+const purgedExerciseVideos = "SELECT id FROM exercise_videos WHERE user_id = @user OR exercise_id IN (" + purgedExercises + ")"
+
+// PurgeUser hard deletes the user and every row that belongs to them in one
+// transaction, soft deleted rows included. Tokens stop working once the user
+// row is gone since every request and refresh looks the user up and ids are
+// never reused. A dry run rolls back after counting and hands back no blobs
+func PurgeUser(db *gorm.DB, userId string, dryRun bool) (*PurgedBlobs, *DeletionCounts, error) {
+	blobs := PurgedBlobs{}
+	counts := DeletionCounts{}
+	user := map[string]interface{}{"user": userId}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&ExerciseVideo{}).Where("id IN ("+purgedExerciseVideos+")", user).Pluck("storage_key", &blobs.Media).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&ArchivedWorkoutSession{}).Where("user_id = @user", user).Pluck("storage_key", &blobs.Archive).Error; err != nil {
+			return err
+		}
+		var exportKeys []string
+		if err := tx.Unscoped().Model(&AccountExport{}).Where("user_id = @user AND storage_key <> ''", user).Pluck("storage_key", &exportKeys).Error; err != nil {
+			return err
+		}
+		blobs.Archive = append(blobs.Archive, exportKeys...)
+		var snapshotKeys []string
+		if err := tx.Unscoped().Model(&Snapshot{}).Where("user_id = @user", user).Pluck("storage_key", &snapshotKeys).Error; err != nil {
+			return err
+		}
+		blobs.Archive = append(blobs.Archive, snapshotKeys...)
+		if err := tx.Unscoped().Model(&ProgressPhoto{}).Where("user_id = @user", user).Pluck("storage_key", &blobs.Photos).Error; err != nil {
+			return err
+		}
+
+		// children before parents, not every relation has a cascading foreign key
+		deletes := []struct {
+			model interface{}
+			where string
+			count *int64
+		}{
+			{&VideoAnnotation{}, "coach_id = @user OR exercise_video_id IN (" + purgedExerciseVideos + ")", nil},
+			{&ExerciseVideo{}, "id IN (" + purgedExerciseVideos + ")", &counts.ExerciseVideos},
+			{&SetEntry{}, "exercise_id IN (" + purgedExercises + ")", &counts.Sets},
+			{&Exercise{}, "id IN (" + purgedExercises + ")", &counts.Exercises},
+			{&WorkoutSessionParticipant{}, "user_id = @user OR workout_session_id IN (" + ownedWorkoutSessions + ")", nil},
+			{&WorkoutSessionShareLink{}, "user_id = @user OR workout_session_id IN (" + ownedWorkoutSessions + ")", nil},
+			{&WorkoutSessionUnlock{}, "user_id = @user OR workout_session_id IN (" + ownedWorkoutSessions + ")", nil},
+			{&WorkoutSessionAutoFinish{}, "user_id = @user OR workout_session_id IN (" + ownedWorkoutSessions + ")", nil},
+			{&WebhookDelivery{}, "webhook_id IN (SELECT id FROM webhooks WHERE user_id = @user) OR workout_session_id IN (" + ownedWorkoutSessions + ")", nil},
+			{&StravaUpload{}, "user_id = @user OR workout_session_id IN (" + ownedWorkoutSessions + ")", nil},
+			{&WorkoutSessionTag{}, "workout_session_id IN (" + ownedWorkoutSessions + ")", nil},
+			{&WorkoutSession{}, "user_id = @user", &counts.WorkoutSessions},
+			{&WorkoutRoutineShareLink{}, "user_id = @user OR workout_routine_id IN (SELECT id FROM workout_routines WHERE user_id = @user)", nil},
+			{&ProgramDay{}, "program_id IN (SELECT id FROM programs WHERE user_id = @user) OR workout_routine_id IN (SELECT id FROM workout_routines WHERE user_id = @user)", nil},
+			{&Program{}, "user_id = @user", nil},
+			{&ExerciseRoutine{}, "workout_routine_id IN (SELECT id FROM workout_routines WHERE user_id = @user)", &counts.ExerciseRoutines},
+			{&WorkoutRoutine{}, "user_id = @user", &counts.WorkoutRoutines},
+			{&Coach{}, "user_id = @user OR coach_id = @user", nil},
+			{&RequestRecording{}, "user_id = @user", nil},
+			{&ArchivedWorkoutSession{}, "user_id = @user", nil},
+			{&BodyWeightEntry{}, "user_id = @user", &counts.BodyWeights},
+			{&Goal{}, "user_id = @user", &counts.Goals},
+			{&NutritionLog{}, "user_id = @user", &counts.NutritionLogs},
+			{&SleepLog{}, "user_id = @user", &counts.SleepLogs},
+			{&UnitConversion{}, "user_id = @user", nil},
+			{&QuickPhrase{}, "user_id = @user", nil},
+			{&AccountExport{}, "user_id = @user", nil},
+			{&Snapshot{}, "user_id = @user", nil},
+			{&Webhook{}, "user_id = @user", nil},
+			{&StravaConnection{}, "user_id = @user", nil},
+			{&ProgressPhoto{}, "user_id = @user", nil},
+			{&SyncRecord{}, "user_id = @user", nil},
+			{&AuditLog{}, "user_id = @user", nil},
+			{&PasswordResetToken{}, "user_id = @user", nil},
+			{&RefreshToken{}, "user_id = @user", nil},
+			{&Session{}, "user_id = @user", nil},
+			{&UserPreferences{}, "user_id = @user", nil},
+			{&PushToken{}, "user_id = @user", nil},
+			{&WeeklyDigest{}, "user_id = @user", nil},
+			{&LoginThrottle{}, "key = (SELECT 'email:' || LOWER(email) FROM users WHERE id = @user)", nil},
+			{&User{}, "id = @user", nil},
+		}
+		for _, d := range deletes {
+			result := tx.Unscoped().Where(d.where, user).Delete(d.model)
+			if result.Error != nil {
+				return result.Error
+			}
+			if d.count != nil {
+				*d.count = result.RowsAffected
+			}
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err == errDryRun {
+		return &PurgedBlobs{}, &counts, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return &blobs, &counts, nil
+}
+
+// MergeUsers moves everything the source user has onto the target user and
+// hard deletes the source in one transaction, for people who ended up with
+// two accounts. Rows only allowed once per user that both accounts have are
+// settled in favour of the target, soft deleted rows move too so unique
+// indexes still hold if they're restored. It's ErrOpenWorkoutSession when
+// both accounts have an open session
+func MergeUsers(db *gorm.DB, sourceId string, targetId string) error {
+	users := map[string]interface{}{"source": sourceId, "target": targetId}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		// one open session per user, one of them has to be finished first
+		var openIds []uint
+		err := tx.Model(&WorkoutSession{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where(`user_id IN (@source, @target) AND "end" IS NULL`, users).
+			Pluck("id", &openIds).Error
+		if err != nil {
+			return err
+		}
+		if len(openIds) > 1 {
+			return ErrOpenWorkoutSession
+		}
+
+		duplicates := []struct {
+			model interface{}
+			where string
+		}{
+			{&WorkoutSessionParticipant{}, "user_id = @source AND workout_session_id IN (SELECT workout_session_id FROM workout_session_participants WHERE user_id = @target)"},
+			// nobody participates in their own session
+			{&WorkoutSessionParticipant{}, "user_id IN (@source, @target) AND workout_session_id IN (SELECT id FROM workout_sessions WHERE user_id IN (@source, @target))"},
+			{&Coach{}, "user_id = @source AND coach_id IN (SELECT coach_id FROM coaches WHERE user_id = @target)"},
+			{&Coach{}, "coach_id = @source AND user_id IN (SELECT user_id FROM coaches WHERE coach_id = @target)"},
+			// or coaches themselves
+			{&Coach{}, "user_id IN (@source, @target) AND coach_id IN (@source, @target)"},
+			{&NutritionLog{}, "user_id = @source AND day IN (SELECT day FROM nutrition_logs WHERE user_id = @target)"},
+			{&SleepLog{}, "user_id = @source AND night IN (SELECT night FROM sleep_logs WHERE user_id = @target)"},
+			{&StravaConnection{}, "user_id = @source AND EXISTS (SELECT 1 FROM strava_connections WHERE user_id = @target)"},
+			{&SyncRecord{}, "user_id = @source AND client_id IN (SELECT client_id FROM sync_records WHERE user_id = @target)"},
+			{&UserPreferences{}, "user_id = @source AND EXISTS (SELECT 1 FROM user_preferences WHERE user_id = @target)"},
+			{&WeeklyDigest{}, "user_id = @source AND week_end IN (SELECT week_end FROM weekly_digests WHERE user_id = @target)"},
+			// links sent to the source's email shouldn't reset the target's password
+			{&PasswordResetToken{}, "user_id = @source"},
+			// and the source's logins end with it
+			{&RefreshToken{}, "user_id = @source"},
+			{&Session{}, "user_id = @source"},
+		}
+		for _, d := range duplicates {
+			if err := tx.Unscoped().Where(d.where, users).Delete(d.model).Error; err != nil {
+				return err
+			}
+		}
+
+		// timestamps are left alone, the rows didn't change only who owns them
+		reparented := []struct {
+			model  interface{}
+			column string
+		}{
+			{&WorkoutRoutine{}, "user_id"},
+			{&WorkoutSession{}, "user_id"},
+			{&Exercise{}, "user_id"},
+			{&ExerciseVideo{}, "user_id"},
+			{&VideoAnnotation{}, "coach_id"},
+			{&WorkoutSessionParticipant{}, "user_id"},
+			{&WorkoutSessionShareLink{}, "user_id"},
+			{&WorkoutSessionUnlock{}, "user_id"},
+			{&WorkoutSessionAutoFinish{}, "user_id"},
+			{&Coach{}, "user_id"},
+			{&Coach{}, "coach_id"},
+			{&RequestRecording{}, "user_id"},
+			{&ArchivedWorkoutSession{}, "user_id"},
+			{&BodyWeightEntry{}, "user_id"},
+			{&Goal{}, "user_id"},
+			{&NutritionLog{}, "user_id"},
+			{&SleepLog{}, "user_id"},
+			{&UnitConversion{}, "user_id"},
+			{&QuickPhrase{}, "user_id"},
+			{&AccountExport{}, "user_id"},
+			{&Snapshot{}, "user_id"},
+			{&Program{}, "user_id"},
+			{&WorkoutRoutineShareLink{}, "user_id"},
+			{&Webhook{}, "user_id"},
+			{&StravaConnection{}, "user_id"},
+			{&StravaUpload{}, "user_id"},
+			{&ProgressPhoto{}, "user_id"},
+			{&SyncRecord{}, "user_id"},
+			{&AuditLog{}, "user_id"},
+			{&UserPreferences{}, "user_id"},
+			{&PushToken{}, "user_id"},
+			{&WeeklyDigest{}, "user_id"},
+		}
+		for _, r := range reparented {
+			if err := tx.Unscoped().Model(r.model).Where(r.column+" = @source", users).UpdateColumn(r.column, targetId).Error; err != nil {
+				return err
+			}
+		}
+
+		// the source's tokens stop working with it since every request looks
+		// the user up
+		return tx.Unscoped().Where("id = @source", users).Delete(&User{}).Error
+	})
+}
@@ -0,0 +1,101 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func AddAccountExport(db *gorm.DB, accountExport *AccountExport) error {
+	result := db.Create(accountExport)
+	return result.Error
+}
+
+func GetAccountExport(db *gorm.DB, accountExportId string) (*AccountExport, error) {
+	var accountExport AccountExport
+	result := db.Where("id = ?", accountExportId).First(&accountExport)
+	return &accountExport, result.Error
+}
+
+// export for userId that is still waiting or being built, if any
+func GetUnfinishedAccountExport(db *gorm.DB, userId string) (*AccountExport, error) {
+	var accountExport AccountExport
+	result := db.Where("user_id = ? AND status IN ?", userId, []string{AccountExportStatusPending, AccountExportStatusRunning}).First(&accountExport)
+	return &accountExport, result.Error
+}
+
+func GetPendingAccountExports(db *gorm.DB, limit int) ([]AccountExport, error) {
+	var accountExports []AccountExport
+	result := db.Where("status = ?", AccountExportStatusPending).Order("id").Limit(limit).Find(&accountExports)
+	return accountExports, result.Error
+}
+
+// StartAccountExport moves a pending export to running, false if another
+// exporter already took it
+func StartAccountExport(db *gorm.DB, accountExportId uint) (bool, error) {
+	result := db.Model(&AccountExport{}).
+		Where("id = ? AND status = ?", accountExportId, AccountExportStatusPending).
+		Update("status", AccountExportStatusRunning)
+	return result.RowsAffected > 0, result.Error
+}
+
+func FinishAccountExport(db *gorm.DB, accountExportId uint, status string, storageKey string, completedAt time.Time) error {
+	result := db.Model(&AccountExport{}).Where("id = ?", accountExportId).Updates(map[string]interface{}{
+		"status":       status,
+		"storage_key":  storageKey,
+		"completed_at": completedAt,
+	})
+	return result.Error
+}
+
+// ready exports completed before the cutoff
+func GetExpiredAccountExports(db *gorm.DB, completedBefore time.Time, limit int) ([]AccountExport, error) {
+	var accountExports []AccountExport
+	result := db.Where("status = ? AND completed_at < ?", AccountExportStatusReady, completedBefore).Order("id").Limit(limit).Find(&accountExports)
+	return accountExports, result.Error
+}
+
+func DeleteAccountExport(db *gorm.DB, accountExportId uint) error {
+	result := db.Where("id = ?", accountExportId).Delete(&AccountExport{})
+	return result.Error
+}
+
+// GetAccountWorkoutRoutines includes each routine's exercise routines in the
+// order the user set
+func GetAccountWorkoutRoutines(db *gorm.DB, userId string) ([]WorkoutRoutine, error) {
+	var workoutRoutines []WorkoutRoutine
+	result := db.Preload("ExerciseRoutines", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position, id")
+	}).Where("user_id = ?", userId).Order("id").Find(&workoutRoutines)
+	return workoutRoutines, result.Error
+}
+
+// GetAccountWorkoutSessions is every session userId owns or co-logged in,
+// with only the exercises they logged. routines are loaded even when soft
+// deleted so every exercise keeps its name
+func GetAccountWorkoutSessions(db *gorm.DB, userId string) ([]WorkoutSession, error) {
+	var workoutSessions []WorkoutSession
+	result := db.
+		Preload("WorkoutRoutine", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		}).
+		Preload("Exercises", func(db *gorm.DB) *gorm.DB {
+			return db.Where("user_id = ?", userId).Order("id")
+		}).
+		Preload("Exercises.ExerciseRoutine", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		}).
+		Preload("Exercises.Sets", func(db *gorm.DB) *gorm.DB {
+			return db.Order("set_order, id")
+		}).
+		Where("user_id = ? OR EXISTS (SELECT 1 FROM exercises WHERE exercises.workout_session_id = workout_sessions.id AND exercises.user_id = ? AND exercises.deleted_at IS NULL)", userId, userId).
+		Order("start, id").
+		Find(&workoutSessions)
+	return workoutSessions, result.Error
+}
+
+func GetAllBodyWeightEntries(db *gorm.DB, userId string) ([]BodyWeightEntry, error) {
+	var entries []BodyWeightEntry
+	result := db.Where("user_id = ?", userId).Order("logged_at, id").Find(&entries)
+	return entries, result.Error
+}
@@ -0,0 +1,153 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrOpenWorkoutSession is when a user starts a session while another one
+// hasn't been finished
+var ErrOpenWorkoutSession = errors.New("another workout session is still open")
+
+// ErrWorkoutSessionFinished is when a session is finished a second time
+var ErrWorkoutSessionFinished = errors.New("workout session is already finished")
+
+// every user has at most one open session so the app knows which one to
+// resume after a crash, the baseline migration's idx_workout_sessions_open
+// holds them to it
+
+func GetOpenWorkoutSession(db *gorm.DB, userId string) (*WorkoutSession, error) {
+	var workoutSession WorkoutSession
+	result := db.Where(`user_id = ? AND "end" IS NULL`, userId).First(&workoutSession)
+	return &workoutSession, result.Error
+}
+
+// checkNoOpenWorkoutSession locks the user's open session, if there is one,
+// so two sessions started at once can't both get past it. The unique index
+// is what stops them in the end
+func checkNoOpenWorkoutSession(tx *gorm.DB, userId uint) error {
+	var ids []uint
+	err := tx.Model(&WorkoutSession{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where(`user_id = ? AND "end" IS NULL`, userId).
+		Limit(1).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return err
+	}
+	if len(ids) > 0 {
+		return ErrOpenWorkoutSession
+	}
+	return nil
+}
+
+// FinishWorkoutSession sets the end of a session that's still open and
+// queues it up for the owner's webhooks and Strava in the same transaction
+func FinishWorkoutSession(db *gorm.DB, workoutSessionId string, end time.Time) (*WorkoutSession, error) {
+	var workoutSession *WorkoutSession
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		workoutSession, err = finishWorkoutSession(tx, workoutSessionId, end)
+		if err != nil {
+			return err
+		}
+		return queueFinishedWorkoutSession(tx, workoutSession)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return workoutSession, nil
+}
+
+func finishWorkoutSession(tx *gorm.DB, workoutSessionId string, end time.Time) (*WorkoutSession, error) {
+	var workoutSession WorkoutSession
+	result := tx.Model(&workoutSession).
+		Clauses(clause.Returning{}).
+		Where(`id = ? AND "end" IS NULL`, workoutSessionId).
+		Update("end", end)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrWorkoutSessionFinished
+	}
+	return &workoutSession, nil
+}
+
+// queueFinishedWorkoutSession sends a session that was just finished on to
+// wherever its owner wants finished sessions to go
+func queueFinishedWorkoutSession(tx *gorm.DB, workoutSession *WorkoutSession) error {
+	if err := queueWebhookDeliveries(tx, workoutSession); err != nil {
+		return err
+	}
+	return queueStravaUpload(tx, workoutSession)
+}
+
+// GetStaleWorkoutSessions is up to limit open sessions that started before
+// openSince in id order, so they can be paged through with id > the last one
+func GetStaleWorkoutSessions(db *gorm.DB, openSince time.Time, limit int) ([]WorkoutSession, error) {
+	var workoutSessions []WorkoutSession
+	result := db.Where(`"end" IS NULL AND start < ?`, openSince).Order("id").Limit(limit).Find(&workoutSessions)
+	return workoutSessions, result.Error
+}
+
+// GetLastSetTime is when the session's last set was done, or logged if it
+// wasn't marked done. nil when the session has no sets
+func GetLastSetTime(db *gorm.DB, workoutSessionId uint) (*time.Time, error) {
+	var last sql.NullTime
+	err := db.Model(&SetEntry{}).
+		Select("MAX(COALESCE(set_entries.completed_at, set_entries.created_at))").
+		Joins("JOIN exercises ON exercises.id = set_entries.exercise_id AND exercises.deleted_at IS NULL").
+		Where("exercises.workout_session_id = ?", workoutSessionId).
+		Scan(&last).Error
+	if err != nil || !last.Valid {
+		return nil, err
+	}
+	return &last.Time, nil
+}
+
+// AutoFinishWorkoutSession finishes a session left open and records that it
+// was the server that did, in the audit log too, in one transaction. It's
+// ErrWorkoutSessionFinished when the user finished it first
+func AutoFinishWorkoutSession(db *gorm.DB, workoutSession *WorkoutSession, end time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		before, err := AuditSnapshot(tx, AuditEntityWorkoutSession, workoutSession.ID)
+		if err != nil {
+			return err
+		}
+		finished, err := finishWorkoutSession(tx, fmt.Sprintf("%d", workoutSession.ID), end)
+		if err != nil {
+			return err
+		}
+		if err := queueFinishedWorkoutSession(tx, finished); err != nil {
+			return err
+		}
+		err = tx.Create(&WorkoutSessionAutoFinish{
+			WorkoutSessionID: workoutSession.ID,
+			UserID:           workoutSession.UserID,
+			Start:            workoutSession.Start,
+			End:              end,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		after, err := AuditSnapshot(tx, AuditEntityWorkoutSession, workoutSession.ID)
+		if err != nil {
+			return err
+		}
+		return AddAuditLog(tx, &AuditLog{
+			UserID:     workoutSession.UserID,
+			Operation:  AuditOperationAutoFinish,
+			EntityType: AuditEntityWorkoutSession,
+			EntityID:   workoutSession.ID,
+			Before:     before,
+			After:      after,
+		})
+	})
+}
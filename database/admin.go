@@ -0,0 +1,49 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GetUsers is a page of users by id after cursor, ones whose name or email
+// contains query when it isn't empty
+func GetUsers(db *gorm.DB, query string, cursor string, limit int) ([]User, error) {
+	var users []User
+	if query != "" {
+		like := "%" + escapeLike(query) + "%"
+		db = db.Where("name ILIKE ? OR email ILIKE ?", like, like)
+	}
+	if cursor != "" {
+		db = db.Where("id > ?", cursor)
+	}
+	err := db.Order("id").Limit(limit).Find(&users).Error
+	return users, err
+}
+
+type UsageStats struct {
+	Users              int64
+	VerifiedUsers      int64
+	WeeklyActiveUsers  int64
+	MonthlyActiveUsers int64
+	WorkoutSessions    int64
+	Sets               int64
+}
+
+// GetUsageStats counts across every user, active ones started a workout
+// session in the week or 30 days before now
+func GetUsageStats(db *gorm.DB, now time.Time) (*UsageStats, error) {
+	var stats UsageStats
+	err := db.Raw(`SELECT
+		(SELECT count(*) FROM users WHERE deleted_at IS NULL) AS users,
+		(SELECT count(*) FROM users WHERE deleted_at IS NULL AND verified) AS verified_users,
+		(SELECT count(DISTINCT user_id) FROM workout_sessions WHERE deleted_at IS NULL AND start > @week) AS weekly_active_users,
+		(SELECT count(DISTINCT user_id) FROM workout_sessions WHERE deleted_at IS NULL AND start > @month) AS monthly_active_users,
+		(SELECT count(*) FROM workout_sessions WHERE deleted_at IS NULL) AS workout_sessions,
+		(SELECT count(*) FROM set_entries WHERE deleted_at IS NULL) AS sets`,
+		map[string]interface{}{
+			"week":  now.AddDate(0, 0, -7),
+			"month": now.AddDate(0, 0, -30),
+		}).Scan(&stats).Error
+	return &stats, err
+}
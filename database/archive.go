@@ -0,0 +1,80 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Archive
+// sessions with form check videos stay hot since the videos point at their
+// exercises
+func GetArchivableWorkoutSessions(db *gorm.DB, startedBefore time.Time, limit int) ([]WorkoutSession, error) {
+	var workoutSessions []WorkoutSession
+	err := db.
+		Preload("Exercises").
+		Preload("Exercises.Sets", func(db *gorm.DB) *gorm.DB {
+			return db.Order("set_order, id")
+		}).
+		Where("start < ?", startedBefore).
+		Where("NOT EXISTS (SELECT 1 FROM exercise_videos JOIN exercises ON exercises.id = exercise_videos.exercise_id WHERE exercises.workout_session_id = workout_sessions.id AND exercise_videos.deleted_at IS NULL)").
+		Order("id").
+		Limit(limit).
+		Find(&workoutSessions).Error
+	return workoutSessions, err
+}
+
+// ArchiveWorkoutSession swaps a workout session for its archive index row.
+// the session and everything under it is hard deleted, the blob in storage is
+// the only copy left
+func ArchiveWorkoutSession(db *gorm.DB, workoutSession *WorkoutSession, archived *ArchivedWorkoutSession) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(archived).Error; err != nil {
+			return err
+		}
+
+		var exerciseIds []uint
+		for _, e := range workoutSession.Exercises {
+			exerciseIds = append(exerciseIds, e.ID)
+		}
+		if len(exerciseIds) > 0 {
+			if err := tx.Unscoped().Where("exercise_id IN ?", exerciseIds).Delete(&SetEntry{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Unscoped().Where("id IN ?", exerciseIds).Delete(&Exercise{}).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Unscoped().Where("workout_session_id = ?", workoutSession.ID).Delete(&WorkoutSessionParticipant{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("workout_session_id = ?", workoutSession.ID).Delete(&WorkoutSessionShareLink{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("id = ?", workoutSession.ID).Delete(&WorkoutSession{}).Error
+	})
+}
+
+func GetArchivedWorkoutSessions(db *gorm.DB, userId string) ([]ArchivedWorkoutSession, error) {
+	var archived []ArchivedWorkoutSession
+	err := db.Where("user_id = ?", userId).Order("start DESC").Find(&archived).Error
+	return archived, err
+}
+
+func GetArchivedWorkoutSession(db *gorm.DB, archivedWorkoutSessionId string) (*ArchivedWorkoutSession, error) {
+	var archived ArchivedWorkoutSession
+	err := db.Where("id = ?", archivedWorkoutSessionId).First(&archived).Error
+	return &archived, err
+}
+
+// RestoreArchivedWorkoutSession puts a workout session back with its original
+// ids and drops the archive index row
+func RestoreArchivedWorkoutSession(db *gorm.DB, archived *ArchivedWorkoutSession, workoutSession *WorkoutSession) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := addWorkoutSession(tx, workoutSession); err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(archived).Error
+	})
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+const (
+	AuditEntityWorkoutRoutine  = "WORKOUT_ROUTINE"
+	AuditEntityExerciseRoutine = "EXERCISE_ROUTINE"
+	AuditEntityWorkoutSession  = "WORKOUT_SESSION"
+	AuditEntityExercise        = "EXERCISE"
+	AuditEntitySetEntry        = "SET_ENTRY"
+	AuditEntityBodyWeight      = "BODY_WEIGHT"
+	AuditEntityGoal            = "GOAL"
+	AuditEntityProgram         = "PROGRAM"
+	AuditEntityUser            = "USER"
+)
+
+// the operation in the audit log for a session the server finished, there's
+// no mutation to name it after
+const AuditOperationAutoFinish = "autoFinishWorkoutSession"
+
+// the model whose table each audited entity's rows are in
+var auditedModels = map[string]interface{}{
+	AuditEntityWorkoutRoutine:  &WorkoutRoutine{},
+	AuditEntityExerciseRoutine: &ExerciseRoutine{},
+	AuditEntityWorkoutSession:  &WorkoutSession{},
+	AuditEntityExercise:        &Exercise{},
+	AuditEntitySetEntry:        &SetEntry{},
+	AuditEntityBodyWeight:      &BodyWeightEntry{},
+	AuditEntityGoal:            &Goal{},
+	AuditEntityProgram:         &Program{},
+}
+
+// AuditSnapshot is the entity's row as it is now, soft deleted or not. It's
+// nil when there's no row or the entity type isn't audited
+func AuditSnapshot(db *gorm.DB, entityType string, entityId uint) (JSONObject, error) {
+	model, ok := auditedModels[entityType]
+	if !ok {
+		return nil, nil
+	}
+
+	row := map[string]interface{}{}
+	err := db.Unscoped().Model(model).Where("id = ?", entityId).Take(&row).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// text and jsonb can come back as bytes, which would be base64 in the
+	// snapshot
+	for column, value := range row {
+		if b, ok := value.([]byte); ok {
+			row[column] = string(b)
+		}
+	}
+	return row, nil
+}
+
+func AddAuditLog(db *gorm.DB, entry *AuditLog) error {
+	return db.Create(entry).Error
+}
+
+// GetAuditTrail is every audited change to the entity, newest first. The
+// entity type can be left empty since ids are only unique per type
+func GetAuditTrail(db *gorm.DB, entityId string, entityType string, limit int) ([]AuditLog, error) {
+	var entries []AuditLog
+	db = db.Where("entity_id = ?", entityId)
+	if entityType != "" {
+		db = db.Where("entity_type = ?", entityType)
+	}
+	err := db.Order("id desc").Limit(limit).Find(&entries).Error
+	return entries, err
+}
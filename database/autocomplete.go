@@ -0,0 +1,89 @@
+package database
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Suggestion is a name that matched an autocomplete prefix, ID is 0 for names
+// that don't belong to a single row
+type Suggestion struct {
+	ID   uint
+	Text string
+	Rank int
+}
+
+// match ranks, lower is better
+const (
+	MatchRankStart = iota // the name starts with the prefix
+	MatchRankWord         // a later word starts with it
+	MatchRankAnywhere
+)
+
+// MatchRank is how well text matches prefix, -1 when it doesn't. Same rules
+// as matchRankSQL for names that aren't in the database
+func MatchRank(text string, prefix string) int {
+	text = strings.ToLower(text)
+	prefix = strings.ToLower(prefix)
+	switch {
+	case strings.HasPrefix(text, prefix):
+		return MatchRankStart
+	case strings.Contains(text, " "+prefix):
+		return MatchRankWord
+	case strings.Contains(text, prefix):
+		return MatchRankAnywhere
+	}
+	return -1
+}
+
+func matchRankSQL(column string) string {
+	return "CASE WHEN " + column + " ILIKE @start THEN 0 WHEN " + column + " ILIKE @word THEN 1 ELSE 2 END"
+}
+
+func matchParams(prefix string, limit int) map[string]interface{} {
+	escaped := escapeLike(prefix)
+	return map[string]interface{}{
+		"start":    escaped + "%",
+		"word":     "% " + escaped + "%",
+		"anywhere": "%" + escaped + "%",
+		"limit":    limit,
+	}
+}
+
+// AutocompleteExerciseRoutines matches the names of exercises in the user's
+// routines, once per name with the newest exercise routine's id
+func AutocompleteExerciseRoutines(db *gorm.DB, userId string, prefix string, limit int) ([]Suggestion, error) {
+	params := matchParams(prefix, limit)
+	params["user"] = userId
+
+	var suggestions []Suggestion
+	err := db.Raw(`SELECT id, text, `+matchRankSQL("text")+` AS rank FROM (
+			SELECT DISTINCT ON (LOWER(exercise_routines.name)) exercise_routines.id, exercise_routines.name AS text
+			FROM exercise_routines
+			JOIN workout_routines ON workout_routines.id = exercise_routines.workout_routine_id
+			WHERE workout_routines.user_id = @user AND exercise_routines.name ILIKE @anywhere
+			AND exercise_routines.deleted_at IS NULL AND workout_routines.deleted_at IS NULL
+			ORDER BY LOWER(exercise_routines.name), exercise_routines.id DESC
+		) names ORDER BY rank, LENGTH(text), text LIMIT @limit`, params).
+		Scan(&suggestions).Error
+	return suggestions, err
+}
+
+func AutocompleteCatalogExercises(db *gorm.DB, prefix string, limit int) ([]Suggestion, error) {
+	var suggestions []Suggestion
+	err := db.Raw(`SELECT id, name AS text, `+matchRankSQL("name")+` AS rank FROM catalog_exercises
+		WHERE name ILIKE @anywhere AND deleted_at IS NULL
+		ORDER BY rank, LENGTH(name), name LIMIT @limit`, matchParams(prefix, limit)).
+		Scan(&suggestions).Error
+	return suggestions, err
+}
+
+func AutocompleteEquipment(db *gorm.DB, prefix string, limit int) ([]Suggestion, error) {
+	var suggestions []Suggestion
+	err := db.Raw(`SELECT equipment AS text, `+matchRankSQL("equipment")+` AS rank FROM catalog_exercises
+		WHERE equipment ILIKE @anywhere AND deleted_at IS NULL
+		GROUP BY equipment ORDER BY rank, LENGTH(equipment), equipment LIMIT @limit`, matchParams(prefix, limit)).
+		Scan(&suggestions).Error
+	return suggestions, err
+}
@@ -0,0 +1,134 @@
+package database
+
+import (
+	"gorm.io/gorm"
+)
+
+type catalogEntry struct {
+	name         string
+	equipment    string
+	muscleGroups []string // primary muscle group first
+	instructions string
+}
+
+// exercises every user can pick from, new entries are added on the next start
+var exerciseCatalog = []catalogEntry{
+	{"Bench Press", "Barbell", []string{MuscleGroupChest, MuscleGroupTriceps, MuscleGroupShoulders}, "Lie on the bench, lower the bar to your mid chest and press it back up over your shoulders."},
+	{"Incline Bench Press", "Barbell", []string{MuscleGroupChest, MuscleGroupShoulders, MuscleGroupTriceps}, "Set the bench to 30 to 45 degrees, lower the bar to your upper chest and press it back up."},
+	{"Dumbbell Bench Press", "Dumbbell", []string{MuscleGroupChest, MuscleGroupTriceps, MuscleGroupShoulders}, "Lie on the bench with a dumbbell in each hand, lower them to your chest and press them back up."},
+	{"Dumbbell Fly", "Dumbbell", []string{MuscleGroupChest}, "Lie on the bench with slightly bent elbows and open your arms until you feel a stretch, then bring the dumbbells back together."},
+	{"Push Up", "Bodyweight", []string{MuscleGroupChest, MuscleGroupTriceps, MuscleGroupShoulders}, "Keep your body in a straight line, lower your chest to the floor and push back up."},
+	{"Dips", "Bodyweight", []string{MuscleGroupTriceps, MuscleGroupChest, MuscleGroupShoulders}, "Lower yourself between the bars until your shoulders are below your elbows and press back up."},
+	{"Overhead Press", "Barbell", []string{MuscleGroupShoulders, MuscleGroupTriceps}, "Press the bar from your shoulders to overhead, moving your head back so the bar travels straight."},
+	{"Dumbbell Shoulder Press", "Dumbbell", []string{MuscleGroupShoulders, MuscleGroupTriceps}, "Press the dumbbells from your shoulders to overhead and lower them under control."},
+	{"Lateral Raise", "Dumbbell", []string{MuscleGroupShoulders}, "Raise the dumbbells out to your sides until your arms are level with your shoulders."},
+	{"Face Pull", "Cable", []string{MuscleGroupShoulders, MuscleGroupBack}, "Pull the rope towards your face, separating your hands as it gets close."},
+	{"Deadlift", "Barbell", []string{MuscleGroupBack, MuscleGroupHamstrings, MuscleGroupGlutes, MuscleGroupForearms}, "With the bar over your mid foot, brace and stand up by pushing the floor away, keeping the bar close."},
+	{"Barbell Row", "Barbell", []string{MuscleGroupBack, MuscleGroupBiceps}, "Hinge forward with a flat back and row the bar to your lower chest."},
+	{"Dumbbell Row", "Dumbbell", []string{MuscleGroupBack, MuscleGroupBiceps}, "Support yourself on a bench and row the dumbbell to your hip."},
+	{"Pull Up", "Bodyweight", []string{MuscleGroupBack, MuscleGroupBiceps}, "Hang with an overhand grip and pull until your chin is over the bar."},
+	{"Chin Up", "Bodyweight", []string{MuscleGroupBack, MuscleGroupBiceps}, "Hang with an underhand grip and pull until your chin is over the bar."},
+	{"Lat Pulldown", "Cable", []string{MuscleGroupBack, MuscleGroupBiceps}, "Pull the bar down to your upper chest while keeping your torso upright."},
+	{"Seated Cable Row", "Cable", []string{MuscleGroupBack, MuscleGroupBiceps}, "Sit tall and row the handle to your stomach, squeezing your shoulder blades together."},
+	{"Barbell Curl", "Barbell", []string{MuscleGroupBiceps, MuscleGroupForearms}, "Curl the bar up without swinging and lower it under control."},
+	{"Dumbbell Curl", "Dumbbell", []string{MuscleGroupBiceps, MuscleGroupForearms}, "Curl the dumbbells up, turning your palms up as they rise."},
+	{"Hammer Curl", "Dumbbell", []string{MuscleGroupBiceps, MuscleGroupForearms}, "Curl the dumbbells with your palms facing each other."},
+	{"Triceps Pushdown", "Cable", []string{MuscleGroupTriceps}, "Keep your elbows at your sides and push the bar down until your arms are straight."},
+	{"Skull Crusher", "Barbell", []string{MuscleGroupTriceps}, "Lie on a bench and lower the bar towards your forehead by bending only at the elbows."},
+	{"Overhead Triceps Extension", "Dumbbell", []string{MuscleGroupTriceps}, "Hold a dumbbell overhead, lower it behind your head and extend your arms back up."},
+	{"Wrist Curl", "Dumbbell", []string{MuscleGroupForearms}, "Rest your forearms on your thighs and curl the dumbbells with your wrists."},
+	{"Squat", "Barbell", []string{MuscleGroupQuads, MuscleGroupGlutes, MuscleGroupHamstrings}, "With the bar on your upper back, sit down until your hips are below your knees and stand back up."},
+	{"Front Squat", "Barbell", []string{MuscleGroupQuads, MuscleGroupGlutes}, "Hold the bar on the front of your shoulders and squat while keeping your torso upright."},
+	{"Leg Press", "Machine", []string{MuscleGroupQuads, MuscleGroupGlutes}, "Lower the platform until your knees are at 90 degrees and press it back up."},
+	{"Bulgarian Split Squat", "Dumbbell", []string{MuscleGroupQuads, MuscleGroupGlutes}, "With your back foot on a bench, lower your back knee towards the floor and drive back up."},
+	{"Leg Extension", "Machine", []string{MuscleGroupQuads}, "Extend your legs until they are straight and lower them under control."},
+	{"Romanian Deadlift", "Barbell", []string{MuscleGroupHamstrings, MuscleGroupGlutes, MuscleGroupBack}, "Hinge at the hips with soft knees, lowering the bar along your legs until you feel a stretch in your hamstrings."},
+	{"Leg Curl", "Machine", []string{MuscleGroupHamstrings}, "Curl your heels towards your glutes and lower them under control."},
+	{"Hip Thrust", "Barbell", []string{MuscleGroupGlutes, MuscleGroupHamstrings}, "With your upper back on a bench, drive your hips up until your body is level from shoulders to knees."},
+	{"Standing Calf Raise", "Machine", []string{MuscleGroupCalves}, "Rise onto your toes as high as you can and lower your heels below the platform."},
+	{"Seated Calf Raise", "Machine", []string{MuscleGroupCalves}, "With the pad on your knees, raise your heels as high as you can and lower them slowly."},
+	{"Plank", "Bodyweight", []string{MuscleGroupAbs}, "Hold your body in a straight line on your forearms and toes."},
+	{"Hanging Leg Raise", "Bodyweight", []string{MuscleGroupAbs}, "Hang from a bar and raise your legs until they are level with your hips."},
+	{"Cable Crunch", "Cable", []string{MuscleGroupAbs}, "Kneel facing the cable and crunch down, bringing your elbows towards your knees."},
+}
+
+// SeedExerciseCatalog adds catalog exercises that aren't in the database yet.
+// Existing entries are left alone
+func SeedExerciseCatalog(db *gorm.DB) error {
+	var existing []string
+	if err := db.Model(&CatalogExercise{}).Pluck("name", &existing).Error; err != nil {
+		return err
+	}
+	seeded := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		seeded[name] = true
+	}
+
+	missing := make([]CatalogExercise, 0)
+	for _, entry := range exerciseCatalog {
+		if seeded[entry.name] {
+			continue
+		}
+		catalogExercise := CatalogExercise{
+			Name:         entry.name,
+			Equipment:    entry.equipment,
+			Instructions: entry.instructions,
+		}
+		for i, muscleGroup := range entry.muscleGroups {
+			catalogExercise.MuscleGroups = append(catalogExercise.MuscleGroups, CatalogExerciseMuscleGroup{
+				MuscleGroup: muscleGroup,
+				IsPrimary:   i == 0,
+			})
+		}
+		missing = append(missing, catalogExercise)
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return db.Create(&missing).Error
+}
+
+// SearchExerciseCatalog matches query anywhere in the name, an empty query or
+// muscle group doesn't filter on it
+func SearchExerciseCatalog(db *gorm.DB, query string, muscleGroup string, limit int) ([]CatalogExercise, error) {
+	var catalogExercises []CatalogExercise
+	if len(query) > 0 {
+		db = db.Where("name ILIKE ?", "%"+escapeLike(query)+"%")
+	}
+	if len(muscleGroup) > 0 {
+		db = db.Where("EXISTS (SELECT 1 FROM catalog_exercise_muscle_groups WHERE catalog_exercise_muscle_groups.catalog_exercise_id = catalog_exercises.id AND catalog_exercise_muscle_groups.muscle_group = ? AND catalog_exercise_muscle_groups.deleted_at IS NULL)", muscleGroup)
+	}
+	result := db.Preload("MuscleGroups", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Order("name").Limit(limit).Find(&catalogExercises)
+	return catalogExercises, result.Error
+}
+
+func GetCatalogExercise(db *gorm.DB, catalogExerciseId string) (*CatalogExercise, error) {
+	var catalogExercise CatalogExercise
+	result := db.Where("id = ?", catalogExerciseId).First(&catalogExercise)
+	return &catalogExercise, result.Error
+}
+
+func GetCatalogExercisesById(db *gorm.DB, ids []string) ([]CatalogExercise, error) {
+	var catalogExercises []CatalogExercise
+	result := db.Preload("MuscleGroups", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id")
+	}).Where("id IN ?", ids).Find(&catalogExercises)
+	return catalogExercises, result.Error
+}
+
+// exercise routine id to the catalog exercise it references
+type ExerciseRoutineCatalogExercise struct {
+	ExerciseRoutineID uint
+	CatalogExerciseID uint
+}
+
+func GetCatalogExerciseIdsByExerciseRoutineId(db *gorm.DB, exerciseRoutineIds []string) ([]ExerciseRoutineCatalogExercise, error) {
+	var references []ExerciseRoutineCatalogExercise
+	result := db.Model(&ExerciseRoutine{}).
+		Select("id AS exercise_routine_id, catalog_exercise_id").
+		Where("id IN ? AND catalog_exercise_id IS NOT NULL", exerciseRoutineIds).
+		Scan(&references)
+	return references, result.Error
+}
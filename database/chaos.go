@@ -0,0 +1,40 @@
+package database
+
+import (
+	"github.com/neilZon/workout-logger-api/chaos"
+	"gorm.io/gorm"
+)
+
+// ChaosPlugin runs every statement past a chaos injector before it's sent,
+// statements picked to fail never reach postgres
+type ChaosPlugin struct {
+	Injector *chaos.Injector
+}
+
+func (p *ChaosPlugin) Name() string {
+	return "chaos"
+}
+
+func (p *ChaosPlugin) Initialize(db *gorm.DB) error {
+	inject := func(tx *gorm.DB) {
+		if err := p.Injector.Inject(tx.Statement.Context); err != nil {
+			tx.AddError(err)
+		}
+	}
+
+	cb := db.Callback()
+	callbacks := []error{
+		cb.Create().Before("gorm:create").Register("chaos:create", inject),
+		cb.Query().Before("gorm:query").Register("chaos:query", inject),
+		cb.Update().Before("gorm:update").Register("chaos:update", inject),
+		cb.Delete().Before("gorm:delete").Register("chaos:delete", inject),
+		cb.Row().Before("gorm:row").Register("chaos:row", inject),
+		cb.Raw().Before("gorm:raw").Register("chaos:raw", inject),
+	}
+	for _, err := range callbacks {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrConflict is when an update was made against a row that changed since
+// the client read it
+var ErrConflict = errors.New("changed since it was read")
+
+// unchangedSince limits an update to the row still being at the updated_at
+// the client saw, nil doesn't check. postgres only keeps microseconds, so
+// times handed back before being read from the database still match
+func unchangedSince(db *gorm.DB, table string, expectedUpdatedAt *time.Time) *gorm.DB {
+	if expectedUpdatedAt == nil {
+		return db
+	}
+	return db.Where(table+".updated_at = ?", expectedUpdatedAt.UTC().Truncate(time.Microsecond))
+}
+
+// checkUnchanged is ErrConflict when an update limited by unchangedSince
+// didn't find the row. Callers make sure the row exists beforehand
+func checkUnchanged(result *gorm.DB, expectedUpdatedAt *time.Time) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if expectedUpdatedAt != nil && result.RowsAffected == 0 {
+		return ErrConflict
+	}
+	return nil
+}
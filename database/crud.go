@@ -1,7 +1,9 @@
 package database
 
 import (
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -27,9 +29,9 @@ func GetUserByVerificationCode(db *gorm.DB, code string) (*User, error) {
 	return &u, result.Error
 }
 
-func GetUserByPasswordCode(db *gorm.DB, code string) (*User, error) {
+func GetUserByEmailChangeCode(db *gorm.DB, code string) (*User, error) {
 	var u User
-	result := db.First(&u, "password_reset_code = ?", code)
+	result := db.First(&u, "email_change_code = ?", code)
 	return &u, result.Error
 }
 
@@ -38,25 +40,36 @@ func VerifyUser(db *gorm.DB, id string, code string) error {
 		map[string]interface{}{"Verified": true, "VerificationCode": nil, "VerificationSentAt": nil}).Error
 }
 
-func ChangePassword(db *gorm.DB, code string, password string) error {
-	return db.Model(&User{}).Where("password_reset_code = ?", code).Updates(
-		map[string]interface{}{"PasswordResetCode": nil, "password": password, "PasswordResetSentAt": nil}).Error
+// ChangeEmail moves the user over to the address they confirmed, the unique
+// index on email still catches it if someone signed up with it in between
+func ChangeEmail(db *gorm.DB, code string, email string) error {
+	return db.Model(&User{}).Where("email_change_code = ?", code).Updates(
+		map[string]interface{}{"Email": email, "PendingEmail": nil, "EmailChangeCode": nil, "EmailChangeSentAt": nil}).Error
 }
 
 func UpdateUser(db *gorm.DB, email string, user *User) error {
 	return db.Model(&User{}).Where("email = ?", email).Updates(*user).Error
 }
 
-func UpdateUserByPasswordCode(db *gorm.DB, code string, user *User) error {
-	return db.Model(&User{}).Where("password_reset_code = ?", code).Updates(*user).Error
+func UpdateUserById(db *gorm.DB, id string, user *User) error {
+	return db.Model(&User{}).Where("id = ?", id).Updates(*user).Error
+}
+
+// UpdateUserFormatPreferences sets weight_unit even when it's nil, which
+// Updates with a struct would skip
+func UpdateUserFormatPreferences(db *gorm.DB, id string, locale string, weightUnit *string) error {
+	return db.Model(&User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"locale":      locale,
+		"weight_unit": weightUnit,
+	}).Error
 }
 
 func UpdateUserByVerificationCode(db *gorm.DB, code string, user *User) error {
 	return db.Model(&User{}).Where("verification_code = ?", code).Updates(*user).Error
 }
 
-func DeleteUser(db *gorm.DB, id string) error {
-	return db.Unscoped().Where("id = ?", id).Delete(&User{}).Error
+func SetRecordRequestsUntil(db *gorm.DB, id string, until time.Time) error {
+	return db.Model(&User{}).Where("id = ?", id).Update("record_requests_until", until).Error
 }
 
 func CreateWorkoutRoutine(db *gorm.DB, routine *WorkoutRoutine) *gorm.DB {
@@ -71,21 +84,70 @@ func GetWorkoutRoutine(db *gorm.DB, workoutRoutineId string) (*WorkoutRoutine, e
 }
 
 // Workout Routine
-func GetWorkoutRoutines(db *gorm.DB, userId string, cursor string, limit int) ([]WorkoutRoutine, error) {
+const (
+	WorkoutRoutineOrderName            = "name"
+	WorkoutRoutineOrderLastPerformedAt = "last_performed_at"
+	WorkoutRoutineOrderCreatedAt       = "created_at"
+)
+
+// sort key for each order. they only reference workout_routines so the same
+// expression works for the cursor row in the keyset subquery
+var workoutRoutineSortKeys = map[string]string{
+	WorkoutRoutineOrderName:            "LOWER(workout_routines.name)",
+	WorkoutRoutineOrderCreatedAt:       "workout_routines.created_at",
+	WorkoutRoutineOrderLastPerformedAt: "COALESCE((SELECT MAX(workout_sessions.start) FROM workout_sessions WHERE workout_sessions.workout_routine_id = workout_routines.id AND workout_sessions.deleted_at IS NULL), '-infinity')",
+}
+
+// WorkoutRoutinePage picks which workout routines GetWorkoutRoutines returns.
+// Cursor is the id of the last routine on the previous page
+type WorkoutRoutinePage struct {
+	Cursor     string
+	Limit      int
+	OrderBy    string
+	Descending bool
+	NamePrefix string
+}
+
+func GetWorkoutRoutines(db *gorm.DB, userId string, page WorkoutRoutinePage) ([]WorkoutRoutine, error) {
 	var workoutRoutines []WorkoutRoutine
-	if len(cursor) == 0 {
-		db = db.Where("user_id = ?", userId)
-	} else {
-		db = db.Where("user_id = ? AND id > ?", userId, cursor)
+	sortKey, ok := workoutRoutineSortKeys[page.OrderBy]
+	if !ok {
+		sortKey = workoutRoutineSortKeys[WorkoutRoutineOrderCreatedAt]
 	}
-	result := db.Order("id").Limit(limit).Find(&workoutRoutines)
+	op, direction := ">", "ASC"
+	if page.Descending {
+		op, direction = "<", "DESC"
+	}
+
+	db = db.Where("workout_routines.user_id = ?", userId)
+	if len(page.NamePrefix) > 0 {
+		db = db.Where("workout_routines.name ILIKE ?", escapeLike(page.NamePrefix)+"%")
+	}
+	if len(page.Cursor) > 0 {
+		// ids break ties so every routine has its own place in the order
+		db = db.Where(fmt.Sprintf(
+			"(%s, workout_routines.id) %s (SELECT %s, workout_routines.id FROM workout_routines WHERE workout_routines.id = ?)",
+			sortKey, op, sortKey,
+		), page.Cursor)
+	}
+	result := db.Order(fmt.Sprintf("%s %s, workout_routines.id %s", sortKey, direction, direction)).Limit(page.Limit).Find(&workoutRoutines)
 	return workoutRoutines, result.Error
 }
 
-func UpdateWorkoutRoutine(db *gorm.DB, workoutRoutineId string, workoutRoutineName string, exerciseRoutines []*ExerciseRoutine) error {
+// escapeLike stops user input from being read as LIKE wildcards
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// UpdateWorkoutRoutine renames the routine and replaces its exercise
+// routines, it's ErrConflict when expectedUpdatedAt is set and the routine
+// changed since. gorm sets the new updated_at on updated
+func UpdateWorkoutRoutine(db *gorm.DB, workoutRoutineId string, workoutRoutineName string, exerciseRoutines []*ExerciseRoutine, expectedUpdatedAt *time.Time, updated *WorkoutRoutine) error {
 	tx := db.Begin()
 
-	if err := tx.Model(&WorkoutRoutine{}).Where("id = ?", workoutRoutineId).Update("name", workoutRoutineName).Error; err != nil {
+	result := unchangedSince(tx.Model(updated).Where("id = ?", workoutRoutineId), "workout_routines", expectedUpdatedAt).
+		Update("name", workoutRoutineName)
+	if err := checkUnchanged(result, expectedUpdatedAt); err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -97,7 +159,7 @@ func UpdateWorkoutRoutine(db *gorm.DB, workoutRoutineId string, workoutRoutineNa
 	for _, er := range exerciseRoutines {
 		result := tx.Clauses(clause.OnConflict{
 			Columns:   []clause.Column{{Name: "id"}},
-			DoUpdates: clause.AssignmentColumns([]string{"reps", "sets", "name", "active"}),
+			DoUpdates: clause.AssignmentColumns([]string{"reps", "sets", "name", "active", "position", "catalog_exercise_id"}),
 		}).Clauses(clause.Returning{}).Create(er)
 
 		exerciseRoutineIds = append(exerciseRoutineIds, er.ID)
@@ -116,25 +178,33 @@ func UpdateWorkoutRoutine(db *gorm.DB, workoutRoutineId string, workoutRoutineNa
 	return tx.Commit().Error
 }
 
-func DeleteWorkoutRoutine(db *gorm.DB, workoutRoutineId string) error {
-	tx := db.Begin()
-	if err := tx.Where("id = ?", workoutRoutineId).Delete(&WorkoutRoutine{}).Error; err != nil {
+// DeleteWorkoutRoutine soft deletes the routine with its exercise routines
+// and every session logged against it. A dry run rolls back after counting
+func DeleteWorkoutRoutine(db *gorm.DB, workoutRoutineId string, dryRun bool) (*DeletionCounts, error) {
+	counts := DeletionCounts{}
+	tx := cascadeDeleteSession(db).Begin()
+	result := tx.Where("id = ?", workoutRoutineId).Delete(&WorkoutRoutine{})
+	if result.Error != nil {
 		tx.Rollback()
-		return err
+		return nil, result.Error
 	}
+	counts.WorkoutRoutines = result.RowsAffected
 
 	// Cascade exercise routines
-	if err := tx.Where("workout_routine_id = ?", workoutRoutineId).Delete(&ExerciseRoutine{}).Error; err != nil {
+	result = tx.Where("workout_routine_id = ?", workoutRoutineId).Delete(&ExerciseRoutine{})
+	if result.Error != nil {
 		tx.Rollback()
-		return err
+		return nil, result.Error
 	}
+	counts.ExerciseRoutines = result.RowsAffected
 
 	// Cascade workout sessions
 	var workoutSessions []*WorkoutSession
 	if err := tx.Clauses(clause.Returning{}).Where("workout_routine_id = ?", workoutRoutineId).Delete(&workoutSessions).Error; err != nil {
 		tx.Rollback()
-		return err
+		return nil, err
 	}
+	counts.WorkoutSessions = int64(len(workoutSessions))
 
 	var workoutSessionIds []string
 	for _, ws := range workoutSessions {
@@ -145,31 +215,59 @@ func DeleteWorkoutRoutine(db *gorm.DB, workoutRoutineId string) error {
 	var exercises []*Exercise
 	if err := tx.Clauses(clause.Returning{}).Where("workout_session_id IN ?", workoutSessionIds).Delete(&exercises).Error; err != nil {
 		tx.Rollback()
-		return err
+		return nil, err
 	}
+	counts.Exercises = int64(len(exercises))
 	var exerciseIds []string
 	for _, e := range exercises {
 		exerciseIds = append(exerciseIds, fmt.Sprintf("%d", e.ID))
 	}
 
 	// Cascade sets
-	if err := tx.Where("exercise_id IN ?", exerciseIds).Delete(&SetEntry{}).Error; err != nil {
+	result = tx.Where("exercise_id IN ?", exerciseIds).Delete(&SetEntry{})
+	if result.Error != nil {
 		tx.Rollback()
-		return err
+		return nil, result.Error
 	}
+	counts.Sets = result.RowsAffected
 
-	return tx.Commit().Error
+	if dryRun {
+		return &counts, tx.Rollback().Error
+	}
+	return &counts, tx.Commit().Error
 }
 
 // Exercise Routine
 func AddExerciseRoutine(db *gorm.DB, exerciseRoutine *ExerciseRoutine) error {
-	result := db.Create(exerciseRoutine)
-	return result.Error
+	// new exercise routines go at the end of the routine
+	var position uint
+	err := db.Model(&ExerciseRoutine{}).
+		Where("workout_routine_id = ?", exerciseRoutine.WorkoutRoutineID).
+		Select("COALESCE(MAX(position) + 1, 0)").
+		Scan(&position).Error
+	if err != nil {
+		return err
+	}
+	exerciseRoutine.Position = position
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := checkNotDeleted(tx, &WorkoutRoutine{}, "id = ?", exerciseRoutine.WorkoutRoutineID); err != nil {
+			return err
+		}
+		if err := tx.Create(exerciseRoutine).Error; err != nil {
+			return err
+		}
+		return touchWorkoutRoutine(tx, exerciseRoutine.WorkoutRoutineID)
+	})
 }
 
 func UpdateExerciseRoutine(db *gorm.DB, exerciseRoutineId string, exerciseRoutine *ExerciseRoutine) error {
-	result := db.Model(exerciseRoutine).Clauses(clause.Returning{}).Where("id = ?", exerciseRoutineId).Updates(exerciseRoutine)
-	return result.Error
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(exerciseRoutine).Clauses(clause.Returning{}).Where("id = ?", exerciseRoutineId).Updates(exerciseRoutine).Error; err != nil {
+			return err
+		}
+		return touchWorkoutRoutine(tx, exerciseRoutine.WorkoutRoutineID)
+	})
 }
 
 func GetExerciseRoutines(db *gorm.DB, workoutRoutineId string) (*[]ExerciseRoutine, error) {
@@ -177,6 +275,7 @@ func GetExerciseRoutines(db *gorm.DB, workoutRoutineId string) (*[]ExerciseRouti
 
 	err := db.
 		Where("workout_routine_id = ?", workoutRoutineId).
+		Order("position, id").
 		Find(&exerciseRoutines).Error
 
 	return &exerciseRoutines, err
@@ -190,10 +289,37 @@ func GetExerciseRoutineIdsByExercises(db *gorm.DB, exerciseIds []string) (*[]str
 
 func GetExerciseRoutinesByWorkoutRoutineId(db *gorm.DB, workoutRoutineIds []string) (*[]ExerciseRoutine, error) {
 	exerciseRoutine := []ExerciseRoutine{}
-	err := db.Where("workout_routine_id IN ?", workoutRoutineIds).Find(&exerciseRoutine).Error
+	err := db.Where("workout_routine_id IN ?", workoutRoutineIds).Order("position, id").Find(&exerciseRoutine).Error
 	return &exerciseRoutine, err
 }
 
+// counts how many of the given exercise routines belong to the workout routine
+// so callers can check them all with one query
+func CountExerciseRoutinesInWorkoutRoutine(db *gorm.DB, workoutRoutineId string, exerciseRoutineIds []string) (int64, error) {
+	var count int64
+	err := db.Model(&ExerciseRoutine{}).
+		Where("workout_routine_id = ? AND id IN ?", workoutRoutineId, exerciseRoutineIds).
+		Count(&count).Error
+	return count, err
+}
+
+// positions follow the order of the ids, callers make sure they are every
+// exercise routine in the workout routine
+func ReorderExerciseRoutines(db *gorm.DB, workoutRoutineId string, orderedIds []string) error {
+	tx := db.Begin()
+	for i, id := range orderedIds {
+		if err := tx.Model(&ExerciseRoutine{}).Where("id = ? AND workout_routine_id = ?", id, workoutRoutineId).Update("position", i).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if err := touchWorkoutRoutine(tx, workoutRoutineId); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
 func GetExerciseRoutine(db *gorm.DB, exerciseRoutineId string, er *ExerciseRoutine) error {
 	result := db.Model(ExerciseRoutine{}).Where("id = ?", exerciseRoutineId).First(er)
 	return result.Error
@@ -206,25 +332,115 @@ func GetExercisesById(db *gorm.DB, ids []string) (*[]Exercise, error) {
 }
 
 func DeleteExerciseRoutine(db *gorm.DB, exerciseRoutineId string) error {
-	tx := db.Begin()
-	if err := tx.Where("id = ?", exerciseRoutineId).Delete(&ExerciseRoutine{}).Error; err != nil {
+	_, err := deleteExerciseRoutines(db, "= ?", exerciseRoutineId)
+	return err
+}
+
+// DeleteExerciseRoutines is DeleteExerciseRoutine for many in one
+// transaction, it's the workout routines they were in
+func DeleteExerciseRoutines(db *gorm.DB, exerciseRoutineIds []string) ([]uint, error) {
+	return deleteExerciseRoutines(db, "IN ?", exerciseRoutineIds)
+}
+
+// cond is "= ?" for one id or "IN ?" for a slice of them
+func deleteExerciseRoutines(db *gorm.DB, cond string, ids interface{}) ([]uint, error) {
+	tx := cascadeDeleteSession(db).Begin()
+	var exerciseRoutines []ExerciseRoutine
+	if err := tx.Clauses(clause.Returning{}).Where("id "+cond, ids).Delete(&exerciseRoutines).Error; err != nil {
 		tx.Rollback()
-		return err
+		return nil, err
 	}
 
 	// Cascade exercises
 	var exercises []*Exercise
-	if err := tx.Clauses(clause.Returning{}).Where("exercise_routine_id = ?", exerciseRoutineId).Delete(&exercises).Error; err != nil {
+	if err := tx.Clauses(clause.Returning{}).Where("exercise_routine_id "+cond, ids).Delete(&exercises).Error; err != nil {
 		tx.Rollback()
-		return err
+		return nil, err
 	}
 	var exerciseIds []string
+	var workoutSessionIds []uint
 	for _, e := range exercises {
 		exerciseIds = append(exerciseIds, fmt.Sprintf("%d", e.ID))
+		workoutSessionIds = append(workoutSessionIds, e.WorkoutSessionID)
 	}
 
 	// Cascade sets
 	if err := tx.Where("exercise_id IN ?", exerciseIds).Delete(&SetEntry{}).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	workoutRoutineIds := make([]uint, 0, 1)
+	touched := make(map[uint]bool)
+	for _, er := range exerciseRoutines {
+		if touched[er.WorkoutRoutineID] {
+			continue
+		}
+		touched[er.WorkoutRoutineID] = true
+		workoutRoutineIds = append(workoutRoutineIds, er.WorkoutRoutineID)
+		if err := touchWorkoutRoutine(tx, er.WorkoutRoutineID); err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+	if err := touchWorkoutSessions(tx, workoutSessionIds); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	return workoutRoutineIds, tx.Commit().Error
+}
+
+// set_entries is partitioned so it has no unique index on id alone for
+// gorm's association upsert to use, sets get inserted after their exercises
+// ErrExerciseRoutineNotInWorkoutRoutine is when a session logs an exercise
+// from some other routine, which could be someone else's
+var ErrExerciseRoutineNotInWorkoutRoutine = errors.New("exercise routine doesn't belong to the workout routine")
+
+// ExerciseError is the exercise that stopped a session from being added,
+// Index is its position in the session
+type ExerciseError struct {
+	Index int
+	Err   error
+}
+
+func (e *ExerciseError) Error() string {
+	return fmt.Sprintf("exercise %d: %v", e.Index, e.Err)
+}
+
+func (e *ExerciseError) Unwrap() error {
+	return e.Err
+}
+
+// AddWorkoutSession checks that the user has no other open session when this
+// one is open and that every exercise's routine is in the session's workout
+// routine, and adds the session, exercises and sets in one transaction. The
+// exercise routines stay locked until it commits so they can't be deleted
+// halfway, and anything failing rolls the whole session back
+func AddWorkoutSession(db *gorm.DB, workout *WorkoutSession) error {
+	tx := db.Begin()
+	if tx.Error != nil {
+		return tx.Error
+	}
+
+	if workout.End == nil {
+		if err := checkNoOpenWorkoutSession(tx, workout.UserID); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := checkNotDeleted(tx, &WorkoutRoutine{}, "id = ?", workout.WorkoutRoutineID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := checkExerciseRoutines(tx, workout); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := addWorkoutSession(tx, workout); err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -232,9 +448,71 @@ func DeleteExerciseRoutine(db *gorm.DB, exerciseRoutineId string) error {
 	return tx.Commit().Error
 }
 
-func AddWorkoutSession(db *gorm.DB, workout *WorkoutSession) error {
-	result := db.Create(workout)
-	return result.Error
+// checkNotDeleted is gorm.ErrRecordNotFound when the row of model where says
+// is deleted, and holds it until the transaction ends so it can't be deleted
+// while something is added under it. Access checks can read owners from a
+// cache that doesn't hear about deletes, this is what keeps deleted routines
+// and sessions shut
+func checkNotDeleted(tx *gorm.DB, model interface{}, where string, args ...interface{}) error {
+	var ids []uint
+	err := tx.Model(model).
+		Clauses(clause.Locking{Strength: "SHARE"}).
+		Where(where, args...).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return err
+	}
+	if len(ids) == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func checkExerciseRoutines(tx *gorm.DB, workout *WorkoutSession) error {
+	if len(workout.Exercises) == 0 {
+		return nil
+	}
+
+	ids := []uint{}
+	seen := map[uint]bool{}
+	for _, e := range workout.Exercises {
+		if !seen[e.ExerciseRoutineID] {
+			seen[e.ExerciseRoutineID] = true
+			ids = append(ids, e.ExerciseRoutineID)
+		}
+	}
+
+	var found []uint
+	err := tx.Model(&ExerciseRoutine{}).
+		Clauses(clause.Locking{Strength: "SHARE"}).
+		Where("workout_routine_id = ? AND id IN ?", workout.WorkoutRoutineID, ids).
+		Pluck("id", &found).Error
+	if err != nil {
+		return err
+	}
+
+	inRoutine := map[uint]bool{}
+	for _, id := range found {
+		inRoutine[id] = true
+	}
+	for i, e := range workout.Exercises {
+		if !inRoutine[e.ExerciseRoutineID] {
+			return &ExerciseError{Index: i, Err: ErrExerciseRoutineNotInWorkoutRoutine}
+		}
+	}
+	return nil
+}
+
+func addWorkoutSession(tx *gorm.DB, workout *WorkoutSession) error {
+	if err := tx.Omit("Exercises.Sets").Create(workout).Error; err != nil {
+		return err
+	}
+	for i := range workout.Exercises {
+		if err := addExerciseSets(tx, &workout.Exercises[i]); err != nil {
+			return &ExerciseError{Index: i, Err: err}
+		}
+	}
+	return nil
 }
 
 func GetWorkoutSession(db *gorm.DB, workoutSessionId string) (*WorkoutSession, error) {
@@ -243,19 +521,69 @@ func GetWorkoutSession(db *gorm.DB, workoutSessionId string) (*WorkoutSession, e
 	return &workoutSession, err
 }
 
+// Owner is who a row belongs to
+type Owner struct {
+	ID     uint
+	UserID uint
+}
+
+// GetWorkoutSessionOwners is who owns each of the sessions, ones that don't
+// exist are left out
+func GetWorkoutSessionOwners(db *gorm.DB, workoutSessionIds []string) ([]Owner, error) {
+	var owners []Owner
+	err := db.Model(&WorkoutSession{}).
+		Select("id, user_id").
+		Where("id IN ?", workoutSessionIds).
+		Scan(&owners).Error
+	return owners, err
+}
+
+// GetExerciseRoutineOwners is who owns the workout routine each of the
+// exercise routines is in, ones that don't exist are left out
+func GetExerciseRoutineOwners(db *gorm.DB, exerciseRoutineIds []string) ([]Owner, error) {
+	var owners []Owner
+	err := db.Model(&ExerciseRoutine{}).
+		Select("exercise_routines.id, workout_routines.user_id").
+		Joins("JOIN workout_routines ON workout_routines.id = exercise_routines.workout_routine_id AND workout_routines.deleted_at IS NULL").
+		Where("exercise_routines.id IN ?", exerciseRoutineIds).
+		Scan(&owners).Error
+	return owners, err
+}
+
 func GetUsersWorkoutSession(db *gorm.DB, workoutSessionId string, userId string) (*WorkoutSession, error) {
 	workoutSession := WorkoutSession{}
 	err := db.Where("id = ? AND user_id = ?", workoutSessionId, userId).First(&workoutSession).Error
 	return &workoutSession, err
 }
 
-func GetWorkoutSessions(db *gorm.DB, userId string, cursor string, limit int) ([]WorkoutSession, error) {
+// Preload is how much of each session GetWorkoutSessions loads with it
+type Preload int
+
+const (
+	PreloadNothing Preload = iota
+	PreloadExercises
+	// exercises and their sets
+	PreloadSets
+)
+
+// GetWorkoutSessions pages through userId's sessions, newest first. What's
+// preloaded takes a query a level however many sessions are on the page
+func GetWorkoutSessions(db *gorm.DB, userId string, cursor string, limit int, tag string, preload Preload) ([]WorkoutSession, error) {
 	var workoutSessions []WorkoutSession
+	if preload >= PreloadExercises {
+		db = db.Preload("Exercises", func(db *gorm.DB) *gorm.DB { return db.Order("id") })
+	}
+	if preload >= PreloadSets {
+		db = db.Preload("Exercises.Sets", func(db *gorm.DB) *gorm.DB { return db.Order("set_order, id") })
+	}
 	if len(cursor) == 0 {
 		db = db.Where("user_id = ?", userId)
 	} else {
 		db = db.Where("user_id = ? AND id > ?", userId, cursor)
 	}
+	if tag != "" {
+		db = db.Where("id IN (SELECT workout_session_tags.workout_session_id FROM workout_session_tags JOIN tags ON tags.id = workout_session_tags.tag_id WHERE tags.name = ?)", tag)
+	}
 	result := db.Order("id desc").Limit(limit).Find(&workoutSessions)
 	return workoutSessions, result.Error
 }
@@ -275,68 +603,203 @@ func GetPreviousWorkoutSessionsByWorkoutRoutineId(db *gorm.DB, workoutRoutineIds
 	return workoutSessions, err
 }
 
-func UpdateWorkoutSession(db *gorm.DB, workoutSessionId string, updatedWorkoutSession *WorkoutSession) error {
-	result := db.Model(updatedWorkoutSession).Clauses(clause.Returning{}).Where("id = ?", workoutSessionId).Updates(updatedWorkoutSession)
-	return result.Error
+// UpdateWorkoutSession is ErrConflict when expectedUpdatedAt is set and the
+// session changed since. Setting the end of a session that's still open
+// finishes it, so it's queued for webhooks and Strava like
+// FinishWorkoutSession does, in the same transaction
+func UpdateWorkoutSession(db *gorm.DB, workoutSessionId string, updatedWorkoutSession *WorkoutSession, expectedUpdatedAt *time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var open []uint
+		if updatedWorkoutSession.End != nil {
+			err := tx.Model(&WorkoutSession{}).
+				Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where(`id = ? AND "end" IS NULL`, workoutSessionId).
+				Pluck("id", &open).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		result := unchangedSince(tx.Model(updatedWorkoutSession).Clauses(clause.Returning{}).Where("id = ?", workoutSessionId), "workout_sessions", expectedUpdatedAt).
+			Updates(updatedWorkoutSession)
+		if err := checkUnchanged(result, expectedUpdatedAt); err != nil {
+			return err
+		}
+		if len(open) == 0 {
+			return nil
+		}
+		return queueFinishedWorkoutSession(tx, updatedWorkoutSession)
+	})
 }
 
-func DeleteWorkoutSession(db *gorm.DB, workoutSessionId string) error {
-	tx := db.Begin()
-	if err := tx.Where("id = ?", workoutSessionId).Delete(&WorkoutSession{}).Error; err != nil {
+// DeleteWorkoutSession soft deletes the session with its exercises and sets.
+// A dry run rolls back after counting
+func DeleteWorkoutSession(db *gorm.DB, workoutSessionId string, dryRun bool) (*DeletionCounts, error) {
+	return deleteWorkoutSessions(db, "= ?", workoutSessionId, dryRun)
+}
+
+// DeleteWorkoutSessions is DeleteWorkoutSession for many in one transaction
+func DeleteWorkoutSessions(db *gorm.DB, workoutSessionIds []string, dryRun bool) (*DeletionCounts, error) {
+	return deleteWorkoutSessions(db, "IN ?", workoutSessionIds, dryRun)
+}
+
+// cond is "= ?" for one id or "IN ?" for a slice of them
+func deleteWorkoutSessions(db *gorm.DB, cond string, ids interface{}, dryRun bool) (*DeletionCounts, error) {
+	counts := DeletionCounts{}
+	tx := cascadeDeleteSession(db).Begin()
+	result := tx.Where("id "+cond, ids).Delete(&WorkoutSession{})
+	if result.Error != nil {
 		tx.Rollback()
-		return err
+		return nil, result.Error
 	}
+	counts.WorkoutSessions = result.RowsAffected
 
 	// Cascade exercises
 	var exercises []*Exercise
-	if err := tx.Clauses(clause.Returning{}).Where("workout_session_id = ?", workoutSessionId).Delete(&exercises).Error; err != nil {
+	if err := tx.Clauses(clause.Returning{}).Where("workout_session_id "+cond, ids).Delete(&exercises).Error; err != nil {
 		tx.Rollback()
-		return err
+		return nil, err
 	}
+	counts.Exercises = int64(len(exercises))
 	var exerciseIds []string
 	for _, e := range exercises {
 		exerciseIds = append(exerciseIds, fmt.Sprintf("%d", e.ID))
 	}
 
 	// Cascade sets
-	if err := tx.Where("exercise_id IN ?", exerciseIds).Delete(&SetEntry{}).Error; err != nil {
+	result = tx.Where("exercise_id IN ?", exerciseIds).Delete(&SetEntry{})
+	if result.Error != nil {
 		tx.Rollback()
-		return err
+		return nil, result.Error
 	}
+	counts.Sets = result.RowsAffected
 
-	return tx.Commit().Error
+	if dryRun {
+		return &counts, tx.Rollback().Error
+	}
+	return &counts, tx.Commit().Error
 }
 
 func AddExercise(db *gorm.DB, exercise *Exercise) error {
-	result := db.Create(exercise)
-	return result.Error
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := checkNotDeleted(tx, &WorkoutSession{}, "id = ?", exercise.WorkoutSessionID); err != nil {
+			return err
+		}
+		if err := tx.Omit("Sets").Create(exercise).Error; err != nil {
+			return err
+		}
+		if err := addExerciseSets(tx, exercise); err != nil {
+			return err
+		}
+		return touchWorkoutSessions(tx, []uint{exercise.WorkoutSessionID})
+	})
 }
 
-func GetExercise(db *gorm.DB, exercise *Exercise, preloadSets bool) error {
-	if preloadSets {
-		db = db.Preload("Sets")
+func addExerciseSets(db *gorm.DB, exercise *Exercise) error {
+	if len(exercise.Sets) == 0 {
+		return nil
 	}
-	result := db.First(exercise)
-	return result.Error
+	for i := range exercise.Sets {
+		exercise.Sets[i].ExerciseID = exercise.ID
+	}
+	return db.Create(&exercise.Sets).Error
 }
 
-func GetExercises(db *gorm.DB, exercises *[]Exercise, workoutSessionId string) error {
-	result := db.Where("workout_session_id = ?", workoutSessionId).Find(&exercises)
+func GetExercise(db *gorm.DB, exercise *Exercise, preloadSets bool) error {
+	result := preloadExerciseSets(db, preloadSets).First(exercise)
 	return result.Error
 }
 
-func GetPrevExercisesByWorkoutRoutineId(db *gorm.DB, workoutRoutineId string, before time.Time) ([]Exercise, error) {
+// GetExerciseForUser is the exercise if the user logged it, anyone else's is
+// gorm.ErrRecordNotFound so there's nothing to leak without an access check
+func GetExerciseForUser(db *gorm.DB, exerciseId string, userId string, preloadSets bool) (*Exercise, error) {
+	var exercise Exercise
+	err := preloadExerciseSets(db, preloadSets).
+		Where("id = ? AND user_id = ?", exerciseId, userId).
+		Take(&exercise).Error
+	return &exercise, err
+}
+
+// GetParticipantExercise is the exercise if it was logged in a session the
+// user owns or joined as a training partner
+func GetParticipantExercise(db *gorm.DB, exerciseId string, userId string, preloadSets bool) (*Exercise, error) {
+	var exercise Exercise
+	err := preloadExerciseSets(db, preloadSets).
+		Joins("JOIN workout_sessions ON workout_sessions.id = exercises.workout_session_id AND workout_sessions.deleted_at IS NULL").
+		Where(`exercises.id = ? AND (workout_sessions.user_id = ? OR EXISTS (
+			SELECT 1 FROM workout_session_participants
+			WHERE workout_session_participants.workout_session_id = workout_sessions.id AND workout_session_participants.user_id = ? AND workout_session_participants.deleted_at IS NULL
+		))`, exerciseId, userId, userId).
+		Take(&exercise).Error
+	return &exercise, err
+}
+
+func preloadExerciseSets(db *gorm.DB, preloadSets bool) *gorm.DB {
+	if !preloadSets {
+		return db
+	}
+	return db.Preload("Sets", func(db *gorm.DB) *gorm.DB {
+		return db.Order("set_order, id")
+	})
+}
+
+// GetExercises is a page of the session's exercises by id, the ones after
+// cursor when it's set
+func GetExercises(db *gorm.DB, workoutSessionId string, cursor string, limit int) ([]Exercise, error) {
 	exercises := []Exercise{}
+	db = db.Where("workout_session_id = ?", workoutSessionId)
+	if cursor != "" {
+		db = db.Where("id > ?", cursor)
+	}
+	err := db.Order("id").Limit(limit).Find(&exercises).Error
+	return exercises, err
+}
+
+// PrevExercisesOf is a routine and a time, the exercises wanted are the last
+// ones logged for each of its exercise routines in sessions started before it
+type PrevExercisesOf struct {
+	WorkoutRoutineID string
+	Before           time.Time
+}
+
+// GetPrevExercises looks up every one of of in one statement however many
+// there are, the exercises come back in of's order
+func GetPrevExercises(db *gorm.DB, of []PrevExercisesOf) ([][]Exercise, error) {
+	prev := make([][]Exercise, len(of))
+	if len(of) == 0 {
+		return prev, nil
+	}
+
+	values := make([]string, 0, len(of))
+	args := make([]interface{}, 0, 3*len(of))
+	for i, o := range of {
+		values = append(values, "(?::int, ?::bigint, ?::timestamptz)")
+		args = append(args, i, o.WorkoutRoutineID, o.Before)
+	}
+
+	rows := []struct {
+		N        int
+		Exercise `gorm:"embedded"`
+	}{}
 	err := db.Raw(`
 		SELECT * from (
-			SELECT exercises.*,
-				ROW_NUMBER() OVER (PARTITION BY exercises.exercise_routine_id ORDER BY workout_sessions.end DESC) AS rows
-			FROM workout_sessions JOIN exercises ON exercises.workout_session_id = workout_sessions.id
-			WHERE workout_sessions.start < ? AND workout_sessions.workout_routine_id = ? AND workout_sessions.deleted_at IS NULL AND exercises.deleted_at IS NULL
+			SELECT prev.n, exercises.*,
+				ROW_NUMBER() OVER (PARTITION BY prev.n, exercises.exercise_routine_id ORDER BY workout_sessions.end DESC) AS rows
+			FROM (VALUES `+strings.Join(values, ", ")+`) AS prev (n, workout_routine_id, before)
+				JOIN workout_sessions ON workout_sessions.workout_routine_id = prev.workout_routine_id AND workout_sessions.start < prev.before
+				JOIN exercises ON exercises.workout_session_id = workout_sessions.id
+			WHERE workout_sessions.deleted_at IS NULL AND exercises.deleted_at IS NULL
 		) TBLE where TBLE.rows = 1`,
-		before, workoutRoutineId,
-	).Scan(&exercises).Error
-	return exercises, err
+		args...,
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range rows {
+		prev[r.N] = append(prev[r.N], r.Exercise)
+	}
+	return prev, nil
 }
 
 func GetExercisesByWorkoutSessionId(db *gorm.DB, workoutSessionIds []string) (*[]Exercise, error) {
@@ -347,16 +810,31 @@ func GetExercisesByWorkoutSessionId(db *gorm.DB, workoutSessionIds []string) (*[
 	return &exercises, err
 }
 
-func UpdateExercise(db *gorm.DB, exerciseId string, updatedExercise *Exercise) error {
-	result := db.Model(updatedExercise).Clauses(clause.Returning{}).Where("id = ?", exerciseId).Updates(updatedExercise)
-	return result.Error
+// UpdateExercise is ErrConflict when expectedUpdatedAt is set and the
+// exercise changed since. Only exercises the user logged are updated
+func UpdateExercise(db *gorm.DB, userId string, exerciseId string, updatedExercise *Exercise, expectedUpdatedAt *time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := unchangedSince(tx.Model(updatedExercise).Clauses(clause.Returning{}).Where("id = ? AND user_id = ?", exerciseId, userId), "exercises", expectedUpdatedAt).
+			Updates(updatedExercise)
+		if err := checkUnchanged(result, expectedUpdatedAt); err != nil {
+			return err
+		}
+		return touchWorkoutSessions(tx, []uint{updatedExercise.WorkoutSessionID})
+	})
 }
 
-func DeleteExercise(db *gorm.DB, exerciseId string) error {
-	tx := db.Begin()
-	if err := tx.Where("id = ?", exerciseId).Delete(&Exercise{}).Error; err != nil {
+// DeleteExercise deletes the exercise with its sets if the user logged it
+func DeleteExercise(db *gorm.DB, userId string, exerciseId string) error {
+	tx := cascadeDeleteSession(db).Begin()
+	var exercise Exercise
+	result := tx.Clauses(clause.Returning{}).Where("id = ? AND user_id = ?", exerciseId, userId).Delete(&exercise)
+	if result.Error != nil {
 		tx.Rollback()
-		return err
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return gorm.ErrRecordNotFound
 	}
 
 	// cascade delete on set entry table
@@ -365,16 +843,47 @@ func DeleteExercise(db *gorm.DB, exerciseId string) error {
 		return err
 	}
 
+	if err := touchWorkoutSessions(tx, []uint{exercise.WorkoutSessionID}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	return tx.Commit().Error
 }
 
 func AddSet(db *gorm.DB, set *SetEntry) error {
-	result := db.Create(set)
-	return result.Error
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(set).Error; err != nil {
+			return err
+		}
+		return touchExercise(tx, set.ExerciseID)
+	})
+}
+
+func NextSetOrder(db *gorm.DB, exerciseId string) (uint, error) {
+	var setOrder uint
+	err := db.Model(&SetEntry{}).
+		Where("exercise_id = ?", exerciseId).
+		Select("COALESCE(MAX(set_order), 0) + 1").
+		Scan(&setOrder).Error
+	return setOrder, err
+}
+
+// GetSetsPage is a page of the exercise's sets in order, the ones after the
+// set cursor when it's set. sets are ordered by set_order before id, so the
+// page starts after wherever cursor is in that order
+func GetSetsPage(db *gorm.DB, exerciseId string, cursor string, limit int) ([]SetEntry, error) {
+	sets := []SetEntry{}
+	db = db.Where("exercise_id = ?", exerciseId)
+	if cursor != "" {
+		db = db.Where("(set_order, id) > (SELECT set_order, id FROM set_entries WHERE id = ? AND exercise_id = ?)", cursor, exerciseId)
+	}
+	err := db.Order("set_order, id").Limit(limit).Find(&sets).Error
+	return sets, err
 }
 
 func GetSets(db *gorm.DB, s *[]SetEntry, exerciseId string) error {
-	result := db.Where("exercise_id = ?", exerciseId).Find(&s)
+	result := db.Where("exercise_id = ?", exerciseId).Order("set_order, id").Find(&s)
 	return result.Error
 }
 
@@ -382,6 +891,7 @@ func GetSetsByExerciseId(db *gorm.DB, exerciseIds []string) (*[]SetEntry, error)
 	setEntries := []SetEntry{}
 	err := db.
 		Where("exercise_id IN ?", exerciseIds).
+		Order("set_order, id").
 		Find(&setEntries).Error
 	return &setEntries, err
 }
@@ -391,12 +901,223 @@ func GetSet(db *gorm.DB, s *SetEntry, setId string) error {
 	return result.Error
 }
 
-func UpdateSet(db *gorm.DB, setID string, updatedSet *SetEntry) error {
-	result := db.Model(updatedSet).Clauses(clause.Returning{}).Where("id = ?", setID).Updates(updatedSet)
+// UserSet is a set with the session its exercise was logged in and who
+// logged it
+type UserSet struct {
+	SetEntry
+	WorkoutSessionID uint
+	LoggedBy         uint
+}
+
+// GetSetForUser is the set if the user logged its exercise, anyone else's is
+// gorm.ErrRecordNotFound
+func GetSetForUser(db *gorm.DB, setId string, userId string) (*UserSet, error) {
+	var set UserSet
+	err := userSets(db).
+		Where("set_entries.id = ? AND exercises.user_id = ?", setId, userId).
+		Take(&set).Error
+	return &set, err
+}
+
+// GetUserSet is GetSetForUser for whoever logged it, for when access was
+// checked some other way
+func GetUserSet(db *gorm.DB, setId string) (*UserSet, error) {
+	var set UserSet
+	err := userSets(db).
+		Where("set_entries.id = ?", setId).
+		Take(&set).Error
+	return &set, err
+}
+
+func userSets(db *gorm.DB) *gorm.DB {
+	return db.Model(&SetEntry{}).
+		Select("set_entries.*, exercises.workout_session_id, exercises.user_id AS logged_by").
+		Joins("JOIN exercises ON exercises.id = set_entries.exercise_id AND exercises.deleted_at IS NULL")
+}
+
+// the user's own sets, for scoping writes the same way GetSetForUser scopes
+// reads
+const userSetCondition = "id = ? AND exercise_id IN (SELECT id FROM exercises WHERE user_id = ? AND deleted_at IS NULL)"
+
+// UpdateSet is ErrConflict when expectedUpdatedAt is set and the set changed
+// since. Only sets the user logged are updated
+func UpdateSet(db *gorm.DB, userId string, setID string, updatedSet *SetEntry, expectedUpdatedAt *time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := unchangedSince(tx.Model(updatedSet).Clauses(clause.Returning{}).Where(userSetCondition, setID, userId), "set_entries", expectedUpdatedAt).
+			Updates(updatedSet)
+		if err := checkUnchanged(result, expectedUpdatedAt); err != nil {
+			return err
+		}
+		return touchExercise(tx, updatedSet.ExerciseID)
+	})
+}
+
+// DeleteSet deletes the set if the user logged it
+func DeleteSet(db *gorm.DB, userId string, setID string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var set SetEntry
+		result := tx.Clauses(clause.Returning{}).Where(userSetCondition, setID, userId).Delete(&set)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return touchExercise(tx, set.ExerciseID)
+	})
+}
+
+// Request Recording
+func AddRequestRecording(db *gorm.DB, recording *RequestRecording) error {
+	result := db.Create(recording)
+	return result.Error
+}
+
+func GetRequestRecordings(db *gorm.DB, userId string, cursor string, limit int) ([]RequestRecording, error) {
+	var recordings []RequestRecording
+	if len(cursor) == 0 {
+		db = db.Where("user_id = ?", userId)
+	} else {
+		db = db.Where("user_id = ? AND id < ?", userId, cursor)
+	}
+	result := db.Order("id desc").Limit(limit).Find(&recordings)
+	return recordings, result.Error
+}
+
+// Workout Session Share Link
+func AddWorkoutSessionShareLink(db *gorm.DB, link *WorkoutSessionShareLink) error {
+	result := db.Create(link)
+	return result.Error
+}
+
+func GetWorkoutSessionShareLink(db *gorm.DB, token string) (*WorkoutSessionShareLink, error) {
+	var link WorkoutSessionShareLink
+	result := db.Preload("WorkoutSession").Where("token = ?", token).First(&link)
+	return &link, result.Error
+}
+
+// Workout Session Participant
+func AddWorkoutSessionParticipant(db *gorm.DB, participant *WorkoutSessionParticipant) error {
+	result := db.Create(participant)
+	return result.Error
+}
+
+func GetWorkoutSessionParticipant(db *gorm.DB, workoutSessionId string, userId string) (*WorkoutSessionParticipant, error) {
+	var participant WorkoutSessionParticipant
+	result := db.Where("workout_session_id = ? AND user_id = ?", workoutSessionId, userId).First(&participant)
+	return &participant, result.Error
+}
+
+// hard delete so the unique index doesn't stop the user from joining again
+func DeleteWorkoutSessionParticipant(db *gorm.DB, workoutSessionId string, userId string) (int64, error) {
+	result := db.Unscoped().Where("workout_session_id = ? AND user_id = ?", workoutSessionId, userId).Delete(&WorkoutSessionParticipant{})
+	return result.RowsAffected, result.Error
+}
+
+func CountWorkoutRoutineParticipations(db *gorm.DB, workoutRoutineId string, userId string) (int64, error) {
+	var count int64
+	result := db.Model(&WorkoutSessionParticipant{}).
+		Joins("JOIN workout_sessions ON workout_sessions.id = workout_session_participants.workout_session_id AND workout_sessions.deleted_at IS NULL").
+		Where("workout_sessions.workout_routine_id = ? AND workout_session_participants.user_id = ?", workoutRoutineId, userId).
+		Count(&count)
+	return count, result.Error
+}
+
+// Coach
+const (
+	CoachAccessRead      = "READ"
+	CoachAccessReadWrite = "READ_WRITE"
+)
+
+// CoachAccessAllows says if a coach granted access can do what needs
+// access, read write covers reading
+func CoachAccessAllows(access string, needs string) bool {
+	return access == needs || access == CoachAccessReadWrite
+}
+
+func AddCoach(db *gorm.DB, coach *Coach) error {
+	result := db.Create(coach)
+	return result.Error
+}
+
+func GetCoach(db *gorm.DB, userId string, coachId string) (*Coach, error) {
+	var coach Coach
+	result := db.Where("user_id = ? AND coach_id = ?", userId, coachId).First(&coach)
+	return &coach, result.Error
+}
+
+func UpdateCoachAccess(db *gorm.DB, coach *Coach, access string) error {
+	return db.Model(coach).Update("access", access).Error
+}
+
+// hard delete so the coach can be linked again later
+func DeleteCoach(db *gorm.DB, userId string, coachId string) (int64, error) {
+	result := db.Unscoped().Where("user_id = ? AND coach_id = ?", userId, coachId).Delete(&Coach{})
+	return result.RowsAffected, result.Error
+}
+
+// Exercise Video
+func AddExerciseVideo(db *gorm.DB, video *ExerciseVideo) error {
+	result := db.Create(video)
+	return result.Error
+}
+
+func GetExerciseVideo(db *gorm.DB, exerciseVideoId string) (*ExerciseVideo, error) {
+	var video ExerciseVideo
+	result := db.Where("id = ?", exerciseVideoId).First(&video)
+	return &video, result.Error
+}
+
+func GetExerciseVideos(db *gorm.DB, exerciseId string) ([]ExerciseVideo, error) {
+	var videos []ExerciseVideo
+	result := db.Where("exercise_id = ?", exerciseId).Order("id").Find(&videos)
+	return videos, result.Error
+}
+
+// Video Annotation
+func AddVideoAnnotation(db *gorm.DB, annotation *VideoAnnotation) error {
+	result := db.Create(annotation)
+	return result.Error
+}
+
+func GetVideoAnnotations(db *gorm.DB, exerciseVideoId string) ([]VideoAnnotation, error) {
+	var annotations []VideoAnnotation
+	result := db.Where("exercise_video_id = ?", exerciseVideoId).Order("timestamp_ms, id").Find(&annotations)
+	return annotations, result.Error
+}
+
+// Body Weight Entry
+func AddBodyWeightEntry(db *gorm.DB, entry *BodyWeightEntry) error {
+	result := db.Create(entry)
+	return result.Error
+}
+
+func GetBodyWeightEntry(db *gorm.DB, bodyWeightEntryId string) (*BodyWeightEntry, error) {
+	var entry BodyWeightEntry
+	result := db.Where("id = ?", bodyWeightEntryId).First(&entry)
+	return &entry, result.Error
+}
+
+// newest first. the cursor is the id of the last entry on the previous page,
+// entries can be logged for past days so the page boundary has to compare
+// logged_at as well as id
+func GetBodyWeightEntries(db *gorm.DB, userId string, cursor string, limit int) ([]BodyWeightEntry, error) {
+	var entries []BodyWeightEntry
+	if len(cursor) == 0 {
+		db = db.Where("user_id = ?", userId)
+	} else {
+		db = db.Where("user_id = ? AND (logged_at, id) < (SELECT logged_at, id FROM body_weight_entries WHERE id = ?)", userId, cursor)
+	}
+	result := db.Order("logged_at desc, id desc").Limit(limit).Find(&entries)
+	return entries, result.Error
+}
+
+func UpdateBodyWeightEntry(db *gorm.DB, bodyWeightEntryId string, updatedEntry *BodyWeightEntry) error {
+	result := db.Model(updatedEntry).Clauses(clause.Returning{}).Where("id = ?", bodyWeightEntryId).Updates(updatedEntry)
 	return result.Error
 }
 
-func DeleteSet(db *gorm.DB, setID string) error {
-	result := db.Where("id = ?", setID).Delete(&SetEntry{})
+func DeleteBodyWeightEntry(db *gorm.DB, bodyWeightEntryId string) error {
+	result := db.Where("id = ?", bodyWeightEntryId).Delete(&BodyWeightEntry{})
 	return result.Error
 }
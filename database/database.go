@@ -2,28 +2,153 @@ package database
 
 import (
 	"fmt"
+	"log"
 	"os"
+	"time"
 
+	"github.com/neilZon/workout-logger-api/clock"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/migrate"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	otelgorm "gorm.io/plugin/opentelemetry/tracing"
 )
 
+var models = []interface{}{User{}, WorkoutRoutine{}, ExerciseRoutine{}, WorkoutSession{}, Exercise{}, SetEntry{}, RequestRecording{}, WorkoutSessionShareLink{}, WorkoutSessionParticipant{}, Coach{}, ExerciseVideo{}, VideoAnnotation{}, ArchivedWorkoutSession{}, BodyWeightEntry{}, CatalogExercise{}, CatalogExerciseMuscleGroup{}, Goal{}, AccountExport{}, NutritionLog{}, SleepLog{}, UnitConversion{}, WorkoutSessionUnlock{}, QuickPhrase{}, WorkoutSessionAutoFinish{}, Snapshot{}, Program{}, ProgramDay{}, WorkoutRoutineShareLink{}, Webhook{}, WebhookDelivery{}, StravaConnection{}, StravaUpload{}, ProgressPhoto{}, Tag{}, WorkoutSessionTag{}, SyncRecord{}, AuditLog{}, PasswordResetToken{}, RefreshToken{}, Session{}, LoginThrottle{}, UserPreferences{}, PushToken{}, WeeklyDigest{}}
+
+// Connect opens the database without touching the schema
+func Connect() (*gorm.DB, error) {
+	pool, err := config.DBPoolFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	sandbox := os.Getenv(config.SANDBOX) == "true"
+	db, err := open(dsn(os.Getenv("DB_HOST"), os.Getenv("DB_PORT"), pool, sandbox), pool, "db")
+	if err != nil {
+		return nil, err
+	}
+
+	if sandbox {
+		err = db.Exec(fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", config.SANDBOX_SCHEMA)).Error
+		if err != nil {
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
 func InitDb() (*gorm.DB, error) {
-	DB_HOST := os.Getenv("DB_HOST")
+	db, err := Connect()
+	if err != nil {
+		return nil, err
+	}
+
+	if os.Getenv(config.MIGRATE_ON_START) != "false" {
+		migrator, err := migrate.New(db)
+		if err != nil {
+			return nil, err
+		}
+		applied, err := migrator.Up()
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range applied {
+			log.Printf("applied migration %s", m)
+		}
+	}
+
+	// migrations partition set_entries, this month's partition has to be
+	// there before the first set goes in
+	err = EnsureSetEntryPartitions(db, clock.Now(), config.SET_ENTRY_PARTITION_MONTHS_AHEAD)
+	if err != nil {
+		return nil, err
+	}
+
+	err = SeedExerciseCatalog(db)
+	if err != nil {
+		return nil, err
+	}
+
+	// a model changed without a migration to go with it shows up here
+	if SchemaDriftCheckEnabled() {
+		drift, err := CheckSchemaDrift(db, models...)
+		if err != nil {
+			log.Printf("error checking schema drift: %v", err)
+		}
+		for _, d := range drift {
+			log.Printf("schema drift: %s", d)
+		}
+	}
+
+	if os.Getenv(config.SANDBOX) == "true" {
+		if _, err := GetUserByEmail(db, config.SANDBOX_USER_EMAIL); err != nil {
+			err = SeedSandbox(db)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return db, nil
+}
+
+func dsn(host string, port string, pool config.DBPool, sandbox bool) string {
 	DB_DBNAME := os.Getenv("DB_DBNAME")
 	DB_USERNAME := os.Getenv("DB_USERNAME")
 	DB_PASSWORD := os.Getenv("DB_PASSWORD")
-	DB_PORT := os.Getenv("DB_PORT")
-	DSN := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable", DB_HOST, DB_USERNAME, DB_PASSWORD, DB_DBNAME, DB_PORT)
+	DSN := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable", host, DB_USERNAME, DB_PASSWORD, DB_DBNAME, port)
+
+	// postgres applies it to every statement on the connection
+	if pool.StatementTimeout > 0 {
+		DSN = fmt.Sprintf("%s statement_timeout=%d", DSN, pool.StatementTimeout.Milliseconds())
+	}
+
+	// keep sandbox tables in their own schema so they never mix with real data
+	if sandbox {
+		DSN = fmt.Sprintf("%s search_path=%s", DSN, config.SANDBOX_SCHEMA)
+	}
+	return DSN
+}
+
+// open connects with the pool, logging and tracing every connection shares,
+// name tells them apart in the logs
+func open(DSN string, pool config.DBPool, name string) (*gorm.DB, error) {
+	slowThreshold := config.SLOW_QUERY_THRESHOLD * time.Millisecond
+	gormLogger := logger.New(log.New(os.Stdout, "\r\n", log.LstdFlags), logger.Config{
+		SlowThreshold: slowThreshold,
+		LogLevel:      logger.Warn,
+		Colorful:      true,
+	})
 
-	var err error
 	db, err := gorm.Open(postgres.New(postgres.Config{
 		DSN:                  DSN,
 		PreferSimpleProtocol: true, // disables implicit prepared statement usage
-	}), &gorm.Config{})
+	}), &gorm.Config{Logger: gormLogger, NowFunc: clock.Now})
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB, err := db.DB()
 	if err != nil {
 		return nil, err
 	}
-	db.AutoMigrate(User{}, WorkoutRoutine{}, ExerciseRoutine{}, WorkoutSession{}, Exercise{}, SetEntry{})
+	sqlDB.SetMaxOpenConns(pool.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(pool.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+	log.Printf("%s pool: %d open, %d idle, %s lifetime, %s idle time, %s statement timeout", name, pool.MaxOpenConns, pool.MaxIdleConns, pool.ConnMaxLifetime, pool.ConnMaxIdleTime, pool.StatementTimeout)
+
+	// a span per query, the global tracer provider is a no-op unless tracing
+	// is set up. bound values stay out of the spans, they can be emails and
+	// password hashes
+	err = db.Use(otelgorm.NewPlugin(otelgorm.WithoutMetrics(), otelgorm.WithoutQueryVariables()))
+	if err != nil {
+		return nil, err
+	}
+
+	if os.Getenv(config.EXPLAIN_SLOW_QUERIES) == "true" {
+		db.Logger = NewPlanLogger(gormLogger, sqlDB, slowThreshold)
+	}
 	return db, nil
 }
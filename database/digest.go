@@ -0,0 +1,52 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DigestRecipient is a user due the weekly digest, with the channels they
+// get it on
+type DigestRecipient struct {
+	ID                uint
+	Name              string
+	Email             string
+	Locale            string
+	WeightUnit        *string
+	WeeklyDigestEmail bool
+	WeeklyDigestPush  bool
+}
+
+// GetDigestRecipients is up to limit verified users after afterId who
+// haven't opted out of the digest for the week ending at weekEnd on every
+// channel and weren't sent it yet, by id. Users who signed up after the week
+// ended wait for the next one
+func GetDigestRecipients(db *gorm.DB, weekEnd time.Time, afterId uint, limit int) ([]DigestRecipient, error) {
+	recipients := []DigestRecipient{}
+	err := db.Raw(`
+		SELECT users.id, users.name, users.email, users.locale, users.weight_unit,
+			COALESCE(user_preferences.weekly_digest_email, true) AS weekly_digest_email,
+			COALESCE(user_preferences.weekly_digest_push, true) AS weekly_digest_push
+		FROM users
+			LEFT JOIN user_preferences ON user_preferences.user_id = users.id AND user_preferences.deleted_at IS NULL
+		WHERE users.id > ? AND users.verified AND users.created_at < ? AND users.deleted_at IS NULL
+			AND (user_preferences.id IS NULL OR user_preferences.weekly_digest_email OR user_preferences.weekly_digest_push)
+			AND NOT EXISTS (SELECT 1 FROM weekly_digests WHERE weekly_digests.user_id = users.id AND weekly_digests.week_end = ?)
+		ORDER BY users.id
+		LIMIT ?`,
+		afterId, weekEnd, weekEnd, limit,
+	).Scan(&recipients).Error
+	return recipients, err
+}
+
+// ClaimWeeklyDigest records the user's digest for the week as sent, false
+// when it already was
+func ClaimWeeklyDigest(db *gorm.DB, userId uint, weekEnd time.Time) (bool, error) {
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&WeeklyDigest{
+		UserID:  userId,
+		WeekEnd: weekEnd,
+	})
+	return result.RowsAffected == 1, result.Error
+}
@@ -0,0 +1,182 @@
+package database
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/neilZon/workout-logger-api/config"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// SchemaDrift is a difference between a model and its table that no
+// migration took care of, queries against it can fail at runtime
+type SchemaDrift struct {
+	Table   string
+	Problem string
+	Fix     string // statement that would likely fix it, empty if it needs a closer look
+}
+
+func (d SchemaDrift) String() string {
+	if d.Fix == "" {
+		return fmt.Sprintf("%s: %s", d.Table, d.Problem)
+	}
+	return fmt.Sprintf("%s: %s, try %s", d.Table, d.Problem, d.Fix)
+}
+
+type liveColumn struct {
+	TableName  string
+	ColumnName string
+	IsNullable string
+	Default    *string
+}
+
+type liveIndex struct {
+	TableName string
+	IndexName string
+	IndexDef  string
+}
+
+// SchemaDriftCheckEnabled is on by default except on Cloud Run, where every
+// cold start would pay for it. SCHEMA_DRIFT_CHECK overrides either way
+func SchemaDriftCheckEnabled() bool {
+	switch os.Getenv(config.SCHEMA_DRIFT_CHECK) {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return os.Getenv("K_SERVICE") == ""
+}
+
+// CheckSchemaDrift compares the models against the tables in the current
+// schema, looking for missing tables, columns and indexes and for extra
+// columns that would make inserts from the model fail
+func CheckSchemaDrift(db *gorm.DB, models ...interface{}) ([]SchemaDrift, error) {
+	columnsByTable, err := getLiveColumns(db)
+	if err != nil {
+		return nil, err
+	}
+	var indexes []liveIndex
+	err = db.Raw(`SELECT tablename AS table_name, indexname AS index_name, indexdef AS index_def FROM pg_indexes WHERE schemaname = CURRENT_SCHEMA()`).Scan(&indexes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	indexesByTable := map[string]map[string]liveIndex{}
+	for _, i := range indexes {
+		if _, ok := indexesByTable[i.TableName]; !ok {
+			indexesByTable[i.TableName] = map[string]liveIndex{}
+		}
+		indexesByTable[i.TableName][i.IndexName] = i
+	}
+
+	drift := []SchemaDrift{}
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		drift = append(drift, modelDrift(db, stmt.Schema, columnsByTable[stmt.Table], indexesByTable[stmt.Table])...)
+	}
+	return drift, nil
+}
+
+// getLiveColumns returns the columns in the current schema by table
+func getLiveColumns(db *gorm.DB) (map[string]map[string]liveColumn, error) {
+	var columns []liveColumn
+	err := db.Raw(`SELECT table_name, column_name, is_nullable, column_default AS "default" FROM information_schema.columns WHERE table_schema = CURRENT_SCHEMA()`).Scan(&columns).Error
+	if err != nil {
+		return nil, err
+	}
+
+	columnsByTable := map[string]map[string]liveColumn{}
+	for _, c := range columns {
+		if _, ok := columnsByTable[c.TableName]; !ok {
+			columnsByTable[c.TableName] = map[string]liveColumn{}
+		}
+		columnsByTable[c.TableName][c.ColumnName] = c
+	}
+	return columnsByTable, nil
+}
+
+func modelDrift(db *gorm.DB, s *schema.Schema, columns map[string]liveColumn, indexes map[string]liveIndex) []SchemaDrift {
+	if len(columns) == 0 {
+		return []SchemaDrift{{Table: s.Table, Problem: "table is missing"}}
+	}
+
+	drift := []SchemaDrift{}
+	for _, dbName := range s.DBNames {
+		field := s.FieldsByDBName[dbName]
+		if field.IgnoreMigration {
+			continue
+		}
+		if _, ok := columns[dbName]; !ok {
+			drift = append(drift, SchemaDrift{
+				Table:   s.Table,
+				Problem: fmt.Sprintf("column %s is missing", dbName),
+				Fix:     fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", s.Table, dbName, db.Dialector.DataTypeOf(field)),
+			})
+			continue
+		}
+		if field.Unique && !hasUniqueIndexOn(indexes, dbName) {
+			drift = append(drift, SchemaDrift{
+				Table:   s.Table,
+				Problem: fmt.Sprintf("column %s is not unique", dbName),
+				Fix:     fmt.Sprintf("ALTER TABLE %s ADD UNIQUE (%s)", s.Table, dbName),
+			})
+		}
+	}
+
+	// inserts from the model leave unknown columns out, so those need to
+	// accept null or have a default
+	extra := []string{}
+	for name, c := range columns {
+		if _, ok := s.FieldsByDBName[name]; !ok && c.IsNullable == "NO" && c.Default == nil {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	for _, name := range extra {
+		drift = append(drift, SchemaDrift{
+			Table:   s.Table,
+			Problem: fmt.Sprintf("column %s isn't on the model and is NOT NULL without a default", name),
+			Fix:     fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL", s.Table, name),
+		})
+	}
+
+	modelIndexes := s.ParseIndexes()
+	names := make([]string, 0, len(modelIndexes))
+	for name := range modelIndexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := indexes[name]; !ok {
+			drift = append(drift, SchemaDrift{
+				Table:   s.Table,
+				Problem: fmt.Sprintf("index %s is missing", name),
+			})
+		}
+	}
+	return drift
+}
+
+// postgres backs unique constraints with an index, whatever it's named
+func hasUniqueIndexOn(indexes map[string]liveIndex, column string) bool {
+	for _, i := range indexes {
+		if !strings.HasPrefix(i.IndexDef, "CREATE UNIQUE INDEX") {
+			continue
+		}
+		// CREATE UNIQUE INDEX name ON table USING btree (column) [WHERE ...]
+		_, def, _ := strings.Cut(i.IndexDef, " USING ")
+		start := strings.Index(def, "(")
+		end := strings.Index(def, ")")
+		if start >= 0 && end > start && strings.TrimSpace(def[start+1:end]) == column {
+			return true
+		}
+	}
+	return false
+}
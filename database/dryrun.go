@@ -0,0 +1,22 @@
+package database
+
+import "errors"
+
+// DeletionCounts is how many rows a cascading delete removed, or would have
+// on a dry run. Tables a delete doesn't reach stay 0
+type DeletionCounts struct {
+	WorkoutRoutines  int64
+	ExerciseRoutines int64
+	WorkoutSessions  int64
+	Exercises        int64
+	Sets             int64
+	ExerciseVideos   int64
+	BodyWeights      int64
+	Goals            int64
+	NutritionLogs    int64
+	SleepLogs        int64
+}
+
+// errDryRun rolls back a transaction that went through every delete so the
+// counts are exactly what a real run would remove
+var errDryRun = errors.New("dry run")
@@ -0,0 +1,28 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WorkoutSessionEditLock is what the edit lock needs to know about a session
+type WorkoutSessionEditLock struct {
+	Start         time.Time
+	UnlockedUntil *time.Time
+}
+
+// GetWorkoutSessionEditLock gets when the session started and how long its
+// latest unlock lasts, nil when it was never unlocked
+func GetWorkoutSessionEditLock(db *gorm.DB, workoutSessionId string) (*WorkoutSessionEditLock, error) {
+	lock := WorkoutSessionEditLock{}
+	err := db.Model(&WorkoutSession{}).
+		Select("start, (SELECT MAX(expires_at) FROM workout_session_unlocks WHERE workout_session_id = workout_sessions.id AND deleted_at IS NULL) AS unlocked_until").
+		Where("id = ?", workoutSessionId).
+		Take(&lock).Error
+	return &lock, err
+}
+
+func AddWorkoutSessionUnlock(db *gorm.DB, unlock *WorkoutSessionUnlock) error {
+	return db.Create(unlock).Error
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"database/sql"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// one row per set, sessions and exercises without sets still get a row with
+// the missing columns null
+type WorkoutHistoryRow struct {
+	WorkoutSessionID uint
+	Start            time.Time
+	End              *time.Time
+	WorkoutRoutine   string
+	ExerciseID       *uint
+	Exercise         *string
+	Notes            *string
+	SetOrder         *uint
+	SetType          *string
+	Weight           *float64
+	Unit             *string
+	Reps             *uint
+	RestTimeSeconds  *uint
+	CompletedAt      *time.Time
+}
+
+// GetWorkoutHistoryRows returns a cursor over everything userId logged, oldest
+// session first. The caller has to close the rows and scan each one with
+// db.ScanRows so the whole history is never in memory at once
+func GetWorkoutHistoryRows(db *gorm.DB, userId string) (*sql.Rows, error) {
+	return db.Raw(`
+		SELECT workout_sessions.id AS workout_session_id,
+			workout_sessions.start AS start,
+			workout_sessions.end AS "end",
+			workout_routines.name AS workout_routine,
+			exercises.id AS exercise_id,
+			exercise_routines.name AS exercise,
+			exercises.notes AS notes,
+			set_entries.set_order AS set_order,
+			set_entries.type AS set_type,
+			set_entries.weight AS weight,
+			set_entries.unit AS unit,
+			set_entries.reps AS reps,
+			set_entries.rest_time_seconds AS rest_time_seconds,
+			set_entries.completed_at AS completed_at
+		FROM workout_sessions
+			JOIN workout_routines ON workout_routines.id = workout_sessions.workout_routine_id
+			LEFT JOIN exercises ON exercises.workout_session_id = workout_sessions.id AND exercises.user_id = ? AND exercises.deleted_at IS NULL
+			LEFT JOIN exercise_routines ON exercise_routines.id = exercises.exercise_routine_id
+			LEFT JOIN set_entries ON set_entries.exercise_id = exercises.id AND set_entries.deleted_at IS NULL
+		WHERE workout_sessions.deleted_at IS NULL AND (workout_sessions.user_id = ? OR exercises.id IS NOT NULL)
+		ORDER BY workout_sessions.start, workout_sessions.id, exercises.id, set_entries.set_order, set_entries.id`,
+		userId, userId,
+	).Rows()
+}
@@ -0,0 +1,81 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func AddGoal(db *gorm.DB, goal *Goal) error {
+	result := db.Create(goal)
+	return result.Error
+}
+
+func GetGoal(db *gorm.DB, goalId string) (*Goal, error) {
+	var goal Goal
+	result := db.Where("id = ?", goalId).First(&goal)
+	return &goal, result.Error
+}
+
+// soonest deadline first
+func GetGoals(db *gorm.DB, userId string) ([]Goal, error) {
+	var goals []Goal
+	result := db.Where("user_id = ?", userId).Order("deadline, id").Find(&goals)
+	return goals, result.Error
+}
+
+// updates is a map so completed_at can be cleared when the target moves
+func UpdateGoal(db *gorm.DB, goalId string, updates map[string]interface{}) (*Goal, error) {
+	var goal Goal
+	result := db.Model(&goal).Clauses(clause.Returning{}).Where("id = ?", goalId).Updates(updates)
+	return &goal, result.Error
+}
+
+func DeleteGoal(db *gorm.DB, goalId string) error {
+	result := db.Where("id = ?", goalId).Delete(&Goal{})
+	return result.Error
+}
+
+// goals that haven't been reached and are still before their deadline
+func GetOpenGoals(db *gorm.DB, now time.Time, limit int) ([]Goal, error) {
+	var goals []Goal
+	result := db.Where("completed_at IS NULL AND deadline > ?", now).Order("id").Limit(limit).Find(&goals)
+	return goals, result.Error
+}
+
+// CompleteGoal only marks goals that weren't completed yet, the returned
+// bool is false when something else got to it first
+func CompleteGoal(db *gorm.DB, goalId uint, completedAt time.Time) (bool, error) {
+	result := db.Model(&Goal{}).Where("id = ? AND completed_at IS NULL", goalId).Update("completed_at", completedAt)
+	return result.RowsAffected > 0, result.Error
+}
+
+// most recently logged body weight, nil if none was logged
+func GetLatestBodyWeight(db *gorm.DB, userId string) (*float64, error) {
+	var weights []float64
+	result := db.Model(&BodyWeightEntry{}).Where("user_id = ?", userId).Order("logged_at desc, id desc").Limit(1).Pluck("weight", &weights)
+	if result.Error != nil || len(weights) == 0 {
+		return nil, result.Error
+	}
+	return &weights[0], nil
+}
+
+// GetBestEstimatedOneRepMax is the highest epley estimate over every working
+// set userId logged on an exercise routine referencing catalogExerciseId in
+// unit, nil if there are none. archived sessions aren't included
+func GetBestEstimatedOneRepMax(db *gorm.DB, userId string, catalogExerciseId uint, unit string) (*float64, error) {
+	var best struct {
+		E1RM *float64 `gorm:"column:e1rm"`
+	}
+	err := db.Raw(`
+		SELECT MAX(`+oneRepMaxSQL[OneRepMaxEpley](setWeight(unit))+`) AS e1rm
+		FROM set_entries
+			JOIN exercises ON exercises.id = set_entries.exercise_id AND exercises.deleted_at IS NULL
+			JOIN exercise_routines ON exercise_routines.id = exercises.exercise_routine_id
+		WHERE exercises.user_id = ? AND exercise_routines.catalog_exercise_id = ?
+			AND set_entries.type <> 'WARMUP' AND set_entries.reps > 0 AND set_entries.deleted_at IS NULL`,
+		userId, catalogExerciseId,
+	).Scan(&best).Error
+	return best.E1RM, err
+}
@@ -0,0 +1,295 @@
+package database
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// ImportedWorkout is one workout read out of another app's export
+type ImportedWorkout struct {
+	Name      string
+	Start     time.Time
+	End       time.Time
+	Exercises []ImportedExercise
+}
+
+type ImportedExercise struct {
+	Name  string
+	Notes string
+	Sets  []ImportedSet
+}
+
+type ImportedSet struct {
+	Weight float64
+	Unit   string
+	Reps   uint
+	Type   string
+}
+
+// HistoryImport is what an import added, or would have on a dry run
+type HistoryImport struct {
+	WorkoutSessions int
+	Sets            int
+	// workouts the user already has a session for, same routine and start,
+	// so importing the same file twice doesn't double up
+	SkippedWorkoutSessions int
+	NewWorkoutRoutines     []string
+	NewExerciseRoutines    []NewExerciseRoutine
+}
+
+type NewExerciseRoutine struct {
+	WorkoutRoutine string
+	Name           string
+	// the catalog exercise it was matched to, nil when nothing matched
+	CatalogExercise *string
+}
+
+// routine and exercise names are capped by their columns
+const maxImportedNameLen = 32
+
+// ImportWorkoutHistory adds the workouts as finished sessions of userId in one
+// transaction. Workouts go on the user's routine with the same name and
+// exercises on the routine's exercise with the same name, either is created
+// when there isn't one. New exercises are matched to the catalog by name. A
+// dry run rolls back once it's gone through everything
+func ImportWorkoutHistory(db *gorm.DB, userId uint, workouts []ImportedWorkout, dryRun bool) (*HistoryImport, error) {
+	result := HistoryImport{
+		NewWorkoutRoutines:  []string{},
+		NewExerciseRoutines: []NewExerciseRoutine{},
+	}
+	if len(workouts) == 0 {
+		return &result, nil
+	}
+
+	sorted := append([]ImportedWorkout{}, workouts...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Start.Before(sorted[j].Start)
+	})
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		h := historyImport{tx: tx, userId: userId, result: &result}
+		if err := h.load(sorted[0].Start, sorted[len(sorted)-1].Start); err != nil {
+			return err
+		}
+		for i := range sorted {
+			if err := h.add(&sorted[i]); err != nil {
+				return err
+			}
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && err != errDryRun {
+		return nil, err
+	}
+	return &result, nil
+}
+
+type historyImport struct {
+	tx     *gorm.DB
+	userId uint
+	result *HistoryImport
+
+	routines map[string]*importedRoutine
+	catalog  map[string]CatalogExercise
+	// routine id and start in unix seconds of sessions the user already has
+	existing map[[2]int64]bool
+}
+
+type importedRoutine struct {
+	routine   WorkoutRoutine
+	exercises map[string]uint
+}
+
+func (h *historyImport) load(first time.Time, last time.Time) error {
+	var routines []WorkoutRoutine
+	err := h.tx.Preload("ExerciseRoutines").Where("user_id = ?", h.userId).Order("id").Find(&routines).Error
+	if err != nil {
+		return err
+	}
+	h.routines = make(map[string]*importedRoutine, len(routines))
+	for _, wr := range routines {
+		key := importKey(wr.Name)
+		if _, ok := h.routines[key]; ok {
+			continue
+		}
+		r := importedRoutine{routine: wr, exercises: make(map[string]uint, len(wr.ExerciseRoutines))}
+		for _, er := range wr.ExerciseRoutines {
+			if _, ok := r.exercises[importKey(er.Name)]; !ok {
+				r.exercises[importKey(er.Name)] = er.ID
+			}
+		}
+		h.routines[key] = &r
+	}
+
+	var catalog []CatalogExercise
+	if err := h.tx.Find(&catalog).Error; err != nil {
+		return err
+	}
+	h.catalog = make(map[string]CatalogExercise, len(catalog))
+	for _, ce := range catalog {
+		h.catalog[importKey(ce.Name)] = ce
+	}
+
+	var sessions []WorkoutSession
+	err = h.tx.Select("workout_routine_id", "start").
+		Where("user_id = ? AND start >= ? AND start <= ?", h.userId, first, last).
+		Find(&sessions).Error
+	if err != nil {
+		return err
+	}
+	h.existing = make(map[[2]int64]bool, len(sessions))
+	for _, ws := range sessions {
+		h.existing[[2]int64{int64(ws.WorkoutRoutineID), ws.Start.Unix()}] = true
+	}
+	return nil
+}
+
+func (h *historyImport) add(workout *ImportedWorkout) error {
+	r, err := h.routine(workout.Name)
+	if err != nil {
+		return err
+	}
+	key := [2]int64{int64(r.routine.ID), workout.Start.Unix()}
+	if h.existing[key] {
+		h.result.SkippedWorkoutSessions++
+		return nil
+	}
+	h.existing[key] = true
+
+	end := workout.End
+	ws := WorkoutSession{
+		Start:            workout.Start,
+		End:              &end,
+		WorkoutRoutineID: r.routine.ID,
+		UserID:           h.userId,
+		Exercises:        make([]Exercise, 0, len(workout.Exercises)),
+	}
+	for i := range workout.Exercises {
+		e := &workout.Exercises[i]
+		exerciseRoutineId, err := h.exerciseRoutine(r, e)
+		if err != nil {
+			return err
+		}
+		exercise := Exercise{
+			ExerciseRoutineID: exerciseRoutineId,
+			UserID:            h.userId,
+			Notes:             truncate(e.Notes, 512),
+			Sets:              make([]SetEntry, 0, len(e.Sets)),
+		}
+		for j, s := range e.Sets {
+			exercise.Sets = append(exercise.Sets, SetEntry{
+				Weight:   s.Weight,
+				Unit:     s.Unit,
+				Reps:     s.Reps,
+				Type:     s.Type,
+				SetOrder: uint(j + 1),
+			})
+		}
+		h.result.Sets += len(exercise.Sets)
+		ws.Exercises = append(ws.Exercises, exercise)
+	}
+
+	if err := h.tx.Create(&ws).Error; err != nil {
+		return err
+	}
+	h.result.WorkoutSessions++
+	return nil
+}
+
+func (h *historyImport) routine(name string) (*importedRoutine, error) {
+	name = truncate(strings.TrimSpace(name), maxImportedNameLen)
+	if r, ok := h.routines[importKey(name)]; ok {
+		return r, nil
+	}
+
+	r := importedRoutine{
+		routine:   WorkoutRoutine{Name: name, UserID: h.userId},
+		exercises: map[string]uint{},
+	}
+	if err := h.tx.Create(&r.routine).Error; err != nil {
+		return nil, err
+	}
+	h.routines[importKey(name)] = &r
+	h.result.NewWorkoutRoutines = append(h.result.NewWorkoutRoutines, name)
+	return &r, nil
+}
+
+func (h *historyImport) exerciseRoutine(r *importedRoutine, e *ImportedExercise) (uint, error) {
+	name := truncate(strings.TrimSpace(e.Name), maxImportedNameLen)
+	if id, ok := r.exercises[importKey(name)]; ok {
+		return id, nil
+	}
+
+	// the first time it shows up is as good a target as any
+	er := ExerciseRoutine{
+		Name:             name,
+		WorkoutRoutineID: r.routine.ID,
+		Position:         uint(len(r.exercises)),
+	}
+	for _, s := range e.Sets {
+		if s.Type == SetTypeWarmup {
+			continue
+		}
+		if er.Sets == 0 {
+			er.Reps = s.Reps
+		}
+		er.Sets++
+	}
+
+	added := NewExerciseRoutine{WorkoutRoutine: r.routine.Name, Name: name}
+	if ce, ok := h.matchCatalog(e.Name); ok {
+		er.CatalogExerciseID = &ce.ID
+		added.CatalogExercise = &ce.Name
+	}
+	if err := h.tx.Create(&er).Error; err != nil {
+		return 0, err
+	}
+	r.exercises[importKey(name)] = er.ID
+	h.result.NewExerciseRoutines = append(h.result.NewExerciseRoutines, added)
+	return er.ID, nil
+}
+
+var equipmentSuffix = regexp.MustCompile(`^(.*?)\s*\(([^)]*)\)\s*$`)
+
+// matchCatalog finds the catalog exercise for an exported name. Strong and
+// Hevy put the equipment after the name, "Bench Press (Dumbbell)", where the
+// catalog has it first when it's not the usual one, "Dumbbell Bench Press"
+func (h *historyImport) matchCatalog(name string) (CatalogExercise, bool) {
+	candidates := []string{name}
+	if m := equipmentSuffix.FindStringSubmatch(name); m != nil {
+		candidates = []string{m[2] + " " + m[1], m[1] + " " + m[2], m[1]}
+	}
+	for _, c := range candidates {
+		if ce, ok := h.catalog[importKey(c)]; ok {
+			return ce, true
+		}
+	}
+	return CatalogExercise{}, false
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
+
+// importKey is a name with case and punctuation taken out, "Pull-Up" and
+// "pull up" are the same exercise
+func importKey(name string) string {
+	return strings.TrimSpace(nonAlphanumeric.ReplaceAllString(strings.ToLower(name), " "))
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	// don't cut a character in half
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// JSONObject is a jsonb column holding a json object, nil is stored as null
+type JSONObject map[string]interface{}
+
+func (j JSONObject) Value() (driver.Value, error) {
+	if j == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(j)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (j *JSONObject) Scan(value interface{}) error {
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+		*j = nil
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("can't scan %T into JSONObject", value)
+	}
+	return json.Unmarshal(b, j)
+}
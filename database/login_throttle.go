@@ -0,0 +1,48 @@
+package database
+
+import (
+	"time"
+
+	"github.com/neilZon/workout-logger-api/config"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func GetLoginThrottles(db *gorm.DB, keys []string) ([]LoginThrottle, error) {
+	var throttles []LoginThrottle
+	err := db.Where("key IN ?", keys).Find(&throttles).Error
+	return throttles, err
+}
+
+// AddLoginFailure counts a failed login for key, starting over when the last
+// one was before windowStart
+func AddLoginFailure(db *gorm.DB, key string, now time.Time, windowStart time.Time) error {
+	t := LoginThrottle{Key: key, Failures: 1, LastFailedAt: now}
+	return db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"failures":       gorm.Expr("CASE WHEN login_throttles.last_failed_at < ? THEN 1 ELSE login_throttles.failures + 1 END", windowStart),
+			"last_failed_at": now,
+		}),
+	}).Create(&t).Error
+}
+
+func DeleteLoginThrottle(db *gorm.DB, key string) error {
+	return db.Where("key = ?", key).Delete(&LoginThrottle{}).Error
+}
+
+// LockedUntil is when the key can log in again, it's locked once it's failed
+// freeFailures times and each failure after doubles the lockout
+func (t *LoginThrottle) LockedUntil(freeFailures int) time.Time {
+	if t.Failures < freeFailures {
+		return time.Time{}
+	}
+	lockout := config.LOGIN_LOCKOUT
+	for i := freeFailures; i < t.Failures && lockout < config.LOGIN_MAX_LOCKOUT; i++ {
+		lockout *= 2
+	}
+	if lockout > config.LOGIN_MAX_LOCKOUT {
+		lockout = config.LOGIN_MAX_LOCKOUT
+	}
+	return t.LastFailedAt.Add(lockout)
+}
@@ -15,10 +15,23 @@ type User struct {
 	Verified            bool             `gorm:"default:false"`
 	VerificationCode    *string          `gorm:"unique"`
 	VerificationSentAt  *time.Time
-	PasswordResetCode   *string `gorm:"unique"`
-	PasswordResetSentAt *time.Time
+	PendingEmail        *string `gorm:"type:varchar(80)"` // new address waiting to be confirmed
+	EmailChangeCode     *string `gorm:"unique"`
+	EmailChangeSentAt   *time.Time
+	Admin               bool `gorm:"default:false"`
+	RecordRequestsUntil *time.Time
+	WeekStart           string  `gorm:"size:8;default:MONDAY"` // first day of the week for weekly stats
+	Locale              string  `gorm:"size:35;default:en-US"`
+	WeightUnit          *string `gorm:"size:2"` // nil follows the locale
 }
 
+// week starts line up with the graphql WeekStart enum
+const (
+	WeekStartSunday   = "SUNDAY"
+	WeekStartMonday   = "MONDAY"
+	WeekStartSaturday = "SATURDAY"
+)
+
 type WorkoutRoutine struct {
 	gorm.Model
 	Name             string            `gorm:"not null;size:32"`
@@ -30,22 +43,53 @@ type WorkoutRoutine struct {
 
 type ExerciseRoutine struct {
 	gorm.Model
-	Name             string     `gorm:"not null;size:32"`
-	Sets             uint       `gorm:"not null"`
-	Reps             uint       `gorm:"not null"`
-	Exercises        []Exercise `gorm:"constraint:OnDelete:CASCADE"`
-	Active           bool       `gorm:"default:true"`
-	WorkoutRoutineID uint
+	Name              string     `gorm:"not null;size:32"`
+	Sets              uint       `gorm:"not null"`
+	Reps              uint       `gorm:"not null"`
+	Exercises         []Exercise `gorm:"constraint:OnDelete:CASCADE"`
+	Active            bool       `gorm:"default:true"`
+	WorkoutRoutineID  uint       `gorm:"index"`
+	Position          uint       `gorm:"default:0"` // where the user wants it in the routine
+	CatalogExerciseID *uint
+	CatalogExercise   *CatalogExercise
 }
 
 type WorkoutSession struct {
 	gorm.Model
-	Start            time.Time `gorm:"not null"`
+	Start            time.Time `gorm:"not null;index:idx_workout_session_user_start,priority:2"`
 	End              *time.Time
 	WorkoutRoutine   WorkoutRoutine
 	Exercises        []Exercise `gorm:"constraint:OnDelete:CASCADE"`
 	WorkoutRoutineID uint
-	UserID           uint
+	UserID           uint `gorm:"index:idx_workout_session_user_start,priority:1"`
+	// whatever the client wants to keep with the session, checked by validator.ClientMetadataIsValid
+	ClientMetadata JSONObject `gorm:"type:jsonb"`
+	Origin         Origin     `gorm:"embedded;embeddedPrefix:origin_"`
+	Notes          string     `gorm:"type:text"`
+	Tags           []Tag      `gorm:"many2many:workout_session_tags"`
+}
+
+// Tag is a label like "deload" put on workout sessions. Names are shared
+// across users so a tag doesn't belong to anyone, what a user sees is the
+// tags on their own sessions
+type Tag struct {
+	gorm.Model
+	Name string `gorm:"uniqueIndex;not null;size:32"`
+}
+
+// WorkoutSessionTag is the join table behind WorkoutSession.Tags
+type WorkoutSessionTag struct {
+	WorkoutSessionID uint `gorm:"primaryKey"`
+	TagID            uint `gorm:"primaryKey;index"`
+}
+
+// Origin is the client that created a row, as it described itself in the
+// request headers. Empty for rows written before it was tracked or by
+// clients that don't send them
+type Origin struct {
+	Platform   string `gorm:"size:16"`
+	AppVersion string `gorm:"size:32"`
+	DeviceID   string `gorm:"size:64"`
 }
 
 type Exercise struct {
@@ -55,12 +99,451 @@ type Exercise struct {
 	Sets              []SetEntry `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 	Notes             string     `gorm:"size:512"`
 	ExerciseRoutineID uint
-	WorkoutSessionID  uint
+	WorkoutSessionID  uint `gorm:"index"`
+	UserID            uint // who logged it, not always the session owner when co-logging
 }
 
 type SetEntry struct {
 	gorm.Model
-	Weight     float32 `gorm:"not null" sql:"type:decimal(10,2);"`
+	Weight     float64 `gorm:"precision:10;scale:2;not null"`
+	Unit       string  `gorm:"size:2"` // the unit weight was logged in
 	Reps       uint    `gorm:"not null"`
-	ExerciseID uint
+	ExerciseID uint    `gorm:"index"`
+	SetOrder   uint    `gorm:"default:0"` // starts at 1, 0 is a set logged before sets were ordered
+	Type       string  `gorm:"size:16;default:WORKING"`
+	// rest taken before starting this set
+	RestTimeSeconds *uint
+	CompletedAt     *time.Time
+	ClientMetadata  JSONObject `gorm:"type:jsonb"`
+	Origin          Origin     `gorm:"embedded;embeddedPrefix:origin_"`
+}
+
+// set types line up with the graphql SetType enum
+const (
+	SetTypeWarmup  = "WARMUP"
+	SetTypeWorking = "WORKING"
+	SetTypeDropset = "DROPSET"
+	SetTypeFailure = "FAILURE"
+)
+
+type RequestRecording struct {
+	gorm.Model
+	UserID        uint
+	OperationName string `gorm:"size:128"`
+	Query         string `gorm:"type:text"`
+	Variables     string `gorm:"type:text"`
+	DurationMs    int64
+}
+
+type WorkoutSessionShareLink struct {
+	gorm.Model
+	Token            string `gorm:"unique;not null"`
+	WorkoutSession   WorkoutSession
+	WorkoutSessionID uint
+	UserID           uint
+	// invites let whoever has them join the session and log in it, every
+	// other link only lets them watch
+	Invite bool
+}
+
+// a public link to a routine, anyone with the token can see and copy it
+type WorkoutRoutineShareLink struct {
+	gorm.Model
+	Token            string `gorm:"unique;not null"`
+	WorkoutRoutine   WorkoutRoutine
+	WorkoutRoutineID uint `gorm:"index"`
+	UserID           uint
+}
+
+// a url the user wants their finished sessions posted to, so something like
+// an Apple Health or Google Fit bridge can pick them up. Secret signs every
+// post so the receiver knows it came from us
+type Webhook struct {
+	gorm.Model
+	UserID uint   `gorm:"index"`
+	URL    string `gorm:"size:2048;not null"`
+	Secret string `gorm:"size:64;not null"`
+}
+
+// a finished session waiting to be posted to a webhook. it's kept once it's
+// delivered or given up on so failures can be looked into
+type WebhookDelivery struct {
+	gorm.Model
+	Webhook          Webhook
+	WebhookID        uint `gorm:"index"`
+	WorkoutSessionID uint
+	Attempts         uint
+	// nil once it's delivered or out of attempts
+	NextAttemptAt *time.Time `gorm:"index"`
+	DeliveredAt   *time.Time
+	LastError     string `gorm:"size:512"`
+}
+
+// a user's link to their Strava account, finished sessions are uploaded to
+// it as weight training activities. one per user
+type StravaConnection struct {
+	gorm.Model
+	UserID       uint   `gorm:"uniqueIndex"`
+	AthleteID    int64  `gorm:"not null"`
+	AccessToken  string `gorm:"size:255;not null"`
+	RefreshToken string `gorm:"size:255;not null"`
+	// when AccessToken stops working, it's refreshed a little before
+	ExpiresAt time.Time `gorm:"not null"`
+}
+
+// a finished session waiting to be uploaded to Strava, kept once it's
+// uploaded or given up on like WebhookDelivery
+type StravaUpload struct {
+	gorm.Model
+	UserID           uint `gorm:"index"`
+	WorkoutSessionID uint
+	Attempts         uint
+	// nil once it's uploaded, out of attempts or the user disconnected
+	NextAttemptAt *time.Time `gorm:"index"`
+	ActivityID    *int64
+	UploadedAt    *time.Time
+	LastError     string `gorm:"size:512"`
+}
+
+type WorkoutSessionParticipant struct {
+	gorm.Model
+	WorkoutSessionID uint `gorm:"uniqueIndex:idx_workout_session_participant"`
+	UserID           uint `gorm:"uniqueIndex:idx_workout_session_participant"`
+}
+
+// a coach the user linked to their account
+type Coach struct {
+	gorm.Model
+	UserID  uint `gorm:"uniqueIndex:idx_coach"`
+	CoachID uint `gorm:"uniqueIndex:idx_coach"`
+	// what the coach can do with the user's routines and sessions, one of
+	// the CoachAccess constants
+	Access string `gorm:"size:10;not null;default:READ"`
+}
+
+type ExerciseVideo struct {
+	gorm.Model
+	ExerciseID  uint
+	SetEntryID  *uint
+	UserID      uint
+	StorageKey  string            `gorm:"not null"`
+	ContentType string            `gorm:"size:64"`
+	Annotations []VideoAnnotation `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// ProgressPhoto is a private photo of the user, the client uploads it
+// straight to the photo store with a signed url and it's only listed once
+// the upload is confirmed
+type ProgressPhoto struct {
+	gorm.Model
+	UserID      uint      `gorm:"index:idx_progress_photo_user_taken_on"`
+	StorageKey  string    `gorm:"not null"`
+	ContentType string    `gorm:"size:64"`
+	TakenOn     time.Time `gorm:"type:date;index:idx_progress_photo_user_taken_on"`
+	BodyWeight  *float32
+	UploadedAt  *time.Time
+}
+
+// SyncRecord is the row an id the client made up offline became, and the
+// latest change to it that was synced. It's what makes sending the same
+// changes twice safe
+type SyncRecord struct {
+	gorm.Model
+	UserID   uint   `gorm:"uniqueIndex:idx_sync_record_client"`
+	ClientID string `gorm:"size:36;uniqueIndex:idx_sync_record_client"`
+	Entity   string `gorm:"size:16;not null"` // one of the SyncEntity constants
+	// 0 when the first thing the server heard about it was that it's deleted
+	EntityID     uint
+	LastModified time.Time `gorm:"not null"`
+	Deleted      bool      `gorm:"not null;default:false"`
+}
+
+// AuditLog is one mutation a user made, with the row it changed as it was
+// before and after. It's there to answer "my workout disappeared"
+type AuditLog struct {
+	gorm.Model
+	UserID     uint       `gorm:"index"`
+	Operation  string     `gorm:"size:64;not null"`
+	EntityType string     `gorm:"size:32;index:idx_audit_log_entity"` // one of the AuditEntity constants, empty for mutations on anything else
+	EntityID   uint       `gorm:"index:idx_audit_log_entity"`
+	Before     JSONObject `gorm:"type:jsonb"`
+	After      JSONObject `gorm:"type:jsonb"`
+	// the admin acting as UserID, and why for mutations with a reason argument
+	ImpersonatedBy uint   `gorm:"index"`
+	Reason         string `gorm:"size:512"`
+}
+
+type VideoAnnotation struct {
+	gorm.Model
+	ExerciseVideoID uint
+	CoachID         uint
+	TimestampMs     uint
+	Note            string `gorm:"size:512"`
+}
+
+// ArchivedWorkoutSession is what stays in postgres after a workout session is
+// moved to cold storage, enough to list it without fetching the blob
+type ArchivedWorkoutSession struct {
+	gorm.Model
+	WorkoutSessionID uint `gorm:"uniqueIndex"`
+	UserID           uint `gorm:"index"`
+	WorkoutRoutineID uint
+	Start            time.Time
+	End              *time.Time
+	ExerciseCount    int
+	SetCount         int
+	TotalVolume      float64
+	VolumeUnit       string `gorm:"size:2"`
+	StorageKey       string `gorm:"not null"`
+}
+
+type BodyWeightEntry struct {
+	gorm.Model
+	UserID   uint `gorm:"index:idx_body_weight_user_logged_at"`
+	Weight   float32
+	LoggedAt time.Time `gorm:"index:idx_body_weight_user_logged_at"`
+}
+
+// Goal is a target body weight or a target estimated one rep max on a
+// catalog exercise to reach before Deadline
+type Goal struct {
+	gorm.Model
+	UserID            uint   `gorm:"index"`
+	Type              string `gorm:"size:16"`
+	TargetValue       float32
+	StartValue        *float32 // current value when the goal was set, nil if nothing was logged yet
+	Unit              string   `gorm:"size:2"` // lift goals are measured in the unit they were set in
+	CatalogExerciseID *uint    // only for lift goals
+	CatalogExercise   *CatalogExercise
+	Deadline          time.Time
+	CompletedAt       *time.Time
+}
+
+// goal types line up with the graphql GoalType enum
+const (
+	GoalTypeBodyWeight = "BODY_WEIGHT"
+	GoalTypeLiftE1RM   = "LIFT_E1RM"
+)
+
+// WorkoutSessionUnlock lets a session past the edit lock be changed until
+// ExpiresAt, and keeps a record of who opened it up
+type WorkoutSessionUnlock struct {
+	gorm.Model
+	WorkoutSessionID uint `gorm:"index"`
+	WorkoutSession   WorkoutSession
+	UserID           uint
+	ExpiresAt        time.Time
+}
+
+// WorkoutSessionAutoFinish records a session the server finished because it
+// was left open, End is what it was given
+type WorkoutSessionAutoFinish struct {
+	gorm.Model
+	WorkoutSessionID uint `gorm:"index"`
+	WorkoutSession   WorkoutSession
+	UserID           uint
+	Start            time.Time
+	End              time.Time
+}
+
+// UnitConversion records a bulk rescale of the weights logged on an exercise
+// routine in sessions that started in the range, for fixing history logged
+// in the wrong unit
+type UnitConversion struct {
+	gorm.Model
+	UserID            uint `gorm:"index"`
+	ExerciseRoutineID uint
+	FromUnit          string `gorm:"size:2"`
+	ToUnit            string `gorm:"size:2"`
+	Factor            float64
+	RangeStart        time.Time
+	RangeEnd          time.Time
+	SetsConverted     int64
+}
+
+// QuickPhrase is a snippet the user keeps handy to drop into notes, in the
+// order they put them
+type QuickPhrase struct {
+	gorm.Model
+	UserID   uint `gorm:"index"`
+	Text     string
+	Position uint `gorm:"default:0"`
+}
+
+// weight units line up with the graphql WeightUnit enum
+const (
+	WeightUnitKg = "KG"
+	WeightUnitLb = "LB"
+)
+
+// AccountExport is a request for everything we have on a user, the
+// exporter job writes the archive to StorageKey in the archive store
+type AccountExport struct {
+	gorm.Model
+	UserID      uint   `gorm:"index"`
+	Status      string `gorm:"size:16;default:PENDING"`
+	StorageKey  string
+	CompletedAt *time.Time
+}
+
+// Program is a weekly schedule of the user's workout routines, the active
+// one is what todaysWorkout goes by
+type Program struct {
+	gorm.Model
+	UserID uint         `gorm:"index"`
+	Name   string       `gorm:"not null;size:32"`
+	Active bool         `gorm:"default:false"`
+	Days   []ProgramDay `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// ProgramDay puts a routine on a day of the week, at most one per day
+type ProgramDay struct {
+	gorm.Model
+	ProgramID        uint   `gorm:"uniqueIndex:idx_program_day_weekday"`
+	Weekday          string `gorm:"size:9;uniqueIndex:idx_program_day_weekday"` // graphql Weekday enum, MONDAY etc.
+	WorkoutRoutineID uint
+	WorkoutRoutine   WorkoutRoutine
+}
+
+// Snapshot is a copy of a user's data they took to restore later, the
+// snapshot package writes it to StorageKey in the archive store
+type Snapshot struct {
+	gorm.Model
+	UserID              uint `gorm:"index"`
+	StorageKey          string
+	WorkoutRoutineCount int
+	WorkoutSessionCount int
+	RestoredAt          *time.Time // last time it was restored
+}
+
+// statuses line up with the graphql AccountExportStatus enum
+const (
+	AccountExportStatusPending = "PENDING"
+	AccountExportStatusRunning = "RUNNING"
+	AccountExportStatusReady   = "READY"
+	AccountExportStatusFailed  = "FAILED"
+)
+
+// NutritionLog is one day of calories and protein, a user has at most one
+// per day
+type NutritionLog struct {
+	gorm.Model
+	UserID       uint      `gorm:"uniqueIndex:idx_nutrition_user_day"`
+	Day          time.Time `gorm:"type:date;uniqueIndex:idx_nutrition_user_day"`
+	Calories     *uint
+	ProteinGrams *float32
+	Notes        string `gorm:"size:256"`
+}
+
+// SleepLog is how long the user slept the night starting on Night, at most
+// one per night
+type SleepLog struct {
+	gorm.Model
+	UserID uint      `gorm:"uniqueIndex:idx_sleep_user_night"`
+	Night  time.Time `gorm:"type:date;uniqueIndex:idx_sleep_user_night"`
+	Hours  float32
+}
+
+// CatalogExercise is an entry in the shared exercise library, it isn't owned
+// by any user and is only written by SeedExerciseCatalog
+type CatalogExercise struct {
+	gorm.Model
+	Name         string                       `gorm:"uniqueIndex;not null;size:64"`
+	Equipment    string                       `gorm:"size:32"`
+	Instructions string                       `gorm:"type:text"`
+	MuscleGroups []CatalogExerciseMuscleGroup `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+type CatalogExerciseMuscleGroup struct {
+	gorm.Model
+	CatalogExerciseID uint   `gorm:"uniqueIndex:idx_catalog_exercise_muscle_group"`
+	MuscleGroup       string `gorm:"uniqueIndex:idx_catalog_exercise_muscle_group;index;size:16"`
+	IsPrimary         bool   `gorm:"default:false"` // the muscle the exercise mainly works
+}
+
+const (
+	MuscleGroupChest      = "CHEST"
+	MuscleGroupBack       = "BACK"
+	MuscleGroupShoulders  = "SHOULDERS"
+	MuscleGroupBiceps     = "BICEPS"
+	MuscleGroupTriceps    = "TRICEPS"
+	MuscleGroupForearms   = "FOREARMS"
+	MuscleGroupAbs        = "ABS"
+	MuscleGroupQuads      = "QUADS"
+	MuscleGroupHamstrings = "HAMSTRINGS"
+	MuscleGroupGlutes     = "GLUTES"
+	MuscleGroupCalves     = "CALVES"
+)
+
+// PasswordResetToken is a reset link that was emailed. Only the sha256 of the
+// token is kept so reading the table isn't enough to reset a password, and
+// it works once before ExpiresAt
+type PasswordResetToken struct {
+	gorm.Model
+	UserID    uint      `gorm:"not null;index"`
+	TokenHash string    `gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+}
+
+// RefreshToken is one refresh token handed out, by its jwt id. Refreshing
+// swaps it for a new one in the same family, and a token used twice means
+// it was stolen so the whole family stops working
+type RefreshToken struct {
+	gorm.Model
+	UserID   uint   `gorm:"not null;index"`
+	FamilyID string `gorm:"size:64;not null;index"`
+	TokenID  string `gorm:"size:64;not null;uniqueIndex"`
+	// when it was swapped for the next token
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
+
+// LoginThrottle is the failed logins in a row for a key like
+// email:someone@example.com or ip:203.0.113.7
+type LoginThrottle struct {
+	Key          string    `gorm:"primaryKey;size:330"`
+	Failures     int       `gorm:"not null"`
+	LastFailedAt time.Time `gorm:"not null"`
+}
+
+// Session is a login on a device, the refresh token family it was handed
+// out with. It's updated with where the family was last refreshed from
+type Session struct {
+	gorm.Model
+	UserID     uint      `gorm:"not null;index"`
+	FamilyID   string    `gorm:"size:64;not null;uniqueIndex"`
+	UserAgent  string    `gorm:"size:255"`
+	IP         string    `gorm:"size:64"`
+	Origin     Origin    `gorm:"embedded;embeddedPrefix:origin_"`
+	LastUsedAt time.Time `gorm:"not null"`
+	RevokedAt  *time.Time
+}
+
+// UserPreferences are the settings a user changed, users without a row have
+// DefaultUserPreferences. week start and weight unit are kept on the user
+type UserPreferences struct {
+	gorm.Model
+	UserID            uint   `gorm:"not null;uniqueIndex"`
+	TimeZone          string `gorm:"size:64;not null;default:UTC"` // IANA name like Europe/Berlin
+	WeeklyDigestEmail bool   `gorm:"not null"`
+	WeeklyDigestPush  bool   `gorm:"not null"`
+}
+
+// PushToken is a device's token for push notifications, a device belongs to
+// whoever registered it last
+type PushToken struct {
+	gorm.Model
+	UserID uint   `gorm:"not null;index"`
+	Token  string `gorm:"size:255;not null;uniqueIndex"`
+}
+
+// WeeklyDigest is the digest of the week ending at WeekEnd a user was sent,
+// or is being sent. one per user and week so instances running the job at
+// the same time don't both send it
+type WeeklyDigest struct {
+	ID        uint      `gorm:"primarykey"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_weekly_digests_user_week"`
+	WeekEnd   time.Time `gorm:"not null;uniqueIndex:idx_weekly_digests_user_week"`
+	CreatedAt time.Time
 }
@@ -0,0 +1,31 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UpsertNutritionLog replaces whatever was logged for that day, a day that
+// was deleted comes back
+func UpsertNutritionLog(db *gorm.DB, nutritionLog *NutritionLog) error {
+	result := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "day"}},
+		DoUpdates: clause.AssignmentColumns([]string{"calories", "protein_grams", "notes", "updated_at", "deleted_at"}),
+	}).Create(nutritionLog)
+	return result.Error
+}
+
+// days in [start, end), oldest first
+func GetNutritionLogs(db *gorm.DB, userId string, start time.Time, end time.Time) ([]NutritionLog, error) {
+	var nutritionLogs []NutritionLog
+	result := db.Where("user_id = ? AND day >= ? AND day < ?", userId, start, end).Order("day").Find(&nutritionLogs)
+	return nutritionLogs, result.Error
+}
+
+func GetAllNutritionLogs(db *gorm.DB, userId string) ([]NutritionLog, error) {
+	var nutritionLogs []NutritionLog
+	result := db.Where("user_id = ?", userId).Order("day").Find(&nutritionLogs)
+	return nutritionLogs, result.Error
+}
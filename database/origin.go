@@ -0,0 +1,44 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DeviceOriginStats is how much one platform and app version wrote
+type DeviceOriginStats struct {
+	Platform   string
+	AppVersion string
+	Sessions   int
+	Sets       int
+	Devices    int
+	LastSeenAt time.Time
+}
+
+// GetDeviceOriginStats counts the sessions and sets created in the range by
+// each platform and app version, across every user. Rows from before origins
+// were tracked are grouped under an empty platform and version
+func GetDeviceOriginStats(db *gorm.DB, start time.Time, end time.Time) ([]DeviceOriginStats, error) {
+	stats := []DeviceOriginStats{}
+	err := db.Raw(`
+		SELECT platform, app_version,
+			COUNT(*) FILTER (WHERE kind = 'session') AS sessions,
+			COUNT(*) FILTER (WHERE kind = 'set') AS sets,
+			COUNT(DISTINCT device_id) FILTER (WHERE device_id <> '') AS devices,
+			MAX(created_at) AS last_seen_at
+		FROM (
+			SELECT 'session' AS kind, COALESCE(origin_platform, '') AS platform, COALESCE(origin_app_version, '') AS app_version, COALESCE(origin_device_id, '') AS device_id, created_at
+			FROM workout_sessions
+			WHERE created_at >= @start AND created_at < @end AND deleted_at IS NULL
+			UNION ALL
+			SELECT 'set', COALESCE(origin_platform, ''), COALESCE(origin_app_version, ''), COALESCE(origin_device_id, ''), created_at
+			FROM set_entries
+			WHERE created_at >= @start AND created_at < @end AND deleted_at IS NULL
+		) AS writes
+		GROUP BY platform, app_version
+		ORDER BY sets DESC, sessions DESC, platform, app_version`,
+		map[string]interface{}{"start": start, "end": end},
+	).Scan(&stats).Error
+	return stats, err
+}
@@ -0,0 +1,59 @@
+package database
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/clock"
+	"gorm.io/gorm"
+)
+
+// set_entries is split into one partition per month of created_at. date
+// ranged queries should filter on set_entries.created_at so postgres can skip
+// the months outside the range
+
+// EnsureSetEntryPartitions makes sure there is a partition for this month and
+// the next monthsAhead months, so inserts never land in the default partition
+func EnsureSetEntryPartitions(db *gorm.DB, now time.Time, monthsAhead int) error {
+	return createSetEntryPartitions(db, now, now.AddDate(0, monthsAhead, 0))
+}
+
+// StartSetEntryPartitionMaintenance keeps creating upcoming partitions every
+// interval until stop is closed
+func StartSetEntryPartitionMaintenance(db *gorm.DB, interval time.Duration, monthsAhead int, stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := EnsureSetEntryPartitions(db, clock.Now(), monthsAhead); err != nil {
+					log.Printf("error creating set entry partitions: %v", err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+func createSetEntryPartitions(db *gorm.DB, from time.Time, to time.Time) error {
+	month := time.Date(from.Year(), from.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for !month.After(to) {
+		next := month.AddDate(0, 1, 0)
+		err := db.Exec(fmt.Sprintf(
+			"CREATE TABLE IF NOT EXISTS %s PARTITION OF set_entries FOR VALUES FROM ('%s') TO ('%s')",
+			SetEntryPartitionName(month), month.Format(time.RFC3339), next.Format(time.RFC3339),
+		)).Error
+		if err != nil {
+			return err
+		}
+		month = next
+	}
+	return nil
+}
+
+func SetEntryPartitionName(month time.Time) string {
+	return fmt.Sprintf("set_entries_y%04dm%02d", month.Year(), int(month.Month()))
+}
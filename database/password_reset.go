@@ -0,0 +1,63 @@
+package database
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidPasswordResetToken is a token that doesn't exist, was already
+// used or expired
+var ErrInvalidPasswordResetToken = errors.New("invalid or expired password reset token")
+
+// HashPasswordResetToken is what's stored for a token
+func HashPasswordResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func AddPasswordResetToken(db *gorm.DB, t *PasswordResetToken) error {
+	return db.Create(t).Error
+}
+
+// CountPasswordResetTokensSince is how many reset links the user was sent
+// since, to keep their inbox from being flooded
+func CountPasswordResetTokensSince(db *gorm.DB, userId uint, since time.Time) (int64, error) {
+	var count int64
+	err := db.Model(&PasswordResetToken{}).Where("user_id = ? AND created_at > ?", userId, since).Count(&count).Error
+	return count, err
+}
+
+// ResetPassword sets the password of the user the token was sent to and uses
+// the token up, along with every other link they were sent so an older email
+// can't undo the reset. Every session they had is logged out, whoever knew the
+// old password doesn't stay signed in. It's ErrInvalidPasswordResetToken when
+// the token can't be used
+func ResetPassword(db *gorm.DB, tokenHash string, hashedPassword string, now time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var t PasswordResetToken
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, now).
+			Take(&t).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidPasswordResetToken
+		}
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&PasswordResetToken{}).Where("user_id = ? AND used_at IS NULL", t.UserID).Update("used_at", now).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Model(&User{}).Where("id = ?", t.UserID).Update("password", hashedPassword).Error
+		if err != nil {
+			return err
+		}
+		return revokeSessions(tx, t.UserID, now)
+	})
+}
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func AddProgressPhoto(db *gorm.DB, photo *ProgressPhoto) error {
+	return db.Create(photo).Error
+}
+
+func GetProgressPhoto(db *gorm.DB, progressPhotoId string) (*ProgressPhoto, error) {
+	var photo ProgressPhoto
+	result := db.Where("id = ?", progressPhotoId).First(&photo)
+	return &photo, result.Error
+}
+
+// GetProgressPhotos lists the user's uploaded photos newest first, ones that
+// were never uploaded are left out
+func GetProgressPhotos(db *gorm.DB, userId string) ([]ProgressPhoto, error) {
+	var photos []ProgressPhoto
+	result := db.Where("user_id = ? AND uploaded_at IS NOT NULL", userId).Order("taken_on desc, id desc").Find(&photos)
+	return photos, result.Error
+}
+
+func MarkProgressPhotoUploaded(db *gorm.DB, photo *ProgressPhoto, at time.Time) error {
+	return db.Model(photo).Update("uploaded_at", at).Error
+}
+
+// DeleteProgressPhoto hard deletes the photo, the file is gone with it so
+// there's nothing to restore
+func DeleteProgressPhoto(db *gorm.DB, progressPhotoId uint) error {
+	return db.Unscoped().Where("id = ?", progressPhotoId).Delete(&ProgressPhoto{}).Error
+}
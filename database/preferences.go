@@ -0,0 +1,68 @@
+package database
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultUserPreferences are what a user has before changing anything,
+// they're on UTC and get every notification
+func DefaultUserPreferences(userId uint) *UserPreferences {
+	return &UserPreferences{
+		UserID:            userId,
+		TimeZone:          "UTC",
+		WeeklyDigestEmail: true,
+		WeeklyDigestPush:  true,
+	}
+}
+
+// GetUserPreferences falls back to DefaultUserPreferences when the user
+// never changed any
+func GetUserPreferences(db *gorm.DB, userId uint) (*UserPreferences, error) {
+	var p UserPreferences
+	err := db.Where("user_id = ?", userId).Take(&p).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return DefaultUserPreferences(userId), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// SaveUserPreferences adds the user's row the first time and overwrites it
+// after that
+func SaveUserPreferences(db *gorm.DB, p *UserPreferences) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"updated_at", "time_zone", "weekly_digest_email", "weekly_digest_push"}),
+	}).Create(p).Error
+}
+
+// SavePushToken registers the device for the user's push notifications,
+// taking it over from whoever had it before
+func SavePushToken(db *gorm.DB, userId uint, token string) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "token"}},
+		DoUpdates: clause.AssignmentColumns([]string{"updated_at", "user_id"}),
+	}).Create(&PushToken{UserID: userId, Token: token}).Error
+}
+
+// DeletePushToken stops push notifications to the device if it's the user's
+func DeletePushToken(db *gorm.DB, userId uint, token string) (int64, error) {
+	result := db.Unscoped().Where("user_id = ? AND token = ?", userId, token).Delete(&PushToken{})
+	return result.RowsAffected, result.Error
+}
+
+func GetPushTokens(db *gorm.DB, userId uint) ([]string, error) {
+	tokens := []string{}
+	err := db.Model(&PushToken{}).Where("user_id = ?", userId).Order("id").Pluck("token", &tokens).Error
+	return tokens, err
+}
+
+// DeletePushTokens drops tokens the push service says are no good anymore
+func DeletePushTokens(db *gorm.DB, tokens []string) error {
+	return db.Unscoped().Where("token IN ?", tokens).Delete(&PushToken{}).Error
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"gorm.io/gorm"
+)
+
+// days in week order with the routines they point at, routines deleted since
+// come back empty
+func preloadProgramDays(db *gorm.DB) *gorm.DB {
+	return db.
+		Preload("Days", func(db *gorm.DB) *gorm.DB {
+			return db.Order("CASE weekday WHEN 'MONDAY' THEN 1 WHEN 'TUESDAY' THEN 2 WHEN 'WEDNESDAY' THEN 3 WHEN 'THURSDAY' THEN 4 WHEN 'FRIDAY' THEN 5 WHEN 'SATURDAY' THEN 6 ELSE 7 END")
+		}).
+		Preload("Days.WorkoutRoutine")
+}
+
+// AddProgram creates the program with its days, an active one takes over
+// from whichever was active before
+func AddProgram(db *gorm.DB, program *Program) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if program.Active {
+			if err := deactivatePrograms(tx, program.UserID); err != nil {
+				return err
+			}
+		}
+		return tx.Omit("Days.WorkoutRoutine").Create(program).Error
+	})
+}
+
+func GetProgram(db *gorm.DB, programId string) (*Program, error) {
+	var program Program
+	result := preloadProgramDays(db).Where("id = ?", programId).First(&program)
+	return &program, result.Error
+}
+
+// active first, then newest
+func GetPrograms(db *gorm.DB, userId string) ([]Program, error) {
+	var programs []Program
+	result := preloadProgramDays(db).Where("user_id = ?", userId).Order("active DESC, id DESC").Find(&programs)
+	return programs, result.Error
+}
+
+// GetActiveProgram is gorm.ErrRecordNotFound when the user hasn't got one
+func GetActiveProgram(db *gorm.DB, userId string) (*Program, error) {
+	var program Program
+	result := preloadProgramDays(db).Where("user_id = ? AND active", userId).First(&program)
+	return &program, result.Error
+}
+
+// UpdateProgram swaps the program's name, active flag and days for the ones
+// in program
+func UpdateProgram(db *gorm.DB, programId string, program *Program) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if program.Active {
+			if err := deactivatePrograms(tx, program.UserID); err != nil {
+				return err
+			}
+		}
+
+		err := tx.Model(&Program{}).Where("id = ?", programId).Updates(map[string]interface{}{
+			"name":   program.Name,
+			"active": program.Active,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		// old days are hard deleted so the weekday index only sees the new ones
+		if err := tx.Unscoped().Where("program_id = ?", programId).Delete(&ProgramDay{}).Error; err != nil {
+			return err
+		}
+		if len(program.Days) == 0 {
+			return nil
+		}
+		return tx.Omit("WorkoutRoutine").Create(&program.Days).Error
+	})
+}
+
+func DeleteProgram(db *gorm.DB, programId string) error {
+	result := db.Where("id = ?", programId).Delete(&Program{})
+	return result.Error
+}
+
+func deactivatePrograms(tx *gorm.DB, userId uint) error {
+	return tx.Model(&Program{}).Where("user_id = ? AND active", userId).Update("active", false).Error
+}
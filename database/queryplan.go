@@ -0,0 +1,85 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/logger"
+)
+
+// PlanLogger wraps a gorm logger and, when a statement goes over the slow
+// threshold, logs its query plan next to the slow query warning
+type PlanLogger struct {
+	logger.Interface
+	DB            *sql.DB
+	SlowThreshold time.Duration
+}
+
+func NewPlanLogger(l logger.Interface, db *sql.DB, slowThreshold time.Duration) *PlanLogger {
+	return &PlanLogger{
+		Interface:     l,
+		DB:            db,
+		SlowThreshold: slowThreshold,
+	}
+}
+
+func (l *PlanLogger) LogMode(level logger.LogLevel) logger.Interface {
+	return NewPlanLogger(l.Interface.LogMode(level), l.DB, l.SlowThreshold)
+}
+
+func (l *PlanLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	l.Interface.Trace(ctx, begin, fc, err)
+
+	if l.DB == nil || time.Since(begin) < l.SlowThreshold {
+		return
+	}
+
+	statement, _ := fc()
+	if !explainable(statement) {
+		return
+	}
+
+	plan, err := ExplainQuery(ctx, l.DB, statement)
+	if err != nil {
+		l.Interface.Warn(ctx, "could not capture query plan: %v", err)
+		return
+	}
+	l.Interface.Warn(ctx, "query plan for slow query %s\n%s", statement, plan)
+}
+
+// ExplainQuery gets the planner's estimate for a statement. ANALYZE is left
+// off so the statement is never actually run a second time
+func ExplainQuery(ctx context.Context, db *sql.DB, statement string) (string, error) {
+	rows, err := db.QueryContext(ctx, "EXPLAIN "+statement)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	lines := []string{}
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return "", err
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n"), rows.Err()
+}
+
+// only plain dml can be explained, things like BEGIN or SET can't
+func explainable(statement string) bool {
+	fields := strings.Fields(statement)
+	if len(fields) == 0 {
+		return false
+	}
+
+	switch strings.ToUpper(fields[0]) {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "WITH":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,96 @@
+package database
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const queryStatsKey = "QUERY_STATS"
+
+const queryStatsStartKey = "query_stats:start"
+
+// QueryStats adds up the statements run for one request, resolvers run
+// concurrently so it's safe to share
+type QueryStats struct {
+	queries int64
+	nanos   int64
+}
+
+func (s *QueryStats) Queries() int64 {
+	return atomic.LoadInt64(&s.queries)
+}
+
+func (s *QueryStats) Duration() time.Duration {
+	return time.Duration(atomic.LoadInt64(&s.nanos))
+}
+
+// WithQueryStats starts counting the statements run with the returned
+// context
+func WithQueryStats(ctx context.Context) (context.Context, *QueryStats) {
+	stats := &QueryStats{}
+	return context.WithValue(ctx, queryStatsKey, stats), stats
+}
+
+// QueryStatsContext is a context for statements that carries ctx's stats and
+// nothing else. A client going away shouldn't cancel a mutation halfway
+// through, and loaders batch keys from more than one request
+func QueryStatsContext(ctx context.Context) context.Context {
+	stats, ok := ctx.Value(queryStatsKey).(*QueryStats)
+	if !ok {
+		return context.Background()
+	}
+	return context.WithValue(context.Background(), queryStatsKey, stats)
+}
+
+// QueryStatsPlugin counts and times statements whose context has stats,
+// statements without are left alone
+type QueryStatsPlugin struct{}
+
+func (p *QueryStatsPlugin) Name() string {
+	return "query_stats"
+}
+
+func (p *QueryStatsPlugin) Initialize(db *gorm.DB) error {
+	before := func(tx *gorm.DB) {
+		if _, ok := tx.Statement.Context.Value(queryStatsKey).(*QueryStats); ok {
+			tx.InstanceSet(queryStatsStartKey, time.Now())
+		}
+	}
+	after := func(tx *gorm.DB) {
+		stats, ok := tx.Statement.Context.Value(queryStatsKey).(*QueryStats)
+		if !ok {
+			return
+		}
+		start, ok := tx.InstanceGet(queryStatsStartKey)
+		if !ok {
+			return
+		}
+		atomic.AddInt64(&stats.queries, 1)
+		atomic.AddInt64(&stats.nanos, int64(time.Since(start.(time.Time))))
+	}
+
+	cb := db.Callback()
+	callbacks := []error{
+		cb.Create().Before("gorm:create").Register("query_stats:before_create", before),
+		cb.Create().After("gorm:create").Register("query_stats:after_create", after),
+		cb.Query().Before("gorm:query").Register("query_stats:before_query", before),
+		cb.Query().After("gorm:query").Register("query_stats:after_query", after),
+		cb.Update().Before("gorm:update").Register("query_stats:before_update", before),
+		cb.Update().After("gorm:update").Register("query_stats:after_update", after),
+		cb.Delete().Before("gorm:delete").Register("query_stats:before_delete", before),
+		cb.Delete().After("gorm:delete").Register("query_stats:after_delete", after),
+		cb.Row().Before("gorm:row").Register("query_stats:before_row", before),
+		cb.Row().After("gorm:row").Register("query_stats:after_row", after),
+		cb.Raw().Before("gorm:raw").Register("query_stats:before_raw", before),
+		cb.Raw().After("gorm:raw").Register("query_stats:after_raw", after),
+	}
+	for _, err := range callbacks {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
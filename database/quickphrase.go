@@ -0,0 +1,64 @@
+package database
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AddQuickPhrase puts the phrase after the user's others
+func AddQuickPhrase(db *gorm.DB, quickPhrase *QuickPhrase) error {
+	var position uint
+	err := db.Model(&QuickPhrase{}).
+		Where("user_id = ?", quickPhrase.UserID).
+		Select("COALESCE(MAX(position) + 1, 0)").
+		Scan(&position).Error
+	if err != nil {
+		return err
+	}
+	quickPhrase.Position = position
+
+	return db.Create(quickPhrase).Error
+}
+
+func CountQuickPhrases(db *gorm.DB, userId string) (int64, error) {
+	var count int64
+	err := db.Model(&QuickPhrase{}).Where("user_id = ?", userId).Count(&count).Error
+	return count, err
+}
+
+func GetQuickPhrase(db *gorm.DB, quickPhraseId string) (*QuickPhrase, error) {
+	var quickPhrase QuickPhrase
+	result := db.Where("id = ?", quickPhraseId).First(&quickPhrase)
+	return &quickPhrase, result.Error
+}
+
+// in the order the user put them
+func GetQuickPhrases(db *gorm.DB, userId string) ([]QuickPhrase, error) {
+	var quickPhrases []QuickPhrase
+	result := db.Where("user_id = ?", userId).Order("position, id").Find(&quickPhrases)
+	return quickPhrases, result.Error
+}
+
+func UpdateQuickPhrase(db *gorm.DB, quickPhraseId string, text string) (*QuickPhrase, error) {
+	var quickPhrase QuickPhrase
+	result := db.Model(&quickPhrase).Clauses(clause.Returning{}).Where("id = ?", quickPhraseId).Update("text", text)
+	return &quickPhrase, result.Error
+}
+
+func DeleteQuickPhrase(db *gorm.DB, quickPhraseId string) error {
+	result := db.Where("id = ?", quickPhraseId).Delete(&QuickPhrase{})
+	return result.Error
+}
+
+// positions follow the order of the ids, callers make sure they are every
+// quick phrase the user has
+func ReorderQuickPhrases(db *gorm.DB, userId string, orderedIds []string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for i, id := range orderedIds {
+			if err := tx.Model(&QuickPhrase{}).Where("id = ? AND user_id = ?", id, userId).Update("position", i).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,99 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidRefreshToken is a refresh token that was never handed out, or
+// belongs to a family that was logged out
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+// ErrRefreshTokenReused is a refresh token used after it was already swapped
+// for another, its family is revoked
+var ErrRefreshTokenReused = errors.New("refresh token reused")
+
+// RotateRefreshToken swaps the token for next, which joins its family, and
+// updates the family's session with where it was seen from. Using a token
+// that was already swapped revokes the family, so whoever else has the
+// family's latest token gets logged out too
+func RotateRefreshToken(db *gorm.DB, tokenId string, next *RefreshToken, seen *Session, now time.Time) error {
+	reused := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var t RefreshToken
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_id = ?", tokenId).
+			Take(&t).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		if err != nil {
+			return err
+		}
+		if t.RevokedAt != nil {
+			return ErrInvalidRefreshToken
+		}
+		if t.UsedAt != nil {
+			reused = true
+			return revokeRefreshTokenFamily(tx, t.UserID, t.FamilyID, now)
+		}
+
+		err = tx.Model(&t).Update("used_at", now).Error
+		if err != nil {
+			return err
+		}
+		next.UserID = t.UserID
+		next.FamilyID = t.FamilyID
+		err = tx.Create(next).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&Session{}).Where("family_id = ?", t.FamilyID).Updates(map[string]interface{}{
+			"user_agent":         seen.UserAgent,
+			"ip":                 seen.IP,
+			"origin_platform":    seen.Origin.Platform,
+			"origin_app_version": seen.Origin.AppVersion,
+			"origin_device_id":   seen.Origin.DeviceID,
+			"last_used_at":       now,
+		}).Error
+	})
+	if err == nil && reused {
+		return ErrRefreshTokenReused
+	}
+	return err
+}
+
+// RevokeRefreshTokenFamily logs out the session the family came from
+func RevokeRefreshTokenFamily(db *gorm.DB, userId uint, familyId string, now time.Time) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		return revokeRefreshTokenFamily(tx, userId, familyId, now)
+	})
+}
+
+// revokeSessions logs out every session of the user's
+func revokeSessions(db *gorm.DB, userId uint, now time.Time) error {
+	err := db.Model(&RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", now).Error
+	if err != nil {
+		return err
+	}
+	return db.Model(&Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", now).Error
+}
+
+func revokeRefreshTokenFamily(db *gorm.DB, userId uint, familyId string, now time.Time) error {
+	err := db.Model(&RefreshToken{}).
+		Where("user_id = ? AND family_id = ? AND revoked_at IS NULL", userId, familyId).
+		Update("revoked_at", now).Error
+	if err != nil {
+		return err
+	}
+	return db.Model(&Session{}).
+		Where("user_id = ? AND family_id = ? AND revoked_at IS NULL", userId, familyId).
+		Update("revoked_at", now).Error
+}
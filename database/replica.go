@@ -0,0 +1,33 @@
+package database
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/neilZon/workout-logger-api/config"
+	"gorm.io/gorm"
+)
+
+// InitReplicaDb connects to the read replica at DB_REPLICA_HOST, it's nil
+// when that isn't set. Nothing is migrated, the replica follows the primary.
+// Transactions are read only so a write fails even when the host turns out
+// to be the primary
+func InitReplicaDb() (*gorm.DB, error) {
+	host := os.Getenv(config.DB_REPLICA_HOST)
+	if host == "" {
+		return nil, nil
+	}
+	port := os.Getenv(config.DB_REPLICA_PORT)
+	if port == "" {
+		port = os.Getenv("DB_PORT")
+	}
+
+	pool, err := config.DBPoolFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	sandbox := os.Getenv(config.SANDBOX) == "true"
+	DSN := fmt.Sprintf("%s default_transaction_read_only=on", dsn(host, port, pool, sandbox))
+	return open(DSN, pool, "replica")
+}
@@ -0,0 +1,188 @@
+package database
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/clock"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// soft deletes that cascade share one deleted_at so a restore can tell which
+// children went down with the parent and which were deleted on their own
+func cascadeDeleteSession(db *gorm.DB) *gorm.DB {
+	now := clock.Now()
+	return db.Session(&gorm.Session{NowFunc: func() time.Time { return now }})
+}
+
+// Workout Routine
+func GetDeletedWorkoutRoutine(db *gorm.DB, workoutRoutineId string) (*WorkoutRoutine, error) {
+	var wr WorkoutRoutine
+	result := db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", workoutRoutineId).First(&wr)
+	return &wr, result.Error
+}
+
+// checkRestoredWorkoutSessions is checkNoOpenWorkoutSession for the deleted
+// sessions where says are being restored, it has to be called before they
+// are so the restored open session doesn't count
+func checkRestoredWorkoutSessions(tx *gorm.DB, where string, args ...interface{}) error {
+	var userIds []uint
+	err := tx.Unscoped().Model(&WorkoutSession{}).
+		Where(where, args...).
+		Where(`"end" IS NULL`).
+		Limit(1).
+		Pluck("user_id", &userIds).Error
+	if err != nil || len(userIds) == 0 {
+		return err
+	}
+	return checkNoOpenWorkoutSession(tx, userIds[0])
+}
+
+// RestoreWorkoutRoutine is ErrOpenWorkoutSession when a session it brings
+// back is open and the user has started another one since
+func RestoreWorkoutRoutine(db *gorm.DB, workoutRoutineId string, deletedAt time.Time) error {
+	tx := db.Begin()
+	if err := checkRestoredWorkoutSessions(tx, "workout_routine_id = ? AND deleted_at = ?", workoutRoutineId, deletedAt); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Unscoped().Model(&WorkoutRoutine{}).Where("id = ?", workoutRoutineId).Update("deleted_at", nil).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Cascade exercise routines
+	if err := tx.Unscoped().Model(&ExerciseRoutine{}).Where("workout_routine_id = ? AND deleted_at = ?", workoutRoutineId, deletedAt).Update("deleted_at", nil).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Cascade workout sessions
+	var workoutSessions []*WorkoutSession
+	if err := tx.Unscoped().Model(&workoutSessions).Clauses(clause.Returning{}).Where("workout_routine_id = ? AND deleted_at = ?", workoutRoutineId, deletedAt).Update("deleted_at", nil).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var workoutSessionIds []string
+	for _, ws := range workoutSessions {
+		workoutSessionIds = append(workoutSessionIds, fmt.Sprintf("%d", ws.ID))
+	}
+
+	// Cascade exercises
+	var exercises []*Exercise
+	if err := tx.Unscoped().Model(&exercises).Clauses(clause.Returning{}).Where("workout_session_id IN ? AND deleted_at = ?", workoutSessionIds, deletedAt).Update("deleted_at", nil).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	var exerciseIds []string
+	for _, e := range exercises {
+		exerciseIds = append(exerciseIds, fmt.Sprintf("%d", e.ID))
+	}
+
+	// Cascade sets
+	if err := tx.Unscoped().Model(&SetEntry{}).Where("exercise_id IN ? AND deleted_at = ?", exerciseIds, deletedAt).Update("deleted_at", nil).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// Exercise Routine
+func GetDeletedExerciseRoutine(db *gorm.DB, exerciseRoutineId string) (*ExerciseRoutine, error) {
+	var er ExerciseRoutine
+	result := db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", exerciseRoutineId).First(&er)
+	return &er, result.Error
+}
+
+// RestoreExerciseRoutine is gorm.ErrRecordNotFound while its routine is
+// still deleted
+func RestoreExerciseRoutine(db *gorm.DB, exerciseRoutineId string, deletedAt time.Time) error {
+	tx := db.Begin()
+	var exerciseRoutine ExerciseRoutine
+	if err := tx.Unscoped().Model(&exerciseRoutine).Clauses(clause.Returning{}).Where("id = ?", exerciseRoutineId).Update("deleted_at", nil).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := checkNotDeleted(tx, &WorkoutRoutine{}, "id = ?", exerciseRoutine.WorkoutRoutineID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Cascade exercises
+	var exercises []*Exercise
+	if err := tx.Unscoped().Model(&exercises).Clauses(clause.Returning{}).Where("exercise_routine_id = ? AND deleted_at = ?", exerciseRoutineId, deletedAt).Update("deleted_at", nil).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	var exerciseIds []string
+	var workoutSessionIds []uint
+	for _, e := range exercises {
+		exerciseIds = append(exerciseIds, fmt.Sprintf("%d", e.ID))
+		workoutSessionIds = append(workoutSessionIds, e.WorkoutSessionID)
+	}
+
+	// Cascade sets
+	if err := tx.Unscoped().Model(&SetEntry{}).Where("exercise_id IN ? AND deleted_at = ?", exerciseIds, deletedAt).Update("deleted_at", nil).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := touchWorkoutRoutine(tx, exerciseRoutine.WorkoutRoutineID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := touchWorkoutSessions(tx, workoutSessionIds); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// Workout Session
+func GetDeletedWorkoutSession(db *gorm.DB, workoutSessionId string) (*WorkoutSession, error) {
+	var ws WorkoutSession
+	result := db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", workoutSessionId).First(&ws)
+	return &ws, result.Error
+}
+
+// RestoreWorkoutSession is gorm.ErrRecordNotFound while its routine is still
+// deleted, and ErrOpenWorkoutSession when the session is open and the user
+// has started another one since
+func RestoreWorkoutSession(db *gorm.DB, workoutSessionId string, deletedAt time.Time) error {
+	tx := db.Begin()
+	if err := checkNotDeleted(tx, &WorkoutRoutine{}, "id = (SELECT workout_routine_id FROM workout_sessions WHERE id = ?)", workoutSessionId); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := checkRestoredWorkoutSessions(tx, "id = ?", workoutSessionId); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Unscoped().Model(&WorkoutSession{}).Where("id = ?", workoutSessionId).Update("deleted_at", nil).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Cascade exercises
+	var exercises []*Exercise
+	if err := tx.Unscoped().Model(&exercises).Clauses(clause.Returning{}).Where("workout_session_id = ? AND deleted_at = ?", workoutSessionId, deletedAt).Update("deleted_at", nil).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	var exerciseIds []string
+	for _, e := range exercises {
+		exerciseIds = append(exerciseIds, fmt.Sprintf("%d", e.ID))
+	}
+
+	// Cascade sets
+	if err := tx.Unscoped().Model(&SetEntry{}).Where("exercise_id IN ? AND deleted_at = ?", exerciseIds, deletedAt).Update("deleted_at", nil).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
@@ -0,0 +1,54 @@
+package database
+
+import (
+	"gorm.io/gorm"
+)
+
+func AddWorkoutRoutineShareLink(db *gorm.DB, link *WorkoutRoutineShareLink) error {
+	return db.Create(link).Error
+}
+
+// GetWorkoutRoutineShareLinkByRoutine is the link already made for a
+// routine, a routine is only ever shared under one token
+func GetWorkoutRoutineShareLinkByRoutine(db *gorm.DB, workoutRoutineId string) (*WorkoutRoutineShareLink, error) {
+	var link WorkoutRoutineShareLink
+	result := db.Where("workout_routine_id = ?", workoutRoutineId).First(&link)
+	return &link, result.Error
+}
+
+// GetWorkoutRoutineShareLink comes with the routine and its exercise routines
+// in order. The routine is empty once it's been deleted
+func GetWorkoutRoutineShareLink(db *gorm.DB, token string) (*WorkoutRoutineShareLink, error) {
+	var link WorkoutRoutineShareLink
+	result := db.
+		Preload("WorkoutRoutine.ExerciseRoutines", func(db *gorm.DB) *gorm.DB {
+			return db.Order("position, id")
+		}).
+		Preload("WorkoutRoutine.ExerciseRoutines.CatalogExercise.MuscleGroups", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		}).
+		Where("token = ?", token).
+		First(&link)
+	return &link, result.Error
+}
+
+// CopyWorkoutRoutine makes a new routine for userId with the same exercise
+// routines, nothing that was logged against the original comes with it
+func CopyWorkoutRoutine(db *gorm.DB, workoutRoutine *WorkoutRoutine, userId uint) (*WorkoutRoutine, error) {
+	copied := WorkoutRoutine{
+		Name:             workoutRoutine.Name,
+		ExerciseRoutines: make([]ExerciseRoutine, 0, len(workoutRoutine.ExerciseRoutines)),
+		UserID:           userId,
+	}
+	for _, er := range workoutRoutine.ExerciseRoutines {
+		copied.ExerciseRoutines = append(copied.ExerciseRoutines, ExerciseRoutine{
+			Name:              er.Name,
+			Sets:              er.Sets,
+			Reps:              er.Reps,
+			Position:          uint(len(copied.ExerciseRoutines)),
+			CatalogExerciseID: er.CatalogExerciseID,
+		})
+	}
+	err := db.Create(&copied).Error
+	return &copied, err
+}
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/config"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// tables ResetSandbox leaves alone, the shared exercise catalog and the users
+// table, where the admins are kept
+var sandboxKeptTables = map[string]bool{
+	"users":                          true,
+	"catalog_exercises":              true,
+	"catalog_exercise_muscle_groups": true,
+}
+
+// sandboxTables are the tables of every model but the kept ones, so a new
+// model is wiped along with the rest without being listed here
+func sandboxTables(db *gorm.DB) ([]string, error) {
+	tables := []string{}
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		if !sandboxKeptTables[stmt.Schema.Table] {
+			tables = append(tables, stmt.Schema.Table)
+		}
+	}
+	return tables, nil
+}
+
+// ResetSandbox wipes everything in the sandbox schema except admins and
+// seeds it again. Sessions go too, the admin resetting it logs in again
+// like everyone else
+func ResetSandbox(db *gorm.DB) error {
+	tables, err := sandboxTables(db)
+	if err != nil {
+		return err
+	}
+
+	tx := db.Begin()
+	// one statement for all of them, so foreign keys between them don't
+	// decide the order
+	if err := tx.Exec(fmt.Sprintf(`TRUNCATE "%s" CASCADE`, strings.Join(tables, `", "`))).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Unscoped().Where("admin = ?", false).Delete(&User{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := SeedSandbox(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// SeedSandbox creates a verified demo user with a routine and a few weeks of
+// progressing sessions to build against
+func SeedSandbox(db *gorm.DB) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(config.SANDBOX_USER_PASSWORD), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	user := User{
+		Name:     "Sandbox Lifter",
+		Email:    config.SANDBOX_USER_EMAIL,
+		Password: string(hashedPassword),
+		Verified: true,
+	}
+	if err := db.Create(&user).Error; err != nil {
+		return err
+	}
+
+	workoutRoutine := WorkoutRoutine{
+		Name:   "Push",
+		UserID: user.ID,
+		ExerciseRoutines: []ExerciseRoutine{
+			{Name: "Bench Press", Sets: 3, Reps: 8},
+			{Name: "Overhead Press", Sets: 3, Reps: 10},
+			{Name: "Dips", Sets: 3, Reps: 12},
+		},
+	}
+	if err := db.Create(&workoutRoutine).Error; err != nil {
+		return err
+	}
+
+	// one session a week for the last four weeks, adding weight each time
+	startingWeights := []float64{135, 85, 0}
+	for week := 0; week < 4; week++ {
+		start := time.Now().AddDate(0, 0, -7*(4-week))
+		end := start.Add(time.Hour)
+
+		var exercises []Exercise
+		for i, er := range workoutRoutine.ExerciseRoutines {
+			var sets []SetEntry
+			for s := uint(0); s < er.Sets; s++ {
+				sets = append(sets, SetEntry{
+					Weight:   startingWeights[i] + float64(5*week),
+					Unit:     WeightUnitLb,
+					Reps:     er.Reps,
+					SetOrder: s + 1,
+					Type:     SetTypeWorking,
+				})
+			}
+			exercises = append(exercises, Exercise{
+				ExerciseRoutineID: er.ID,
+				UserID:            user.ID,
+				Sets:              sets,
+			})
+		}
+
+		workoutSession := WorkoutSession{
+			Start:            start,
+			End:              &end,
+			WorkoutRoutineID: workoutRoutine.ID,
+			UserID:           user.ID,
+			Exercises:        exercises,
+		}
+		if err := AddWorkoutSession(db, &workoutSession); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package database
+
+import (
+	"gorm.io/gorm"
+)
+
+// search matches words with english stemming, so "pressing" finds "Bench
+// Press". The baseline migration indexes the same expressions the query uses
+// so postgres picks them up. Session tags are aggregated per session so they
+// can't be indexed, there are only ever a few per session though
+
+// kinds of rows a search hit points at
+const (
+	SearchHitWorkoutRoutine  = "workout_routine"
+	SearchHitExerciseRoutine = "exercise_routine"
+	SearchHitExercise        = "exercise"
+	SearchHitWorkoutSession  = "workout_session"
+)
+
+type SearchHit struct {
+	Kind string
+	ID   uint
+	Rank float64
+}
+
+// Search ranks the user's routine names, exercise routine names, exercise
+// notes and session notes and tags against query, most relevant first
+func Search(db *gorm.DB, userId string, query string, limit int) ([]SearchHit, error) {
+	var hits []SearchHit
+	err := db.Raw(`WITH q AS (SELECT plainto_tsquery('english', @query) AS query)
+		SELECT kind, id, rank FROM (
+			SELECT 'workout_routine' AS kind, workout_routines.id, ts_rank(to_tsvector('english', workout_routines.name), q.query) AS rank
+			FROM workout_routines CROSS JOIN q
+			WHERE workout_routines.user_id = @user AND workout_routines.deleted_at IS NULL
+			AND to_tsvector('english', workout_routines.name) @@ q.query
+			UNION ALL
+			SELECT 'exercise_routine', exercise_routines.id, ts_rank(to_tsvector('english', exercise_routines.name), q.query)
+			FROM exercise_routines
+			JOIN workout_routines ON workout_routines.id = exercise_routines.workout_routine_id
+			CROSS JOIN q
+			WHERE workout_routines.user_id = @user AND exercise_routines.deleted_at IS NULL AND workout_routines.deleted_at IS NULL
+			AND to_tsvector('english', exercise_routines.name) @@ q.query
+			UNION ALL
+			SELECT 'exercise', exercises.id, ts_rank(to_tsvector('english', exercises.notes), q.query)
+			FROM exercises
+			JOIN workout_sessions ON workout_sessions.id = exercises.workout_session_id
+			CROSS JOIN q
+			WHERE workout_sessions.user_id = @user AND exercises.deleted_at IS NULL AND workout_sessions.deleted_at IS NULL
+			AND to_tsvector('english', exercises.notes) @@ q.query
+			UNION ALL
+			SELECT 'workout_session', workout_sessions.id, ts_rank(to_tsvector('english', workout_sessions.notes || ' ' || COALESCE(STRING_AGG(tags.name, ' '), '')), q.query)
+			FROM workout_sessions
+			LEFT JOIN workout_session_tags ON workout_session_tags.workout_session_id = workout_sessions.id
+			LEFT JOIN tags ON tags.id = workout_session_tags.tag_id
+			CROSS JOIN q
+			WHERE workout_sessions.user_id = @user AND workout_sessions.deleted_at IS NULL
+			GROUP BY workout_sessions.id, q.query
+			HAVING to_tsvector('english', workout_sessions.notes || ' ' || COALESCE(STRING_AGG(tags.name, ' '), '')) @@ q.query
+		) hits ORDER BY rank DESC, kind, id DESC LIMIT @limit`,
+		map[string]interface{}{"query": query, "user": userId, "limit": limit}).
+		Scan(&hits).Error
+	return hits, err
+}
+
+// SearchRows are the rows a page of search hits point at, by id
+type SearchRows struct {
+	WorkoutRoutines  map[uint]WorkoutRoutine
+	ExerciseRoutines map[uint]ExerciseRoutine
+	Exercises        map[uint]Exercise
+	WorkoutSessions  map[uint]WorkoutSession
+}
+
+// GetSearchRows looks up what hits point at with one query per kind that
+// has hits
+func GetSearchRows(db *gorm.DB, hits []SearchHit) (*SearchRows, error) {
+	ids := map[string][]uint{}
+	for _, hit := range hits {
+		ids[hit.Kind] = append(ids[hit.Kind], hit.ID)
+	}
+
+	rows := SearchRows{
+		WorkoutRoutines:  map[uint]WorkoutRoutine{},
+		ExerciseRoutines: map[uint]ExerciseRoutine{},
+		Exercises:        map[uint]Exercise{},
+		WorkoutSessions:  map[uint]WorkoutSession{},
+	}
+	if len(ids[SearchHitWorkoutRoutine]) > 0 {
+		var workoutRoutines []WorkoutRoutine
+		if err := db.Where("id IN ?", ids[SearchHitWorkoutRoutine]).Find(&workoutRoutines).Error; err != nil {
+			return nil, err
+		}
+		for _, workoutRoutine := range workoutRoutines {
+			rows.WorkoutRoutines[workoutRoutine.ID] = workoutRoutine
+		}
+	}
+	if len(ids[SearchHitExerciseRoutine]) > 0 {
+		var exerciseRoutines []ExerciseRoutine
+		if err := db.Where("id IN ?", ids[SearchHitExerciseRoutine]).Find(&exerciseRoutines).Error; err != nil {
+			return nil, err
+		}
+		for _, exerciseRoutine := range exerciseRoutines {
+			rows.ExerciseRoutines[exerciseRoutine.ID] = exerciseRoutine
+		}
+	}
+	if len(ids[SearchHitExercise]) > 0 {
+		var exercises []Exercise
+		if err := db.Where("id IN ?", ids[SearchHitExercise]).Find(&exercises).Error; err != nil {
+			return nil, err
+		}
+		for _, exercise := range exercises {
+			rows.Exercises[exercise.ID] = exercise
+		}
+	}
+	if len(ids[SearchHitWorkoutSession]) > 0 {
+		var workoutSessions []WorkoutSession
+		if err := db.Where("id IN ?", ids[SearchHitWorkoutSession]).Find(&workoutSessions).Error; err != nil {
+			return nil, err
+		}
+		for _, workoutSession := range workoutSessions {
+			rows.WorkoutSessions[workoutSession.ID] = workoutSession
+		}
+	}
+	return &rows, nil
+}
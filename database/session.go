@@ -0,0 +1,42 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StartSession adds the session along with the first refresh token of its
+// family
+func StartSession(db *gorm.DB, s *Session, t *RefreshToken) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(s).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(t).Error
+	})
+}
+
+func GetSession(db *gorm.DB, id string) (*Session, error) {
+	var s Session
+	err := db.Where("id = ?", id).First(&s).Error
+	return &s, err
+}
+
+// GetSessionByFamily is the session of a login, revoked or not
+func GetSessionByFamily(db *gorm.DB, familyId string) (*Session, error) {
+	var s Session
+	err := db.Where("family_id = ?", familyId).First(&s).Error
+	return &s, err
+}
+
+// GetActiveSessions are the user's sessions that weren't revoked and were
+// refreshed since, most recently used first
+func GetActiveSessions(db *gorm.DB, userId uint, since time.Time) ([]Session, error) {
+	var sessions []Session
+	err := db.Where("user_id = ? AND revoked_at IS NULL AND last_used_at > ?", userId, since).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
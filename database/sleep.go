@@ -0,0 +1,30 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UpsertSleepLog replaces the hours logged for that night
+func UpsertSleepLog(db *gorm.DB, sleepLog *SleepLog) error {
+	result := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "night"}},
+		DoUpdates: clause.AssignmentColumns([]string{"hours", "updated_at", "deleted_at"}),
+	}).Create(sleepLog)
+	return result.Error
+}
+
+// nights in [start, end), oldest first
+func GetSleepLogs(db *gorm.DB, userId string, start time.Time, end time.Time) ([]SleepLog, error) {
+	var sleepLogs []SleepLog
+	result := db.Where("user_id = ? AND night >= ? AND night < ?", userId, start, end).Order("night").Find(&sleepLogs)
+	return sleepLogs, result.Error
+}
+
+func GetAllSleepLogs(db *gorm.DB, userId string) ([]SleepLog, error) {
+	var sleepLogs []SleepLog
+	result := db.Where("user_id = ?", userId).Order("night").Find(&sleepLogs)
+	return sleepLogs, result.Error
+}
@@ -0,0 +1,441 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/config"
+	"gorm.io/gorm"
+)
+
+// SnapshotData is everything a snapshot keeps, the rows are stored as they
+// were so ids can be matched up against the account when merging
+type SnapshotData struct {
+	TakenAt           time.Time
+	WorkoutRoutines   []WorkoutRoutine
+	WorkoutSessions   []WorkoutSession
+	BodyWeightEntries []BodyWeightEntry
+	Goals             []Goal
+	NutritionLogs     []NutritionLog
+	SleepLogs         []SleepLog
+	QuickPhrases      []QuickPhrase
+}
+
+func AddSnapshot(db *gorm.DB, snapshot *Snapshot) error {
+	result := db.Create(snapshot)
+	return result.Error
+}
+
+func GetSnapshot(db *gorm.DB, snapshotId string) (*Snapshot, error) {
+	var snapshot Snapshot
+	result := db.Where("id = ?", snapshotId).First(&snapshot)
+	return &snapshot, result.Error
+}
+
+// newest first
+func GetSnapshots(db *gorm.DB, userId string) ([]Snapshot, error) {
+	var snapshots []Snapshot
+	result := db.Where("user_id = ?", userId).Order("id DESC").Find(&snapshots)
+	return snapshots, result.Error
+}
+
+func CountSnapshots(db *gorm.DB, userId string) (int64, error) {
+	var count int64
+	err := db.Model(&Snapshot{}).Where("user_id = ?", userId).Count(&count).Error
+	return count, err
+}
+
+// DeleteSnapshot hard deletes the row, the caller removes the blob
+func DeleteSnapshot(db *gorm.DB, snapshotId string) error {
+	result := db.Unscoped().Where("id = ?", snapshotId).Delete(&Snapshot{})
+	return result.Error
+}
+
+// GetSnapshotWorkoutSessions is every session userId owns with everything
+// logged in it, co-loggers' exercises included since they go with the session
+func GetSnapshotWorkoutSessions(db *gorm.DB, userId string) ([]WorkoutSession, error) {
+	var workoutSessions []WorkoutSession
+	result := db.
+		Preload("Exercises", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		}).
+		Preload("Exercises.Sets", func(db *gorm.DB) *gorm.DB {
+			return db.Order("set_order, id")
+		}).
+		Where("user_id = ?", userId).
+		Order("start, id").
+		Find(&workoutSessions)
+	return workoutSessions, result.Error
+}
+
+// RestoreSnapshot puts data back into the snapshot's account in one
+// transaction. Merging only adds back what's gone from the account, replacing
+// deletes what the account has first. Either way restored rows get new ids,
+// deletes share a deleted_at so a replaced routine can still be restored
+func RestoreSnapshot(db *gorm.DB, snapshot *Snapshot, data *SnapshotData, replace bool, now time.Time) error {
+	user := map[string]interface{}{"user": snapshot.UserID}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if replace {
+			if err := clearSnapshotData(cascadeDeleteSession(tx), user); err != nil {
+				return err
+			}
+		}
+
+		r := snapshotRestore{
+			tx:                 tx,
+			user:               user,
+			userId:             snapshot.UserID,
+			workoutRoutineIds:  make(map[uint]uint),
+			exerciseRoutineIds: make(map[uint]uint),
+		}
+		steps := []func(*SnapshotData) error{
+			r.workoutRoutines,
+			r.workoutSessions,
+			r.bodyWeightEntries,
+			r.goals,
+			r.nutritionLogs,
+			r.sleepLogs,
+			r.quickPhrases,
+		}
+		for _, step := range steps {
+			if err := step(data); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(snapshot).Update("restored_at", now).Error; err != nil {
+			return err
+		}
+		snapshot.RestoredAt = &now
+		return nil
+	})
+}
+
+// clearSnapshotData soft deletes everything a snapshot holds, children first
+func clearSnapshotData(tx *gorm.DB, user map[string]interface{}) error {
+	deletes := []struct {
+		model interface{}
+		where string
+	}{
+		{&SetEntry{}, "exercise_id IN (SELECT id FROM exercises WHERE workout_session_id IN (" + ownedWorkoutSessions + "))"},
+		{&Exercise{}, "workout_session_id IN (" + ownedWorkoutSessions + ")"},
+		{&WorkoutSession{}, "user_id = @user"},
+		{&ExerciseRoutine{}, "workout_routine_id IN (SELECT id FROM workout_routines WHERE user_id = @user)"},
+		{&WorkoutRoutine{}, "user_id = @user"},
+		{&BodyWeightEntry{}, "user_id = @user"},
+		{&Goal{}, "user_id = @user"},
+		{&NutritionLog{}, "user_id = @user"},
+		{&SleepLog{}, "user_id = @user"},
+		{&QuickPhrase{}, "user_id = @user"},
+	}
+	for _, d := range deletes {
+		if err := tx.Where(d.where, user).Delete(d.model).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshotRestore keeps track of the ids restored routines were given so the
+// sessions logged against them can be pointed at the new ones
+type snapshotRestore struct {
+	tx                 *gorm.DB
+	user               map[string]interface{}
+	userId             uint
+	workoutRoutineIds  map[uint]uint
+	exerciseRoutineIds map[uint]uint
+}
+
+// liveIds is the ids of model that are in the account right now
+func (r *snapshotRestore) liveIds(model interface{}, where string) (map[uint]bool, error) {
+	var ids []uint
+	if err := r.tx.Model(model).Where(where, r.user).Pluck("id", &ids).Error; err != nil {
+		return nil, err
+	}
+	live := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		live[id] = true
+	}
+	return live, nil
+}
+
+// ids of routines that weren't restored stay as they were, rows that point
+// at a deleted routine keep pointing at it
+func restoredId(ids map[uint]uint, id uint) uint {
+	if restored, ok := ids[id]; ok {
+		return restored
+	}
+	return id
+}
+
+func (r *snapshotRestore) workoutRoutines(data *SnapshotData) error {
+	liveWorkoutRoutines, err := r.liveIds(&WorkoutRoutine{}, "user_id = @user")
+	if err != nil {
+		return err
+	}
+	liveExerciseRoutines, err := r.liveIds(&ExerciseRoutine{}, "workout_routine_id IN (SELECT id FROM workout_routines WHERE user_id = @user AND deleted_at IS NULL)")
+	if err != nil {
+		return err
+	}
+
+	for _, wr := range data.WorkoutRoutines {
+		r.workoutRoutineIds[wr.ID] = wr.ID
+		if !liveWorkoutRoutines[wr.ID] {
+			workoutRoutine := WorkoutRoutine{
+				Model:  gorm.Model{CreatedAt: wr.CreatedAt},
+				Name:   wr.Name,
+				Active: wr.Active,
+				UserID: r.userId,
+			}
+			if err := r.tx.Create(&workoutRoutine).Error; err != nil {
+				return err
+			}
+			r.workoutRoutineIds[wr.ID] = workoutRoutine.ID
+		}
+
+		for _, er := range wr.ExerciseRoutines {
+			r.exerciseRoutineIds[er.ID] = er.ID
+			if liveExerciseRoutines[er.ID] {
+				continue
+			}
+			exerciseRoutine := ExerciseRoutine{
+				Model:             gorm.Model{CreatedAt: er.CreatedAt},
+				Name:              er.Name,
+				Sets:              er.Sets,
+				Reps:              er.Reps,
+				Active:            er.Active,
+				WorkoutRoutineID:  r.workoutRoutineIds[wr.ID],
+				Position:          er.Position,
+				CatalogExerciseID: er.CatalogExerciseID,
+			}
+			if err := r.tx.Create(&exerciseRoutine).Error; err != nil {
+				return err
+			}
+			r.exerciseRoutineIds[er.ID] = exerciseRoutine.ID
+		}
+	}
+	return nil
+}
+
+func (r *snapshotRestore) workoutSessions(data *SnapshotData) error {
+	live, err := r.liveIds(&WorkoutSession{}, "user_id = @user")
+	if err != nil {
+		return err
+	}
+
+	for _, ws := range data.WorkoutSessions {
+		if live[ws.ID] {
+			continue
+		}
+
+		// a session that was open when the snapshot was taken can only come
+		// back open if nothing else is
+		end := ws.End
+		if end == nil {
+			err := checkNoOpenWorkoutSession(r.tx, r.userId)
+			if errors.Is(err, ErrOpenWorkoutSession) {
+				last := lastSetTime(&ws)
+				end = &last
+			} else if err != nil {
+				return err
+			}
+		}
+
+		workoutSession := WorkoutSession{
+			Model:            gorm.Model{CreatedAt: ws.CreatedAt},
+			Start:            ws.Start,
+			End:              end,
+			WorkoutRoutineID: restoredId(r.workoutRoutineIds, ws.WorkoutRoutineID),
+			UserID:           r.userId,
+			ClientMetadata:   ws.ClientMetadata,
+			Origin:           ws.Origin,
+		}
+		for _, e := range ws.Exercises {
+			exercise := Exercise{
+				Model:             gorm.Model{CreatedAt: e.CreatedAt},
+				Notes:             e.Notes,
+				ExerciseRoutineID: restoredId(r.exerciseRoutineIds, e.ExerciseRoutineID),
+				UserID:            e.UserID,
+			}
+			for _, s := range e.Sets {
+				exercise.Sets = append(exercise.Sets, SetEntry{
+					Model:           gorm.Model{CreatedAt: s.CreatedAt},
+					Weight:          s.Weight,
+					Unit:            s.Unit,
+					Reps:            s.Reps,
+					SetOrder:        s.SetOrder,
+					Type:            s.Type,
+					RestTimeSeconds: s.RestTimeSeconds,
+					CompletedAt:     s.CompletedAt,
+					ClientMetadata:  s.ClientMetadata,
+					Origin:          s.Origin,
+				})
+			}
+			workoutSession.Exercises = append(workoutSession.Exercises, exercise)
+		}
+		if err := r.tx.Create(&workoutSession).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lastSetTime is when the session's last set was done or logged, the same
+// end the auto finish job would give it
+func lastSetTime(ws *WorkoutSession) time.Time {
+	last := ws.Start
+	for _, e := range ws.Exercises {
+		for _, s := range e.Sets {
+			at := s.CreatedAt
+			if s.CompletedAt != nil {
+				at = *s.CompletedAt
+			}
+			if at.After(last) {
+				last = at
+			}
+		}
+	}
+	return last
+}
+
+func (r *snapshotRestore) bodyWeightEntries(data *SnapshotData) error {
+	live, err := r.liveIds(&BodyWeightEntry{}, "user_id = @user")
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range data.BodyWeightEntries {
+		if live[entry.ID] {
+			continue
+		}
+		err := r.tx.Create(&BodyWeightEntry{
+			Model:    gorm.Model{CreatedAt: entry.CreatedAt},
+			UserID:   r.userId,
+			Weight:   entry.Weight,
+			LoggedAt: entry.LoggedAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *snapshotRestore) goals(data *SnapshotData) error {
+	live, err := r.liveIds(&Goal{}, "user_id = @user")
+	if err != nil {
+		return err
+	}
+
+	for _, g := range data.Goals {
+		if live[g.ID] {
+			continue
+		}
+		err := r.tx.Create(&Goal{
+			Model:             gorm.Model{CreatedAt: g.CreatedAt},
+			UserID:            r.userId,
+			Type:              g.Type,
+			TargetValue:       g.TargetValue,
+			StartValue:        g.StartValue,
+			CatalogExerciseID: g.CatalogExerciseID,
+			Deadline:          g.Deadline,
+			CompletedAt:       g.CompletedAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// days the account already has are kept, deleted ones come back through the
+// upsert since the unique index counts them
+func (r *snapshotRestore) nutritionLogs(data *SnapshotData) error {
+	var days []time.Time
+	if err := r.tx.Model(&NutritionLog{}).Where("user_id = @user", r.user).Pluck("day", &days).Error; err != nil {
+		return err
+	}
+	live := make(map[string]bool, len(days))
+	for _, day := range days {
+		live[day.Format("2006-01-02")] = true
+	}
+
+	for _, n := range data.NutritionLogs {
+		if live[n.Day.Format("2006-01-02")] {
+			continue
+		}
+		err := UpsertNutritionLog(r.tx, &NutritionLog{
+			UserID:       r.userId,
+			Day:          n.Day,
+			Calories:     n.Calories,
+			ProteinGrams: n.ProteinGrams,
+			Notes:        n.Notes,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *snapshotRestore) sleepLogs(data *SnapshotData) error {
+	var nights []time.Time
+	if err := r.tx.Model(&SleepLog{}).Where("user_id = @user", r.user).Pluck("night", &nights).Error; err != nil {
+		return err
+	}
+	live := make(map[string]bool, len(nights))
+	for _, night := range nights {
+		live[night.Format("2006-01-02")] = true
+	}
+
+	for _, s := range data.SleepLogs {
+		if live[s.Night.Format("2006-01-02")] {
+			continue
+		}
+		err := UpsertSleepLog(r.tx, &SleepLog{
+			UserID: r.userId,
+			Night:  s.Night,
+			Hours:  s.Hours,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// phrases the account already has aren't doubled up, and the ones that don't
+// fit under MAX_QUICK_PHRASES are left out
+func (r *snapshotRestore) quickPhrases(data *SnapshotData) error {
+	var texts []string
+	if err := r.tx.Model(&QuickPhrase{}).Where("user_id = @user", r.user).Order("position, id").Pluck("text", &texts).Error; err != nil {
+		return err
+	}
+	live := make(map[string]bool, len(texts))
+	for _, text := range texts {
+		live[text] = true
+	}
+
+	position := uint(len(texts))
+	for _, qp := range data.QuickPhrases {
+		if live[qp.Text] {
+			continue
+		}
+		if position >= config.MAX_QUICK_PHRASES {
+			return nil
+		}
+		err := r.tx.Create(&QuickPhrase{
+			Model:    gorm.Model{CreatedAt: qp.CreatedAt},
+			UserID:   r.userId,
+			Text:     qp.Text,
+			Position: position,
+		}).Error
+		if err != nil {
+			return err
+		}
+		live[qp.Text] = true
+		position++
+	}
+	return nil
+}
@@ -0,0 +1,293 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// aggregate rows scanned from the stats queries
+type WorkoutStats struct {
+	TotalVolume  float64
+	TotalSets    int
+	SessionCount int
+}
+
+type ExerciseRoutineStats struct {
+	ExerciseRoutineID  uint
+	Name               string
+	Sets               int
+	Volume             float64
+	AverageRestSeconds *float64 // nil when no rest was logged
+}
+
+// Stats
+// only counts what userId logged so co-logged sessions are attributed to
+// each participant separately. warm up sets don't count towards volume, which
+// is in unit whatever the sets were logged in.
+// sets can't be logged before their session starts, so the range start also
+// bounds set_entries.created_at and lets postgres skip older partitions
+func GetWorkoutStats(db *gorm.DB, workoutRoutineId string, userId string, start time.Time, end time.Time, unit string) (*WorkoutStats, error) {
+	stats := WorkoutStats{}
+	err := db.Raw(`
+		SELECT COUNT(DISTINCT workout_sessions.id) AS session_count,
+			COUNT(set_entries.id) AS total_sets,
+			COALESCE(SUM(`+setWeight(unit)+` * set_entries.reps), 0) AS total_volume
+		FROM workout_sessions
+			LEFT JOIN exercises ON exercises.workout_session_id = workout_sessions.id AND exercises.user_id = ? AND exercises.deleted_at IS NULL
+			LEFT JOIN set_entries ON set_entries.exercise_id = exercises.id AND set_entries.type <> 'WARMUP' AND set_entries.created_at >= ? AND set_entries.deleted_at IS NULL
+		WHERE workout_sessions.workout_routine_id = ? AND workout_sessions.start >= ? AND workout_sessions.start < ? AND workout_sessions.deleted_at IS NULL
+			AND (workout_sessions.user_id = ? OR exercises.id IS NOT NULL)`,
+		userId, start, workoutRoutineId, start, end, userId,
+	).Scan(&stats).Error
+	return &stats, err
+}
+
+func GetExerciseRoutineStats(db *gorm.DB, workoutRoutineId string, userId string, start time.Time, end time.Time, unit string) ([]ExerciseRoutineStats, error) {
+	stats := []ExerciseRoutineStats{}
+	err := db.Raw(`
+		SELECT exercise_routines.id AS exercise_routine_id,
+			exercise_routines.name AS name,
+			COUNT(set_entries.id) AS sets,
+			COALESCE(SUM(`+setWeight(unit)+` * set_entries.reps), 0) AS volume,
+			AVG(set_entries.rest_time_seconds) AS average_rest_seconds
+		FROM workout_sessions
+			JOIN exercises ON exercises.workout_session_id = workout_sessions.id AND exercises.user_id = ? AND exercises.deleted_at IS NULL
+			JOIN exercise_routines ON exercise_routines.id = exercises.exercise_routine_id
+			LEFT JOIN set_entries ON set_entries.exercise_id = exercises.id AND set_entries.type <> 'WARMUP' AND set_entries.created_at >= ? AND set_entries.deleted_at IS NULL
+		WHERE workout_sessions.workout_routine_id = ? AND workout_sessions.start >= ? AND workout_sessions.start < ? AND workout_sessions.deleted_at IS NULL
+		GROUP BY exercise_routines.id, exercise_routines.name
+		ORDER BY exercise_routines.id`,
+		userId, start, workoutRoutineId, start, end,
+	).Scan(&stats).Error
+	return stats, err
+}
+
+type WeeklyStats struct {
+	WeekStart    time.Time
+	SessionCount int
+	TotalSets    int
+	TotalVolume  float64
+}
+
+// days to push a time forward so postgres' monday weeks line up with the
+// user's, e.g. a sunday plus a day lands on the monday starting its week
+var weekStartOffsetDays = map[string]int{
+	WeekStartMonday:   0,
+	WeekStartSunday:   1,
+	WeekStartSaturday: 2,
+}
+
+// weekBucketSQL is the start of the week column falls in, it takes the offset
+// from weekStartOffsetDays twice. date_trunc('week') always goes back to a
+// monday, so the time is shifted into a week that does and back again.
+// weeks are cut in the database's time zone
+func weekBucketSQL(column string) string {
+	return "date_trunc('week', " + column + " + ? * interval '1 day') - ? * interval '1 day'"
+}
+
+// GetWeeklyStats breaks GetWorkoutStats down by the weeks the sessions
+// started in, for weeks starting on weekStart
+func GetWeeklyStats(db *gorm.DB, workoutRoutineId string, userId string, start time.Time, end time.Time, weekStart string, unit string) ([]WeeklyStats, error) {
+	offset := weekStartOffsetDays[weekStart]
+	stats := []WeeklyStats{}
+	err := db.Raw(`
+		SELECT `+weekBucketSQL("workout_sessions.start")+` AS week_start,
+			COUNT(DISTINCT workout_sessions.id) AS session_count,
+			COUNT(set_entries.id) AS total_sets,
+			COALESCE(SUM(`+setWeight(unit)+` * set_entries.reps), 0) AS total_volume
+		FROM workout_sessions
+			LEFT JOIN exercises ON exercises.workout_session_id = workout_sessions.id AND exercises.user_id = ? AND exercises.deleted_at IS NULL
+			LEFT JOIN set_entries ON set_entries.exercise_id = exercises.id AND set_entries.type <> 'WARMUP' AND set_entries.created_at >= ? AND set_entries.deleted_at IS NULL
+		WHERE workout_sessions.workout_routine_id = ? AND workout_sessions.start >= ? AND workout_sessions.start < ? AND workout_sessions.deleted_at IS NULL
+			AND (workout_sessions.user_id = ? OR exercises.id IS NOT NULL)
+		GROUP BY week_start
+		ORDER BY week_start`,
+		offset, offset, userId, start, workoutRoutineId, start, end, userId,
+	).Scan(&stats).Error
+	return stats, err
+}
+
+type MuscleGroupStats struct {
+	MuscleGroup string
+	Sets        int
+	Volume      float64
+}
+
+// sets are counted once, towards the primary muscle group of the catalog
+// exercise their exercise routine references. routines without one are left out
+func GetMuscleGroupStats(db *gorm.DB, workoutRoutineId string, userId string, start time.Time, end time.Time, unit string) ([]MuscleGroupStats, error) {
+	stats := []MuscleGroupStats{}
+	err := db.Raw(`
+		SELECT catalog_exercise_muscle_groups.muscle_group AS muscle_group,
+			COUNT(set_entries.id) AS sets,
+			COALESCE(SUM(`+setWeight(unit)+` * set_entries.reps), 0) AS volume
+		FROM workout_sessions
+			JOIN exercises ON exercises.workout_session_id = workout_sessions.id AND exercises.user_id = ? AND exercises.deleted_at IS NULL
+			JOIN exercise_routines ON exercise_routines.id = exercises.exercise_routine_id
+			JOIN catalog_exercise_muscle_groups ON catalog_exercise_muscle_groups.catalog_exercise_id = exercise_routines.catalog_exercise_id AND catalog_exercise_muscle_groups.is_primary AND catalog_exercise_muscle_groups.deleted_at IS NULL
+			LEFT JOIN set_entries ON set_entries.exercise_id = exercises.id AND set_entries.type <> 'WARMUP' AND set_entries.created_at >= ? AND set_entries.deleted_at IS NULL
+		WHERE workout_sessions.workout_routine_id = ? AND workout_sessions.start >= ? AND workout_sessions.start < ? AND workout_sessions.deleted_at IS NULL
+		GROUP BY catalog_exercise_muscle_groups.muscle_group
+		ORDER BY catalog_exercise_muscle_groups.muscle_group`,
+		userId, start, workoutRoutineId, start, end,
+	).Scan(&stats).Error
+	return stats, err
+}
+
+// GetTrainingTotals is GetWorkoutStats across every routine userId logged
+// sessions of between start and end
+func GetTrainingTotals(db *gorm.DB, userId string, start time.Time, end time.Time, unit string) (*WorkoutStats, error) {
+	stats := WorkoutStats{}
+	err := db.Raw(`
+		SELECT COUNT(DISTINCT workout_sessions.id) AS session_count,
+			COUNT(set_entries.id) AS total_sets,
+			COALESCE(SUM(`+setWeight(unit)+` * set_entries.reps), 0) AS total_volume
+		FROM workout_sessions
+			LEFT JOIN exercises ON exercises.workout_session_id = workout_sessions.id AND exercises.user_id = ? AND exercises.deleted_at IS NULL
+			LEFT JOIN set_entries ON set_entries.exercise_id = exercises.id AND set_entries.type <> 'WARMUP' AND set_entries.created_at >= ? AND set_entries.deleted_at IS NULL
+		WHERE workout_sessions.start >= ? AND workout_sessions.start < ? AND workout_sessions.deleted_at IS NULL
+			AND (workout_sessions.user_id = ? OR exercises.id IS NOT NULL)`,
+		userId, start, start, end, userId,
+	).Scan(&stats).Error
+	return &stats, err
+}
+
+type LifetimeStats struct {
+	TotalSessions int
+	TotalSets     int
+	TotalVolume   float64
+}
+
+// GetLifetimeStats adds up everything userId has logged, including sessions
+// that were moved to cold storage. those only count through their summary row,
+// whose volume was summed in its own unit
+func GetLifetimeStats(db *gorm.DB, userId string, unit string) (*LifetimeStats, error) {
+	live := LifetimeStats{}
+	err := db.Raw(`
+		SELECT COUNT(DISTINCT workout_sessions.id) AS total_sessions,
+			COUNT(set_entries.id) AS total_sets,
+			COALESCE(SUM(`+setWeight(unit)+` * set_entries.reps), 0) AS total_volume
+		FROM workout_sessions
+			LEFT JOIN exercises ON exercises.workout_session_id = workout_sessions.id AND exercises.user_id = ? AND exercises.deleted_at IS NULL
+			LEFT JOIN set_entries ON set_entries.exercise_id = exercises.id AND set_entries.type <> 'WARMUP' AND set_entries.deleted_at IS NULL
+		WHERE workout_sessions.deleted_at IS NULL AND (workout_sessions.user_id = ? OR exercises.id IS NOT NULL)`,
+		userId, userId,
+	).Scan(&live).Error
+	if err != nil {
+		return nil, err
+	}
+
+	archived := LifetimeStats{}
+	err = db.Raw(`
+		SELECT COUNT(*) AS total_sessions,
+			COALESCE(SUM(set_count), 0) AS total_sets,
+			COALESCE(SUM(`+weightInUnit("total_volume", "volume_unit", unit)+`), 0) AS total_volume
+		FROM archived_workout_sessions
+		WHERE user_id = ? AND deleted_at IS NULL`,
+		userId,
+	).Scan(&archived).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &LifetimeStats{
+		TotalSessions: live.TotalSessions + archived.TotalSessions,
+		TotalSets:     live.TotalSets + archived.TotalSets,
+		TotalVolume:   live.TotalVolume + archived.TotalVolume,
+	}, nil
+}
+
+// WorkoutSummary adds up the working sets the session's owner logged in it
+type WorkoutSummary struct {
+	TotalVolume        float64
+	TotalSets          int
+	ExercisesCompleted int
+	ExercisesPlanned   int
+}
+
+// GetWorkoutSummary counts an exercise as completed once it has a working set,
+// planned ones are the active exercise routines in the session's routine.
+// Volume is in unit
+func GetWorkoutSummary(db *gorm.DB, workoutSessionId string, unit string) (*WorkoutSummary, error) {
+	summary := WorkoutSummary{}
+	err := db.Raw(`
+		SELECT COALESCE(SUM(`+setWeight(unit)+` * set_entries.reps), 0) AS total_volume,
+			COUNT(set_entries.id) AS total_sets,
+			COUNT(DISTINCT exercises.exercise_routine_id) FILTER (WHERE set_entries.id IS NOT NULL) AS exercises_completed,
+			(SELECT COUNT(*) FROM exercise_routines
+				WHERE exercise_routines.workout_routine_id = workout_sessions.workout_routine_id AND exercise_routines.active AND exercise_routines.deleted_at IS NULL) AS exercises_planned
+		FROM workout_sessions
+			LEFT JOIN exercises ON exercises.workout_session_id = workout_sessions.id AND exercises.user_id = workout_sessions.user_id AND exercises.deleted_at IS NULL
+			LEFT JOIN set_entries ON set_entries.exercise_id = exercises.id AND set_entries.type <> 'WARMUP' AND set_entries.deleted_at IS NULL
+		WHERE workout_sessions.id = ? AND workout_sessions.deleted_at IS NULL
+		GROUP BY workout_sessions.id, workout_sessions.workout_routine_id`,
+		workoutSessionId,
+	).Scan(&summary).Error
+	return &summary, err
+}
+
+// PersonalRecord is the heaviest working set on an exercise routine in a
+// session when it beats every earlier session's, weights are in the unit asked
+// for
+type PersonalRecord struct {
+	ExerciseRoutineID uint
+	Name              string
+	Weight            float64
+	Reps              int
+	PreviousWeight    float64
+}
+
+// GetPersonalRecords is the records the session's owner set in it. The first
+// time an exercise routine is logged there's nothing to beat, so it's not one
+func GetPersonalRecords(db *gorm.DB, workoutSessionId string, unit string) ([]PersonalRecord, error) {
+	return personalRecords(db, unit, "workout_sessions.id = ?", workoutSessionId)
+}
+
+// GetPersonalRecordsBetween is GetPersonalRecords for the sessions userId
+// started between start and end, the best set on each exercise routine is
+// held up against the sessions before its own
+func GetPersonalRecordsBetween(db *gorm.DB, userId string, start time.Time, end time.Time, unit string) ([]PersonalRecord, error) {
+	return personalRecords(db, unit, "workout_sessions.user_id = ? AND workout_sessions.start >= ? AND workout_sessions.start < ?", userId, start, end)
+}
+
+// personalRecords compares the best working set on each exercise routine in
+// the sessions matching where with the heaviest one from the owner's earlier
+// sessions. sets are compared in unit so switching units doesn't make records
+func personalRecords(db *gorm.DB, unit string, where string, args ...interface{}) ([]PersonalRecord, error) {
+	weight := setWeight(unit)
+	records := []PersonalRecord{}
+	err := db.Raw(`
+		WITH session_best AS (
+			SELECT DISTINCT ON (exercises.exercise_routine_id)
+				exercises.exercise_routine_id, workout_sessions.user_id, workout_sessions.start,
+				`+weight+` AS weight, set_entries.reps
+			FROM workout_sessions
+				JOIN exercises ON exercises.workout_session_id = workout_sessions.id AND exercises.user_id = workout_sessions.user_id AND exercises.deleted_at IS NULL
+				JOIN set_entries ON set_entries.exercise_id = exercises.id AND set_entries.type <> 'WARMUP'
+					AND set_entries.reps > 0 AND set_entries.weight > 0 AND set_entries.deleted_at IS NULL
+			WHERE `+where+` AND workout_sessions.deleted_at IS NULL
+			ORDER BY exercises.exercise_routine_id, weight DESC, set_entries.reps DESC
+		)
+		SELECT exercise_routines.id AS exercise_routine_id,
+			exercise_routines.name AS name,
+			session_best.weight AS weight,
+			session_best.reps AS reps,
+			previous_best.weight AS previous_weight
+		FROM session_best
+			JOIN exercise_routines ON exercise_routines.id = session_best.exercise_routine_id
+			JOIN LATERAL (
+				SELECT MAX(`+weight+`) AS weight
+				FROM workout_sessions
+					JOIN exercises ON exercises.workout_session_id = workout_sessions.id AND exercises.deleted_at IS NULL
+					JOIN set_entries ON set_entries.exercise_id = exercises.id AND set_entries.type <> 'WARMUP'
+						AND set_entries.reps > 0 AND set_entries.deleted_at IS NULL
+				WHERE exercises.exercise_routine_id = session_best.exercise_routine_id AND exercises.user_id = session_best.user_id
+					AND workout_sessions.start < session_best.start AND workout_sessions.deleted_at IS NULL
+			) previous_best ON previous_best.weight IS NOT NULL
+		WHERE session_best.weight > previous_best.weight
+		ORDER BY exercise_routines.id`,
+		args...,
+	).Scan(&records).Error
+	return records, err
+}
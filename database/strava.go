@@ -0,0 +1,96 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UpsertStravaConnection links the user to a Strava athlete, replacing the
+// tokens of a link they already have
+func UpsertStravaConnection(db *gorm.DB, connection *StravaConnection) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"athlete_id", "access_token", "refresh_token", "expires_at", "updated_at"}),
+	}).Create(connection).Error
+}
+
+func GetStravaConnection(db *gorm.DB, userId string) (*StravaConnection, error) {
+	var connection StravaConnection
+	result := db.Where("user_id = ?", userId).First(&connection)
+	return &connection, result.Error
+}
+
+// UpdateStravaTokens saves the tokens a refresh handed back
+func UpdateStravaTokens(db *gorm.DB, connectionId uint, accessToken string, refreshToken string, expiresAt time.Time) error {
+	return db.Model(&StravaConnection{}).Where("id = ?", connectionId).Updates(map[string]interface{}{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"expires_at":    expiresAt,
+	}).Error
+}
+
+// ExpireStravaToken makes the next upload refresh the access token first,
+// for when Strava turned it away before it was meant to expire
+func ExpireStravaToken(db *gorm.DB, connectionId uint, at time.Time) error {
+	return db.Model(&StravaConnection{}).Where("id = ?", connectionId).Update("expires_at", at).Error
+}
+
+// DeleteStravaConnection stops uploads that are still waiting and removes
+// the link for good, its tokens are no use to anyone once it's gone
+func DeleteStravaConnection(db *gorm.DB, userId string) (int64, error) {
+	var deleted int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&StravaUpload{}).
+			Where("user_id = ? AND next_attempt_at IS NOT NULL", userId).
+			Update("next_attempt_at", nil).Error
+		if err != nil {
+			return err
+		}
+		result := tx.Unscoped().Where("user_id = ?", userId).Delete(&StravaConnection{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	return deleted, err
+}
+
+// queueStravaUpload adds an upload of the finished session when its owner
+// is connected to Strava, due right away
+func queueStravaUpload(tx *gorm.DB, workoutSession *WorkoutSession) error {
+	return tx.Exec(`INSERT INTO strava_uploads (created_at, updated_at, user_id, workout_session_id, attempts, next_attempt_at, last_error)
+		SELECT NOW(), NOW(), user_id, ?, 0, NOW(), '' FROM strava_connections WHERE user_id = ? AND deleted_at IS NULL`,
+		workoutSession.ID, workoutSession.UserID,
+	).Error
+}
+
+// GetDueStravaUploads is up to limit uploads whose next attempt is at or
+// before now, oldest first
+func GetDueStravaUploads(db *gorm.DB, now time.Time, limit int) ([]StravaUpload, error) {
+	var uploads []StravaUpload
+	result := db.Where("next_attempt_at <= ?", now).Order("next_attempt_at, id").Limit(limit).Find(&uploads)
+	return uploads, result.Error
+}
+
+func MarkStravaUploaded(db *gorm.DB, uploadId uint, activityId int64, at time.Time) error {
+	return db.Model(&StravaUpload{}).Where("id = ?", uploadId).Updates(map[string]interface{}{
+		"attempts":        gorm.Expr("attempts + 1"),
+		"next_attempt_at": nil,
+		"activity_id":     activityId,
+		"uploaded_at":     at,
+		"last_error":      "",
+	}).Error
+}
+
+// MarkStravaUploadFailed records a failed attempt, a nil nextAttemptAt
+// gives up on the upload
+func MarkStravaUploadFailed(db *gorm.DB, uploadId uint, nextAttemptAt *time.Time, reason string) error {
+	if len(reason) > 512 {
+		reason = reason[:512]
+	}
+	return db.Model(&StravaUpload{}).Where("id = ?", uploadId).Updates(map[string]interface{}{
+		"attempts":        gorm.Expr("attempts + 1"),
+		"next_attempt_at": nextAttemptAt,
+		"last_error":      reason,
+	}).Error
+}
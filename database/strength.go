@@ -0,0 +1,75 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// one rep max formulas line up with the graphql OneRepMaxFormula enum
+const (
+	OneRepMaxEpley   = "EPLEY"
+	OneRepMaxBrzycki = "BRZYCKI"
+)
+
+// estimated one rep max of a set in sql from its weight, a single is its own
+// max
+var oneRepMaxSQL = map[string]func(weight string) string{
+	OneRepMaxEpley: func(weight string) string {
+		return "CASE WHEN set_entries.reps = 1 THEN " + weight + " ELSE " + weight + " * (1 + set_entries.reps / 30.0) END"
+	},
+	OneRepMaxBrzycki: func(weight string) string {
+		return weight + " * 36.0 / (37 - set_entries.reps)"
+	},
+}
+
+// ExerciseOneRepMax is the set with the best estimated one rep max on an
+// exercise routine
+type ExerciseOneRepMax struct {
+	ExerciseRoutineID   uint
+	Name                string
+	CatalogExerciseID   *uint
+	CatalogExerciseName *string
+	EstimatedOneRepMax  float64
+	Weight              float64
+	Reps                int
+	PerformedAt         time.Time
+}
+
+// GetBestOneRepMaxes is the best estimated one rep max on every exercise
+// routine userId logged working sets on, with the set it came from. Sets of
+// more than maxReps are left out, estimates from them are too far off. Ties
+// go to the earliest set. Weights are in unit
+func GetBestOneRepMaxes(db *gorm.DB, userId string, formula string, maxReps int, unit string) ([]ExerciseOneRepMax, error) {
+	estimateOf, ok := oneRepMaxSQL[formula]
+	if !ok {
+		estimateOf = oneRepMaxSQL[OneRepMaxEpley]
+	}
+	weight := setWeight(unit)
+	estimate := estimateOf(weight)
+
+	best := []ExerciseOneRepMax{}
+	err := db.Raw(`
+		SELECT * FROM (
+			SELECT DISTINCT ON (exercise_routines.id)
+				exercise_routines.id AS exercise_routine_id,
+				exercise_routines.name AS name,
+				exercise_routines.catalog_exercise_id AS catalog_exercise_id,
+				catalog_exercises.name AS catalog_exercise_name,
+				`+estimate+` AS estimated_one_rep_max,
+				`+weight+` AS weight,
+				set_entries.reps AS reps,
+				COALESCE(set_entries.completed_at, set_entries.created_at) AS performed_at
+			FROM set_entries
+				JOIN exercises ON exercises.id = set_entries.exercise_id AND exercises.deleted_at IS NULL
+				JOIN exercise_routines ON exercise_routines.id = exercises.exercise_routine_id AND exercise_routines.deleted_at IS NULL
+				LEFT JOIN catalog_exercises ON catalog_exercises.id = exercise_routines.catalog_exercise_id
+			WHERE exercises.user_id = ? AND set_entries.type <> 'WARMUP'
+				AND set_entries.reps > 0 AND set_entries.reps <= ? AND set_entries.weight > 0 AND set_entries.deleted_at IS NULL
+			ORDER BY exercise_routines.id, estimated_one_rep_max DESC, performed_at
+		) best
+		ORDER BY estimated_one_rep_max DESC, exercise_routine_id`,
+		userId, maxReps,
+	).Scan(&best).Error
+	return best, err
+}
@@ -0,0 +1,503 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/common"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// what a sync change is to, lines up with the graphql SyncEntity enum
+const (
+	SyncEntityWorkoutSession = "WORKOUT_SESSION"
+	SyncEntityExercise       = "EXERCISE"
+	SyncEntitySetEntry       = "SET_ENTRY"
+)
+
+// lines up with the graphql SyncOperation enum
+const (
+	SyncOperationCreate = "CREATE"
+	SyncOperationUpdate = "UPDATE"
+	SyncOperationDelete = "DELETE"
+)
+
+// what happened to a sync change, lines up with the graphql SyncStatus enum
+const (
+	SyncStatusApplied   = "APPLIED"
+	SyncStatusDuplicate = "DUPLICATE" // the same change was applied before
+	SyncStatusStale     = "STALE"     // a newer change was applied before, it's kept
+	SyncStatusRejected  = "REJECTED"
+)
+
+// SyncChange is a session, exercise or set the client created, changed or
+// deleted while it was offline. Fields that are nil are left as they are on
+// updates
+type SyncChange struct {
+	Entity       string
+	Operation    string
+	ClientID     string
+	LastModified time.Time
+	// the session an exercise is in or the exercise a set is in, they have
+	// to have been synced too
+	ParentClientID string
+
+	WorkoutRoutineID  *uint
+	ExerciseRoutineID *uint
+	Start             *time.Time
+	End               *time.Time
+	Notes             *string
+	Weight            *float64
+	Unit              *string
+	Reps              *uint
+	Type              *string
+	CompletedAt       *time.Time
+	Origin            Origin
+}
+
+type SyncOutcome struct {
+	Entity   string
+	ClientID string
+	// 0 when the server never had it
+	EntityID uint
+	Status   string
+	// why it was rejected
+	Message string
+	// an applied change that finished or deleted a session, anyone watching
+	// it live has nothing left to see
+	Ended bool
+}
+
+// syncRejection is a change that won't ever apply however often it's sent
+type syncRejection string
+
+func (r syncRejection) Error() string {
+	return string(r)
+}
+
+// ApplySyncChanges applies changes in order, each in its own transaction so
+// one that's rejected doesn't hold up the rest. A change that's older than
+// the last one synced for the same client id loses, deletes are final, and
+// changes to or in a session canEdit says is locked are rejected. An error is
+// returned for anything other than a rejection, the changes before it stay
+// applied and sending the batch again is safe
+func ApplySyncChanges(db *gorm.DB, userId uint, changes []SyncChange, now time.Time, canEdit func(workoutSessionId uint) error) ([]SyncOutcome, error) {
+	clientIds := make([]string, 0, len(changes))
+	for _, change := range changes {
+		clientIds = append(clientIds, change.ClientID)
+		if change.ParentClientID != "" {
+			clientIds = append(clientIds, change.ParentClientID)
+		}
+	}
+	var found []SyncRecord
+	if err := db.Where("user_id = ? AND client_id IN ?", userId, clientIds).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	records := make(map[string]*SyncRecord, len(found))
+	for i := range found {
+		records[found[i].ClientID] = &found[i]
+	}
+
+	// postgres keeps microseconds, and a clock that's ahead doesn't get to
+	// win every conflict
+	now = now.Truncate(time.Microsecond)
+
+	outcomes := make([]SyncOutcome, 0, len(changes))
+	for _, change := range changes {
+		change.LastModified = change.LastModified.Truncate(time.Microsecond)
+		if change.LastModified.After(now) {
+			change.LastModified = now
+		}
+
+		outcome := SyncOutcome{Entity: change.Entity, ClientID: change.ClientID}
+		record := records[change.ClientID]
+		if record != nil {
+			outcome.EntityID = record.EntityID
+		}
+
+		status, err := syncStatus(record, &change)
+		if status == "" {
+			err = checkSyncEditable(db, canEdit, records, record, &change)
+			if err == nil {
+				// cascade deletes share a timestamp so they can be restored together
+				err = cascadeDeleteSession(db).Transaction(func(tx *gorm.DB) error {
+					var err error
+					record, err = applySyncChange(tx, userId, records, record, &change)
+					return err
+				})
+			}
+			status = SyncStatusApplied
+		}
+
+		var rejection syncRejection
+		switch {
+		case errors.As(err, &rejection):
+			outcome.Status = SyncStatusRejected
+			outcome.Message = rejection.Error()
+		case err != nil:
+			return nil, err
+		default:
+			outcome.Status = status
+			if status == SyncStatusApplied {
+				records[change.ClientID] = record
+				outcome.EntityID = record.EntityID
+				outcome.Ended = change.Entity == SyncEntityWorkoutSession &&
+					(change.End != nil || change.Operation == SyncOperationDelete)
+			}
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	return outcomes, nil
+}
+
+// syncStatus is the status of a change that shouldn't be applied, empty for
+// one that should
+func syncStatus(record *SyncRecord, change *SyncChange) (string, error) {
+	if record == nil {
+		if change.Operation == SyncOperationUpdate {
+			return SyncStatusRejected, syncRejection("it hasn't been created")
+		}
+		return "", nil
+	}
+	switch {
+	case record.Entity != change.Entity:
+		return SyncStatusRejected, syncRejection("the client id is already used by something else")
+	case change.LastModified.Equal(record.LastModified):
+		return SyncStatusDuplicate, nil
+	case change.LastModified.Before(record.LastModified):
+		return SyncStatusStale, nil
+	case record.Deleted:
+		return SyncStatusRejected, syncRejection("it was deleted")
+	}
+	return "", nil
+}
+
+// checkSyncEditable rejects a change to or in a session past the edit lock
+// the same as the mutations are
+func checkSyncEditable(db *gorm.DB, canEdit func(workoutSessionId uint) error, records map[string]*SyncRecord, record *SyncRecord, change *SyncChange) error {
+	workoutSessionId, err := syncedWorkoutSession(db, records, record, change)
+	if err != nil || workoutSessionId == 0 {
+		return err
+	}
+	err = canEdit(workoutSessionId)
+	var lockedError *common.WorkoutSessionLockedError
+	if errors.As(err, &lockedError) {
+		return syncRejection(lockedError.Error())
+	}
+	// applying it says it was deleted
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	return err
+}
+
+// syncedWorkoutSession is the session a change is to or in, 0 for a session
+// that's being created and when what it's in is already gone
+func syncedWorkoutSession(db *gorm.DB, records map[string]*SyncRecord, record *SyncRecord, change *SyncChange) (uint, error) {
+	entity := change.Entity
+	var id uint
+	if record != nil {
+		id = record.EntityID
+	}
+	// created in its parent
+	if id == 0 && change.Operation != SyncOperationDelete {
+		parent := records[change.ParentClientID]
+		if parent == nil || parent.Deleted {
+			return 0, nil
+		}
+		entity, id = parent.Entity, parent.EntityID
+	}
+	if id == 0 {
+		return 0, nil
+	}
+
+	var workoutSessionIds []uint
+	var err error
+	switch entity {
+	case SyncEntityWorkoutSession:
+		return id, nil
+	case SyncEntityExercise:
+		err = db.Model(&Exercise{}).Where("id = ?", id).Pluck("workout_session_id", &workoutSessionIds).Error
+	case SyncEntitySetEntry:
+		err = db.Model(&SetEntry{}).
+			Joins("JOIN exercises ON exercises.id = set_entries.exercise_id AND exercises.deleted_at IS NULL").
+			Where("set_entries.id = ?", id).
+			Pluck("exercises.workout_session_id", &workoutSessionIds).Error
+	}
+	if err != nil || len(workoutSessionIds) == 0 {
+		return 0, err
+	}
+	return workoutSessionIds[0], nil
+}
+
+// applySyncChange is the record after the change, creates of something that
+// was already created are updates in case the client never heard back
+func applySyncChange(tx *gorm.DB, userId uint, records map[string]*SyncRecord, record *SyncRecord, change *SyncChange) (*SyncRecord, error) {
+	next := SyncRecord{UserID: userId, ClientID: change.ClientID, Entity: change.Entity}
+	if record != nil {
+		next = *record
+	}
+	next.LastModified = change.LastModified
+
+	var err error
+	switch {
+	case change.Operation == SyncOperationDelete:
+		if next.EntityID != 0 {
+			err = deleteSynced(tx, change.Entity, next.EntityID)
+		}
+		next.Deleted = true
+	case next.EntityID == 0:
+		next.EntityID, err = createSynced(tx, userId, records, change)
+	default:
+		err = updateSynced(tx, next.EntityID, change)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &next, tx.Save(&next).Error
+}
+
+// syncedParent is the server id of the parent of change, which has to be
+// an entity that was synced and isn't deleted
+func syncedParent(records map[string]*SyncRecord, change *SyncChange, entity string) (uint, error) {
+	parent := records[change.ParentClientID]
+	if parent != nil && parent.Deleted {
+		return 0, syncRejection("its parent was deleted")
+	}
+	if parent == nil || parent.Entity != entity {
+		return 0, syncRejection("its parent hasn't been synced")
+	}
+	return parent.EntityID, nil
+}
+
+func createSynced(tx *gorm.DB, userId uint, records map[string]*SyncRecord, change *SyncChange) (uint, error) {
+	switch change.Entity {
+	case SyncEntityWorkoutSession:
+		var count int64
+		err := tx.Model(&WorkoutRoutine{}).Where("id = ? AND user_id = ?", *change.WorkoutRoutineID, userId).Count(&count).Error
+		if err != nil {
+			return 0, err
+		}
+		if count == 0 {
+			return 0, syncRejection("the workout routine doesn't exist")
+		}
+		if change.End == nil {
+			err := checkNoOpenWorkoutSession(tx, userId)
+			if errors.Is(err, ErrOpenWorkoutSession) {
+				return 0, syncRejection(err.Error())
+			}
+			if err != nil {
+				return 0, err
+			}
+		}
+
+		workoutSession := WorkoutSession{
+			Start:            *change.Start,
+			End:              change.End,
+			WorkoutRoutineID: *change.WorkoutRoutineID,
+			UserID:           userId,
+			Origin:           change.Origin,
+		}
+		if change.Notes != nil {
+			workoutSession.Notes = *change.Notes
+		}
+		if err := tx.Create(&workoutSession).Error; err != nil {
+			return 0, err
+		}
+		// finished offline, it goes out now
+		if workoutSession.End != nil {
+			if err := queueFinishedWorkoutSession(tx, &workoutSession); err != nil {
+				return 0, err
+			}
+		}
+		return workoutSession.ID, nil
+
+	case SyncEntityExercise:
+		workoutSessionId, err := syncedParent(records, change, SyncEntityWorkoutSession)
+		if err != nil {
+			return 0, err
+		}
+		var workoutSession WorkoutSession
+		err = tx.Select("id", "workout_routine_id").Where("id = ?", workoutSessionId).Take(&workoutSession).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, syncRejection("its parent was deleted")
+		}
+		if err != nil {
+			return 0, err
+		}
+		count, err := CountExerciseRoutinesInWorkoutRoutine(tx, fmt.Sprintf("%d", workoutSession.WorkoutRoutineID), []string{fmt.Sprintf("%d", *change.ExerciseRoutineID)})
+		if err != nil {
+			return 0, err
+		}
+		if count == 0 {
+			return 0, syncRejection(ErrExerciseRoutineNotInWorkoutRoutine.Error())
+		}
+
+		exercise := Exercise{
+			WorkoutSessionID:  workoutSessionId,
+			ExerciseRoutineID: *change.ExerciseRoutineID,
+			UserID:            userId,
+		}
+		if change.Notes != nil {
+			exercise.Notes = *change.Notes
+		}
+		if err := tx.Omit("Sets").Create(&exercise).Error; err != nil {
+			return 0, err
+		}
+		return exercise.ID, touchWorkoutSessions(tx, []uint{workoutSessionId})
+
+	case SyncEntitySetEntry:
+		exerciseId, err := syncedParent(records, change, SyncEntityExercise)
+		if err != nil {
+			return 0, err
+		}
+		var count int64
+		if err := tx.Model(&Exercise{}).Where("id = ?", exerciseId).Count(&count).Error; err != nil {
+			return 0, err
+		}
+		if count == 0 {
+			return 0, syncRejection("its parent was deleted")
+		}
+		setOrder, err := NextSetOrder(tx, fmt.Sprintf("%d", exerciseId))
+		if err != nil {
+			return 0, err
+		}
+
+		set := SetEntry{
+			ExerciseID:  exerciseId,
+			Weight:      *change.Weight,
+			Reps:        *change.Reps,
+			SetOrder:    setOrder,
+			Type:        SetTypeWorking,
+			CompletedAt: change.CompletedAt,
+			Origin:      change.Origin,
+		}
+		if change.Type != nil {
+			set.Type = *change.Type
+		}
+		if change.Unit != nil {
+			set.Unit = *change.Unit
+		}
+		if err := tx.Create(&set).Error; err != nil {
+			return 0, err
+		}
+		return set.ID, touchExercise(tx, exerciseId)
+	}
+	return 0, fmt.Errorf("unknown sync entity %q", change.Entity)
+}
+
+func updateSynced(tx *gorm.DB, id uint, change *SyncChange) error {
+	updates := map[string]interface{}{}
+	switch change.Entity {
+	case SyncEntityWorkoutSession:
+		var workoutSession WorkoutSession
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).Take(&workoutSession).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return syncRejection("it was deleted")
+		}
+		if err != nil {
+			return err
+		}
+		if change.Start != nil {
+			updates["start"] = *change.Start
+		}
+		if change.End != nil {
+			updates["end"] = *change.End
+		}
+		if change.Notes != nil {
+			updates["notes"] = *change.Notes
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		if err := tx.Model(&WorkoutSession{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+			return err
+		}
+		// finished offline, it goes out now
+		if workoutSession.End == nil && change.End != nil {
+			return queueFinishedWorkoutSession(tx, &workoutSession)
+		}
+		return nil
+
+	case SyncEntityExercise:
+		if change.Notes == nil {
+			return nil
+		}
+		result := tx.Model(&Exercise{}).Where("id = ?", id).Update("notes", *change.Notes)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return syncRejection("it was deleted")
+		}
+		return touchExercise(tx, id)
+
+	case SyncEntitySetEntry:
+		var set SetEntry
+		err := tx.Select("id", "exercise_id").Where("id = ?", id).Take(&set).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return syncRejection("it was deleted")
+		}
+		if err != nil {
+			return err
+		}
+		if change.Weight != nil {
+			updates["weight"] = *change.Weight
+		}
+		if change.Unit != nil {
+			updates["unit"] = *change.Unit
+		}
+		if change.Reps != nil {
+			updates["reps"] = *change.Reps
+		}
+		if change.Type != nil {
+			updates["type"] = *change.Type
+		}
+		if change.CompletedAt != nil {
+			updates["completed_at"] = *change.CompletedAt
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+		if err := tx.Model(&SetEntry{}).Where("id = ?", id).Updates(updates).Error; err != nil {
+			return err
+		}
+		return touchExercise(tx, set.ExerciseID)
+	}
+	return fmt.Errorf("unknown sync entity %q", change.Entity)
+}
+
+// deleteSynced soft deletes the row with what's in it, a row that's already
+// gone is fine
+func deleteSynced(tx *gorm.DB, entity string, id uint) error {
+	switch entity {
+	case SyncEntityWorkoutSession:
+		if err := tx.Where("exercise_id IN (SELECT id FROM exercises WHERE workout_session_id = ?)", id).Delete(&SetEntry{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("workout_session_id = ?", id).Delete(&Exercise{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&WorkoutSession{}).Error
+
+	case SyncEntityExercise:
+		if err := tx.Where("exercise_id = ?", id).Delete(&SetEntry{}).Error; err != nil {
+			return err
+		}
+		var exercise Exercise
+		if err := tx.Clauses(clause.Returning{}).Where("id = ?", id).Delete(&exercise).Error; err != nil {
+			return err
+		}
+		return touchWorkoutSessions(tx, []uint{exercise.WorkoutSessionID})
+
+	case SyncEntitySetEntry:
+		var set SetEntry
+		if err := tx.Clauses(clause.Returning{}).Where("id = ?", id).Delete(&set).Error; err != nil {
+			return err
+		}
+		return touchExercise(tx, set.ExerciseID)
+	}
+	return fmt.Errorf("unknown sync entity %q", entity)
+}
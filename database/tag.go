@@ -0,0 +1,98 @@
+package database
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NormalizeTag lower cases and trims a tag name, so "Deload" and "deload "
+// are the same tag
+func NormalizeTag(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// NormalizeTags normalizes tag names and drops repeats
+func NormalizeTags(names []string) []string {
+	seen := map[string]bool{}
+	normalized := []string{}
+	for _, name := range names {
+		name = NormalizeTag(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		normalized = append(normalized, name)
+	}
+	return normalized
+}
+
+// SetWorkoutSessionTags replaces the session's tags with names, creating
+// the tags nobody has used yet. names are expected to be normalized
+func SetWorkoutSessionTags(db *gorm.DB, workoutSessionId uint, names []string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("workout_session_id = ?", workoutSessionId).Delete(&WorkoutSessionTag{}).Error
+		if err != nil || len(names) == 0 {
+			return err
+		}
+
+		tags := make([]Tag, 0, len(names))
+		for _, name := range names {
+			tags = append(tags, Tag{Name: name})
+		}
+		err = tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tags).Error
+		if err != nil {
+			return err
+		}
+
+		// ids of tags that already existed aren't handed back by the insert
+		var tagIds []uint
+		err = tx.Model(&Tag{}).Where("name IN ?", names).Pluck("id", &tagIds).Error
+		if err != nil {
+			return err
+		}
+
+		sessionTags := make([]WorkoutSessionTag, 0, len(tagIds))
+		for _, tagId := range tagIds {
+			sessionTags = append(sessionTags, WorkoutSessionTag{WorkoutSessionID: workoutSessionId, TagID: tagId})
+		}
+		return tx.Create(&sessionTags).Error
+	})
+}
+
+func SetWorkoutSessionNotes(db *gorm.DB, workoutSessionId uint, notes string) error {
+	return db.Model(&WorkoutSession{}).Where("id = ?", workoutSessionId).Update("notes", notes).Error
+}
+
+type WorkoutSessionTagName struct {
+	WorkoutSessionID uint
+	Name             string
+}
+
+// GetTagNamesByWorkoutSessionId looks up the tags of a batch of sessions in
+// alphabetical order
+func GetTagNamesByWorkoutSessionId(db *gorm.DB, workoutSessionIds []string) ([]WorkoutSessionTagName, error) {
+	var tagNames []WorkoutSessionTagName
+	err := db.Model(&WorkoutSessionTag{}).
+		Select("workout_session_tags.workout_session_id, tags.name").
+		Joins("JOIN tags ON tags.id = workout_session_tags.tag_id").
+		Where("workout_session_tags.workout_session_id IN ?", workoutSessionIds).
+		Order("tags.name").
+		Scan(&tagNames).Error
+	return tagNames, err
+}
+
+// GetUserTags lists every tag on the user's sessions in alphabetical order,
+// for suggesting ones they've used before
+func GetUserTags(db *gorm.DB, userId string) ([]string, error) {
+	var names []string
+	err := db.Model(&Tag{}).
+		Distinct("tags.name").
+		Joins("JOIN workout_session_tags ON workout_session_tags.tag_id = tags.id").
+		Joins("JOIN workout_sessions ON workout_sessions.id = workout_session_tags.workout_session_id").
+		Where("workout_sessions.user_id = ? AND workout_sessions.deleted_at IS NULL", userId).
+		Order("tags.name").
+		Pluck("tags.name", &names).Error
+	return names, err
+}
@@ -0,0 +1,41 @@
+package database
+
+import (
+	"gorm.io/gorm"
+)
+
+// writes to a child bump updated_at on its parents in the same transaction
+// so clients syncing by updated_at and caches keyed on it see the change.
+// soft deleted parents are left alone, the deletion is their last change
+
+func touchWorkoutRoutine(tx *gorm.DB, workoutRoutineId interface{}) error {
+	return tx.Model(&WorkoutRoutine{}).Where("id = ?", workoutRoutineId).UpdateColumn("updated_at", tx.NowFunc()).Error
+}
+
+func touchWorkoutSessions(tx *gorm.DB, workoutSessionIds []uint) error {
+	if len(workoutSessionIds) == 0 {
+		return nil
+	}
+	return tx.Model(&WorkoutSession{}).Where("id IN ?", workoutSessionIds).UpdateColumn("updated_at", tx.NowFunc()).Error
+}
+
+// touchExercises bumps the exercises and the sessions they were logged in
+func touchExercises(tx *gorm.DB, exerciseIds []uint) error {
+	if len(exerciseIds) == 0 {
+		return nil
+	}
+	now := tx.NowFunc()
+	if err := tx.Model(&Exercise{}).Where("id IN ?", exerciseIds).UpdateColumn("updated_at", now).Error; err != nil {
+		return err
+	}
+	return tx.Model(&WorkoutSession{}).Where("id IN (SELECT workout_session_id FROM exercises WHERE id IN ?)", exerciseIds).UpdateColumn("updated_at", now).Error
+}
+
+// touchExercise bumps the exercise and the session it was logged in
+func touchExercise(tx *gorm.DB, exerciseId interface{}) error {
+	now := tx.NowFunc()
+	if err := tx.Model(&Exercise{}).Where("id = ?", exerciseId).UpdateColumn("updated_at", now).Error; err != nil {
+		return err
+	}
+	return tx.Model(&WorkoutSession{}).Where("id = (SELECT workout_session_id FROM exercises WHERE id = ?)", exerciseId).UpdateColumn("updated_at", now).Error
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"fmt"
+	"math"
+
+	"gorm.io/gorm"
+)
+
+// how much a weight in one unit is in the other
+var WeightUnitFactors = map[string]map[string]float64{
+	WeightUnitKg: {WeightUnitLb: 2.20462262185},
+	WeightUnitLb: {WeightUnitKg: 0.45359237},
+}
+
+// ConvertWeight is weight, logged in from, in to. Weights without a unit are
+// taken to be in to already
+func ConvertWeight(weight float64, from string, to string) float64 {
+	if factor, ok := WeightUnitFactors[from][to]; ok {
+		return weight * factor
+	}
+	return weight
+}
+
+// RoundWeight is weight to the 2 decimals the weight columns keep, so a
+// weight reads the same before and after it's saved
+func RoundWeight(weight float64) float64 {
+	return math.Round(weight*100) / 100
+}
+
+// weightInUnit is ConvertWeight in sql, column holds weights in the unit in
+// unitColumn and comes out in unit
+func weightInUnit(column string, unitColumn string, unit string) string {
+	other := WeightUnitKg
+	if unit == WeightUnitKg {
+		other = WeightUnitLb
+	}
+	factor, ok := WeightUnitFactors[other][unit]
+	if !ok {
+		return column
+	}
+	return fmt.Sprintf("(CASE WHEN %s = '%s' THEN %s * %v ELSE %s END)", unitColumn, other, column, factor, column)
+}
+
+// setWeight is a set's weight in unit, the stats add sets logged in either
+// unit up in the one the user reads them in
+func setWeight(unit string) string {
+	return weightInUnit("set_entries.weight", "set_entries.unit", unit)
+}
+
+// the user's own exercises on the routine logged in sessions that started in
+// the range, co-loggers' sets in the same sessions are theirs to convert
+const convertibleExercises = `SELECT exercises.id FROM exercises
+	JOIN workout_sessions ON workout_sessions.id = exercises.workout_session_id
+	WHERE exercises.exercise_routine_id = @routine AND exercises.user_id = @user AND exercises.deleted_at IS NULL
+		AND workout_sessions.start >= @start AND workout_sessions.start < @end AND workout_sessions.deleted_at IS NULL`
+
+func unitConversionParams(c *UnitConversion) map[string]interface{} {
+	return map[string]interface{}{
+		"routine": c.ExerciseRoutineID,
+		"user":    c.UserID,
+		"start":   c.RangeStart,
+		"end":     c.RangeEnd,
+		"from":    c.FromUnit,
+	}
+}
+
+// GetMaxConvertibleWeight is the heaviest set the conversion would rescale
+func GetMaxConvertibleWeight(db *gorm.DB, c *UnitConversion) (float64, error) {
+	var max float64
+	err := db.Raw(`SELECT COALESCE(MAX(weight), 0) FROM set_entries WHERE exercise_id IN (`+convertibleExercises+`) AND unit = @from AND deleted_at IS NULL`, unitConversionParams(c)).
+		Scan(&max).Error
+	return max, err
+}
+
+// HasOverlappingUnitConversion is true when part of the range was already
+// converted the same way, doing it again would rescale those sets twice
+func HasOverlappingUnitConversion(db *gorm.DB, c *UnitConversion) (bool, error) {
+	var count int64
+	err := db.Model(&UnitConversion{}).
+		Where("user_id = ? AND exercise_routine_id = ? AND from_unit = ? AND to_unit = ? AND range_start < ? AND range_end > ?",
+			c.UserID, c.ExerciseRoutineID, c.FromUnit, c.ToUnit, c.RangeEnd, c.RangeStart).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ConvertHistoricalUnits rescales the weights of the sets logged in
+// c.FromUnit by c.Factor, relabels them c.ToUnit and records the conversion
+// in one transaction, it returns the exercises whose sets changed
+func ConvertHistoricalUnits(db *gorm.DB, c *UnitConversion) ([]uint, error) {
+	var exerciseIds []uint
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Raw(convertibleExercises, unitConversionParams(c)).Scan(&exerciseIds).Error; err != nil {
+			return err
+		}
+		if len(exerciseIds) > 0 {
+			result := tx.Model(&SetEntry{}).
+				Where("exercise_id IN ? AND unit = ?", exerciseIds, c.FromUnit).
+				Updates(map[string]interface{}{
+					"weight": gorm.Expr("ROUND((weight * ?)::numeric, 2)", c.Factor),
+					"unit":   c.ToUnit,
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			c.SetsConverted = result.RowsAffected
+		}
+		if err := touchExercises(tx, exerciseIds); err != nil {
+			return err
+		}
+		return tx.Create(c).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return exerciseIds, nil
+}
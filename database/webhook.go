@@ -0,0 +1,109 @@
+package database
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func AddWebhook(db *gorm.DB, webhook *Webhook) error {
+	return db.Create(webhook).Error
+}
+
+func CountWebhooks(db *gorm.DB, userId string) (int64, error) {
+	var count int64
+	err := db.Model(&Webhook{}).Where("user_id = ?", userId).Count(&count).Error
+	return count, err
+}
+
+func GetWebhook(db *gorm.DB, webhookId string) (*Webhook, error) {
+	var webhook Webhook
+	result := db.Where("id = ?", webhookId).First(&webhook)
+	return &webhook, result.Error
+}
+
+func GetWebhooks(db *gorm.DB, userId string) ([]Webhook, error) {
+	var webhooks []Webhook
+	result := db.Where("user_id = ?", userId).Order("id").Find(&webhooks)
+	return webhooks, result.Error
+}
+
+// DeleteWebhook also stops deliveries that are still waiting to go out
+func DeleteWebhook(db *gorm.DB, webhookId string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&WebhookDelivery{}).
+			Where("webhook_id = ? AND next_attempt_at IS NOT NULL", webhookId).
+			Update("next_attempt_at", nil).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("id = ?", webhookId).Delete(&Webhook{}).Error
+	})
+}
+
+// queueWebhookDeliveries adds a delivery of the finished session for each of
+// its owner's webhooks, due right away
+func queueWebhookDeliveries(tx *gorm.DB, workoutSession *WorkoutSession) error {
+	return tx.Exec(`INSERT INTO webhook_deliveries (created_at, updated_at, webhook_id, workout_session_id, attempts, next_attempt_at, last_error)
+		SELECT NOW(), NOW(), id, ?, 0, NOW(), '' FROM webhooks WHERE user_id = ? AND deleted_at IS NULL`,
+		workoutSession.ID, workoutSession.UserID,
+	).Error
+}
+
+// GetDueWebhookDeliveries is up to limit deliveries whose next attempt is at
+// or before now, with their webhook, oldest first
+func GetDueWebhookDeliveries(db *gorm.DB, now time.Time, limit int) ([]WebhookDelivery, error) {
+	var deliveries []WebhookDelivery
+	result := db.
+		Joins("Webhook").
+		Where(`webhook_deliveries.next_attempt_at <= ? AND "Webhook"."id" IS NOT NULL`, now).
+		Order("webhook_deliveries.next_attempt_at, webhook_deliveries.id").
+		Limit(limit).
+		Find(&deliveries)
+	return deliveries, result.Error
+}
+
+func MarkWebhookDelivered(db *gorm.DB, deliveryId uint, at time.Time) error {
+	return db.Model(&WebhookDelivery{}).Where("id = ?", deliveryId).Updates(map[string]interface{}{
+		"attempts":        gorm.Expr("attempts + 1"),
+		"next_attempt_at": nil,
+		"delivered_at":    at,
+		"last_error":      "",
+	}).Error
+}
+
+// MarkWebhookFailed records a failed attempt, a nil nextAttemptAt gives up
+// on the delivery
+func MarkWebhookFailed(db *gorm.DB, deliveryId uint, nextAttemptAt *time.Time, reason string) error {
+	if len(reason) > 512 {
+		reason = reason[:512]
+	}
+	return db.Model(&WebhookDelivery{}).Where("id = ?", deliveryId).Updates(map[string]interface{}{
+		"attempts":        gorm.Expr("attempts + 1"),
+		"next_attempt_at": nextAttemptAt,
+		"last_error":      reason,
+	}).Error
+}
+
+// GetFinishedWorkoutSession is a session with its routine, exercises and
+// sets in the order they were done, routines are loaded even when soft
+// deleted so every exercise keeps its name
+func GetFinishedWorkoutSession(db *gorm.DB, workoutSessionId uint) (*WorkoutSession, error) {
+	var workoutSession WorkoutSession
+	result := db.
+		Preload("WorkoutRoutine", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		}).
+		Preload("Exercises", func(db *gorm.DB) *gorm.DB {
+			return db.Order("id")
+		}).
+		Preload("Exercises.ExerciseRoutine", func(db *gorm.DB) *gorm.DB {
+			return db.Unscoped()
+		}).
+		Preload("Exercises.Sets", func(db *gorm.DB) *gorm.DB {
+			return db.Order("set_order, id")
+		}).
+		Where(`id = ? AND "end" IS NOT NULL`, workoutSessionId).
+		First(&workoutSession)
+	return &workoutSession, result.Error
+}
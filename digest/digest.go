@@ -0,0 +1,202 @@
+package digest
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/format"
+	"github.com/neilZon/workout-logger-api/mail"
+	"github.com/neilZon/workout-logger-api/notify"
+	"github.com/neilZon/workout-logger-api/schedule"
+	"github.com/neilZon/workout-logger-api/utils"
+	"gorm.io/gorm"
+)
+
+// Schedule is when digests go out from DIGEST_SCHEDULE, nil when it's "off"
+func Schedule() (*schedule.Schedule, error) {
+	spec := os.Getenv(config.DIGEST_SCHEDULE)
+	switch spec {
+	case "off":
+		return nil, nil
+	case "":
+		spec = config.DEFAULT_DIGEST_SCHEDULE
+	}
+	return schedule.Parse(spec)
+}
+
+// Digester sends users a summary of their last week of training on every
+// channel they didn't opt out of
+type Digester struct {
+	DB        *gorm.DB
+	Notifiers []notify.Notifier
+	BatchSize int
+}
+
+func NewDigester(db *gorm.DB, notifiers []notify.Notifier, batchSize int) *Digester {
+	return &Digester{
+		DB:        db,
+		Notifiers: notifiers,
+		BatchSize: batchSize,
+	}
+}
+
+// Start sends the week's digests every time s is due until stop is closed
+func (d *Digester) Start(s *schedule.Schedule, stop <-chan struct{}) {
+	schedule.Start(s, "weekly digest", func(at time.Time) error {
+		sent, err := d.SendWeek(at)
+		if sent > 0 {
+			log.Printf("sent %d weekly digests", sent)
+		}
+		return err
+	}, stop)
+}
+
+// SendWeek sends the digest of the week ending at weekEnd to everyone due
+// it in batches and returns how many were sent. Users who didn't train that
+// week or the one before aren't sent one. A failed notification is logged,
+// the digest still counts as sent so it isn't sent twice on the other
+// channels
+func (d *Digester) SendWeek(weekEnd time.Time) (int, error) {
+	sent := 0
+	var lastId uint
+	for {
+		recipients, err := database.GetDigestRecipients(d.DB, weekEnd, lastId, d.BatchSize)
+		if err != nil {
+			return sent, err
+		}
+
+		for i := range recipients {
+			r := &recipients[i]
+			lastId = r.ID
+
+			n, err := Compile(d.DB, r, weekEnd)
+			if err != nil {
+				return sent, err
+			}
+			if n == nil {
+				continue
+			}
+
+			claimed, err := database.ClaimWeeklyDigest(d.DB, r.ID, weekEnd)
+			if err != nil {
+				return sent, err
+			}
+			if !claimed {
+				// another instance got to it first
+				continue
+			}
+
+			to := &notify.Recipient{UserID: r.ID, Name: r.Name, Email: r.Email}
+			for _, notifier := range d.Notifiers {
+				if !wants(r, notifier.Channel()) {
+					continue
+				}
+				if err := notifier.Notify(to, n); err != nil {
+					log.Printf("error sending weekly digest to user %d by %s: %v", r.ID, notifier.Channel(), err)
+				}
+			}
+			sent++
+		}
+
+		if len(recipients) < d.BatchSize {
+			return sent, nil
+		}
+	}
+}
+
+func wants(r *database.DigestRecipient, channel string) bool {
+	switch channel {
+	case notify.ChannelEmail:
+		return r.WeeklyDigestEmail
+	case notify.ChannelPush:
+		return r.WeeklyDigestPush
+	default:
+		return false
+	}
+}
+
+// Compile is the user's digest of the week ending at weekEnd, nil when
+// they didn't train that week or the one before
+func Compile(db *gorm.DB, r *database.DigestRecipient, weekEnd time.Time) (*notify.Notification, error) {
+	userId := utils.UIntToString(r.ID)
+	weekStart := weekEnd.AddDate(0, 0, -7)
+	hints := format.NewHints(r.Locale, r.WeightUnit)
+	unit := hints.WeightUnit
+
+	week, err := database.GetTrainingTotals(db, userId, weekStart, weekEnd, unit)
+	if err != nil {
+		return nil, err
+	}
+	weekBefore, err := database.GetTrainingTotals(db, userId, weekStart.AddDate(0, 0, -7), weekStart, unit)
+	if err != nil {
+		return nil, err
+	}
+	if week.SessionCount == 0 && weekBefore.SessionCount == 0 {
+		return nil, nil
+	}
+	records, err := database.GetPersonalRecordsBetween(db, userId, weekStart, weekEnd, unit)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &mail.WeeklyDigest{
+		Name:         r.Name,
+		Sessions:     week.SessionCount,
+		Sets:         week.TotalSets,
+		Volume:       fmt.Sprintf("%g %s", math.Round(week.TotalVolume), hints.WeightUnitLabel),
+		VolumeChange: volumeChange(week.TotalVolume, weekBefore.TotalVolume),
+		Records:      []string{},
+	}
+	for _, pr := range records {
+		d.Records = append(d.Records, fmt.Sprintf("%s: %g %s x %d, up from %g", pr.Name, database.RoundWeight(pr.Weight), hints.WeightUnitLabel, pr.Reps, database.RoundWeight(pr.PreviousWeight)))
+	}
+
+	html, err := mail.WeeklyDigestBody(d)
+	if err != nil {
+		return nil, err
+	}
+
+	text := fmt.Sprintf("%d sessions, %d working sets and %s of volume", d.Sessions, d.Sets, d.Volume)
+	if d.VolumeChange != "" {
+		text += ", " + d.VolumeChange
+	}
+	text += "."
+	if len(records) > 0 {
+		text += fmt.Sprintf(" New personal records on %s.", strings.Join(recordNames(records), ", "))
+	}
+
+	return &notify.Notification{
+		Subject: "Your Week In Training",
+		Text:    text,
+		HTML:    html,
+	}, nil
+}
+
+func volumeChange(volume float64, before float64) string {
+	if before <= 0 {
+		return ""
+	}
+	percent := math.Round((volume - before) / before * 100)
+	switch {
+	case percent > 0:
+		return fmt.Sprintf("up %g%% on the week before", percent)
+	case percent < 0:
+		return fmt.Sprintf("down %g%% on the week before", -percent)
+	default:
+		return "level with the week before"
+	}
+}
+
+func recordNames(records []database.PersonalRecord) []string {
+	names := make([]string, 0, len(records))
+	for _, pr := range records {
+		names = append(names, pr.Name)
+	}
+	return names
+}
@@ -0,0 +1,185 @@
+// Package e2e is only mounted in e2e test mode. It lets end to end tests move
+// the server's clock and log in as seeded users, so ttls and deadlines can be
+// tested without waiting for them
+package e2e
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/clock"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/token"
+	"github.com/neilZon/workout-logger-api/utils"
+	"gorm.io/gorm"
+)
+
+// Enabled is whether E2E_TEST_MODE is on. Cloud Run sets K_SERVICE, and
+// there it's always off so a stray env var can't open these up in production
+func Enabled() bool {
+	return os.Getenv(config.E2E_TEST_MODE) == "true" && os.Getenv("K_SERVICE") == ""
+}
+
+// seededUser is whether email is the sandbox user or one listed in
+// E2E_USER_EMAILS, tokens are never signed for anyone else
+func seededUser(email string) bool {
+	if strings.EqualFold(email, config.SANDBOX_USER_EMAIL) {
+		return true
+	}
+	for _, seeded := range strings.Split(os.Getenv(config.E2E_USER_EMAILS), ",") {
+		seeded = strings.TrimSpace(seeded)
+		if seeded != "" && strings.EqualFold(email, seeded) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("error writing e2e response: %v", err)
+	}
+}
+
+type clockRequest struct {
+	// stops the clock at this time
+	Freeze *time.Time `json:"freeze"`
+	// moves the clock forward, like "25h"
+	Advance string `json:"advance"`
+	// back to the real time
+	Reset bool `json:"reset"`
+}
+
+type clockResponse struct {
+	Now time.Time `json:"now"`
+}
+
+// ClockHandler answers GET with the server's now. POST resets, freezes or
+// advances it, in that order when a request asks for more than one
+type ClockHandler struct{}
+
+func (h *ClockHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, clockResponse{Now: clock.Now()})
+	case http.MethodPost:
+		var req clockRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "body needs to be json like {\"freeze\": \"2023-01-01T00:00:00Z\"}", http.StatusBadRequest)
+			return
+		}
+		var advance time.Duration
+		if req.Advance != "" {
+			d, err := time.ParseDuration(req.Advance)
+			if err != nil || d < 0 {
+				http.Error(w, "advance needs to be a positive duration like 90m", http.StatusBadRequest)
+				return
+			}
+			advance = d
+		}
+
+		if req.Reset {
+			clock.Reset()
+		}
+		if req.Freeze != nil {
+			clock.Freeze(*req.Freeze)
+		}
+		clock.Advance(advance)
+		writeJSON(w, clockResponse{Now: clock.Now()})
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte("405 Method not allowed"))
+	}
+}
+
+type tokenRequest struct {
+	Email string `json:"email"`
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+// TokenHandler logs a seeded user in without a password, as of the server's
+// clock. It starts a session like login does, so the tokens refresh, log out
+// and get revoked like real ones. It checks Enabled itself so mounting it by
+// mistake doesn't open it up
+type TokenHandler struct {
+	DB *gorm.DB
+}
+
+func (h *TokenHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !Enabled() {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte("405 Method not allowed"))
+		return
+	}
+
+	var req tokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		http.Error(w, "body needs to be json like {\"email\": \"lifter@sandbox.untilfailure.app\"}", http.StatusBadRequest)
+		return
+	}
+	if !seededUser(req.Email) {
+		http.Error(w, "tokens are only signed for the sandbox user and E2E_USER_EMAILS", http.StatusForbidden)
+		return
+	}
+
+	user, err := database.GetUserByEmail(h.DB, req.Email)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	familyId, err := utils.GenerateSecureToken(16)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	tokenId, err := utils.GenerateSecureToken(16)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	err = database.StartSession(h.DB, &database.Session{
+		UserID:     user.ID,
+		FamilyID:   familyId,
+		UserAgent:  r.UserAgent(),
+		LastUsedAt: clock.Now(),
+	}, &database.RefreshToken{
+		UserID:   user.ID,
+		FamilyID: familyId,
+		TokenID:  tokenId,
+	})
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	c := &token.Credentials{
+		ID:    user.ID,
+		Email: user.Email,
+		Name:  user.Name,
+	}
+	writeJSON(w, tokenResponse{
+		AccessToken:  token.SignAccess(c, familyId, []byte(os.Getenv(config.ACCESS_SECRET)), config.ACCESS_TTL),
+		RefreshToken: token.SignRefresh(c, familyId, tokenId, []byte(os.Getenv(config.REFRESH_SECRET)), config.REFRESH_TTL),
+	})
+}
@@ -0,0 +1,112 @@
+package errors
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Code goes in the code extension of an error so clients can branch on it
+// instead of parsing the message
+type Code string
+
+const (
+	NotFoundCode     Code = "NOT_FOUND"
+	ForbiddenCode    Code = "FORBIDDEN"
+	InvalidInputCode Code = "INVALID_INPUT"
+	InternalCode     Code = "INTERNAL"
+
+	UnauthorizedCode     Code = "UNAUTHORIZED"
+	SessionLockedCode    Code = "SESSION_LOCKED"
+	RateLimitedCode      Code = "RATE_LIMITED"
+	UpgradeRequiredCode  Code = "UPGRADE_REQUIRED"
+	ResponseTooLargeCode Code = "RESPONSE_TOO_LARGE"
+	ConflictCode         Code = "CONFLICT"
+	LoginLockedCode      Code = "LOGIN_LOCKED"
+)
+
+// Error is an error with a message that's fine to show users and a code for
+// the client to branch on
+type Error struct {
+	Code    Code
+	Message string
+	// set on INVALID_INPUT errors from validating a whole input
+	Fields []FieldError
+}
+
+// FieldError is one input field that isn't valid, Field is its path from the
+// argument like workout.exercises.0.setEntries.1.reps
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func Errorf(code Code, format string, args ...interface{}) *Error {
+	return &Error{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func NotFound(format string, args ...interface{}) *Error {
+	return Errorf(NotFoundCode, format, args...)
+}
+
+func Forbidden(format string, args ...interface{}) *Error {
+	return Errorf(ForbiddenCode, format, args...)
+}
+
+func InvalidInput(format string, args ...interface{}) *Error {
+	return Errorf(InvalidInputCode, format, args...)
+}
+
+// InvalidFields is an INVALID_INPUT error listing every field that's wrong,
+// its message is theirs joined together
+func InvalidFields(fields []FieldError) *Error {
+	messages := make([]string, 0, len(fields))
+	for _, f := range fields {
+		messages = append(messages, f.Message)
+	}
+	return &Error{
+		Code:    InvalidInputCode,
+		Message: strings.Join(messages, "; "),
+		Fields:  fields,
+	}
+}
+
+func Internal(format string, args ...interface{}) *Error {
+	return Errorf(InternalCode, format, args...)
+}
+
+// From is for presenting err with a message of its own, it keeps err's code
+// and calls a missing row NOT_FOUND. Anything else is INTERNAL
+func From(err error, format string, args ...interface{}) *Error {
+	return Errorf(CodeOf(err), format, args...)
+}
+
+func CodeOf(err error) Code {
+	var codedErr *Error
+	if errors.As(err, &codedErr) {
+		return codedErr.Code
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return NotFoundCode
+	}
+	return InternalCode
+}
+
+// Is and As are the standard library's, so files using this package don't
+// need both imports
+func Is(err, target error) bool {
+	return errors.Is(err, target)
+}
+
+func As(err error, target interface{}) bool {
+	return errors.As(err, target)
+}
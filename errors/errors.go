@@ -13,4 +13,6 @@ const (
 	GetWorkoutSessionError    = "Could not get workout session, %s"
 	UpdateWorkoutSessionError = "Could not update session session, %s"
 	DeleteWorkoutSessionError = "Could not delete workout session, %s"
+
+	GetWorkoutStatsError = "Could not get workout stats, %s"
 )
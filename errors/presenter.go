@@ -0,0 +1,74 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"math"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/neilZon/workout-logger-api/common"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Present is the server's error presenter, every error it hands back has a code
+func Present(ctx context.Context, e error) *gqlerror.Error {
+	err := graphql.DefaultErrorPresenter(ctx, e)
+
+	// add status code for unauthorized errors so client knows to refresh token
+	var unauthorizedError *common.UnauthorizedError
+	if errors.As(e, &unauthorizedError) {
+		err.Extensions = map[string]interface{}{
+			"code": string(UnauthorizedCode),
+		}
+		return err
+	}
+	// so the app knows to offer unlocking the session
+	var lockedError *common.WorkoutSessionLockedError
+	if errors.As(e, &lockedError) {
+		err.Extensions = map[string]interface{}{
+			"code":          string(SessionLockedCode),
+			"lockAfterDays": lockedError.LockAfterDays,
+		}
+		return err
+	}
+
+	// so the app can say how long until it can try again
+	var loginLockedError *common.LoginLockedError
+	if errors.As(e, &loginLockedError) {
+		err.Extensions = map[string]interface{}{
+			"code":              string(LoginLockedCode),
+			"retryAfterSeconds": int(math.Ceil(loginLockedError.RetryAfter.Seconds())),
+		}
+		return err
+	}
+
+	// so the app can merge its change into what's on the server now
+	var conflictError *common.ConflictError
+	if errors.As(e, &conflictError) {
+		err.Extensions = map[string]interface{}{
+			"code":    string(ConflictCode),
+			"current": conflictError.Current,
+		}
+		return err
+	}
+
+	// so apps can point at the fields that need fixing
+	var codedErr *Error
+	if errors.As(e, &codedErr) && len(codedErr.Fields) > 0 {
+		err.Extensions = map[string]interface{}{
+			"code":   string(codedErr.Code),
+			"fields": codedErr.Fields,
+		}
+		return err
+	}
+
+	// errors the resolvers didn't give a code, like ones from a dataloader,
+	// are INTERNAL unless they're a missing row
+	if _, ok := err.Extensions["code"]; !ok {
+		if err.Extensions == nil {
+			err.Extensions = map[string]interface{}{}
+		}
+		err.Extensions["code"] = string(CodeOf(e))
+	}
+	return err
+}
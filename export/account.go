@@ -0,0 +1,442 @@
+package export
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/archive"
+	"github.com/neilZon/workout-logger-api/clock"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/media"
+	"github.com/neilZon/workout-logger-api/utils"
+	"gorm.io/gorm"
+)
+
+// accountArchive is everything a user has logged, shaped for people reading
+// it rather than mirroring the tables. passwords and codes are left out
+type accountArchive struct {
+	ExportedAt        time.Time         `json:"exportedAt"`
+	Profile           profile           `json:"profile"`
+	WorkoutRoutines   []workoutRoutine  `json:"workoutRoutines"`
+	WorkoutSessions   []workoutSession  `json:"workoutSessions"`
+	BodyWeightEntries []bodyWeightEntry `json:"bodyWeightEntries"`
+	Goals             []goal            `json:"goals"`
+	NutritionLogs     []nutritionLog    `json:"nutritionLogs"`
+	SleepLogs         []sleepLog        `json:"sleepLogs"`
+	QuickPhrases      []string          `json:"quickPhrases"`
+}
+
+type profile struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Verified  bool      `json:"verified"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type workoutRoutine struct {
+	ID               uint              `json:"id"`
+	Name             string            `json:"name"`
+	Active           bool              `json:"active"`
+	CreatedAt        time.Time         `json:"createdAt"`
+	ExerciseRoutines []exerciseRoutine `json:"exerciseRoutines"`
+}
+
+type exerciseRoutine struct {
+	ID     uint   `json:"id"`
+	Name   string `json:"name"`
+	Sets   uint   `json:"sets"`
+	Reps   uint   `json:"reps"`
+	Active bool   `json:"active"`
+}
+
+type workoutSession struct {
+	ID               uint                `json:"id"`
+	WorkoutRoutineID uint                `json:"workoutRoutineId"`
+	WorkoutRoutine   string              `json:"workoutRoutine"`
+	Start            time.Time           `json:"start"`
+	End              *time.Time          `json:"end"`
+	Archived         bool                `json:"archived"`
+	Exercises        []exercise          `json:"exercises"`
+	ClientMetadata   database.JSONObject `json:"clientMetadata,omitempty"`
+	Origin           *origin             `json:"origin,omitempty"`
+}
+
+type exercise struct {
+	ID                uint       `json:"id"`
+	ExerciseRoutineID uint       `json:"exerciseRoutineId"`
+	Name              string     `json:"name"`
+	Notes             string     `json:"notes"`
+	Sets              []setEntry `json:"sets"`
+}
+
+type setEntry struct {
+	SetOrder        uint                `json:"setOrder"`
+	Type            string              `json:"type"`
+	Weight          float64             `json:"weight"`
+	Unit            string              `json:"unit"`
+	Reps            uint                `json:"reps"`
+	RestTimeSeconds *uint               `json:"restTimeSeconds"`
+	CompletedAt     *time.Time          `json:"completedAt"`
+	ClientMetadata  database.JSONObject `json:"clientMetadata,omitempty"`
+	Origin          *origin             `json:"origin,omitempty"`
+}
+
+// the app that logged a session or set, left out when it isn't known
+type origin struct {
+	Platform   string `json:"platform"`
+	AppVersion string `json:"appVersion"`
+	DeviceID   string `json:"deviceId"`
+}
+
+type bodyWeightEntry struct {
+	Weight   float32   `json:"weight"`
+	LoggedAt time.Time `json:"loggedAt"`
+}
+
+type nutritionLog struct {
+	Day          string   `json:"day"`
+	Calories     *uint    `json:"calories"`
+	ProteinGrams *float32 `json:"proteinGrams"`
+	Notes        string   `json:"notes"`
+}
+
+type sleepLog struct {
+	Night string  `json:"night"`
+	Hours float32 `json:"hours"`
+}
+
+type goal struct {
+	Type              string     `json:"type"`
+	TargetValue       float32    `json:"targetValue"`
+	StartValue        *float32   `json:"startValue"`
+	CatalogExerciseID *uint      `json:"catalogExerciseId"`
+	Deadline          time.Time  `json:"deadline"`
+	CompletedAt       *time.Time `json:"completedAt"`
+}
+
+// AccountExporter builds requested account exports in the background. The
+// archive store holds both the archived sessions it reads and the exports it
+// writes, neither is served publicly
+type AccountExporter struct {
+	DB        *gorm.DB
+	Store     media.Store
+	BatchSize int
+	TTL       time.Duration
+}
+
+func NewAccountExporter(db *gorm.DB, store media.Store, batchSize int, ttl time.Duration) *AccountExporter {
+	return &AccountExporter{
+		DB:        db,
+		Store:     store,
+		BatchSize: batchSize,
+		TTL:       ttl,
+	}
+}
+
+// Start builds pending exports and removes expired ones every interval until
+// stop is closed
+func (e *AccountExporter) Start(interval time.Duration, stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				ctx := context.Background()
+				built, err := e.RunPending(ctx, clock.Now())
+				if err != nil {
+					log.Printf("error building account exports: %v", err)
+				} else if built > 0 {
+					log.Printf("built %d account exports", built)
+				}
+				if err := e.RemoveExpired(ctx, clock.Now()); err != nil {
+					log.Printf("error removing expired account exports: %v", err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// RunPending builds up to BatchSize pending exports. an export that fails to
+// build is marked failed so the user can ask for a new one
+func (e *AccountExporter) RunPending(ctx context.Context, now time.Time) (int, error) {
+	accountExports, err := database.GetPendingAccountExports(e.DB, e.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	built := 0
+	for i := range accountExports {
+		accountExport := &accountExports[i]
+		started, err := database.StartAccountExport(e.DB, accountExport.ID)
+		if err != nil {
+			return built, err
+		}
+		if !started {
+			continue
+		}
+
+		key := AccountExportStorageKey(accountExport.UserID, accountExport.ID)
+		status := database.AccountExportStatusReady
+		if err := e.build(ctx, accountExport.UserID, key, now); err != nil {
+			log.Printf("error building account export %d: %v", accountExport.ID, err)
+			status = database.AccountExportStatusFailed
+			key = ""
+		} else {
+			built++
+		}
+
+		if err := database.FinishAccountExport(e.DB, accountExport.ID, status, key, now); err != nil {
+			return built, err
+		}
+	}
+	return built, nil
+}
+
+// RemoveExpired deletes ready exports older than TTL along with their blobs
+func (e *AccountExporter) RemoveExpired(ctx context.Context, now time.Time) error {
+	accountExports, err := database.GetExpiredAccountExports(e.DB, now.Add(-e.TTL), e.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	for i := range accountExports {
+		if err := e.Store.Delete(ctx, accountExports[i].StorageKey); err != nil {
+			log.Printf("error deleting account export %s: %v", accountExports[i].StorageKey, err)
+		}
+		if err := database.DeleteAccountExport(e.DB, accountExports[i].ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *AccountExporter) build(ctx context.Context, userId uint, key string, now time.Time) error {
+	data, err := e.collect(ctx, userId, now)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	encoder := json.NewEncoder(gz)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(data); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	return e.Store.Save(ctx, key, &buf)
+}
+
+// collect loads the whole account into memory, fine for a background job
+// working through one export at a time
+func (e *AccountExporter) collect(ctx context.Context, userId uint, now time.Time) (*accountArchive, error) {
+	id := utils.UIntToString(userId)
+
+	user, err := database.GetUserById(e.DB, id)
+	if err != nil {
+		return nil, err
+	}
+	data := accountArchive{
+		ExportedAt: now,
+		Profile: profile{
+			ID:        user.ID,
+			Name:      user.Name,
+			Email:     user.Email,
+			Verified:  user.Verified,
+			CreatedAt: user.CreatedAt,
+		},
+		WorkoutRoutines:   make([]workoutRoutine, 0),
+		WorkoutSessions:   make([]workoutSession, 0),
+		BodyWeightEntries: make([]bodyWeightEntry, 0),
+		Goals:             make([]goal, 0),
+		NutritionLogs:     make([]nutritionLog, 0),
+		SleepLogs:         make([]sleepLog, 0),
+		QuickPhrases:      make([]string, 0),
+	}
+
+	dbWorkoutRoutines, err := database.GetAccountWorkoutRoutines(e.DB, id)
+	if err != nil {
+		return nil, err
+	}
+	// archived sessions only keep ids, names come from the live routines
+	workoutRoutineNames := make(map[uint]string)
+	exerciseRoutineNames := make(map[uint]string)
+	for _, wr := range dbWorkoutRoutines {
+		workoutRoutineNames[wr.ID] = wr.Name
+		routine := workoutRoutine{
+			ID:               wr.ID,
+			Name:             wr.Name,
+			Active:           wr.Active,
+			CreatedAt:        wr.CreatedAt,
+			ExerciseRoutines: make([]exerciseRoutine, 0),
+		}
+		for _, er := range wr.ExerciseRoutines {
+			exerciseRoutineNames[er.ID] = er.Name
+			routine.ExerciseRoutines = append(routine.ExerciseRoutines, exerciseRoutine{
+				ID:     er.ID,
+				Name:   er.Name,
+				Sets:   er.Sets,
+				Reps:   er.Reps,
+				Active: er.Active,
+			})
+		}
+		data.WorkoutRoutines = append(data.WorkoutRoutines, routine)
+	}
+
+	dbWorkoutSessions, err := database.GetAccountWorkoutSessions(e.DB, id)
+	if err != nil {
+		return nil, err
+	}
+	for i := range dbWorkoutSessions {
+		data.WorkoutSessions = append(data.WorkoutSessions, toWorkoutSession(&dbWorkoutSessions[i], userId, false, workoutRoutineNames, exerciseRoutineNames))
+	}
+
+	archivedWorkoutSessions, err := database.GetArchivedWorkoutSessions(e.DB, id)
+	if err != nil {
+		return nil, err
+	}
+	for i := range archivedWorkoutSessions {
+		ws, err := archive.Load(ctx, e.Store, &archivedWorkoutSessions[i])
+		if err != nil {
+			return nil, fmt.Errorf("loading archived workout session %d: %w", archivedWorkoutSessions[i].WorkoutSessionID, err)
+		}
+		data.WorkoutSessions = append(data.WorkoutSessions, toWorkoutSession(ws, userId, true, workoutRoutineNames, exerciseRoutineNames))
+	}
+
+	entries, err := database.GetAllBodyWeightEntries(e.DB, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		data.BodyWeightEntries = append(data.BodyWeightEntries, bodyWeightEntry{
+			Weight:   entry.Weight,
+			LoggedAt: entry.LoggedAt,
+		})
+	}
+
+	goals, err := database.GetGoals(e.DB, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, g := range goals {
+		data.Goals = append(data.Goals, goal{
+			Type:              g.Type,
+			TargetValue:       g.TargetValue,
+			StartValue:        g.StartValue,
+			CatalogExerciseID: g.CatalogExerciseID,
+			Deadline:          g.Deadline,
+			CompletedAt:       g.CompletedAt,
+		})
+	}
+
+	nutritionLogs, err := database.GetAllNutritionLogs(e.DB, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range nutritionLogs {
+		data.NutritionLogs = append(data.NutritionLogs, nutritionLog{
+			Day:          n.Day.Format("2006-01-02"),
+			Calories:     n.Calories,
+			ProteinGrams: n.ProteinGrams,
+			Notes:        n.Notes,
+		})
+	}
+
+	sleepLogs, err := database.GetAllSleepLogs(e.DB, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sleepLogs {
+		data.SleepLogs = append(data.SleepLogs, sleepLog{
+			Night: s.Night.Format("2006-01-02"),
+			Hours: s.Hours,
+		})
+	}
+
+	quickPhrases, err := database.GetQuickPhrases(e.DB, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, qp := range quickPhrases {
+		data.QuickPhrases = append(data.QuickPhrases, qp.Text)
+	}
+
+	return &data, nil
+}
+
+// only the exercises userId logged end up in their export, co-loggers get
+// their own
+func toWorkoutSession(ws *database.WorkoutSession, userId uint, archived bool, workoutRoutineNames map[uint]string, exerciseRoutineNames map[uint]string) workoutSession {
+	session := workoutSession{
+		ID:               ws.ID,
+		WorkoutRoutineID: ws.WorkoutRoutineID,
+		WorkoutRoutine:   ws.WorkoutRoutine.Name,
+		Start:            ws.Start,
+		End:              ws.End,
+		Archived:         archived,
+		Exercises:        make([]exercise, 0),
+		ClientMetadata:   ws.ClientMetadata,
+		Origin:           toOrigin(ws.Origin),
+	}
+	if session.WorkoutRoutine == "" {
+		session.WorkoutRoutine = workoutRoutineNames[ws.WorkoutRoutineID]
+	}
+
+	for _, e := range ws.Exercises {
+		if e.UserID != userId {
+			continue
+		}
+		ex := exercise{
+			ID:                e.ID,
+			ExerciseRoutineID: e.ExerciseRoutineID,
+			Name:              e.ExerciseRoutine.Name,
+			Notes:             e.Notes,
+			Sets:              make([]setEntry, 0),
+		}
+		if ex.Name == "" {
+			ex.Name = exerciseRoutineNames[e.ExerciseRoutineID]
+		}
+		for _, s := range e.Sets {
+			ex.Sets = append(ex.Sets, setEntry{
+				SetOrder:        s.SetOrder,
+				Type:            s.Type,
+				Weight:          s.Weight,
+				Unit:            s.Unit,
+				Reps:            s.Reps,
+				RestTimeSeconds: s.RestTimeSeconds,
+				CompletedAt:     s.CompletedAt,
+				ClientMetadata:  s.ClientMetadata,
+				Origin:          toOrigin(s.Origin),
+			})
+		}
+		session.Exercises = append(session.Exercises, ex)
+	}
+	return session
+}
+
+func toOrigin(o database.Origin) *origin {
+	if o == (database.Origin{}) {
+		return nil
+	}
+	return &origin{
+		Platform:   o.Platform,
+		AppVersion: o.AppVersion,
+		DeviceID:   o.DeviceID,
+	}
+}
+
+func AccountExportStorageKey(userId uint, accountExportId uint) string {
+	return fmt.Sprintf("exports/accounts/%d/%d.json.gz", userId, accountExportId)
+}
@@ -0,0 +1,77 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/clock"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/media"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"gorm.io/gorm"
+)
+
+// AccountExportHandler downloads a ready account export, ?id= is the export
+// id. It expects the auth middleware to have put the user in the request
+// context
+type AccountExportHandler struct {
+	DB    *gorm.DB
+	Store media.Store
+	TTL   time.Duration
+}
+
+func (h *AccountExportHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte("405 Method not allowed"))
+		return
+	}
+
+	u, err := middleware.GetUser(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if err := middleware.VerifyUser(h.DB, fmt.Sprintf("%d", u.ID)); err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	accountExportId := r.URL.Query().Get("id")
+	if accountExportId == "" {
+		http.NotFound(w, r)
+		return
+	}
+	accountExport, err := database.GetAccountExport(h.DB, accountExportId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	// someone else's export looks the same as a missing one
+	if accountExport.UserID != u.ID || accountExport.Status != database.AccountExportStatusReady ||
+		accountExport.CompletedAt == nil || clock.Since(*accountExport.CompletedAt) > h.TTL {
+		http.NotFound(w, r)
+		return
+	}
+
+	blob, err := h.Store.Open(r.Context(), accountExport.StorageKey)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer blob.Close()
+
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="account-export-%s.json.gz"`, accountExport.CompletedAt.UTC().Format("2006-01-02")))
+	if _, err := io.Copy(w, blob); err != nil {
+		log.Printf("error sending account export %d: %v", accountExport.ID, err)
+	}
+}
@@ -0,0 +1,149 @@
+// Package export writes a user's workout history out of the api in formats
+// other tools can read
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"gorm.io/gorm"
+)
+
+var csvHeader = []string{
+	"workout_session_id",
+	"start",
+	"end",
+	"workout_routine",
+	"exercise_id",
+	"exercise",
+	"notes",
+	"set_order",
+	"set_type",
+	"weight",
+	"unit",
+	"reps",
+	"rest_time_seconds",
+	"completed_at",
+}
+
+// flush to the client every so many rows instead of buffering the response
+const csvFlushEvery = 500
+
+// CSVHandler streams the caller's workout history as csv. It expects the auth
+// middleware to have put the user in the request context
+type CSVHandler struct {
+	DB *gorm.DB
+}
+
+func (h *CSVHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte("405 Method not allowed"))
+		return
+	}
+
+	u, err := middleware.GetUser(r.Context())
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	userId := fmt.Sprintf("%d", u.ID)
+	if err := middleware.VerifyUser(h.DB, userId); err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	rows, err := database.GetWorkoutHistoryRows(h.DB, userId)
+	if err != nil {
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="workout-history-%s.csv"`, time.Now().UTC().Format("2006-01-02")))
+
+	// headers are sent with the first write, after that errors can only be
+	// logged and the download ends early
+	writer := csv.NewWriter(w)
+	flusher, _ := w.(http.Flusher)
+	writer.Write(csvHeader)
+
+	written := 0
+	for rows.Next() {
+		var row database.WorkoutHistoryRow
+		if err := h.DB.ScanRows(rows, &row); err != nil {
+			log.Printf("error scanning workout history for user %s: %v", userId, err)
+			break
+		}
+		if err := writer.Write(toCSVRecord(&row)); err != nil {
+			// client went away
+			return
+		}
+
+		written++
+		if written%csvFlushEvery == 0 {
+			writer.Flush()
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("error reading workout history for user %s: %v", userId, err)
+	}
+	writer.Flush()
+}
+
+func toCSVRecord(row *database.WorkoutHistoryRow) []string {
+	return []string{
+		strconv.FormatUint(uint64(row.WorkoutSessionID), 10),
+		row.Start.UTC().Format(time.RFC3339),
+		formatTime(row.End),
+		row.WorkoutRoutine,
+		formatUint(row.ExerciseID),
+		formatString(row.Exercise),
+		formatString(row.Notes),
+		formatUint(row.SetOrder),
+		formatString(row.SetType),
+		formatWeight(row.Weight),
+		formatString(row.Unit),
+		formatUint(row.Reps),
+		formatUint(row.RestTimeSeconds),
+		formatTime(row.CompletedAt),
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatUint(n *uint) string {
+	if n == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*n), 10)
+}
+
+func formatString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func formatWeight(w *float64) string {
+	if w == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*w, 'f', -1, 64)
+}
@@ -0,0 +1,120 @@
+package format
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/neilZon/workout-logger-api/database"
+)
+
+const DefaultLocale = "en-US"
+
+// Hints is how numbers are shown to a user. Apps use them instead of their
+// own locale rules so the same weight reads the same on every device
+type Hints struct {
+	Locale            string
+	DecimalSeparator  string
+	GroupingSeparator string
+	WeightUnit        string
+	WeightUnitLabel   string
+	WeightDecimals    int
+	VolumeDecimals    int
+}
+
+type separators struct {
+	decimal  string
+	grouping string
+}
+
+// by language, languages that aren't here format like english. grouping
+// spaces are no-break ones so numbers never wrap
+var languageSeparators = map[string]separators{
+	"en": {".", ","},
+	"ja": {".", ","},
+	"ko": {".", ","},
+	"zh": {".", ","},
+	"de": {",", "."},
+	"es": {",", "."},
+	"it": {",", "."},
+	"nl": {",", "."},
+	"pt": {",", "."},
+	"da": {",", "."},
+	"tr": {",", "."},
+	"id": {",", "."},
+	"fr": {",", "\u202f"},
+	"ru": {",", "\u00a0"},
+	"uk": {",", "\u00a0"},
+	"pl": {",", "\u00a0"},
+	"cs": {",", "\u00a0"},
+	"sv": {",", "\u00a0"},
+	"nb": {",", "\u00a0"},
+	"fi": {",", "\u00a0"},
+}
+
+// regions that don't format like the rest of their language
+var regionSeparators = map[string]separators{
+	"de-CH": {".", "’"},
+	"it-CH": {".", "’"},
+	"fr-CH": {",", "\u202f"},
+	"pt-PT": {",", "\u00a0"},
+	"es-MX": {".", ","},
+}
+
+// regions that lift in pounds, everywhere else gets kilograms
+var poundRegions = map[string]bool{
+	"US": true,
+	"LR": true,
+	"MM": true,
+}
+
+var localePattern = regexp.MustCompile(`^([a-zA-Z]{2,3})(?:[-_]([a-zA-Z]{2}|[0-9]{3}))?$`)
+
+// Canonical turns a locale like en_us into en-US, ok is false when it isn't
+// a language with an optional region
+func Canonical(locale string) (string, bool) {
+	m := localePattern.FindStringSubmatch(locale)
+	if m == nil {
+		return "", false
+	}
+	if m[2] == "" {
+		return strings.ToLower(m[1]), true
+	}
+	return strings.ToLower(m[1]) + "-" + strings.ToUpper(m[2]), true
+}
+
+// NewHints works out the hints for locale, weightUnit is the user's own
+// pick and nil leaves it to the locale's region
+func NewHints(locale string, weightUnit *string) Hints {
+	locale, ok := Canonical(locale)
+	if !ok {
+		locale = DefaultLocale
+	}
+	language, region, _ := strings.Cut(locale, "-")
+
+	sep, ok := regionSeparators[locale]
+	if !ok {
+		sep, ok = languageSeparators[language]
+	}
+	if !ok {
+		sep = languageSeparators["en"]
+	}
+
+	unit := database.WeightUnitKg
+	if poundRegions[region] {
+		unit = database.WeightUnitLb
+	}
+	if weightUnit != nil {
+		unit = *weightUnit
+	}
+
+	return Hints{
+		Locale:            locale,
+		DecimalSeparator:  sep.decimal,
+		GroupingSeparator: sep.grouping,
+		WeightUnit:        unit,
+		WeightUnitLabel:   strings.ToLower(unit),
+		// plates go down to 1.25kg and 2.5lb, volume is only ever a total
+		WeightDecimals: 2,
+		VolumeDecimals: 0,
+	}
+}
@@ -0,0 +1,222 @@
+package goal
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/clock"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/mail"
+	"github.com/neilZon/workout-logger-api/utils"
+	"gorm.io/gorm"
+)
+
+// statuses line up with the graphql GoalStatus enum
+const (
+	StatusInProgress = "IN_PROGRESS"
+	StatusCompleted  = "COMPLETED"
+	StatusExpired    = "EXPIRED"
+)
+
+type Progress struct {
+	Current  *float64 // nil when nothing was logged yet
+	Fraction float64  // 0 at the start value, 1 at the target
+	Reached  bool
+}
+
+// CurrentValue is the latest logged body weight for body weight goals and
+// the best estimated one rep max in the goal's unit for lift goals
+func CurrentValue(db *gorm.DB, g *database.Goal) (*float64, error) {
+	userId := utils.UIntToString(g.UserID)
+	switch g.Type {
+	case database.GoalTypeBodyWeight:
+		return database.GetLatestBodyWeight(db, userId)
+	case database.GoalTypeLiftE1RM:
+		if g.CatalogExerciseID == nil {
+			return nil, fmt.Errorf("lift goal %d has no catalog exercise", g.ID)
+		}
+		return database.GetBestEstimatedOneRepMax(db, userId, *g.CatalogExerciseID, g.Unit)
+	default:
+		return nil, fmt.Errorf("unknown goal type %s", g.Type)
+	}
+}
+
+func Evaluate(db *gorm.DB, g *database.Goal) (*Progress, error) {
+	current, err := CurrentValue(db, g)
+	if err != nil {
+		return nil, err
+	}
+	return Measure(g, current), nil
+}
+
+// Measure works out progress from an already fetched current value. body
+// weight goals can go either way so the direction comes from where the user
+// started, lifts only ever go up
+func Measure(g *database.Goal, current *float64) *Progress {
+	p := Progress{Current: current}
+	if current == nil {
+		return &p
+	}
+
+	start := 0.0
+	if g.StartValue != nil {
+		start = float64(*g.StartValue)
+	}
+	target := float64(g.TargetValue)
+
+	if g.Type == database.GoalTypeBodyWeight && target < start {
+		p.Reached = *current <= target
+	} else {
+		p.Reached = *current >= target
+	}
+
+	if p.Reached {
+		p.Fraction = 1
+	} else if target != start {
+		p.Fraction = clamp((*current - start) / (target - start))
+	}
+	return &p
+}
+
+func clamp(f float64) float64 {
+	if f < 0 {
+		return 0
+	}
+	if f > 1 {
+		return 1
+	}
+	return f
+}
+
+// Status counts a goal reached before its deadline but not yet picked up by
+// the Checker as completed, once completed it stays that way
+func Status(g *database.Goal, p *Progress, now time.Time) string {
+	if g.CompletedAt != nil {
+		return StatusCompleted
+	}
+	if !g.Deadline.After(now) {
+		return StatusExpired
+	}
+	if p.Reached {
+		return StatusCompleted
+	}
+	return StatusInProgress
+}
+
+// Checker marks open goals as completed once the logged data reaches them
+// and notifies the user
+type Checker struct {
+	DB        *gorm.DB
+	BatchSize int
+	Notify    func(user *database.User, g *database.Goal) error
+}
+
+func NewChecker(db *gorm.DB, batchSize int) *Checker {
+	return &Checker{
+		DB:        db,
+		BatchSize: batchSize,
+		Notify:    EmailNotification(db),
+	}
+}
+
+// Start checks open goals every interval until stop is closed
+func (c *Checker) Start(interval time.Duration, stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				completed, err := c.CheckOpenGoals(clock.Now())
+				if err != nil {
+					log.Printf("error checking goals: %v", err)
+				} else if completed > 0 {
+					log.Printf("completed %d goals", completed)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// CheckOpenGoals evaluates open goals in batches and returns how many were
+// completed. a failed notification is logged but the goal stays completed so
+// users don't get the same email every interval
+func (c *Checker) CheckOpenGoals(now time.Time) (int, error) {
+	completed := 0
+	var lastId uint
+	for {
+		goals, err := database.GetOpenGoals(c.DB.Where("id > ?", lastId), now, c.BatchSize)
+		if err != nil {
+			return completed, err
+		}
+
+		for i := range goals {
+			g := &goals[i]
+			lastId = g.ID
+
+			p, err := Evaluate(c.DB, g)
+			if err != nil {
+				return completed, err
+			}
+			if !p.Reached {
+				continue
+			}
+
+			marked, err := database.CompleteGoal(c.DB, g.ID, now)
+			if err != nil {
+				return completed, err
+			}
+			if !marked {
+				continue
+			}
+			completed++
+			g.CompletedAt = &now
+
+			user, err := database.GetUserById(c.DB, utils.UIntToString(g.UserID))
+			if err != nil {
+				log.Printf("error getting user for goal %d: %v", g.ID, err)
+				continue
+			}
+			if err := c.Notify(user, g); err != nil {
+				log.Printf("error notifying user %d of goal %d: %v", g.UserID, g.ID, err)
+			}
+		}
+
+		if len(goals) < c.BatchSize {
+			return completed, nil
+		}
+	}
+}
+
+// EmailNotification emails the user which goal they reached
+func EmailNotification(db *gorm.DB) func(user *database.User, g *database.Goal) error {
+	return func(user *database.User, g *database.Goal) error {
+		description, err := Describe(db, g)
+		if err != nil {
+			return err
+		}
+		return mail.SendGoalCompleted(user.Name, description, user.Email)
+	}
+}
+
+// Describe puts the goal in words for notifications
+func Describe(db *gorm.DB, g *database.Goal) (string, error) {
+	switch g.Type {
+	case database.GoalTypeBodyWeight:
+		return fmt.Sprintf("a body weight of %g", g.TargetValue), nil
+	case database.GoalTypeLiftE1RM:
+		if g.CatalogExerciseID == nil {
+			return "", fmt.Errorf("lift goal %d has no catalog exercise", g.ID)
+		}
+		catalogExercise, err := database.GetCatalogExercise(db, utils.UIntToString(*g.CatalogExerciseID))
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("an estimated one rep max of %g on %s", g.TargetValue, catalogExercise.Name), nil
+	default:
+		return "", fmt.Errorf("unknown goal type %s", g.Type)
+	}
+}
@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/utils"
+	"gorm.io/gorm"
+)
+
+// ExportAccountData is the resolver for the exportAccountData field.
+func (r *mutationResolver) ExportAccountData(ctx context.Context) (*model.AccountExport, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.AccountExport{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.AccountExport{}, err
+	}
+
+	// asking again while one is being built just hands back that one
+	accountExport, err := database.GetUnfinishedAccountExport(r.db(ctx), utils.UIntToString(u.ID))
+	if err == nil {
+		return toAccountExport(accountExport), nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return &model.AccountExport{}, errors.From(err, "Error Exporting Account Data")
+	}
+
+	accountExport = &database.AccountExport{
+		UserID: u.ID,
+		Status: database.AccountExportStatusPending,
+	}
+	err = database.AddAccountExport(r.db(ctx), accountExport)
+	if err != nil {
+		return &model.AccountExport{}, errors.From(err, "Error Exporting Account Data")
+	}
+
+	return toAccountExport(accountExport), nil
+}
+
+// AccountExport is the resolver for the accountExport field.
+func (r *queryResolver) AccountExport(ctx context.Context, accountExportID string) (*model.AccountExport, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.AccountExport{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.AccountExport{}, err
+	}
+
+	accountExport, err := database.GetAccountExport(r.db(ctx), accountExportID)
+	if err != nil {
+		return &model.AccountExport{}, errors.From(err, "Error Getting Account Export")
+	}
+	if accountExport.UserID != u.ID {
+		return &model.AccountExport{}, errors.Forbidden("Error Getting Account Export: Access Denied")
+	}
+
+	return toAccountExport(accountExport), nil
+}
+
+func toAccountExport(accountExport *database.AccountExport) *model.AccountExport {
+	var downloadURL *string
+	if accountExport.Status == database.AccountExportStatusReady {
+		url := fmt.Sprintf("%s/export/account?id=%d", os.Getenv(config.HOST), accountExport.ID)
+		downloadURL = &url
+	}
+	return &model.AccountExport{
+		ID:          utils.UIntToString(accountExport.ID),
+		Status:      model.AccountExportStatus(accountExport.Status),
+		RequestedAt: accountExport.CreatedAt,
+		CompletedAt: accountExport.CompletedAt,
+		DownloadURL: downloadURL,
+	}
+}
@@ -0,0 +1,153 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/neilZon/workout-logger-api/clock"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/token"
+	"github.com/neilZon/workout-logger-api/utils"
+	"gorm.io/gorm"
+)
+
+// AdminUsers is the resolver for the adminUsers field.
+func (r *queryResolver) AdminUsers(ctx context.Context, query *string, limit *int, after *string) ([]*model.AdminUser, error) {
+	n := 50
+	if limit != nil {
+		n = *limit
+	}
+	if n <= 0 || n > 100 {
+		return []*model.AdminUser{}, errors.InvalidInput("limit needs to be between 1 to 100")
+	}
+
+	q, cursor := "", ""
+	if query != nil {
+		q = *query
+	}
+	if after != nil {
+		cursor = *after
+	}
+	dbUsers, err := database.GetUsers(r.db(ctx), q, cursor, n)
+	if err != nil {
+		return []*model.AdminUser{}, errors.From(err, "Error Getting Users")
+	}
+
+	users := make([]*model.AdminUser, 0, len(dbUsers))
+	for _, u := range dbUsers {
+		users = append(users, &model.AdminUser{
+			ID:        utils.UIntToString(u.ID),
+			Name:      u.Name,
+			Email:     u.Email,
+			Verified:  u.Verified,
+			Admin:     u.Admin,
+			CreatedAt: u.CreatedAt,
+		})
+	}
+	return users, nil
+}
+
+// UsageStats is the resolver for the usageStats field.
+func (r *queryResolver) UsageStats(ctx context.Context) (*model.UsageStats, error) {
+	stats, err := database.GetUsageStats(r.db(ctx), clock.Now())
+	if err != nil {
+		return nil, errors.From(err, "Error Getting Usage Stats")
+	}
+
+	return &model.UsageStats{
+		Users:              int(stats.Users),
+		VerifiedUsers:      int(stats.VerifiedUsers),
+		WeeklyActiveUsers:  int(stats.WeeklyActiveUsers),
+		MonthlyActiveUsers: int(stats.MonthlyActiveUsers),
+		WorkoutSessions:    int(stats.WorkoutSessions),
+		Sets:               int(stats.Sets),
+	}, nil
+}
+
+// ImpersonateUser is the resolver for the impersonateUser field.
+func (r *mutationResolver) ImpersonateUser(ctx context.Context, userID string, reason string) (*model.Impersonation, error) {
+	admin, err := middleware.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(reason) < 10 || len(reason) > 512 {
+		return nil, errors.InvalidInput("Error Impersonating User: reason needs to be between 10 and 512 characters")
+	}
+
+	user, err := database.GetUserById(r.db(ctx), userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.NotFound("Error Impersonating User: user %s does not exist", userID)
+	}
+	if err != nil {
+		return nil, errors.From(err, "Error Impersonating User")
+	}
+	if user.Admin {
+		return nil, errors.Forbidden("Error Impersonating User: admins can't be impersonated")
+	}
+
+	c := &token.Credentials{
+		ID:             user.ID,
+		Email:          user.Email,
+		Name:           user.Name,
+		ImpersonatedBy: admin.ID,
+	}
+	return &model.Impersonation{
+		AccessToken: token.Sign(c, []byte(os.Getenv(config.ACCESS_SECRET)), config.IMPERSONATION_TTL),
+		ExpiresAt:   clock.Now().Add(config.IMPERSONATION_TTL * time.Hour),
+	}, nil
+}
+
+// ForceDeleteUser is the resolver for the forceDeleteUser field.
+func (r *mutationResolver) ForceDeleteUser(ctx context.Context, userID string, reason string, dryRun *bool) (*model.DeleteResult, error) {
+	if len(reason) < 10 || len(reason) > 512 {
+		return nil, errors.InvalidInput("Error Deleting User: reason needs to be between 10 and 512 characters")
+	}
+
+	user, err := database.GetUserById(r.db(ctx), userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.NotFound("Error Deleting User: user %s does not exist", userID)
+	}
+	if err != nil {
+		return nil, errors.From(err, "Error Deleting User")
+	}
+	if user.Admin {
+		return nil, errors.Forbidden("Error Deleting User: admins can't be force deleted")
+	}
+
+	isDryRun := dryRun != nil && *dryRun
+	counts, err := r.purgeAccount(ctx, utils.UIntToString(user.ID), isDryRun)
+	if err != nil {
+		return nil, err
+	}
+	return toDeleteResult(counts, isDryRun), nil
+}
+
+// HasRole is the @hasRole directive
+func (r *Resolver) HasRole(ctx context.Context, obj interface{}, next graphql.Resolver, role model.Role) (interface{}, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// an admin impersonating someone is acting as them, roles included
+	if u.ImpersonatedBy != 0 {
+		return nil, errors.Forbidden("Access Denied")
+	}
+
+	switch role {
+	case model.RoleAdmin:
+		err = middleware.VerifyAdmin(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	default:
+		err = errors.Forbidden("Access Denied")
+	}
+	if err != nil {
+		return nil, errors.Forbidden("Access Denied")
+	}
+	return next(ctx)
+}
@@ -0,0 +1,93 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/neilZon/workout-logger-api/archive"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/utils"
+)
+
+// ArchivedWorkoutSessions is the resolver for the archivedWorkoutSessions field.
+func (r *queryResolver) ArchivedWorkoutSessions(ctx context.Context) ([]*model.ArchivedWorkoutSession, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return []*model.ArchivedWorkoutSession{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return []*model.ArchivedWorkoutSession{}, err
+	}
+
+	dbArchived, err := database.GetArchivedWorkoutSessions(r.db(ctx), utils.UIntToString(u.ID))
+	if err != nil {
+		return []*model.ArchivedWorkoutSession{}, errors.From(err, "Error Getting Archived Workout Sessions")
+	}
+
+	archived := make([]*model.ArchivedWorkoutSession, 0)
+	for _, a := range dbArchived {
+		var volumeUnit *model.WeightUnit
+		if a.VolumeUnit != "" {
+			unit := model.WeightUnit(a.VolumeUnit)
+			volumeUnit = &unit
+		}
+		archived = append(archived, &model.ArchivedWorkoutSession{
+			ID:               utils.UIntToString(a.ID),
+			WorkoutSessionID: utils.UIntToString(a.WorkoutSessionID),
+			WorkoutRoutineID: utils.UIntToString(a.WorkoutRoutineID),
+			Start:            a.Start,
+			End:              a.End,
+			ExerciseCount:    a.ExerciseCount,
+			SetCount:         a.SetCount,
+			TotalVolume:      a.TotalVolume,
+			VolumeUnit:       volumeUnit,
+		})
+	}
+
+	return archived, nil
+}
+
+// RestoreArchivedWorkoutSession is the resolver for the restoreArchivedWorkoutSession field.
+func (r *mutationResolver) RestoreArchivedWorkoutSession(ctx context.Context, archivedWorkoutSessionID string) (*model.WorkoutSession, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.WorkoutSession{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.WorkoutSession{}, err
+	}
+
+	archived, err := database.GetArchivedWorkoutSession(r.db(ctx), archivedWorkoutSessionID)
+	if err != nil {
+		return &model.WorkoutSession{}, errors.From(err, "Error Restoring Archived Workout Session")
+	}
+	if archived.UserID != u.ID {
+		return &model.WorkoutSession{}, errors.Forbidden("Error Restoring Archived Workout Session: Access Denied")
+	}
+
+	ws, err := archive.Restore(ctx, r.db(ctx), r.Archive, archived)
+	if err != nil {
+		return &model.WorkoutSession{}, errors.From(err, "Error Restoring Archived Workout Session")
+	}
+
+	return &model.WorkoutSession{
+		ID: utils.UIntToString(ws.ID),
+		// return workout routine ID to access in workout routine resolver
+		WorkoutRoutine: model.WorkoutRoutine{
+			ID: utils.UIntToString(ws.WorkoutRoutineID),
+		},
+		Start:          ws.Start,
+		End:            ws.End,
+		ClientMetadata: ws.ClientMetadata,
+		Origin:         toClientOrigin(ws.Origin),
+		Notes:          ws.Notes,
+		UpdatedAt:      ws.UpdatedAt,
+	}, nil
+}
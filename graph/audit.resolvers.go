@@ -0,0 +1,59 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/utils"
+)
+
+// AuditTrail is the resolver for the auditTrail field.
+func (r *queryResolver) AuditTrail(ctx context.Context, entityID string, entityType *model.AuditEntity, limit *int) ([]*model.AuditLogEntry, error) {
+	n := 50
+	if limit != nil {
+		n = *limit
+	}
+	if n <= 0 || n > config.MAX_AUDIT_TRAIL_ENTRIES {
+		return []*model.AuditLogEntry{}, errors.InvalidInput("Error Getting Audit Trail: limit must be between 1 and %d", config.MAX_AUDIT_TRAIL_ENTRIES)
+	}
+
+	var dbEntityType string
+	if entityType != nil {
+		dbEntityType = string(*entityType)
+	}
+	dbEntries, err := database.GetAuditTrail(r.db(ctx), entityID, dbEntityType, n)
+	if err != nil {
+		return []*model.AuditLogEntry{}, errors.From(err, "Error Getting Audit Trail")
+	}
+
+	entries := make([]*model.AuditLogEntry, 0, len(dbEntries))
+	for _, e := range dbEntries {
+		entry := &model.AuditLogEntry{
+			ID:         utils.UIntToString(e.ID),
+			UserID:     utils.UIntToString(e.UserID),
+			Operation:  e.Operation,
+			Before:     e.Before,
+			After:      e.After,
+			RecordedAt: e.CreatedAt,
+		}
+		if e.ImpersonatedBy != 0 {
+			impersonatedBy := utils.UIntToString(e.ImpersonatedBy)
+			entry.ImpersonatedBy = &impersonatedBy
+		}
+		if e.Reason != "" {
+			reason := e.Reason
+			entry.Reason = &reason
+		}
+		if e.EntityType != "" {
+			entityType := model.AuditEntity(e.EntityType)
+			entityID := utils.UIntToString(e.EntityID)
+			entry.EntityType = &entityType
+			entry.EntityID = &entityID
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -2,20 +2,23 @@ package graph
 
 import (
 	"context"
-	"errors"
 	"fmt"
+	"log"
 	"os"
+	"strings"
 	"time"
 
+	"github.com/neilZon/workout-logger-api/clock"
+	"github.com/neilZon/workout-logger-api/common"
 	"github.com/neilZon/workout-logger-api/config"
 	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
 	"github.com/neilZon/workout-logger-api/graph/model"
 	"github.com/neilZon/workout-logger-api/mail"
 	"github.com/neilZon/workout-logger-api/middleware"
 	"github.com/neilZon/workout-logger-api/token"
 	"github.com/neilZon/workout-logger-api/utils"
 	"github.com/neilZon/workout-logger-api/validator"
-	"github.com/vektah/gqlparser/v2/gqlerror"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
@@ -24,53 +27,59 @@ import (
 func (r *mutationResolver) Login(ctx context.Context, loginInput model.LoginInput) (*model.AuthResult, error) {
 	err := validator.ValidateEmail(loginInput.Email)
 	if err != nil {
-		return &model.AuthResult{}, gqlerror.Errorf("invalid email")
+		return &model.AuthResult{}, errors.From(err, "invalid email")
 	}
 
-	dbUser, err := database.GetUserByEmail(r.DB, loginInput.Email)
+	throttles := loginThrottles(ctx, loginInput.Email)
+	err = r.checkLoginThrottles(ctx, throttles)
+	if err != nil {
+		return &model.AuthResult{}, err
+	}
+
+	dbUser, err := database.GetUserByEmail(r.db(ctx), loginInput.Email)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return &model.AuthResult{}, gqlerror.Errorf("Email does not exist")
+		r.addLoginFailure(ctx, throttles)
+		return &model.AuthResult{}, errors.NotFound("Email does not exist")
 	}
 	if err != nil {
-		return &model.AuthResult{}, gqlerror.Errorf("Error Logging In")
+		return &model.AuthResult{}, errors.From(err, "Error Logging In")
 	}
 
-	err = middleware.VerifyUser(r.DB, fmt.Sprintf("%d", dbUser.ID))
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", dbUser.ID))
 	if err != nil {
 		return &model.AuthResult{}, err
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(dbUser.Password), []byte(loginInput.Password)); err != nil {
-		return &model.AuthResult{}, gqlerror.Errorf("Incorrect Password")
+		r.addLoginFailure(ctx, throttles)
+		return &model.AuthResult{}, errors.InvalidInput("Incorrect Password")
 	}
+
+	// the ip's failures stay, logging into an account of your own shouldn't
+	// give more guesses at someone else's
+	err = database.DeleteLoginThrottle(r.db(ctx), throttles[0].Key)
+	if err != nil {
+		log.Printf("error clearing failed logins for user %d: %s", dbUser.ID, err)
+	}
+
 	c := &token.Credentials{
 		ID:    dbUser.ID,
 		Email: dbUser.Email,
 		Name:  dbUser.Name,
 	}
 
-	refreshToken := token.Sign(c, []byte(os.Getenv(config.REFRESH_SECRET)), config.REFRESH_TTL)
-	accessToken := token.Sign(c, []byte(os.Getenv(config.ACCESS_SECRET)), config.ACCESS_TTL)
-
-	return &model.AuthResult{
-		RefreshToken: refreshToken,
-		AccessToken:  accessToken,
-	}, nil
+	return r.signIn(ctx, c)
 }
 
 // Signup is the resolver for the signup field.
 func (r *mutationResolver) Signup(ctx context.Context, signupInput model.SignupInput) (*model.AuthResult, error) {
-	if err := validator.SignupInputIsValid(&signupInput); err != nil {
-		return &model.AuthResult{}, err
-	}
-
 	// check if user was found from query
-	dbUser, err := database.GetUserByEmail(r.DB, signupInput.Email)
+	dbUser, err := database.GetUserByEmail(r.db(ctx), signupInput.Email)
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
-		return &model.AuthResult{}, gqlerror.Errorf("error signing up")
+		return &model.AuthResult{}, errors.From(err, "error signing up")
 	}
 	if dbUser.Email == signupInput.Email {
-		return &model.AuthResult{}, gqlerror.Errorf("email already exists")
+		return &model.AuthResult{}, errors.InvalidInput("email already exists")
 	}
 
 	// Hashing the password with the default cost of 10
@@ -81,9 +90,9 @@ func (r *mutationResolver) Signup(ctx context.Context, signupInput model.SignupI
 
 	verificationCode, err := utils.GenerateVerificationCode(64)
 	if err != nil {
-		return &model.AuthResult{}, gqlerror.Errorf(err.Error())
+		return &model.AuthResult{}, errors.From(err, "%s", err.Error())
 	}
-	now := time.Now()
+	now := clock.Now()
 	u := database.User{
 		Name:               signupInput.Name,
 		Email:              signupInput.Email,
@@ -92,15 +101,15 @@ func (r *mutationResolver) Signup(ctx context.Context, signupInput model.SignupI
 		Verified:           false,
 		VerificationSentAt: &now,
 	}
-	err = r.DB.Create(&u).Error
+	err = r.db(ctx).Create(&u).Error
 	if err != nil {
-		return &model.AuthResult{}, gqlerror.Errorf(err.Error())
+		return &model.AuthResult{}, errors.From(err, "%s", err.Error())
 	}
 
 	// should this be moved to inside the user create tx?
 	err = mail.SendVerificationCode(verificationCode, u.Email)
 	if err != nil {
-		return &model.AuthResult{}, gqlerror.Errorf("Issue sending verification email")
+		return &model.AuthResult{}, errors.From(err, "Issue sending verification email")
 	}
 
 	c := &token.Credentials{
@@ -109,13 +118,7 @@ func (r *mutationResolver) Signup(ctx context.Context, signupInput model.SignupI
 		Name:  u.Name,
 	}
 
-	refreshToken := token.Sign(c, []byte(os.Getenv(config.REFRESH_SECRET)), config.REFRESH_TTL)
-	accessToken := token.Sign(c, []byte(os.Getenv(config.ACCESS_SECRET)), config.ACCESS_TTL)
-
-	return &model.AuthResult{
-		RefreshToken: refreshToken,
-		AccessToken:  accessToken,
-	}, nil
+	return r.signIn(ctx, c)
 }
 
 // RefreshAccessToken is the resolver for the refreshAccessToken field.
@@ -123,132 +126,346 @@ func (r *mutationResolver) RefreshAccessToken(ctx context.Context, refreshToken
 	// read token from context
 	claims, err := token.Decode(refreshToken, []byte(os.Getenv(config.REFRESH_SECRET)))
 	if err != nil {
-		return nil, gqlerror.Errorf("Refresh token invalid")
+		return nil, errors.InvalidInput("Refresh token invalid")
+	}
+
+	// signed before refresh tokens were rotated, there's nothing to rotate
+	if claims.Id == "" {
+		return nil, errors.InvalidInput("Refresh token invalid")
 	}
 
-	err = middleware.VerifyUser(r.DB, fmt.Sprintf("%d", claims.ID))
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", claims.ID))
 	if err != nil {
 		return &model.RefreshSuccess{}, err
 	}
 
-	accessToken := token.Sign(&token.Credentials{
+	tokenId, err := utils.GenerateSecureToken(16)
+	if err != nil {
+		return nil, errors.From(err, "Error refreshing token")
+	}
+	next := database.RefreshToken{TokenID: tokenId}
+	err = database.RotateRefreshToken(r.db(ctx), claims.Id, &next, sessionFrom(ctx), clock.Now())
+	if errors.Is(err, database.ErrRefreshTokenReused) {
+		log.Printf("refresh token reused for user %d, logged out its family", claims.ID)
+		return nil, errors.InvalidInput("Refresh token invalid")
+	}
+	if errors.Is(err, database.ErrInvalidRefreshToken) {
+		return nil, errors.InvalidInput("Refresh token invalid")
+	}
+	if err != nil {
+		return nil, errors.From(err, "Error refreshing token")
+	}
+
+	c := &token.Credentials{
 		ID:    claims.ID,
 		Email: claims.Subject,
 		Name:  claims.Name,
-	},
-		[]byte(os.Getenv(config.ACCESS_SECRET)),
-		config.ACCESS_TTL,
-	)
-
+	}
 	return &model.RefreshSuccess{
-		AccessToken: accessToken,
+		AccessToken:  token.SignAccess(c, next.FamilyID, []byte(os.Getenv(config.ACCESS_SECRET)), config.ACCESS_TTL),
+		RefreshToken: token.SignRefresh(c, next.FamilyID, next.TokenID, []byte(os.Getenv(config.REFRESH_SECRET)), config.REFRESH_TTL),
 	}, nil
 }
 
+// Logout is the resolver for the logout field.
+func (r *mutationResolver) Logout(ctx context.Context, refreshToken string) (bool, error) {
+	claims, err := token.Decode(refreshToken, []byte(os.Getenv(config.REFRESH_SECRET)))
+	if err != nil || claims.Family == "" {
+		return false, errors.InvalidInput("Refresh token invalid")
+	}
+
+	err = database.RevokeRefreshTokenFamily(r.db(ctx), claims.ID, claims.Family, clock.Now())
+	if err != nil {
+		return false, errors.From(err, "Error logging out")
+	}
+	return true, nil
+}
+
 // ResendVerificationCode is the resolver for the resendVerificationCode field.
 func (r *mutationResolver) ResendVerificationCode(ctx context.Context, email string) (bool, error) {
 	err := validator.ValidateEmail(email)
 	if err != nil {
-		return false, gqlerror.Errorf(err.Error())
+		return false, errors.From(err, "%s", err.Error())
 	}
 
 	// check if user exists to send email to
-	_, err = database.GetUserByEmail(r.DB, email)
+	_, err = database.GetUserByEmail(r.db(ctx), email)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return false, gqlerror.Errorf("user does not exist")
+		return false, errors.NotFound("user does not exist")
 	}
 	if err != nil {
-		return false, gqlerror.Errorf(err.Error())
+		return false, errors.From(err, "%s", err.Error())
 	}
 
 	verificationCode, err := utils.GenerateVerificationCode(64)
 	if err != nil {
-		return false, gqlerror.Errorf("could not send verification email")
+		return false, errors.From(err, "could not send verification email")
 	}
 
-	now := time.Now()
+	now := clock.Now()
 	u := database.User{
 		VerificationCode:   &verificationCode,
 		VerificationSentAt: &now,
 	}
-	err = database.UpdateUser(r.DB, email, &u)
+	err = database.UpdateUser(r.db(ctx), email, &u)
 	if err != nil {
-		return false, gqlerror.Errorf("could not send verification email")
+		return false, errors.From(err, "could not send verification email")
 	}
 
 	// should this be moved to inside the user create tx?
 	err = mail.SendVerificationCode(verificationCode, email)
 	if err != nil {
-		return false, gqlerror.Errorf("could not send verification email")
+		return false, errors.From(err, "could not send verification email")
 	}
 
 	return true, nil
 }
 
-// SendForgotPasswordLink is the resolver for the sendForgotPasswordLink field.
-func (r *mutationResolver) SendForgotPasswordLink(ctx context.Context, email string) (bool, error) {
+// RequestPasswordReset is the resolver for the requestPasswordReset field.
+func (r *mutationResolver) RequestPasswordReset(ctx context.Context, email string) (bool, error) {
 	err := validator.ValidateEmail(email)
 	if err != nil {
-		return false, gqlerror.Errorf("not a valid email")
+		return false, errors.From(err, "not a valid email")
 	}
 
-	// check if user exists to send email to
-	_, err = database.GetUserByEmail(r.DB, email)
+	// the answer is the same whether or not there's an account, so this can't
+	// be used to find out who has one
+	user, err := database.GetUserByEmail(r.db(ctx), email)
 	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return false, gqlerror.Errorf("user does not exist")
+		return true, nil
 	}
 	if err != nil {
-		return false, gqlerror.Errorf("error sending password reset code")
+		return false, errors.From(err, "error sending password reset link")
 	}
 
-	passwordResetCode, err := utils.GenerateVerificationCode(64)
+	now := clock.Now()
+	sent, err := database.CountPasswordResetTokensSince(r.db(ctx), user.ID, now.Add(-time.Hour))
 	if err != nil {
-		return false, gqlerror.Errorf("error sending password reset code")
+		return false, errors.From(err, "error sending password reset link")
+	}
+	if sent >= config.MAX_PASSWORD_RESETS_PER_HOUR {
+		log.Printf("not sending user %d another password reset link, %d were sent in the last hour", user.ID, sent)
+		return true, nil
 	}
 
-	now := time.Now()
-	u := database.User{
-		PasswordResetCode:   &passwordResetCode,
-		PasswordResetSentAt: &now,
+	resetToken, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return false, errors.From(err, "error sending password reset link")
 	}
-	err = database.UpdateUser(r.DB, email, &u)
+	err = database.AddPasswordResetToken(r.db(ctx), &database.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: database.HashPasswordResetToken(resetToken),
+		ExpiresAt: now.Add(config.PASSWORD_RESET_TTL),
+	})
 	if err != nil {
-		return false, gqlerror.Errorf("error sending password reset code")
+		return false, errors.From(err, "error sending password reset link")
 	}
 
-	err = mail.SendResetLink(passwordResetCode, email)
+	err = mail.SendPasswordResetLink(r.Mailer, resetToken, user.Email)
 	if err != nil {
-		return false, gqlerror.Errorf("error sending password reset code")
+		return false, errors.From(err, "error sending password reset link")
 	}
 
 	return true, nil
 }
 
+// SendForgotPasswordLink is the resolver for the sendForgotPasswordLink field.
+func (r *mutationResolver) SendForgotPasswordLink(ctx context.Context, email string) (bool, error) {
+	return r.RequestPasswordReset(ctx, email)
+}
+
 // ResetPassword is the resolver for the resetPassword field.
-func (r *mutationResolver) ResetPassword(ctx context.Context, passwordResetCredentials model.PasswordResetCredentials) (bool, error) {
-	if passwordResetCredentials.Password != passwordResetCredentials.ConfirmPassword {
-		return false, gqlerror.Errorf("passwords don't match")
+func (r *mutationResolver) ResetPassword(ctx context.Context, token *string, newPassword *string, passwordResetCredentials *model.PasswordResetCredentials) (bool, error) {
+	// older apps send the token as the code, with the password twice
+	if passwordResetCredentials != nil {
+		if passwordResetCredentials.Password != passwordResetCredentials.ConfirmPassword {
+			return false, errors.InvalidInput("passwords don't match")
+		}
+		token = &passwordResetCredentials.Code
+		newPassword = &passwordResetCredentials.Password
+	}
+	if token == nil || newPassword == nil {
+		return false, errors.InvalidInput("Error Resetting Password: token and newPassword are required")
 	}
 
-	user, err := database.GetUserByPasswordCode(r.DB, passwordResetCredentials.Code)
+	err := validator.PasswordIsValid(*newPassword)
 	if err != nil {
-		return false, gqlerror.Errorf(err.Error())
-	}
-	expiryTime := time.Now().Add(24 * time.Hour)
-	if user.PasswordResetCode == nil || *user.PasswordResetCode != passwordResetCredentials.Code || user.PasswordResetSentAt == nil || user.PasswordResetSentAt.After(expiryTime) {
-		return false, gqlerror.Errorf("could not reset password")
+		return false, err
 	}
 
 	// Hashing the password with the default cost of 10
-	newHashedPassword, err := bcrypt.GenerateFromPassword([]byte(passwordResetCredentials.Password), bcrypt.DefaultCost)
+	newHashedPassword, err := bcrypt.GenerateFromPassword([]byte(*newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return false, errors.From(err, "could not reset password")
+	}
+
+	err = database.ResetPassword(r.db(ctx), database.HashPasswordResetToken(*token), string(newHashedPassword), clock.Now())
+	if errors.Is(err, database.ErrInvalidPasswordResetToken) {
+		return false, errors.InvalidInput("Error Resetting Password: Invalid Or Expired Token")
+	}
+	if err != nil {
+		return false, errors.From(err, "could not reset password")
+	}
+
+	return true, nil
+}
+
+// RequestEmailChange is the resolver for the requestEmailChange field.
+func (r *mutationResolver) RequestEmailChange(ctx context.Context, newEmail string) (bool, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return false, err
+	}
+
+	err = validator.ValidateEmail(newEmail)
 	if err != nil {
-		return false, gqlerror.Errorf("could not reset password")
+		return false, errors.From(err, "not a valid email")
 	}
 
-	err = database.ChangePassword(r.DB, passwordResetCredentials.Code, string(newHashedPassword))
+	_, err = database.GetUserByEmail(r.db(ctx), newEmail)
+	if err == nil {
+		return false, errors.InvalidInput("email already exists")
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, errors.From(err, "error requesting email change")
+	}
+
+	emailChangeCode, err := utils.GenerateVerificationCode(64)
+	if err != nil {
+		return false, errors.From(err, "error requesting email change")
+	}
+
+	// the current email keeps working until the new one is confirmed
+	now := clock.Now()
+	user := database.User{
+		PendingEmail:      &newEmail,
+		EmailChangeCode:   &emailChangeCode,
+		EmailChangeSentAt: &now,
+	}
+	err = database.UpdateUserById(r.db(ctx), utils.UIntToString(u.ID), &user)
 	if err != nil {
-		return false, gqlerror.Errorf(err.Error())
+		return false, errors.From(err, "error requesting email change")
+	}
+
+	err = mail.SendEmailChangeLink(emailChangeCode, newEmail)
+	if err != nil {
+		return false, errors.From(err, "error requesting email change")
 	}
 
 	return true, nil
 }
+
+// ConfirmEmailChange is the resolver for the confirmEmailChange field.
+func (r *mutationResolver) ConfirmEmailChange(ctx context.Context, token string) (bool, error) {
+	user, err := database.GetUserByEmailChangeCode(r.db(ctx), token)
+	if err != nil {
+		return false, errors.From(err, "could not change email")
+	}
+	if user.PendingEmail == nil || user.EmailChangeSentAt == nil || clock.Since(*user.EmailChangeSentAt) > config.EMAIL_CHANGE_TTL {
+		return false, errors.InvalidInput("email change link expired")
+	}
+
+	oldEmail := user.Email
+	newEmail := *user.PendingEmail
+	err = database.ChangeEmail(r.db(ctx), token, newEmail)
+	if err != nil {
+		return false, errors.From(err, "could not change email")
+	}
+
+	// the change already went through, don't fail it over the notice
+	err = mail.SendEmailChanged(newEmail, oldEmail)
+	if err != nil {
+		log.Printf("error notifying %s of email change for user %d: %v", oldEmail, user.ID, err)
+	}
+
+	return true, nil
+}
+
+// signIn starts a new session with its own refresh token family
+func (r *mutationResolver) signIn(ctx context.Context, c *token.Credentials) (*model.AuthResult, error) {
+	familyId, err := utils.GenerateSecureToken(16)
+	if err != nil {
+		return &model.AuthResult{}, errors.From(err, "Error Logging In")
+	}
+	tokenId, err := utils.GenerateSecureToken(16)
+	if err != nil {
+		return &model.AuthResult{}, errors.From(err, "Error Logging In")
+	}
+	session := sessionFrom(ctx)
+	session.UserID = c.ID
+	session.FamilyID = familyId
+	session.LastUsedAt = clock.Now()
+	err = database.StartSession(r.db(ctx), session, &database.RefreshToken{
+		UserID:   c.ID,
+		FamilyID: familyId,
+		TokenID:  tokenId,
+	})
+	if err != nil {
+		return &model.AuthResult{}, errors.From(err, "Error Logging In")
+	}
+
+	return &model.AuthResult{
+		RefreshToken: token.SignRefresh(c, familyId, tokenId, []byte(os.Getenv(config.REFRESH_SECRET)), config.REFRESH_TTL),
+		AccessToken:  token.SignAccess(c, familyId, []byte(os.Getenv(config.ACCESS_SECRET)), config.ACCESS_TTL),
+	}, nil
+}
+
+// loginThrottles are the keys failed logins are counted under, with how many
+// failures each can have before it's locked. The email's is first
+func loginThrottles(ctx context.Context, email string) []loginThrottle {
+	throttles := []loginThrottle{{Key: "email:" + strings.ToLower(email), FreeFailures: config.LOGIN_FREE_FAILURES}}
+	if ip := middleware.GetClientIP(ctx); ip != "" {
+		throttles = append(throttles, loginThrottle{Key: "ip:" + ip, FreeFailures: config.LOGIN_FREE_FAILURES_PER_IP})
+	}
+	return throttles
+}
+
+type loginThrottle struct {
+	Key          string
+	FreeFailures int
+}
+
+// checkLoginThrottles is a LoginLockedError until the last of the throttles
+// is unlocked
+func (r *mutationResolver) checkLoginThrottles(ctx context.Context, throttles []loginThrottle) error {
+	keys := make([]string, 0, len(throttles))
+	freeFailures := map[string]int{}
+	for _, t := range throttles {
+		keys = append(keys, t.Key)
+		freeFailures[t.Key] = t.FreeFailures
+	}
+	dbThrottles, err := database.GetLoginThrottles(r.db(ctx), keys)
+	if err != nil {
+		return errors.From(err, "Error Logging In")
+	}
+
+	now := clock.Now()
+	var lockedUntil time.Time
+	for i := range dbThrottles {
+		until := dbThrottles[i].LockedUntil(freeFailures[dbThrottles[i].Key])
+		if until.After(lockedUntil) {
+			lockedUntil = until
+		}
+	}
+	if lockedUntil.After(now) {
+		return &common.LoginLockedError{RetryAfter: lockedUntil.Sub(now)}
+	}
+	return nil
+}
+
+// addLoginFailure counts a failed login against every throttle. Not being
+// able to count it doesn't stop the login from failing
+func (r *mutationResolver) addLoginFailure(ctx context.Context, throttles []loginThrottle) {
+	now := clock.Now()
+	for _, t := range throttles {
+		err := database.AddLoginFailure(r.db(ctx), t.Key, now, now.Add(-config.LOGIN_FAILURE_WINDOW))
+		if err != nil {
+			log.Printf("error counting failed login: %s", err)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/utils"
+)
+
+// Autocomplete is the resolver for the autocomplete field.
+func (r *queryResolver) Autocomplete(ctx context.Context, prefix string, scope *model.AutocompleteScope, limit *int) ([]*model.Suggestion, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return []*model.Suggestion{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return []*model.Suggestion{}, err
+	}
+
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" || utf8.RuneCountInString(prefix) > 64 {
+		return []*model.Suggestion{}, errors.InvalidInput("Error Autocompleting: prefix must have between 1 and 64 characters")
+	}
+	n := 10
+	if limit != nil {
+		n = *limit
+	}
+	if n < 1 || n > config.MAX_AUTOCOMPLETE_SUGGESTIONS {
+		return []*model.Suggestion{}, errors.InvalidInput("Error Autocompleting: limit must be between 1 and %d", config.MAX_AUTOCOMPLETE_SUGGESTIONS)
+	}
+	s := model.AutocompleteScopeAll
+	if scope != nil {
+		s = *scope
+	}
+
+	db := r.DB.WithContext(ctx)
+	suggestions := make([]rankedSuggestion, 0)
+	add := func(kind model.SuggestionKind, found []database.Suggestion) {
+		for _, suggestion := range found {
+			suggestions = append(suggestions, rankedSuggestion{Suggestion: suggestion, Kind: kind})
+		}
+	}
+
+	// each source is asked for a full page since any of them could hold the best matches
+	if s == model.AutocompleteScopeAll || s == model.AutocompleteScopeExercises {
+		found, err := database.AutocompleteExerciseRoutines(db, utils.UIntToString(u.ID), prefix, n)
+		if err != nil {
+			return []*model.Suggestion{}, errors.From(err, "Error Autocompleting")
+		}
+		add(model.SuggestionKindExercise, found)
+	}
+	if s == model.AutocompleteScopeAll || s == model.AutocompleteScopeCatalog {
+		found, err := database.AutocompleteCatalogExercises(db, prefix, n)
+		if err != nil {
+			return []*model.Suggestion{}, errors.From(err, "Error Autocompleting")
+		}
+		add(model.SuggestionKindCatalogExercise, found)
+	}
+	if s == model.AutocompleteScopeAll || s == model.AutocompleteScopeTags {
+		muscleGroups := make([]database.Suggestion, 0)
+		for _, muscleGroup := range model.AllMuscleGroup {
+			if rank := database.MatchRank(string(muscleGroup), prefix); rank >= 0 {
+				muscleGroups = append(muscleGroups, database.Suggestion{Text: string(muscleGroup), Rank: rank})
+			}
+		}
+		add(model.SuggestionKindMuscleGroup, muscleGroups)
+
+		found, err := database.AutocompleteEquipment(db, prefix, n)
+		if err != nil {
+			return []*model.Suggestion{}, errors.From(err, "Error Autocompleting")
+		}
+		add(model.SuggestionKindEquipment, found)
+	}
+
+	return toSuggestions(suggestions, n), nil
+}
+
+type rankedSuggestion struct {
+	database.Suggestion
+	Kind model.SuggestionKind
+}
+
+// ties go to the user's own exercises over the catalog, then tags
+var suggestionKindOrder = map[model.SuggestionKind]int{
+	model.SuggestionKindExercise:        0,
+	model.SuggestionKindCatalogExercise: 1,
+	model.SuggestionKindMuscleGroup:     2,
+	model.SuggestionKindEquipment:       3,
+}
+
+// toSuggestions orders suggestions best match first, shorter names first
+// within a match, and drops catalog exercises the user already has one named
+// the same as
+func toSuggestions(suggestions []rankedSuggestion, limit int) []*model.Suggestion {
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		a, b := suggestions[i], suggestions[j]
+		if a.Rank != b.Rank {
+			return a.Rank < b.Rank
+		}
+		if suggestionKindOrder[a.Kind] != suggestionKindOrder[b.Kind] {
+			return suggestionKindOrder[a.Kind] < suggestionKindOrder[b.Kind]
+		}
+		if len(a.Text) != len(b.Text) {
+			return len(a.Text) < len(b.Text)
+		}
+		return strings.ToLower(a.Text) < strings.ToLower(b.Text)
+	})
+
+	exerciseNames := make(map[string]bool)
+	result := make([]*model.Suggestion, 0)
+	for _, suggestion := range suggestions {
+		if len(result) == limit {
+			break
+		}
+		name := strings.ToLower(suggestion.Text)
+		switch suggestion.Kind {
+		case model.SuggestionKindExercise:
+			exerciseNames[name] = true
+		case model.SuggestionKindCatalogExercise:
+			if exerciseNames[name] {
+				continue
+			}
+		}
+
+		var id *string
+		if suggestion.ID != 0 {
+			suggestionId := utils.UIntToString(suggestion.ID)
+			id = &suggestionId
+		}
+		result = append(result, &model.Suggestion{
+			Text: suggestion.Text,
+			Kind: suggestion.Kind,
+			ID:   id,
+		})
+	}
+	return result
+}
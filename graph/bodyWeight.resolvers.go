@@ -0,0 +1,172 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/clock"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/utils"
+)
+
+// LogBodyWeight is the resolver for the logBodyWeight field.
+func (r *mutationResolver) LogBodyWeight(ctx context.Context, bodyWeight model.BodyWeightInput) (*model.BodyWeightEntry, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.BodyWeightEntry{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.BodyWeightEntry{}, err
+	}
+
+	// no loggedAt means it was weighed just now
+	loggedAt := clock.Now()
+	if bodyWeight.LoggedAt != nil {
+		loggedAt = *bodyWeight.LoggedAt
+	}
+
+	entry := database.BodyWeightEntry{
+		UserID:   u.ID,
+		Weight:   float32(bodyWeight.Weight),
+		LoggedAt: loggedAt,
+	}
+	err = database.AddBodyWeightEntry(r.db(ctx), &entry)
+	if err != nil {
+		return &model.BodyWeightEntry{}, errors.From(err, "Error Logging Body Weight")
+	}
+
+	return toBodyWeightEntry(&entry), nil
+}
+
+// UpdateBodyWeight is the resolver for the updateBodyWeight field.
+func (r *mutationResolver) UpdateBodyWeight(ctx context.Context, bodyWeightEntryID string, bodyWeight model.UpdateBodyWeightInput) (*model.BodyWeightEntry, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.BodyWeightEntry{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.BodyWeightEntry{}, err
+	}
+
+	entry, err := database.GetBodyWeightEntry(r.db(ctx), bodyWeightEntryID)
+	if err != nil {
+		return &model.BodyWeightEntry{}, errors.From(err, "Error Updating Body Weight")
+	}
+	if entry.UserID != u.ID {
+		return &model.BodyWeightEntry{}, errors.Forbidden("Error Updating Body Weight: Access Denied")
+	}
+
+	// check optional inputs
+	var weight float32
+	if bodyWeight.Weight != nil {
+		weight = float32(*bodyWeight.Weight)
+	}
+	var loggedAt time.Time
+	if bodyWeight.LoggedAt != nil {
+		loggedAt = *bodyWeight.LoggedAt
+	}
+
+	updatedEntry := database.BodyWeightEntry{
+		Weight:   weight,
+		LoggedAt: loggedAt,
+	}
+	err = database.UpdateBodyWeightEntry(r.db(ctx), bodyWeightEntryID, &updatedEntry)
+	if err != nil {
+		return &model.BodyWeightEntry{}, errors.From(err, "Error Updating Body Weight")
+	}
+
+	return toBodyWeightEntry(&updatedEntry), nil
+}
+
+// DeleteBodyWeight is the resolver for the deleteBodyWeight field.
+func (r *mutationResolver) DeleteBodyWeight(ctx context.Context, bodyWeightEntryID string) (int, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return 0, err
+	}
+
+	entry, err := database.GetBodyWeightEntry(r.db(ctx), bodyWeightEntryID)
+	if err != nil {
+		return 0, errors.From(err, "Error Deleting Body Weight")
+	}
+	if entry.UserID != u.ID {
+		return 0, errors.Forbidden("Error Deleting Body Weight: Access Denied")
+	}
+
+	err = database.DeleteBodyWeightEntry(r.db(ctx), bodyWeightEntryID)
+	if err != nil {
+		return 0, errors.From(err, "Error Deleting Body Weight")
+	}
+
+	return 1, nil
+}
+
+// BodyWeightHistory is the resolver for the bodyWeightHistory field.
+func (r *queryResolver) BodyWeightHistory(ctx context.Context, limit int, after *string) (*model.BodyWeightEntryConnection, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.BodyWeightEntryConnection{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.BodyWeightEntryConnection{}, err
+	}
+
+	if limit <= 0 || limit > 100 {
+		return &model.BodyWeightEntryConnection{}, errors.InvalidInput("Error Getting Body Weight History: limit needs to be between 1 to 100")
+	}
+	limit = middleware.PageSize(ctx, limit)
+
+	cursor := ""
+	if after != nil && *after != "" {
+		cursor = *after
+	}
+
+	// fetch one extra to know if there is another page
+	dbEntries, err := database.GetBodyWeightEntries(r.db(ctx), utils.UIntToString(u.ID), cursor, limit+1)
+	if err != nil {
+		return &model.BodyWeightEntryConnection{}, errors.From(err, "Error Getting Body Weight History")
+	}
+
+	hasNextPage := len(dbEntries) > limit
+	if hasNextPage {
+		dbEntries = dbEntries[:limit]
+	}
+
+	edges := make([]*model.BodyWeightEntryEdge, 0)
+	for i := range dbEntries {
+		edges = append(edges, &model.BodyWeightEntryEdge{
+			Cursor: utils.UIntToString(dbEntries[i].ID),
+			Node:   toBodyWeightEntry(&dbEntries[i]),
+		})
+	}
+
+	return &model.BodyWeightEntryConnection{
+		Edges: edges,
+		PageInfo: &model.PageInfo{
+			HasNextPage: hasNextPage,
+		},
+	}, nil
+}
+
+func toBodyWeightEntry(entry *database.BodyWeightEntry) *model.BodyWeightEntry {
+	return &model.BodyWeightEntry{
+		ID:       utils.UIntToString(entry.ID),
+		Weight:   float64(entry.Weight),
+		LoggedAt: entry.LoggedAt,
+	}
+}
@@ -0,0 +1,87 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/graph-gophers/dataloader"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+)
+
+// SearchExerciseCatalog is the resolver for the searchExerciseCatalog field.
+func (r *queryResolver) SearchExerciseCatalog(ctx context.Context, query *string, muscleGroup *model.MuscleGroup) ([]*model.CatalogExercise, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return []*model.CatalogExercise{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return []*model.CatalogExercise{}, err
+	}
+
+	q := ""
+	if query != nil {
+		q = *query
+	}
+	if len(q) > 64 {
+		return []*model.CatalogExercise{}, errors.InvalidInput("Error Searching Exercise Catalog: query must have less than 64 characters")
+	}
+	group := ""
+	if muscleGroup != nil {
+		group = string(*muscleGroup)
+	}
+
+	dbCatalogExercises, err := database.SearchExerciseCatalog(r.db(ctx), q, group, 50)
+	if err != nil {
+		return []*model.CatalogExercise{}, errors.From(err, "Error Searching Exercise Catalog")
+	}
+
+	catalogExercises := make([]*model.CatalogExercise, 0)
+	for i := range dbCatalogExercises {
+		catalogExercises = append(catalogExercises, toCatalogExercise(&dbCatalogExercises[i]))
+	}
+	return catalogExercises, nil
+}
+
+// CatalogExercise is the resolver for the catalogExercise field.
+func (r *exerciseRoutineResolver) CatalogExercise(ctx context.Context, obj *model.ExerciseRoutine) (*model.CatalogExercise, error) {
+	loaders := middleware.GetLoaders(ctx)
+	thunk := loaders.CatalogExerciseLoader.Load(ctx, dataloader.StringKey(obj.ID))
+	result, err := thunk()
+	if err != nil {
+		return nil, err
+	}
+	return result.(*model.CatalogExercise), nil
+}
+
+func toCatalogExercise(catalogExercise *database.CatalogExercise) *model.CatalogExercise {
+	muscleGroups := make([]model.MuscleGroup, 0)
+	for _, muscleGroup := range catalogExercise.MuscleGroups {
+		muscleGroups = append(muscleGroups, model.MuscleGroup(muscleGroup.MuscleGroup))
+	}
+	return &model.CatalogExercise{
+		ID:           fmt.Sprintf("%d", catalogExercise.ID),
+		Name:         catalogExercise.Name,
+		MuscleGroups: muscleGroups,
+		Equipment:    catalogExercise.Equipment,
+		Instructions: catalogExercise.Instructions,
+	}
+}
+
+// catalog exercise ids are optional on exercise routine inputs
+func toCatalogExerciseID(catalogExerciseID *string) (*uint, error) {
+	if catalogExerciseID == nil {
+		return nil, nil
+	}
+	id, err := strconv.ParseUint(*catalogExerciseID, 10, strconv.IntSize)
+	if err != nil {
+		return nil, err
+	}
+	catalogExerciseIDUint := uint(id)
+	return &catalogExerciseIDUint, nil
+}
@@ -0,0 +1,131 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/utils"
+)
+
+// LinkCoach is the resolver for the linkCoach field.
+func (r *mutationResolver) LinkCoach(ctx context.Context, email string) (bool, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return false, err
+	}
+
+	coach, err := database.GetUserByEmail(r.db(ctx), email)
+	if err != nil || !coach.Verified {
+		return false, errors.NotFound("Error Linking Coach: Coach Not Found")
+	}
+	if coach.ID == u.ID {
+		return false, errors.InvalidInput("Error Linking Coach: Can't Coach Yourself")
+	}
+
+	_, err = database.GetCoach(r.db(ctx), utils.UIntToString(u.ID), utils.UIntToString(coach.ID))
+	if err == nil {
+		return true, nil
+	}
+
+	err = database.AddCoach(r.db(ctx), &database.Coach{
+		UserID:  u.ID,
+		CoachID: coach.ID,
+	})
+	if err != nil {
+		return false, errors.From(err, "Error Linking Coach")
+	}
+
+	return true, nil
+}
+
+// UnlinkCoach is the resolver for the unlinkCoach field.
+func (r *mutationResolver) UnlinkCoach(ctx context.Context, coachID string) (int, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := database.DeleteCoach(r.db(ctx), utils.UIntToString(u.ID), coachID)
+	if err != nil {
+		return 0, errors.From(err, "Error Unlinking Coach")
+	}
+
+	return int(deleted), nil
+}
+
+// GrantAccess is the resolver for the grantAccess field.
+func (r *mutationResolver) GrantAccess(ctx context.Context, email string, access model.CoachAccess) (*model.CoachGrant, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.CoachGrant{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.CoachGrant{}, err
+	}
+
+	coach, err := database.GetUserByEmail(r.db(ctx), email)
+	if err != nil || !coach.Verified {
+		return &model.CoachGrant{}, errors.NotFound("Error Granting Access: Coach Not Found")
+	}
+	if coach.ID == u.ID {
+		return &model.CoachGrant{}, errors.InvalidInput("Error Granting Access: Can't Coach Yourself")
+	}
+
+	// granting to someone who isn't a coach yet links them too
+	dbCoach, err := database.GetCoach(r.db(ctx), utils.UIntToString(u.ID), utils.UIntToString(coach.ID))
+	if err == nil {
+		err = database.UpdateCoachAccess(r.db(ctx), dbCoach, access.String())
+	} else {
+		dbCoach = &database.Coach{
+			UserID:  u.ID,
+			CoachID: coach.ID,
+			Access:  access.String(),
+		}
+		err = database.AddCoach(r.db(ctx), dbCoach)
+	}
+	if err != nil {
+		return &model.CoachGrant{}, errors.From(err, "Error Granting Access")
+	}
+
+	return &model.CoachGrant{
+		CoachID: utils.UIntToString(coach.ID),
+		Access:  access,
+	}, nil
+}
+
+// RevokeAccess is the resolver for the revokeAccess field.
+func (r *mutationResolver) RevokeAccess(ctx context.Context, coachID string) (int, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return 0, err
+	}
+
+	// a coach with no access left isn't a coach anymore
+	deleted, err := database.DeleteCoach(r.db(ctx), utils.UIntToString(u.ID), coachID)
+	if err != nil {
+		return 0, errors.From(err, "Error Revoking Access")
+	}
+
+	return int(deleted), nil
+}
@@ -0,0 +1,54 @@
+package graph
+
+import (
+	"context"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+)
+
+// longest range deviceOrigins aggregates over, it scans every session and set
+// in the range
+const maxDeviceOriginDays = 92
+
+// DeviceOrigins is the resolver for the deviceOrigins field.
+func (r *queryResolver) DeviceOrigins(ctx context.Context, rangeArg model.DateRangeInput) ([]*model.DeviceOriginStats, error) {
+	if !rangeArg.Start.Before(rangeArg.End) {
+		return []*model.DeviceOriginStats{}, errors.InvalidInput("Error Getting Device Origins: range start needs to be before range end")
+	}
+	if rangeArg.End.Sub(rangeArg.Start) > maxDeviceOriginDays*24*time.Hour {
+		return []*model.DeviceOriginStats{}, errors.InvalidInput("Error Getting Device Origins: range can be at most %d days", maxDeviceOriginDays)
+	}
+
+	dbStats, err := database.GetDeviceOriginStats(r.db(ctx), rangeArg.Start, rangeArg.End)
+	if err != nil {
+		return []*model.DeviceOriginStats{}, errors.From(err, "Error Getting Device Origins")
+	}
+
+	stats := make([]*model.DeviceOriginStats, 0)
+	for _, s := range dbStats {
+		stats = append(stats, &model.DeviceOriginStats{
+			Platform:   s.Platform,
+			AppVersion: s.AppVersion,
+			Sessions:   s.Sessions,
+			Sets:       s.Sets,
+			Devices:    s.Devices,
+			LastSeenAt: s.LastSeenAt,
+		})
+	}
+	return stats, nil
+}
+
+// toClientOrigin is nil when the client didn't say who it was
+func toClientOrigin(o database.Origin) *model.ClientOrigin {
+	if o == (database.Origin{}) {
+		return nil
+	}
+	return &model.ClientOrigin{
+		Platform:   o.Platform,
+		AppVersion: o.AppVersion,
+		DeviceID:   o.DeviceID,
+	}
+}
@@ -0,0 +1,68 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/neilZon/workout-logger-api/clock"
+	"github.com/neilZon/workout-logger-api/common"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/utils"
+)
+
+// UnlockWorkoutSession is the resolver for the unlockWorkoutSession field.
+func (r *mutationResolver) UnlockWorkoutSession(ctx context.Context, workoutSessionID string) (*model.WorkoutSessionUnlock, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.WorkoutSessionUnlock{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.WorkoutSessionUnlock{}, err
+	}
+
+	// training partners have to ask the owner
+	err = r.ACS.IsWorkoutSessionOwner(utils.UIntToString(u.ID), workoutSessionID)
+	if err != nil {
+		return &model.WorkoutSessionUnlock{}, errors.Forbidden("Error Unlocking Workout Session: Access Denied")
+	}
+	workoutSessionIDUint, err := strconv.ParseUint(workoutSessionID, 10, 64)
+	if err != nil {
+		return &model.WorkoutSessionUnlock{}, errors.InvalidInput("Error Unlocking Workout Session: Invalid Workout Session ID")
+	}
+
+	unlock := database.WorkoutSessionUnlock{
+		WorkoutSessionID: uint(workoutSessionIDUint),
+		UserID:           u.ID,
+		ExpiresAt:        clock.Now().Add(config.SESSION_UNLOCK_TTL),
+	}
+	err = database.AddWorkoutSessionUnlock(r.db(ctx), &unlock)
+	if err != nil {
+		return &model.WorkoutSessionUnlock{}, errors.From(err, "Error Unlocking Workout Session")
+	}
+
+	return &model.WorkoutSessionUnlock{
+		WorkoutSessionID: workoutSessionID,
+		UnlockedUntil:    unlock.ExpiresAt,
+	}, nil
+}
+
+// canEditWorkoutSession hands back the lock error as is so the app gets the
+// SESSION_LOCKED code, anything else is reported under action
+func (r *mutationResolver) canEditWorkoutSession(workoutSessionID string, action string) error {
+	err := r.ACS.CanEditWorkoutSession(workoutSessionID)
+	var lockedError *common.WorkoutSessionLockedError
+	if errors.As(err, &lockedError) {
+		return err
+	}
+	if err != nil {
+		return errors.From(err, "%s", action)
+	}
+	return nil
+}
@@ -4,13 +4,17 @@ import (
 	"context"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/graph-gophers/dataloader"
+	"github.com/neilZon/workout-logger-api/common"
+	"github.com/neilZon/workout-logger-api/config"
 	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
 	"github.com/neilZon/workout-logger-api/graph/model"
 	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/reader"
 	"github.com/neilZon/workout-logger-api/utils"
-	"github.com/vektah/gqlparser/v2/gqlerror"
 	"gorm.io/gorm"
 )
 
@@ -21,38 +25,45 @@ func (r *mutationResolver) AddExercise(ctx context.Context, workoutSessionID str
 		return &model.Exercise{}, err
 	}
 
-	err = middleware.VerifyUser(r.DB, fmt.Sprintf("%d", u.ID))
+	user, err := middleware.VerifiedUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
 	if err != nil {
 		return &model.Exercise{}, err
 	}
 
 	userId := fmt.Sprintf("%d", u.ID)
-	err = r.ACS.CanAccessWorkoutSession(userId, workoutSessionID)
+	err = r.ACS.CanParticipateInWorkoutSession(userId, workoutSessionID)
+	coaching := false
 	if err != nil {
-		return &model.Exercise{}, gqlerror.Errorf("Error Adding Exercise: %s", err.Error())
+		// coaches with read write access log for their athletes
+		if r.ACS.CanAccessWorkoutSession(userId, workoutSessionID) != nil {
+			return &model.Exercise{}, errors.From(err, "Error Adding Exercise: %s", err.Error())
+		}
+		coaching = true
 	}
-
-	// todo: check can access exercise routines that are being added
-	if len(exercise.SetEntries) > 20 {
-		return &model.Exercise{}, gqlerror.Errorf("exercises can only have a maximum of 20 sets")
+	err = r.canEditWorkoutSession(workoutSessionID, "Error Adding Exercise")
+	if err != nil {
+		return &model.Exercise{}, err
 	}
 
-	var setEntries []database.SetEntry
-	for _, s := range exercise.SetEntries {
-		setEntries = append(setEntries, database.SetEntry{
-			Reps:   uint(s.Reps),
-			Weight: float32(s.Weight),
-		})
+	workoutSession, err := database.GetWorkoutSession(r.db(ctx), workoutSessionID)
+	if err != nil {
+		return &model.Exercise{}, errors.From(err, "Error Adding Exercise: %s", err.Error())
 	}
+	count, err := database.CountExerciseRoutinesInWorkoutRoutine(r.db(ctx), utils.UIntToString(workoutSession.WorkoutRoutineID), []string{exercise.ExerciseRoutineID})
+	if err != nil || count != 1 {
+		return &model.Exercise{}, errors.InvalidInput("Error Adding Exercise: Exercise Routine Must Belong To Workout Routine")
+	}
+
+	setEntries := toDBSetEntries(exercise.SetEntries, user, middleware.GetClient(ctx))
 
 	workoutSessionIDUint, err := strconv.ParseUint(workoutSessionID, 10, 32)
 	if err != nil {
-		return &model.Exercise{}, gqlerror.Errorf("Error Adding Exercise: %s", err.Error())
+		return &model.Exercise{}, errors.From(err, "Error Adding Exercise: %s", err.Error())
 	}
 
 	exerciseRoutineID, err := strconv.ParseUint(exercise.ExerciseRoutineID, 10, 32)
 	if err != nil {
-		return &model.Exercise{}, gqlerror.Errorf("Error Adding Exercise: %s", err.Error())
+		return &model.Exercise{}, errors.From(err, "Error Adding Exercise: %s", err.Error())
 	}
 
 	dbExercise := &database.Exercise{
@@ -60,11 +71,15 @@ func (r *mutationResolver) AddExercise(ctx context.Context, workoutSessionID str
 		ExerciseRoutineID: uint(exerciseRoutineID),
 		Sets:              setEntries,
 		Notes:             exercise.Notes,
+		UserID:            u.ID,
+	}
+	if coaching {
+		dbExercise.UserID = workoutSession.UserID
 	}
 
-	err = database.AddExercise(r.DB, dbExercise)
+	err = database.AddExercise(r.db(ctx), dbExercise)
 	if err != nil {
-		return &model.Exercise{}, gqlerror.Errorf("Error Adding Exercise: %s", err.Error())
+		return &model.Exercise{}, errors.From(err, "Error Adding Exercise: %s", err.Error())
 	}
 
 	// invalidate exercise resolver dataloader cache
@@ -72,8 +87,10 @@ func (r *mutationResolver) AddExercise(ctx context.Context, workoutSessionID str
 	loaders.ExerciseSliceLoader.Clear(ctx, dataloader.StringKey(workoutSessionID))
 
 	return &model.Exercise{
-		ID:    utils.UIntToString(dbExercise.ID),
-		Notes: dbExercise.Notes,
+		ID:        utils.UIntToString(dbExercise.ID),
+		Notes:     dbExercise.Notes,
+		LoggedBy:  utils.UIntToString(dbExercise.UserID),
+		UpdatedAt: dbExercise.UpdatedAt,
 	}, nil
 }
 
@@ -84,29 +101,19 @@ func (r *queryResolver) Exercise(ctx context.Context, exerciseID string) (*model
 		return &model.Exercise{}, err
 	}
 
-	err = middleware.VerifyUser(r.DB, fmt.Sprintf("%d", u.ID))
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
 	if err != nil {
 		return &model.Exercise{}, err
 	}
 
-	exerciseIDUint, err := strconv.ParseUint(exerciseID, 10, 64)
+	_, err = strconv.ParseUint(exerciseID, 10, 64)
 	if err != nil {
-		return &model.Exercise{}, gqlerror.Errorf("Error Getting Exercise: Invalid Exercise ID")
+		return &model.Exercise{}, errors.InvalidInput("Error Getting Exercise: Invalid Exercise ID")
 	}
 
-	exercise := &database.Exercise{
-		Model: gorm.Model{
-			ID: uint(exerciseIDUint),
-		},
-	}
-	err = database.GetExercise(r.DB, exercise, false)
+	exercise, err := database.GetParticipantExercise(r.db(ctx), exerciseID, fmt.Sprintf("%d", u.ID), false)
 	if err != nil {
-		return &model.Exercise{}, gqlerror.Errorf("Error Getting Exercise: %s", err.Error())
-	}
-
-	err = r.ACS.CanAccessWorkoutSession(fmt.Sprintf("%d", u.ID), fmt.Sprintf("%d", exercise.WorkoutSessionID))
-	if err != nil {
-		return &model.Exercise{}, gqlerror.Errorf("Error Getting Exercise: %s", err.Error())
+		return &model.Exercise{}, errors.From(err, "Error Getting Exercise: %s", err.Error())
 	}
 
 	// invalidate exercise resolver dataloader cache
@@ -114,11 +121,65 @@ func (r *queryResolver) Exercise(ctx context.Context, exerciseID string) (*model
 	loaders.SetEntrySliceLoader.Clear(ctx, dataloader.StringKey(fmt.Sprintf("%d", exercise.ID)))
 
 	return &model.Exercise{
-		ID:    exerciseID,
-		Notes: exercise.Notes,
+		ID:        exerciseID,
+		Notes:     exercise.Notes,
+		LoggedBy:  utils.UIntToString(exercise.UserID),
+		UpdatedAt: exercise.UpdatedAt,
 	}, nil
 }
 
+// Exercises is the resolver for the exercises field.
+func (r *queryResolver) Exercises(ctx context.Context, workoutSessionID string, limit *int, after *string) ([]*model.Exercise, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return []*model.Exercise{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return []*model.Exercise{}, err
+	}
+
+	_, err = strconv.ParseUint(workoutSessionID, 10, 64)
+	if err != nil {
+		return []*model.Exercise{}, errors.InvalidInput("Error Getting Exercises: Invalid Workout Session ID")
+	}
+	n, err := pageLimit(ctx, limit, config.DEFAULT_EXERCISES_PAGE_SIZE, config.MAX_EXERCISES_PAGE_SIZE, "Error Getting Exercises")
+	if err != nil {
+		return []*model.Exercise{}, err
+	}
+	cursor, err := pageCursor(after, "Error Getting Exercises")
+	if err != nil {
+		return []*model.Exercise{}, err
+	}
+
+	userId := utils.UIntToString(u.ID)
+	err = r.ACS.CanParticipateInWorkoutSession(userId, workoutSessionID)
+	if err != nil {
+		// coaches can look at their athletes' sessions
+		if r.ACS.CanReadWorkoutSession(userId, workoutSessionID) != nil {
+			return []*model.Exercise{}, errors.Forbidden("Error Getting Exercises: Access Denied")
+		}
+	}
+
+	dbExercises, err := database.GetExercises(r.db(ctx), workoutSessionID, cursor, n)
+	if err != nil {
+		return []*model.Exercise{}, errors.From(err, "Error Getting Exercises")
+	}
+
+	exercises := make([]*model.Exercise, 0, len(dbExercises))
+	for _, e := range dbExercises {
+		exercises = append(exercises, &model.Exercise{
+			ID:        utils.UIntToString(e.ID),
+			Notes:     e.Notes,
+			LoggedBy:  utils.UIntToString(e.UserID),
+			UpdatedAt: e.UpdatedAt,
+		})
+	}
+
+	return exercises, nil
+}
+
 // UpdateExercise is the resolver for the updateExercise field.
 func (r *mutationResolver) UpdateExercise(ctx context.Context, exerciseID string, exercise model.UpdateExerciseInput) (*model.Exercise, error) {
 	u, err := middleware.GetUser(ctx)
@@ -126,33 +187,41 @@ func (r *mutationResolver) UpdateExercise(ctx context.Context, exerciseID string
 		return &model.Exercise{}, err
 	}
 
-	err = middleware.VerifyUser(r.DB, fmt.Sprintf("%d", u.ID))
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
 	if err != nil {
 		return &model.Exercise{}, err
 	}
 
-	exerciseIDUint, err := strconv.ParseUint(exerciseID, 10, strconv.IntSize)
-	dbExercise := database.Exercise{
-		Model: gorm.Model{
-			ID: uint(exerciseIDUint),
-		},
-	}
-	err = database.GetExercise(r.DB, &dbExercise, false)
+	userId := fmt.Sprintf("%d", u.ID)
+	dbExercise, err := r.exerciseToChange(ctx, exerciseID, userId)
 	if err != nil {
-		return &model.Exercise{}, gqlerror.Errorf("Error Updating Exercise")
+		return &model.Exercise{}, errors.From(err, "Error Updating Exercise")
 	}
-
-	err = r.ACS.CanAccessWorkoutSession(fmt.Sprintf("%d", u.ID), fmt.Sprintf("%d", dbExercise.WorkoutSessionID))
+	err = r.canEditWorkoutSession(utils.UIntToString(dbExercise.WorkoutSessionID), "Error Updating Exercise")
 	if err != nil {
-		return &model.Exercise{}, gqlerror.Errorf("Error Updating Exercise: Access Denied")
+		return &model.Exercise{}, err
 	}
 
 	updatedExercise := database.Exercise{
 		Notes: exercise.Notes,
 	}
-	err = database.UpdateExercise(r.DB, exerciseID, &updatedExercise)
+	err = database.UpdateExercise(r.db(ctx), utils.UIntToString(dbExercise.UserID), exerciseID, &updatedExercise, exercise.ExpectedUpdatedAt)
+	if errors.Is(err, database.ErrConflict) {
+		current := database.Exercise{Model: gorm.Model{ID: dbExercise.ID}}
+		if err := database.GetExercise(r.db(ctx), &current, false); err != nil {
+			return &model.Exercise{}, errors.From(err, "Error Updating Exercise")
+		}
+		return &model.Exercise{}, &common.ConflictError{
+			Action: "Error Updating Exercise",
+			Current: map[string]interface{}{
+				"id":        exerciseID,
+				"notes":     current.Notes,
+				"updatedAt": current.UpdatedAt,
+			},
+		}
+	}
 	if err != nil {
-		return &model.Exercise{}, gqlerror.Errorf("Error Updating Exercise")
+		return &model.Exercise{}, errors.From(err, "Error Updating Exercise")
 	}
 
 	// invalidate exercise resolver dataloader cache
@@ -160,8 +229,10 @@ func (r *mutationResolver) UpdateExercise(ctx context.Context, exerciseID string
 	loaders.ExerciseSliceLoader.Clear(ctx, dataloader.StringKey(fmt.Sprintf("%d", dbExercise.WorkoutSessionID)))
 
 	return &model.Exercise{
-		ID:    exerciseID,
-		Notes: updatedExercise.Notes,
+		ID:        exerciseID,
+		Notes:     updatedExercise.Notes,
+		LoggedBy:  utils.UIntToString(dbExercise.UserID),
+		UpdatedAt: updatedExercise.UpdatedAt,
 	}, nil
 }
 
@@ -172,30 +243,24 @@ func (r *mutationResolver) DeleteExercise(ctx context.Context, exerciseID string
 		return 0, err
 	}
 
-	err = middleware.VerifyUser(r.DB, fmt.Sprintf("%d", u.ID))
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
 	if err != nil {
 		return 0, err
 	}
 
-	exerciseIDUint, err := strconv.ParseUint(exerciseID, 10, strconv.IntSize)
-	dbExercise := database.Exercise{
-		Model: gorm.Model{
-			ID: uint(exerciseIDUint),
-		},
-	}
-	err = database.GetExercise(r.DB, &dbExercise, false)
+	userId := fmt.Sprintf("%d", u.ID)
+	dbExercise, err := r.exerciseToChange(ctx, exerciseID, userId)
 	if err != nil {
-		return 0, gqlerror.Errorf("Error Deleting Exercise")
+		return 0, errors.From(err, "Error Deleting Exercise")
 	}
-
-	err = r.ACS.CanAccessWorkoutSession(fmt.Sprintf("%d", u.ID), fmt.Sprintf("%d", dbExercise.WorkoutSessionID))
+	err = r.canEditWorkoutSession(utils.UIntToString(dbExercise.WorkoutSessionID), "Error Deleting Exercise")
 	if err != nil {
-		return 0, gqlerror.Errorf("Error Deleting Exercise: Access Denied")
+		return 0, err
 	}
 
-	err = database.DeleteExercise(r.DB, exerciseID)
+	err = database.DeleteExercise(r.db(ctx), utils.UIntToString(dbExercise.UserID), exerciseID)
 	if err != nil {
-		return 0, gqlerror.Errorf("Error Deleting Exercise")
+		return 0, errors.From(err, "Error Deleting Exercise")
 	}
 
 	// invalidate exercise resolver dataloader cache
@@ -205,6 +270,23 @@ func (r *mutationResolver) DeleteExercise(ctx context.Context, exerciseID string
 	return 1, nil
 }
 
+// exerciseToChange is the exercise if the user logged it or coaches whoever
+// did with read write access, anyone else gets gorm.ErrRecordNotFound like
+// they do from GetExerciseForUser
+func (r *mutationResolver) exerciseToChange(ctx context.Context, exerciseID string, userId string) (*database.Exercise, error) {
+	exercise, err := database.GetExerciseForUser(r.db(ctx), exerciseID, userId, false)
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return exercise, err
+	}
+	exerciseIDUint, parseErr := strconv.ParseUint(exerciseID, 10, 64)
+	if parseErr != nil || r.ACS.CanAccessExercise(userId, exerciseID) != nil {
+		return exercise, err
+	}
+
+	coached := &database.Exercise{Model: gorm.Model{ID: uint(exerciseIDUint)}}
+	return coached, database.GetExercise(r.db(ctx), coached, false)
+}
+
 // Exercises is the resolver for the exercises field.
 func (r *workoutSessionResolver) Exercises(ctx context.Context, obj *model.WorkoutSession) ([]*model.Exercise, error) {
 	loaders := middleware.GetLoaders(ctx)
@@ -219,18 +301,23 @@ func (r *workoutSessionResolver) Exercises(ctx context.Context, obj *model.Worko
 
 // PrevExercises is the resolver for the prevExercises field.
 func (r *workoutSessionResolver) PrevExercises(ctx context.Context, obj *model.WorkoutSession) ([]*model.Exercise, error) {
-	dbExercises, err := database.GetPrevExercisesByWorkoutRoutineId(r.DB, obj.WorkoutRoutine.ID, obj.Start)
-	if err != nil {
-		return []*model.Exercise{}, gqlerror.Errorf("Error getting previous exercises")
+	// clients on a bad connection log without last time's numbers
+	if middleware.IsLowBandwidth(ctx) {
+		return []*model.Exercise{}, nil
 	}
 
-	var exercises []*model.Exercise
-	for _, e := range dbExercises {
-		exercises = append(exercises, &model.Exercise{
-			ID:    fmt.Sprintf("%d", e.ID),
-			Notes: e.Notes,
-		})
-	}
+	return loadPrevExercises(ctx, obj.WorkoutRoutine.ID, obj.Start)
+}
 
-	return exercises, nil
+// loadPrevExercises batches every session's lookup in a request into one
+// statement
+func loadPrevExercises(ctx context.Context, workoutRoutineId string, before time.Time) ([]*model.Exercise, error) {
+	loaders := middleware.GetLoaders(ctx)
+	key := reader.PrevExerciseArgs{WorkoutRoutineID: workoutRoutineId, Before: before}
+	thunk := loaders.PrevExerciseSliceLoader.Load(ctx, dataloader.StringKey(key.String()))
+	result, err := thunk()
+	if err != nil {
+		return []*model.Exercise{}, errors.From(err, "Error getting previous exercises")
+	}
+	return result.([]*model.Exercise), nil
 }
@@ -6,11 +6,14 @@ import (
 	"strconv"
 
 	"github.com/graph-gophers/dataloader"
+	"github.com/neilZon/workout-logger-api/config"
 	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
 	"github.com/neilZon/workout-logger-api/graph/model"
 	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/responsecache"
 	"github.com/neilZon/workout-logger-api/utils"
-	"github.com/vektah/gqlparser/v2/gqlerror"
+	"gorm.io/gorm"
 )
 
 // AddExerciseRoutine is the resolver for the addExerciseRoutine field.
@@ -20,42 +23,40 @@ func (r *mutationResolver) AddExerciseRoutine(ctx context.Context, workoutRoutin
 		return &model.ExerciseRoutine{}, err
 	}
 
-	err = middleware.VerifyUser(r.DB, fmt.Sprintf("%d", u.ID))
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
 	if err != nil {
 		return &model.ExerciseRoutine{}, err
 	}
 
-	if exerciseRoutine.Sets > 20 {
-		return &model.ExerciseRoutine{}, gqlerror.Errorf("Exercise routine cannot have more than 20 sets")
-	}
-
-	if exerciseRoutine.Sets < 0 {
-		return &model.ExerciseRoutine{}, gqlerror.Errorf("sets cannot be a negative number")
-	}
-
 	userId := fmt.Sprintf("%d", u.ID)
 	err = r.ACS.CanAccessWorkoutRoutine(userId, workoutRoutineID)
 	if err != nil {
-		return &model.ExerciseRoutine{}, gqlerror.Errorf("Error Adding Exercise Routine: Access Denied")
+		return &model.ExerciseRoutine{}, errors.Forbidden("Error Adding Exercise Routine: Access Denied")
 	}
 
 	workoutRoutineIDUint, err := strconv.ParseUint(workoutRoutineID, 10, strconv.IntSize)
 	if err != nil {
-		return &model.ExerciseRoutine{}, gqlerror.Errorf("Error Adding Exercise Routine")
+		return &model.ExerciseRoutine{}, errors.From(err, "Error Adding Exercise Routine")
+	}
+	catalogExerciseID, err := toCatalogExerciseID(exerciseRoutine.CatalogExerciseID)
+	if err != nil {
+		return &model.ExerciseRoutine{}, errors.InvalidInput("Error Adding Exercise Routine: Invalid Catalog Exercise")
 	}
 	dbExerciseRoutine := &database.ExerciseRoutine{
-		Name:             exerciseRoutine.Name,
-		Sets:             uint(exerciseRoutine.Sets),
-		Reps:             uint(exerciseRoutine.Reps),
-		WorkoutRoutineID: uint(workoutRoutineIDUint),
+		Name:              exerciseRoutine.Name,
+		Sets:              uint(exerciseRoutine.Sets),
+		Reps:              uint(exerciseRoutine.Reps),
+		WorkoutRoutineID:  uint(workoutRoutineIDUint),
+		CatalogExerciseID: catalogExerciseID,
 	}
-	err = database.AddExerciseRoutine(r.DB, dbExerciseRoutine)
+	err = database.AddExerciseRoutine(r.db(ctx), dbExerciseRoutine)
 	if err != nil {
-		return &model.ExerciseRoutine{}, gqlerror.Errorf("Error Adding Exercise Routine")
+		return &model.ExerciseRoutine{}, errors.From(err, "Error Adding Exercise Routine")
 	}
 
 	loaders := middleware.GetLoaders(ctx)
 	loaders.ExerciseRoutineSliceLoader.Clear(ctx, dataloader.StringKey(workoutRoutineID))
+	r.Cache.Invalidate(responsecache.WorkoutRoutineScope(workoutRoutineID))
 
 	return &model.ExerciseRoutine{
 		ID:     utils.UIntToString(dbExerciseRoutine.ID),
@@ -73,20 +74,29 @@ func (r *queryResolver) ExerciseRoutines(ctx context.Context, workoutRoutineID s
 		return []*model.ExerciseRoutine{}, err
 	}
 
-	err = middleware.VerifyUser(r.DB, fmt.Sprintf("%d", u.ID))
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
 	if err != nil {
 		return []*model.ExerciseRoutine{}, err
 	}
 
 	userId := fmt.Sprintf("%d", u.ID)
-	err = r.ACS.CanAccessWorkoutRoutine(userId, workoutRoutineID)
+	err = r.ACS.CanReadWorkoutRoutine(userId, workoutRoutineID)
 	if err != nil {
-		return []*model.ExerciseRoutine{}, gqlerror.Errorf("Error Getting Exercise Routine: Access Denied")
+		return []*model.ExerciseRoutine{}, errors.Forbidden("Error Getting Exercise Routine: Access Denied")
+	}
+
+	// access is checked first, the cached result can be shared with whoever
+	// else reads the routine but the key is still per user
+	cacheKey := responsecache.Key("exerciseRoutines", userId, workoutRoutineID)
+	cacheScopes := []string{responsecache.UserScope(userId), responsecache.WorkoutRoutineScope(workoutRoutineID)}
+	cached := []*model.ExerciseRoutine{}
+	if r.Cache.Get(cacheKey, cacheScopes, &cached) {
+		return cached, nil
 	}
 
-	dbExerciseRoutines, err := database.GetExerciseRoutines(r.DB, workoutRoutineID)
+	dbExerciseRoutines, err := database.GetExerciseRoutines(r.db(ctx), workoutRoutineID)
 	if err != nil {
-		return []*model.ExerciseRoutine{}, gqlerror.Errorf("Error Getting Exercise Routine")
+		return []*model.ExerciseRoutine{}, errors.From(err, "Error Getting Exercise Routine")
 	}
 
 	exerciseRoutines := make([]*model.ExerciseRoutine, 0)
@@ -99,6 +109,7 @@ func (r *queryResolver) ExerciseRoutines(ctx context.Context, workoutRoutineID s
 		})
 	}
 
+	r.Cache.Set(cacheKey, cacheScopes, exerciseRoutines)
 	return exerciseRoutines, nil
 }
 
@@ -109,31 +120,132 @@ func (r *mutationResolver) DeleteExerciseRoutine(ctx context.Context, exerciseRo
 		return 0, err
 	}
 
-	err = middleware.VerifyUser(r.DB, fmt.Sprintf("%d", u.ID))
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
 	if err != nil {
 		return 0, err
 	}
 
 	exerciseRoutine := database.ExerciseRoutine{}
-	err = database.GetExerciseRoutine(r.DB, exerciseRoutineID, &exerciseRoutine)
+	err = database.GetExerciseRoutine(r.db(ctx), exerciseRoutineID, &exerciseRoutine)
 	if err != nil {
-		return 0, gqlerror.Errorf("Error Deleting Exercise Routine")
+		return 0, errors.From(err, "Error Deleting Exercise Routine")
 	}
 
 	userId := fmt.Sprintf("%d", u.ID)
 	err = r.ACS.CanAccessWorkoutRoutine(userId, fmt.Sprintf("%d", exerciseRoutine.WorkoutRoutineID))
 	if err != nil {
-		return 0, gqlerror.Errorf("Error Deleting Exercise Routine: Access Denied")
+		return 0, errors.Forbidden("Error Deleting Exercise Routine: Access Denied")
 	}
 
-	err = database.DeleteExerciseRoutine(r.DB, exerciseRoutineID)
+	err = database.DeleteExerciseRoutine(r.db(ctx), exerciseRoutineID)
 	if err != nil {
-		return 0, gqlerror.Errorf("Error Deleting Exercise Routine")
+		return 0, errors.From(err, "Error Deleting Exercise Routine")
 	}
+	r.Cache.Invalidate(responsecache.WorkoutRoutineScope(fmt.Sprintf("%d", exerciseRoutine.WorkoutRoutineID)))
 
 	return 1, nil
 }
 
+// DeleteExerciseRoutines is the resolver for the deleteExerciseRoutines field.
+func (r *mutationResolver) DeleteExerciseRoutines(ctx context.Context, exerciseRoutineIds []string) (int, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return 0, err
+	}
+
+	if len(exerciseRoutineIds) == 0 || len(exerciseRoutineIds) > config.MAX_BULK_IDS {
+		return 0, errors.InvalidInput("Error Deleting Exercise Routines: between 1 and %d ids are allowed", config.MAX_BULK_IDS)
+	}
+
+	userId := fmt.Sprintf("%d", u.ID)
+	err = r.ACS.CanAccessExerciseRoutines(userId, exerciseRoutineIds)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, errors.NotFound("Error Deleting Exercise Routines: exercise routine not found")
+	}
+	if err != nil {
+		return 0, errors.Forbidden("Error Deleting Exercise Routines: Access Denied")
+	}
+
+	workoutRoutineIds, err := database.DeleteExerciseRoutines(r.db(ctx), exerciseRoutineIds)
+	if err != nil {
+		return 0, errors.From(err, "Error Deleting Exercise Routines")
+	}
+	for _, id := range workoutRoutineIds {
+		r.Cache.Invalidate(responsecache.WorkoutRoutineScope(utils.UIntToString(id)))
+	}
+
+	return len(exerciseRoutineIds), nil
+}
+
+// ReorderExerciseRoutines is the resolver for the reorderExerciseRoutines field.
+func (r *mutationResolver) ReorderExerciseRoutines(ctx context.Context, workoutRoutineID string, orderedIds []string) ([]*model.ExerciseRoutine, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return []*model.ExerciseRoutine{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return []*model.ExerciseRoutine{}, err
+	}
+
+	userId := fmt.Sprintf("%d", u.ID)
+	err = r.ACS.CanAccessWorkoutRoutine(userId, workoutRoutineID)
+	if err != nil {
+		return []*model.ExerciseRoutine{}, errors.Forbidden("Error Reordering Exercise Routines: Access Denied")
+	}
+
+	dbExerciseRoutines, err := database.GetExerciseRoutines(r.db(ctx), workoutRoutineID)
+	if err != nil {
+		return []*model.ExerciseRoutine{}, errors.From(err, "Error Reordering Exercise Routines")
+	}
+
+	// the new order has to name every exercise routine in the workout routine
+	// exactly once
+	byId := make(map[string]database.ExerciseRoutine, len(*dbExerciseRoutines))
+	for _, er := range *dbExerciseRoutines {
+		byId[utils.UIntToString(er.ID)] = er
+	}
+	if len(orderedIds) != len(byId) {
+		return []*model.ExerciseRoutine{}, errors.InvalidInput("Error Reordering Exercise Routines: Order Must Include Every Exercise Routine")
+	}
+	seen := make(map[string]bool, len(orderedIds))
+	for _, id := range orderedIds {
+		if _, ok := byId[id]; !ok || seen[id] {
+			return []*model.ExerciseRoutine{}, errors.InvalidInput("Error Reordering Exercise Routines: Order Must Include Every Exercise Routine")
+		}
+		seen[id] = true
+	}
+
+	err = database.ReorderExerciseRoutines(r.db(ctx), workoutRoutineID, orderedIds)
+	if err != nil {
+		return []*model.ExerciseRoutine{}, errors.From(err, "Error Reordering Exercise Routines")
+	}
+
+	loaders := middleware.GetLoaders(ctx)
+	loaders.ExerciseRoutineSliceLoader.Clear(ctx, dataloader.StringKey(workoutRoutineID))
+	r.Cache.Invalidate(responsecache.WorkoutRoutineScope(workoutRoutineID))
+
+	exerciseRoutines := make([]*model.ExerciseRoutine, 0)
+	for _, id := range orderedIds {
+		er := byId[id]
+		exerciseRoutines = append(exerciseRoutines, &model.ExerciseRoutine{
+			ID:     id,
+			Active: er.Active,
+			Name:   er.Name,
+			Sets:   int(er.Sets),
+			Reps:   int(er.Reps),
+		})
+	}
+
+	return exerciseRoutines, nil
+}
+
 // ExerciseRoutine is the resolver for the exerciseRoutine field.
 func (r *exerciseResolver) ExerciseRoutine(ctx context.Context, obj *model.Exercise) (*model.ExerciseRoutine, error) {
 	loaders := middleware.GetLoaders(ctx)
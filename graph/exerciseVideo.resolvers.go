@@ -0,0 +1,255 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/neilZon/workout-logger-api/clock"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/utils"
+	"gorm.io/gorm"
+)
+
+// UploadExerciseVideo is the resolver for the uploadExerciseVideo field.
+func (r *mutationResolver) UploadExerciseVideo(ctx context.Context, exerciseID string, setEntryID *string, video graphql.Upload) (*model.ExerciseVideo, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.ExerciseVideo{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.ExerciseVideo{}, err
+	}
+
+	if !strings.HasPrefix(video.ContentType, "video/") {
+		return &model.ExerciseVideo{}, errors.InvalidInput("Error Uploading Video: File Needs To Be A Video")
+	}
+	if video.Size <= 0 || video.Size > config.MAX_VIDEO_BYTES {
+		return &model.ExerciseVideo{}, errors.InvalidInput("Error Uploading Video: Video Needs To Be Under %dMB", config.MAX_VIDEO_BYTES>>20)
+	}
+
+	err = r.ACS.CanAccessExercise(utils.UIntToString(u.ID), exerciseID)
+	if err != nil {
+		return &model.ExerciseVideo{}, errors.Forbidden("Error Uploading Video: Access Denied")
+	}
+
+	exerciseIDUint, err := strconv.ParseUint(exerciseID, 10, 64)
+	if err != nil {
+		return &model.ExerciseVideo{}, errors.InvalidInput("Error Uploading Video: Invalid Exercise ID")
+	}
+
+	// optionally pin the video to one set of the exercise
+	var setEntryIDUint *uint
+	if setEntryID != nil {
+		var setEntry database.SetEntry
+		err = database.GetSet(r.db(ctx), &setEntry, *setEntryID)
+		if err != nil || setEntry.ExerciseID != uint(exerciseIDUint) {
+			return &model.ExerciseVideo{}, errors.InvalidInput("Error Uploading Video: Set Must Belong To Exercise")
+		}
+		setEntryIDUint = &setEntry.ID
+	}
+
+	code, err := utils.GenerateVerificationCode(16)
+	if err != nil {
+		return &model.ExerciseVideo{}, errors.From(err, "Error Uploading Video")
+	}
+	key := fmt.Sprintf("videos/%d/%s%s", u.ID, strings.TrimRight(code, "="), path.Ext(video.Filename))
+
+	err = r.Media.Save(ctx, key, video.File)
+	if err != nil {
+		return &model.ExerciseVideo{}, errors.From(err, "Error Uploading Video")
+	}
+
+	dbVideo := database.ExerciseVideo{
+		ExerciseID:  uint(exerciseIDUint),
+		SetEntryID:  setEntryIDUint,
+		UserID:      u.ID,
+		StorageKey:  key,
+		ContentType: video.ContentType,
+	}
+	err = database.AddExerciseVideo(r.db(ctx), &dbVideo)
+	if err != nil {
+		// don't leave orphaned files around
+		r.Media.Delete(ctx, key)
+		return &model.ExerciseVideo{}, errors.From(err, "Error Uploading Video")
+	}
+
+	return r.toExerciseVideo(&dbVideo)
+}
+
+// AddVideoAnnotation is the resolver for the addVideoAnnotation field.
+func (r *mutationResolver) AddVideoAnnotation(ctx context.Context, exerciseVideoID string, timestampMs int, note string) (*model.VideoAnnotation, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.VideoAnnotation{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.VideoAnnotation{}, err
+	}
+
+	if timestampMs < 0 {
+		return &model.VideoAnnotation{}, errors.InvalidInput("timestampMs can't be negative")
+	}
+	if len(note) == 0 || len(note) > 512 {
+		return &model.VideoAnnotation{}, errors.InvalidInput("note needs to be between 1 and 512 characters")
+	}
+
+	video, err := database.GetExerciseVideo(r.db(ctx), exerciseVideoID)
+	if err != nil {
+		return &model.VideoAnnotation{}, errors.From(err, "Error Adding Annotation")
+	}
+
+	// only linked coaches annotate, not the athlete
+	err = r.ACS.CanCoach(utils.UIntToString(u.ID), utils.UIntToString(video.UserID))
+	if err != nil {
+		return &model.VideoAnnotation{}, errors.Forbidden("Error Adding Annotation: Access Denied")
+	}
+
+	annotation := database.VideoAnnotation{
+		ExerciseVideoID: video.ID,
+		CoachID:         u.ID,
+		TimestampMs:     uint(timestampMs),
+		Note:            note,
+	}
+	err = database.AddVideoAnnotation(r.db(ctx), &annotation)
+	if err != nil {
+		return &model.VideoAnnotation{}, errors.From(err, "Error Adding Annotation")
+	}
+
+	return toVideoAnnotation(&annotation), nil
+}
+
+// ExerciseVideos is the resolver for the exerciseVideos field.
+func (r *queryResolver) ExerciseVideos(ctx context.Context, exerciseID string) ([]*model.ExerciseVideo, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return []*model.ExerciseVideo{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return []*model.ExerciseVideo{}, err
+	}
+
+	exerciseIDUint, err := strconv.ParseUint(exerciseID, 10, 64)
+	if err != nil {
+		return []*model.ExerciseVideo{}, errors.InvalidInput("Error Getting Videos: Invalid Exercise ID")
+	}
+	exercise := database.Exercise{
+		Model: gorm.Model{
+			ID: uint(exerciseIDUint),
+		},
+	}
+	err = database.GetExercise(r.db(ctx), &exercise, false)
+	if err != nil {
+		return []*model.ExerciseVideo{}, errors.From(err, "Error Getting Videos")
+	}
+
+	err = r.canViewVideos(utils.UIntToString(u.ID), exercise.UserID)
+	if err != nil {
+		return []*model.ExerciseVideo{}, errors.Forbidden("Error Getting Videos: Access Denied")
+	}
+
+	dbVideos, err := database.GetExerciseVideos(r.db(ctx), exerciseID)
+	if err != nil {
+		return []*model.ExerciseVideo{}, errors.From(err, "Error Getting Videos")
+	}
+
+	videos := make([]*model.ExerciseVideo, 0)
+	for i := range dbVideos {
+		video, err := r.toExerciseVideo(&dbVideos[i])
+		if err != nil {
+			return []*model.ExerciseVideo{}, err
+		}
+		videos = append(videos, video)
+	}
+
+	return videos, nil
+}
+
+// VideoAnnotations is the resolver for the videoAnnotations field.
+func (r *queryResolver) VideoAnnotations(ctx context.Context, exerciseVideoID string) ([]*model.VideoAnnotation, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return []*model.VideoAnnotation{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return []*model.VideoAnnotation{}, err
+	}
+
+	video, err := database.GetExerciseVideo(r.db(ctx), exerciseVideoID)
+	if err != nil {
+		return []*model.VideoAnnotation{}, errors.From(err, "Error Getting Annotations")
+	}
+
+	err = r.canViewVideos(utils.UIntToString(u.ID), video.UserID)
+	if err != nil {
+		return []*model.VideoAnnotation{}, errors.Forbidden("Error Getting Annotations: Access Denied")
+	}
+
+	dbAnnotations, err := database.GetVideoAnnotations(r.db(ctx), exerciseVideoID)
+	if err != nil {
+		return []*model.VideoAnnotation{}, errors.From(err, "Error Getting Annotations")
+	}
+
+	annotations := make([]*model.VideoAnnotation, 0)
+	for i := range dbAnnotations {
+		annotations = append(annotations, toVideoAnnotation(&dbAnnotations[i]))
+	}
+
+	return annotations, nil
+}
+
+// the athlete and their linked coaches can see videos
+func (r *Resolver) canViewVideos(userId string, ownerId uint) error {
+	if userId == utils.UIntToString(ownerId) {
+		return nil
+	}
+	return r.ACS.CanCoach(userId, utils.UIntToString(ownerId))
+}
+
+// toExerciseVideo signs a fresh download url for the video
+func (r *Resolver) toExerciseVideo(video *database.ExerciseVideo) (*model.ExerciseVideo, error) {
+	url, err := r.Media.SignedDownloadURL(video.StorageKey, clock.Now().Add(config.VIDEO_URL_TTL))
+	if err != nil {
+		return &model.ExerciseVideo{}, errors.From(err, "Error Getting Exercise Video")
+	}
+
+	var setEntryID *string
+	if video.SetEntryID != nil {
+		id := utils.UIntToString(*video.SetEntryID)
+		setEntryID = &id
+	}
+
+	return &model.ExerciseVideo{
+		ID:          utils.UIntToString(video.ID),
+		ExerciseID:  utils.UIntToString(video.ExerciseID),
+		SetEntryID:  setEntryID,
+		URL:         url,
+		ContentType: video.ContentType,
+		UploadedAt:  video.CreatedAt,
+	}, nil
+}
+
+func toVideoAnnotation(annotation *database.VideoAnnotation) *model.VideoAnnotation {
+	return &model.VideoAnnotation{
+		ID:          utils.UIntToString(annotation.ID),
+		CoachID:     utils.UIntToString(annotation.CoachID),
+		TimestampMs: int(annotation.TimestampMs),
+		Note:        annotation.Note,
+		CreatedAt:   annotation.CreatedAt,
+	}
+}
@@ -0,0 +1,217 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/clock"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/goal"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/utils"
+	"github.com/neilZon/workout-logger-api/validator"
+)
+
+// CreateGoal is the resolver for the createGoal field.
+func (r *mutationResolver) CreateGoal(ctx context.Context, goalInput model.GoalInput) (*model.Goal, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.Goal{}, err
+	}
+
+	user, err := middleware.VerifiedUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.Goal{}, err
+	}
+
+	now := clock.Now()
+	if err := validator.GoalDeadlineIsValid(goalInput.Deadline, now); err != nil {
+		return &model.Goal{}, errors.From(err, "Error Creating Goal: %s", err.Error())
+	}
+
+	dbGoal := database.Goal{
+		UserID:      u.ID,
+		Type:        string(goalInput.Type),
+		TargetValue: float32(goalInput.TargetValue),
+		Unit:        weightUnitOf(user),
+		Deadline:    goalInput.Deadline,
+	}
+	switch goalInput.Type {
+	case model.GoalTypeBodyWeight:
+		if goalInput.CatalogExerciseID != nil {
+			return &model.Goal{}, errors.InvalidInput("Error Creating Goal: body weight goals can't have a catalog exercise")
+		}
+	case model.GoalTypeLiftE1rm:
+		if goalInput.CatalogExerciseID == nil {
+			return &model.Goal{}, errors.InvalidInput("Error Creating Goal: lift goals need a catalog exercise")
+		}
+		dbGoal.CatalogExerciseID, err = toCatalogExerciseID(goalInput.CatalogExerciseID)
+		if err != nil {
+			return &model.Goal{}, errors.From(err, "Error Creating Goal")
+		}
+		_, err = database.GetCatalogExercise(r.db(ctx), *goalInput.CatalogExerciseID)
+		if err != nil {
+			return &model.Goal{}, errors.From(err, "Error Creating Goal")
+		}
+	}
+
+	// progress is measured from wherever the user is right now
+	current, err := goal.CurrentValue(r.db(ctx), &dbGoal)
+	if err != nil {
+		return &model.Goal{}, errors.From(err, "Error Creating Goal")
+	}
+	if current == nil && dbGoal.Type == database.GoalTypeBodyWeight {
+		return &model.Goal{}, errors.InvalidInput("Error Creating Goal: log your body weight first")
+	}
+	if current != nil {
+		startValue := float32(*current)
+		dbGoal.StartValue = &startValue
+	}
+
+	progress := goal.Measure(&dbGoal, current)
+	if progress.Reached {
+		return &model.Goal{}, errors.InvalidInput("Error Creating Goal: target is already reached")
+	}
+
+	err = database.AddGoal(r.db(ctx), &dbGoal)
+	if err != nil {
+		return &model.Goal{}, errors.From(err, "Error Creating Goal")
+	}
+
+	return toGoal(&dbGoal, progress, now), nil
+}
+
+// UpdateGoal is the resolver for the updateGoal field.
+func (r *mutationResolver) UpdateGoal(ctx context.Context, goalID string, goalInput model.UpdateGoalInput) (*model.Goal, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.Goal{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.Goal{}, err
+	}
+
+	now := clock.Now()
+	updates := map[string]interface{}{}
+	if goalInput.TargetValue != nil {
+		// a new target has to be reached again
+		updates["target_value"] = float32(*goalInput.TargetValue)
+		updates["completed_at"] = nil
+	}
+	if goalInput.Deadline != nil {
+		if err := validator.GoalDeadlineIsValid(*goalInput.Deadline, now); err != nil {
+			return &model.Goal{}, errors.From(err, "Error Updating Goal: %s", err.Error())
+		}
+		updates["deadline"] = *goalInput.Deadline
+	}
+
+	dbGoal, err := database.GetGoal(r.db(ctx), goalID)
+	if err != nil {
+		return &model.Goal{}, errors.From(err, "Error Updating Goal")
+	}
+	if dbGoal.UserID != u.ID {
+		return &model.Goal{}, errors.Forbidden("Error Updating Goal: Access Denied")
+	}
+
+	if len(updates) > 0 {
+		dbGoal, err = database.UpdateGoal(r.db(ctx), goalID, updates)
+		if err != nil {
+			return &model.Goal{}, errors.From(err, "Error Updating Goal")
+		}
+	}
+
+	progress, err := goal.Evaluate(r.db(ctx), dbGoal)
+	if err != nil {
+		return &model.Goal{}, errors.From(err, "Error Updating Goal")
+	}
+
+	return toGoal(dbGoal, progress, now), nil
+}
+
+// DeleteGoal is the resolver for the deleteGoal field.
+func (r *mutationResolver) DeleteGoal(ctx context.Context, goalID string) (int, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return 0, err
+	}
+
+	dbGoal, err := database.GetGoal(r.db(ctx), goalID)
+	if err != nil {
+		return 0, errors.From(err, "Error Deleting Goal")
+	}
+	if dbGoal.UserID != u.ID {
+		return 0, errors.Forbidden("Error Deleting Goal: Access Denied")
+	}
+
+	err = database.DeleteGoal(r.db(ctx), goalID)
+	if err != nil {
+		return 0, errors.From(err, "Error Deleting Goal")
+	}
+
+	return 1, nil
+}
+
+// Goals is the resolver for the goals field.
+func (r *queryResolver) Goals(ctx context.Context) ([]*model.Goal, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return []*model.Goal{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return []*model.Goal{}, err
+	}
+
+	dbGoals, err := database.GetGoals(r.db(ctx), utils.UIntToString(u.ID))
+	if err != nil {
+		return []*model.Goal{}, errors.From(err, "Error Getting Goals")
+	}
+
+	now := clock.Now()
+	goals := make([]*model.Goal, 0)
+	for i := range dbGoals {
+		progress, err := goal.Evaluate(r.db(ctx), &dbGoals[i])
+		if err != nil {
+			return []*model.Goal{}, errors.From(err, "Error Getting Goals")
+		}
+		goals = append(goals, toGoal(&dbGoals[i], progress, now))
+	}
+
+	return goals, nil
+}
+
+func toGoal(g *database.Goal, progress *goal.Progress, now time.Time) *model.Goal {
+	var startValue *float64
+	if g.StartValue != nil {
+		s := float64(*g.StartValue)
+		startValue = &s
+	}
+	var catalogExerciseID *string
+	if g.CatalogExerciseID != nil {
+		id := utils.UIntToString(*g.CatalogExerciseID)
+		catalogExerciseID = &id
+	}
+	return &model.Goal{
+		ID:                utils.UIntToString(g.ID),
+		Type:              model.GoalType(g.Type),
+		TargetValue:       float64(g.TargetValue),
+		StartValue:        startValue,
+		CurrentValue:      progress.Current,
+		Progress:          progress.Fraction,
+		CatalogExerciseID: catalogExerciseID,
+		Deadline:          g.Deadline,
+		Status:            model.GoalStatus(goal.Status(g, progress, now)),
+		CompletedAt:       g.CompletedAt,
+	}
+}
@@ -0,0 +1,75 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/format"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/importer"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/utils"
+)
+
+// ImportWorkoutHistory is the resolver for the importWorkoutHistory field.
+func (r *mutationResolver) ImportWorkoutHistory(ctx context.Context, file graphql.Upload, timeZone *string, dryRun *bool) (*model.WorkoutHistoryImport, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.WorkoutHistoryImport{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.WorkoutHistoryImport{}, err
+	}
+
+	if file.Size <= 0 || file.Size > config.MAX_IMPORT_BYTES {
+		return &model.WorkoutHistoryImport{}, errors.InvalidInput("Error Importing Workout History: File Needs To Be Under %dMB", config.MAX_IMPORT_BYTES>>20)
+	}
+
+	loc, err := r.userLocation(ctx, u.ID, timeZone, "Error Importing Workout History")
+	if err != nil {
+		return &model.WorkoutHistoryImport{}, err
+	}
+
+	// Hevy's weights are converted to whatever the user logs in
+	user, err := database.GetUserById(r.db(ctx), utils.UIntToString(u.ID))
+	if err != nil {
+		return &model.WorkoutHistoryImport{}, errors.From(err, "Error Importing Workout History")
+	}
+	unit := format.NewHints(user.Locale, user.WeightUnit).WeightUnit
+
+	importFormat, workouts, err := importer.Parse(file.File, loc, unit)
+	if err != nil {
+		return &model.WorkoutHistoryImport{}, errors.InvalidInput("Error Importing Workout History: %s", err.Error())
+	}
+
+	isDryRun := dryRun != nil && *dryRun
+	imported, err := database.ImportWorkoutHistory(r.db(ctx), u.ID, workouts, isDryRun)
+	if err != nil {
+		return &model.WorkoutHistoryImport{}, errors.From(err, "Error Importing Workout History")
+	}
+
+	newExerciseRoutines := make([]*model.ImportedExerciseRoutine, 0)
+	for _, er := range imported.NewExerciseRoutines {
+		newExerciseRoutines = append(newExerciseRoutines, &model.ImportedExerciseRoutine{
+			WorkoutRoutine:  er.WorkoutRoutine,
+			Name:            er.Name,
+			CatalogExercise: er.CatalogExercise,
+		})
+	}
+
+	return &model.WorkoutHistoryImport{
+		Format:                 model.ImportFormat(importFormat),
+		DryRun:                 isDryRun,
+		WorkoutSessions:        imported.WorkoutSessions,
+		Sets:                   imported.Sets,
+		SkippedWorkoutSessions: imported.SkippedWorkoutSessions,
+		NewWorkoutRoutines:     imported.NewWorkoutRoutines,
+		NewExerciseRoutines:    newExerciseRoutines,
+	}, nil
+}
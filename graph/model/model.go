@@ -2,19 +2,42 @@ package model
 
 import "time"
 
+type User struct {
+	ID         string      `json:"id"`
+	Name       string      `json:"name"`
+	Email      string      `json:"email"`
+	WeekStart  WeekStart   `json:"weekStart"`
+	Locale     string      `json:"locale"`
+	WeightUnit *WeightUnit `json:"weightUnit"`
+	CreatedAt  time.Time   `json:"-"` // for lifetimeStats memberSince
+}
+
 type WorkoutRoutine struct {
 	ID               string             `json:"id"`
 	Name             string             `json:"name"`
 	Active           bool               `json:"active"`
 	ExerciseRoutines []*ExerciseRoutine `json:"exerciseRoutines"`
+	UpdatedAt        time.Time          `json:"updatedAt"`
+}
+
+type ExerciseRoutine struct {
+	ID     string `json:"id"`
+	Active bool   `json:"active"`
+	Name   string `json:"name"`
+	Sets   int    `json:"sets"`
+	Reps   int    `json:"reps"`
 }
 
 type WorkoutSession struct {
-	ID             string         `json:"id"`
-	Start          time.Time      `json:"start"`
-	End            *time.Time     `json:"end"`
-	WorkoutRoutine WorkoutRoutine `json:"workoutRoutine"`
-	Exercises      []*Exercise    `json:"exercises"`
+	ID             string                 `json:"id"`
+	Start          time.Time              `json:"start"`
+	End            *time.Time             `json:"end"`
+	WorkoutRoutine WorkoutRoutine         `json:"workoutRoutine"`
+	Exercises      []*Exercise            `json:"exercises"`
+	ClientMetadata map[string]interface{} `json:"clientMetadata"`
+	Origin         *ClientOrigin          `json:"origin"`
+	Notes          string                 `json:"notes"`
+	UpdatedAt      time.Time              `json:"updatedAt"`
 }
 
 type Exercise struct {
@@ -23,6 +46,8 @@ type Exercise struct {
 	Prev            *PrevExercise   `json:"prev"`
 	Sets            []*SetEntry     `json:"sets"`
 	Notes           string          `json:"notes"`
+	LoggedBy        string          `json:"loggedBy"`
+	UpdatedAt       time.Time       `json:"updatedAt"`
 }
 
 type PrevExercise struct {
@@ -30,3 +55,30 @@ type PrevExercise struct {
 	Sets  []*SetEntry `json:"sets"`
 	Notes string      `json:"notes"`
 }
+
+type TodaysWorkout struct {
+	Day            time.Time      `json:"day"`
+	Weekday        Weekday        `json:"weekday"`
+	Program        *Program       `json:"program"`
+	WorkoutRoutine WorkoutRoutine `json:"workoutRoutine"`
+}
+
+type WorkoutStats struct {
+	WeightUnit           WeightUnit              `json:"weightUnit"`
+	TotalVolume          float64                 `json:"totalVolume"`
+	TotalSets            int                     `json:"totalSets"`
+	SessionCount         int                     `json:"sessionCount"`
+	ExerciseRoutineStats []*ExerciseRoutineStats `json:"exerciseRoutineStats"`
+	MuscleGroupStats     []*MuscleGroupStats     `json:"muscleGroupStats"`
+	// what the stats were asked for, for weeks
+	WorkoutRoutineID string         `json:"-"`
+	UserID           string         `json:"-"`
+	Range            DateRangeInput `json:"-"`
+}
+
+// the types search results can be
+
+func (WorkoutRoutine) IsSearchResult()  {}
+func (ExerciseRoutine) IsSearchResult() {}
+func (Exercise) IsSearchResult()        {}
+func (WorkoutSession) IsSearchResult()  {}
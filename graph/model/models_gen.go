@@ -3,32 +3,248 @@
 package model
 
 import (
+	"fmt"
+	"io"
+	"strconv"
 	"time"
 )
 
+type SearchResult interface {
+	IsSearchResult()
+}
+
+type AccountExport struct {
+	ID          string              `json:"id"`
+	Status      AccountExportStatus `json:"status"`
+	RequestedAt time.Time           `json:"requestedAt"`
+	CompletedAt *time.Time          `json:"completedAt"`
+	DownloadURL *string             `json:"downloadUrl"`
+}
+
+type ActiveSession struct {
+	ID         string        `json:"id"`
+	UserAgent  string        `json:"userAgent"`
+	IP         string        `json:"ip"`
+	Origin     *ClientOrigin `json:"origin"`
+	CreatedAt  time.Time     `json:"createdAt"`
+	LastUsedAt time.Time     `json:"lastUsedAt"`
+}
+
+type AdminUser struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Verified  bool      `json:"verified"`
+	Admin     bool      `json:"admin"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type ArchivedWorkoutSession struct {
+	ID               string      `json:"id"`
+	WorkoutSessionID string      `json:"workoutSessionId"`
+	WorkoutRoutineID string      `json:"workoutRoutineId"`
+	Start            time.Time   `json:"start"`
+	End              *time.Time  `json:"end"`
+	ExerciseCount    int         `json:"exerciseCount"`
+	SetCount         int         `json:"setCount"`
+	TotalVolume      float64     `json:"totalVolume"`
+	VolumeUnit       *WeightUnit `json:"volumeUnit"`
+}
+
+type AuditLogEntry struct {
+	ID             string                 `json:"id"`
+	UserID         string                 `json:"userId"`
+	ImpersonatedBy *string                `json:"impersonatedBy"`
+	Operation      string                 `json:"operation"`
+	EntityType     *AuditEntity           `json:"entityType"`
+	EntityID       *string                `json:"entityId"`
+	Before         map[string]interface{} `json:"before"`
+	After          map[string]interface{} `json:"after"`
+	Reason         *string                `json:"reason"`
+	RecordedAt     time.Time              `json:"recordedAt"`
+}
+
 type AuthResult struct {
 	RefreshToken string `json:"refreshToken"`
 	AccessToken  string `json:"accessToken"`
 }
 
+type BodyWeightEntry struct {
+	ID       string    `json:"id"`
+	Weight   float64   `json:"weight"`
+	LoggedAt time.Time `json:"loggedAt"`
+}
+
+type BodyWeightEntryConnection struct {
+	Edges    []*BodyWeightEntryEdge `json:"edges"`
+	PageInfo *PageInfo              `json:"pageInfo"`
+}
+
+type BodyWeightEntryEdge struct {
+	Node   *BodyWeightEntry `json:"node"`
+	Cursor string           `json:"cursor"`
+}
+
+type BodyWeightInput struct {
+	Weight   float64    `json:"weight"`
+	LoggedAt *time.Time `json:"loggedAt"`
+}
+
+type CatalogExercise struct {
+	ID           string        `json:"id"`
+	Name         string        `json:"name"`
+	MuscleGroups []MuscleGroup `json:"muscleGroups"`
+	Equipment    string        `json:"equipment"`
+	Instructions string        `json:"instructions"`
+}
+
+type ClientOrigin struct {
+	Platform   string `json:"platform"`
+	AppVersion string `json:"appVersion"`
+	DeviceID   string `json:"deviceId"`
+}
+
+type CoachGrant struct {
+	CoachID string      `json:"coachId"`
+	Access  CoachAccess `json:"access"`
+}
+
+type DateRangeInput struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+type DeleteResult struct {
+	DryRun bool            `json:"dryRun"`
+	Counts *DeletionCounts `json:"counts"`
+}
+
+type DeletionCounts struct {
+	WorkoutRoutines  int `json:"workoutRoutines"`
+	ExerciseRoutines int `json:"exerciseRoutines"`
+	WorkoutSessions  int `json:"workoutSessions"`
+	Exercises        int `json:"exercises"`
+	Sets             int `json:"sets"`
+	ExerciseVideos   int `json:"exerciseVideos"`
+	BodyWeights      int `json:"bodyWeights"`
+	Goals            int `json:"goals"`
+	NutritionLogs    int `json:"nutritionLogs"`
+	SleepLogs        int `json:"sleepLogs"`
+}
+
+type DeviceOriginStats struct {
+	Platform   string    `json:"platform"`
+	AppVersion string    `json:"appVersion"`
+	Sessions   int       `json:"sessions"`
+	Sets       int       `json:"sets"`
+	Devices    int       `json:"devices"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+}
+
 type ExerciseInput struct {
 	ExerciseRoutineID string           `json:"exerciseRoutineId"`
 	Notes             string           `json:"notes"`
 	SetEntries        []*SetEntryInput `json:"setEntries"`
 }
 
-type ExerciseRoutine struct {
-	ID     string `json:"id"`
-	Active bool   `json:"active"`
-	Name   string `json:"name"`
-	Sets   int    `json:"sets"`
-	Reps   int    `json:"reps"`
+type ExerciseRoutineInput struct {
+	Name              string  `json:"name"`
+	Sets              int     `json:"sets"`
+	Reps              int     `json:"reps"`
+	CatalogExerciseID *string `json:"catalogExerciseId"`
 }
 
-type ExerciseRoutineInput struct {
-	Name string `json:"name"`
-	Sets int    `json:"sets"`
-	Reps int    `json:"reps"`
+type ExerciseRoutineStats struct {
+	ExerciseRoutineID  string   `json:"exerciseRoutineId"`
+	Name               string   `json:"name"`
+	Sets               int      `json:"sets"`
+	Volume             float64  `json:"volume"`
+	AverageRestSeconds *float64 `json:"averageRestSeconds"`
+}
+
+type ExerciseStrength struct {
+	ExerciseRoutineID  string         `json:"exerciseRoutineId"`
+	Name               string         `json:"name"`
+	CatalogExerciseID  *string        `json:"catalogExerciseId"`
+	EstimatedOneRepMax float64        `json:"estimatedOneRepMax"`
+	Weight             float64        `json:"weight"`
+	Reps               int            `json:"reps"`
+	PerformedAt        time.Time      `json:"performedAt"`
+	BodyWeightRatio    *float64       `json:"bodyWeightRatio"`
+	Level              *StrengthLevel `json:"level"`
+}
+
+type ExerciseSyncChange struct {
+	ClientID               string        `json:"clientId"`
+	Operation              SyncOperation `json:"operation"`
+	LastModified           time.Time     `json:"lastModified"`
+	WorkoutSessionClientID *string       `json:"workoutSessionClientId"`
+	ExerciseRoutineID      *string       `json:"exerciseRoutineId"`
+	Notes                  *string       `json:"notes"`
+}
+
+type ExerciseVideo struct {
+	ID          string    `json:"id"`
+	ExerciseID  string    `json:"exerciseId"`
+	SetEntryID  *string   `json:"setEntryId"`
+	URL         string    `json:"url"`
+	ContentType string    `json:"contentType"`
+	UploadedAt  time.Time `json:"uploadedAt"`
+}
+
+type FormatHints struct {
+	Locale            string     `json:"locale"`
+	DecimalSeparator  string     `json:"decimalSeparator"`
+	GroupingSeparator string     `json:"groupingSeparator"`
+	WeightUnit        WeightUnit `json:"weightUnit"`
+	WeightUnitLabel   string     `json:"weightUnitLabel"`
+	WeightDecimals    int        `json:"weightDecimals"`
+	VolumeDecimals    int        `json:"volumeDecimals"`
+}
+
+type Goal struct {
+	ID                string     `json:"id"`
+	Type              GoalType   `json:"type"`
+	TargetValue       float64    `json:"targetValue"`
+	StartValue        *float64   `json:"startValue"`
+	CurrentValue      *float64   `json:"currentValue"`
+	Progress          float64    `json:"progress"`
+	CatalogExerciseID *string    `json:"catalogExerciseId"`
+	Deadline          time.Time  `json:"deadline"`
+	Status            GoalStatus `json:"status"`
+	CompletedAt       *time.Time `json:"completedAt"`
+}
+
+type GoalInput struct {
+	Type              GoalType  `json:"type"`
+	TargetValue       float64   `json:"targetValue"`
+	CatalogExerciseID *string   `json:"catalogExerciseId"`
+	Deadline          time.Time `json:"deadline"`
+}
+
+type Impersonation struct {
+	AccessToken string    `json:"accessToken"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+type ImportedExerciseRoutine struct {
+	WorkoutRoutine  string  `json:"workoutRoutine"`
+	Name            string  `json:"name"`
+	CatalogExercise *string `json:"catalogExercise"`
+}
+
+type LifetimeStats struct {
+	TotalSessions int        `json:"totalSessions"`
+	TotalSets     int        `json:"totalSets"`
+	TotalVolume   float64    `json:"totalVolume"`
+	WeightUnit    WeightUnit `json:"weightUnit"`
+	MemberSince   time.Time  `json:"memberSince"`
+}
+
+type LiveSetUpdate struct {
+	ExerciseID string    `json:"exerciseId"`
+	Set        *SetEntry `json:"set"`
+	Deleted    bool      `json:"deleted"`
 }
 
 type LoginInput struct {
@@ -36,6 +252,37 @@ type LoginInput struct {
 	Password string `json:"password"`
 }
 
+type MuscleGroupStats struct {
+	MuscleGroup MuscleGroup `json:"muscleGroup"`
+	Sets        int         `json:"sets"`
+	Volume      float64     `json:"volume"`
+}
+
+type NotificationPreferences struct {
+	WeeklyDigestEmail bool `json:"weeklyDigestEmail"`
+	WeeklyDigestPush  bool `json:"weeklyDigestPush"`
+}
+
+type NotificationPreferencesInput struct {
+	WeeklyDigestEmail *bool `json:"weeklyDigestEmail"`
+	WeeklyDigestPush  *bool `json:"weeklyDigestPush"`
+}
+
+type NutritionInput struct {
+	Day      time.Time `json:"day"`
+	Calories *int      `json:"calories"`
+	Protein  *float64  `json:"protein"`
+	Notes    *string   `json:"notes"`
+}
+
+type NutritionLog struct {
+	ID       string    `json:"id"`
+	Day      time.Time `json:"day"`
+	Calories *int      `json:"calories"`
+	Protein  *float64  `json:"protein"`
+	Notes    string    `json:"notes"`
+}
+
 type PageInfo struct {
 	HasNextPage bool `json:"hasNextPage"`
 }
@@ -46,19 +293,141 @@ type PasswordResetCredentials struct {
 	ConfirmPassword string `json:"confirmPassword"`
 }
 
+type PersonalRecord struct {
+	ExerciseRoutineID string  `json:"exerciseRoutineId"`
+	Name              string  `json:"name"`
+	Weight            float64 `json:"weight"`
+	Reps              int     `json:"reps"`
+	PreviousWeight    float64 `json:"previousWeight"`
+}
+
+type Preferences struct {
+	WeightUnit    WeightUnit               `json:"weightUnit"`
+	WeekStart     WeekStart                `json:"weekStart"`
+	TimeZone      string                   `json:"timeZone"`
+	Notifications *NotificationPreferences `json:"notifications"`
+}
+
+type PreferencesInput struct {
+	WeightUnit    *WeightUnit                   `json:"weightUnit"`
+	WeekStart     *WeekStart                    `json:"weekStart"`
+	TimeZone      *string                       `json:"timeZone"`
+	Notifications *NotificationPreferencesInput `json:"notifications"`
+}
+
+type Program struct {
+	ID     string        `json:"id"`
+	Name   string        `json:"name"`
+	Active bool          `json:"active"`
+	Days   []*ProgramDay `json:"days"`
+}
+
+type ProgramDay struct {
+	Weekday        Weekday         `json:"weekday"`
+	WorkoutRoutine *WorkoutRoutine `json:"workoutRoutine"`
+}
+
+type ProgramDayInput struct {
+	Weekday          Weekday `json:"weekday"`
+	WorkoutRoutineID string  `json:"workoutRoutineId"`
+}
+
+type ProgramInput struct {
+	Name   string             `json:"name"`
+	Active *bool              `json:"active"`
+	Days   []*ProgramDayInput `json:"days"`
+}
+
+type ProgressPhoto struct {
+	ID          string    `json:"id"`
+	TakenOn     time.Time `json:"takenOn"`
+	BodyWeight  *float64  `json:"bodyWeight"`
+	ContentType string    `json:"contentType"`
+	URL         string    `json:"url"`
+	ExpiresAt   time.Time `json:"expiresAt"`
+}
+
+type ProgressPhotoInput struct {
+	ContentType string     `json:"contentType"`
+	TakenOn     *time.Time `json:"takenOn"`
+	BodyWeight  *float64   `json:"bodyWeight"`
+}
+
+type ProgressPhotoUpload struct {
+	ProgressPhotoID string    `json:"progressPhotoId"`
+	UploadURL       string    `json:"uploadUrl"`
+	ContentType     string    `json:"contentType"`
+	MaxBytes        int       `json:"maxBytes"`
+	ExpiresAt       time.Time `json:"expiresAt"`
+}
+
+type QuickPhrase struct {
+	ID       string `json:"id"`
+	Text     string `json:"text"`
+	Position int    `json:"position"`
+}
+
 type RefreshSuccess struct {
-	AccessToken string `json:"accessToken"`
+	AccessToken  string `json:"accessToken"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+type RequestRecording struct {
+	ID            string    `json:"id"`
+	OperationName string    `json:"operationName"`
+	Query         string    `json:"query"`
+	Variables     string    `json:"variables"`
+	DurationMs    int       `json:"durationMs"`
+	RecordedAt    time.Time `json:"recordedAt"`
 }
 
 type SetEntry struct {
-	ID     string  `json:"id"`
-	Weight float64 `json:"weight"`
-	Reps   int     `json:"reps"`
+	ID              string                 `json:"id"`
+	Weight          float64                `json:"weight"`
+	Unit            WeightUnit             `json:"unit"`
+	Reps            int                    `json:"reps"`
+	SetOrder        int                    `json:"setOrder"`
+	Type            SetType                `json:"type"`
+	RestTimeSeconds *int                   `json:"restTimeSeconds"`
+	CompletedAt     *time.Time             `json:"completedAt"`
+	ClientMetadata  map[string]interface{} `json:"clientMetadata"`
+	Origin          *ClientOrigin          `json:"origin"`
+	UpdatedAt       time.Time              `json:"updatedAt"`
 }
 
 type SetEntryInput struct {
-	Weight float64 `json:"weight"`
-	Reps   int     `json:"reps"`
+	Weight          float64                `json:"weight"`
+	Unit            *WeightUnit            `json:"unit"`
+	Reps            int                    `json:"reps"`
+	SetOrder        *int                   `json:"setOrder"`
+	Type            *SetType               `json:"type"`
+	RestTimeSeconds *int                   `json:"restTimeSeconds"`
+	CompletedAt     *time.Time             `json:"completedAt"`
+	ClientMetadata  map[string]interface{} `json:"clientMetadata"`
+}
+
+type SetEntrySyncChange struct {
+	ClientID         string        `json:"clientId"`
+	Operation        SyncOperation `json:"operation"`
+	LastModified     time.Time     `json:"lastModified"`
+	ExerciseClientID *string       `json:"exerciseClientId"`
+	Weight           *float64      `json:"weight"`
+	Unit             *WeightUnit   `json:"unit"`
+	Reps             *int          `json:"reps"`
+	Type             *SetType      `json:"type"`
+	CompletedAt      *time.Time    `json:"completedAt"`
+}
+
+type SharedExerciseRoutine struct {
+	Name            string           `json:"name"`
+	Sets            int              `json:"sets"`
+	Reps            int              `json:"reps"`
+	CatalogExercise *CatalogExercise `json:"catalogExercise"`
+}
+
+type SharedWorkoutRoutine struct {
+	Name             string                   `json:"name"`
+	ExerciseRoutines []*SharedExerciseRoutine `json:"exerciseRoutines"`
 }
 
 type SignupInput struct {
@@ -68,37 +437,156 @@ type SignupInput struct {
 	ConfirmPassword string `json:"confirmPassword"`
 }
 
+type SleepInput struct {
+	Night time.Time `json:"night"`
+	Hours float64   `json:"hours"`
+}
+
+type SleepLog struct {
+	ID    string    `json:"id"`
+	Night time.Time `json:"night"`
+	Hours float64   `json:"hours"`
+}
+
+type Snapshot struct {
+	ID                  string     `json:"id"`
+	CreatedAt           time.Time  `json:"createdAt"`
+	WorkoutRoutineCount int        `json:"workoutRoutineCount"`
+	WorkoutSessionCount int        `json:"workoutSessionCount"`
+	RestoredAt          *time.Time `json:"restoredAt"`
+}
+
+type StravaConnection struct {
+	AthleteID   string    `json:"athleteId"`
+	ConnectedAt time.Time `json:"connectedAt"`
+}
+
+type StrengthProfile struct {
+	Formula    OneRepMaxFormula    `json:"formula"`
+	WeightUnit WeightUnit          `json:"weightUnit"`
+	BodyWeight *float64            `json:"bodyWeight"`
+	Exercises  []*ExerciseStrength `json:"exercises"`
+}
+
+type Suggestion struct {
+	Text string         `json:"text"`
+	Kind SuggestionKind `json:"kind"`
+	ID   *string        `json:"id"`
+}
+
+type SyncResult struct {
+	Entity   SyncEntity `json:"entity"`
+	ClientID string     `json:"clientId"`
+	ID       *string    `json:"id"`
+	Status   SyncStatus `json:"status"`
+	Message  *string    `json:"message"`
+}
+
+type SyncWorkoutDataInput struct {
+	WorkoutSessions []*WorkoutSessionSyncChange `json:"workoutSessions"`
+	Exercises       []*ExerciseSyncChange       `json:"exercises"`
+	Sets            []*SetEntrySyncChange       `json:"sets"`
+}
+
+type UnitConversion struct {
+	ID                string     `json:"id"`
+	ExerciseRoutineID string     `json:"exerciseRoutineId"`
+	From              WeightUnit `json:"from"`
+	To                WeightUnit `json:"to"`
+	RangeStart        time.Time  `json:"rangeStart"`
+	RangeEnd          time.Time  `json:"rangeEnd"`
+	SetsConverted     int        `json:"setsConverted"`
+	ConvertedAt       time.Time  `json:"convertedAt"`
+}
+
+type UpdateBodyWeightInput struct {
+	Weight   *float64   `json:"weight"`
+	LoggedAt *time.Time `json:"loggedAt"`
+}
+
 type UpdateExerciseInput struct {
-	Notes string `json:"notes"`
+	Notes             string     `json:"notes"`
+	ExpectedUpdatedAt *time.Time `json:"expectedUpdatedAt"`
 }
 
 type UpdateExerciseRoutineInput struct {
-	ID   *string `json:"id"`
-	Name string  `json:"name"`
-	Sets int     `json:"sets"`
-	Reps int     `json:"reps"`
+	ID                *string `json:"id"`
+	Name              string  `json:"name"`
+	Sets              int     `json:"sets"`
+	Reps              int     `json:"reps"`
+	CatalogExerciseID *string `json:"catalogExerciseId"`
+}
+
+type UpdateGoalInput struct {
+	TargetValue *float64   `json:"targetValue"`
+	Deadline    *time.Time `json:"deadline"`
 }
 
 type UpdateSetEntryInput struct {
-	Weight *float64 `json:"weight"`
-	Reps   *int     `json:"reps"`
+	Weight            *float64               `json:"weight"`
+	Unit              *WeightUnit            `json:"unit"`
+	Reps              *int                   `json:"reps"`
+	SetOrder          *int                   `json:"setOrder"`
+	Type              *SetType               `json:"type"`
+	RestTimeSeconds   *int                   `json:"restTimeSeconds"`
+	CompletedAt       *time.Time             `json:"completedAt"`
+	ClientMetadata    map[string]interface{} `json:"clientMetadata"`
+	ExpectedUpdatedAt *time.Time             `json:"expectedUpdatedAt"`
 }
 
 type UpdateWorkoutRoutineInput struct {
-	ID               string                        `json:"id"`
-	Name             string                        `json:"name"`
-	ExerciseRoutines []*UpdateExerciseRoutineInput `json:"exerciseRoutines"`
+	ID                string                        `json:"id"`
+	Name              string                        `json:"name"`
+	ExerciseRoutines  []*UpdateExerciseRoutineInput `json:"exerciseRoutines"`
+	ExpectedUpdatedAt *time.Time                    `json:"expectedUpdatedAt"`
 }
 
 type UpdateWorkoutSessionInput struct {
-	Start *time.Time `json:"start"`
-	End   *time.Time `json:"end"`
+	Start             *time.Time             `json:"start"`
+	End               *time.Time             `json:"end"`
+	ClientMetadata    map[string]interface{} `json:"clientMetadata"`
+	ExpectedUpdatedAt *time.Time             `json:"expectedUpdatedAt"`
+}
+
+type UsageStats struct {
+	Users              int `json:"users"`
+	VerifiedUsers      int `json:"verifiedUsers"`
+	WeeklyActiveUsers  int `json:"weeklyActiveUsers"`
+	MonthlyActiveUsers int `json:"monthlyActiveUsers"`
+	WorkoutSessions    int `json:"workoutSessions"`
+	Sets               int `json:"sets"`
+}
+
+type VideoAnnotation struct {
+	ID          string    `json:"id"`
+	CoachID     string    `json:"coachId"`
+	TimestampMs int       `json:"timestampMs"`
+	Note        string    `json:"note"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type Webhook struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type WeeklyStats struct {
+	WeekStart    time.Time `json:"weekStart"`
+	SessionCount int       `json:"sessionCount"`
+	TotalSets    int       `json:"totalSets"`
+	TotalVolume  float64   `json:"totalVolume"`
 }
 
-type User struct {
-	ID    string `json:"id"`
-	Name  string `json:"name"`
-	Email string `json:"email"`
+type WorkoutHistoryImport struct {
+	Format                 ImportFormat               `json:"format"`
+	DryRun                 bool                       `json:"dryRun"`
+	WorkoutSessions        int                        `json:"workoutSessions"`
+	Sets                   int                        `json:"sets"`
+	SkippedWorkoutSessions int                        `json:"skippedWorkoutSessions"`
+	NewWorkoutRoutines     []string                   `json:"newWorkoutRoutines"`
+	NewExerciseRoutines    []*ImportedExerciseRoutine `json:"newExerciseRoutines"`
 }
 
 type WorkoutRoutineConnection struct {
@@ -111,11 +599,25 @@ type WorkoutRoutineEdge struct {
 	Cursor string          `json:"cursor"`
 }
 
+type WorkoutRoutineFilter struct {
+	NamePrefix *string `json:"namePrefix"`
+}
+
 type WorkoutRoutineInput struct {
 	Name             string                  `json:"name"`
 	ExerciseRoutines []*ExerciseRoutineInput `json:"exerciseRoutines"`
 }
 
+type WorkoutRoutineOrder struct {
+	Field     WorkoutRoutineOrderField `json:"field"`
+	Direction *OrderDirection          `json:"direction"`
+}
+
+type WorkoutRoutineShareLink struct {
+	Token            string `json:"token"`
+	WorkoutRoutineID string `json:"workoutRoutineId"`
+}
+
 type WorkoutSessionConnection struct {
 	Edges    []*WorkoutSessionEdge `json:"edges"`
 	PageInfo *PageInfo             `json:"pageInfo"`
@@ -127,8 +629,1018 @@ type WorkoutSessionEdge struct {
 }
 
 type WorkoutSessionInput struct {
-	WorkoutRoutineID string           `json:"workoutRoutineId"`
-	Start            time.Time        `json:"start"`
-	End              *time.Time       `json:"end"`
-	Exercises        []*ExerciseInput `json:"exercises"`
+	WorkoutRoutineID string                 `json:"workoutRoutineId"`
+	Start            time.Time              `json:"start"`
+	End              *time.Time             `json:"end"`
+	Exercises        []*ExerciseInput       `json:"exercises"`
+	ClientMetadata   map[string]interface{} `json:"clientMetadata"`
+}
+
+type WorkoutSessionShareLink struct {
+	Token            string `json:"token"`
+	WorkoutSessionID string `json:"workoutSessionId"`
+	Invite           bool   `json:"invite"`
+}
+
+type WorkoutSessionSyncChange struct {
+	ClientID         string        `json:"clientId"`
+	Operation        SyncOperation `json:"operation"`
+	LastModified     time.Time     `json:"lastModified"`
+	WorkoutRoutineID *string       `json:"workoutRoutineId"`
+	Start            *time.Time    `json:"start"`
+	End              *time.Time    `json:"end"`
+	Notes            *string       `json:"notes"`
+}
+
+type WorkoutSessionUnlock struct {
+	WorkoutSessionID string    `json:"workoutSessionId"`
+	UnlockedUntil    time.Time `json:"unlockedUntil"`
+}
+
+type WorkoutSummary struct {
+	DurationSeconds    int               `json:"durationSeconds"`
+	TotalVolume        float64           `json:"totalVolume"`
+	WeightUnit         WeightUnit        `json:"weightUnit"`
+	TotalSets          int               `json:"totalSets"`
+	ExercisesCompleted int               `json:"exercisesCompleted"`
+	ExercisesPlanned   int               `json:"exercisesPlanned"`
+	PersonalRecords    []*PersonalRecord `json:"personalRecords"`
+}
+
+type AccountExportStatus string
+
+const (
+	AccountExportStatusPending AccountExportStatus = "PENDING"
+	AccountExportStatusRunning AccountExportStatus = "RUNNING"
+	AccountExportStatusReady   AccountExportStatus = "READY"
+	AccountExportStatusFailed  AccountExportStatus = "FAILED"
+)
+
+var AllAccountExportStatus = []AccountExportStatus{
+	AccountExportStatusPending,
+	AccountExportStatusRunning,
+	AccountExportStatusReady,
+	AccountExportStatusFailed,
+}
+
+func (e AccountExportStatus) IsValid() bool {
+	switch e {
+	case AccountExportStatusPending, AccountExportStatusRunning, AccountExportStatusReady, AccountExportStatusFailed:
+		return true
+	}
+	return false
+}
+
+func (e AccountExportStatus) String() string {
+	return string(e)
+}
+
+func (e *AccountExportStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = AccountExportStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid AccountExportStatus", str)
+	}
+	return nil
+}
+
+func (e AccountExportStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type AuditEntity string
+
+const (
+	AuditEntityWorkoutRoutine  AuditEntity = "WORKOUT_ROUTINE"
+	AuditEntityExerciseRoutine AuditEntity = "EXERCISE_ROUTINE"
+	AuditEntityWorkoutSession  AuditEntity = "WORKOUT_SESSION"
+	AuditEntityExercise        AuditEntity = "EXERCISE"
+	AuditEntitySetEntry        AuditEntity = "SET_ENTRY"
+	AuditEntityBodyWeight      AuditEntity = "BODY_WEIGHT"
+	AuditEntityGoal            AuditEntity = "GOAL"
+	AuditEntityProgram         AuditEntity = "PROGRAM"
+	AuditEntityUser            AuditEntity = "USER"
+)
+
+var AllAuditEntity = []AuditEntity{
+	AuditEntityWorkoutRoutine,
+	AuditEntityExerciseRoutine,
+	AuditEntityWorkoutSession,
+	AuditEntityExercise,
+	AuditEntitySetEntry,
+	AuditEntityBodyWeight,
+	AuditEntityGoal,
+	AuditEntityProgram,
+	AuditEntityUser,
+}
+
+func (e AuditEntity) IsValid() bool {
+	switch e {
+	case AuditEntityWorkoutRoutine, AuditEntityExerciseRoutine, AuditEntityWorkoutSession, AuditEntityExercise, AuditEntitySetEntry, AuditEntityBodyWeight, AuditEntityGoal, AuditEntityProgram, AuditEntityUser:
+		return true
+	}
+	return false
+}
+
+func (e AuditEntity) String() string {
+	return string(e)
+}
+
+func (e *AuditEntity) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = AuditEntity(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid AuditEntity", str)
+	}
+	return nil
+}
+
+func (e AuditEntity) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type AutocompleteScope string
+
+const (
+	AutocompleteScopeAll       AutocompleteScope = "ALL"
+	AutocompleteScopeExercises AutocompleteScope = "EXERCISES"
+	AutocompleteScopeCatalog   AutocompleteScope = "CATALOG"
+	AutocompleteScopeTags      AutocompleteScope = "TAGS"
+)
+
+var AllAutocompleteScope = []AutocompleteScope{
+	AutocompleteScopeAll,
+	AutocompleteScopeExercises,
+	AutocompleteScopeCatalog,
+	AutocompleteScopeTags,
+}
+
+func (e AutocompleteScope) IsValid() bool {
+	switch e {
+	case AutocompleteScopeAll, AutocompleteScopeExercises, AutocompleteScopeCatalog, AutocompleteScopeTags:
+		return true
+	}
+	return false
+}
+
+func (e AutocompleteScope) String() string {
+	return string(e)
+}
+
+func (e *AutocompleteScope) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = AutocompleteScope(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid AutocompleteScope", str)
+	}
+	return nil
+}
+
+func (e AutocompleteScope) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type CoachAccess string
+
+const (
+	CoachAccessRead      CoachAccess = "READ"
+	CoachAccessReadWrite CoachAccess = "READ_WRITE"
+)
+
+var AllCoachAccess = []CoachAccess{
+	CoachAccessRead,
+	CoachAccessReadWrite,
+}
+
+func (e CoachAccess) IsValid() bool {
+	switch e {
+	case CoachAccessRead, CoachAccessReadWrite:
+		return true
+	}
+	return false
+}
+
+func (e CoachAccess) String() string {
+	return string(e)
+}
+
+func (e *CoachAccess) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = CoachAccess(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid CoachAccess", str)
+	}
+	return nil
+}
+
+func (e CoachAccess) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type GoalStatus string
+
+const (
+	GoalStatusInProgress GoalStatus = "IN_PROGRESS"
+	GoalStatusCompleted  GoalStatus = "COMPLETED"
+	GoalStatusExpired    GoalStatus = "EXPIRED"
+)
+
+var AllGoalStatus = []GoalStatus{
+	GoalStatusInProgress,
+	GoalStatusCompleted,
+	GoalStatusExpired,
+}
+
+func (e GoalStatus) IsValid() bool {
+	switch e {
+	case GoalStatusInProgress, GoalStatusCompleted, GoalStatusExpired:
+		return true
+	}
+	return false
+}
+
+func (e GoalStatus) String() string {
+	return string(e)
+}
+
+func (e *GoalStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = GoalStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid GoalStatus", str)
+	}
+	return nil
+}
+
+func (e GoalStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type GoalType string
+
+const (
+	GoalTypeBodyWeight GoalType = "BODY_WEIGHT"
+	GoalTypeLiftE1rm   GoalType = "LIFT_E1RM"
+)
+
+var AllGoalType = []GoalType{
+	GoalTypeBodyWeight,
+	GoalTypeLiftE1rm,
+}
+
+func (e GoalType) IsValid() bool {
+	switch e {
+	case GoalTypeBodyWeight, GoalTypeLiftE1rm:
+		return true
+	}
+	return false
+}
+
+func (e GoalType) String() string {
+	return string(e)
+}
+
+func (e *GoalType) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = GoalType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid GoalType", str)
+	}
+	return nil
+}
+
+func (e GoalType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ImportFormat string
+
+const (
+	ImportFormatStrong ImportFormat = "STRONG"
+	ImportFormatHevy   ImportFormat = "HEVY"
+)
+
+var AllImportFormat = []ImportFormat{
+	ImportFormatStrong,
+	ImportFormatHevy,
+}
+
+func (e ImportFormat) IsValid() bool {
+	switch e {
+	case ImportFormatStrong, ImportFormatHevy:
+		return true
+	}
+	return false
+}
+
+func (e ImportFormat) String() string {
+	return string(e)
+}
+
+func (e *ImportFormat) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ImportFormat(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ImportFormat", str)
+	}
+	return nil
+}
+
+func (e ImportFormat) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type MuscleGroup string
+
+const (
+	MuscleGroupChest      MuscleGroup = "CHEST"
+	MuscleGroupBack       MuscleGroup = "BACK"
+	MuscleGroupShoulders  MuscleGroup = "SHOULDERS"
+	MuscleGroupBiceps     MuscleGroup = "BICEPS"
+	MuscleGroupTriceps    MuscleGroup = "TRICEPS"
+	MuscleGroupForearms   MuscleGroup = "FOREARMS"
+	MuscleGroupAbs        MuscleGroup = "ABS"
+	MuscleGroupQuads      MuscleGroup = "QUADS"
+	MuscleGroupHamstrings MuscleGroup = "HAMSTRINGS"
+	MuscleGroupGlutes     MuscleGroup = "GLUTES"
+	MuscleGroupCalves     MuscleGroup = "CALVES"
+)
+
+var AllMuscleGroup = []MuscleGroup{
+	MuscleGroupChest,
+	MuscleGroupBack,
+	MuscleGroupShoulders,
+	MuscleGroupBiceps,
+	MuscleGroupTriceps,
+	MuscleGroupForearms,
+	MuscleGroupAbs,
+	MuscleGroupQuads,
+	MuscleGroupHamstrings,
+	MuscleGroupGlutes,
+	MuscleGroupCalves,
+}
+
+func (e MuscleGroup) IsValid() bool {
+	switch e {
+	case MuscleGroupChest, MuscleGroupBack, MuscleGroupShoulders, MuscleGroupBiceps, MuscleGroupTriceps, MuscleGroupForearms, MuscleGroupAbs, MuscleGroupQuads, MuscleGroupHamstrings, MuscleGroupGlutes, MuscleGroupCalves:
+		return true
+	}
+	return false
+}
+
+func (e MuscleGroup) String() string {
+	return string(e)
+}
+
+func (e *MuscleGroup) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = MuscleGroup(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid MuscleGroup", str)
+	}
+	return nil
+}
+
+func (e MuscleGroup) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type OneRepMaxFormula string
+
+const (
+	OneRepMaxFormulaEpley   OneRepMaxFormula = "EPLEY"
+	OneRepMaxFormulaBrzycki OneRepMaxFormula = "BRZYCKI"
+)
+
+var AllOneRepMaxFormula = []OneRepMaxFormula{
+	OneRepMaxFormulaEpley,
+	OneRepMaxFormulaBrzycki,
+}
+
+func (e OneRepMaxFormula) IsValid() bool {
+	switch e {
+	case OneRepMaxFormulaEpley, OneRepMaxFormulaBrzycki:
+		return true
+	}
+	return false
+}
+
+func (e OneRepMaxFormula) String() string {
+	return string(e)
+}
+
+func (e *OneRepMaxFormula) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = OneRepMaxFormula(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid OneRepMaxFormula", str)
+	}
+	return nil
+}
+
+func (e OneRepMaxFormula) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type OrderDirection string
+
+const (
+	OrderDirectionAsc  OrderDirection = "ASC"
+	OrderDirectionDesc OrderDirection = "DESC"
+)
+
+var AllOrderDirection = []OrderDirection{
+	OrderDirectionAsc,
+	OrderDirectionDesc,
+}
+
+func (e OrderDirection) IsValid() bool {
+	switch e {
+	case OrderDirectionAsc, OrderDirectionDesc:
+		return true
+	}
+	return false
+}
+
+func (e OrderDirection) String() string {
+	return string(e)
+}
+
+func (e *OrderDirection) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = OrderDirection(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid OrderDirection", str)
+	}
+	return nil
+}
+
+func (e OrderDirection) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type Role string
+
+const (
+	RoleAdmin Role = "ADMIN"
+)
+
+var AllRole = []Role{
+	RoleAdmin,
+}
+
+func (e Role) IsValid() bool {
+	switch e {
+	case RoleAdmin:
+		return true
+	}
+	return false
+}
+
+func (e Role) String() string {
+	return string(e)
+}
+
+func (e *Role) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Role(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Role", str)
+	}
+	return nil
+}
+
+func (e Role) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type SetType string
+
+const (
+	SetTypeWarmup  SetType = "WARMUP"
+	SetTypeWorking SetType = "WORKING"
+	SetTypeDropset SetType = "DROPSET"
+	SetTypeFailure SetType = "FAILURE"
+)
+
+var AllSetType = []SetType{
+	SetTypeWarmup,
+	SetTypeWorking,
+	SetTypeDropset,
+	SetTypeFailure,
+}
+
+func (e SetType) IsValid() bool {
+	switch e {
+	case SetTypeWarmup, SetTypeWorking, SetTypeDropset, SetTypeFailure:
+		return true
+	}
+	return false
+}
+
+func (e SetType) String() string {
+	return string(e)
+}
+
+func (e *SetType) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SetType(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SetType", str)
+	}
+	return nil
+}
+
+func (e SetType) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type SnapshotRestoreMode string
+
+const (
+	SnapshotRestoreModeMerge   SnapshotRestoreMode = "MERGE"
+	SnapshotRestoreModeReplace SnapshotRestoreMode = "REPLACE"
+)
+
+var AllSnapshotRestoreMode = []SnapshotRestoreMode{
+	SnapshotRestoreModeMerge,
+	SnapshotRestoreModeReplace,
+}
+
+func (e SnapshotRestoreMode) IsValid() bool {
+	switch e {
+	case SnapshotRestoreModeMerge, SnapshotRestoreModeReplace:
+		return true
+	}
+	return false
+}
+
+func (e SnapshotRestoreMode) String() string {
+	return string(e)
+}
+
+func (e *SnapshotRestoreMode) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SnapshotRestoreMode(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SnapshotRestoreMode", str)
+	}
+	return nil
+}
+
+func (e SnapshotRestoreMode) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type StrengthLevel string
+
+const (
+	StrengthLevelBeginner     StrengthLevel = "BEGINNER"
+	StrengthLevelNovice       StrengthLevel = "NOVICE"
+	StrengthLevelIntermediate StrengthLevel = "INTERMEDIATE"
+	StrengthLevelAdvanced     StrengthLevel = "ADVANCED"
+	StrengthLevelElite        StrengthLevel = "ELITE"
+)
+
+var AllStrengthLevel = []StrengthLevel{
+	StrengthLevelBeginner,
+	StrengthLevelNovice,
+	StrengthLevelIntermediate,
+	StrengthLevelAdvanced,
+	StrengthLevelElite,
+}
+
+func (e StrengthLevel) IsValid() bool {
+	switch e {
+	case StrengthLevelBeginner, StrengthLevelNovice, StrengthLevelIntermediate, StrengthLevelAdvanced, StrengthLevelElite:
+		return true
+	}
+	return false
+}
+
+func (e StrengthLevel) String() string {
+	return string(e)
+}
+
+func (e *StrengthLevel) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = StrengthLevel(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid StrengthLevel", str)
+	}
+	return nil
+}
+
+func (e StrengthLevel) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type SuggestionKind string
+
+const (
+	SuggestionKindExercise        SuggestionKind = "EXERCISE"
+	SuggestionKindCatalogExercise SuggestionKind = "CATALOG_EXERCISE"
+	SuggestionKindMuscleGroup     SuggestionKind = "MUSCLE_GROUP"
+	SuggestionKindEquipment       SuggestionKind = "EQUIPMENT"
+)
+
+var AllSuggestionKind = []SuggestionKind{
+	SuggestionKindExercise,
+	SuggestionKindCatalogExercise,
+	SuggestionKindMuscleGroup,
+	SuggestionKindEquipment,
+}
+
+func (e SuggestionKind) IsValid() bool {
+	switch e {
+	case SuggestionKindExercise, SuggestionKindCatalogExercise, SuggestionKindMuscleGroup, SuggestionKindEquipment:
+		return true
+	}
+	return false
+}
+
+func (e SuggestionKind) String() string {
+	return string(e)
+}
+
+func (e *SuggestionKind) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SuggestionKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SuggestionKind", str)
+	}
+	return nil
+}
+
+func (e SuggestionKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type SyncEntity string
+
+const (
+	SyncEntityWorkoutSession SyncEntity = "WORKOUT_SESSION"
+	SyncEntityExercise       SyncEntity = "EXERCISE"
+	SyncEntitySetEntry       SyncEntity = "SET_ENTRY"
+)
+
+var AllSyncEntity = []SyncEntity{
+	SyncEntityWorkoutSession,
+	SyncEntityExercise,
+	SyncEntitySetEntry,
+}
+
+func (e SyncEntity) IsValid() bool {
+	switch e {
+	case SyncEntityWorkoutSession, SyncEntityExercise, SyncEntitySetEntry:
+		return true
+	}
+	return false
+}
+
+func (e SyncEntity) String() string {
+	return string(e)
+}
+
+func (e *SyncEntity) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SyncEntity(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SyncEntity", str)
+	}
+	return nil
+}
+
+func (e SyncEntity) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type SyncOperation string
+
+const (
+	SyncOperationCreate SyncOperation = "CREATE"
+	SyncOperationUpdate SyncOperation = "UPDATE"
+	SyncOperationDelete SyncOperation = "DELETE"
+)
+
+var AllSyncOperation = []SyncOperation{
+	SyncOperationCreate,
+	SyncOperationUpdate,
+	SyncOperationDelete,
+}
+
+func (e SyncOperation) IsValid() bool {
+	switch e {
+	case SyncOperationCreate, SyncOperationUpdate, SyncOperationDelete:
+		return true
+	}
+	return false
+}
+
+func (e SyncOperation) String() string {
+	return string(e)
+}
+
+func (e *SyncOperation) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SyncOperation(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SyncOperation", str)
+	}
+	return nil
+}
+
+func (e SyncOperation) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type SyncStatus string
+
+const (
+	SyncStatusApplied   SyncStatus = "APPLIED"
+	SyncStatusDuplicate SyncStatus = "DUPLICATE"
+	SyncStatusStale     SyncStatus = "STALE"
+	SyncStatusRejected  SyncStatus = "REJECTED"
+)
+
+var AllSyncStatus = []SyncStatus{
+	SyncStatusApplied,
+	SyncStatusDuplicate,
+	SyncStatusStale,
+	SyncStatusRejected,
+}
+
+func (e SyncStatus) IsValid() bool {
+	switch e {
+	case SyncStatusApplied, SyncStatusDuplicate, SyncStatusStale, SyncStatusRejected:
+		return true
+	}
+	return false
+}
+
+func (e SyncStatus) String() string {
+	return string(e)
+}
+
+func (e *SyncStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SyncStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SyncStatus", str)
+	}
+	return nil
+}
+
+func (e SyncStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type WeekStart string
+
+const (
+	WeekStartSunday   WeekStart = "SUNDAY"
+	WeekStartMonday   WeekStart = "MONDAY"
+	WeekStartSaturday WeekStart = "SATURDAY"
+)
+
+var AllWeekStart = []WeekStart{
+	WeekStartSunday,
+	WeekStartMonday,
+	WeekStartSaturday,
+}
+
+func (e WeekStart) IsValid() bool {
+	switch e {
+	case WeekStartSunday, WeekStartMonday, WeekStartSaturday:
+		return true
+	}
+	return false
+}
+
+func (e WeekStart) String() string {
+	return string(e)
+}
+
+func (e *WeekStart) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = WeekStart(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid WeekStart", str)
+	}
+	return nil
+}
+
+func (e WeekStart) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type Weekday string
+
+const (
+	WeekdayMonday    Weekday = "MONDAY"
+	WeekdayTuesday   Weekday = "TUESDAY"
+	WeekdayWednesday Weekday = "WEDNESDAY"
+	WeekdayThursday  Weekday = "THURSDAY"
+	WeekdayFriday    Weekday = "FRIDAY"
+	WeekdaySaturday  Weekday = "SATURDAY"
+	WeekdaySunday    Weekday = "SUNDAY"
+)
+
+var AllWeekday = []Weekday{
+	WeekdayMonday,
+	WeekdayTuesday,
+	WeekdayWednesday,
+	WeekdayThursday,
+	WeekdayFriday,
+	WeekdaySaturday,
+	WeekdaySunday,
+}
+
+func (e Weekday) IsValid() bool {
+	switch e {
+	case WeekdayMonday, WeekdayTuesday, WeekdayWednesday, WeekdayThursday, WeekdayFriday, WeekdaySaturday, WeekdaySunday:
+		return true
+	}
+	return false
+}
+
+func (e Weekday) String() string {
+	return string(e)
+}
+
+func (e *Weekday) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Weekday(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Weekday", str)
+	}
+	return nil
+}
+
+func (e Weekday) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type WeightUnit string
+
+const (
+	WeightUnitKg WeightUnit = "KG"
+	WeightUnitLb WeightUnit = "LB"
+)
+
+var AllWeightUnit = []WeightUnit{
+	WeightUnitKg,
+	WeightUnitLb,
+}
+
+func (e WeightUnit) IsValid() bool {
+	switch e {
+	case WeightUnitKg, WeightUnitLb:
+		return true
+	}
+	return false
+}
+
+func (e WeightUnit) String() string {
+	return string(e)
+}
+
+func (e *WeightUnit) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = WeightUnit(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid WeightUnit", str)
+	}
+	return nil
+}
+
+func (e WeightUnit) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type WorkoutRoutineOrderField string
+
+const (
+	WorkoutRoutineOrderFieldName            WorkoutRoutineOrderField = "NAME"
+	WorkoutRoutineOrderFieldLastPerformedAt WorkoutRoutineOrderField = "LAST_PERFORMED_AT"
+	WorkoutRoutineOrderFieldCreatedAt       WorkoutRoutineOrderField = "CREATED_AT"
+)
+
+var AllWorkoutRoutineOrderField = []WorkoutRoutineOrderField{
+	WorkoutRoutineOrderFieldName,
+	WorkoutRoutineOrderFieldLastPerformedAt,
+	WorkoutRoutineOrderFieldCreatedAt,
+}
+
+func (e WorkoutRoutineOrderField) IsValid() bool {
+	switch e {
+	case WorkoutRoutineOrderFieldName, WorkoutRoutineOrderFieldLastPerformedAt, WorkoutRoutineOrderFieldCreatedAt:
+		return true
+	}
+	return false
+}
+
+func (e WorkoutRoutineOrderField) String() string {
+	return string(e)
+}
+
+func (e *WorkoutRoutineOrderField) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = WorkoutRoutineOrderField(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid WorkoutRoutineOrderField", str)
+	}
+	return nil
+}
+
+func (e WorkoutRoutineOrderField) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
 }
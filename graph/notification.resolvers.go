@@ -0,0 +1,114 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+)
+
+// UpdateNotificationPreferences is the resolver for the updateNotificationPreferences field.
+func (r *mutationResolver) UpdateNotificationPreferences(ctx context.Context, preferences model.NotificationPreferencesInput) (*model.NotificationPreferences, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.NotificationPreferences{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.NotificationPreferences{}, err
+	}
+
+	p, err := database.GetUserPreferences(r.db(ctx), u.ID)
+	if err != nil {
+		return &model.NotificationPreferences{}, errors.From(err, "Error Updating Notification Preferences")
+	}
+	applyNotificationPreferences(p, &preferences)
+	err = database.SaveUserPreferences(r.db(ctx), p)
+	if err != nil {
+		return &model.NotificationPreferences{}, errors.From(err, "Error Updating Notification Preferences")
+	}
+
+	return toNotificationPreferences(p), nil
+}
+
+// RegisterPushToken is the resolver for the registerPushToken field.
+func (r *mutationResolver) RegisterPushToken(ctx context.Context, token string) (bool, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return false, err
+	}
+
+	if token == "" || len(token) > 255 {
+		return false, errors.InvalidInput("Error Registering Push Token: token needs to be between 1 and 255 characters")
+	}
+
+	err = database.SavePushToken(r.db(ctx), u.ID, token)
+	if err != nil {
+		return false, errors.From(err, "Error Registering Push Token")
+	}
+	return true, nil
+}
+
+// UnregisterPushToken is the resolver for the unregisterPushToken field.
+func (r *mutationResolver) UnregisterPushToken(ctx context.Context, token string) (int, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := database.DeletePushToken(r.db(ctx), u.ID, token)
+	if err != nil {
+		return 0, errors.From(err, "Error Unregistering Push Token")
+	}
+	return int(deleted), nil
+}
+
+// NotificationPreferences is the resolver for the notificationPreferences field.
+func (r *queryResolver) NotificationPreferences(ctx context.Context) (*model.NotificationPreferences, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.NotificationPreferences{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.NotificationPreferences{}, err
+	}
+
+	p, err := database.GetUserPreferences(r.db(ctx), u.ID)
+	if err != nil {
+		return &model.NotificationPreferences{}, errors.From(err, "Error Getting Notification Preferences")
+	}
+	return toNotificationPreferences(p), nil
+}
+
+// applyNotificationPreferences changes the opt-ins that were sent
+func applyNotificationPreferences(p *database.UserPreferences, preferences *model.NotificationPreferencesInput) {
+	if preferences.WeeklyDigestEmail != nil {
+		p.WeeklyDigestEmail = *preferences.WeeklyDigestEmail
+	}
+	if preferences.WeeklyDigestPush != nil {
+		p.WeeklyDigestPush = *preferences.WeeklyDigestPush
+	}
+}
+
+func toNotificationPreferences(p *database.UserPreferences) *model.NotificationPreferences {
+	return &model.NotificationPreferences{
+		WeeklyDigestEmail: p.WeeklyDigestEmail,
+		WeeklyDigestPush:  p.WeeklyDigestPush,
+	}
+}
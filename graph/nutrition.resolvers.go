@@ -0,0 +1,112 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/utils"
+)
+
+// longest range nutritionLogs returns in one go
+const maxNutritionLogDays = 366
+
+// LogNutrition is the resolver for the logNutrition field.
+func (r *mutationResolver) LogNutrition(ctx context.Context, nutrition model.NutritionInput) (*model.NutritionLog, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.NutritionLog{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.NutritionLog{}, err
+	}
+
+	// logging the same day again replaces it, anything left out is cleared
+	nutritionLog := database.NutritionLog{
+		UserID: u.ID,
+		Day:    toDay(nutrition.Day),
+	}
+	if nutrition.Calories != nil {
+		calories := uint(*nutrition.Calories)
+		nutritionLog.Calories = &calories
+	}
+	if nutrition.Protein != nil {
+		protein := float32(*nutrition.Protein)
+		nutritionLog.ProteinGrams = &protein
+	}
+	if nutrition.Notes != nil {
+		nutritionLog.Notes = *nutrition.Notes
+	}
+
+	err = database.UpsertNutritionLog(r.db(ctx), &nutritionLog)
+	if err != nil {
+		return &model.NutritionLog{}, errors.From(err, "Error Logging Nutrition")
+	}
+
+	return toNutritionLog(&nutritionLog), nil
+}
+
+// NutritionLogs is the resolver for the nutritionLogs field.
+func (r *queryResolver) NutritionLogs(ctx context.Context, rangeArg model.DateRangeInput) ([]*model.NutritionLog, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return []*model.NutritionLog{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return []*model.NutritionLog{}, err
+	}
+
+	start := toDay(rangeArg.Start)
+	end := toDay(rangeArg.End)
+	if !start.Before(end) {
+		return []*model.NutritionLog{}, errors.InvalidInput("Error Getting Nutrition Logs: range start needs to be before range end")
+	}
+	if end.Sub(start) > maxNutritionLogDays*24*time.Hour {
+		return []*model.NutritionLog{}, errors.InvalidInput("Error Getting Nutrition Logs: range can be at most %d days", maxNutritionLogDays)
+	}
+
+	dbNutritionLogs, err := database.GetNutritionLogs(r.db(ctx), utils.UIntToString(u.ID), start, end)
+	if err != nil {
+		return []*model.NutritionLog{}, errors.From(err, "Error Getting Nutrition Logs")
+	}
+
+	nutritionLogs := make([]*model.NutritionLog, 0)
+	for i := range dbNutritionLogs {
+		nutritionLogs = append(nutritionLogs, toNutritionLog(&dbNutritionLogs[i]))
+	}
+	return nutritionLogs, nil
+}
+
+// toDay keeps the calendar date the client sent in its own offset, so local
+// midnight doesn't land on the previous day in UTC
+func toDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func toNutritionLog(nutritionLog *database.NutritionLog) *model.NutritionLog {
+	var calories *int
+	if nutritionLog.Calories != nil {
+		c := int(*nutritionLog.Calories)
+		calories = &c
+	}
+	var protein *float64
+	if nutritionLog.ProteinGrams != nil {
+		p := float64(*nutritionLog.ProteinGrams)
+		protein = &p
+	}
+	return &model.NutritionLog{
+		ID:       utils.UIntToString(nutritionLog.ID),
+		Day:      nutritionLog.Day,
+		Calories: calories,
+		Protein:  protein,
+		Notes:    nutritionLog.Notes,
+	}
+}
@@ -0,0 +1,127 @@
+package graph
+
+import (
+	"context"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/format"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/utils"
+)
+
+// UpdatePreferences is the resolver for the updatePreferences field.
+func (r *mutationResolver) UpdatePreferences(ctx context.Context, preferences model.PreferencesInput) (*model.Preferences, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.Preferences{}, err
+	}
+
+	userId := utils.UIntToString(u.ID)
+	err = middleware.VerifyUser(r.db(ctx), userId)
+	if err != nil {
+		return &model.Preferences{}, err
+	}
+
+	if preferences.TimeZone != nil {
+		if _, err := time.LoadLocation(*preferences.TimeZone); err != nil || *preferences.TimeZone == "" || len(*preferences.TimeZone) > 64 {
+			return &model.Preferences{}, errors.InvalidInput("Error Updating Preferences: Unknown Time Zone %q", *preferences.TimeZone)
+		}
+	}
+
+	// week start and weight unit live on the user, the rest on their
+	// preferences row
+	update := database.User{}
+	if preferences.WeekStart != nil {
+		update.WeekStart = string(*preferences.WeekStart)
+	}
+	if preferences.WeightUnit != nil {
+		weightUnit := string(*preferences.WeightUnit)
+		update.WeightUnit = &weightUnit
+	}
+	if update.WeekStart != "" || update.WeightUnit != nil {
+		err = database.UpdateUserById(r.db(ctx), userId, &update)
+		if err != nil {
+			return &model.Preferences{}, errors.From(err, "Error Updating Preferences")
+		}
+	}
+
+	p, err := database.GetUserPreferences(r.db(ctx), u.ID)
+	if err != nil {
+		return &model.Preferences{}, errors.From(err, "Error Updating Preferences")
+	}
+	if preferences.TimeZone != nil || preferences.Notifications != nil {
+		if preferences.TimeZone != nil {
+			p.TimeZone = *preferences.TimeZone
+		}
+		if preferences.Notifications != nil {
+			applyNotificationPreferences(p, preferences.Notifications)
+		}
+		err = database.SaveUserPreferences(r.db(ctx), p)
+		if err != nil {
+			return &model.Preferences{}, errors.From(err, "Error Updating Preferences")
+		}
+	}
+
+	user, err := database.GetUserById(r.db(ctx), userId)
+	if err != nil {
+		return &model.Preferences{}, errors.From(err, "Error Updating Preferences")
+	}
+	return toPreferences(user, p), nil
+}
+
+// Preferences is the resolver for the preferences field.
+func (r *queryResolver) Preferences(ctx context.Context) (*model.Preferences, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.Preferences{}, err
+	}
+
+	userId := utils.UIntToString(u.ID)
+	err = middleware.VerifyUser(r.db(ctx), userId)
+	if err != nil {
+		return &model.Preferences{}, err
+	}
+
+	user, err := database.GetUserById(r.db(ctx), userId)
+	if err != nil {
+		return &model.Preferences{}, errors.From(err, "Error Getting Preferences")
+	}
+	p, err := database.GetUserPreferences(r.db(ctx), u.ID)
+	if err != nil {
+		return &model.Preferences{}, errors.From(err, "Error Getting Preferences")
+	}
+	return toPreferences(user, p), nil
+}
+
+// userLocation is the time zone asked for, or the user's preferred one when
+// none was. action starts the error when it's unknown
+func (r *Resolver) userLocation(ctx context.Context, userId uint, timeZone *string, action string) (*time.Location, error) {
+	name := ""
+	if timeZone != nil {
+		name = *timeZone
+	} else {
+		p, err := database.GetUserPreferences(r.db(ctx), userId)
+		if err != nil {
+			return nil, errors.From(err, "%s", action)
+		}
+		name = p.TimeZone
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, errors.InvalidInput("%s: Unknown Time Zone %q", action, name)
+	}
+	return loc, nil
+}
+
+func toPreferences(user *database.User, p *database.UserPreferences) *model.Preferences {
+	return &model.Preferences{
+		WeightUnit:    model.WeightUnit(format.NewHints(user.Locale, user.WeightUnit).WeightUnit),
+		WeekStart:     model.WeekStart(user.WeekStart),
+		TimeZone:      p.TimeZone,
+		Notifications: toNotificationPreferences(p),
+	}
+}
@@ -0,0 +1,254 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/clock"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/utils"
+	"gorm.io/gorm"
+)
+
+// CreateProgram is the resolver for the createProgram field.
+func (r *mutationResolver) CreateProgram(ctx context.Context, program model.ProgramInput) (*model.Program, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.Program{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.Program{}, err
+	}
+
+	days, err := r.toProgramDays(u.ID, program.Days)
+	if err != nil {
+		return &model.Program{}, errors.Forbidden("Error Creating Program: Access Denied")
+	}
+
+	dbProgram := database.Program{
+		UserID: u.ID,
+		Name:   program.Name,
+		Active: program.Active == nil || *program.Active,
+		Days:   days,
+	}
+	err = database.AddProgram(r.db(ctx), &dbProgram)
+	if err != nil {
+		return &model.Program{}, errors.From(err, "Error Creating Program")
+	}
+
+	// reload for the routines the days point at
+	created, err := database.GetProgram(r.db(ctx), utils.UIntToString(dbProgram.ID))
+	if err != nil {
+		return &model.Program{}, errors.From(err, "Error Creating Program")
+	}
+
+	return toProgram(created), nil
+}
+
+// UpdateProgram is the resolver for the updateProgram field.
+func (r *mutationResolver) UpdateProgram(ctx context.Context, programID string, program model.ProgramInput) (*model.Program, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.Program{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.Program{}, err
+	}
+
+	existing, err := database.GetProgram(r.db(ctx), programID)
+	if err != nil {
+		return &model.Program{}, errors.From(err, "Error Updating Program")
+	}
+	if existing.UserID != u.ID {
+		return &model.Program{}, errors.Forbidden("Error Updating Program: Access Denied")
+	}
+
+	days, err := r.toProgramDays(u.ID, program.Days)
+	if err != nil {
+		return &model.Program{}, errors.Forbidden("Error Updating Program: Access Denied")
+	}
+	for i := range days {
+		days[i].ProgramID = existing.ID
+	}
+
+	err = database.UpdateProgram(r.db(ctx), programID, &database.Program{
+		UserID: u.ID,
+		Name:   program.Name,
+		Active: program.Active == nil || *program.Active,
+		Days:   days,
+	})
+	if err != nil {
+		return &model.Program{}, errors.From(err, "Error Updating Program")
+	}
+
+	updated, err := database.GetProgram(r.db(ctx), programID)
+	if err != nil {
+		return &model.Program{}, errors.From(err, "Error Updating Program")
+	}
+
+	return toProgram(updated), nil
+}
+
+// DeleteProgram is the resolver for the deleteProgram field.
+func (r *mutationResolver) DeleteProgram(ctx context.Context, programID string) (int, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return 0, err
+	}
+
+	program, err := database.GetProgram(r.db(ctx), programID)
+	if err != nil {
+		return 0, errors.From(err, "Error Deleting Program")
+	}
+	if program.UserID != u.ID {
+		return 0, errors.Forbidden("Error Deleting Program: Access Denied")
+	}
+
+	err = database.DeleteProgram(r.db(ctx), programID)
+	if err != nil {
+		return 0, errors.From(err, "Error Deleting Program")
+	}
+
+	return 1, nil
+}
+
+// Programs is the resolver for the programs field.
+func (r *queryResolver) Programs(ctx context.Context) ([]*model.Program, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return []*model.Program{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return []*model.Program{}, err
+	}
+
+	dbPrograms, err := database.GetPrograms(r.db(ctx), utils.UIntToString(u.ID))
+	if err != nil {
+		return []*model.Program{}, errors.From(err, "Error Getting Programs")
+	}
+
+	programs := make([]*model.Program, 0)
+	for i := range dbPrograms {
+		programs = append(programs, toProgram(&dbPrograms[i]))
+	}
+	return programs, nil
+}
+
+// TodaysWorkout is the resolver for the todaysWorkout field.
+func (r *queryResolver) TodaysWorkout(ctx context.Context, timeZone *string) (*model.TodaysWorkout, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	loc, err := r.userLocation(ctx, u.ID, timeZone, "Error Getting Today's Workout")
+	if err != nil {
+		return nil, err
+	}
+	now := clock.Now().In(loc)
+	weekday := strings.ToUpper(now.Weekday().String())
+
+	program, err := database.GetActiveProgram(r.db(ctx), utils.UIntToString(u.ID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.From(err, "Error Getting Today's Workout")
+	}
+
+	// a day without a routine, or whose routine was deleted, is a rest day
+	for _, d := range program.Days {
+		if d.Weekday != weekday || d.WorkoutRoutine.ID == 0 {
+			continue
+		}
+		return &model.TodaysWorkout{
+			Day:            time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc),
+			Weekday:        model.Weekday(weekday),
+			Program:        toProgram(program),
+			WorkoutRoutine: *toProgramWorkoutRoutine(&d.WorkoutRoutine),
+		}, nil
+	}
+	return nil, nil
+}
+
+// PrevExercises is the resolver for the prevExercises field.
+func (r *todaysWorkoutResolver) PrevExercises(ctx context.Context, obj *model.TodaysWorkout) ([]*model.Exercise, error) {
+	// clients on a bad connection start without last time's numbers
+	if middleware.IsLowBandwidth(ctx) {
+		return []*model.Exercise{}, nil
+	}
+
+	return loadPrevExercises(ctx, obj.WorkoutRoutine.ID, obj.Day)
+}
+
+// toProgramDays checks the user can schedule each routine, every routine is
+// only checked once however many days it's on
+func (r *mutationResolver) toProgramDays(userId uint, days []*model.ProgramDayInput) ([]database.ProgramDay, error) {
+	checked := make(map[string]bool)
+	programDays := make([]database.ProgramDay, 0, len(days))
+	for _, d := range days {
+		if !checked[d.WorkoutRoutineID] {
+			if err := r.ACS.IsWorkoutRoutineOwner(utils.UIntToString(userId), d.WorkoutRoutineID); err != nil {
+				return nil, err
+			}
+			checked[d.WorkoutRoutineID] = true
+		}
+		programDays = append(programDays, database.ProgramDay{
+			Weekday:          d.Weekday.String(),
+			WorkoutRoutineID: utils.StringToUInt(d.WorkoutRoutineID),
+		})
+	}
+	return programDays, nil
+}
+
+func toProgram(program *database.Program) *model.Program {
+	days := make([]*model.ProgramDay, 0)
+	for i := range program.Days {
+		d := &program.Days[i]
+		// deleted routines drop off the schedule
+		if d.WorkoutRoutine.ID == 0 {
+			continue
+		}
+		days = append(days, &model.ProgramDay{
+			Weekday:        model.Weekday(d.Weekday),
+			WorkoutRoutine: toProgramWorkoutRoutine(&d.WorkoutRoutine),
+		})
+	}
+	return &model.Program{
+		ID:     utils.UIntToString(program.ID),
+		Name:   program.Name,
+		Active: program.Active,
+		Days:   days,
+	}
+}
+
+// exercise routines are left to the workout routine resolver
+func toProgramWorkoutRoutine(wr *database.WorkoutRoutine) *model.WorkoutRoutine {
+	return &model.WorkoutRoutine{
+		ID:        utils.UIntToString(wr.ID),
+		Name:      wr.Name,
+		Active:    wr.Active,
+		UpdatedAt: wr.UpdatedAt,
+	}
+}
@@ -0,0 +1,208 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/clock"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/utils"
+)
+
+// photo types phones take, keyed to the extension the file is stored with
+var progressPhotoExtensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/heic": ".heic",
+	"image/webp": ".webp",
+}
+
+// CreateProgressPhotoUpload is the resolver for the createProgressPhotoUpload field.
+func (r *mutationResolver) CreateProgressPhotoUpload(ctx context.Context, photo model.ProgressPhotoInput) (*model.ProgressPhotoUpload, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.ProgressPhotoUpload{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.ProgressPhotoUpload{}, err
+	}
+
+	ext, ok := progressPhotoExtensions[photo.ContentType]
+	if !ok {
+		return &model.ProgressPhotoUpload{}, errors.InvalidInput("Error Creating Photo Upload: Photo Needs To Be A JPEG, PNG, HEIC Or WebP")
+	}
+
+	// no takenOn means it was taken today
+	takenOn := clock.Now().UTC()
+	if photo.TakenOn != nil {
+		takenOn = photo.TakenOn.UTC()
+	}
+	takenOn = time.Date(takenOn.Year(), takenOn.Month(), takenOn.Day(), 0, 0, 0, 0, time.UTC)
+
+	code, err := utils.GenerateVerificationCode(16)
+	if err != nil {
+		return &model.ProgressPhotoUpload{}, errors.From(err, "Error Creating Photo Upload")
+	}
+	// keys can't be guessed, the signed urls are what keep photos private
+	// but there's no reason to make them easy to enumerate
+	key := fmt.Sprintf("photos/%d/%s%s", u.ID, strings.TrimRight(code, "="), ext)
+
+	dbPhoto := database.ProgressPhoto{
+		UserID:      u.ID,
+		StorageKey:  key,
+		ContentType: photo.ContentType,
+		TakenOn:     takenOn,
+	}
+	if photo.BodyWeight != nil {
+		bodyWeight := float32(*photo.BodyWeight)
+		dbPhoto.BodyWeight = &bodyWeight
+	}
+
+	expiresAt := clock.Now().Add(config.PHOTO_URL_TTL)
+	uploadURL, err := r.Photos.SignedUploadURL(key, photo.ContentType, config.MAX_PHOTO_BYTES, expiresAt)
+	if err != nil {
+		return &model.ProgressPhotoUpload{}, errors.From(err, "Error Creating Photo Upload")
+	}
+
+	err = database.AddProgressPhoto(r.db(ctx), &dbPhoto)
+	if err != nil {
+		return &model.ProgressPhotoUpload{}, errors.From(err, "Error Creating Photo Upload")
+	}
+
+	return &model.ProgressPhotoUpload{
+		ProgressPhotoID: utils.UIntToString(dbPhoto.ID),
+		UploadURL:       uploadURL,
+		ContentType:     photo.ContentType,
+		MaxBytes:        config.MAX_PHOTO_BYTES,
+		ExpiresAt:       expiresAt,
+	}, nil
+}
+
+// CompleteProgressPhotoUpload is the resolver for the completeProgressPhotoUpload field.
+func (r *mutationResolver) CompleteProgressPhotoUpload(ctx context.Context, progressPhotoID string) (*model.ProgressPhoto, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.ProgressPhoto{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.ProgressPhoto{}, err
+	}
+
+	photo, err := database.GetProgressPhoto(r.db(ctx), progressPhotoID)
+	if err != nil {
+		return &model.ProgressPhoto{}, errors.From(err, "Error Completing Photo Upload")
+	}
+	// photos are only ever the owner's, coaches don't see them either
+	if photo.UserID != u.ID {
+		return &model.ProgressPhoto{}, errors.Forbidden("Error Completing Photo Upload: Access Denied")
+	}
+
+	if photo.UploadedAt == nil {
+		file, err := r.Photos.Open(ctx, photo.StorageKey)
+		if err != nil {
+			return &model.ProgressPhoto{}, errors.InvalidInput("Error Completing Photo Upload: Photo Hasn't Been Uploaded")
+		}
+		file.Close()
+
+		err = database.MarkProgressPhotoUploaded(r.db(ctx), photo, clock.Now())
+		if err != nil {
+			return &model.ProgressPhoto{}, errors.From(err, "Error Completing Photo Upload")
+		}
+	}
+
+	return r.toProgressPhoto(photo)
+}
+
+// DeleteProgressPhoto is the resolver for the deleteProgressPhoto field.
+func (r *mutationResolver) DeleteProgressPhoto(ctx context.Context, progressPhotoID string) (int, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return 0, err
+	}
+
+	photo, err := database.GetProgressPhoto(r.db(ctx), progressPhotoID)
+	if err != nil {
+		return 0, errors.From(err, "Error Deleting Photo")
+	}
+	if photo.UserID != u.ID {
+		return 0, errors.Forbidden("Error Deleting Photo: Access Denied")
+	}
+
+	err = database.DeleteProgressPhoto(r.db(ctx), photo.ID)
+	if err != nil {
+		return 0, errors.From(err, "Error Deleting Photo")
+	}
+
+	// a photo that was never uploaded has no file to remove, failing to
+	// remove one that was is only logged since the row is already gone
+	if err := r.Photos.Delete(ctx, photo.StorageKey); err != nil && photo.UploadedAt != nil {
+		log.Printf("error deleting photo %s for user %d: %v", photo.StorageKey, u.ID, err)
+	}
+	return 1, nil
+}
+
+// ProgressPhotos is the resolver for the progressPhotos field.
+func (r *queryResolver) ProgressPhotos(ctx context.Context) ([]*model.ProgressPhoto, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	photos, err := database.GetProgressPhotos(r.db(ctx), utils.UIntToString(u.ID))
+	if err != nil {
+		return nil, errors.From(err, "Error Getting Photos")
+	}
+
+	progressPhotos := make([]*model.ProgressPhoto, 0, len(photos))
+	for i := range photos {
+		photo, err := r.toProgressPhoto(&photos[i])
+		if err != nil {
+			return nil, err
+		}
+		progressPhotos = append(progressPhotos, photo)
+	}
+	return progressPhotos, nil
+}
+
+// toProgressPhoto signs a fresh download url for the photo
+func (r *Resolver) toProgressPhoto(photo *database.ProgressPhoto) (*model.ProgressPhoto, error) {
+	expiresAt := clock.Now().Add(config.PHOTO_URL_TTL)
+	url, err := r.Photos.SignedDownloadURL(photo.StorageKey, expiresAt)
+	if err != nil {
+		return &model.ProgressPhoto{}, errors.From(err, "Error Getting Photo")
+	}
+
+	progressPhoto := model.ProgressPhoto{
+		ID:          utils.UIntToString(photo.ID),
+		TakenOn:     photo.TakenOn,
+		ContentType: photo.ContentType,
+		URL:         url,
+		ExpiresAt:   expiresAt,
+	}
+	if photo.BodyWeight != nil {
+		bodyWeight := float64(*photo.BodyWeight)
+		progressPhoto.BodyWeight = &bodyWeight
+	}
+	return &progressPhoto, nil
+}
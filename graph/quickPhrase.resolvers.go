@@ -0,0 +1,190 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/utils"
+	"github.com/neilZon/workout-logger-api/validator"
+)
+
+// AddQuickPhrase is the resolver for the addQuickPhrase field.
+func (r *mutationResolver) AddQuickPhrase(ctx context.Context, text string) (*model.QuickPhrase, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.QuickPhrase{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.QuickPhrase{}, err
+	}
+
+	if err := validator.QuickPhraseIsValid(text); err != nil {
+		return &model.QuickPhrase{}, errors.From(err, "Error Adding Quick Phrase: %s", err.Error())
+	}
+
+	count, err := database.CountQuickPhrases(r.db(ctx), utils.UIntToString(u.ID))
+	if err != nil {
+		return &model.QuickPhrase{}, errors.From(err, "Error Adding Quick Phrase")
+	}
+	if count >= config.MAX_QUICK_PHRASES {
+		return &model.QuickPhrase{}, errors.InvalidInput("Error Adding Quick Phrase: at most %d quick phrases", config.MAX_QUICK_PHRASES)
+	}
+
+	quickPhrase := database.QuickPhrase{
+		UserID: u.ID,
+		Text:   text,
+	}
+	err = database.AddQuickPhrase(r.db(ctx), &quickPhrase)
+	if err != nil {
+		return &model.QuickPhrase{}, errors.From(err, "Error Adding Quick Phrase")
+	}
+
+	return toQuickPhrase(&quickPhrase), nil
+}
+
+// UpdateQuickPhrase is the resolver for the updateQuickPhrase field.
+func (r *mutationResolver) UpdateQuickPhrase(ctx context.Context, quickPhraseID string, text string) (*model.QuickPhrase, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.QuickPhrase{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.QuickPhrase{}, err
+	}
+
+	if err := validator.QuickPhraseIsValid(text); err != nil {
+		return &model.QuickPhrase{}, errors.From(err, "Error Updating Quick Phrase: %s", err.Error())
+	}
+
+	quickPhrase, err := database.GetQuickPhrase(r.db(ctx), quickPhraseID)
+	if err != nil {
+		return &model.QuickPhrase{}, errors.From(err, "Error Updating Quick Phrase")
+	}
+	if quickPhrase.UserID != u.ID {
+		return &model.QuickPhrase{}, errors.Forbidden("Error Updating Quick Phrase: Access Denied")
+	}
+
+	updatedQuickPhrase, err := database.UpdateQuickPhrase(r.db(ctx), quickPhraseID, text)
+	if err != nil {
+		return &model.QuickPhrase{}, errors.From(err, "Error Updating Quick Phrase")
+	}
+
+	return toQuickPhrase(updatedQuickPhrase), nil
+}
+
+// DeleteQuickPhrase is the resolver for the deleteQuickPhrase field.
+func (r *mutationResolver) DeleteQuickPhrase(ctx context.Context, quickPhraseID string) (int, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return 0, err
+	}
+
+	quickPhrase, err := database.GetQuickPhrase(r.db(ctx), quickPhraseID)
+	if err != nil {
+		return 0, errors.From(err, "Error Deleting Quick Phrase")
+	}
+	if quickPhrase.UserID != u.ID {
+		return 0, errors.Forbidden("Error Deleting Quick Phrase: Access Denied")
+	}
+
+	err = database.DeleteQuickPhrase(r.db(ctx), quickPhraseID)
+	if err != nil {
+		return 0, errors.From(err, "Error Deleting Quick Phrase")
+	}
+
+	return 1, nil
+}
+
+// ReorderQuickPhrases is the resolver for the reorderQuickPhrases field.
+func (r *mutationResolver) ReorderQuickPhrases(ctx context.Context, orderedIds []string) ([]*model.QuickPhrase, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return []*model.QuickPhrase{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return []*model.QuickPhrase{}, err
+	}
+
+	userId := utils.UIntToString(u.ID)
+	dbQuickPhrases, err := database.GetQuickPhrases(r.db(ctx), userId)
+	if err != nil {
+		return []*model.QuickPhrase{}, errors.From(err, "Error Reordering Quick Phrases")
+	}
+
+	// the new order has to name every quick phrase exactly once
+	byId := make(map[string]database.QuickPhrase, len(dbQuickPhrases))
+	for _, qp := range dbQuickPhrases {
+		byId[utils.UIntToString(qp.ID)] = qp
+	}
+	if len(orderedIds) != len(byId) {
+		return []*model.QuickPhrase{}, errors.InvalidInput("Error Reordering Quick Phrases: Order Must Include Every Quick Phrase")
+	}
+	seen := make(map[string]bool, len(orderedIds))
+	for _, id := range orderedIds {
+		if _, ok := byId[id]; !ok || seen[id] {
+			return []*model.QuickPhrase{}, errors.InvalidInput("Error Reordering Quick Phrases: Order Must Include Every Quick Phrase")
+		}
+		seen[id] = true
+	}
+
+	err = database.ReorderQuickPhrases(r.db(ctx), userId, orderedIds)
+	if err != nil {
+		return []*model.QuickPhrase{}, errors.From(err, "Error Reordering Quick Phrases")
+	}
+
+	quickPhrases := make([]*model.QuickPhrase, 0)
+	for i, id := range orderedIds {
+		qp := byId[id]
+		qp.Position = uint(i)
+		quickPhrases = append(quickPhrases, toQuickPhrase(&qp))
+	}
+	return quickPhrases, nil
+}
+
+// QuickPhrases is the resolver for the quickPhrases field.
+func (r *queryResolver) QuickPhrases(ctx context.Context) ([]*model.QuickPhrase, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return []*model.QuickPhrase{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return []*model.QuickPhrase{}, err
+	}
+
+	dbQuickPhrases, err := database.GetQuickPhrases(r.db(ctx), utils.UIntToString(u.ID))
+	if err != nil {
+		return []*model.QuickPhrase{}, errors.From(err, "Error Getting Quick Phrases")
+	}
+
+	quickPhrases := make([]*model.QuickPhrase, 0)
+	for i := range dbQuickPhrases {
+		quickPhrases = append(quickPhrases, toQuickPhrase(&dbQuickPhrases[i]))
+	}
+	return quickPhrases, nil
+}
+
+func toQuickPhrase(quickPhrase *database.QuickPhrase) *model.QuickPhrase {
+	return &model.QuickPhrase{
+		ID:       utils.UIntToString(quickPhrase.ID),
+		Text:     quickPhrase.Text,
+		Position: int(quickPhrase.Position),
+	}
+}
@@ -0,0 +1,71 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/clock"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/utils"
+)
+
+// StartRequestRecording is the resolver for the startRequestRecording field.
+func (r *mutationResolver) StartRequestRecording(ctx context.Context, minutes int) (*time.Time, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	if minutes <= 0 || minutes > config.MAX_RECORDING_MINUTES {
+		return nil, errors.InvalidInput("minutes needs to be between 1 and %d", config.MAX_RECORDING_MINUTES)
+	}
+
+	until := clock.Now().Add(time.Duration(minutes) * time.Minute)
+	err = database.SetRecordRequestsUntil(r.db(ctx), utils.UIntToString(u.ID), until)
+	if err != nil {
+		return nil, errors.From(err, "Error Starting Request Recording")
+	}
+
+	return &until, nil
+}
+
+// RequestRecordings is the resolver for the requestRecordings field.
+func (r *queryResolver) RequestRecordings(ctx context.Context, userID string, limit int, after *string) ([]*model.RequestRecording, error) {
+	if limit <= 0 || limit > 100 {
+		return []*model.RequestRecording{}, errors.InvalidInput("limit needs to be between 1 to 100")
+	}
+
+	cursor := ""
+	if after != nil && *after != "" {
+		cursor = *after
+	}
+
+	dbRecordings, err := database.GetRequestRecordings(r.db(ctx), userID, cursor, limit)
+	if err != nil {
+		return []*model.RequestRecording{}, errors.From(err, "Error Getting Request Recordings")
+	}
+
+	recordings := make([]*model.RequestRecording, 0)
+	for _, rec := range dbRecordings {
+		recordings = append(recordings, &model.RequestRecording{
+			ID:            utils.UIntToString(rec.ID),
+			OperationName: rec.OperationName,
+			Query:         rec.Query,
+			Variables:     rec.Variables,
+			DurationMs:    int(rec.DurationMs),
+			RecordedAt:    rec.CreatedAt,
+		})
+	}
+
+	return recordings, nil
+}
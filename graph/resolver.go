@@ -1,7 +1,15 @@
 package graph
 
 import (
+	"context"
+
 	"github.com/neilZon/workout-logger-api/accesscontroller"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/integration"
+	"github.com/neilZon/workout-logger-api/live"
+	"github.com/neilZon/workout-logger-api/mail"
+	"github.com/neilZon/workout-logger-api/media"
+	"github.com/neilZon/workout-logger-api/responsecache"
 	"gorm.io/gorm"
 )
 
@@ -10,6 +18,24 @@ import (
 // It serves as dependency injection for your app, add any dependencies you require here.
 
 type Resolver struct {
-	DB  *gorm.DB
-	ACS accesscontroller.AccessControllerService
+	DB   *gorm.DB
+	ACS  accesscontroller.AccessControllerService
+	Live *live.Broker
+	// form check videos, only handed out through signed urls
+	Media media.SignedStore
+	// cold storage for archived workout sessions, never publicly served
+	Archive media.Store
+	// progress photos, only handed out through signed urls
+	Photos media.SignedStore
+	Strava *integration.Strava
+	// workoutRoutines and exerciseRoutines results, nil doesn't cache
+	Cache *responsecache.Cache
+	// password reset links go out through it
+	Mailer mail.Mailer
+}
+
+// db is the database for a resolver, its statements are counted towards the
+// request's query stats
+func (r *Resolver) db(ctx context.Context) *gorm.DB {
+	return r.DB.WithContext(database.QueryStatsContext(ctx))
 }
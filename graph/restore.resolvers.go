@@ -0,0 +1,155 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/clock"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/responsecache"
+	"github.com/neilZon/workout-logger-api/utils"
+	"gorm.io/gorm"
+)
+
+// RestoreWorkoutRoutine is the resolver for the restoreWorkoutRoutine field.
+func (r *mutationResolver) RestoreWorkoutRoutine(ctx context.Context, workoutRoutineID string) (*model.WorkoutRoutine, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.WorkoutRoutine{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.WorkoutRoutine{}, err
+	}
+
+	wr, err := database.GetDeletedWorkoutRoutine(r.db(ctx), workoutRoutineID)
+	if err != nil {
+		return &model.WorkoutRoutine{}, errors.From(err, "Error Restoring Workout Routine")
+	}
+	if wr.UserID != u.ID {
+		return &model.WorkoutRoutine{}, errors.Forbidden("Error Restoring Workout Routine: Access Denied")
+	}
+	if !canRestore(wr.DeletedAt.Time) {
+		return &model.WorkoutRoutine{}, errors.InvalidInput("Error Restoring Workout Routine: Grace Period Expired")
+	}
+
+	err = database.RestoreWorkoutRoutine(r.db(ctx), workoutRoutineID, wr.DeletedAt.Time)
+	if errors.Is(err, database.ErrOpenWorkoutSession) {
+		return &model.WorkoutRoutine{}, errors.InvalidInput("Error Restoring Workout Routine: Finish The Current Workout Session First")
+	}
+	if err != nil {
+		return &model.WorkoutRoutine{}, errors.From(err, "Error Restoring Workout Routine")
+	}
+	r.Cache.Invalidate(responsecache.WorkoutRoutineScope(workoutRoutineID))
+
+	return &model.WorkoutRoutine{
+		ID:        utils.UIntToString(wr.ID),
+		Name:      wr.Name,
+		Active:    wr.Active,
+		UpdatedAt: wr.UpdatedAt,
+	}, nil
+}
+
+// RestoreExerciseRoutine is the resolver for the restoreExerciseRoutine field.
+func (r *mutationResolver) RestoreExerciseRoutine(ctx context.Context, exerciseRoutineID string) (*model.ExerciseRoutine, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.ExerciseRoutine{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.ExerciseRoutine{}, err
+	}
+
+	er, err := database.GetDeletedExerciseRoutine(r.db(ctx), exerciseRoutineID)
+	if err != nil {
+		return &model.ExerciseRoutine{}, errors.From(err, "Error Restoring Exercise Routine")
+	}
+
+	err = r.ACS.CanAccessWorkoutRoutine(utils.UIntToString(u.ID), utils.UIntToString(er.WorkoutRoutineID))
+	if err != nil {
+		return &model.ExerciseRoutine{}, errors.Forbidden("Error Restoring Exercise Routine: Access Denied")
+	}
+	if !canRestore(er.DeletedAt.Time) {
+		return &model.ExerciseRoutine{}, errors.InvalidInput("Error Restoring Exercise Routine: Grace Period Expired")
+	}
+
+	// the access check can be answered from the cache, the restore itself
+	// checks the workout routine isn't still deleted
+	err = database.RestoreExerciseRoutine(r.db(ctx), exerciseRoutineID, er.DeletedAt.Time)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &model.ExerciseRoutine{}, errors.NotFound("Error Restoring Exercise Routine: Restore Its Workout Routine First")
+	}
+	if err != nil {
+		return &model.ExerciseRoutine{}, errors.From(err, "Error Restoring Exercise Routine")
+	}
+	r.Cache.Invalidate(responsecache.WorkoutRoutineScope(utils.UIntToString(er.WorkoutRoutineID)))
+
+	return &model.ExerciseRoutine{
+		ID:     utils.UIntToString(er.ID),
+		Active: er.Active,
+		Name:   er.Name,
+		Sets:   int(er.Sets),
+		Reps:   int(er.Reps),
+	}, nil
+}
+
+// RestoreWorkoutSession is the resolver for the restoreWorkoutSession field.
+func (r *mutationResolver) RestoreWorkoutSession(ctx context.Context, workoutSessionID string) (*model.WorkoutSession, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.WorkoutSession{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.WorkoutSession{}, err
+	}
+
+	ws, err := database.GetDeletedWorkoutSession(r.db(ctx), workoutSessionID)
+	if err != nil {
+		return &model.WorkoutSession{}, errors.From(err, "Error Restoring Workout Session")
+	}
+
+	err = r.ACS.CanAccessWorkoutRoutine(utils.UIntToString(u.ID), utils.UIntToString(ws.WorkoutRoutineID))
+	if err != nil || ws.UserID != u.ID {
+		return &model.WorkoutSession{}, errors.Forbidden("Error Restoring Workout Session: Access Denied")
+	}
+	if !canRestore(ws.DeletedAt.Time) {
+		return &model.WorkoutSession{}, errors.InvalidInput("Error Restoring Workout Session: Grace Period Expired")
+	}
+
+	// the access check can be answered from the cache, the restore itself
+	// checks the workout routine isn't still deleted
+	err = database.RestoreWorkoutSession(r.db(ctx), workoutSessionID, ws.DeletedAt.Time)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &model.WorkoutSession{}, errors.NotFound("Error Restoring Workout Session: Restore Its Workout Routine First")
+	}
+	if errors.Is(err, database.ErrOpenWorkoutSession) {
+		return &model.WorkoutSession{}, errors.InvalidInput("Error Restoring Workout Session: Finish The Current Workout Session First")
+	}
+	if err != nil {
+		return &model.WorkoutSession{}, errors.From(err, "Error Restoring Workout Session")
+	}
+
+	return &model.WorkoutSession{
+		ID:             utils.UIntToString(ws.ID),
+		Start:          ws.Start,
+		End:            ws.End,
+		ClientMetadata: ws.ClientMetadata,
+		Origin:         toClientOrigin(ws.Origin),
+		Notes:          ws.Notes,
+		UpdatedAt:      ws.UpdatedAt,
+	}, nil
+}
+
+func canRestore(deletedAt time.Time) bool {
+	return clock.Since(deletedAt) <= config.RESTORE_GRACE_PERIOD*time.Hour
+}
@@ -0,0 +1,139 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/utils"
+	"gorm.io/gorm"
+)
+
+// ShareWorkoutRoutine is the resolver for the shareWorkoutRoutine field.
+func (r *mutationResolver) ShareWorkoutRoutine(ctx context.Context, workoutRoutineID string) (*model.WorkoutRoutineShareLink, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.WorkoutRoutineShareLink{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.WorkoutRoutineShareLink{}, err
+	}
+
+	err = r.ACS.IsWorkoutRoutineOwner(utils.UIntToString(u.ID), workoutRoutineID)
+	if err != nil {
+		return &model.WorkoutRoutineShareLink{}, errors.Forbidden("Error Sharing Workout Routine: Access Denied")
+	}
+
+	// sharing again hands back the same link so ones already sent keep working
+	link, err := database.GetWorkoutRoutineShareLinkByRoutine(r.db(ctx), workoutRoutineID)
+	if err == nil {
+		return toWorkoutRoutineShareLink(link), nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return &model.WorkoutRoutineShareLink{}, errors.From(err, "Error Sharing Workout Routine")
+	}
+
+	workoutRoutine, err := database.GetWorkoutRoutine(r.db(ctx), workoutRoutineID)
+	if err != nil {
+		return &model.WorkoutRoutineShareLink{}, errors.From(err, "Error Sharing Workout Routine")
+	}
+
+	token, err := utils.GenerateVerificationCode(32)
+	if err != nil {
+		return &model.WorkoutRoutineShareLink{}, errors.From(err, "Error Sharing Workout Routine")
+	}
+
+	link = &database.WorkoutRoutineShareLink{
+		Token:            token,
+		WorkoutRoutineID: workoutRoutine.ID,
+		UserID:           u.ID,
+	}
+	err = database.AddWorkoutRoutineShareLink(r.db(ctx), link)
+	if err != nil {
+		return &model.WorkoutRoutineShareLink{}, errors.From(err, "Error Sharing Workout Routine")
+	}
+
+	return toWorkoutRoutineShareLink(link), nil
+}
+
+// ImportSharedRoutine is the resolver for the importSharedRoutine field.
+func (r *mutationResolver) ImportSharedRoutine(ctx context.Context, token string) (*model.WorkoutRoutine, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.WorkoutRoutine{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.WorkoutRoutine{}, err
+	}
+
+	link, err := getSharedWorkoutRoutine(r.db(ctx), token)
+	if err != nil {
+		return &model.WorkoutRoutine{}, errors.NotFound("Error Importing Shared Routine: Invalid Share Link")
+	}
+
+	workoutRoutine, err := database.CopyWorkoutRoutine(r.db(ctx), &link.WorkoutRoutine, u.ID)
+	if err != nil {
+		return &model.WorkoutRoutine{}, errors.From(err, "Error Importing Shared Routine")
+	}
+
+	return &model.WorkoutRoutine{
+		ID:               utils.UIntToString(workoutRoutine.ID),
+		Name:             workoutRoutine.Name,
+		Active:           workoutRoutine.Active,
+		ExerciseRoutines: []*model.ExerciseRoutine{},
+		UpdatedAt:        workoutRoutine.UpdatedAt,
+	}, nil
+}
+
+// SharedWorkoutRoutine is the resolver for the sharedWorkoutRoutine field.
+func (r *queryResolver) SharedWorkoutRoutine(ctx context.Context, token string) (*model.SharedWorkoutRoutine, error) {
+	// no account needed, the share token is the access check
+	link, err := getSharedWorkoutRoutine(r.db(ctx), token)
+	if err != nil {
+		return &model.SharedWorkoutRoutine{}, errors.NotFound("Error Getting Shared Workout Routine: Invalid Share Link")
+	}
+
+	exerciseRoutines := make([]*model.SharedExerciseRoutine, 0, len(link.WorkoutRoutine.ExerciseRoutines))
+	for _, er := range link.WorkoutRoutine.ExerciseRoutines {
+		sharedExerciseRoutine := &model.SharedExerciseRoutine{
+			Name: er.Name,
+			Sets: int(er.Sets),
+			Reps: int(er.Reps),
+		}
+		if er.CatalogExercise != nil {
+			sharedExerciseRoutine.CatalogExercise = toCatalogExercise(er.CatalogExercise)
+		}
+		exerciseRoutines = append(exerciseRoutines, sharedExerciseRoutine)
+	}
+
+	return &model.SharedWorkoutRoutine{
+		Name:             link.WorkoutRoutine.Name,
+		ExerciseRoutines: exerciseRoutines,
+	}, nil
+}
+
+// links stop working while their routine is deleted
+func getSharedWorkoutRoutine(db *gorm.DB, token string) (*database.WorkoutRoutineShareLink, error) {
+	link, err := database.GetWorkoutRoutineShareLink(db, token)
+	if err != nil {
+		return nil, err
+	}
+	if link.WorkoutRoutine.ID == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return link, nil
+}
+
+func toWorkoutRoutineShareLink(link *database.WorkoutRoutineShareLink) *model.WorkoutRoutineShareLink {
+	return &model.WorkoutRoutineShareLink{
+		Token:            link.Token,
+		WorkoutRoutineID: utils.UIntToString(link.WorkoutRoutineID),
+	}
+}
@@ -0,0 +1,25 @@
+package graph
+
+import (
+	"context"
+	"os"
+
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+)
+
+// ResetSandbox is the resolver for the resetSandbox field.
+func (r *mutationResolver) ResetSandbox(ctx context.Context) (bool, error) {
+	// never let this anywhere near real data
+	if os.Getenv(config.SANDBOX) != "true" {
+		return false, errors.Forbidden("Error Resetting Sandbox: Server Is Not In Sandbox Mode")
+	}
+
+	err := database.ResetSandbox(r.db(ctx))
+	if err != nil {
+		return false, errors.From(err, "Error Resetting Sandbox")
+	}
+
+	return true, nil
+}
@@ -10,12 +10,26 @@ import (
 // Exercise returns generated.ExerciseResolver implementation.
 func (r *Resolver) Exercise() generated.ExerciseResolver { return &exerciseResolver{r} }
 
+// ExerciseRoutine returns generated.ExerciseRoutineResolver implementation.
+func (r *Resolver) ExerciseRoutine() generated.ExerciseRoutineResolver {
+	return &exerciseRoutineResolver{r}
+}
+
 // Mutation returns generated.MutationResolver implementation.
 func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
 
 // Query returns generated.QueryResolver implementation.
 func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
 
+// Subscription returns generated.SubscriptionResolver implementation.
+func (r *Resolver) Subscription() generated.SubscriptionResolver { return &subscriptionResolver{r} }
+
+// TodaysWorkout returns generated.TodaysWorkoutResolver implementation.
+func (r *Resolver) TodaysWorkout() generated.TodaysWorkoutResolver { return &todaysWorkoutResolver{r} }
+
+// User returns generated.UserResolver implementation.
+func (r *Resolver) User() generated.UserResolver { return &userResolver{r} }
+
 // WorkoutRoutine returns generated.WorkoutRoutineResolver implementation.
 func (r *Resolver) WorkoutRoutine() generated.WorkoutRoutineResolver {
 	return &workoutRoutineResolver{r}
@@ -26,8 +40,16 @@ func (r *Resolver) WorkoutSession() generated.WorkoutSessionResolver {
 	return &workoutSessionResolver{r}
 }
 
+// WorkoutStats returns generated.WorkoutStatsResolver implementation.
+func (r *Resolver) WorkoutStats() generated.WorkoutStatsResolver { return &workoutStatsResolver{r} }
+
 type exerciseResolver struct{ *Resolver }
+type exerciseRoutineResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type subscriptionResolver struct{ *Resolver }
+type todaysWorkoutResolver struct{ *Resolver }
+type userResolver struct{ *Resolver }
 type workoutRoutineResolver struct{ *Resolver }
 type workoutSessionResolver struct{ *Resolver }
+type workoutStatsResolver struct{ *Resolver }
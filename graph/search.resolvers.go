@@ -0,0 +1,94 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/utils"
+)
+
+// Search is the resolver for the search field.
+func (r *queryResolver) Search(ctx context.Context, query string, limit *int) ([]model.SearchResult, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return []model.SearchResult{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return []model.SearchResult{}, err
+	}
+
+	query = strings.TrimSpace(query)
+	if query == "" || utf8.RuneCountInString(query) > 64 {
+		return []model.SearchResult{}, errors.InvalidInput("Error Searching: query must have between 1 and 64 characters")
+	}
+	n := 20
+	if limit != nil {
+		n = *limit
+	}
+	if n < 1 || n > config.MAX_SEARCH_RESULTS {
+		return []model.SearchResult{}, errors.InvalidInput("Error Searching: limit must be between 1 and %d", config.MAX_SEARCH_RESULTS)
+	}
+
+	hits, err := database.Search(r.db(ctx), utils.UIntToString(u.ID), query, n)
+	if err != nil {
+		return []model.SearchResult{}, errors.From(err, "Error Searching")
+	}
+	rows, err := database.GetSearchRows(r.db(ctx), hits)
+	if err != nil {
+		return []model.SearchResult{}, errors.From(err, "Error Searching")
+	}
+
+	return toSearchResults(hits, rows), nil
+}
+
+// toSearchResults keeps the order of the hits, dropping any whose row went
+// away between the two queries
+func toSearchResults(hits []database.SearchHit, rows *database.SearchRows) []model.SearchResult {
+	results := make([]model.SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		switch hit.Kind {
+		case database.SearchHitWorkoutRoutine:
+			if workoutRoutine, ok := rows.WorkoutRoutines[hit.ID]; ok {
+				results = append(results, &model.WorkoutRoutine{
+					ID:        utils.UIntToString(workoutRoutine.ID),
+					Name:      workoutRoutine.Name,
+					Active:    workoutRoutine.Active,
+					UpdatedAt: workoutRoutine.UpdatedAt,
+				})
+			}
+		case database.SearchHitExerciseRoutine:
+			if exerciseRoutine, ok := rows.ExerciseRoutines[hit.ID]; ok {
+				results = append(results, &model.ExerciseRoutine{
+					ID:     utils.UIntToString(exerciseRoutine.ID),
+					Active: exerciseRoutine.Active,
+					Name:   exerciseRoutine.Name,
+					Sets:   int(exerciseRoutine.Sets),
+					Reps:   int(exerciseRoutine.Reps),
+				})
+			}
+		case database.SearchHitExercise:
+			if exercise, ok := rows.Exercises[hit.ID]; ok {
+				results = append(results, &model.Exercise{
+					ID:        utils.UIntToString(exercise.ID),
+					Notes:     exercise.Notes,
+					LoggedBy:  utils.UIntToString(exercise.UserID),
+					UpdatedAt: exercise.UpdatedAt,
+				})
+			}
+		case database.SearchHitWorkoutSession:
+			if workoutSession, ok := rows.WorkoutSessions[hit.ID]; ok {
+				results = append(results, toWorkoutSession(&workoutSession))
+			}
+		}
+	}
+	return results
+}
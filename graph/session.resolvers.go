@@ -0,0 +1,97 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/clock"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/utils"
+	"gorm.io/gorm"
+)
+
+// ActiveSessions is the resolver for the activeSessions field.
+func (r *queryResolver) ActiveSessions(ctx context.Context) ([]*model.ActiveSession, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return []*model.ActiveSession{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return []*model.ActiveSession{}, err
+	}
+
+	// a session that hasn't refreshed within REFRESH_TTL can't anymore
+	since := clock.Now().Add(-config.REFRESH_TTL * time.Hour)
+	dbSessions, err := database.GetActiveSessions(r.db(ctx), u.ID, since)
+	if err != nil {
+		return []*model.ActiveSession{}, errors.From(err, "Error Getting Active Sessions")
+	}
+
+	sessions := make([]*model.ActiveSession, 0)
+	for i := range dbSessions {
+		sessions = append(sessions, toActiveSession(&dbSessions[i]))
+	}
+	return sessions, nil
+}
+
+// RevokeSession is the resolver for the revokeSession field.
+func (r *mutationResolver) RevokeSession(ctx context.Context, sessionID string) (int, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return 0, err
+	}
+
+	session, err := database.GetSession(r.db(ctx), sessionID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, errors.NotFound("Error Revoking Session: Session Not Found")
+	}
+	if err != nil {
+		return 0, errors.From(err, "Error Revoking Session")
+	}
+	if session.UserID != u.ID {
+		return 0, errors.Forbidden("Error Revoking Session: Access Denied")
+	}
+	if session.RevokedAt != nil {
+		return 0, nil
+	}
+
+	err = database.RevokeRefreshTokenFamily(r.db(ctx), u.ID, session.FamilyID, clock.Now())
+	if err != nil {
+		return 0, errors.From(err, "Error Revoking Session")
+	}
+
+	return 1, nil
+}
+
+// sessionFrom is where the request came from, for the session it signs in or
+// refreshes
+func sessionFrom(ctx context.Context) *database.Session {
+	return &database.Session{
+		UserAgent: middleware.GetUserAgent(ctx),
+		IP:        middleware.GetClientIP(ctx),
+		Origin:    middleware.GetClient(ctx),
+	}
+}
+
+func toActiveSession(session *database.Session) *model.ActiveSession {
+	return &model.ActiveSession{
+		ID:         utils.UIntToString(session.ID),
+		UserAgent:  session.UserAgent,
+		IP:         session.IP,
+		Origin:     toClientOrigin(session.Origin),
+		CreatedAt:  session.CreatedAt,
+		LastUsedAt: session.LastUsedAt,
+	}
+}
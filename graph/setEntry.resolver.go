@@ -6,12 +6,13 @@ import (
 	"strconv"
 
 	"github.com/graph-gophers/dataloader"
+	"github.com/neilZon/workout-logger-api/common"
+	"github.com/neilZon/workout-logger-api/config"
 	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
 	"github.com/neilZon/workout-logger-api/graph/model"
 	"github.com/neilZon/workout-logger-api/middleware"
 	"github.com/neilZon/workout-logger-api/utils"
-	"github.com/neilZon/workout-logger-api/validator"
-	"github.com/vektah/gqlparser/v2/gqlerror"
 	"gorm.io/gorm"
 )
 
@@ -22,56 +23,66 @@ func (r *mutationResolver) AddSet(ctx context.Context, exerciseID string, set mo
 		return &model.SetEntry{}, err
 	}
 
-	err = middleware.VerifyUser(r.DB, fmt.Sprintf("%d", u.ID))
+	user, err := middleware.VerifiedUser(r.DB, fmt.Sprintf("%d", u.ID))
 	if err != nil {
 		return &model.SetEntry{}, err
 	}
 
-	if err := validator.SetEntryInputIsValid(&model.SetEntry{Weight: set.Weight, Reps: set.Reps}); err != nil {
-		return &model.SetEntry{}, err
-	}
-
 	exerciseIDUint, err := strconv.ParseUint(exerciseID, 10, 64)
 	if err != nil {
-		return &model.SetEntry{}, gqlerror.Errorf("Error Adding Set: Invalid Exercise ID")
-	}
-	exercise := database.Exercise{
-		Model: gorm.Model{
-			ID: uint(exerciseIDUint),
-		},
+		return &model.SetEntry{}, errors.InvalidInput("Error Adding Set: Invalid Exercise ID")
 	}
-	err = database.GetExercise(r.DB, &exercise, false)
+	exercise, err := r.exerciseToChange(ctx, exerciseID, fmt.Sprintf("%d", u.ID))
 	if err != nil {
-		return &model.SetEntry{}, gqlerror.Errorf("Error Adding Set: %s", err)
+		return &model.SetEntry{}, errors.From(err, "Error Adding Set: %s", err)
 	}
-	err = r.ACS.CanAccessWorkoutSession(fmt.Sprintf("%d", u.ID), fmt.Sprintf("%d", exercise.WorkoutSessionID))
+	err = r.canEditWorkoutSession(utils.UIntToString(exercise.WorkoutSessionID), "Error Adding Set")
 	if err != nil {
-		return &model.SetEntry{}, gqlerror.Errorf("Error Adding Set: Access Denied")
+		return &model.SetEntry{}, err
+	}
+
+	var setOrder uint
+	if set.SetOrder != nil {
+		setOrder = uint(*set.SetOrder)
+	} else {
+		setOrder, err = database.NextSetOrder(r.DB, exerciseID)
+		if err != nil {
+			return &model.SetEntry{}, errors.From(err, "Error Adding Set")
+		}
 	}
 
 	dbSet := database.SetEntry{
-		ExerciseID: uint(exerciseIDUint),
-		Weight:     float32(set.Weight),
-		Reps:       uint(set.Reps),
+		ExerciseID:      uint(exerciseIDUint),
+		Weight:          database.RoundWeight(set.Weight),
+		Unit:            setUnit(set.Unit, user),
+		Reps:            uint(set.Reps),
+		SetOrder:        setOrder,
+		Type:            setTypeOrDefault(set.Type),
+		RestTimeSeconds: toUintPtr(set.RestTimeSeconds),
+		CompletedAt:     set.CompletedAt,
+		ClientMetadata:  set.ClientMetadata,
+		Origin:          middleware.GetClient(ctx),
 	}
 	err = database.AddSet(r.DB, &dbSet)
 	if err != nil {
-		return &model.SetEntry{}, gqlerror.Errorf("Error Adding Set")
+		return &model.SetEntry{}, errors.From(err, "Error Adding Set")
 	}
 
 	// invalidate set entry resolver dataloader cache
 	loaders := middleware.GetLoaders(ctx)
 	loaders.SetEntrySliceLoader.Clear(ctx, dataloader.StringKey(exerciseID))
 
-	return &model.SetEntry{
-		ID:     utils.UIntToString(dbSet.ID),
-		Weight: float64(dbSet.Weight),
-		Reps:   int(dbSet.Reps),
-	}, nil
+	addedSet := toSetEntry(&dbSet)
+	r.Live.Publish(utils.UIntToString(exercise.WorkoutSessionID), &model.LiveSetUpdate{
+		ExerciseID: exerciseID,
+		Set:        addedSet,
+	})
+
+	return addedSet, nil
 }
 
 // Sets is the resolver for the sets field.
-func (r *queryResolver) Sets(ctx context.Context, exerciseID string) ([]*model.SetEntry, error) {
+func (r *queryResolver) Sets(ctx context.Context, exerciseID string, limit *int, after *string) ([]*model.SetEntry, error) {
 	u, err := middleware.GetUser(ctx)
 	if err != nil {
 		return []*model.SetEntry{}, err
@@ -82,32 +93,31 @@ func (r *queryResolver) Sets(ctx context.Context, exerciseID string) ([]*model.S
 		return []*model.SetEntry{}, err
 	}
 
-	exerciseIDUint, err := strconv.ParseUint(exerciseID, 10, 64)
+	_, err = strconv.ParseUint(exerciseID, 10, 64)
 	if err != nil {
-		return []*model.SetEntry{}, gqlerror.Errorf("Error Getting Sets: Invalid Exercise ID")
+		return []*model.SetEntry{}, errors.InvalidInput("Error Getting Sets: Invalid Exercise ID")
 	}
-	exercise := database.Exercise{
-		Model: gorm.Model{
-			ID: uint(exerciseIDUint),
-		},
+	n, err := pageLimit(ctx, limit, config.DEFAULT_SETS_PAGE_SIZE, config.MAX_SETS_PAGE_SIZE, "Error Getting Sets")
+	if err != nil {
+		return []*model.SetEntry{}, err
 	}
-	err = database.GetExercise(r.DB, &exercise, true)
+	cursor, err := pageCursor(after, "Error Getting Sets")
 	if err != nil {
-		return []*model.SetEntry{}, gqlerror.Errorf("Error Getting Sets")
+		return []*model.SetEntry{}, err
 	}
 
-	err = r.ACS.CanAccessWorkoutSession(fmt.Sprintf("%d", u.ID), fmt.Sprintf("%d", exercise.WorkoutSessionID))
+	_, err = database.GetParticipantExercise(r.DB, exerciseID, fmt.Sprintf("%d", u.ID), false)
 	if err != nil {
-		return []*model.SetEntry{}, gqlerror.Errorf("Error Getting Sets: Access Denied")
+		return []*model.SetEntry{}, errors.From(err, "Error Getting Sets")
+	}
+	dbSets, err := database.GetSetsPage(r.DB, exerciseID, cursor, n)
+	if err != nil {
+		return []*model.SetEntry{}, errors.From(err, "Error Getting Sets")
 	}
 
-	var sets []*model.SetEntry
-	for _, s := range exercise.Sets {
-		sets = append(sets, &model.SetEntry{
-			ID:     fmt.Sprintf("%d", s.ID),
-			Reps:   int(s.Reps),
-			Weight: float64(s.Weight),
-		})
+	sets := make([]*model.SetEntry, 0, len(dbSets))
+	for i := range dbSets {
+		sets = append(sets, toSetEntry(&dbSets[i]))
 	}
 
 	return sets, nil
@@ -120,42 +130,19 @@ func (r *mutationResolver) UpdateSet(ctx context.Context, setID string, set mode
 		return &model.SetEntry{}, err
 	}
 
-	err = middleware.VerifyUser(r.DB, fmt.Sprintf("%d", u.ID))
+	user, err := middleware.VerifiedUser(r.DB, fmt.Sprintf("%d", u.ID))
 	if err != nil {
 		return &model.SetEntry{}, err
 	}
 
-	if set.Reps != nil && (*set.Reps < 0 || *set.Reps > 9999) {
-		return &model.SetEntry{}, gqlerror.Errorf("Reps needs to be between 0 and 9999")
-	}
-
-	if set.Weight != nil && (*set.Weight < 0 || *set.Weight > 9999) {
-		return &model.SetEntry{}, gqlerror.Errorf("Weight needs to be between 0 and 9999")
-	}
-
-	if err := validator.UpdateSetEntryInputIsValid(&set); err != nil {
-		return &model.SetEntry{}, err
-	}
-
-	var setEntry database.SetEntry
-	err = database.GetSet(r.DB, &setEntry, setID)
+	userId := fmt.Sprintf("%d", u.ID)
+	setEntry, err := r.setToChange(setID, userId)
 	if err != nil {
-		return &model.SetEntry{}, gqlerror.Errorf("Error Updating Set")
-	}
-
-	exercise := database.Exercise{
-		Model: gorm.Model{
-			ID: setEntry.ExerciseID,
-		},
+		return &model.SetEntry{}, errors.From(err, "Error Updating Set")
 	}
-	err = database.GetExercise(r.DB, &exercise, false)
+	err = r.canEditWorkoutSession(utils.UIntToString(setEntry.WorkoutSessionID), "Error Updating Set")
 	if err != nil {
-		return &model.SetEntry{}, gqlerror.Errorf("Error Updating Set")
-	}
-
-	err = r.ACS.CanAccessWorkoutSession(fmt.Sprintf("%d", u.ID), fmt.Sprintf("%d", exercise.WorkoutSessionID))
-	if err != nil {
-		return &model.SetEntry{}, gqlerror.Errorf("Error Updating Set: Access Denied")
+		return &model.SetEntry{}, err
 	}
 
 	// check optional inputs
@@ -163,29 +150,55 @@ func (r *mutationResolver) UpdateSet(ctx context.Context, setID string, set mode
 	if set.Reps != nil {
 		reps = uint(*set.Reps)
 	}
-	var weight float32
+	var weight float64
+	var unit string
 	if set.Weight != nil {
-		weight = float32(*set.Weight)
+		weight = database.RoundWeight(*set.Weight)
+		unit = setUnit(set.Unit, user)
+	}
+	var setOrder uint
+	if set.SetOrder != nil {
+		setOrder = uint(*set.SetOrder)
+	}
+	var setType string
+	if set.Type != nil {
+		setType = string(*set.Type)
 	}
 
 	updatedSet := database.SetEntry{
-		Reps:   reps,
-		Weight: weight,
+		Reps:            reps,
+		Weight:          weight,
+		Unit:            unit,
+		SetOrder:        setOrder,
+		Type:            setType,
+		RestTimeSeconds: toUintPtr(set.RestTimeSeconds),
+		CompletedAt:     set.CompletedAt,
+		ClientMetadata:  set.ClientMetadata,
+	}
+	err = database.UpdateSet(r.DB, utils.UIntToString(setEntry.LoggedBy), setID, &updatedSet, set.ExpectedUpdatedAt)
+	if errors.Is(err, database.ErrConflict) {
+		var current database.SetEntry
+		if err := database.GetSet(r.DB, &current, setID); err != nil {
+			return &model.SetEntry{}, errors.From(err, "Error Updating Set")
+		}
+		return &model.SetEntry{}, &common.ConflictError{Action: "Error Updating Set", Current: toSetEntry(&current)}
 	}
-	err = database.UpdateSet(r.DB, setID, &updatedSet)
 	if err != nil {
-		return &model.SetEntry{}, gqlerror.Errorf("Error Updating Set")
+		return &model.SetEntry{}, errors.From(err, "Error Updating Set")
 	}
 
 	// invalidate set entry resolver dataloader cache
 	loaders := middleware.GetLoaders(ctx)
-	loaders.SetEntrySliceLoader.Clear(ctx, dataloader.StringKey(fmt.Sprintf("%d", exercise.ID)))
+	loaders.SetEntrySliceLoader.Clear(ctx, dataloader.StringKey(fmt.Sprintf("%d", setEntry.ExerciseID)))
 
-	return &model.SetEntry{
-		ID:     fmt.Sprintf("%d", updatedSet.ID),
-		Weight: float64(updatedSet.Weight),
-		Reps:   int(updatedSet.Reps),
-	}, nil
+	publishedSet := toSetEntry(&updatedSet)
+	publishedSet.ID = setID
+	r.Live.Publish(utils.UIntToString(setEntry.WorkoutSessionID), &model.LiveSetUpdate{
+		ExerciseID: utils.UIntToString(setEntry.ExerciseID),
+		Set:        publishedSet,
+	})
+
+	return toSetEntry(&updatedSet), nil
 }
 
 // DeleteSet is the resolver for the deleteSet field.
@@ -200,39 +213,49 @@ func (r *mutationResolver) DeleteSet(ctx context.Context, setID string) (int, er
 		return 0, err
 	}
 
-	var setEntry database.SetEntry
-	err = database.GetSet(r.DB, &setEntry, setID)
+	userId := fmt.Sprintf("%d", u.ID)
+	setEntry, err := r.setToChange(setID, userId)
 	if err != nil {
-		return 0, gqlerror.Errorf("Error Deleting Set")
+		return 0, errors.From(err, "Error Deleting Set")
 	}
-
-	exercise := database.Exercise{
-		Model: gorm.Model{
-			ID: setEntry.ExerciseID,
-		},
-	}
-	err = database.GetExercise(r.DB, &exercise, false)
-	if err != nil {
-		return 0, gqlerror.Errorf("Error Deleting Set")
-	}
-
-	err = r.ACS.CanAccessWorkoutSession(fmt.Sprintf("%d", u.ID), fmt.Sprintf("%d", exercise.WorkoutSessionID))
+	err = r.canEditWorkoutSession(utils.UIntToString(setEntry.WorkoutSessionID), "Error Deleting Set")
 	if err != nil {
-		return 0, gqlerror.Errorf("Error Deleting Set: Access Denied")
+		return 0, err
 	}
 
-	err = database.DeleteSet(r.DB, setID)
+	err = database.DeleteSet(r.DB, utils.UIntToString(setEntry.LoggedBy), setID)
 	if err != nil {
-		return 0, gqlerror.Errorf("Error Deleting Set")
+		return 0, errors.From(err, "Error Deleting Set")
 	}
 
 	// invalidate set entry resolver dataloader cache
 	loaders := middleware.GetLoaders(ctx)
-	loaders.SetEntrySliceLoader.Clear(ctx, dataloader.StringKey(fmt.Sprintf("%d", exercise.ID)))
+	loaders.SetEntrySliceLoader.Clear(ctx, dataloader.StringKey(fmt.Sprintf("%d", setEntry.ExerciseID)))
+
+	r.Live.Publish(utils.UIntToString(setEntry.WorkoutSessionID), &model.LiveSetUpdate{
+		ExerciseID: utils.UIntToString(setEntry.ExerciseID),
+		Set:        toSetEntry(&setEntry.SetEntry),
+		Deleted:    true,
+	})
 
 	return 1, nil
 }
 
+// setToChange is the set if the user logged its exercise or coaches whoever
+// did with read write access, anyone else gets gorm.ErrRecordNotFound like
+// they do from GetSetForUser
+func (r *mutationResolver) setToChange(setID string, userId string) (*database.UserSet, error) {
+	setEntry, err := database.GetSetForUser(r.DB, setID, userId)
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return setEntry, err
+	}
+	coached, coachedErr := database.GetUserSet(r.DB, setID)
+	if coachedErr != nil || r.ACS.CanAccessExercise(userId, utils.UIntToString(coached.ExerciseID)) != nil {
+		return setEntry, err
+	}
+	return coached, nil
+}
+
 // Sets is the resolver for the sets field.
 func (r *exerciseResolver) Sets(ctx context.Context, obj *model.Exercise) ([]*model.SetEntry, error) {
 	loaders := middleware.GetLoaders(ctx)
@@ -243,3 +266,89 @@ func (r *exerciseResolver) Sets(ctx context.Context, obj *model.Exercise) ([]*mo
 	}
 	return result.([]*model.SetEntry), nil
 }
+
+// sets logged together are numbered in the order they were sent unless the
+// client gives its own order
+func toDBSetEntries(inputs []*model.SetEntryInput, user *database.User, origin database.Origin) []database.SetEntry {
+	var setEntries []database.SetEntry
+	for i, s := range inputs {
+		setOrder := uint(i + 1)
+		if s.SetOrder != nil {
+			setOrder = uint(*s.SetOrder)
+		}
+
+		setEntries = append(setEntries, database.SetEntry{
+			Weight:          database.RoundWeight(s.Weight),
+			Unit:            setUnit(s.Unit, user),
+			Reps:            uint(s.Reps),
+			SetOrder:        setOrder,
+			Type:            setTypeOrDefault(s.Type),
+			RestTimeSeconds: toUintPtr(s.RestTimeSeconds),
+			CompletedAt:     s.CompletedAt,
+			ClientMetadata:  s.ClientMetadata,
+			Origin:          origin,
+		})
+	}
+	return setEntries
+}
+
+func setTypeOrDefault(setType *model.SetType) string {
+	if setType == nil {
+		return database.SetTypeWorking
+	}
+	return string(*setType)
+}
+
+func toSetEntry(s *database.SetEntry) *model.SetEntry {
+	var restTimeSeconds *int
+	if s.RestTimeSeconds != nil {
+		rest := int(*s.RestTimeSeconds)
+		restTimeSeconds = &rest
+	}
+
+	return &model.SetEntry{
+		ID:              utils.UIntToString(s.ID),
+		Weight:          database.RoundWeight(s.Weight),
+		Unit:            model.WeightUnit(s.Unit),
+		Reps:            int(s.Reps),
+		SetOrder:        int(s.SetOrder),
+		Type:            model.SetType(s.Type),
+		RestTimeSeconds: restTimeSeconds,
+		CompletedAt:     s.CompletedAt,
+		ClientMetadata:  s.ClientMetadata,
+		Origin:          toClientOrigin(s.Origin),
+		UpdatedAt:       s.UpdatedAt,
+	}
+}
+
+func toUintPtr(i *int) *uint {
+	if i == nil {
+		return nil
+	}
+	u := uint(*i)
+	return &u
+}
+
+// pageLimit is the page size a client asked for, def when it didn't ask, and
+// an error past max
+func pageLimit(ctx context.Context, limit *int, def int, max int, action string) (int, error) {
+	n := def
+	if limit != nil {
+		n = *limit
+	}
+	if n <= 0 || n > max {
+		return 0, errors.InvalidInput("%s: limit needs to be between 1 and %d", action, max)
+	}
+	return middleware.PageSize(ctx, n), nil
+}
+
+// pageCursor is the id a page starts after, empty for the first page
+func pageCursor(after *string, action string) (string, error) {
+	if after == nil || *after == "" {
+		return "", nil
+	}
+	if _, err := strconv.ParseUint(*after, 10, 64); err != nil {
+		return "", errors.InvalidInput("%s: Invalid Cursor", action)
+	}
+	return *after, nil
+}
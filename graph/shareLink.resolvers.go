@@ -0,0 +1,76 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/utils"
+)
+
+// CreateWorkoutSessionShareLink is the resolver for the createWorkoutSessionShareLink field.
+func (r *mutationResolver) CreateWorkoutSessionShareLink(ctx context.Context, workoutSessionID string, invite *bool) (*model.WorkoutSessionShareLink, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.WorkoutSessionShareLink{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.WorkoutSessionShareLink{}, err
+	}
+
+	err = r.ACS.IsWorkoutSessionOwner(utils.UIntToString(u.ID), workoutSessionID)
+	if err != nil {
+		return &model.WorkoutSessionShareLink{}, errors.Forbidden("Error Creating Share Link: Access Denied")
+	}
+
+	workoutSession, err := database.GetWorkoutSession(r.db(ctx), workoutSessionID)
+	if err != nil {
+		return &model.WorkoutSessionShareLink{}, errors.From(err, "Error Creating Share Link")
+	}
+	if workoutSession.End != nil {
+		return &model.WorkoutSessionShareLink{}, errors.InvalidInput("Error Creating Share Link: Workout Session Already Ended")
+	}
+
+	token, err := utils.GenerateVerificationCode(32)
+	if err != nil {
+		return &model.WorkoutSessionShareLink{}, errors.From(err, "Error Creating Share Link")
+	}
+
+	link := database.WorkoutSessionShareLink{
+		Token:            token,
+		WorkoutSessionID: workoutSession.ID,
+		UserID:           u.ID,
+		Invite:           invite != nil && *invite,
+	}
+	err = database.AddWorkoutSessionShareLink(r.db(ctx), &link)
+	if err != nil {
+		return &model.WorkoutSessionShareLink{}, errors.From(err, "Error Creating Share Link")
+	}
+
+	return &model.WorkoutSessionShareLink{
+		Token:            link.Token,
+		WorkoutSessionID: utils.UIntToString(link.WorkoutSessionID),
+		Invite:           link.Invite,
+	}, nil
+}
+
+// LiveSetUpdates is the resolver for the liveSetUpdates field.
+func (r *subscriptionResolver) LiveSetUpdates(ctx context.Context, shareToken string) (<-chan *model.LiveSetUpdate, error) {
+	// spectators don't need an account, the share token is the access check
+	link, err := database.GetWorkoutSessionShareLink(r.db(ctx), shareToken)
+	if err != nil {
+		return nil, errors.NotFound("Error Subscribing To Live Set Updates: Invalid Share Link")
+	}
+
+	// links expire once the session ends (or gets deleted)
+	if link.WorkoutSession.ID == 0 || link.WorkoutSession.End != nil {
+		return nil, errors.InvalidInput("Error Subscribing To Live Set Updates: Share Link Expired")
+	}
+
+	return r.Live.Subscribe(ctx, utils.UIntToString(link.WorkoutSessionID)), nil
+}
@@ -0,0 +1,83 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/utils"
+)
+
+// longest range sleepLogs returns in one go
+const maxSleepLogDays = 366
+
+// LogSleep is the resolver for the logSleep field.
+func (r *mutationResolver) LogSleep(ctx context.Context, sleep model.SleepInput) (*model.SleepLog, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.SleepLog{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.SleepLog{}, err
+	}
+
+	// the night is read like a nutrition day, logging it again replaces it
+	sleepLog := database.SleepLog{
+		UserID: u.ID,
+		Night:  toDay(sleep.Night),
+		Hours:  float32(sleep.Hours),
+	}
+	err = database.UpsertSleepLog(r.db(ctx), &sleepLog)
+	if err != nil {
+		return &model.SleepLog{}, errors.From(err, "Error Logging Sleep")
+	}
+
+	return toSleepLog(&sleepLog), nil
+}
+
+// SleepLogs is the resolver for the sleepLogs field.
+func (r *queryResolver) SleepLogs(ctx context.Context, rangeArg model.DateRangeInput) ([]*model.SleepLog, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return []*model.SleepLog{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return []*model.SleepLog{}, err
+	}
+
+	start := toDay(rangeArg.Start)
+	end := toDay(rangeArg.End)
+	if !start.Before(end) {
+		return []*model.SleepLog{}, errors.InvalidInput("Error Getting Sleep Logs: range start needs to be before range end")
+	}
+	if end.Sub(start) > maxSleepLogDays*24*time.Hour {
+		return []*model.SleepLog{}, errors.InvalidInput("Error Getting Sleep Logs: range can be at most %d days", maxSleepLogDays)
+	}
+
+	dbSleepLogs, err := database.GetSleepLogs(r.db(ctx), utils.UIntToString(u.ID), start, end)
+	if err != nil {
+		return []*model.SleepLog{}, errors.From(err, "Error Getting Sleep Logs")
+	}
+
+	sleepLogs := make([]*model.SleepLog, 0)
+	for i := range dbSleepLogs {
+		sleepLogs = append(sleepLogs, toSleepLog(&dbSleepLogs[i]))
+	}
+	return sleepLogs, nil
+}
+
+func toSleepLog(sleepLog *database.SleepLog) *model.SleepLog {
+	return &model.SleepLog{
+		ID:    utils.UIntToString(sleepLog.ID),
+		Night: sleepLog.Night,
+		Hours: float64(sleepLog.Hours),
+	}
+}
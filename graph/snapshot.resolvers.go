@@ -0,0 +1,134 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/neilZon/workout-logger-api/clock"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/snapshot"
+	"github.com/neilZon/workout-logger-api/utils"
+)
+
+// CreateSnapshot is the resolver for the createSnapshot field.
+func (r *mutationResolver) CreateSnapshot(ctx context.Context) (*model.Snapshot, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.Snapshot{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.Snapshot{}, err
+	}
+
+	count, err := database.CountSnapshots(r.db(ctx), utils.UIntToString(u.ID))
+	if err != nil {
+		return &model.Snapshot{}, errors.From(err, "Error Creating Snapshot")
+	}
+	if count >= config.MAX_SNAPSHOTS {
+		return &model.Snapshot{}, errors.InvalidInput("Error Creating Snapshot: at most %d snapshots, delete one first", config.MAX_SNAPSHOTS)
+	}
+
+	s, err := snapshot.Create(ctx, r.db(ctx), r.Archive, u.ID, clock.Now())
+	if err != nil {
+		return &model.Snapshot{}, errors.From(err, "Error Creating Snapshot")
+	}
+
+	return toSnapshot(s), nil
+}
+
+// RestoreSnapshot is the resolver for the restoreSnapshot field.
+func (r *mutationResolver) RestoreSnapshot(ctx context.Context, snapshotID string, mode *model.SnapshotRestoreMode) (*model.Snapshot, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.Snapshot{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.Snapshot{}, err
+	}
+
+	s, err := database.GetSnapshot(r.db(ctx), snapshotID)
+	if err != nil {
+		return &model.Snapshot{}, errors.From(err, "Error Restoring Snapshot")
+	}
+	if s.UserID != u.ID {
+		return &model.Snapshot{}, errors.Forbidden("Error Restoring Snapshot: Access Denied")
+	}
+
+	replace := mode != nil && *mode == model.SnapshotRestoreModeReplace
+	err = snapshot.Restore(ctx, r.db(ctx), r.Archive, s, replace, clock.Now())
+	if err != nil {
+		return &model.Snapshot{}, errors.From(err, "Error Restoring Snapshot")
+	}
+
+	return toSnapshot(s), nil
+}
+
+// DeleteSnapshot is the resolver for the deleteSnapshot field.
+func (r *mutationResolver) DeleteSnapshot(ctx context.Context, snapshotID string) (int, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return 0, err
+	}
+
+	s, err := database.GetSnapshot(r.db(ctx), snapshotID)
+	if err != nil {
+		return 0, errors.From(err, "Error Deleting Snapshot")
+	}
+	if s.UserID != u.ID {
+		return 0, errors.Forbidden("Error Deleting Snapshot: Access Denied")
+	}
+
+	err = snapshot.Delete(ctx, r.db(ctx), r.Archive, s)
+	if err != nil {
+		return 0, errors.From(err, "Error Deleting Snapshot")
+	}
+
+	return 1, nil
+}
+
+// Snapshots is the resolver for the snapshots field.
+func (r *queryResolver) Snapshots(ctx context.Context) ([]*model.Snapshot, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return []*model.Snapshot{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return []*model.Snapshot{}, err
+	}
+
+	dbSnapshots, err := database.GetSnapshots(r.db(ctx), utils.UIntToString(u.ID))
+	if err != nil {
+		return []*model.Snapshot{}, errors.From(err, "Error Getting Snapshots")
+	}
+
+	snapshots := make([]*model.Snapshot, 0)
+	for i := range dbSnapshots {
+		snapshots = append(snapshots, toSnapshot(&dbSnapshots[i]))
+	}
+	return snapshots, nil
+}
+
+func toSnapshot(s *database.Snapshot) *model.Snapshot {
+	return &model.Snapshot{
+		ID:                  utils.UIntToString(s.ID),
+		CreatedAt:           s.CreatedAt,
+		WorkoutRoutineCount: s.WorkoutRoutineCount,
+		WorkoutSessionCount: s.WorkoutSessionCount,
+		RestoredAt:          s.RestoredAt,
+	}
+}
@@ -0,0 +1,117 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/utils"
+	"gorm.io/gorm"
+)
+
+// ConnectStrava is the resolver for the connectStrava field.
+func (r *mutationResolver) ConnectStrava(ctx context.Context, code string) (*model.StravaConnection, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.StravaConnection{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.StravaConnection{}, err
+	}
+
+	if !r.Strava.Enabled() {
+		return &model.StravaConnection{}, errors.Internal("Error Connecting Strava: Strava isn't set up")
+	}
+
+	tokens, err := r.Strava.ExchangeCode(code)
+	if err != nil {
+		log.Printf("error exchanging strava code: %v", err)
+		return &model.StravaConnection{}, errors.InvalidInput("Error Connecting Strava: Strava didn't accept the code")
+	}
+
+	connection := database.StravaConnection{
+		UserID:       u.ID,
+		AthleteID:    tokens.Athlete.ID,
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresAt:    tokens.Expiry(),
+	}
+	err = database.UpsertStravaConnection(r.db(ctx), &connection)
+	if err != nil {
+		return &model.StravaConnection{}, errors.From(err, "Error Connecting Strava")
+	}
+
+	return toStravaConnection(&connection), nil
+}
+
+// DisconnectStrava is the resolver for the disconnectStrava field.
+func (r *mutationResolver) DisconnectStrava(ctx context.Context) (int, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return 0, err
+	}
+
+	userId := utils.UIntToString(u.ID)
+	connection, err := database.GetStravaConnection(r.db(ctx), userId)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.From(err, "Error Disconnecting Strava")
+	}
+
+	// the link goes either way, the athlete can revoke us from Strava too
+	if r.Strava.Enabled() {
+		if err := r.Strava.Deauthorize(connection.AccessToken); err != nil {
+			log.Printf("error deauthorizing strava athlete %d: %v", connection.AthleteID, err)
+		}
+	}
+
+	deleted, err := database.DeleteStravaConnection(r.db(ctx), userId)
+	if err != nil {
+		return 0, errors.From(err, "Error Disconnecting Strava")
+	}
+
+	return int(deleted), nil
+}
+
+// StravaConnection is the resolver for the stravaConnection field.
+func (r *queryResolver) StravaConnection(ctx context.Context) (*model.StravaConnection, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	connection, err := database.GetStravaConnection(r.db(ctx), utils.UIntToString(u.ID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.From(err, "Error Getting Strava Connection")
+	}
+
+	return toStravaConnection(connection), nil
+}
+
+func toStravaConnection(connection *database.StravaConnection) *model.StravaConnection {
+	return &model.StravaConnection{
+		AthleteID:   fmt.Sprintf("%d", connection.AthleteID),
+		ConnectedAt: connection.CreatedAt,
+	}
+}
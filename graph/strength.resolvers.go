@@ -0,0 +1,78 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/strength"
+	"github.com/neilZon/workout-logger-api/utils"
+)
+
+// StrengthProfile is the resolver for the strengthProfile field.
+func (r *queryResolver) StrengthProfile(ctx context.Context, formula *model.OneRepMaxFormula) (*model.StrengthProfile, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.StrengthProfile{}, err
+	}
+
+	user, err := middleware.VerifiedUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.StrengthProfile{}, err
+	}
+	unit := weightUnitOf(user)
+
+	f := model.OneRepMaxFormulaEpley
+	if formula != nil {
+		f = *formula
+	}
+
+	userId := utils.UIntToString(u.ID)
+	best, err := database.GetBestOneRepMaxes(r.db(ctx), userId, string(f), config.MAX_ONE_REP_MAX_REPS, unit)
+	if err != nil {
+		return &model.StrengthProfile{}, errors.From(err, "Error Getting Strength Profile")
+	}
+
+	bodyWeight, err := database.GetLatestBodyWeight(r.db(ctx), userId)
+	if err != nil {
+		return &model.StrengthProfile{}, errors.From(err, "Error Getting Strength Profile")
+	}
+
+	exercises := make([]*model.ExerciseStrength, 0)
+	for _, b := range best {
+		exercise := model.ExerciseStrength{
+			ExerciseRoutineID:  utils.UIntToString(b.ExerciseRoutineID),
+			Name:               b.Name,
+			EstimatedOneRepMax: database.RoundWeight(b.EstimatedOneRepMax),
+			Weight:             database.RoundWeight(b.Weight),
+			Reps:               b.Reps,
+			PerformedAt:        b.PerformedAt,
+		}
+		if b.CatalogExerciseID != nil {
+			catalogExerciseId := utils.UIntToString(*b.CatalogExerciseID)
+			exercise.CatalogExerciseID = &catalogExerciseId
+		}
+		if bodyWeight != nil && *bodyWeight > 0 {
+			ratio := b.EstimatedOneRepMax / *bodyWeight
+			exercise.BodyWeightRatio = &ratio
+			if b.CatalogExerciseName != nil {
+				if level, ok := strength.Classify(*b.CatalogExerciseName, b.EstimatedOneRepMax, *bodyWeight); ok {
+					l := model.StrengthLevel(level)
+					exercise.Level = &l
+				}
+			}
+		}
+		exercises = append(exercises, &exercise)
+	}
+
+	return &model.StrengthProfile{
+		Formula:    f,
+		WeightUnit: model.WeightUnit(unit),
+		BodyWeight: bodyWeight,
+		Exercises:  exercises,
+	}, nil
+}
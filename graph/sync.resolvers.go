@@ -0,0 +1,146 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/neilZon/workout-logger-api/clock"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/utils"
+)
+
+// SyncWorkoutData is the resolver for the syncWorkoutData field.
+func (r *mutationResolver) SyncWorkoutData(ctx context.Context, changes model.SyncWorkoutDataInput) ([]*model.SyncResult, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := middleware.VerifiedUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	syncChanges, err := toSyncChanges(&changes, user, middleware.GetClient(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	// the edit lock holds for changes made offline too
+	canEdit := func(workoutSessionId uint) error {
+		return r.ACS.CanEditWorkoutSession(utils.UIntToString(workoutSessionId))
+	}
+	outcomes, err := database.ApplySyncChanges(r.db(ctx), u.ID, syncChanges, clock.Now(), canEdit)
+	if err != nil {
+		return nil, errors.From(err, "Error Syncing Workout Data")
+	}
+
+	results := make([]*model.SyncResult, 0, len(outcomes))
+	for _, outcome := range outcomes {
+		result := model.SyncResult{
+			Entity:   model.SyncEntity(outcome.Entity),
+			ClientID: outcome.ClientID,
+			Status:   model.SyncStatus(outcome.Status),
+		}
+		if outcome.EntityID != 0 {
+			id := utils.UIntToString(outcome.EntityID)
+			result.ID = &id
+		}
+		if outcome.Message != "" {
+			result.Message = &outcome.Message
+		}
+		// same as finishing or deleting it online
+		if outcome.Ended {
+			r.Live.Close(utils.UIntToString(outcome.EntityID))
+		}
+		results = append(results, &result)
+	}
+	return results, nil
+}
+
+// toSyncChanges flattens the changes into the order they're applied in,
+// sessions before the exercises in them before the sets in those
+func toSyncChanges(changes *model.SyncWorkoutDataInput, user *database.User, origin database.Origin) ([]database.SyncChange, error) {
+	syncChanges := make([]database.SyncChange, 0, len(changes.WorkoutSessions)+len(changes.Exercises)+len(changes.Sets))
+	for _, ws := range changes.WorkoutSessions {
+		workoutRoutineID, err := toSyncID(ws.WorkoutRoutineID)
+		if err != nil {
+			return nil, errors.InvalidInput("Error Syncing Workout Data: Invalid Workout Routine ID")
+		}
+		syncChanges = append(syncChanges, database.SyncChange{
+			Entity:           database.SyncEntityWorkoutSession,
+			Operation:        string(ws.Operation),
+			ClientID:         strings.ToLower(ws.ClientID),
+			LastModified:     ws.LastModified,
+			WorkoutRoutineID: workoutRoutineID,
+			Start:            ws.Start,
+			End:              ws.End,
+			Notes:            ws.Notes,
+			Origin:           origin,
+		})
+	}
+	for _, e := range changes.Exercises {
+		exerciseRoutineID, err := toSyncID(e.ExerciseRoutineID)
+		if err != nil {
+			return nil, errors.InvalidInput("Error Syncing Workout Data: Invalid Exercise Routine ID")
+		}
+		syncChanges = append(syncChanges, database.SyncChange{
+			Entity:            database.SyncEntityExercise,
+			Operation:         string(e.Operation),
+			ClientID:          strings.ToLower(e.ClientID),
+			LastModified:      e.LastModified,
+			ParentClientID:    toParentClientID(e.WorkoutSessionClientID),
+			ExerciseRoutineID: exerciseRoutineID,
+			Notes:             e.Notes,
+			Origin:            origin,
+		})
+	}
+	for _, s := range changes.Sets {
+		change := database.SyncChange{
+			Entity:         database.SyncEntitySetEntry,
+			Operation:      string(s.Operation),
+			ClientID:       strings.ToLower(s.ClientID),
+			LastModified:   s.LastModified,
+			ParentClientID: toParentClientID(s.ExerciseClientID),
+			Reps:           toUintPtr(s.Reps),
+			CompletedAt:    s.CompletedAt,
+			Origin:         origin,
+		}
+		if s.Weight != nil {
+			weight := database.RoundWeight(*s.Weight)
+			change.Weight = &weight
+			unit := setUnit(s.Unit, user)
+			change.Unit = &unit
+		}
+		if s.Type != nil {
+			setType := string(*s.Type)
+			change.Type = &setType
+		}
+		syncChanges = append(syncChanges, change)
+	}
+	return syncChanges, nil
+}
+
+func toSyncID(id *string) (*uint, error) {
+	if id == nil {
+		return nil, nil
+	}
+	parsed, err := strconv.ParseUint(*id, 10, strconv.IntSize)
+	if err != nil {
+		return nil, err
+	}
+	idUint := uint(parsed)
+	return &idUint, nil
+}
+
+func toParentClientID(clientID *string) string {
+	if clientID == nil {
+		return ""
+	}
+	return strings.ToLower(*clientID)
+}
@@ -0,0 +1,110 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/graph-gophers/dataloader"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/format"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/utils"
+)
+
+// heaviest weight a set can have, same as the set validators
+const maxSetWeight = 9999
+
+// ConvertHistoricalUnits is the resolver for the convertHistoricalUnits field.
+func (r *mutationResolver) ConvertHistoricalUnits(ctx context.Context, exerciseRoutineID string, from model.WeightUnit, to model.WeightUnit, rangeArg model.DateRangeInput) (*model.UnitConversion, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.UnitConversion{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.UnitConversion{}, err
+	}
+
+	factor, ok := database.WeightUnitFactors[string(from)][string(to)]
+	if !ok {
+		return &model.UnitConversion{}, errors.InvalidInput("Error Converting Units: from and to need to be different units")
+	}
+	if !rangeArg.Start.Before(rangeArg.End) {
+		return &model.UnitConversion{}, errors.InvalidInput("Error Converting Units: range start needs to be before range end")
+	}
+
+	exerciseRoutine := database.ExerciseRoutine{}
+	err = database.GetExerciseRoutine(r.db(ctx), exerciseRoutineID, &exerciseRoutine)
+	if err != nil {
+		return &model.UnitConversion{}, errors.From(err, "Error Converting Units")
+	}
+	err = r.ACS.CanAccessWorkoutRoutine(utils.UIntToString(u.ID), utils.UIntToString(exerciseRoutine.WorkoutRoutineID))
+	if err != nil {
+		return &model.UnitConversion{}, errors.Forbidden("Error Converting Units: Access Denied")
+	}
+
+	conversion := database.UnitConversion{
+		UserID:            u.ID,
+		ExerciseRoutineID: exerciseRoutine.ID,
+		FromUnit:          string(from),
+		ToUnit:            string(to),
+		Factor:            factor,
+		RangeStart:        rangeArg.Start,
+		RangeEnd:          rangeArg.End,
+	}
+
+	// guard against converting the same sets twice or into weights the app
+	// won't accept, either would need another conversion to undo
+	overlaps, err := database.HasOverlappingUnitConversion(r.db(ctx), &conversion)
+	if err != nil {
+		return &model.UnitConversion{}, errors.From(err, "Error Converting Units")
+	}
+	if overlaps {
+		return &model.UnitConversion{}, errors.InvalidInput("Error Converting Units: part of this range was already converted from %s to %s", from, to)
+	}
+	maxWeight, err := database.GetMaxConvertibleWeight(r.db(ctx), &conversion)
+	if err != nil {
+		return &model.UnitConversion{}, errors.From(err, "Error Converting Units")
+	}
+	if maxWeight*factor > maxSetWeight {
+		return &model.UnitConversion{}, errors.InvalidInput("Error Converting Units: a set would weigh more than %d", maxSetWeight)
+	}
+
+	exerciseIds, err := database.ConvertHistoricalUnits(r.db(ctx), &conversion)
+	if err != nil {
+		return &model.UnitConversion{}, errors.From(err, "Error Converting Units")
+	}
+
+	// invalidate set entry resolver dataloader cache
+	loaders := middleware.GetLoaders(ctx)
+	for _, id := range exerciseIds {
+		loaders.SetEntrySliceLoader.Clear(ctx, dataloader.StringKey(utils.UIntToString(id)))
+	}
+
+	return &model.UnitConversion{
+		ID:                utils.UIntToString(conversion.ID),
+		ExerciseRoutineID: utils.UIntToString(conversion.ExerciseRoutineID),
+		From:              from,
+		To:                to,
+		RangeStart:        conversion.RangeStart,
+		RangeEnd:          conversion.RangeEnd,
+		SetsConverted:     int(conversion.SetsConverted),
+		ConvertedAt:       conversion.CreatedAt,
+	}, nil
+}
+
+// weightUnitOf is what the user logs and reads weights in
+func weightUnitOf(user *database.User) string {
+	return format.NewHints(user.Locale, user.WeightUnit).WeightUnit
+}
+
+// setUnit is the unit a set's weight was sent in, the user's own without one
+func setUnit(unit *model.WeightUnit, user *database.User) string {
+	if unit == nil {
+		return weightUnitOf(user)
+	}
+	return string(*unit)
+}
@@ -3,11 +3,15 @@ package graph
 import (
 	"context"
 	"fmt"
+	"log"
 
 	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/format"
 	"github.com/neilZon/workout-logger-api/graph/model"
 	"github.com/neilZon/workout-logger-api/middleware"
-	"github.com/vektah/gqlparser/v2/gqlerror"
+	"github.com/neilZon/workout-logger-api/utils"
+	"github.com/neilZon/workout-logger-api/validator"
 )
 
 // DeleteUser is the resolver for the deleteUser field.
@@ -22,13 +26,99 @@ func (r *mutationResolver) DeleteUser(ctx context.Context) (int, error) {
 		return 0, err
 	}
 
-	err = database.DeleteUser(r.DB, fmt.Sprintf("%d", u.ID))
+	_, err = r.purgeAccount(ctx, fmt.Sprintf("%d", u.ID), false)
 	if err != nil {
 		return 0, err
 	}
 	return 1, err
 }
 
+// DeleteAccount is the resolver for the deleteAccount field.
+func (r *mutationResolver) DeleteAccount(ctx context.Context, dryRun *bool) (*model.DeleteResult, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = middleware.VerifyUser(r.DB, fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	isDryRun := dryRun != nil && *dryRun
+	counts, err := r.purgeAccount(ctx, fmt.Sprintf("%d", u.ID), isDryRun)
+	if err != nil {
+		return nil, err
+	}
+	return toDeleteResult(counts, isDryRun), nil
+}
+
+// purgeAccount removes everything we have on the user, files in storage are
+// removed after the database commits and a failure there is only logged. A
+// dry run only counts and leaves storage alone
+func (r *mutationResolver) purgeAccount(ctx context.Context, userId string, dryRun bool) (*database.DeletionCounts, error) {
+	blobs, counts, err := database.PurgeUser(r.DB, userId, dryRun)
+	if err != nil {
+		return nil, errors.From(err, "Error Deleting Account")
+	}
+
+	for _, key := range blobs.Media {
+		if err := r.Media.Delete(ctx, key); err != nil {
+			log.Printf("error deleting media %s for user %s: %v", key, userId, err)
+		}
+	}
+	for _, key := range blobs.Archive {
+		if err := r.Archive.Delete(ctx, key); err != nil {
+			log.Printf("error deleting archive %s for user %s: %v", key, userId, err)
+		}
+	}
+	for _, key := range blobs.Photos {
+		if err := r.Photos.Delete(ctx, key); err != nil {
+			log.Printf("error deleting photo %s for user %s: %v", key, userId, err)
+		}
+	}
+	return counts, nil
+}
+
+func toDeleteResult(counts *database.DeletionCounts, dryRun bool) *model.DeleteResult {
+	return &model.DeleteResult{
+		DryRun: dryRun,
+		Counts: &model.DeletionCounts{
+			WorkoutRoutines:  int(counts.WorkoutRoutines),
+			ExerciseRoutines: int(counts.ExerciseRoutines),
+			WorkoutSessions:  int(counts.WorkoutSessions),
+			Exercises:        int(counts.Exercises),
+			Sets:             int(counts.Sets),
+			ExerciseVideos:   int(counts.ExerciseVideos),
+			BodyWeights:      int(counts.BodyWeights),
+			Goals:            int(counts.Goals),
+			NutritionLogs:    int(counts.NutritionLogs),
+			SleepLogs:        int(counts.SleepLogs),
+		},
+	}
+}
+
+// MergeUsers is the resolver for the mergeUsers field.
+func (r *mutationResolver) MergeUsers(ctx context.Context, sourceUserID string, targetUserID string) (bool, error) {
+	if sourceUserID == targetUserID {
+		return false, errors.InvalidInput("Error Merging Users: source and target need to be different users")
+	}
+	for _, userId := range []string{sourceUserID, targetUserID} {
+		if _, err := database.GetUserById(r.DB, userId); err != nil {
+			return false, errors.NotFound("Error Merging Users: user %s does not exist", userId)
+		}
+	}
+
+	err := database.MergeUsers(r.DB, sourceUserID, targetUserID)
+	if errors.Is(err, database.ErrOpenWorkoutSession) {
+		return false, errors.InvalidInput("Error Merging Users: Both Users Have An Open Workout Session, Finish One First")
+	}
+	if err != nil {
+		return false, errors.From(err, "Error Merging Users")
+	}
+	return true, nil
+}
+
 // User is the resolver for the user field.
 func (r *queryResolver) User(ctx context.Context) (*model.User, error) {
 	u, err := middleware.GetUser(ctx)
@@ -47,12 +137,114 @@ func (r *queryResolver) User(ctx context.Context) (*model.User, error) {
 		return &model.User{}, err
 	}
 	if user == nil {
-		return &model.User{}, gqlerror.Errorf("User does not exist")
+		return &model.User{}, errors.NotFound("User does not exist")
 	}
 
-	return &model.User{
-		ID:    userId,
-		Email: user.Email,
-		Name:  user.Name,
+	return toUser(user), nil
+}
+
+// LifetimeStats is the resolver for the lifetimeStats field.
+func (r *userResolver) LifetimeStats(ctx context.Context, obj *model.User) (*model.LifetimeStats, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.LifetimeStats{}, err
+	}
+	if fmt.Sprintf("%d", u.ID) != obj.ID {
+		return &model.LifetimeStats{}, errors.Forbidden("Error Getting Lifetime Stats: Access Denied")
+	}
+
+	unit := format.NewHints(obj.Locale, (*string)(obj.WeightUnit)).WeightUnit
+	stats, err := database.GetLifetimeStats(r.DB, obj.ID, unit)
+	if err != nil {
+		return &model.LifetimeStats{}, errors.From(err, "Error Getting Lifetime Stats")
+	}
+
+	return &model.LifetimeStats{
+		TotalSessions: stats.TotalSessions,
+		TotalSets:     stats.TotalSets,
+		TotalVolume:   stats.TotalVolume,
+		WeightUnit:    model.WeightUnit(unit),
+		MemberSince:   obj.CreatedAt,
+	}, nil
+}
+
+// UpdateWeekStart is the resolver for the updateWeekStart field.
+func (r *mutationResolver) UpdateWeekStart(ctx context.Context, weekStart model.WeekStart) (*model.User, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.User{}, err
+	}
+
+	userId := fmt.Sprintf("%d", u.ID)
+	err = middleware.VerifyUser(r.DB, userId)
+	if err != nil {
+		return &model.User{}, err
+	}
+
+	err = database.UpdateUserById(r.DB, userId, &database.User{WeekStart: string(weekStart)})
+	if err != nil {
+		return &model.User{}, errors.From(err, "Error Updating Week Start")
+	}
+
+	user, err := database.GetUserById(r.DB, userId)
+	if err != nil {
+		return &model.User{}, errors.From(err, "Error Updating Week Start")
+	}
+	return toUser(user), nil
+}
+
+// UpdateFormatPreferences is the resolver for the updateFormatPreferences field.
+func (r *mutationResolver) UpdateFormatPreferences(ctx context.Context, locale string, weightUnit *model.WeightUnit) (*model.User, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.User{}, err
+	}
+
+	userId := fmt.Sprintf("%d", u.ID)
+	err = middleware.VerifyUser(r.DB, userId)
+	if err != nil {
+		return &model.User{}, err
+	}
+
+	if err := validator.LocaleIsValid(locale); err != nil {
+		return &model.User{}, err
+	}
+	locale, _ = format.Canonical(locale)
+
+	err = database.UpdateUserFormatPreferences(r.DB, userId, locale, (*string)(weightUnit))
+	if err != nil {
+		return &model.User{}, errors.From(err, "Error Updating Format Preferences")
+	}
+
+	user, err := database.GetUserById(r.DB, userId)
+	if err != nil {
+		return &model.User{}, errors.From(err, "Error Updating Format Preferences")
+	}
+	return toUser(user), nil
+}
+
+// FormatHints is the resolver for the formatHints field.
+func (r *userResolver) FormatHints(ctx context.Context, obj *model.User) (*model.FormatHints, error) {
+	hints := format.NewHints(obj.Locale, (*string)(obj.WeightUnit))
+	return &model.FormatHints{
+		Locale:            hints.Locale,
+		DecimalSeparator:  hints.DecimalSeparator,
+		GroupingSeparator: hints.GroupingSeparator,
+		WeightUnit:        model.WeightUnit(hints.WeightUnit),
+		WeightUnitLabel:   hints.WeightUnitLabel,
+		WeightDecimals:    hints.WeightDecimals,
+		VolumeDecimals:    hints.VolumeDecimals,
 	}, nil
 }
+
+func toUser(user *database.User) *model.User {
+	return &model.User{
+		ID:         utils.UIntToString(user.ID),
+		Email:      user.Email,
+		Name:       user.Name,
+		WeekStart:  model.WeekStart(user.WeekStart),
+		Locale:     user.Locale,
+		WeightUnit: (*model.WeightUnit)(user.WeightUnit),
+		CreatedAt:  user.CreatedAt,
+	}
+}
@@ -0,0 +1,117 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/utils"
+	"github.com/neilZon/workout-logger-api/validator"
+)
+
+// RegisterWebhook is the resolver for the registerWebhook field.
+func (r *mutationResolver) RegisterWebhook(ctx context.Context, url string) (*model.Webhook, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.Webhook{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.Webhook{}, err
+	}
+
+	if err := validator.WebhookURLIsValid(url); err != nil {
+		return &model.Webhook{}, errors.From(err, "Error Registering Webhook: %s", err.Error())
+	}
+
+	count, err := database.CountWebhooks(r.db(ctx), utils.UIntToString(u.ID))
+	if err != nil {
+		return &model.Webhook{}, errors.From(err, "Error Registering Webhook")
+	}
+	if count >= config.MAX_WEBHOOKS {
+		return &model.Webhook{}, errors.InvalidInput("Error Registering Webhook: at most %d webhooks", config.MAX_WEBHOOKS)
+	}
+
+	secret, err := utils.GenerateVerificationCode(32)
+	if err != nil {
+		return &model.Webhook{}, errors.From(err, "Error Registering Webhook")
+	}
+
+	webhook := database.Webhook{
+		UserID: u.ID,
+		URL:    url,
+		Secret: secret,
+	}
+	err = database.AddWebhook(r.db(ctx), &webhook)
+	if err != nil {
+		return &model.Webhook{}, errors.From(err, "Error Registering Webhook")
+	}
+
+	return toWebhook(&webhook), nil
+}
+
+// DeleteWebhook is the resolver for the deleteWebhook field.
+func (r *mutationResolver) DeleteWebhook(ctx context.Context, webhookID string) (int, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return 0, err
+	}
+
+	webhook, err := database.GetWebhook(r.db(ctx), webhookID)
+	if err != nil {
+		return 0, errors.From(err, "Error Deleting Webhook")
+	}
+	if webhook.UserID != u.ID {
+		return 0, errors.Forbidden("Error Deleting Webhook: Access Denied")
+	}
+
+	err = database.DeleteWebhook(r.db(ctx), webhookID)
+	if err != nil {
+		return 0, errors.From(err, "Error Deleting Webhook")
+	}
+
+	return 1, nil
+}
+
+// Webhooks is the resolver for the webhooks field.
+func (r *queryResolver) Webhooks(ctx context.Context) ([]*model.Webhook, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return []*model.Webhook{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return []*model.Webhook{}, err
+	}
+
+	dbWebhooks, err := database.GetWebhooks(r.db(ctx), utils.UIntToString(u.ID))
+	if err != nil {
+		return []*model.Webhook{}, errors.From(err, "Error Getting Webhooks")
+	}
+
+	webhooks := make([]*model.Webhook, 0)
+	for i := range dbWebhooks {
+		webhooks = append(webhooks, toWebhook(&dbWebhooks[i]))
+	}
+	return webhooks, nil
+}
+
+func toWebhook(webhook *database.Webhook) *model.Webhook {
+	return &model.Webhook{
+		ID:        utils.UIntToString(webhook.ID),
+		URL:       webhook.URL,
+		Secret:    webhook.Secret,
+		CreatedAt: webhook.CreatedAt,
+	}
+}
@@ -6,13 +6,13 @@ import (
 	"strconv"
 
 	"github.com/graph-gophers/dataloader"
+	"github.com/neilZon/workout-logger-api/common"
 	"github.com/neilZon/workout-logger-api/database"
 	"github.com/neilZon/workout-logger-api/errors"
 	"github.com/neilZon/workout-logger-api/graph/model"
 	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/responsecache"
 	"github.com/neilZon/workout-logger-api/utils"
-	"github.com/neilZon/workout-logger-api/validator"
-	"github.com/vektah/gqlparser/v2/gqlerror"
 	"gorm.io/gorm"
 )
 
@@ -23,32 +23,18 @@ func (r *mutationResolver) CreateWorkoutRoutine(ctx context.Context, routine mod
 		return &model.WorkoutRoutine{}, err
 	}
 
-	err = middleware.VerifyUser(r.DB, fmt.Sprintf("%d", u.ID))
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
 	if err != nil {
 		return &model.WorkoutRoutine{}, err
 	}
 
-	// validate input
-	if len([]rune(routine.Name)) <= 2 {
-		return &model.WorkoutRoutine{}, gqlerror.Errorf("Invalid Routine Name Length")
-	}
-
-	if len(routine.ExerciseRoutines) > 20 {
-		return &model.WorkoutRoutine{}, gqlerror.Errorf("workout routine can only have 20 exercise routines max")
-	}
-
-	for _, exerciseRoutine := range routine.ExerciseRoutines {
-		validator.ExerciseRoutineIsValid(&model.ExerciseRoutine{
-			ID:   "", // blank string to pass to validator
-			Name: exerciseRoutine.Name,
-			Reps: exerciseRoutine.Reps,
-			Sets: exerciseRoutine.Sets,
-		})
-	}
-
 	exerciseRoutines := make([]database.ExerciseRoutine, 0)
 	for _, er := range routine.ExerciseRoutines {
-		exerciseRoutines = append(exerciseRoutines, database.ExerciseRoutine{Name: er.Name, Reps: uint(er.Reps), Sets: uint(er.Sets)})
+		catalogExerciseID, err := toCatalogExerciseID(er.CatalogExerciseID)
+		if err != nil {
+			return &model.WorkoutRoutine{}, errors.InvalidInput("Error Creating Workout Routine: Invalid Catalog Exercise")
+		}
+		exerciseRoutines = append(exerciseRoutines, database.ExerciseRoutine{Name: er.Name, Reps: uint(er.Reps), Sets: uint(er.Sets), Position: uint(len(exerciseRoutines)), CatalogExerciseID: catalogExerciseID})
 	}
 
 	wr := &database.WorkoutRoutine{
@@ -57,9 +43,9 @@ func (r *mutationResolver) CreateWorkoutRoutine(ctx context.Context, routine mod
 		UserID:           u.ID,
 	}
 
-	res := database.CreateWorkoutRoutine(r.DB, wr)
+	res := database.CreateWorkoutRoutine(r.db(ctx), wr)
 	if res.Error != nil {
-		return &model.WorkoutRoutine{}, gqlerror.Errorf("Error Creating Workout Routine")
+		return &model.WorkoutRoutine{}, errors.From(res.Error, "Error Creating Workout Routine")
 	}
 
 	dbExerciseRoutines := make([]*model.ExerciseRoutine, 0)
@@ -77,55 +63,91 @@ func (r *mutationResolver) CreateWorkoutRoutine(ctx context.Context, routine mod
 		Name:             wr.Name,
 		ExerciseRoutines: []*model.ExerciseRoutine{},
 		Active:           wr.Active,
+		UpdatedAt:        wr.UpdatedAt,
 	}, nil
 }
 
 // WorkoutRoutines is the resolver for the workoutRoutines field.
-func (r *queryResolver) WorkoutRoutines(ctx context.Context, limit int, after *string) (*model.WorkoutRoutineConnection, error) {
+func (r *queryResolver) WorkoutRoutines(ctx context.Context, first *int, after *string, orderBy *model.WorkoutRoutineOrder, filter *model.WorkoutRoutineFilter, limit *int) (*model.WorkoutRoutineConnection, error) {
 	u, err := middleware.GetUser(ctx)
 	if err != nil {
 		return &model.WorkoutRoutineConnection{}, err
 	}
 
-	err = middleware.VerifyUser(r.DB, fmt.Sprintf("%d", u.ID))
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
 	if err != nil {
 		return &model.WorkoutRoutineConnection{}, err
 	}
 
-	if limit <= 0 || limit > 50 {
-		return &model.WorkoutRoutineConnection{}, fmt.Errorf(errors.GetWorkoutRoutinesError, "limit needs to be between 1 to 50")
+	// limit is what older clients send, first wins when both are there
+	pageSize := 0
+	if first != nil {
+		pageSize = *first
+	} else if limit != nil {
+		pageSize = *limit
 	}
+	if pageSize <= 0 || pageSize > 50 {
+		return &model.WorkoutRoutineConnection{}, errors.InvalidInput(errors.GetWorkoutRoutinesError, "first needs to be between 1 to 50")
+	}
+	pageSize = middleware.PageSize(ctx, pageSize)
 
-	var dbWorkoutRoutines []database.WorkoutRoutine
-	cursor := ""
+	page := database.WorkoutRoutinePage{
+		// fetch one extra to know if there is another page
+		Limit:   pageSize + 1,
+		OrderBy: database.WorkoutRoutineOrderCreatedAt,
+	}
 	if after != nil && *after != "" {
-		cursor = *after
+		page.Cursor = *after
+	}
+	if orderBy != nil {
+		page.OrderBy, page.Descending = toWorkoutRoutineOrder(orderBy)
+	}
+	if filter != nil && filter.NamePrefix != nil {
+		if len(*filter.NamePrefix) > 32 {
+			return &model.WorkoutRoutineConnection{}, errors.InvalidInput(errors.GetWorkoutRoutinesError, "namePrefix must have less than 32 characters")
+		}
+		page.NamePrefix = *filter.NamePrefix
 	}
 
-	dbWorkoutRoutines, err = database.GetWorkoutRoutines(r.DB, utils.UIntToString(u.ID), cursor, limit)
+	userId := utils.UIntToString(u.ID)
+	cacheKey := responsecache.Key("workoutRoutines", userId, page)
+	cacheScopes := []string{responsecache.UserScope(userId)}
+	var cached model.WorkoutRoutineConnection
+	if r.Cache.Get(cacheKey, cacheScopes, &cached) {
+		return &cached, nil
+	}
 
+	dbWorkoutRoutines, err := database.GetWorkoutRoutines(r.db(ctx), userId, page)
 	if err != nil {
-		return &model.WorkoutRoutineConnection{}, gqlerror.Errorf("Error Getting Workout Routine")
+		return &model.WorkoutRoutineConnection{}, errors.From(err, "Error Getting Workout Routine")
 	}
 
-	var edges []*model.WorkoutRoutineEdge
+	hasNextPage := len(dbWorkoutRoutines) > pageSize
+	if hasNextPage {
+		dbWorkoutRoutines = dbWorkoutRoutines[:pageSize]
+	}
+
+	edges := make([]*model.WorkoutRoutineEdge, 0)
 	for _, workoutRoutine := range dbWorkoutRoutines {
 		edges = append(edges, &model.WorkoutRoutineEdge{
 			Cursor: utils.UIntToString(workoutRoutine.ID),
 			Node: &model.WorkoutRoutine{
-				ID:     utils.UIntToString(workoutRoutine.ID),
-				Name:   workoutRoutine.Name,
-				Active: workoutRoutine.Active,
+				ID:        utils.UIntToString(workoutRoutine.ID),
+				Name:      workoutRoutine.Name,
+				Active:    workoutRoutine.Active,
+				UpdatedAt: workoutRoutine.UpdatedAt,
 			},
 		})
 	}
 
-	return &model.WorkoutRoutineConnection{
+	connection := &model.WorkoutRoutineConnection{
 		Edges: edges,
 		PageInfo: &model.PageInfo{
-			HasNextPage: true,
+			HasNextPage: hasNextPage,
 		},
-	}, nil
+	}
+	r.Cache.Set(cacheKey, cacheScopes, connection)
+	return connection, nil
 }
 
 // WorkoutRoutine is the resolver for the workoutRoutine field.
@@ -135,26 +157,27 @@ func (r *queryResolver) WorkoutRoutine(ctx context.Context, workoutRoutineID str
 		return &model.WorkoutRoutine{}, err
 	}
 
-	err = middleware.VerifyUser(r.DB, fmt.Sprintf("%d", u.ID))
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
 	if err != nil {
 		return &model.WorkoutRoutine{}, err
 	}
 
 	userId := fmt.Sprintf("%d", u.ID)
-	err = r.ACS.CanAccessWorkoutRoutine(userId, workoutRoutineID)
+	err = r.ACS.CanReadWorkoutRoutine(userId, workoutRoutineID)
 	if err != nil {
-		return &model.WorkoutRoutine{}, gqlerror.Errorf("Error Getting Workout Routine: Access Denied")
+		return &model.WorkoutRoutine{}, errors.Forbidden("Error Getting Workout Routine: Access Denied")
 	}
 
-	workoutRoutine, err := database.GetWorkoutRoutine(r.DB, workoutRoutineID)
+	workoutRoutine, err := database.GetWorkoutRoutine(r.db(ctx), workoutRoutineID)
 	if err != nil {
-		return &model.WorkoutRoutine{}, gqlerror.Errorf("Error Getting Workout Routine")
+		return &model.WorkoutRoutine{}, errors.From(err, "Error Getting Workout Routine")
 	}
 
 	return &model.WorkoutRoutine{
-		ID:     fmt.Sprintf("%d", workoutRoutine.ID),
-		Name:   workoutRoutine.Name,
-		Active: workoutRoutine.Active,
+		ID:        fmt.Sprintf("%d", workoutRoutine.ID),
+		Name:      workoutRoutine.Name,
+		Active:    workoutRoutine.Active,
+		UpdatedAt: workoutRoutine.UpdatedAt,
 	}, nil
 }
 
@@ -165,28 +188,15 @@ func (r *mutationResolver) UpdateWorkoutRoutine(ctx context.Context, workoutRout
 		return &model.WorkoutRoutine{}, err
 	}
 
-	err = middleware.VerifyUser(r.DB, fmt.Sprintf("%d", u.ID))
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
 	if err != nil {
 		return &model.WorkoutRoutine{}, err
 	}
 
-	for _, exerciseRoutine := range workoutRoutine.ExerciseRoutines {
-		err = validator.ExerciseRoutineIsValid(&model.ExerciseRoutine{
-			ID:   "", // blank string to pass to validator
-			Name: exerciseRoutine.Name,
-			Reps: exerciseRoutine.Reps,
-			Sets: exerciseRoutine.Sets,
-		})
-
-		if err != nil {
-			return &model.WorkoutRoutine{}, err
-		}
-	}
-
 	userId := fmt.Sprintf("%d", u.ID)
 	err = r.ACS.CanAccessWorkoutRoutine(userId, workoutRoutine.ID)
 	if err != nil {
-		return &model.WorkoutRoutine{}, gqlerror.Errorf("Error Updating Workout Routine: Access Denied")
+		return &model.WorkoutRoutine{}, errors.Forbidden("Error Updating Workout Routine: Access Denied")
 	}
 
 	var exerciseRoutines []*database.ExerciseRoutine
@@ -208,54 +218,103 @@ func (r *mutationResolver) UpdateWorkoutRoutine(ctx context.Context, workoutRout
 			panic(err)
 		}
 
+		catalogExerciseID, err := toCatalogExerciseID(er.CatalogExerciseID)
+		if err != nil {
+			return nil, errors.InvalidInput("Error Updating Workout Routine: Invalid Catalog Exercise")
+		}
+
 		exerciseRoutines = append(exerciseRoutines, &database.ExerciseRoutine{
-			Model:            model,
-			Name:             er.Name,
-			Sets:             uint(er.Sets),
-			Reps:             uint(er.Reps),
-			WorkoutRoutineID: uint(workoutRoutineIDUint),
+			Model:             model,
+			Name:              er.Name,
+			Sets:              uint(er.Sets),
+			Reps:              uint(er.Reps),
+			WorkoutRoutineID:  uint(workoutRoutineIDUint),
+			Position:          uint(len(exerciseRoutines)),
+			CatalogExerciseID: catalogExerciseID,
 		})
 	}
 
-	err = database.UpdateWorkoutRoutine(r.DB, workoutRoutine.ID, workoutRoutine.Name, exerciseRoutines)
+	var updatedWorkoutRoutine database.WorkoutRoutine
+	err = database.UpdateWorkoutRoutine(r.db(ctx), workoutRoutine.ID, workoutRoutine.Name, exerciseRoutines, workoutRoutine.ExpectedUpdatedAt, &updatedWorkoutRoutine)
+	if errors.Is(err, database.ErrConflict) {
+		return &model.WorkoutRoutine{}, r.workoutRoutineConflict(ctx, workoutRoutine.ID)
+	}
 	if err != nil {
-		return &model.WorkoutRoutine{}, gqlerror.Errorf("Error Updating Workout Routine")
+		return &model.WorkoutRoutine{}, errors.From(err, "Error Updating Workout Routine")
 	}
 
 	// invalidate cache to return freshly updated exercise routines
 	loaders := middleware.GetLoaders(ctx)
 	loaders.ExerciseRoutineSliceLoader.Clear(ctx, dataloader.StringKey(workoutRoutine.ID))
+	r.Cache.Invalidate(responsecache.WorkoutRoutineScope(workoutRoutine.ID))
 
 	return &model.WorkoutRoutine{
-		ID:   workoutRoutine.ID,
-		Name: workoutRoutine.Name,
+		ID:        workoutRoutine.ID,
+		Name:      workoutRoutine.Name,
+		UpdatedAt: updatedWorkoutRoutine.UpdatedAt,
 	}, nil
 }
 
+// workoutRoutineConflict has the routine with its exercise routines as the
+// server has them now
+func (r *mutationResolver) workoutRoutineConflict(ctx context.Context, workoutRoutineID string) error {
+	current, err := database.GetWorkoutRoutine(r.db(ctx), workoutRoutineID)
+	if err != nil {
+		return errors.From(err, "Error Updating Workout Routine")
+	}
+	exerciseRoutines, err := database.GetExerciseRoutines(r.db(ctx), workoutRoutineID)
+	if err != nil {
+		return errors.From(err, "Error Updating Workout Routine")
+	}
+
+	currentExerciseRoutines := make([]map[string]interface{}, 0, len(*exerciseRoutines))
+	for _, er := range *exerciseRoutines {
+		currentExerciseRoutines = append(currentExerciseRoutines, map[string]interface{}{
+			"id":   utils.UIntToString(er.ID),
+			"name": er.Name,
+			"sets": er.Sets,
+			"reps": er.Reps,
+		})
+	}
+	return &common.ConflictError{
+		Action: "Error Updating Workout Routine",
+		Current: map[string]interface{}{
+			"id":               workoutRoutineID,
+			"name":             current.Name,
+			"exerciseRoutines": currentExerciseRoutines,
+			"updatedAt":        current.UpdatedAt,
+		},
+	}
+}
+
 // DeleteWorkoutRoutine is the resolver for the deleteWorkoutRoutine field.
-func (r *mutationResolver) DeleteWorkoutRoutine(ctx context.Context, workoutRoutineID string) (int, error) {
+func (r *mutationResolver) DeleteWorkoutRoutine(ctx context.Context, workoutRoutineID string, dryRun *bool) (*model.DeleteResult, error) {
 	u, err := middleware.GetUser(ctx)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	err = middleware.VerifyUser(r.DB, fmt.Sprintf("%d", u.ID))
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	userId := fmt.Sprintf("%d", u.ID)
 	err = r.ACS.CanAccessWorkoutRoutine(userId, workoutRoutineID)
 	if err != nil {
-		return 0, gqlerror.Errorf("Error Deleting Workout Routine: Access Denied")
+		return nil, errors.Forbidden("Error Deleting Workout Routine: Access Denied")
 	}
 
-	err = database.DeleteWorkoutRoutine(r.DB, workoutRoutineID)
+	isDryRun := dryRun != nil && *dryRun
+	counts, err := database.DeleteWorkoutRoutine(r.db(ctx), workoutRoutineID, isDryRun)
 	if err != nil {
-		return 0, gqlerror.Errorf("Error Deleting Workout Routine")
+		return nil, errors.From(err, "Error Deleting Workout Routine")
+	}
+	if !isDryRun {
+		r.Cache.Invalidate(responsecache.WorkoutRoutineScope(workoutRoutineID))
 	}
 
-	return 1, nil
+	return toDeleteResult(counts, isDryRun), nil
 }
 
 // WorkoutRoutine is the resolver for the workoutRoutine field.
@@ -268,3 +327,23 @@ func (r *workoutSessionResolver) WorkoutRoutine(ctx context.Context, obj *model.
 	}
 	return result.(*model.WorkoutRoutine), nil
 }
+
+// routines are listed oldest first by default, except by last performed
+// where the most recent is what people want at the top
+func toWorkoutRoutineOrder(orderBy *model.WorkoutRoutineOrder) (string, bool) {
+	var field string
+	descending := false
+	switch orderBy.Field {
+	case model.WorkoutRoutineOrderFieldName:
+		field = database.WorkoutRoutineOrderName
+	case model.WorkoutRoutineOrderFieldLastPerformedAt:
+		field = database.WorkoutRoutineOrderLastPerformedAt
+		descending = true
+	default:
+		field = database.WorkoutRoutineOrderCreatedAt
+	}
+	if orderBy.Direction != nil {
+		descending = *orderBy.Direction == model.OrderDirectionDesc
+	}
+	return field, descending
+}
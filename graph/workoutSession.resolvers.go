@@ -6,12 +6,16 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/graph-gophers/dataloader"
+	"github.com/neilZon/workout-logger-api/common"
+	"github.com/neilZon/workout-logger-api/config"
 	"github.com/neilZon/workout-logger-api/database"
 	"github.com/neilZon/workout-logger-api/errors"
 	"github.com/neilZon/workout-logger-api/graph/model"
 	"github.com/neilZon/workout-logger-api/middleware"
 	"github.com/neilZon/workout-logger-api/utils"
-	"github.com/vektah/gqlparser/v2/gqlerror"
+	"gorm.io/gorm"
 )
 
 // AddWorkoutSession is the resolver for the addWorkoutSession field.
@@ -21,37 +25,40 @@ func (r *mutationResolver) AddWorkoutSession(ctx context.Context, workout model.
 		return &model.WorkoutSession{}, err
 	}
 
-	err = middleware.VerifyUser(r.DB, fmt.Sprintf("%d", u.ID))
+	user, err := middleware.VerifiedUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
 	if err != nil {
 		return &model.WorkoutSession{}, err
 	}
 
+	userId := utils.UIntToString(u.ID)
+	// sessions get logged to the caller, so coaches can't log against a routine
+	// they don't own
+	err = r.ACS.IsWorkoutRoutineOwner(userId, workout.WorkoutRoutineID)
+	if err != nil {
+		return &model.WorkoutSession{}, errors.Forbidden("Error Adding Workout Session: Access Denied")
+	}
+
+	origin := middleware.GetClient(ctx)
 	var dbExercises []database.Exercise
 	for _, e := range workout.Exercises {
-		var set []database.SetEntry
-
-		for _, s := range e.SetEntries {
-			set = append(set, database.SetEntry{
-				Weight: float32(s.Weight),
-				Reps:   uint(s.Reps),
-			})
-		}
+		set := toDBSetEntries(e.SetEntries, user, origin)
 
 		exerciseRoutineId, err := strconv.ParseUint(e.ExerciseRoutineID, 10, 32)
 		if err != nil {
-			return &model.WorkoutSession{}, gqlerror.Errorf("Error Adding Workout Session")
+			return &model.WorkoutSession{}, errors.From(err, "Error Adding Workout Session")
 		}
 
 		dbExercises = append(dbExercises, database.Exercise{
 			Sets:              set,
 			ExerciseRoutineID: uint(exerciseRoutineId),
 			Notes:             e.Notes,
+			UserID:            u.ID,
 		})
 	}
 
 	workotuRoutineID, err := strconv.ParseUint(workout.WorkoutRoutineID, 10, 64)
 	if err != nil {
-		return &model.WorkoutSession{}, gqlerror.Errorf("Error Adding Workout Session: Invalid Workout Routine ID")
+		return &model.WorkoutSession{}, errors.InvalidInput("Error Adding Workout Session: Invalid Workout Routine ID")
 	}
 
 	ws := &database.WorkoutSession{
@@ -60,10 +67,30 @@ func (r *mutationResolver) AddWorkoutSession(ctx context.Context, workout model.
 		WorkoutRoutineID: uint(workotuRoutineID),
 		UserID:           u.ID,
 		Exercises:        dbExercises,
+		ClientMetadata:   workout.ClientMetadata,
+		Origin:           origin,
+	}
+	// every exercise has to come from the routine the session is for,
+	// otherwise users could log against someone else's exercise routines.
+	// that's checked in the same transaction the session is added in
+	err = database.AddWorkoutSession(r.db(ctx), ws)
+	if errors.Is(err, database.ErrOpenWorkoutSession) {
+		return &model.WorkoutSession{}, errors.InvalidInput("Error Adding Workout Session: Finish The Current Workout Session First")
+	}
+	var exerciseErr *database.ExerciseError
+	if errors.As(err, &exerciseErr) {
+		if errors.Is(err, database.ErrExerciseRoutineNotInWorkoutRoutine) {
+			e := errors.InvalidInput("Error Adding Workout Session: Exercise %d's Routine Must Belong To Workout Routine", exerciseErr.Index)
+			e.Fields = []errors.FieldError{{
+				Field:   fmt.Sprintf("workout.exercises.%d.exerciseRoutineId", exerciseErr.Index),
+				Message: "exercise routine must belong to the workout routine",
+			}}
+			return &model.WorkoutSession{}, e
+		}
+		return &model.WorkoutSession{}, errors.From(err, "Error Adding Workout Session: Could Not Add Exercise %d", exerciseErr.Index)
 	}
-	err = database.AddWorkoutSession(r.DB, ws)
 	if err != nil {
-		return &model.WorkoutSession{}, gqlerror.Errorf("Error Adding Workout Session")
+		return &model.WorkoutSession{}, errors.From(err, "Error Adding Workout Session")
 	}
 
 	return &model.WorkoutSession{
@@ -72,8 +99,12 @@ func (r *mutationResolver) AddWorkoutSession(ctx context.Context, workout model.
 		WorkoutRoutine: model.WorkoutRoutine{
 			ID: workout.WorkoutRoutineID,
 		},
-		Start: ws.Start,
-		End:   ws.End,
+		Start:          ws.Start,
+		End:            ws.End,
+		ClientMetadata: ws.ClientMetadata,
+		Origin:         toClientOrigin(ws.Origin),
+		Notes:          ws.Notes,
+		UpdatedAt:      ws.UpdatedAt,
 	}, nil
 }
 
@@ -84,7 +115,7 @@ func (r *mutationResolver) UpdateWorkoutSession(ctx context.Context, workoutSess
 		return &model.WorkoutSession{}, err
 	}
 
-	err = middleware.VerifyUser(r.DB, fmt.Sprintf("%d", u.ID))
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
 	if err != nil {
 		return &model.WorkoutSession{}, err
 	}
@@ -92,7 +123,11 @@ func (r *mutationResolver) UpdateWorkoutSession(ctx context.Context, workoutSess
 	userId := utils.UIntToString(u.ID)
 	err = r.ACS.CanAccessWorkoutSession(userId, workoutSessionID)
 	if err != nil {
-		return &model.WorkoutSession{}, gqlerror.Errorf("Error Updating Workout Session: Access Denied")
+		return &model.WorkoutSession{}, errors.Forbidden("Error Updating Workout Session: Access Denied")
+	}
+	err = r.canEditWorkoutSession(workoutSessionID, "Error Updating Workout Session")
+	if err != nil {
+		return &model.WorkoutSession{}, err
 	}
 
 	var start time.Time
@@ -100,72 +135,223 @@ func (r *mutationResolver) UpdateWorkoutSession(ctx context.Context, workoutSess
 		start = *updateWorkoutSessionInput.Start
 	}
 	updatedWorkoutSession := database.WorkoutSession{
-		Start: start,
-		End:   updateWorkoutSessionInput.End,
+		Start:          start,
+		End:            updateWorkoutSessionInput.End,
+		ClientMetadata: updateWorkoutSessionInput.ClientMetadata,
+	}
+	err = database.UpdateWorkoutSession(r.db(ctx), workoutSessionID, &updatedWorkoutSession, updateWorkoutSessionInput.ExpectedUpdatedAt)
+	if errors.Is(err, database.ErrConflict) {
+		current, err := database.GetWorkoutSession(r.db(ctx), workoutSessionID)
+		if err != nil {
+			return &model.WorkoutSession{}, errors.From(err, "Error Updating Workout Session")
+		}
+		return &model.WorkoutSession{}, &common.ConflictError{
+			Action: "Error Updating Workout Session",
+			Current: map[string]interface{}{
+				"id":             workoutSessionID,
+				"start":          current.Start,
+				"end":            current.End,
+				"clientMetadata": current.ClientMetadata,
+				"notes":          current.Notes,
+				"updatedAt":      current.UpdatedAt,
+			},
+		}
+	}
+	if err != nil {
+		return &model.WorkoutSession{}, errors.From(err, "Error Updating Workout Session")
+	}
+
+	// ending the session expires any share links to it
+	if updatedWorkoutSession.End != nil {
+		r.Live.Close(workoutSessionID)
+	}
+
+	return &model.WorkoutSession{
+		ID:             utils.UIntToString(updatedWorkoutSession.ID),
+		Start:          updatedWorkoutSession.Start,
+		End:            updatedWorkoutSession.End,
+		ClientMetadata: updatedWorkoutSession.ClientMetadata,
+		Origin:         toClientOrigin(updatedWorkoutSession.Origin),
+		Notes:          updatedWorkoutSession.Notes,
+		UpdatedAt:      updatedWorkoutSession.UpdatedAt,
+	}, nil
+}
+
+// FinishWorkoutSession is the resolver for the finishWorkoutSession field.
+func (r *mutationResolver) FinishWorkoutSession(ctx context.Context, workoutSessionID string, end time.Time) (*model.WorkoutSession, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.WorkoutSession{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.WorkoutSession{}, err
+	}
+
+	userId := utils.UIntToString(u.ID)
+	err = r.ACS.CanAccessWorkoutSession(userId, workoutSessionID)
+	if err != nil {
+		return &model.WorkoutSession{}, errors.Forbidden("Error Finishing Workout Session: Access Denied")
+	}
+
+	// not held back by the edit lock, a session left open for months still
+	// has to be finished before the next one can start
+	workoutSession, err := database.GetWorkoutSession(r.db(ctx), workoutSessionID)
+	if err != nil {
+		return &model.WorkoutSession{}, errors.From(err, "Error Finishing Workout Session")
+	}
+	if end.Before(workoutSession.Start) {
+		return &model.WorkoutSession{}, errors.InvalidInput("Error Finishing Workout Session: End Can't Be Before Start")
+	}
+
+	workoutSession, err = database.FinishWorkoutSession(r.db(ctx), workoutSessionID, end)
+	if errors.Is(err, database.ErrWorkoutSessionFinished) {
+		return &model.WorkoutSession{}, errors.InvalidInput("Error Finishing Workout Session: Already Finished")
 	}
-	err = database.UpdateWorkoutSession(r.DB, workoutSessionID, &updatedWorkoutSession)
 	if err != nil {
-		return &model.WorkoutSession{}, gqlerror.Errorf("Error Updating Workout Session")
+		return &model.WorkoutSession{}, errors.From(err, "Error Finishing Workout Session")
 	}
 
+	// ending the session expires any share links to it
+	r.Live.Close(workoutSessionID)
+
 	return &model.WorkoutSession{
-		ID:    utils.UIntToString(updatedWorkoutSession.ID),
-		Start: updatedWorkoutSession.Start,
-		End:   updatedWorkoutSession.End,
+		ID: utils.UIntToString(workoutSession.ID),
+		WorkoutRoutine: model.WorkoutRoutine{
+			ID: utils.UIntToString(workoutSession.WorkoutRoutineID),
+		},
+		Start:          workoutSession.Start,
+		End:            workoutSession.End,
+		ClientMetadata: workoutSession.ClientMetadata,
+		Origin:         toClientOrigin(workoutSession.Origin),
+		Notes:          workoutSession.Notes,
+		UpdatedAt:      workoutSession.UpdatedAt,
 	}, nil
 }
 
 // DeleteWorkoutSession is the resolver for the deleteWorkoutSession field.
-func (r *mutationResolver) DeleteWorkoutSession(ctx context.Context, workoutSessionID string) (int, error) {
+func (r *mutationResolver) DeleteWorkoutSession(ctx context.Context, workoutSessionID string, dryRun *bool) (*model.DeleteResult, error) {
 	u, err := middleware.GetUser(ctx)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	err = middleware.VerifyUser(r.DB, fmt.Sprintf("%d", u.ID))
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	userId := utils.UIntToString(u.ID)
 	err = r.ACS.CanAccessWorkoutSession(userId, workoutSessionID)
 	if err != nil {
-		return 0, gqlerror.Errorf("Error Deleting Workout Session: Access Denied")
+		return nil, errors.Forbidden("Error Deleting Workout Session: Access Denied")
+	}
+	err = r.canEditWorkoutSession(workoutSessionID, "Error Deleting Workout Session")
+	if err != nil {
+		return nil, err
+	}
+
+	isDryRun := dryRun != nil && *dryRun
+	counts, err := database.DeleteWorkoutSession(r.db(ctx), workoutSessionID, isDryRun)
+	if err != nil {
+		return nil, errors.From(err, "Error Deleting Workout Session")
+	}
+
+	if !isDryRun {
+		r.Live.Close(workoutSessionID)
+	}
+
+	return toDeleteResult(counts, isDryRun), nil
+}
+
+// DeleteWorkoutSessions is the resolver for the deleteWorkoutSessions field.
+func (r *mutationResolver) DeleteWorkoutSessions(ctx context.Context, workoutSessionIds []string, dryRun *bool) (*model.DeleteResult, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(workoutSessionIds) == 0 || len(workoutSessionIds) > config.MAX_BULK_IDS {
+		return nil, errors.InvalidInput("Error Deleting Workout Sessions: between 1 and %d ids are allowed", config.MAX_BULK_IDS)
+	}
+
+	userId := utils.UIntToString(u.ID)
+	err = r.ACS.CanAccessWorkoutSessions(userId, workoutSessionIds)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errors.NotFound("Error Deleting Workout Sessions: workout session not found")
+	}
+	if err != nil {
+		return nil, errors.Forbidden("Error Deleting Workout Sessions: Access Denied")
+	}
+	for _, id := range workoutSessionIds {
+		err = r.canEditWorkoutSession(id, "Error Deleting Workout Sessions")
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	err = database.DeleteWorkoutSession(r.DB, workoutSessionID)
+	isDryRun := dryRun != nil && *dryRun
+	counts, err := database.DeleteWorkoutSessions(r.db(ctx), workoutSessionIds, isDryRun)
 	if err != nil {
-		return 0, gqlerror.Errorf("Error Deleting Workout Session")
+		return nil, errors.From(err, "Error Deleting Workout Sessions")
+	}
+
+	if !isDryRun {
+		for _, id := range workoutSessionIds {
+			r.Live.Close(id)
+		}
 	}
 
-	return 1, nil
+	return toDeleteResult(counts, isDryRun), nil
 }
 
 // WorkoutSessions is the resolver for the workoutSessions field.
-func (r *queryResolver) WorkoutSessions(ctx context.Context, limit int, after *string) (*model.WorkoutSessionConnection, error) {
+func (r *queryResolver) WorkoutSessions(ctx context.Context, limit int, after *string, tag *string) (*model.WorkoutSessionConnection, error) {
 	u, err := middleware.GetUser(ctx)
 	if err != nil {
 		return &model.WorkoutSessionConnection{}, err
 	}
 
-	err = middleware.VerifyUser(r.DB, fmt.Sprintf("%d", u.ID))
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
 	if err != nil {
 		return &model.WorkoutSessionConnection{}, err
 	}
 
 	if limit <= 0 || limit > 30 {
-		return &model.WorkoutSessionConnection{}, gqlerror.Errorf(errors.GetWorkoutRoutinesError, "limit needs to be between 1 to 30")
+		return &model.WorkoutSessionConnection{}, errors.InvalidInput(errors.GetWorkoutRoutinesError, "limit needs to be between 1 to 30")
 	}
+	limit = middleware.PageSize(ctx, limit)
 
 	cursor := ""
 	if after != nil && *after != "" {
 		cursor = *after
 	}
 
-	dbWorkoutSessions, err := database.GetWorkoutSessions(r.DB, utils.UIntToString(u.ID), cursor, limit)
+	tagName := ""
+	if tag != nil {
+		tagName = database.NormalizeTag(*tag)
+	}
+
+	// loading exercises and sets with the page keeps them to a query a level,
+	// rather than however many the loaders' batches come to
+	preload := database.PreloadNothing
+	if selects(ctx, "edges", "node", "exercises", "sets") {
+		preload = database.PreloadSets
+	} else if selects(ctx, "edges", "node", "exercises") {
+		preload = database.PreloadExercises
+	}
+
+	dbWorkoutSessions, err := database.GetWorkoutSessions(r.db(ctx), utils.UIntToString(u.ID), cursor, limit, tagName, preload)
 	if err != nil {
-		return &model.WorkoutSessionConnection{}, gqlerror.Errorf(errors.GetWorkoutSessionsError)
+		return &model.WorkoutSessionConnection{}, errors.From(err, errors.GetWorkoutSessionsError, "please try again")
 	}
+	primeWorkoutSessionLoaders(ctx, dbWorkoutSessions, preload)
 
 	var edges []*model.WorkoutSessionEdge
 	for _, workoutSession := range dbWorkoutSessions {
@@ -177,8 +363,12 @@ func (r *queryResolver) WorkoutSessions(ctx context.Context, limit int, after *s
 				WorkoutRoutine: model.WorkoutRoutine{
 					ID: utils.UIntToString(workoutSession.WorkoutRoutineID),
 				},
-				Start: workoutSession.Start,
-				End:   workoutSession.End,
+				Start:          workoutSession.Start,
+				End:            workoutSession.End,
+				ClientMetadata: workoutSession.ClientMetadata,
+				Origin:         toClientOrigin(workoutSession.Origin),
+				Notes:          workoutSession.Notes,
+				UpdatedAt:      workoutSession.UpdatedAt,
 			},
 		})
 	}
@@ -198,14 +388,58 @@ func (r *queryResolver) WorkoutSession(ctx context.Context, workoutSessionID str
 		return &model.WorkoutSession{}, err
 	}
 
-	err = middleware.VerifyUser(r.DB, fmt.Sprintf("%d", u.ID))
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
 	if err != nil {
 		return &model.WorkoutSession{}, err
 	}
 
-	workoutSession, err := database.GetUsersWorkoutSession(r.DB, workoutSessionID, utils.UIntToString(u.ID))
+	userId := utils.UIntToString(u.ID)
+	err = r.ACS.CanParticipateInWorkoutSession(userId, workoutSessionID)
+	if err != nil {
+		// coaches can look at their athletes' sessions
+		if r.ACS.CanReadWorkoutSession(userId, workoutSessionID) != nil {
+			return &model.WorkoutSession{}, errors.Forbidden("Error Getting Workout Session: Access Denied")
+		}
+	}
+
+	workoutSession, err := database.GetWorkoutSession(r.db(ctx), workoutSessionID)
+	if err != nil {
+		return &model.WorkoutSession{}, errors.From(err, "Error Getting Workout Session")
+	}
+
+	return &model.WorkoutSession{
+		ID: utils.UIntToString(workoutSession.ID),
+		// return workout routine ID to access in workout routine resolver
+		WorkoutRoutine: model.WorkoutRoutine{
+			ID: utils.UIntToString(workoutSession.WorkoutRoutineID),
+		},
+		Start:          workoutSession.Start,
+		End:            workoutSession.End,
+		ClientMetadata: workoutSession.ClientMetadata,
+		Origin:         toClientOrigin(workoutSession.Origin),
+		Notes:          workoutSession.Notes,
+		UpdatedAt:      workoutSession.UpdatedAt,
+	}, nil
+}
+
+// CurrentWorkoutSession is the resolver for the currentWorkoutSession field.
+func (r *queryResolver) CurrentWorkoutSession(ctx context.Context) (*model.WorkoutSession, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
 	if err != nil {
-		return &model.WorkoutSession{}, gqlerror.Errorf("Error Getting Workout Session: Access Denied")
+		return nil, err
+	}
+
+	workoutSession, err := database.GetOpenWorkoutSession(r.db(ctx), utils.UIntToString(u.ID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.From(err, "Error Getting Current Workout Session")
 	}
 
 	return &model.WorkoutSession{
@@ -214,7 +448,112 @@ func (r *queryResolver) WorkoutSession(ctx context.Context, workoutSessionID str
 		WorkoutRoutine: model.WorkoutRoutine{
 			ID: utils.UIntToString(workoutSession.WorkoutRoutineID),
 		},
-		Start: workoutSession.Start,
-		End:   workoutSession.End,
+		Start:          workoutSession.Start,
+		End:            workoutSession.End,
+		ClientMetadata: workoutSession.ClientMetadata,
+		Origin:         toClientOrigin(workoutSession.Origin),
+		Notes:          workoutSession.Notes,
+		UpdatedAt:      workoutSession.UpdatedAt,
 	}, nil
 }
+
+// Summary is the resolver for the summary field.
+func (r *workoutSessionResolver) Summary(ctx context.Context, obj *model.WorkoutSession) (*model.WorkoutSummary, error) {
+	if obj.End == nil {
+		return nil, nil
+	}
+
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+	// coaches read their clients' sessions in their own unit
+	viewer, err := database.GetUserById(r.db(ctx), utils.UIntToString(u.ID))
+	if err != nil {
+		return nil, errors.From(err, "Error Getting Workout Summary")
+	}
+	unit := weightUnitOf(viewer)
+
+	dbSummary, err := database.GetWorkoutSummary(r.db(ctx), obj.ID, unit)
+	if err != nil {
+		return nil, errors.From(err, "Error Getting Workout Summary")
+	}
+	dbRecords, err := database.GetPersonalRecords(r.db(ctx), obj.ID, unit)
+	if err != nil {
+		return nil, errors.From(err, "Error Getting Workout Summary")
+	}
+
+	records := make([]*model.PersonalRecord, 0, len(dbRecords))
+	for _, pr := range dbRecords {
+		records = append(records, &model.PersonalRecord{
+			ExerciseRoutineID: utils.UIntToString(pr.ExerciseRoutineID),
+			Name:              pr.Name,
+			Weight:            database.RoundWeight(pr.Weight),
+			Reps:              pr.Reps,
+			PreviousWeight:    database.RoundWeight(pr.PreviousWeight),
+		})
+	}
+
+	return &model.WorkoutSummary{
+		DurationSeconds:    int(obj.End.Sub(obj.Start).Seconds()),
+		TotalVolume:        dbSummary.TotalVolume,
+		WeightUnit:         model.WeightUnit(unit),
+		TotalSets:          dbSummary.TotalSets,
+		ExercisesCompleted: dbSummary.ExercisesCompleted,
+		ExercisesPlanned:   dbSummary.ExercisesPlanned,
+		PersonalRecords:    records,
+	}, nil
+}
+
+// selects is whether the query asks for the field at path below the one being
+// resolved, like edges.node.exercises
+func selects(ctx context.Context, path ...string) bool {
+	opCtx := graphql.GetOperationContext(ctx)
+	fc := graphql.GetFieldContext(ctx)
+	fields := graphql.CollectFields(opCtx, fc.Field.Selections, []string{fc.Field.Definition.Type.Name()})
+	for _, name := range path {
+		found := false
+		var next []graphql.CollectedField
+		for _, f := range fields {
+			// the same field can be asked for under more than one alias
+			if f.Name == name {
+				found = true
+				next = append(next, graphql.CollectFields(opCtx, f.Selections, []string{f.Definition.Type.Name()})...)
+			}
+		}
+		if !found {
+			return false
+		}
+		fields = next
+	}
+	return true
+}
+
+// primeWorkoutSessionLoaders hands what GetWorkoutSessions preloaded to the
+// loaders the exercises and sets resolvers read from
+func primeWorkoutSessionLoaders(ctx context.Context, workoutSessions []database.WorkoutSession, preload database.Preload) {
+	if preload == database.PreloadNothing {
+		return
+	}
+	loaders := middleware.GetLoaders(ctx)
+	for _, workoutSession := range workoutSessions {
+		exercises := make([]*model.Exercise, 0, len(workoutSession.Exercises))
+		for _, e := range workoutSession.Exercises {
+			exercises = append(exercises, &model.Exercise{
+				ID:        utils.UIntToString(e.ID),
+				Notes:     e.Notes,
+				LoggedBy:  utils.UIntToString(e.UserID),
+				UpdatedAt: e.UpdatedAt,
+			})
+
+			if preload == database.PreloadSets {
+				sets := make([]*model.SetEntry, 0, len(e.Sets))
+				for i := range e.Sets {
+					sets = append(sets, toSetEntry(&e.Sets[i]))
+				}
+				loaders.SetEntrySliceLoader.Prime(ctx, dataloader.StringKey(utils.UIntToString(e.ID)), sets)
+			}
+		}
+		loaders.ExerciseSliceLoader.Prime(ctx, dataloader.StringKey(utils.UIntToString(workoutSession.ID)), exercises)
+	}
+}
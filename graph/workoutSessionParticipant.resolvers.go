@@ -0,0 +1,90 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/utils"
+)
+
+// JoinWorkoutSession is the resolver for the joinWorkoutSession field.
+func (r *mutationResolver) JoinWorkoutSession(ctx context.Context, inviteToken string) (*model.WorkoutSession, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.WorkoutSession{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.WorkoutSession{}, err
+	}
+
+	link, err := database.GetWorkoutSessionShareLink(r.db(ctx), inviteToken)
+	if err != nil {
+		return &model.WorkoutSession{}, errors.NotFound("Error Joining Workout Session: Invalid Invite")
+	}
+	// spectator links are passed around to watch, they don't let anyone log
+	// in the session
+	if !link.Invite {
+		return &model.WorkoutSession{}, errors.Forbidden("Error Joining Workout Session: Share Link Isn't An Invite")
+	}
+	workoutSession := link.WorkoutSession
+	if workoutSession.ID == 0 || workoutSession.End != nil {
+		return &model.WorkoutSession{}, errors.InvalidInput("Error Joining Workout Session: Invite Expired")
+	}
+	if workoutSession.UserID == u.ID {
+		return &model.WorkoutSession{}, errors.InvalidInput("Error Joining Workout Session: Can't Join Your Own Workout Session")
+	}
+
+	workoutSessionId := utils.UIntToString(workoutSession.ID)
+	_, err = database.GetWorkoutSessionParticipant(r.db(ctx), workoutSessionId, utils.UIntToString(u.ID))
+	if err != nil {
+		err = database.AddWorkoutSessionParticipant(r.db(ctx), &database.WorkoutSessionParticipant{
+			WorkoutSessionID: workoutSession.ID,
+			UserID:           u.ID,
+		})
+		if err != nil {
+			return &model.WorkoutSession{}, errors.From(err, "Error Joining Workout Session")
+		}
+	}
+
+	return &model.WorkoutSession{
+		ID: workoutSessionId,
+		// return workout routine ID to access in workout routine resolver
+		WorkoutRoutine: model.WorkoutRoutine{
+			ID: utils.UIntToString(workoutSession.WorkoutRoutineID),
+		},
+		Start:          workoutSession.Start,
+		End:            workoutSession.End,
+		ClientMetadata: workoutSession.ClientMetadata,
+		Origin:         toClientOrigin(workoutSession.Origin),
+		Notes:          workoutSession.Notes,
+		UpdatedAt:      workoutSession.UpdatedAt,
+	}, nil
+}
+
+// LeaveWorkoutSession is the resolver for the leaveWorkoutSession field.
+func (r *mutationResolver) LeaveWorkoutSession(ctx context.Context, workoutSessionID string) (int, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return 0, err
+	}
+
+	// exercises already logged stay in the session and keep counting
+	// towards the user's stats
+	deleted, err := database.DeleteWorkoutSessionParticipant(r.db(ctx), workoutSessionID, utils.UIntToString(u.ID))
+	if err != nil {
+		return 0, errors.From(err, "Error Leaving Workout Session")
+	}
+
+	return int(deleted), nil
+}
@@ -0,0 +1,139 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/graph-gophers/dataloader"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/utils"
+	"github.com/neilZon/workout-logger-api/validator"
+)
+
+// SetWorkoutSessionNotes is the resolver for the setWorkoutSessionNotes field.
+func (r *mutationResolver) SetWorkoutSessionNotes(ctx context.Context, workoutSessionID string, notes string) (*model.WorkoutSession, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.WorkoutSession{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.WorkoutSession{}, err
+	}
+
+	if err := validator.WorkoutSessionNotesIsValid(notes); err != nil {
+		return &model.WorkoutSession{}, err
+	}
+
+	workoutSession, err := r.getEditableWorkoutSession(ctx, utils.UIntToString(u.ID), workoutSessionID, "Error Setting Workout Session Notes")
+	if err != nil {
+		return &model.WorkoutSession{}, err
+	}
+
+	err = database.SetWorkoutSessionNotes(r.db(ctx), workoutSession.ID, notes)
+	if err != nil {
+		return &model.WorkoutSession{}, errors.From(err, "Error Setting Workout Session Notes")
+	}
+	workoutSession.Notes = notes
+
+	return toWorkoutSession(workoutSession), nil
+}
+
+// SetWorkoutSessionTags is the resolver for the setWorkoutSessionTags field.
+func (r *mutationResolver) SetWorkoutSessionTags(ctx context.Context, workoutSessionID string, tags []string) (*model.WorkoutSession, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.WorkoutSession{}, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.WorkoutSession{}, err
+	}
+
+	if err := validator.TagsAreValid(tags); err != nil {
+		return &model.WorkoutSession{}, err
+	}
+
+	workoutSession, err := r.getEditableWorkoutSession(ctx, utils.UIntToString(u.ID), workoutSessionID, "Error Setting Workout Session Tags")
+	if err != nil {
+		return &model.WorkoutSession{}, err
+	}
+
+	err = database.SetWorkoutSessionTags(r.db(ctx), workoutSession.ID, database.NormalizeTags(tags))
+	if err != nil {
+		return &model.WorkoutSession{}, errors.From(err, "Error Setting Workout Session Tags")
+	}
+
+	return toWorkoutSession(workoutSession), nil
+}
+
+// WorkoutSessionTags is the resolver for the workoutSessionTags field.
+func (r *queryResolver) WorkoutSessionTags(ctx context.Context) ([]string, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = middleware.VerifyUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := database.GetUserTags(r.db(ctx), utils.UIntToString(u.ID))
+	if err != nil {
+		return nil, errors.From(err, "Error Getting Workout Session Tags")
+	}
+	return tags, nil
+}
+
+// Tags is the resolver for the tags field.
+func (r *workoutSessionResolver) Tags(ctx context.Context, obj *model.WorkoutSession) ([]string, error) {
+	loaders := middleware.GetLoaders(ctx)
+	thunk := loaders.TagSliceLoader.Load(ctx, dataloader.StringKey(obj.ID))
+	result, err := thunk()
+	if err != nil {
+		return nil, err
+	}
+
+	return result.([]string), nil
+}
+
+// getEditableWorkoutSession is the session if it's the user's own and not
+// locked for edits
+func (r *mutationResolver) getEditableWorkoutSession(ctx context.Context, userId string, workoutSessionID string, action string) (*database.WorkoutSession, error) {
+	err := r.ACS.CanAccessWorkoutSession(userId, workoutSessionID)
+	if err != nil {
+		return nil, errors.Forbidden("%s: Access Denied", action)
+	}
+	err = r.canEditWorkoutSession(workoutSessionID, action)
+	if err != nil {
+		return nil, err
+	}
+
+	workoutSession, err := database.GetWorkoutSession(r.db(ctx), workoutSessionID)
+	if err != nil {
+		return nil, errors.From(err, "%s", action)
+	}
+	return workoutSession, nil
+}
+
+func toWorkoutSession(workoutSession *database.WorkoutSession) *model.WorkoutSession {
+	return &model.WorkoutSession{
+		ID: utils.UIntToString(workoutSession.ID),
+		// return workout routine ID to access in workout routine resolver
+		WorkoutRoutine: model.WorkoutRoutine{
+			ID: utils.UIntToString(workoutSession.WorkoutRoutineID),
+		},
+		Start:          workoutSession.Start,
+		End:            workoutSession.End,
+		ClientMetadata: workoutSession.ClientMetadata,
+		Origin:         toClientOrigin(workoutSession.Origin),
+		Notes:          workoutSession.Notes,
+		UpdatedAt:      workoutSession.UpdatedAt,
+	}
+}
@@ -0,0 +1,111 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/utils"
+)
+
+// WorkoutStats is the resolver for the workoutStats field.
+func (r *queryResolver) WorkoutStats(ctx context.Context, workoutRoutineID string, rangeArg model.DateRangeInput) (*model.WorkoutStats, error) {
+	u, err := middleware.GetUser(ctx)
+	if err != nil {
+		return &model.WorkoutStats{}, err
+	}
+
+	user, err := middleware.VerifiedUser(r.db(ctx), fmt.Sprintf("%d", u.ID))
+	if err != nil {
+		return &model.WorkoutStats{}, err
+	}
+	unit := weightUnitOf(user)
+
+	if !rangeArg.Start.Before(rangeArg.End) {
+		return &model.WorkoutStats{}, errors.InvalidInput(errors.GetWorkoutStatsError, "range start needs to be before range end")
+	}
+
+	userId := utils.UIntToString(u.ID)
+	err = r.ACS.CanAccessWorkoutRoutine(userId, workoutRoutineID)
+	if err != nil {
+		// training partners get their own stats for routines they co-logged
+		count, err := database.CountWorkoutRoutineParticipations(r.db(ctx), workoutRoutineID, userId)
+		if err != nil || count == 0 {
+			return &model.WorkoutStats{}, errors.Forbidden("Error Getting Workout Stats: Access Denied")
+		}
+	}
+
+	dbStats, err := database.GetWorkoutStats(r.db(ctx), workoutRoutineID, userId, rangeArg.Start, rangeArg.End, unit)
+	if err != nil {
+		return &model.WorkoutStats{}, errors.From(err, "Error Getting Workout Stats")
+	}
+
+	dbExerciseRoutineStats, err := database.GetExerciseRoutineStats(r.db(ctx), workoutRoutineID, userId, rangeArg.Start, rangeArg.End, unit)
+	if err != nil {
+		return &model.WorkoutStats{}, errors.From(err, "Error Getting Workout Stats")
+	}
+
+	dbMuscleGroupStats, err := database.GetMuscleGroupStats(r.db(ctx), workoutRoutineID, userId, rangeArg.Start, rangeArg.End, unit)
+	if err != nil {
+		return &model.WorkoutStats{}, errors.From(err, "Error Getting Workout Stats")
+	}
+
+	exerciseRoutineStats := make([]*model.ExerciseRoutineStats, 0)
+	for _, ers := range dbExerciseRoutineStats {
+		exerciseRoutineStats = append(exerciseRoutineStats, &model.ExerciseRoutineStats{
+			ExerciseRoutineID:  utils.UIntToString(ers.ExerciseRoutineID),
+			Name:               ers.Name,
+			Sets:               ers.Sets,
+			Volume:             ers.Volume,
+			AverageRestSeconds: ers.AverageRestSeconds,
+		})
+	}
+
+	muscleGroupStats := make([]*model.MuscleGroupStats, 0)
+	for _, mgs := range dbMuscleGroupStats {
+		muscleGroupStats = append(muscleGroupStats, &model.MuscleGroupStats{
+			MuscleGroup: model.MuscleGroup(mgs.MuscleGroup),
+			Sets:        mgs.Sets,
+			Volume:      mgs.Volume,
+		})
+	}
+
+	return &model.WorkoutStats{
+		WeightUnit:           model.WeightUnit(unit),
+		TotalVolume:          dbStats.TotalVolume,
+		TotalSets:            dbStats.TotalSets,
+		SessionCount:         dbStats.SessionCount,
+		ExerciseRoutineStats: exerciseRoutineStats,
+		MuscleGroupStats:     muscleGroupStats,
+		WorkoutRoutineID:     workoutRoutineID,
+		UserID:               userId,
+		Range:                rangeArg,
+	}, nil
+}
+
+// Weeks is the resolver for the weeks field.
+func (r *workoutStatsResolver) Weeks(ctx context.Context, obj *model.WorkoutStats) ([]*model.WeeklyStats, error) {
+	user, err := database.GetUserById(r.db(ctx), obj.UserID)
+	if err != nil {
+		return []*model.WeeklyStats{}, errors.From(err, "Error Getting Workout Stats")
+	}
+
+	dbWeeklyStats, err := database.GetWeeklyStats(r.db(ctx), obj.WorkoutRoutineID, obj.UserID, obj.Range.Start, obj.Range.End, user.WeekStart, string(obj.WeightUnit))
+	if err != nil {
+		return []*model.WeeklyStats{}, errors.From(err, "Error Getting Workout Stats")
+	}
+
+	weeklyStats := make([]*model.WeeklyStats, 0)
+	for _, ws := range dbWeeklyStats {
+		weeklyStats = append(weeklyStats, &model.WeeklyStats{
+			WeekStart:    ws.WeekStart,
+			SessionCount: ws.SessionCount,
+			TotalSets:    ws.TotalSets,
+			TotalVolume:  ws.TotalVolume,
+		})
+	}
+	return weeklyStats, nil
+}
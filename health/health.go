@@ -0,0 +1,107 @@
+// Package health answers the liveness and readiness probes of whatever runs
+// the api, so rolling updates only send traffic to instances that can serve it
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/migrate"
+	"gorm.io/gorm"
+)
+
+type status struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+func writeStatus(w http.ResponseWriter, code int, s status) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(s); err != nil {
+		log.Printf("error writing health status: %v", err)
+	}
+}
+
+// LivenessHandler answers as long as the process can serve http. It doesn't
+// look at the database, a database outage shouldn't get every instance
+// restarted
+type LivenessHandler struct{}
+
+func (h *LivenessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	writeStatus(w, http.StatusOK, status{Status: "ok"})
+}
+
+// ReadinessHandler answers once the database is reachable and every migration
+// has been applied, and stops answering when the database goes away
+type ReadinessHandler struct {
+	DB      *gorm.DB
+	Timeout time.Duration
+
+	// migrations don't come undone, so once they're seen applied only the
+	// ping runs
+	migrated atomic.Bool
+}
+
+func (h *ReadinessHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), h.Timeout)
+	defer cancel()
+
+	checks := map[string]string{"database": "ok", "migrations": "ok"}
+	ready := true
+
+	sqlDB, err := h.DB.DB()
+	if err == nil {
+		err = sqlDB.PingContext(ctx)
+	}
+	if err != nil {
+		log.Printf("readiness: database unreachable: %v", err)
+		checks["database"] = "unreachable"
+		checks["migrations"] = "unknown"
+		writeStatus(w, http.StatusServiceUnavailable, status{Status: "unavailable", Checks: checks})
+		return
+	}
+
+	if !h.migrated.Load() {
+		pending, err := pendingMigrations(h.DB.WithContext(ctx))
+		switch {
+		case err != nil:
+			log.Printf("readiness: error checking migrations: %v", err)
+			checks["migrations"] = "unknown"
+			ready = false
+		case len(pending) > 0:
+			checks["migrations"] = "pending: " + strings.Join(pending, ", ")
+			ready = false
+		default:
+			h.migrated.Store(true)
+		}
+	}
+
+	if !ready {
+		writeStatus(w, http.StatusServiceUnavailable, status{Status: "unavailable", Checks: checks})
+		return
+	}
+	writeStatus(w, http.StatusOK, status{Status: "ok", Checks: checks})
+}
+
+func pendingMigrations(db *gorm.DB) ([]string, error) {
+	migrator, err := migrate.New(db)
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := migrator.Pending()
+	if err != nil {
+		return nil, err
+	}
+	pending := make([]string, 0, len(migrations))
+	for _, m := range migrations {
+		pending = append(pending, m.String())
+	}
+	return pending, nil
+}
@@ -2,28 +2,53 @@ package helpers
 
 import (
 	"context"
-	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
 
 	"github.com/99designs/gqlgen/client"
 	"github.com/99designs/gqlgen/graphql"
 	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/graph-gophers/dataloader"
 	"github.com/neilZon/workout-logger-api/accesscontroller"
-	"github.com/neilZon/workout-logger-api/common"
+	"github.com/neilZon/workout-logger-api/cache"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
 	"github.com/neilZon/workout-logger-api/graph"
 	"github.com/neilZon/workout-logger-api/graph/generated"
+	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/integration"
+	"github.com/neilZon/workout-logger-api/live"
 	"github.com/neilZon/workout-logger-api/loader"
+	"github.com/neilZon/workout-logger-api/mail"
+	"github.com/neilZon/workout-logger-api/media"
 	"github.com/neilZon/workout-logger-api/middleware"
 	"github.com/neilZon/workout-logger-api/reader"
+	"github.com/neilZon/workout-logger-api/responsecache"
 	"github.com/neilZon/workout-logger-api/token"
-	"github.com/vektah/gqlparser/v2/gqlerror"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
+const VerifyUserQuery = `SELECT * FROM "users" WHERE id = $1 AND "users"."deleted_at" IS NULL ORDER BY "users"."id" LIMIT 1`
+const ExerciseForUserQuery = `SELECT * FROM "exercises" WHERE (id = $1 AND user_id = $2) AND "exercises"."deleted_at" IS NULL LIMIT 1`
 const WorkoutRoutineAccessQuery = `SELECT * FROM "workout_routines" WHERE id = $1 AND "workout_routines"."deleted_at" IS NULL ORDER BY "workout_routines"."id" LIMIT 1`
 const WorkoutSessionAccessQuery = `SELECT * FROM "workout_sessions" WHERE id = $1 AND "workout_sessions"."deleted_at" IS NULL ORDER BY "workout_sessions"."id" LIMIT 1`
+const TouchWorkoutRoutineStmt = `UPDATE "workout_routines" SET "updated_at"=$1 WHERE id = $2 AND "workout_routines"."deleted_at" IS NULL`
+const TouchExerciseStmt = `UPDATE "exercises" SET "updated_at"=$1 WHERE id = $2 AND "exercises"."deleted_at" IS NULL`
+const TouchExerciseSessionStmt = `UPDATE "workout_sessions" SET "updated_at"=$1 WHERE id = (SELECT workout_session_id FROM exercises WHERE id = $2) AND "workout_sessions"."deleted_at" IS NULL`
+const WorkoutSessionEditLockQuery = `SELECT start, (SELECT MAX(expires_at) FROM workout_session_unlocks WHERE workout_session_id = workout_sessions.id AND deleted_at IS NULL) AS unlocked_until FROM "workout_sessions" WHERE id = $1 AND "workout_sessions"."deleted_at" IS NULL LIMIT 1`
+const ExerciseRoutineCountQuery = `SELECT count(*) FROM "exercise_routines" WHERE (workout_routine_id = $1 AND id IN ($2)) AND "exercise_routines"."deleted_at" IS NULL`
+const LockExerciseRoutinesQuery = `SELECT "id" FROM "exercise_routines" WHERE (workout_routine_id = $1 AND id IN ($2,$3)) AND "exercise_routines"."deleted_at" IS NULL FOR SHARE`
+const QueueWebhookDeliveriesStmt = `INSERT INTO webhook_deliveries (created_at, updated_at, webhook_id, workout_session_id, attempts, next_attempt_at, last_error)`
+const QueueStravaUploadStmt = `INSERT INTO strava_uploads (created_at, updated_at, user_id, workout_session_id, attempts, next_attempt_at, last_error)`
+const LockOpenWorkoutSessionQuery = `SELECT "id" FROM "workout_sessions" WHERE (user_id = $1 AND "end" IS NULL) AND "workout_sessions"."deleted_at" IS NULL LIMIT 1 FOR UPDATE`
 
 func SetupMockDB() (sqlmock.Sqlmock, *gorm.DB) {
 	mockDb, mock, err := sqlmock.New()
@@ -38,22 +63,88 @@ func SetupMockDB() (sqlmock.Sqlmock, *gorm.DB) {
 	return mock, gormDB
 }
 
+// ExpectEditableWorkoutSession mocks the edit lock lookup for a session
+// recent enough to change
+func ExpectEditableWorkoutSession(mock sqlmock.Sqlmock, workoutSessionId uint) {
+	row := sqlmock.NewRows([]string{"start", "unlocked_until"}).AddRow(time.Now(), nil)
+	mock.ExpectQuery(regexp.QuoteMeta(WorkoutSessionEditLockQuery)).WithArgs(fmt.Sprintf("%d", workoutSessionId)).WillReturnRows(row)
+}
+
 func NewGqlServer(gormDB *gorm.DB, acs accesscontroller.AccessControllerService) *handler.Server {
-	srv := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{Resolvers: &graph.Resolver{
-		DB:  gormDB,
-		ACS: acs,
-	}}))
-
-	srv.SetErrorPresenter(func(ctx context.Context, e error) *gqlerror.Error {
-		err := graphql.DefaultErrorPresenter(ctx, e)
-		// add status code for unauthorized errors so client knows to refresh token
-		var unauthorizedError *common.UnauthorizedError
-		if errors.As(e, &unauthorizedError) {
-			err.Extensions = map[string]interface{}{
-				"code": "UNAUTHORIZED",
-			}
-		}
-		return err
+	return NewSharedGqlServer(gormDB, acs, cache.NewMemory(config.CACHE_SWEEP_INTERVAL), live.NewBroker(), media.NewLocalStore(os.Getenv(config.ARCHIVE_DIR), ""))
+}
+
+// NewSharedGqlServer is NewGqlServer with responses cached in store, which
+// instances sharing redis invalidate for each other, and live updates going
+// through broker so background jobs can end them too. archive is the store
+// the archiver and account exports write to
+func NewSharedGqlServer(gormDB *gorm.DB, acs accesscontroller.AccessControllerService, store cache.Cache, broker *live.Broker, archive media.Store) *handler.Server {
+	responseCache := responsecache.New(store, config.RESPONSE_CACHE_TTL)
+	srv := newServer(newSchema(&graph.Resolver{
+		DB:      gormDB,
+		ACS:     acs,
+		Live:    broker,
+		Media:   media.NewSignedLocalStore(os.Getenv(config.MEDIA_DIR), os.Getenv(config.HOST), "/media", os.Getenv(config.MEDIA_SIGNING_SECRET)),
+		Archive: archive,
+		Photos:  media.NewSignedLocalStore(os.Getenv(config.PHOTO_DIR), os.Getenv(config.HOST), "/photos", os.Getenv(config.PHOTO_SIGNING_SECRET)),
+		Strava:  integration.NewStrava(os.Getenv(config.STRAVA_CLIENT_ID), os.Getenv(config.STRAVA_CLIENT_SECRET), config.STRAVA_TIMEOUT),
+		Cache:   responseCache,
+		Mailer:  mail.NewMailer(),
+	}))
+
+	srv.SetErrorPresenter(errors.Present)
+	// resolvers count on their input objects having been checked
+	srv.Use(middleware.InputValidation{})
+	srv.Use(middleware.InvalidateResponseCache{Cache: responseCache})
+	return srv
+}
+
+// NewReadOnlyGqlServer answers queries only, gormDB is meant to be the read
+// replica. Input validation is left out since there's nothing to write
+func NewReadOnlyGqlServer(gormDB *gorm.DB, acs accesscontroller.AccessControllerService, archive media.Store) *handler.Server {
+	srv := newServer(newSchema(&graph.Resolver{
+		DB:      gormDB,
+		ACS:     acs,
+		Live:    live.NewBroker(),
+		Media:   media.NewSignedLocalStore(os.Getenv(config.MEDIA_DIR), os.Getenv(config.HOST), "/media", os.Getenv(config.MEDIA_SIGNING_SECRET)),
+		Archive: archive,
+		Photos:  media.NewSignedLocalStore(os.Getenv(config.PHOTO_DIR), os.Getenv(config.HOST), "/photos", os.Getenv(config.PHOTO_SIGNING_SECRET)),
+		Strava:  integration.NewStrava(os.Getenv(config.STRAVA_CLIENT_ID), os.Getenv(config.STRAVA_CLIENT_SECRET), config.STRAVA_TIMEOUT),
+	}))
+
+	srv.SetErrorPresenter(errors.Present)
+	srv.Use(middleware.ReadOnly{})
+	return srv
+}
+
+// newSchema wires the resolver's directives next to it
+func newSchema(resolver *graph.Resolver) graphql.ExecutableSchema {
+	return generated.NewExecutableSchema(generated.Config{
+		Resolvers:  resolver,
+		Directives: generated.DirectiveRoot{HasRole: resolver.HasRole},
+	})
+}
+
+// newServer is handler.NewDefaultServer with room for every document the app
+// sends. Apps send the sha256 of a document instead of the whole thing
+// (automatic persisted queries) and only send the document when it isn't
+// cached yet
+func newServer(es graphql.ExecutableSchema) *handler.Server {
+	srv := handler.New(es)
+
+	srv.AddTransport(transport.Websocket{
+		KeepAlivePingInterval: 10 * time.Second,
+	})
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.AddTransport(transport.MultipartForm{})
+
+	srv.SetQueryCache(lru.New(config.QUERY_CACHE_SIZE))
+
+	srv.Use(extension.Introspection{})
+	srv.Use(extension.AutomaticPersistedQuery{
+		Cache: lru.New(config.APQ_CACHE_SIZE),
 	})
 	return srv
 }
@@ -63,8 +154,19 @@ func NewGqlClient(gormDB *gorm.DB, acs accesscontroller.AccessControllerService)
 	return client.New(srv)
 }
 
-// NewLoaders instantiates data loaders for the middleware
+// NewLoaders instantiates data loaders for the middleware, each keeps what
+// it loads in memory
 func NewLoaders(gormDB *gorm.DB) *loader.Loaders {
+	return newLoaders(gormDB, nil)
+}
+
+// NewSharedLoaders is NewLoaders with what's loaded kept in store instead,
+// so instances sharing redis see each other's clears
+func NewSharedLoaders(gormDB *gorm.DB, store cache.Cache) *loader.Loaders {
+	return newLoaders(gormDB, store)
+}
+
+func newLoaders(gormDB *gorm.DB, store cache.Cache) *loader.Loaders {
 	exerciseRoutineReader := &reader.ExerciseRoutineReader{DB: gormDB}
 	exerciseRoutineNoCache := &dataloader.NoCache{}
 
@@ -76,12 +178,37 @@ func NewLoaders(gormDB *gorm.DB) *loader.Loaders {
 
 	exerciseSliceLoader := &reader.ExerciseSliceReader{DB: gormDB}
 
+	// what was logged last changes whenever a session is, so only a batch
+	// shares what it loads
+	prevExerciseSliceReader := &reader.PrevExerciseSliceReader{DB: gormDB}
+
+	catalogExerciseReader := &reader.CatalogExerciseReader{DB: gormDB}
+
+	tagSliceReader := &reader.TagSliceReader{DB: gormDB}
+
+	// the default in-memory cache unless there's a store to share
+	cached := func(prefix string, newValue func() interface{}) []dataloader.Option {
+		if store == nil {
+			return nil
+		}
+		return []dataloader.Option{dataloader.WithCache(loader.NewCache(store, prefix, config.LOADER_CACHE_TTL, newValue))}
+	}
+
 	loaders := &loader.Loaders{
-		ExerciseRoutineLoader:      dataloader.NewBatchedLoader(exerciseRoutineReader.GetExerciseRoutines, dataloader.WithCache(exerciseRoutineNoCache)),
-		SetEntrySliceLoader:        dataloader.NewBatchedLoader(setEntrySliceReader.GetSetEntrySlices),
-		WorkoutRoutineLoader:       dataloader.NewBatchedLoader(workoutRoutineReader.GetWorkoutRoutines),
-		ExerciseRoutineSliceLoader: dataloader.NewBatchedLoader(exerciseRoutineSliceLoader.GetExerciseRoutineSlices),
-		ExerciseSliceLoader:        dataloader.NewBatchedLoader(exerciseSliceLoader.GetExerciseSlices),
+		ExerciseRoutineLoader: dataloader.NewBatchedLoader(exerciseRoutineReader.GetExerciseRoutines, dataloader.WithCache(exerciseRoutineNoCache)),
+		SetEntrySliceLoader: dataloader.NewBatchedLoader(setEntrySliceReader.GetSetEntrySlices,
+			cached("set_entry_slice", func() interface{} { return new([]*model.SetEntry) })...),
+		WorkoutRoutineLoader: dataloader.NewBatchedLoader(workoutRoutineReader.GetWorkoutRoutines,
+			cached("workout_routine", func() interface{} { return new(*model.WorkoutRoutine) })...),
+		ExerciseRoutineSliceLoader: dataloader.NewBatchedLoader(exerciseRoutineSliceLoader.GetExerciseRoutineSlices,
+			cached("exercise_routine_slice", func() interface{} { return new([]*model.ExerciseRoutine) })...),
+		ExerciseSliceLoader: dataloader.NewBatchedLoader(exerciseSliceLoader.GetExerciseSlices,
+			cached("exercise_slice", func() interface{} { return new([]*model.Exercise) })...),
+		PrevExerciseSliceLoader: dataloader.NewBatchedLoader(prevExerciseSliceReader.GetPrevExerciseSlices, dataloader.WithCache(&dataloader.NoCache{})),
+		CatalogExerciseLoader: dataloader.NewBatchedLoader(catalogExerciseReader.GetCatalogExercises,
+			cached("catalog_exercise", func() interface{} { return new(*model.CatalogExercise) })...),
+		TagSliceLoader: dataloader.NewBatchedLoader(tagSliceReader.GetTagSlices,
+			cached("tag_slice", func() interface{} { return new([]string) })...),
 	}
 	return loaders
 }
@@ -93,3 +220,11 @@ func AddContext(u *token.Claims, l *loader.Loaders) client.Option {
 		bd.HTTP = bd.HTTP.WithContext(ctx)
 	}
 }
+
+// AddClient stands in for the client headers ClientMiddleware reads
+func AddClient(origin database.Origin) client.Option {
+	return func(bd *client.Request) {
+		ctx := context.WithValue(bd.HTTP.Context(), middleware.ClientCtxKey, origin)
+		bd.HTTP = bd.HTTP.WithContext(ctx)
+	}
+}
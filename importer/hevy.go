@@ -0,0 +1,98 @@
+package importer
+
+import (
+	"strings"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/database"
+)
+
+// Hevy has changed how it writes times, the first layout is the current one
+var hevyTimeLayouts = []string{
+	"2 Jan 2006, 15:04",
+	"Jan 2, 2006, 15:04",
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+}
+
+var hevySetTypes = map[string]string{
+	"normal":  database.SetTypeWorking,
+	"warmup":  database.SetTypeWarmup,
+	"dropset": database.SetTypeDropset,
+	"failure": database.SetTypeFailure,
+}
+
+// parseHevy reads Hevy's export, a row per set with the workout's start and
+// end repeated on each one. Weights are in weight_lbs or weight_kg depending
+// on the user's settings in Hevy
+func parseHevy(rows *csvRows, loc *time.Location, unit string) ([]database.ImportedWorkout, error) {
+	weightColumn, factor := "weight_kg", 1.0
+	if rows.has("weight_lbs") {
+		weightColumn = "weight_lbs"
+		if unit == database.WeightUnitKg {
+			factor = kgPerLb
+		}
+	} else if unit == database.WeightUnitLb {
+		factor = 1 / kgPerLb
+	}
+
+	b := workoutBuilder{}
+	for {
+		ok, err := rows.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return b.done(), nil
+		}
+
+		startTime := rows.get("start_time")
+		name := rows.get("title")
+		w, err := b.workout(startTime+"\x00"+name, func() (database.ImportedWorkout, error) {
+			start, ok := parseHevyTime(startTime, loc)
+			if !ok {
+				return database.ImportedWorkout{}, rows.errorf("start_time %q isn't a time", startTime)
+			}
+			end, ok := parseHevyTime(rows.get("end_time"), loc)
+			if !ok || end.Before(start) {
+				end = start
+			}
+			return database.ImportedWorkout{Name: name, Start: start, End: end}, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		setType, ok := hevySetTypes[strings.ToLower(rows.get("set_type"))]
+		if !ok {
+			setType = database.SetTypeWorking
+		}
+		reps, ok, err := parseReps(rows.get("reps"))
+		if err != nil {
+			return nil, rows.errorf("%s", err)
+		}
+		if !ok {
+			continue
+		}
+		weight, err := parseNumber(rows.get(weightColumn))
+		if err != nil {
+			return nil, rows.errorf("%s %q isn't a number", weightColumn, rows.get(weightColumn))
+		}
+
+		addSet(w, rows.get("exercise_title"), rows.get("exercise_notes"), database.ImportedSet{
+			Weight: database.RoundWeight(weight * factor),
+			Unit:   unit,
+			Reps:   reps,
+			Type:   setType,
+		})
+	}
+}
+
+func parseHevyTime(s string, loc *time.Location) (time.Time, bool) {
+	for _, layout := range hevyTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,183 @@
+// Package importer reads workout history exported by other workout apps so it
+// can be brought into the api, see database.ImportWorkoutHistory
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/database"
+)
+
+// formats line up with the graphql ImportFormat enum
+const (
+	FormatStrong = "STRONG"
+	FormatHevy   = "HEVY"
+)
+
+// ErrUnknownFormat is a file that isn't a Strong or Hevy csv export
+var ErrUnknownFormat = errors.New("not a Strong or Hevy csv export")
+
+const kgPerLb = 0.45359237
+
+// Parse reads a Strong or Hevy csv export, telling them apart by their
+// header. Times without a zone in them are taken to be in loc. Weights are
+// converted to unit when the export says what it's in, Strong doesn't so its
+// weights are kept as they are and taken to be in unit
+func Parse(r io.Reader, loc *time.Location, unit string) (string, []database.ImportedWorkout, error) {
+	br := bufio.NewReader(r)
+	// Strong uses semicolons in locales where the decimal separator is a comma
+	firstLine, err := br.Peek(br.Size())
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return "", nil, err
+	}
+	firstLine = bytes.TrimPrefix(firstLine, []byte("\ufeff"))
+	if i := bytes.IndexByte(firstLine, '\n'); i >= 0 {
+		firstLine = firstLine[:i]
+	}
+
+	reader := csv.NewReader(br)
+	reader.FieldsPerRecord = -1
+	if bytes.Count(firstLine, []byte(";")) > bytes.Count(firstLine, []byte(",")) {
+		reader.Comma = ';'
+	}
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return "", nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	columns := make(map[string]int, len(header))
+	for i, h := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(h, "\ufeff")))] = i
+	}
+
+	rows := csvRows{reader: reader, columns: columns}
+	switch {
+	case rows.has("workout name", "exercise name", "set order"):
+		workouts, err := parseStrong(&rows, loc, unit)
+		return FormatStrong, workouts, err
+	case rows.has("title", "start_time", "exercise_title", "set_type"):
+		workouts, err := parseHevy(&rows, loc, unit)
+		return FormatHevy, workouts, err
+	}
+	return "", nil, ErrUnknownFormat
+}
+
+// csvRows reads the rows of an export by column name
+type csvRows struct {
+	reader  *csv.Reader
+	columns map[string]int
+	record  []string
+	line    int
+}
+
+func (r *csvRows) has(columns ...string) bool {
+	for _, c := range columns {
+		if _, ok := r.columns[c]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *csvRows) next() (bool, error) {
+	record, err := r.reader.Read()
+	if err == io.EOF {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	r.record = record
+	r.line, _ = r.reader.FieldPos(0)
+	return true, nil
+}
+
+// get is the value in the column, empty when the export doesn't have it
+func (r *csvRows) get(column string) string {
+	i, ok := r.columns[column]
+	if !ok || i >= len(r.record) {
+		return ""
+	}
+	return strings.TrimSpace(r.record[i])
+}
+
+func (r *csvRows) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("line %d: %s", r.line, fmt.Sprintf(format, args...))
+}
+
+// parseNumber takes a comma as the decimal separator too
+func parseNumber(s string) (float64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+}
+
+// parseReps is false for rows that aren't sets of reps, like cardio
+func parseReps(s string) (uint, bool, error) {
+	if s == "" {
+		return 0, false, nil
+	}
+	reps, err := parseNumber(s)
+	if err != nil || reps < 0 {
+		return 0, false, fmt.Errorf("reps %q isn't a number", s)
+	}
+	return uint(reps), reps > 0, nil
+}
+
+// workoutBuilder puts rows together into workouts, the exports repeat the
+// workout on every set row and list a workout's sets together
+type workoutBuilder struct {
+	workouts []database.ImportedWorkout
+	key      string
+}
+
+// workout is the workout key belongs to, a new one when it's not the one the
+// last row was in
+func (b *workoutBuilder) workout(key string, start func() (database.ImportedWorkout, error)) (*database.ImportedWorkout, error) {
+	if key != b.key || len(b.workouts) == 0 {
+		w, err := start()
+		if err != nil {
+			return nil, err
+		}
+		b.workouts = append(b.workouts, w)
+		b.key = key
+	}
+	return &b.workouts[len(b.workouts)-1], nil
+}
+
+// addSet goes on the exercise the last set was on when it's the same one
+func addSet(w *database.ImportedWorkout, exercise string, notes string, set database.ImportedSet) {
+	n := len(w.Exercises)
+	if n == 0 || w.Exercises[n-1].Name != exercise {
+		w.Exercises = append(w.Exercises, database.ImportedExercise{Name: exercise})
+		n++
+	}
+	e := &w.Exercises[n-1]
+	if e.Notes == "" {
+		e.Notes = notes
+	}
+	e.Sets = append(e.Sets, set)
+}
+
+// done drops workouts that had no sets of reps in them
+func (b *workoutBuilder) done() []database.ImportedWorkout {
+	workouts := make([]database.ImportedWorkout, 0, len(b.workouts))
+	for _, w := range b.workouts {
+		if len(w.Exercises) > 0 {
+			workouts = append(workouts, w)
+		}
+	}
+	return workouts
+}
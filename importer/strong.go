@@ -0,0 +1,102 @@
+package importer
+
+import (
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/database"
+)
+
+const strongDateLayout = "2006-01-02 15:04:05"
+
+// what Strong puts in Set Order instead of a number for sets that aren't
+// working sets
+var strongSetTypes = map[string]string{
+	"W": database.SetTypeWarmup,
+	"D": database.SetTypeDropset,
+	"F": database.SetTypeFailure,
+}
+
+// parseStrong reads Strong's export, a row per set with the workout's start
+// and duration repeated on each one. It doesn't say what its weights are in,
+// they're taken to be in unit
+func parseStrong(rows *csvRows, loc *time.Location, unit string) ([]database.ImportedWorkout, error) {
+	b := workoutBuilder{}
+	for {
+		ok, err := rows.next()
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return b.done(), nil
+		}
+
+		date := rows.get("date")
+		name := rows.get("workout name")
+		w, err := b.workout(date+"\x00"+name, func() (database.ImportedWorkout, error) {
+			start, err := time.ParseInLocation(strongDateLayout, date, loc)
+			if err != nil {
+				return database.ImportedWorkout{}, rows.errorf("date %q isn't a date", date)
+			}
+			duration, err := parseStrongDuration(rows.get("duration"))
+			if err != nil {
+				return database.ImportedWorkout{}, rows.errorf("duration %q isn't a duration", rows.get("duration"))
+			}
+			return database.ImportedWorkout{Name: name, Start: start, End: start.Add(duration)}, nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// newer exports have rows for rest timers between sets
+		setType := database.SetTypeWorking
+		order := rows.get("set order")
+		if t, ok := strongSetTypes[order]; ok {
+			setType = t
+		} else if _, err := strconv.Atoi(order); err != nil {
+			continue
+		}
+
+		reps, ok, err := parseReps(rows.get("reps"))
+		if err != nil {
+			return nil, rows.errorf("%s", err)
+		}
+		if !ok {
+			continue
+		}
+		weight, err := parseNumber(rows.get("weight"))
+		if err != nil {
+			return nil, rows.errorf("weight %q isn't a number", rows.get("weight"))
+		}
+
+		addSet(w, rows.get("exercise name"), rows.get("notes"), database.ImportedSet{
+			Weight: database.RoundWeight(weight),
+			Unit:   unit,
+			Reps:   reps,
+			Type:   setType,
+		})
+	}
+}
+
+var strongDurationPart = regexp.MustCompile(`(\d+)\s*([hms])`)
+
+// parseStrongDuration reads durations like "1h 5m" and "45m"
+func parseStrongDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	parts := strongDurationPart.FindAllStringSubmatch(s, -1)
+	if len(parts) == 0 {
+		// older exports have it in seconds
+		seconds, err := strconv.Atoi(s)
+		return time.Duration(seconds) * time.Second, err
+	}
+	var d time.Duration
+	units := map[string]time.Duration{"h": time.Hour, "m": time.Minute, "s": time.Second}
+	for _, p := range parts {
+		n, _ := strconv.Atoi(p[1])
+		d += time.Duration(n) * units[p[2]]
+	}
+	return d, nil
+}
@@ -0,0 +1,305 @@
+package integration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/clock"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/utils"
+	"gorm.io/gorm"
+)
+
+const StravaBaseURL = "https://www.strava.com"
+
+// what finished sessions show up as on Strava
+const StravaSportType = "WeightTraining"
+
+// ErrStravaUnauthorized is when Strava turns an access token away, it was
+// revoked or expired early
+var ErrStravaUnauthorized = errors.New("strava rejected the access token")
+
+// Strava talks to the Strava api as our api app
+type Strava struct {
+	BaseURL      string
+	ClientID     string
+	ClientSecret string
+	Client       *http.Client
+}
+
+func NewStrava(clientId string, clientSecret string, timeout time.Duration) *Strava {
+	return &Strava{
+		BaseURL:      StravaBaseURL,
+		ClientID:     clientId,
+		ClientSecret: clientSecret,
+		Client:       &http.Client{Timeout: timeout},
+	}
+}
+
+// Enabled is whether the api app is set up, nobody can connect without it
+func (s *Strava) Enabled() bool {
+	return s != nil && s.ClientID != "" && s.ClientSecret != ""
+}
+
+// StravaTokens are what Strava hands back for an authorization code or a
+// refresh token
+type StravaTokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresAt    int64  `json:"expires_at"`
+	Athlete      struct {
+		ID int64 `json:"id"`
+	} `json:"athlete"`
+}
+
+func (t *StravaTokens) Expiry() time.Time {
+	return time.Unix(t.ExpiresAt, 0).UTC()
+}
+
+// ExchangeCode swaps the code Strava redirected the app back with for the
+// athlete's tokens
+func (s *Strava) ExchangeCode(code string) (*StravaTokens, error) {
+	return s.token(url.Values{
+		"code":       {code},
+		"grant_type": {"authorization_code"},
+	})
+}
+
+// RefreshTokens gets a new access token, Strava may hand back a new refresh
+// token with it which replaces the old one
+func (s *Strava) RefreshTokens(refreshToken string) (*StravaTokens, error) {
+	return s.token(url.Values{
+		"refresh_token": {refreshToken},
+		"grant_type":    {"refresh_token"},
+	})
+}
+
+func (s *Strava) token(form url.Values) (*StravaTokens, error) {
+	form.Set("client_id", s.ClientID)
+	form.Set("client_secret", s.ClientSecret)
+
+	var tokens StravaTokens
+	if err := s.post("/oauth/token", "", form, &tokens); err != nil {
+		return nil, err
+	}
+	return &tokens, nil
+}
+
+// Deauthorize revokes our access to the athlete's account
+func (s *Strava) Deauthorize(accessToken string) error {
+	return s.post("/oauth/deauthorize", accessToken, url.Values{}, nil)
+}
+
+// StravaActivity is a manually created activity
+type StravaActivity struct {
+	Name           string
+	Start          time.Time
+	ElapsedSeconds int64
+	Description    string
+}
+
+// CreateActivity adds the activity to the athlete's account and returns its
+// id
+func (s *Strava) CreateActivity(accessToken string, activity *StravaActivity) (int64, error) {
+	form := url.Values{
+		"name":             {activity.Name},
+		"type":             {StravaSportType},
+		"sport_type":       {StravaSportType},
+		"start_date_local": {activity.Start.Format(time.RFC3339)},
+		"elapsed_time":     {strconv.FormatInt(activity.ElapsedSeconds, 10)},
+		"description":      {activity.Description},
+	}
+
+	var created struct {
+		ID int64 `json:"id"`
+	}
+	if err := s.post("/api/v3/activities", accessToken, form, &created); err != nil {
+		return 0, err
+	}
+	return created.ID, nil
+}
+
+func (s *Strava) post(path string, accessToken string, form url.Values, out interface{}) error {
+	req, err := http.NewRequest(http.MethodPost, s.BaseURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized {
+		return ErrStravaUnauthorized
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("strava responded %s: %s", resp.Status, body)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// NewStravaActivity is a finished session as a weight training activity,
+// every exercise gets a line with its working sets
+func NewStravaActivity(ws *database.WorkoutSession) *StravaActivity {
+	finished := NewWorkoutSessionFinished(ws)
+
+	lines := []string{}
+	for _, e := range finished.Exercises {
+		sets := []string{}
+		for _, s := range e.Sets {
+			if s.Type == database.SetTypeWarmup {
+				continue
+			}
+			sets = append(sets, fmt.Sprintf("%g%sx%d", s.Weight, strings.ToLower(s.Unit), s.Reps))
+		}
+		if len(sets) == 0 {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", e.Name, strings.Join(sets, ", ")))
+	}
+	lines = append(lines, fmt.Sprintf("Total volume: %g%s", finished.TotalVolume, strings.ToLower(finished.VolumeUnit)))
+
+	name := finished.WorkoutRoutine
+	if name == "" {
+		name = "Weight Training"
+	}
+	return &StravaActivity{
+		Name:           name,
+		Start:          finished.Start,
+		ElapsedSeconds: finished.DurationSeconds,
+		Description:    strings.Join(lines, "\n"),
+	}
+}
+
+// StravaSyncer uploads finished sessions of users connected to Strava,
+// refreshing their access tokens as they run out. failed uploads, failed
+// refreshes included, are tried again with backoff until MaxAttempts
+type StravaSyncer struct {
+	DB          *gorm.DB
+	Strava      *Strava
+	BatchSize   int
+	MaxAttempts int
+	// how long after the first failed attempt to try again, doubled after
+	// every failure after that
+	Backoff time.Duration
+	// tokens with less than this left are refreshed before uploading
+	RefreshBefore time.Duration
+}
+
+func NewStravaSyncer(db *gorm.DB, strava *Strava, batchSize int, maxAttempts int, backoff time.Duration, refreshBefore time.Duration) *StravaSyncer {
+	return &StravaSyncer{
+		DB:            db,
+		Strava:        strava,
+		BatchSize:     batchSize,
+		MaxAttempts:   maxAttempts,
+		Backoff:       backoff,
+		RefreshBefore: refreshBefore,
+	}
+}
+
+// Start uploads due sessions every interval until stop is closed
+func (s *StravaSyncer) Start(interval time.Duration, stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				uploaded, err := s.SyncDue(clock.Now())
+				if err != nil {
+					log.Printf("error syncing to strava: %v", err)
+				} else if uploaded > 0 {
+					log.Printf("uploaded %d workout sessions to strava", uploaded)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// SyncDue makes one attempt at every upload that's due and returns how many
+// went through. a failed upload is only logged against itself, it doesn't
+// stop the rest
+func (s *StravaSyncer) SyncDue(now time.Time) (int, error) {
+	uploads, err := database.GetDueStravaUploads(s.DB, now, s.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	uploaded := 0
+	for i := range uploads {
+		upload := &uploads[i]
+		activityId, err := s.upload(upload, now)
+		if err == nil {
+			if err := database.MarkStravaUploaded(s.DB, upload.ID, activityId, now); err != nil {
+				return uploaded, err
+			}
+			uploaded++
+			continue
+		}
+
+		var next *time.Time
+		if attempts := int(upload.Attempts) + 1; attempts < s.MaxAttempts && !errors.Is(err, gorm.ErrRecordNotFound) {
+			at := now.Add(s.Backoff << (attempts - 1))
+			next = &at
+		} else {
+			log.Printf("giving up on strava upload %d after %d attempts: %v", upload.ID, attempts, err)
+		}
+		if err := database.MarkStravaUploadFailed(s.DB, upload.ID, next, err.Error()); err != nil {
+			return uploaded, err
+		}
+	}
+	return uploaded, nil
+}
+
+func (s *StravaSyncer) upload(upload *database.StravaUpload, now time.Time) (int64, error) {
+	// the user disconnected or the session is gone, there's nothing to retry
+	connection, err := database.GetStravaConnection(s.DB, utils.UIntToString(upload.UserID))
+	if err != nil {
+		return 0, fmt.Errorf("getting strava connection: %w", err)
+	}
+	ws, err := database.GetFinishedWorkoutSession(s.DB, upload.WorkoutSessionID)
+	if err != nil {
+		return 0, fmt.Errorf("getting workout session: %w", err)
+	}
+
+	accessToken := connection.AccessToken
+	if connection.ExpiresAt.Before(now.Add(s.RefreshBefore)) {
+		tokens, err := s.Strava.RefreshTokens(connection.RefreshToken)
+		if err != nil {
+			return 0, fmt.Errorf("refreshing strava token: %v", err)
+		}
+		err = database.UpdateStravaTokens(s.DB, connection.ID, tokens.AccessToken, tokens.RefreshToken, tokens.Expiry())
+		if err != nil {
+			return 0, err
+		}
+		accessToken = tokens.AccessToken
+	}
+
+	activityId, err := s.Strava.CreateActivity(accessToken, NewStravaActivity(ws))
+	if err == ErrStravaUnauthorized {
+		// refreshed on the next attempt
+		if err := database.ExpireStravaToken(s.DB, connection.ID, now); err != nil {
+			return 0, err
+		}
+	}
+	return activityId, err
+}
@@ -0,0 +1,245 @@
+package integration
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/clock"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/utils"
+	"github.com/neilZon/workout-logger-api/validator"
+	"gorm.io/gorm"
+)
+
+// receivers check SignatureHeader against the raw body with their webhook's
+// secret, see Sign
+const (
+	SignatureHeader = "X-Until-Failure-Signature"
+	EventHeader     = "X-Until-Failure-Event"
+
+	WorkoutSessionFinishedEvent = "workout_session.finished"
+)
+
+// WorkoutSessionFinished is what gets posted when a session is finished.
+// warm up sets are listed but don't count towards volume, same as the stats.
+// volume is in the unit the session's first set was logged in
+type WorkoutSessionFinished struct {
+	Event            string            `json:"event"`
+	WorkoutSessionID string            `json:"workoutSessionId"`
+	WorkoutRoutine   string            `json:"workoutRoutine"`
+	Start            time.Time         `json:"start"`
+	End              time.Time         `json:"end"`
+	DurationSeconds  int64             `json:"durationSeconds"`
+	TotalVolume      float64           `json:"totalVolume"`
+	VolumeUnit       string            `json:"volumeUnit"`
+	Exercises        []WebhookExercise `json:"exercises"`
+}
+
+type WebhookExercise struct {
+	Name   string       `json:"name"`
+	Volume float64      `json:"volume"`
+	Sets   []WebhookSet `json:"sets"`
+}
+
+type WebhookSet struct {
+	Weight float64 `json:"weight"`
+	Unit   string  `json:"unit"`
+	Reps   uint    `json:"reps"`
+	Type   string  `json:"type"`
+}
+
+// NewWorkoutSessionFinished builds the payload for a session loaded with
+// database.GetFinishedWorkoutSession
+func NewWorkoutSessionFinished(ws *database.WorkoutSession) *WorkoutSessionFinished {
+	payload := WorkoutSessionFinished{
+		Event:            WorkoutSessionFinishedEvent,
+		WorkoutSessionID: utils.UIntToString(ws.ID),
+		WorkoutRoutine:   ws.WorkoutRoutine.Name,
+		Start:            ws.Start,
+		End:              *ws.End,
+		DurationSeconds:  int64(ws.End.Sub(ws.Start).Seconds()),
+		Exercises:        []WebhookExercise{},
+	}
+
+	for _, e := range ws.Exercises {
+		exercise := WebhookExercise{
+			Name: e.ExerciseRoutine.Name,
+			Sets: []WebhookSet{},
+		}
+		for _, s := range e.Sets {
+			exercise.Sets = append(exercise.Sets, WebhookSet{
+				Weight: s.Weight,
+				Unit:   s.Unit,
+				Reps:   s.Reps,
+				Type:   s.Type,
+			})
+			if payload.VolumeUnit == "" {
+				payload.VolumeUnit = s.Unit
+			}
+			if s.Type != database.SetTypeWarmup {
+				exercise.Volume += database.ConvertWeight(float64(s.Weight), s.Unit, payload.VolumeUnit) * float64(s.Reps)
+			}
+		}
+		payload.TotalVolume += exercise.Volume
+		payload.Exercises = append(payload.Exercises, exercise)
+	}
+	return &payload
+}
+
+// Sign is the value of SignatureHeader, t is when it was sent in unix
+// seconds and v1 the hex HMAC-SHA256 of "t.body" with the webhook's secret.
+// receivers should turn away old timestamps so posts can't be replayed
+func Sign(secret string, sentAt time.Time, body []byte) string {
+	t := strconv.FormatInt(sentAt.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t + "."))
+	mac.Write(body)
+	return fmt.Sprintf("t=%s,v1=%s", t, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// Deliverer posts finished sessions to the webhooks users registered,
+// retrying failed posts with backoff until MaxAttempts
+type Deliverer struct {
+	DB          *gorm.DB
+	Client      *http.Client
+	BatchSize   int
+	MaxAttempts int
+	// how long after the first failed attempt to try again, doubled after
+	// every failure after that
+	Backoff time.Duration
+}
+
+func NewDeliverer(db *gorm.DB, timeout time.Duration, batchSize int, maxAttempts int, backoff time.Duration) *Deliverer {
+	return &Deliverer{
+		DB:          db,
+		Client:      NewWebhookClient(timeout),
+		BatchSize:   batchSize,
+		MaxAttempts: maxAttempts,
+		Backoff:     backoff,
+	}
+}
+
+// NewWebhookClient only connects to public addresses and doesn't follow
+// redirects, so a webhook can't be used to reach the server's network. The
+// address is checked as it's dialed, after the host resolves, so pointing a
+// registered host somewhere else later doesn't get around it either
+func NewWebhookClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout, Control: publicOnly}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would be the address checked instead of the webhook's
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+func publicOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !validator.IsPublicIP(ip) {
+		return fmt.Errorf("%s isn't a public address", host)
+	}
+	return nil
+}
+
+// Start delivers due webhooks every interval until stop is closed
+func (d *Deliverer) Start(interval time.Duration, stop <-chan struct{}) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				delivered, err := d.DeliverDue(clock.Now())
+				if err != nil {
+					log.Printf("error delivering webhooks: %v", err)
+				} else if delivered > 0 {
+					log.Printf("delivered %d webhooks", delivered)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// DeliverDue makes one attempt at every delivery that's due and returns how
+// many went through. a failed post is only logged against its delivery, it
+// doesn't stop the rest
+func (d *Deliverer) DeliverDue(now time.Time) (int, error) {
+	deliveries, err := database.GetDueWebhookDeliveries(d.DB, now, d.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for i := range deliveries {
+		delivery := &deliveries[i]
+		err := d.deliver(delivery, now)
+		if err == nil {
+			if err := database.MarkWebhookDelivered(d.DB, delivery.ID, now); err != nil {
+				return delivered, err
+			}
+			delivered++
+			continue
+		}
+
+		var next *time.Time
+		if attempts := int(delivery.Attempts) + 1; attempts < d.MaxAttempts {
+			at := now.Add(d.Backoff << (attempts - 1))
+			next = &at
+		} else {
+			log.Printf("giving up on webhook delivery %d after %d attempts: %v", delivery.ID, attempts, err)
+		}
+		if err := database.MarkWebhookFailed(d.DB, delivery.ID, next, err.Error()); err != nil {
+			return delivered, err
+		}
+	}
+	return delivered, nil
+}
+
+func (d *Deliverer) deliver(delivery *database.WebhookDelivery, now time.Time) error {
+	ws, err := database.GetFinishedWorkoutSession(d.DB, delivery.WorkoutSessionID)
+	if err != nil {
+		return fmt.Errorf("getting workout session: %w", err)
+	}
+	body, err := json.Marshal(NewWorkoutSessionFinished(ws))
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, delivery.Webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, WorkoutSessionFinishedEvent)
+	req.Header.Set(SignatureHeader, Sign(delivery.Webhook.Secret, now, body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package live
+
+import (
+	"context"
+	"sync"
+
+	"github.com/neilZon/workout-logger-api/graph/model"
+)
+
+// how many updates a spectator can fall behind before we start dropping them
+const subscriberBuffer = 16
+
+// Broker fans out set updates for a workout session to everyone watching it
+// through a share link
+type Broker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan *model.LiveSetUpdate]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		subscribers: map[string]map[chan *model.LiveSetUpdate]struct{}{},
+	}
+}
+
+// Subscribe returns a channel of updates for the workout session that gets
+// cleaned up when ctx is done or the session is closed
+func (b *Broker) Subscribe(ctx context.Context, workoutSessionId string) <-chan *model.LiveSetUpdate {
+	ch := make(chan *model.LiveSetUpdate, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subscribers[workoutSessionId] == nil {
+		b.subscribers[workoutSessionId] = map[chan *model.LiveSetUpdate]struct{}{}
+	}
+	b.subscribers[workoutSessionId][ch] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.unsubscribe(workoutSessionId, ch)
+	}()
+
+	return ch
+}
+
+// Publish sends the update to every spectator of the workout session, slow
+// spectators miss updates instead of blocking the mutation
+func (b *Broker) Publish(workoutSessionId string, update *model.LiveSetUpdate) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[workoutSessionId] {
+		select {
+		case ch <- update:
+		default:
+		}
+	}
+}
+
+// Close ends every subscription to the workout session
+func (b *Broker) Close(workoutSessionId string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[workoutSessionId] {
+		close(ch)
+	}
+	delete(b.subscribers, workoutSessionId)
+}
+
+func (b *Broker) unsubscribe(workoutSessionId string, ch chan *model.LiveSetUpdate) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// already closed if the session ended first
+	if _, ok := b.subscribers[workoutSessionId][ch]; !ok {
+		return
+	}
+	close(ch)
+	delete(b.subscribers[workoutSessionId], ch)
+	if len(b.subscribers[workoutSessionId]) == 0 {
+		delete(b.subscribers, workoutSessionId)
+	}
+}
@@ -0,0 +1,119 @@
+package loader
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/graph-gophers/dataloader"
+	"github.com/neilZon/workout-logger-api/cache"
+)
+
+// Cache is a dataloader.Cache kept in a cache.Cache, so with redis what one
+// instance loads the others can use and what one clears is cleared for all
+// of them. Results are stored as json once their batch comes back, errors
+// aren't stored
+type Cache struct {
+	store  cache.Cache
+	prefix string
+	ttl    time.Duration
+	// a pointer to what the loader returns, for decoding into
+	newValue func() interface{}
+
+	mu sync.Mutex
+	// loads still waiting on their batch, other loads of the same key share
+	// them instead of asking for it again
+	pending map[string]*pendingLoad
+}
+
+type pendingLoad struct {
+	thunk dataloader.Thunk
+}
+
+var _ dataloader.Cache = &Cache{}
+
+// NewCache keys the loader's results with prefix in store. newValue is like
+// func() interface{} { return new([]*model.SetEntry) }
+func NewCache(store cache.Cache, prefix string, ttl time.Duration, newValue func() interface{}) *Cache {
+	return &Cache{
+		store:    store,
+		prefix:   prefix,
+		ttl:      ttl,
+		newValue: newValue,
+		pending:  map[string]*pendingLoad{},
+	}
+}
+
+func (c *Cache) Get(ctx context.Context, key dataloader.Key) (dataloader.Thunk, bool) {
+	c.mu.Lock()
+	load, ok := c.pending[key.String()]
+	c.mu.Unlock()
+	if ok {
+		return load.thunk, true
+	}
+
+	b, ok := c.store.Get(c.key(key))
+	if !ok {
+		return nil, false
+	}
+	value := c.newValue()
+	if err := json.Unmarshal(b, value); err != nil {
+		return nil, false
+	}
+	data := reflect.ValueOf(value).Elem().Interface()
+	return func() (interface{}, error) {
+		return data, nil
+	}, true
+}
+
+func (c *Cache) Set(ctx context.Context, key dataloader.Key, thunk dataloader.Thunk) {
+	load := &pendingLoad{thunk: thunk}
+	c.mu.Lock()
+	c.pending[key.String()] = load
+	c.mu.Unlock()
+
+	go func() {
+		data, err := thunk()
+
+		c.mu.Lock()
+		current, ok := c.pending[key.String()]
+		// deleted while the batch ran, what it read may be from before the
+		// change that deleted it
+		stale := !ok || current != load
+		if !stale {
+			delete(c.pending, key.String())
+		}
+		c.mu.Unlock()
+		if stale || err != nil {
+			return
+		}
+
+		b, err := json.Marshal(data)
+		if err != nil {
+			return
+		}
+		c.store.Set(c.key(key), b, c.ttl)
+	}()
+}
+
+func (c *Cache) Delete(ctx context.Context, key dataloader.Key) bool {
+	c.mu.Lock()
+	delete(c.pending, key.String())
+	c.mu.Unlock()
+	c.store.Delete(c.key(key))
+	return true
+}
+
+// Clear only forgets the loads waiting on a batch, what's in the store stays
+// until it expires since redis can't find every key with the prefix cheaply
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	c.pending = map[string]*pendingLoad{}
+	c.mu.Unlock()
+}
+
+func (c *Cache) key(key dataloader.Key) string {
+	return "loader:" + c.prefix + ":" + key.String()
+}
@@ -10,5 +10,8 @@ type Loaders struct {
 	ExerciseRoutineLoader      *dataloader.Loader
 	ExerciseRoutineSliceLoader *dataloader.Loader
 	ExerciseSliceLoader        *dataloader.Loader
+	PrevExerciseSliceLoader    *dataloader.Loader
 	SetEntrySliceLoader        *dataloader.Loader
+	CatalogExerciseLoader      *dataloader.Loader
+	TagSliceLoader             *dataloader.Loader
 }
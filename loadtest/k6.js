@@ -0,0 +1,49 @@
+// Runs the scenarios `go run ./cmd/loadtest -format k6` wrote, each on its own
+// arrival rate. SCENARIOS points somewhere else than loadtest/scenarios.json
+// and RATE changes requests a second per scenario.
+//
+//   k6 run loadtest/k6.js
+//   k6 run -e RATE=100 -e DURATION=2m loadtest/k6.js
+import http from 'k6/http';
+import { check } from 'k6';
+import { SharedArray } from 'k6/data';
+
+const file = __ENV.SCENARIOS || './scenarios.json';
+const names = ['workout_sessions', 'add_set', 'login'];
+const requests = {};
+for (const name of names) {
+  requests[name] = new SharedArray(name, () => JSON.parse(open(file)).scenarios[name]);
+}
+
+const rate = parseInt(__ENV.RATE || '20');
+const duration = __ENV.DURATION || '30s';
+
+export const options = {
+  scenarios: Object.fromEntries(names.map((name) => [name, {
+    executor: 'constant-arrival-rate',
+    exec: 'run',
+    env: { SCENARIO: name },
+    // logins are bcrypt bound, a tenth of the rate is plenty
+    rate: name === 'login' ? Math.max(1, Math.floor(rate / 10)) : rate,
+    timeUnit: '1s',
+    duration,
+    preAllocatedVUs: 20,
+    maxVUs: 200,
+    tags: { scenario: name },
+  }])),
+  thresholds: {
+    'http_req_duration{scenario:workout_sessions}': ['p(95)<500'],
+    'http_req_duration{scenario:add_set}': ['p(95)<300'],
+    'http_req_failed': ['rate<0.01'],
+  },
+};
+
+export function run() {
+  const list = requests[__ENV.SCENARIO];
+  const r = list[Math.floor(Math.random() * list.length)];
+  const res = http.request(r.method, r.url, r.body, { headers: r.headers });
+  check(res, {
+    'status is 200': (res) => res.status === 200,
+    'no graphql errors': (res) => !res.json('errors'),
+  });
+}
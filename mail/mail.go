@@ -2,11 +2,12 @@ package mail
 
 import (
 	"bytes"
+	"embed"
 	"errors"
 	"fmt"
 	"net/smtp"
+	"net/url"
 	"os"
-	"path/filepath"
 	"text/template"
 
 	"github.com/neilZon/workout-logger-api/config"
@@ -38,6 +39,18 @@ func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
 	return nil, nil
 }
 
+// Mailer sends an email with an html body, SMTP is the one that goes out
+type Mailer interface {
+	Send(to []string, subject string, body string) error
+}
+
+// SMTP sends through the EMAIL account's smtp server
+type SMTP struct{}
+
+func (SMTP) Send(to []string, subject string, body string) error {
+	return sendEmail(to, subject, body)
+}
+
 func sendEmail(to []string, subject_line string, body string) error {
 	from := os.Getenv(config.EMAIL)
 	pass := os.Getenv(config.APP_PASSWORD)
@@ -54,8 +67,13 @@ func sendEmail(to []string, subject_line string, body string) error {
 	return nil
 }
 
+// templates are built in so emails don't depend on the working directory
+//
+//go:embed *.html
+var templates embed.FS
+
 func parseTemplate(templateFileName string, data interface{}) (string, error) {
-	t, err := template.ParseFiles(templateFileName)
+	t, err := template.ParseFS(templates, templateFileName)
 	if err != nil {
 		return "", err
 	}
@@ -75,17 +93,58 @@ func SendVerificationCode(code string, recipient string) error {
 		Link: fmt.Sprintf("%s/verify?code=%s", host, code),
 	}
 
-	abs, err := filepath.Abs("./mail/email-verification-template.html")
+	body, err := parseTemplate("email-verification-template.html", templateData)
 	if err != nil {
 		return err
 	}
 
-	body, err := parseTemplate(abs, templateData)
+	err = sendEmail([]string{recipient}, "Email Verification", body)
 	if err != nil {
 		return err
 	}
 
-	err = sendEmail([]string{recipient}, "Email Verification", body)
+	return nil
+}
+
+// SendPasswordResetLink emails the link for resetPassword, the redirect page
+// hands the token to the app
+func SendPasswordResetLink(m Mailer, token string, recipient string) error {
+	host := os.Getenv(config.HOST)
+
+	templateData := struct {
+		Link string
+	}{
+		Link: fmt.Sprintf("%s/static/password-redirect.html?code=%s", host, url.QueryEscape(token)),
+	}
+
+	body, err := parseTemplate("reset-password-template.html", templateData)
+	if err != nil {
+		return err
+	}
+
+	err = m.Send([]string{recipient}, "Til Failure Password Reset", body)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func SendGoalCompleted(name string, goal string, recipient string) error {
+	templateData := struct {
+		Name string
+		Goal string
+	}{
+		Name: name,
+		Goal: goal,
+	}
+
+	body, err := parseTemplate("goal-completed-template.html", templateData)
+	if err != nil {
+		return err
+	}
+
+	err = sendEmail([]string{recipient}, "Goal Completed", body)
 	if err != nil {
 		return err
 	}
@@ -93,29 +152,59 @@ func SendVerificationCode(code string, recipient string) error {
 	return nil
 }
 
-func SendResetLink(code string, recipient string) error {
+func SendEmailChangeLink(code string, recipient string) error {
 	host := os.Getenv(config.HOST)
 
 	templateData := struct {
 		Link string
 	}{
-		Link: fmt.Sprintf("%s/static/password-redirect.html?code=%s", host, code),
+		Link: fmt.Sprintf("%s/static/email-change-redirect.html?code=%s", host, code),
+	}
+
+	body, err := parseTemplate("email-change-template.html", templateData)
+	if err != nil {
+		return err
 	}
 
-	abs, err := filepath.Abs("./mail/reset-password-template.html")
+	err = sendEmail([]string{recipient}, "Confirm Your New Email", body)
 	if err != nil {
 		return err
 	}
 
-	body, err := parseTemplate(abs, templateData)
+	return nil
+}
+
+// SendEmailChanged lets the old address know the account moved to email
+func SendEmailChanged(email string, recipient string) error {
+	templateData := struct {
+		Email string
+	}{
+		Email: email,
+	}
+
+	body, err := parseTemplate("email-changed-template.html", templateData)
 	if err != nil {
 		return err
 	}
 
-	err = sendEmail([]string{recipient}, "Til Failure Password Reset", body)
+	err = sendEmail([]string{recipient}, "Your Email Was Changed", body)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// WeeklyDigest is a user's week of training in words
+type WeeklyDigest struct {
+	Name         string
+	Sessions     int
+	Sets         int
+	Volume       string
+	VolumeChange string // like "up 12% on the week before", empty without a week before
+	Records      []string
+}
+
+func WeeklyDigestBody(d *WeeklyDigest) (string, error) {
+	return parseTemplate("weekly-digest-template.html", d)
+}
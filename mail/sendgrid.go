@@ -0,0 +1,96 @@
+package mail
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/config"
+)
+
+const SendGridBaseURL = "https://api.sendgrid.com"
+
+// SendGrid sends through SendGrid's api from the EMAIL address, which has to
+// be a verified sender there
+type SendGrid struct {
+	BaseURL string
+	APIKey  string
+	From    string
+	Client  *http.Client
+}
+
+func NewSendGrid(apiKey string, from string, timeout time.Duration) *SendGrid {
+	return &SendGrid{
+		BaseURL: SendGridBaseURL,
+		APIKey:  apiKey,
+		From:    from,
+		Client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// NewMailer is SendGrid when SENDGRID_API_KEY is set and SMTP otherwise
+func NewMailer() Mailer {
+	if key := os.Getenv(config.SENDGRID_API_KEY); key != "" {
+		return NewSendGrid(key, os.Getenv(config.EMAIL), config.SENDGRID_TIMEOUT)
+	}
+	return SMTP{}
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+}
+
+type sendGridPersonalization struct {
+	To []sendGridAddress `json:"to"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridMessage struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+}
+
+func (s *SendGrid) Send(to []string, subject string, body string) error {
+	recipients := sendGridPersonalization{}
+	for _, address := range to {
+		recipients.To = append(recipients.To, sendGridAddress{Email: address})
+	}
+	msg := sendGridMessage{
+		Personalizations: []sendGridPersonalization{recipients},
+		From:             sendGridAddress{Email: s.From},
+		Subject:          subject,
+		Content:          []sendGridContent{{Type: "text/html", Value: body}},
+	}
+
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.BaseURL+"/v3/mail/send", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.APIKey)
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("sendgrid responded %s: %s", resp.Status, b)
+	}
+	return nil
+}
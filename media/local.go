@@ -0,0 +1,78 @@
+package media
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore keeps media on disk, it isn't served on its own, see
+// SignedLocalStore for files that are
+type LocalStore struct {
+	Dir  string
+	Host string
+}
+
+func NewLocalStore(dir string, host string) *LocalStore {
+	return &LocalStore{
+		Dir:  dir,
+		Host: host,
+	}
+}
+
+func (s *LocalStore) Save(ctx context.Context, key string, r io.Reader) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(file, r)
+	if err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+func (s *LocalStore) URL(key string) string {
+	return fmt.Sprintf("%s/media/%s", s.Host, key)
+}
+
+// keys are generated by us but make sure they can't escape the media dir
+func (s *LocalStore) path(key string) (string, error) {
+	path := filepath.Join(s.Dir, filepath.FromSlash(key))
+	if !strings.HasPrefix(path, filepath.Clean(s.Dir)+string(os.PathSeparator)) {
+		return "", fmt.Errorf("invalid media key %s", key)
+	}
+	return path, nil
+}
@@ -0,0 +1,15 @@
+package media
+
+import (
+	"context"
+	"io"
+)
+
+// Store is where uploaded media (form check videos etc.) ends up, kept behind
+// an interface so we can move off local disk onto a bucket later
+type Store interface {
+	Save(ctx context.Context, key string, r io.Reader) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
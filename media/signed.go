@@ -0,0 +1,174 @@
+package media
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/clock"
+)
+
+// Signer hands out urls that let whoever has them upload or download one
+// file directly until they expire, so private files never need a public url
+// and big ones don't pass through the api. A bucket backed store pre-signs
+// them with S3 or GCS, SignedLocalStore signs them itself
+type Signer interface {
+	// the upload is a PUT of at most maxBytes with contentType as its
+	// Content-Type
+	SignedUploadURL(key string, contentType string, maxBytes int64, expires time.Time) (string, error)
+	SignedDownloadURL(key string, expires time.Time) (string, error)
+}
+
+// SignedStore is a Store for private files, its URL isn't meant to be
+// handed out, the signed ones are
+type SignedStore interface {
+	Store
+	Signer
+}
+
+// SignedLocalStore keeps private files on disk, its signed urls are checked
+// and served by SignedURLHandler mounted on Path
+type SignedLocalStore struct {
+	LocalStore
+	Path   string
+	Secret []byte
+}
+
+func NewSignedLocalStore(dir string, host string, path string, secret string) *SignedLocalStore {
+	return &SignedLocalStore{
+		LocalStore: LocalStore{Dir: dir, Host: host},
+		Path:       path,
+		Secret:     []byte(secret),
+	}
+}
+
+func (s *SignedLocalStore) URL(key string) string {
+	return fmt.Sprintf("%s%s?key=%s", s.Host, s.Path, url.QueryEscape(key))
+}
+
+func (s *SignedLocalStore) SignedUploadURL(key string, contentType string, maxBytes int64, expires time.Time) (string, error) {
+	return s.signedURL(url.Values{
+		"key":          {key},
+		"method":       {http.MethodPut},
+		"content_type": {contentType},
+		"max_bytes":    {strconv.FormatInt(maxBytes, 10)},
+		"expires":      {strconv.FormatInt(expires.Unix(), 10)},
+	})
+}
+
+func (s *SignedLocalStore) SignedDownloadURL(key string, expires time.Time) (string, error) {
+	return s.signedURL(url.Values{
+		"key":     {key},
+		"method":  {http.MethodGet},
+		"expires": {strconv.FormatInt(expires.Unix(), 10)},
+	})
+}
+
+func (s *SignedLocalStore) signedURL(params url.Values) (string, error) {
+	if len(s.Secret) == 0 {
+		return "", fmt.Errorf("no secret to sign media urls with")
+	}
+	params.Set("signature", s.sign(params))
+	return fmt.Sprintf("%s%s?%s", s.Host, s.Path, params.Encode()), nil
+}
+
+// sign covers every param but the signature, Encode sorts them by name so
+// the order they came in doesn't matter
+func (s *SignedLocalStore) sign(params url.Values) string {
+	signed := url.Values{}
+	for k, v := range params {
+		if k != "signature" {
+			signed[k] = v
+		}
+	}
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(signed.Encode()))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignedURLHandler serves the signed urls of a SignedLocalStore, nothing
+// else gets in or out
+type SignedURLHandler struct {
+	Store *SignedLocalStore
+}
+
+func (h *SignedURLHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	signature, err := hex.DecodeString(params.Get("signature"))
+	if err != nil || len(h.Store.Secret) == 0 {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	expected, _ := hex.DecodeString(h.Store.sign(params))
+	if !hmac.Equal(signature, expected) || params.Get("method") != r.Method {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	expires, err := strconv.ParseInt(params.Get("expires"), 10, 64)
+	if err != nil || clock.Now().Unix() > expires {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	key := params.Get("key")
+	switch r.Method {
+	case http.MethodPut:
+		maxBytes, _ := strconv.ParseInt(params.Get("max_bytes"), 10, 64)
+		if r.Header.Get("Content-Type") != params.Get("content_type") {
+			http.Error(w, "Wrong Content-Type", http.StatusBadRequest)
+			return
+		}
+		if r.ContentLength > maxBytes {
+			http.Error(w, "Too Large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		// one byte over the limit is enough to know it's too big
+		body := io.LimitReader(r.Body, maxBytes+1)
+		limited := &countingReader{r: body}
+		if err := h.Store.Save(r.Context(), key, limited); err != nil {
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		if limited.n > maxBytes {
+			h.Store.Delete(context.Background(), key)
+			http.Error(w, "Too Large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodGet:
+		file, err := h.Store.Open(r.Context(), key)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer file.Close()
+		// private, don't let shared caches keep a copy
+		w.Header().Set("Cache-Control", "private, max-age=0, no-store")
+		// files on disk can seek, so players get ranges to skip around videos
+		if seeker, ok := file.(io.ReadSeeker); ok {
+			http.ServeContent(w, r, "", time.Time{}, seeker)
+			return
+		}
+		io.Copy(w, file)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,226 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"gorm.io/gorm"
+)
+
+// what a mutation changes, idArg is the path to the argument with the id in
+// it. Mutations that create the entity leave it empty and the id is taken
+// from what they return
+type auditedMutation struct {
+	entityType string
+	idArg      string
+}
+
+var auditedMutations = map[string]auditedMutation{
+	"createWorkoutRoutine":    {database.AuditEntityWorkoutRoutine, ""},
+	"updateWorkoutRoutine":    {database.AuditEntityWorkoutRoutine, "workoutRoutine.id"},
+	"deleteWorkoutRoutine":    {database.AuditEntityWorkoutRoutine, "workoutRoutineId"},
+	"restoreWorkoutRoutine":   {database.AuditEntityWorkoutRoutine, "workoutRoutineId"},
+	"importSharedRoutine":     {database.AuditEntityWorkoutRoutine, ""},
+	"reorderExerciseRoutines": {database.AuditEntityWorkoutRoutine, "workoutRoutineId"},
+
+	"addExerciseRoutine":     {database.AuditEntityExerciseRoutine, ""},
+	"deleteExerciseRoutine":  {database.AuditEntityExerciseRoutine, "exerciseRoutineId"},
+	"restoreExerciseRoutine": {database.AuditEntityExerciseRoutine, "exerciseRoutineId"},
+
+	"addWorkoutSession":             {database.AuditEntityWorkoutSession, ""},
+	"updateWorkoutSession":          {database.AuditEntityWorkoutSession, "workoutSessionId"},
+	"finishWorkoutSession":          {database.AuditEntityWorkoutSession, "workoutSessionId"},
+	"deleteWorkoutSession":          {database.AuditEntityWorkoutSession, "workoutSessionId"},
+	"restoreWorkoutSession":         {database.AuditEntityWorkoutSession, "workoutSessionId"},
+	"unlockWorkoutSession":          {database.AuditEntityWorkoutSession, "workoutSessionId"},
+	"restoreArchivedWorkoutSession": {database.AuditEntityWorkoutSession, ""},
+	"joinWorkoutSession":            {database.AuditEntityWorkoutSession, ""},
+	"leaveWorkoutSession":           {database.AuditEntityWorkoutSession, "workoutSessionId"},
+	"setWorkoutSessionNotes":        {database.AuditEntityWorkoutSession, "workoutSessionId"},
+	"setWorkoutSessionTags":         {database.AuditEntityWorkoutSession, "workoutSessionId"},
+
+	"addExercise":    {database.AuditEntityExercise, ""},
+	"updateExercise": {database.AuditEntityExercise, "exerciseId"},
+	"deleteExercise": {database.AuditEntityExercise, "exerciseId"},
+
+	"addSet":    {database.AuditEntitySetEntry, ""},
+	"updateSet": {database.AuditEntitySetEntry, "setId"},
+	"deleteSet": {database.AuditEntitySetEntry, "setId"},
+
+	"logBodyWeight":    {database.AuditEntityBodyWeight, ""},
+	"updateBodyWeight": {database.AuditEntityBodyWeight, "bodyWeightEntryId"},
+	"deleteBodyWeight": {database.AuditEntityBodyWeight, "bodyWeightEntryId"},
+
+	"createGoal": {database.AuditEntityGoal, ""},
+	"updateGoal": {database.AuditEntityGoal, "goalId"},
+	"deleteGoal": {database.AuditEntityGoal, "goalId"},
+
+	"createProgram": {database.AuditEntityProgram, ""},
+	"updateProgram": {database.AuditEntityProgram, "programId"},
+	"deleteProgram": {database.AuditEntityProgram, "programId"},
+
+	"impersonateUser": {database.AuditEntityUser, "userId"},
+	"forceDeleteUser": {database.AuditEntityUser, "userId"},
+}
+
+// mutations on the account itself that an impersonation token can't make, an
+// admin acting as someone shouldn't be able to delete them, take over their
+// email or log them out
+var accountMutations = map[string]bool{
+	"deleteUser":         true,
+	"deleteAccount":      true,
+	"requestEmailChange": true,
+	"confirmEmailChange": true,
+	"logout":             true,
+	"revokeSession":      true,
+}
+
+// AuditLog is a gqlgen extension that records every mutation a signed in
+// user makes to the audit log, along with the admin when they were
+// impersonating and the reason argument of mutations that take one. With an
+// impersonation token queries are recorded too and account mutations are
+// refused.
+// Mutations on workout data also get the row they changed before and after.
+// The row before is read ahead of the resolver, the rest is written after the
+// response so clients don't wait on it. Mutations that fail aren't recorded
+type AuditLog struct {
+	DB *gorm.DB
+
+	pending sync.WaitGroup
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.FieldInterceptor
+} = &AuditLog{}
+
+func (*AuditLog) ExtensionName() string {
+	return "AuditLog"
+}
+
+func (*AuditLog) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (a *AuditLog) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || !isRootField(fc) {
+		return next(ctx)
+	}
+	u, err := GetUser(ctx)
+	if err != nil {
+		return next(ctx)
+	}
+	if u.ImpersonatedBy == 0 && fc.Object != "Mutation" {
+		return next(ctx)
+	}
+	if u.ImpersonatedBy != 0 && fc.Object == "Mutation" && accountMutations[fc.Field.Name] {
+		return nil, errors.Forbidden("Access Denied")
+	}
+
+	entry := &database.AuditLog{
+		UserID:         u.ID,
+		Operation:      fc.Field.Name,
+		ImpersonatedBy: u.ImpersonatedBy,
+	}
+	args := fc.Field.ArgumentMap(graphql.GetOperationContext(ctx).Variables)
+	if reason, ok := args["reason"].(string); ok {
+		entry.Reason = reason
+	}
+	mutation, audited := auditedMutations[fc.Field.Name]
+	if audited {
+		entry.EntityType = mutation.entityType
+		if mutation.idArg != "" {
+			entry.EntityID = auditID(argument(args, mutation.idArg))
+			entry.Before, err = database.AuditSnapshot(a.DB, entry.EntityType, entry.EntityID)
+			if err != nil {
+				log.Printf("could not read audit snapshot for %s: %s", fc.Field.Name, err)
+			}
+		}
+	}
+
+	res, err := next(ctx)
+	if err != nil {
+		return res, err
+	}
+	if audited && mutation.idArg == "" {
+		entry.EntityID = auditID(returnedID(res))
+	}
+
+	a.pending.Add(1)
+	go func() {
+		defer a.pending.Done()
+		if entry.EntityID != 0 {
+			after, err := database.AuditSnapshot(a.DB, entry.EntityType, entry.EntityID)
+			if err != nil {
+				log.Printf("could not read audit snapshot for %s: %s", entry.Operation, err)
+			}
+			entry.After = after
+		}
+		if err := database.AddAuditLog(a.DB, entry); err != nil {
+			log.Printf("could not save audit log for %s: %s", entry.Operation, err)
+		}
+	}()
+
+	return res, nil
+}
+
+// Wait blocks until the audit log entries already started are written, for
+// shutting down without losing them
+func (a *AuditLog) Wait() {
+	a.pending.Wait()
+}
+
+// isRootField is whether the field is one of the operation's top level fields
+// rather than a field of what they return
+func isRootField(fc *graphql.FieldContext) bool {
+	switch fc.Object {
+	case "Mutation", "Query", "Subscription":
+		return true
+	}
+	return false
+}
+
+// argument follows a dotted path like workoutRoutine.id into the arguments
+func argument(args map[string]interface{}, path string) interface{} {
+	var value interface{} = args
+	for _, key := range strings.Split(path, ".") {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[key]
+	}
+	return value
+}
+
+// returnedID is the id field of what a mutation returned
+func returnedID(res interface{}) interface{} {
+	b, err := json.Marshal(res)
+	if err != nil {
+		return nil
+	}
+	var returned struct {
+		ID interface{} `json:"id"`
+	}
+	if json.Unmarshal(b, &returned) != nil {
+		return nil
+	}
+	return returned.ID
+}
+
+func auditID(id interface{}) uint {
+	parsed, err := strconv.ParseUint(fmt.Sprint(id), 10, strconv.IntSize)
+	if err != nil {
+		return 0
+	}
+	return uint(parsed)
+}
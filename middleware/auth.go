@@ -2,33 +2,82 @@ package middleware
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"net/http"
 	"os"
 
 	"github.com/neilZon/workout-logger-api/common"
 	"github.com/neilZon/workout-logger-api/config"
 	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
 	"github.com/neilZon/workout-logger-api/token"
 	"gorm.io/gorm"
 )
 
 const UserCtxKey string = "USER"
 
-func AuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		t := r.Header.Get("Authorization")
+// Authenticator finds the user a request's Authorization header is for. The
+// claims look the same whatever issued the token, so GetUser and the
+// resolvers don't need to know which one did
+type Authenticator interface {
+	Authenticate(authorization string) (*token.Claims, error)
+}
+
+// JWT authenticates the access tokens login and signup hand out. They carry
+// the session they were issued for and stop working as soon as it's revoked,
+// not when they expire. Impersonation tokens have no session, they're short
+// lived instead
+type JWT struct {
+	Secret []byte
+	DB     *gorm.DB
+}
 
-		// decode token to get user
-		claims, _ := token.Decode(t, []byte(os.Getenv(config.ACCESS_SECRET)))
+func (a JWT) Authenticate(authorization string) (*token.Claims, error) {
+	claims, err := token.Decode(authorization, a.Secret)
+	if err != nil {
+		return nil, err
+	}
+	if claims.ImpersonatedBy != 0 {
+		return claims, nil
+	}
+	// signed before access tokens carried their session, there's no telling
+	// whether it was revoked
+	if claims.Family == "" {
+		return nil, fmt.Errorf("access token has no session")
+	}
+	session, err := database.GetSessionByFamily(a.DB, claims.Family)
+	if err != nil {
+		return nil, err
+	}
+	if session.UserID != claims.ID || session.RevokedAt != nil {
+		return nil, fmt.Errorf("session %d was revoked", session.ID)
+	}
+	return claims, nil
+}
 
-		// put it in context
-		ctx := context.WithValue(r.Context(), UserCtxKey, claims)
+// NewAuthenticator is the one AUTH_PROVIDER in .env names, jwt when it's
+// empty. db is where sessions are looked up
+func NewAuthenticator(provider string, db *gorm.DB) (Authenticator, error) {
+	switch provider {
+	case "", "jwt":
+		return JWT{Secret: []byte(os.Getenv(config.ACCESS_SECRET)), DB: db}, nil
+	}
+	return nil, fmt.Errorf("%s %q isn't supported, the only provider is jwt", config.AUTH_PROVIDER, provider)
+}
 
-		// and call the next with our new context
-		r = r.WithContext(ctx)
-		next.ServeHTTP(w, r)
-	})
+// Authenticate puts the user the request is for in its context, requests
+// that don't authenticate go through without one
+func Authenticate(a Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err := a.Authenticate(r.Header.Get("Authorization"))
+			if err != nil {
+				claims = nil
+			}
+			ctx := context.WithValue(r.Context(), UserCtxKey, claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
 }
 
 func GetUser(ctx context.Context) (*token.Claims, error) {
@@ -40,12 +89,30 @@ func GetUser(ctx context.Context) (*token.Claims, error) {
 }
 
 func VerifyUser(db *gorm.DB, userId string) error {
+	_, err := VerifiedUser(db, userId)
+	return err
+}
+
+// VerifiedUser is VerifyUser for resolvers that need the user's settings too,
+// it hands back the user it checked so they aren't looked up twice
+func VerifiedUser(db *gorm.DB, userId string) (*database.User, error) {
 	user, err := database.GetUserById(db, userId)
 	if err != nil {
-		return errors.New("could not verify user")
+		return nil, errors.From(err, "could not verify user")
 	}
 	if !user.Verified {
-		return errors.New("user not verified")
+		return nil, errors.Forbidden("user not verified")
+	}
+	return user, nil
+}
+
+func VerifyAdmin(db *gorm.DB, userId string) error {
+	user, err := database.GetUserById(db, userId)
+	if err != nil {
+		return errors.From(err, "could not verify user")
+	}
+	if !user.Admin {
+		return errors.Forbidden("admin access required")
 	}
 	return nil
 }
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/neilZon/workout-logger-api/config"
+)
+
+const (
+	LowBandwidthCtxKey = ctxKey("LOW_BANDWIDTH")
+
+	// set to "true" by apps that are on a bad connection
+	LowBandwidthHeader = "X-Low-Bandwidth"
+)
+
+// nullable fields that are nice to have but not needed to log a workout,
+// they come back null in low bandwidth mode
+var lowBandwidthTrimmed = map[string]map[string]bool{
+	"WorkoutSession":  {"clientMetadata": true, "origin": true},
+	"SetEntry":        {"clientMetadata": true, "origin": true},
+	"ExerciseRoutine": {"catalogExercise": true},
+}
+
+func IsLowBandwidth(ctx context.Context) bool {
+	low, _ := ctx.Value(LowBandwidthCtxKey).(bool)
+	return low
+}
+
+// PageSize shrinks the page a client asked for in low bandwidth mode, the
+// rest comes with the next page
+func PageSize(ctx context.Context, requested int) int {
+	if IsLowBandwidth(ctx) && requested > config.LOW_BANDWIDTH_PAGE_SIZE {
+		return config.LOW_BANDWIDTH_PAGE_SIZE
+	}
+	return requested
+}
+
+// LowBandwidth is a gqlgen extension that leaves out optional fields for
+// clients in low bandwidth mode so they don't pay for what they can't show
+type LowBandwidth struct{}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.FieldInterceptor
+} = LowBandwidth{}
+
+func (LowBandwidth) ExtensionName() string {
+	return "LowBandwidth"
+}
+
+func (LowBandwidth) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (LowBandwidth) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	if !IsLowBandwidth(ctx) {
+		return next(ctx)
+	}
+	fc := graphql.GetFieldContext(ctx)
+	if fc != nil && lowBandwidthTrimmed[fc.Object][fc.Field.Name] {
+		return nil, nil
+	}
+	return next(ctx)
+}
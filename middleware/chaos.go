@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/neilZon/workout-logger-api/chaos"
+	"github.com/neilZon/workout-logger-api/errors"
+)
+
+// Chaos is a gqlgen extension that slows down or fails a share of resolver
+// calls. Failures look like any other INTERNAL error so apps treat them the
+// same as a real one
+type Chaos struct {
+	Injector *chaos.Injector
+	// "Type.field" like "Mutation.addWorkoutSession", empty is every resolver
+	Fields []string
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.FieldInterceptor
+} = Chaos{}
+
+func (Chaos) ExtensionName() string {
+	return "Chaos"
+}
+
+func (Chaos) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (c Chaos) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	fc := graphql.GetFieldContext(ctx)
+	// plain struct fields aren't worth slowing down, there's nothing to retry
+	if fc == nil || !fc.IsResolver || !c.targets(fc.Object+"."+fc.Field.Name) {
+		return next(ctx)
+	}
+	if err := c.Injector.Inject(ctx); err != nil {
+		return nil, errors.Internal("Internal server error")
+	}
+	return next(ctx)
+}
+
+func (c Chaos) targets(field string) bool {
+	if len(c.Fields) == 0 {
+		return true
+	}
+	for _, f := range c.Fields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/neilZon/workout-logger-api/database"
+)
+
+const (
+	ClientCtxKey    = ctxKey("CLIENT")
+	ClientIPCtxKey  = ctxKey("CLIENT_IP")
+	UserAgentCtxKey = ctxKey("USER_AGENT")
+)
+
+// headers the apps send to say where a write came from
+const (
+	ClientPlatformHeader   = "X-Client-Platform"
+	ClientAppVersionHeader = "X-Client-Version"
+	ClientDeviceIDHeader   = "X-Device-Id"
+)
+
+// ClientMiddleware puts the client headers in the context so writes can be
+// traced back to the app version that made them, along with the caller's ip
+// and user agent and whether it asked for low bandwidth mode or its operations' cost.
+// The ip is only taken from X-Forwarded-For on requests from trustedProxies
+func ClientMiddleware(trustedProxies []*net.IPNet, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := database.Origin{
+			Platform:   clientHeader(r, ClientPlatformHeader, 16, true),
+			AppVersion: clientHeader(r, ClientAppVersionHeader, 32, false),
+			DeviceID:   clientHeader(r, ClientDeviceIDHeader, 64, false),
+		}
+		ctx := context.WithValue(r.Context(), ClientCtxKey, origin)
+		ctx = context.WithValue(ctx, ClientIPCtxKey, clientIP(r, trustedProxies))
+		ctx = context.WithValue(ctx, UserAgentCtxKey, clientHeader(r, "User-Agent", 255, false))
+		ctx = context.WithValue(ctx, LowBandwidthCtxKey, r.Header.Get(LowBandwidthHeader) == "true")
+		ctx = context.WithValue(ctx, DebugCostCtxKey, r.Header.Get(DebugCostHeader) == "true")
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetClient is empty for clients that don't send the headers
+func GetClient(ctx context.Context) database.Origin {
+	origin, _ := ctx.Value(ClientCtxKey).(database.Origin)
+	return origin
+}
+
+// GetClientIP is empty outside of a request
+func GetClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(ClientIPCtxKey).(string)
+	return ip
+}
+
+// GetUserAgent is empty outside of a request
+func GetUserAgent(ctx context.Context) string {
+	userAgent, _ := ctx.Value(UserAgentCtxKey).(string)
+	return userAgent
+}
+
+// behind a trusted proxy the caller is the last address in X-Forwarded-For
+// that isn't another trusted proxy, anything before it was sent by the caller
+// and can't be trusted. Without one in front the header is the caller's own
+// word and only who connected counts
+func clientIP(r *http.Request, trustedProxies []*net.IPNet) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !isTrustedProxy(host, trustedProxies) {
+		return host
+	}
+
+	addrs := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(addrs) - 1; i >= 0; i-- {
+		addr := strings.TrimSpace(addrs[i])
+		if addr != "" && !isTrustedProxy(addr, trustedProxies) {
+			return addr
+		}
+	}
+	return host
+}
+
+func isTrustedProxy(addr string, trustedProxies []*net.IPNet) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range trustedProxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// headers are cut to fit their column rather than failing the write
+func clientHeader(r *http.Request, name string, max int, lower bool) string {
+	v := strings.TrimSpace(r.Header.Get(name))
+	if lower {
+		v = strings.ToLower(v)
+	}
+	if len(v) > max {
+		v = v[:max]
+	}
+	return v
+}
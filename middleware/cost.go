@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"context"
+	"time"
+
+	"github.com/99designs/gqlgen/complexity"
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+const (
+	DebugCostCtxKey = ctxKey("DEBUG_COST")
+
+	// set to "true" by client developers who want to see what an operation
+	// costs
+	DebugCostHeader = "X-Debug-Cost"
+)
+
+func IsDebugCost(ctx context.Context) bool {
+	debug, _ := ctx.Value(DebugCostCtxKey).(bool)
+	return debug
+}
+
+// QueryCost is a gqlgen extension that reports what an operation cost under
+// the "cost" response extension when the debug cost header is sent. The
+// complexity is the one budgets will be enforced on, the database numbers
+// only cover statements run by resolvers and loaders
+type QueryCost struct {
+	schema graphql.ExecutableSchema
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+} = &QueryCost{}
+
+func (*QueryCost) ExtensionName() string {
+	return "QueryCost"
+}
+
+func (c *QueryCost) Validate(schema graphql.ExecutableSchema) error {
+	c.schema = schema
+	return nil
+}
+
+func (c *QueryCost) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	if !IsDebugCost(ctx) || !graphql.HasOperationContext(ctx) {
+		return next(ctx)
+	}
+	oc := graphql.GetOperationContext(ctx)
+	// a subscription's responses come in one at a time, there's no total
+	if oc.Operation == nil || oc.Operation.Operation == ast.Subscription {
+		return next(ctx)
+	}
+
+	ctx, stats := database.WithQueryStats(ctx)
+	resp := next(ctx)
+	if resp == nil {
+		return resp
+	}
+
+	if resp.Extensions == nil {
+		resp.Extensions = map[string]interface{}{}
+	}
+	resp.Extensions["cost"] = map[string]interface{}{
+		"complexity":   complexity.Calculate(c.schema, oc.Operation, oc.Variables),
+		"dbQueries":    stats.Queries(),
+		"dbDurationMs": milliseconds(stats.Duration()),
+		"durationMs":   milliseconds(time.Since(oc.Stats.OperationStart)),
+	}
+	return resp
+}
+
+// to the microsecond, anything finer is noise
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// WebsocketDrainer closes subscriptions on shutdown. http.Server.Shutdown
+// neither waits for nor closes hijacked connections, so websockets are
+// tracked here and told to close once the server stops taking requests
+type WebsocketDrainer struct {
+	mu       sync.Mutex
+	draining bool
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+func NewWebsocketDrainer() *WebsocketDrainer {
+	return &WebsocketDrainer{done: make(chan struct{})}
+}
+
+// Middleware hands websocket upgrades a context that's cancelled on Close,
+// gqlgen then sends a normal close frame and the client reconnects to
+// another instance. Other requests pass straight through
+func (d *WebsocketDrainer) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		d.mu.Lock()
+		if d.draining {
+			d.mu.Unlock()
+			http.Error(w, "Shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		d.wg.Add(1)
+		d.mu.Unlock()
+		defer d.wg.Done()
+
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		go func() {
+			select {
+			case <-d.done:
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Close tells every open websocket to close and turns new ones away
+func (d *WebsocketDrainer) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.draining {
+		return
+	}
+	d.draining = true
+	close(d.done)
+}
+
+// Wait blocks until every websocket has closed or ctx is done
+func (d *WebsocketDrainer) Wait(ctx context.Context) error {
+	closed := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(closed)
+	}()
+
+	select {
+	case <-closed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
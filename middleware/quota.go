@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const responseNodesKey = ctxKey("RESPONSE_NODES")
+
+// ResponseQuota is a gqlgen extension that caps how big a single operation's
+// response can get so accidental mega-queries get rejected instead of
+// hogging the server
+type ResponseQuota struct {
+	MaxBytes int
+	MaxNodes int64
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+	graphql.FieldInterceptor
+} = ResponseQuota{}
+
+func (ResponseQuota) ExtensionName() string {
+	return "ResponseQuota"
+}
+
+func (ResponseQuota) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (q ResponseQuota) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	var nodes int64
+	resp := next(context.WithValue(ctx, responseNodesKey, &nodes))
+	if resp == nil {
+		return resp
+	}
+
+	if atomic.LoadInt64(&nodes) > q.MaxNodes || len(resp.Data) > q.MaxBytes {
+		return &graphql.Response{
+			Errors: gqlerror.List{responseTooLargeError()},
+		}
+	}
+	return resp
+}
+
+// counts every resolved field and stops resolving once the cap is hit so we
+// don't keep hitting the db for a response that will be thrown away anyways
+func (q ResponseQuota) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	nodes, ok := ctx.Value(responseNodesKey).(*int64)
+	if ok && atomic.AddInt64(nodes, 1) > q.MaxNodes {
+		return nil, responseTooLargeError()
+	}
+	return next(ctx)
+}
+
+func responseTooLargeError() *gqlerror.Error {
+	return &gqlerror.Error{
+		Message: "Response too large, use pagination (limit/after) or request fewer fields",
+		Extensions: map[string]interface{}{
+			"code": string(errors.ResponseTooLargeCode),
+		},
+	}
+}
@@ -0,0 +1,142 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// buckets are swept once there are this many, dropping the ones that have
+// refilled since they'd behave the same as a new one
+const rateLimiterSweepSize = 10000
+
+// mutations anyone can call without a token, limited per ip on top of the
+// per operation limit to slow down password guessing and email spam
+var authMutations = map[string]bool{
+	"login":                  true,
+	"signup":                 true,
+	"refreshAccessToken":     true,
+	"logout":                 true,
+	"sendForgotPasswordLink": true,
+	"requestPasswordReset":   true,
+	"resendVerificationCode": true,
+	"resetPassword":          true,
+	"confirmEmailChange":     true,
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter is an in memory token bucket per key, each one starts with
+// burst tokens and gets perMinute back every minute. Limits are per instance
+type RateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	perSecond float64
+	burst     float64
+}
+
+func NewRateLimiter(perMinute int, burst int) *RateLimiter {
+	return &RateLimiter{
+		buckets:   map[string]*tokenBucket{},
+		perSecond: float64(perMinute) / 60,
+		burst:     float64(burst),
+	}
+}
+
+// Allow takes a token for key, when there isn't one it says how long until
+// there will be
+func (l *RateLimiter) Allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	b, ok := l.buckets[key]
+	if !ok {
+		if len(l.buckets) >= rateLimiterSweepSize {
+			l.sweep(now)
+		}
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.perSecond)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.perSecond * float64(time.Second))
+		return false, wait
+	}
+	b.tokens--
+	return true, 0
+}
+
+func (l *RateLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.perSecond >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// RateLimit is a gqlgen extension that limits operations per user, or per ip
+// for requests without a token, and the auth mutations per ip
+type RateLimit struct {
+	Operations *RateLimiter
+	Auth       *RateLimiter
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+	graphql.FieldInterceptor
+} = RateLimit{}
+
+func (RateLimit) ExtensionName() string {
+	return "RateLimit"
+}
+
+func (RateLimit) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (rl RateLimit) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	key := "ip:" + GetClientIP(ctx)
+	if u, err := GetUser(ctx); err == nil {
+		key = fmt.Sprintf("user:%d", u.ID)
+	}
+	if ok, wait := rl.Operations.Allow(key); !ok {
+		return &graphql.Response{
+			Errors: gqlerror.List{rateLimitedError(wait)},
+		}
+	}
+	return next(ctx)
+}
+
+func (rl RateLimit) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || fc.Object != "Mutation" || !authMutations[fc.Field.Name] {
+		return next(ctx)
+	}
+	if ok, wait := rl.Auth.Allow("ip:" + GetClientIP(ctx)); !ok {
+		return nil, rateLimitedError(wait)
+	}
+	return next(ctx)
+}
+
+func rateLimitedError(wait time.Duration) *gqlerror.Error {
+	return &gqlerror.Error{
+		Message: "Too many requests, try again later",
+		Extensions: map[string]interface{}{
+			"code":              string(errors.RateLimitedCode),
+			"retryAfterSeconds": int(math.Ceil(wait.Seconds())),
+		},
+	}
+}
@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/introspection"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// root types the read only endpoint leaves out
+var readOnlyHidden = map[string]bool{
+	"Mutation":     true,
+	"Subscription": true,
+}
+
+// ReadOnly is a gqlgen extension for the read only endpoint. Only queries
+// are answered, and introspection leaves out the mutation and subscription
+// types so dashboards built against it don't see operations they can't run
+type ReadOnly struct{}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+	graphql.FieldInterceptor
+} = ReadOnly{}
+
+func (ReadOnly) ExtensionName() string {
+	return "ReadOnly"
+}
+
+func (ReadOnly) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (ReadOnly) MutateOperationContext(ctx context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	if rc.Operation == nil || rc.Operation.Operation == ast.Query {
+		return nil
+	}
+	return &gqlerror.Error{
+		Message: "This endpoint is read only, send " + string(rc.Operation.Operation) + "s to /query",
+		Extensions: map[string]interface{}{
+			"code": string(errors.ForbiddenCode),
+		},
+	}
+}
+
+func (ReadOnly) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil {
+		return next(ctx)
+	}
+
+	switch fc.Object + "." + fc.Field.Name {
+	case "__Schema.mutationType", "__Schema.subscriptionType":
+		return nil, nil
+	case "Query.__type":
+		if name, _ := fc.Args["name"].(string); readOnlyHidden[name] {
+			return nil, nil
+		}
+	case "__Schema.types":
+		res, err := next(ctx)
+		types, ok := res.([]introspection.Type)
+		if err != nil || !ok {
+			return res, err
+		}
+		shown := make([]introspection.Type, 0, len(types))
+		for _, t := range types {
+			if name := t.Name(); name == nil || !readOnlyHidden[*name] {
+				shown = append(shown, t)
+			}
+		}
+		return shown, nil
+	}
+	return next(ctx)
+}
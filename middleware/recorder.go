@@ -0,0 +1,187 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/neilZon/workout-logger-api/cache"
+	"github.com/neilZon/workout-logger-api/clock"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/lexer"
+	"gorm.io/gorm"
+)
+
+// variable keys that never get written to a recording
+var redactedKeys = []string{"password", "token", "email", "code", "secret"}
+
+const redacted = "[REDACTED]"
+
+// RequestRecorder is a gqlgen extension that records operations for users
+// that have opted into debug recording (or everyone when RecordAll is set)
+// so hard to reproduce client issues can be replayed by an admin
+type RequestRecorder struct {
+	DB        *gorm.DB
+	RecordAll bool
+	// when each user's recording ends, nil reads the user every operation
+	Cache cache.Cache
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+} = RequestRecorder{}
+
+func (RequestRecorder) ExtensionName() string {
+	return "RequestRecorder"
+}
+
+func (RequestRecorder) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (rr RequestRecorder) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	start := time.Now()
+	resp := next(ctx)
+	duration := time.Since(start)
+
+	u, err := GetUser(ctx)
+	if err != nil || !graphql.HasOperationContext(ctx) {
+		return resp
+	}
+
+	oc := graphql.GetOperationContext(ctx)
+	if !rr.RecordAll {
+		until, err := rr.recordingUntil(oc, u.ID)
+		if err != nil || until.Before(clock.Now()) {
+			return resp
+		}
+	}
+
+	variables, err := json.Marshal(anonymize(oc.Variables))
+	if err != nil {
+		return resp
+	}
+
+	recording := &database.RequestRecording{
+		UserID:        u.ID,
+		OperationName: oc.OperationName,
+		Query:         anonymizeQuery(oc.RawQuery),
+		Variables:     string(variables),
+		DurationMs:    duration.Milliseconds(),
+	}
+
+	// don't make the client wait on a debug write
+	go func() {
+		if err := database.AddRequestRecording(rr.DB, recording); err != nil {
+			log.Printf("could not save request recording: %s", err)
+		}
+	}()
+
+	return resp
+}
+
+// recordingUntil is when the user's recording ends, the epoch when they
+// never started one. It's read from the user after startRequestRecording so
+// the recording starts with the next operation
+func (rr RequestRecorder) recordingUntil(oc *graphql.OperationContext, userId uint) (time.Time, error) {
+	key := fmt.Sprintf("recording:%d", userId)
+	if rr.Cache != nil && !startsRecording(oc) {
+		if b, ok := rr.Cache.Get(key); ok {
+			nanos, err := strconv.ParseInt(string(b), 10, 64)
+			if err == nil {
+				return time.Unix(0, nanos), nil
+			}
+		}
+	}
+
+	user, err := database.GetUserById(rr.DB, fmt.Sprintf("%d", userId))
+	if err != nil {
+		return time.Time{}, err
+	}
+	var nanos int64
+	if user.RecordRequestsUntil != nil {
+		nanos = user.RecordRequestsUntil.UnixNano()
+	}
+	if rr.Cache != nil {
+		rr.Cache.Set(key, []byte(strconv.FormatInt(nanos, 10)), config.RECORDING_CACHE_TTL)
+	}
+	return time.Unix(0, nanos), nil
+}
+
+func startsRecording(oc *graphql.OperationContext) bool {
+	if oc.Operation == nil {
+		return false
+	}
+	for _, field := range graphql.CollectFields(oc, oc.Operation.SelectionSet, nil) {
+		if field.Name == "startRequestRecording" {
+			return true
+		}
+	}
+	return false
+}
+
+// walks the variables and redacts anything that looks like credentials or
+// personal info
+func anonymize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		anonymized := map[string]interface{}{}
+		for key, val := range v {
+			if isRedactedKey(key) {
+				anonymized[key] = redacted
+			} else {
+				anonymized[key] = anonymize(val)
+			}
+		}
+		return anonymized
+	case []interface{}:
+		anonymized := make([]interface{}, len(v))
+		for i, val := range v {
+			anonymized[i] = anonymize(val)
+		}
+		return anonymized
+	default:
+		return v
+	}
+}
+
+// replaces every string literal in the operation document so inline
+// arguments (e.g. login(loginInput: {password: "..."})) don't leak either
+func anonymizeQuery(query string) string {
+	runes := []rune(query)
+	l := lexer.New(&ast.Source{Input: query})
+
+	var b strings.Builder
+	last := 0
+	for {
+		tok, err := l.ReadToken()
+		if err != nil || tok.Kind == lexer.EOF {
+			break
+		}
+		if tok.Kind == lexer.String || tok.Kind == lexer.BlockString {
+			b.WriteString(string(runes[last:tok.Pos.Start]))
+			b.WriteString(`"` + redacted + `"`)
+			last = tok.Pos.End
+		}
+	}
+	b.WriteString(string(runes[last:]))
+	return b.String()
+}
+
+func isRedactedKey(key string) bool {
+	lowerKey := strings.ToLower(key)
+	for _, k := range redactedKeys {
+		if strings.Contains(lowerKey, k) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/neilZon/workout-logger-api/responsecache"
+)
+
+// InvalidateResponseCache is a gqlgen extension that drops the responses
+// cached for a user whenever they run a mutation. It's done even when the
+// mutation fails since it may have changed something before failing
+type InvalidateResponseCache struct {
+	Cache *responsecache.Cache
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.FieldInterceptor
+} = InvalidateResponseCache{}
+
+func (InvalidateResponseCache) ExtensionName() string {
+	return "InvalidateResponseCache"
+}
+
+func (InvalidateResponseCache) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (i InvalidateResponseCache) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || fc.Object != "Mutation" {
+		return next(ctx)
+	}
+
+	res, err := next(ctx)
+	if u, userErr := GetUser(ctx); userErr == nil {
+		i.Cache.Invalidate(responsecache.UserScope(fmt.Sprintf("%d", u.ID)))
+	}
+	return res, err
+}
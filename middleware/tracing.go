@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/99designs/gqlgen/graphql"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/neilZon/workout-logger-api/middleware"
+
+// Tracer is a gqlgen extension that opens a span for every operation and a
+// child span for every resolver it runs, so a slow request can be broken
+// down by resolver. Fields that just read a struct don't get one. A nil
+// Provider uses the global one
+type Tracer struct {
+	Provider trace.TracerProvider
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+	graphql.FieldInterceptor
+} = Tracer{}
+
+// TraceContextMiddleware picks up a trace the app or a load balancer started
+// from the request headers, so the operation span joins it
+func TraceContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (Tracer) ExtensionName() string {
+	return "Tracer"
+}
+
+func (Tracer) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (t Tracer) tracer() trace.Tracer {
+	provider := t.Provider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return provider.Tracer(tracerName)
+}
+
+func (t Tracer) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	if !graphql.HasOperationContext(ctx) {
+		return next(ctx)
+	}
+	oc := graphql.GetOperationContext(ctx)
+	if oc.Operation == nil {
+		return next(ctx)
+	}
+
+	name := oc.Operation.Name
+	if name == "" {
+		name = "anonymous"
+	}
+	attrs := []attribute.KeyValue{
+		attribute.String("graphql.operation.type", string(oc.Operation.Operation)),
+		attribute.String("graphql.operation.name", oc.Operation.Name),
+	}
+	if u, err := GetUser(ctx); err == nil {
+		attrs = append(attrs, attribute.Int64("enduser.id", int64(u.ID)))
+	}
+	if client := GetClient(ctx); client.Platform != "" {
+		attrs = append(attrs, attribute.String("client.platform", client.Platform), attribute.String("client.version", client.AppVersion))
+	}
+
+	ctx, span := t.tracer().Start(ctx, fmt.Sprintf("%s %s", oc.Operation.Operation, name),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(attrs...),
+	)
+	defer span.End()
+
+	resp := next(ctx)
+	if resp != nil && len(resp.Errors) > 0 {
+		span.SetStatus(codes.Error, resp.Errors.Error())
+	}
+	return resp
+}
+
+func (t Tracer) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || !fc.IsResolver {
+		return next(ctx)
+	}
+
+	ctx, span := t.tracer().Start(ctx, fmt.Sprintf("%s.%s", fc.Object, fc.Field.Name),
+		trace.WithAttributes(attribute.String("graphql.field.path", fc.Path().String())),
+	)
+	defer span.End()
+
+	res, err := next(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return res, err
+}
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/neilZon/workout-logger-api/validator"
+)
+
+// InputValidation is a gqlgen extension that checks mutation input objects
+// before their resolver runs, so bad values like negative reps never reach
+// the uint columns
+type InputValidation struct{}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.FieldInterceptor
+} = InputValidation{}
+
+func (InputValidation) ExtensionName() string {
+	return "InputValidation"
+}
+
+func (InputValidation) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (InputValidation) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || fc.Object != "Mutation" {
+		return next(ctx)
+	}
+	if err := validator.Input(fc.Args); err != nil {
+		return nil, err
+	}
+	return next(ctx)
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// MinClientVersion is a gqlgen extension that turns away apps older than
+// Minimum so broken releases can be retired. Requests without a version
+// header (web, scripts, the playground) are let through. An empty Minimum
+// turns it off
+type MinClientVersion struct {
+	Minimum   string
+	StoreURLs map[string]string // by platform, sent back so the app can link to the update
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+} = MinClientVersion{}
+
+func (MinClientVersion) ExtensionName() string {
+	return "MinClientVersion"
+}
+
+func (m MinClientVersion) Validate(schema graphql.ExecutableSchema) error {
+	if m.Minimum != "" && !isVersion(m.Minimum) {
+		return fmt.Errorf("minimum client version %q isn't a version", m.Minimum)
+	}
+	return nil
+}
+
+func (m MinClientVersion) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	if m.Minimum == "" {
+		return next(ctx)
+	}
+	client := GetClient(ctx)
+	if client.AppVersion == "" || !isVersion(client.AppVersion) || compareVersions(client.AppVersion, m.Minimum) >= 0 {
+		return next(ctx)
+	}
+
+	storeURLs := map[string]interface{}{}
+	for platform, url := range m.StoreURLs {
+		if url != "" {
+			storeURLs[platform] = url
+		}
+	}
+	return &graphql.Response{
+		Errors: gqlerror.List{{
+			Message: fmt.Sprintf("App version %s is no longer supported, update to %s or later", client.AppVersion, m.Minimum),
+			Extensions: map[string]interface{}{
+				"code":           string(errors.UpgradeRequiredCode),
+				"minimumVersion": m.Minimum,
+				"storeUrls":      storeURLs,
+			},
+		}},
+	}
+}
+
+// versions are dot separated numbers, anything after the numbers in a part
+// like a -beta suffix is ignored
+func versionParts(v string) []int {
+	parts := []int{}
+	for _, p := range strings.Split(v, ".") {
+		end := 0
+		for end < len(p) && p[end] >= '0' && p[end] <= '9' {
+			end++
+		}
+		n, err := strconv.Atoi(p[:end])
+		if err != nil {
+			return nil
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+func isVersion(v string) bool {
+	return versionParts(strings.TrimPrefix(v, "v")) != nil
+}
+
+// compareVersions is negative when a is older than b, missing parts count as 0
+func compareVersions(a string, b string) int {
+	ap := versionParts(strings.TrimPrefix(a, "v"))
+	bp := versionParts(strings.TrimPrefix(b, "v"))
+	for i := 0; i < len(ap) || i < len(bp); i++ {
+		var x, y int
+		if i < len(ap) {
+			x = ap[i]
+		}
+		if i < len(bp) {
+			y = bp[i]
+		}
+		if x != y {
+			return x - y
+		}
+	}
+	return 0
+}
@@ -0,0 +1,338 @@
+// Package migrate keeps the schema in versioned sql files under sql/, named
+// like 0002_add_set_indexes.up.sql with a matching .down.sql. golang-migrate
+// applies them in version order and keeps the version the database is at in
+// schema_migrations, so column type and index changes AutoMigrate couldn't
+// make are just sql
+package migrate
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	gomigrate "github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"gorm.io/gorm"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// Dir is where the migrations live in the repo, new ones are created there
+const Dir = "migrate/sql"
+
+// any number, adopting a database and migrating it just need the same one
+// wherever they run
+const lockKey = 7146358112
+
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// Status is a migration and whether it's been applied. A dirty one failed
+// while it ran and needs looking at before anything else runs
+type Status struct {
+	Migration
+	Applied bool
+	Dirty   bool
+}
+
+type Migrator struct {
+	db         *gorm.DB
+	fsys       fs.FS
+	dir        string
+	migrations []Migration
+}
+
+// New migrates db with the migrations built into the binary
+func New(db *gorm.DB) (*Migrator, error) {
+	return NewFromFS(db, files, "sql")
+}
+
+// NewFromFS migrates db with the migrations in dir of fsys
+func NewFromFS(db *gorm.DB, fsys fs.FS, dir string) (*Migrator, error) {
+	src, err := iofs.New(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	migrations, err := load(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, fsys: fsys, dir: dir, migrations: migrations}, nil
+}
+
+func load(src source.Driver) ([]Migration, error) {
+	migrations := []Migration{}
+	version, err := src.First()
+	for ; err == nil; version, err = src.Next(version) {
+		up, name, err := src.ReadUp(version)
+		if err != nil {
+			return nil, fmt.Errorf("migration %04d has no up: %w", version, err)
+		}
+		m := Migration{Version: version, Name: name}
+		if m.Up, err = readAll(up); err != nil {
+			return nil, err
+		}
+		down, _, err := src.ReadDown(version)
+		if err == nil {
+			m.Down, err = readAll(down)
+		}
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		migrations = append(migrations, m)
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	return migrations, nil
+}
+
+func readAll(r io.ReadCloser) (string, error) {
+	defer r.Close()
+	contents, err := io.ReadAll(r)
+	return string(contents), err
+}
+
+// Migrations are all of them, oldest first
+func (m *Migrator) Migrations() []Migration {
+	return m.migrations
+}
+
+// version is the one the database is at and whether it's dirty, nil before
+// the first migration
+func version(db *gorm.DB) (*uint, bool, error) {
+	var exists bool
+	err := db.Raw(`SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists).Error
+	if err != nil || !exists {
+		return nil, false, err
+	}
+
+	var rows []struct {
+		Version uint
+		Dirty   bool
+	}
+	err = db.Raw(`SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&rows).Error
+	if err != nil || len(rows) == 0 {
+		return nil, false, err
+	}
+	return &rows[0].Version, rows[0].Dirty, nil
+}
+
+// Status is every migration, oldest first, with whether it's been applied
+func (m *Migrator) Status() ([]Status, error) {
+	return m.status(m.db)
+}
+
+func (m *Migrator) status(db *gorm.DB) ([]Status, error) {
+	current, dirty, err := version(db)
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]Status, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		s := Status{Migration: migration}
+		if current != nil {
+			s.Dirty = dirty && migration.Version == *current
+			s.Applied = migration.Version < *current || migration.Version == *current && !dirty
+		}
+		statuses = append(statuses, s)
+	}
+	return statuses, nil
+}
+
+// Pending are the migrations that haven't been applied yet, oldest first
+func (m *Migrator) Pending() ([]Migration, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+	pending := []Migration{}
+	for _, s := range statuses {
+		if !s.Applied {
+			pending = append(pending, s.Migration)
+		}
+	}
+	return pending, nil
+}
+
+// Up applies every pending migration and returns the ones it applied. It
+// holds a lock while it runs so instances starting together take turns
+func (m *Migrator) Up() ([]Migration, error) {
+	var done []Migration
+	err := m.locked(func(conn *gorm.DB, migrator *gomigrate.Migrate) error {
+		if err := m.adopt(conn, migrator); err != nil {
+			return err
+		}
+		var err error
+		done, err = m.run(conn, false, migrator.Up)
+		return err
+	})
+	return done, err
+}
+
+// Down undoes the latest steps applied migrations, newest first, and returns
+// the ones it undid
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(func(conn *gorm.DB, migrator *gomigrate.Migrate) error {
+		var err error
+		done, err = m.run(conn, true, func() error {
+			return migrator.Steps(-steps)
+		})
+		// fewer than steps were applied, or none at all
+		var short gomigrate.ErrShortLimit
+		if errors.As(err, &short) || errors.Is(err, os.ErrNotExist) && len(done) == 0 {
+			return nil
+		}
+		return err
+	})
+	return done, err
+}
+
+// Force marks the database as at version, clean, without running anything.
+// It's for after a migration failed and the database was put right by hand,
+// -1 is before the first one
+func (m *Migrator) Force(version int) error {
+	return m.locked(func(conn *gorm.DB, migrator *gomigrate.Migrate) error {
+		return migrator.Force(version)
+	})
+}
+
+// run calls migrate and returns the migrations it applied, or undid newest
+// first when down
+func (m *Migrator) run(conn *gorm.DB, down bool, migrate func() error) ([]Migration, error) {
+	before, err := m.status(conn)
+	if err != nil {
+		return nil, err
+	}
+	err = migrate()
+	if errors.Is(err, gomigrate.ErrNoChange) {
+		err = nil
+	}
+	after, statusErr := m.status(conn)
+	if statusErr != nil {
+		return nil, statusErr
+	}
+
+	done := []Migration{}
+	for i := range after {
+		if down {
+			i = len(after) - 1 - i
+		}
+		if before[i].Applied != after[i].Applied {
+			done = append(done, after[i].Migration)
+		}
+	}
+
+	var dirty gomigrate.ErrDirty
+	if errors.As(err, &dirty) {
+		return done, fmt.Errorf("migration %s failed on an earlier run and the database is marked dirty, check what it left behind and then run `go run ./cmd/migrate force %d`", m.named(uint(dirty.Version)), m.previous(uint(dirty.Version)))
+	}
+	if err != nil {
+		for _, s := range after {
+			if s.Dirty {
+				return done, fmt.Errorf("migration %s: %w", s.Migration, err)
+			}
+		}
+	}
+	return done, err
+}
+
+func (m *Migrator) named(version uint) string {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration.String()
+		}
+	}
+	return fmt.Sprintf("%04d", version)
+}
+
+// previous is the version before version, -1 for the first
+func (m *Migrator) previous(version uint) int {
+	previous := -1
+	for _, migration := range m.migrations {
+		if migration.Version >= version {
+			break
+		}
+		previous = int(migration.Version)
+	}
+	return previous
+}
+
+// locked runs fn holding the migration lock, on one connection since advisory
+// locks belong to the connection that took them. golang-migrate has a second
+// connection and its own lock, but that one doesn't cover adopting the
+// database
+func (m *Migrator) locked(fn func(conn *gorm.DB, migrator *gomigrate.Migrate) error) error {
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return err
+	}
+	return m.db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec(`SELECT pg_advisory_lock(?)`, lockKey).Error; err != nil {
+			return err
+		}
+		defer conn.Exec(`SELECT pg_advisory_unlock(?)`, lockKey)
+
+		src, err := iofs.New(m.fsys, m.dir)
+		if err != nil {
+			return err
+		}
+		// a connection rather than the pool, closing the driver would
+		// close the pool too
+		migrationConn, err := sqlDB.Conn(context.Background())
+		if err != nil {
+			src.Close()
+			return err
+		}
+		driver, err := postgres.WithConnection(context.Background(), migrationConn, &postgres.Config{})
+		if err != nil {
+			src.Close()
+			migrationConn.Close()
+			return err
+		}
+		migrator, err := gomigrate.NewWithInstance("iofs", src, "postgres", driver)
+		if err != nil {
+			src.Close()
+			driver.Close()
+			return err
+		}
+		defer migrator.Close()
+		return fn(conn, migrator)
+	})
+}
+
+// adopt marks the baseline applied on databases AutoMigrate built before
+// there were migrations, the later migrations bring them the rest of the way.
+// Anything the baseline has that they don't shows up in the schema drift check
+func (m *Migrator) adopt(conn *gorm.DB, migrator *gomigrate.Migrate) error {
+	if len(m.migrations) == 0 {
+		return nil
+	}
+	current, _, err := version(conn)
+	if err != nil || current != nil {
+		return err
+	}
+	var built bool
+	err = conn.Raw(`SELECT to_regclass('users') IS NOT NULL`).Scan(&built).Error
+	if err != nil || !built {
+		return err
+	}
+	return migrator.Force(int(m.migrations[0].Version))
+}
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "set_entries", "exercises", "workout_sessions", "exercise_routines", "workout_routines", "users" CASCADE;
@@ -0,0 +1,24 @@
+-- the schema as the first AutoMigrate of the six original models left it.
+-- databases it built are adopted at this version when they have every table
+-- and column here, the migrations after it bring them up to date. the first
+-- User's password_reset_code and password_reset_sent_at aren't here, nothing
+-- reads them since reset tokens got their own table
+
+CREATE TABLE "users" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"name" varchar(50) NOT NULL,"email" varchar(80) NOT NULL UNIQUE,"password" text NOT NULL,"verified" boolean DEFAULT false,"verification_code" text UNIQUE,"verification_sent_at" timestamptz,PRIMARY KEY ("id"));
+CREATE INDEX IF NOT EXISTS "idx_users_deleted_at" ON "users" ("deleted_at");
+
+CREATE TABLE "workout_routines" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"name" varchar(32) NOT NULL,"active" boolean DEFAULT true,"user_id" bigint,PRIMARY KEY ("id"),CONSTRAINT "fk_users_workout_routines" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE);
+CREATE INDEX IF NOT EXISTS "idx_workout_routines_deleted_at" ON "workout_routines" ("deleted_at");
+
+CREATE TABLE "exercise_routines" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"name" varchar(32) NOT NULL,"sets" bigint NOT NULL,"reps" bigint NOT NULL,"active" boolean DEFAULT true,"workout_routine_id" bigint,PRIMARY KEY ("id"),CONSTRAINT "fk_workout_routines_exercise_routines" FOREIGN KEY ("workout_routine_id") REFERENCES "workout_routines"("id") ON DELETE CASCADE);
+CREATE INDEX IF NOT EXISTS "idx_exercise_routines_deleted_at" ON "exercise_routines" ("deleted_at");
+
+CREATE TABLE "workout_sessions" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"start" timestamptz NOT NULL,"end" timestamptz,"workout_routine_id" bigint,"user_id" bigint,PRIMARY KEY ("id"),CONSTRAINT "fk_workout_routines_workout_sessions" FOREIGN KEY ("workout_routine_id") REFERENCES "workout_routines"("id") ON DELETE CASCADE);
+CREATE INDEX IF NOT EXISTS "idx_workout_sessions_deleted_at" ON "workout_sessions" ("deleted_at");
+
+CREATE TABLE "exercises" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"notes" varchar(512),"exercise_routine_id" bigint,"workout_session_id" bigint,PRIMARY KEY ("id"),CONSTRAINT "fk_workout_sessions_exercises" FOREIGN KEY ("workout_session_id") REFERENCES "workout_sessions"("id") ON DELETE CASCADE,CONSTRAINT "fk_exercise_routines_exercises" FOREIGN KEY ("exercise_routine_id") REFERENCES "exercise_routines"("id") ON DELETE CASCADE);
+CREATE INDEX IF NOT EXISTS "idx_exercises_deleted_at" ON "exercises" ("deleted_at");
+
+-- a plain table, 0004_partition_set_entries partitions it
+CREATE TABLE "set_entries" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"weight" decimal NOT NULL,"reps" bigint NOT NULL,"exercise_id" bigint,PRIMARY KEY ("id"),CONSTRAINT "fk_exercises_sets" FOREIGN KEY ("exercise_id") REFERENCES "exercises"("id") ON DELETE CASCADE ON UPDATE CASCADE);
+CREATE INDEX IF NOT EXISTS "idx_set_entries_deleted_at" ON "set_entries" ("deleted_at");
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS "idx_exercise_routines_workout_routine_id";
+DROP INDEX IF EXISTS "idx_exercises_workout_session_id";
+DROP INDEX IF EXISTS "idx_workout_session_user_start";
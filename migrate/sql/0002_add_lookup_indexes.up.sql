@@ -0,0 +1,7 @@
+-- session history, exercises and routines were being found by sequential
+-- scans. set_entries (exercise_id) is indexed by 0004_partition_set_entries. a
+-- migration runs in one transaction, which CREATE INDEX CONCURRENTLY can't, so
+-- writes to these tables wait the few seconds the builds take
+CREATE INDEX IF NOT EXISTS "idx_workout_session_user_start" ON "workout_sessions" ("user_id","start");
+CREATE INDEX IF NOT EXISTS "idx_exercises_workout_session_id" ON "exercises" ("workout_session_id");
+CREATE INDEX IF NOT EXISTS "idx_exercise_routines_workout_routine_id" ON "exercise_routines" ("workout_routine_id");
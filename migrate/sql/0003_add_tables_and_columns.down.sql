@@ -0,0 +1,9 @@
+-- columns of the baseline tables go, and the tables that came after them.
+-- set_entries stays partitioned, 0001's down drops it
+ALTER TABLE "users" DROP COLUMN IF EXISTS "pending_email", DROP COLUMN IF EXISTS "email_change_code", DROP COLUMN IF EXISTS "email_change_sent_at", DROP COLUMN IF EXISTS "admin", DROP COLUMN IF EXISTS "record_requests_until", DROP COLUMN IF EXISTS "week_start", DROP COLUMN IF EXISTS "locale", DROP COLUMN IF EXISTS "weight_unit";
+ALTER TABLE "exercise_routines" DROP COLUMN IF EXISTS "position", DROP COLUMN IF EXISTS "catalog_exercise_id";
+ALTER TABLE "workout_sessions" DROP COLUMN IF EXISTS "client_metadata", DROP COLUMN IF EXISTS "origin_platform", DROP COLUMN IF EXISTS "origin_app_version", DROP COLUMN IF EXISTS "origin_device_id", DROP COLUMN IF EXISTS "notes";
+ALTER TABLE "exercises" DROP COLUMN IF EXISTS "user_id";
+ALTER TABLE "set_entries" DROP COLUMN IF EXISTS "unit", DROP COLUMN IF EXISTS "set_order", DROP COLUMN IF EXISTS "type", DROP COLUMN IF EXISTS "rest_time_seconds", DROP COLUMN IF EXISTS "completed_at", DROP COLUMN IF EXISTS "client_metadata", DROP COLUMN IF EXISTS "origin_platform", DROP COLUMN IF EXISTS "origin_app_version", DROP COLUMN IF EXISTS "origin_device_id";
+DROP INDEX IF EXISTS idx_workout_routines_name_fts, idx_exercise_routines_name_fts, idx_exercise_routines_name_trgm, idx_exercises_notes_fts, idx_workout_sessions_open;
+DROP TABLE IF EXISTS "weekly_digests", "push_tokens", "user_preferences", "login_throttles", "sessions", "refresh_tokens", "password_reset_tokens", "audit_logs", "sync_records", "tags", "progress_photos", "strava_uploads", "strava_connections", "webhook_deliveries", "webhooks", "workout_routine_share_links", "program_days", "programs", "snapshots", "workout_session_auto_finishes", "quick_phrases", "workout_session_unlocks", "unit_conversions", "sleep_logs", "nutrition_logs", "account_exports", "goals", "catalog_exercise_muscle_groups", "body_weight_entries", "archived_workout_sessions", "video_annotations", "exercise_videos", "coaches", "workout_session_participants", "workout_session_share_links", "request_recordings", "workout_session_tags", "catalog_exercises" CASCADE;
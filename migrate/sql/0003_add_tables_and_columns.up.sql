@@ -0,0 +1,188 @@
+-- the tables and columns added since the baseline. databases AutoMigrate
+-- built later on already have some or all of them, so everything here is
+-- skipped when it's already there
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE "users" ADD COLUMN IF NOT EXISTS "pending_email" varchar(80), ADD COLUMN IF NOT EXISTS "email_change_code" text UNIQUE, ADD COLUMN IF NOT EXISTS "email_change_sent_at" timestamptz, ADD COLUMN IF NOT EXISTS "admin" boolean DEFAULT false, ADD COLUMN IF NOT EXISTS "record_requests_until" timestamptz, ADD COLUMN IF NOT EXISTS "week_start" varchar(8) DEFAULT 'MONDAY', ADD COLUMN IF NOT EXISTS "locale" varchar(35) DEFAULT 'en-US', ADD COLUMN IF NOT EXISTS "weight_unit" varchar(2);
+
+CREATE TABLE IF NOT EXISTS "catalog_exercises" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"name" varchar(64) NOT NULL,"equipment" varchar(32),"instructions" text,PRIMARY KEY ("id"));
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_catalog_exercises_name" ON "catalog_exercises" ("name");
+CREATE INDEX IF NOT EXISTS "idx_catalog_exercises_deleted_at" ON "catalog_exercises" ("deleted_at");
+
+ALTER TABLE "exercise_routines" ADD COLUMN IF NOT EXISTS "position" bigint DEFAULT 0, ADD COLUMN IF NOT EXISTS "catalog_exercise_id" bigint CONSTRAINT "fk_exercise_routines_catalog_exercise" REFERENCES "catalog_exercises"("id");
+
+ALTER TABLE "workout_sessions" ADD COLUMN IF NOT EXISTS "client_metadata" jsonb, ADD COLUMN IF NOT EXISTS "origin_platform" varchar(16), ADD COLUMN IF NOT EXISTS "origin_app_version" varchar(32), ADD COLUMN IF NOT EXISTS "origin_device_id" varchar(64), ADD COLUMN IF NOT EXISTS "notes" text;
+
+CREATE TABLE IF NOT EXISTS "workout_session_tags" ("workout_session_id" bigint,"tag_id" bigint,PRIMARY KEY ("workout_session_id","tag_id"));
+CREATE INDEX IF NOT EXISTS "idx_workout_session_tags_tag_id" ON "workout_session_tags" ("tag_id");
+
+ALTER TABLE "exercises" ADD COLUMN IF NOT EXISTS "user_id" bigint;
+
+ALTER TABLE "set_entries" ADD COLUMN IF NOT EXISTS "unit" varchar(2), ADD COLUMN IF NOT EXISTS "set_order" bigint DEFAULT 0, ADD COLUMN IF NOT EXISTS "type" varchar(16) DEFAULT 'WORKING', ADD COLUMN IF NOT EXISTS "rest_time_seconds" bigint, ADD COLUMN IF NOT EXISTS "completed_at" timestamptz, ADD COLUMN IF NOT EXISTS "client_metadata" jsonb, ADD COLUMN IF NOT EXISTS "origin_platform" varchar(16), ADD COLUMN IF NOT EXISTS "origin_app_version" varchar(32), ADD COLUMN IF NOT EXISTS "origin_device_id" varchar(64);
+ALTER TABLE "set_entries" ALTER COLUMN "weight" TYPE numeric(10, 2);
+
+CREATE TABLE IF NOT EXISTS "request_recordings" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"user_id" bigint,"operation_name" varchar(128),"query" text,"variables" text,"duration_ms" bigint,PRIMARY KEY ("id"));
+CREATE INDEX IF NOT EXISTS "idx_request_recordings_deleted_at" ON "request_recordings" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "workout_session_share_links" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"token" text NOT NULL UNIQUE,"workout_session_id" bigint,"user_id" bigint,PRIMARY KEY ("id"),CONSTRAINT "fk_workout_session_share_links_workout_session" FOREIGN KEY ("workout_session_id") REFERENCES "workout_sessions"("id"));
+CREATE INDEX IF NOT EXISTS "idx_workout_session_share_links_deleted_at" ON "workout_session_share_links" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "workout_session_participants" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"workout_session_id" bigint,"user_id" bigint,PRIMARY KEY ("id"));
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_workout_session_participant" ON "workout_session_participants" ("workout_session_id","user_id");
+CREATE INDEX IF NOT EXISTS "idx_workout_session_participants_deleted_at" ON "workout_session_participants" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "coaches" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"user_id" bigint,"coach_id" bigint,"access" varchar(10) NOT NULL DEFAULT 'READ',PRIMARY KEY ("id"));
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_coach" ON "coaches" ("user_id","coach_id");
+CREATE INDEX IF NOT EXISTS "idx_coaches_deleted_at" ON "coaches" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "exercise_videos" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"exercise_id" bigint,"set_entry_id" bigint,"user_id" bigint,"storage_key" text NOT NULL,"content_type" varchar(64),PRIMARY KEY ("id"));
+CREATE INDEX IF NOT EXISTS "idx_exercise_videos_deleted_at" ON "exercise_videos" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "video_annotations" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"exercise_video_id" bigint,"coach_id" bigint,"timestamp_ms" bigint,"note" varchar(512),PRIMARY KEY ("id"),CONSTRAINT "fk_exercise_videos_annotations" FOREIGN KEY ("exercise_video_id") REFERENCES "exercise_videos"("id") ON DELETE CASCADE);
+CREATE INDEX IF NOT EXISTS "idx_video_annotations_deleted_at" ON "video_annotations" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "archived_workout_sessions" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"workout_session_id" bigint,"user_id" bigint,"workout_routine_id" bigint,"start" timestamptz,"end" timestamptz,"exercise_count" bigint,"set_count" bigint,"total_volume" decimal,"volume_unit" varchar(2),"storage_key" text NOT NULL,PRIMARY KEY ("id"));
+CREATE INDEX IF NOT EXISTS "idx_archived_workout_sessions_user_id" ON "archived_workout_sessions" ("user_id");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_archived_workout_sessions_workout_session_id" ON "archived_workout_sessions" ("workout_session_id");
+CREATE INDEX IF NOT EXISTS "idx_archived_workout_sessions_deleted_at" ON "archived_workout_sessions" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "body_weight_entries" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"user_id" bigint,"weight" decimal,"logged_at" timestamptz,PRIMARY KEY ("id"));
+CREATE INDEX IF NOT EXISTS "idx_body_weight_user_logged_at" ON "body_weight_entries" ("user_id","logged_at");
+CREATE INDEX IF NOT EXISTS "idx_body_weight_entries_deleted_at" ON "body_weight_entries" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "catalog_exercise_muscle_groups" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"catalog_exercise_id" bigint,"muscle_group" varchar(16),"is_primary" boolean DEFAULT false,PRIMARY KEY ("id"),CONSTRAINT "fk_catalog_exercises_muscle_groups" FOREIGN KEY ("catalog_exercise_id") REFERENCES "catalog_exercises"("id") ON DELETE CASCADE);
+CREATE INDEX IF NOT EXISTS "idx_catalog_exercise_muscle_groups_muscle_group" ON "catalog_exercise_muscle_groups" ("muscle_group");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_catalog_exercise_muscle_group" ON "catalog_exercise_muscle_groups" ("catalog_exercise_id","muscle_group");
+CREATE INDEX IF NOT EXISTS "idx_catalog_exercise_muscle_groups_deleted_at" ON "catalog_exercise_muscle_groups" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "goals" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"user_id" bigint,"type" varchar(16),"target_value" decimal,"start_value" decimal,"unit" varchar(2),"catalog_exercise_id" bigint,"deadline" timestamptz,"completed_at" timestamptz,PRIMARY KEY ("id"),CONSTRAINT "fk_goals_catalog_exercise" FOREIGN KEY ("catalog_exercise_id") REFERENCES "catalog_exercises"("id"));
+CREATE INDEX IF NOT EXISTS "idx_goals_user_id" ON "goals" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_goals_deleted_at" ON "goals" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "account_exports" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"user_id" bigint,"status" varchar(16) DEFAULT 'PENDING',"storage_key" text,"completed_at" timestamptz,PRIMARY KEY ("id"));
+CREATE INDEX IF NOT EXISTS "idx_account_exports_user_id" ON "account_exports" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_account_exports_deleted_at" ON "account_exports" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "nutrition_logs" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"user_id" bigint,"day" date,"calories" bigint,"protein_grams" decimal,"notes" varchar(256),PRIMARY KEY ("id"));
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_nutrition_user_day" ON "nutrition_logs" ("user_id","day");
+CREATE INDEX IF NOT EXISTS "idx_nutrition_logs_deleted_at" ON "nutrition_logs" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "sleep_logs" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"user_id" bigint,"night" date,"hours" decimal,PRIMARY KEY ("id"));
+CREATE INDEX IF NOT EXISTS "idx_sleep_logs_deleted_at" ON "sleep_logs" ("deleted_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_sleep_user_night" ON "sleep_logs" ("user_id","night");
+
+CREATE TABLE IF NOT EXISTS "unit_conversions" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"user_id" bigint,"exercise_routine_id" bigint,"from_unit" varchar(2),"to_unit" varchar(2),"factor" decimal,"range_start" timestamptz,"range_end" timestamptz,"sets_converted" bigint,PRIMARY KEY ("id"));
+CREATE INDEX IF NOT EXISTS "idx_unit_conversions_user_id" ON "unit_conversions" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_unit_conversions_deleted_at" ON "unit_conversions" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "workout_session_unlocks" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"workout_session_id" bigint,"user_id" bigint,"expires_at" timestamptz,PRIMARY KEY ("id"),CONSTRAINT "fk_workout_session_unlocks_workout_session" FOREIGN KEY ("workout_session_id") REFERENCES "workout_sessions"("id"));
+CREATE INDEX IF NOT EXISTS "idx_workout_session_unlocks_workout_session_id" ON "workout_session_unlocks" ("workout_session_id");
+CREATE INDEX IF NOT EXISTS "idx_workout_session_unlocks_deleted_at" ON "workout_session_unlocks" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "quick_phrases" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"user_id" bigint,"text" text,"position" bigint DEFAULT 0,PRIMARY KEY ("id"));
+CREATE INDEX IF NOT EXISTS "idx_quick_phrases_user_id" ON "quick_phrases" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_quick_phrases_deleted_at" ON "quick_phrases" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "workout_session_auto_finishes" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"workout_session_id" bigint,"user_id" bigint,"start" timestamptz,"end" timestamptz,PRIMARY KEY ("id"),CONSTRAINT "fk_workout_session_auto_finishes_workout_session" FOREIGN KEY ("workout_session_id") REFERENCES "workout_sessions"("id"));
+CREATE INDEX IF NOT EXISTS "idx_workout_session_auto_finishes_workout_session_id" ON "workout_session_auto_finishes" ("workout_session_id");
+CREATE INDEX IF NOT EXISTS "idx_workout_session_auto_finishes_deleted_at" ON "workout_session_auto_finishes" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "snapshots" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"user_id" bigint,"storage_key" text,"workout_routine_count" bigint,"workout_session_count" bigint,"restored_at" timestamptz,PRIMARY KEY ("id"));
+CREATE INDEX IF NOT EXISTS "idx_snapshots_user_id" ON "snapshots" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_snapshots_deleted_at" ON "snapshots" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "programs" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"user_id" bigint,"name" varchar(32) NOT NULL,"active" boolean DEFAULT false,PRIMARY KEY ("id"));
+CREATE INDEX IF NOT EXISTS "idx_programs_user_id" ON "programs" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_programs_deleted_at" ON "programs" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "program_days" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"program_id" bigint,"weekday" varchar(9),"workout_routine_id" bigint,PRIMARY KEY ("id"),CONSTRAINT "fk_program_days_workout_routine" FOREIGN KEY ("workout_routine_id") REFERENCES "workout_routines"("id"),CONSTRAINT "fk_programs_days" FOREIGN KEY ("program_id") REFERENCES "programs"("id") ON DELETE CASCADE);
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_program_day_weekday" ON "program_days" ("program_id","weekday");
+CREATE INDEX IF NOT EXISTS "idx_program_days_deleted_at" ON "program_days" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "workout_routine_share_links" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"token" text NOT NULL UNIQUE,"workout_routine_id" bigint,"user_id" bigint,PRIMARY KEY ("id"),CONSTRAINT "fk_workout_routine_share_links_workout_routine" FOREIGN KEY ("workout_routine_id") REFERENCES "workout_routines"("id"));
+CREATE INDEX IF NOT EXISTS "idx_workout_routine_share_links_workout_routine_id" ON "workout_routine_share_links" ("workout_routine_id");
+CREATE INDEX IF NOT EXISTS "idx_workout_routine_share_links_deleted_at" ON "workout_routine_share_links" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "webhooks" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"user_id" bigint,"url" varchar(2048) NOT NULL,"secret" varchar(64) NOT NULL,PRIMARY KEY ("id"));
+CREATE INDEX IF NOT EXISTS "idx_webhooks_user_id" ON "webhooks" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_webhooks_deleted_at" ON "webhooks" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "webhook_deliveries" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"webhook_id" bigint,"workout_session_id" bigint,"attempts" bigint,"next_attempt_at" timestamptz,"delivered_at" timestamptz,"last_error" varchar(512),PRIMARY KEY ("id"),CONSTRAINT "fk_webhook_deliveries_webhook" FOREIGN KEY ("webhook_id") REFERENCES "webhooks"("id"));
+CREATE INDEX IF NOT EXISTS "idx_webhook_deliveries_next_attempt_at" ON "webhook_deliveries" ("next_attempt_at");
+CREATE INDEX IF NOT EXISTS "idx_webhook_deliveries_webhook_id" ON "webhook_deliveries" ("webhook_id");
+CREATE INDEX IF NOT EXISTS "idx_webhook_deliveries_deleted_at" ON "webhook_deliveries" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "strava_connections" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"user_id" bigint,"athlete_id" bigint NOT NULL,"access_token" varchar(255) NOT NULL,"refresh_token" varchar(255) NOT NULL,"expires_at" timestamptz NOT NULL,PRIMARY KEY ("id"));
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_strava_connections_user_id" ON "strava_connections" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_strava_connections_deleted_at" ON "strava_connections" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "strava_uploads" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"user_id" bigint,"workout_session_id" bigint,"attempts" bigint,"next_attempt_at" timestamptz,"activity_id" bigint,"uploaded_at" timestamptz,"last_error" varchar(512),PRIMARY KEY ("id"));
+CREATE INDEX IF NOT EXISTS "idx_strava_uploads_deleted_at" ON "strava_uploads" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_strava_uploads_next_attempt_at" ON "strava_uploads" ("next_attempt_at");
+CREATE INDEX IF NOT EXISTS "idx_strava_uploads_user_id" ON "strava_uploads" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "progress_photos" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"user_id" bigint,"storage_key" text NOT NULL,"content_type" varchar(64),"taken_on" date,"body_weight" decimal,"uploaded_at" timestamptz,PRIMARY KEY ("id"));
+CREATE INDEX IF NOT EXISTS "idx_progress_photo_user_taken_on" ON "progress_photos" ("user_id","taken_on");
+CREATE INDEX IF NOT EXISTS "idx_progress_photos_deleted_at" ON "progress_photos" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "tags" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"name" varchar(32) NOT NULL,PRIMARY KEY ("id"));
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_tags_name" ON "tags" ("name");
+CREATE INDEX IF NOT EXISTS "idx_tags_deleted_at" ON "tags" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "sync_records" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"user_id" bigint,"client_id" varchar(36),"entity" varchar(16) NOT NULL,"entity_id" bigint,"last_modified" timestamptz NOT NULL,"deleted" boolean NOT NULL DEFAULT false,PRIMARY KEY ("id"));
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_sync_record_client" ON "sync_records" ("user_id","client_id");
+CREATE INDEX IF NOT EXISTS "idx_sync_records_deleted_at" ON "sync_records" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "audit_logs" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"user_id" bigint,"operation" varchar(64) NOT NULL,"entity_type" varchar(32),"entity_id" bigint,"before" jsonb,"after" jsonb,"impersonated_by" bigint,"reason" varchar(512),PRIMARY KEY ("id"));
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_impersonated_by" ON "audit_logs" ("impersonated_by");
+CREATE INDEX IF NOT EXISTS "idx_audit_log_entity" ON "audit_logs" ("entity_type","entity_id");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_user_id" ON "audit_logs" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_audit_logs_deleted_at" ON "audit_logs" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "password_reset_tokens" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"user_id" bigint NOT NULL,"token_hash" varchar(64) NOT NULL,"expires_at" timestamptz NOT NULL,"used_at" timestamptz,PRIMARY KEY ("id"));
+CREATE INDEX IF NOT EXISTS "idx_password_reset_tokens_user_id" ON "password_reset_tokens" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_password_reset_tokens_deleted_at" ON "password_reset_tokens" ("deleted_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_password_reset_tokens_token_hash" ON "password_reset_tokens" ("token_hash");
+
+CREATE TABLE IF NOT EXISTS "refresh_tokens" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"user_id" bigint NOT NULL,"family_id" varchar(64) NOT NULL,"token_id" varchar(64) NOT NULL,"used_at" timestamptz,"revoked_at" timestamptz,PRIMARY KEY ("id"));
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_refresh_tokens_token_id" ON "refresh_tokens" ("token_id");
+CREATE INDEX IF NOT EXISTS "idx_refresh_tokens_family_id" ON "refresh_tokens" ("family_id");
+CREATE INDEX IF NOT EXISTS "idx_refresh_tokens_user_id" ON "refresh_tokens" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_refresh_tokens_deleted_at" ON "refresh_tokens" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "sessions" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"user_id" bigint NOT NULL,"family_id" varchar(64) NOT NULL,"user_agent" varchar(255),"ip" varchar(64),"origin_platform" varchar(16),"origin_app_version" varchar(32),"origin_device_id" varchar(64),"last_used_at" timestamptz NOT NULL,"revoked_at" timestamptz,PRIMARY KEY ("id"));
+CREATE INDEX IF NOT EXISTS "idx_sessions_deleted_at" ON "sessions" ("deleted_at");
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_sessions_family_id" ON "sessions" ("family_id");
+CREATE INDEX IF NOT EXISTS "idx_sessions_user_id" ON "sessions" ("user_id");
+
+CREATE TABLE IF NOT EXISTS "login_throttles" ("key" varchar(330),"failures" bigint NOT NULL,"last_failed_at" timestamptz NOT NULL,PRIMARY KEY ("key"));
+
+CREATE TABLE IF NOT EXISTS "user_preferences" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"user_id" bigint NOT NULL,"time_zone" varchar(64) NOT NULL DEFAULT 'UTC',"weekly_digest_email" boolean NOT NULL,"weekly_digest_push" boolean NOT NULL,PRIMARY KEY ("id"));
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_user_preferences_user_id" ON "user_preferences" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_user_preferences_deleted_at" ON "user_preferences" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "push_tokens" ("id" bigserial,"created_at" timestamptz,"updated_at" timestamptz,"deleted_at" timestamptz,"user_id" bigint NOT NULL,"token" varchar(255) NOT NULL,PRIMARY KEY ("id"));
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_push_tokens_token" ON "push_tokens" ("token");
+CREATE INDEX IF NOT EXISTS "idx_push_tokens_user_id" ON "push_tokens" ("user_id");
+CREATE INDEX IF NOT EXISTS "idx_push_tokens_deleted_at" ON "push_tokens" ("deleted_at");
+
+CREATE TABLE IF NOT EXISTS "weekly_digests" ("id" bigserial,"user_id" bigint NOT NULL,"week_end" timestamptz NOT NULL,"created_at" timestamptz,PRIMARY KEY ("id"));
+CREATE UNIQUE INDEX IF NOT EXISTS "idx_weekly_digests_user_week" ON "weekly_digests" ("user_id","week_end");
+
+-- indexes AutoMigrate had no way to describe
+
+-- autocomplete's ILIKE '%prefix%' lookups
+CREATE INDEX IF NOT EXISTS idx_exercise_routines_name_trgm ON exercise_routines USING gin (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_catalog_exercises_name_trgm ON catalog_exercises USING gin (name gin_trgm_ops);
+
+-- search, the expressions have to match the search query's for postgres to
+-- pick them up
+CREATE INDEX IF NOT EXISTS idx_workout_routines_name_fts ON workout_routines USING gin (to_tsvector('english', name));
+CREATE INDEX IF NOT EXISTS idx_exercise_routines_name_fts ON exercise_routines USING gin (to_tsvector('english', name));
+CREATE INDEX IF NOT EXISTS idx_exercises_notes_fts ON exercises USING gin (to_tsvector('english', notes));
+
+-- one open session per user. databases from before it could have more, all
+-- but the newest are ended where they started
+UPDATE workout_sessions SET "end" = start WHERE "end" IS NULL AND deleted_at IS NULL AND id NOT IN (SELECT max(id) FROM workout_sessions WHERE "end" IS NULL AND deleted_at IS NULL GROUP BY user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_workout_sessions_open ON workout_sessions (user_id) WHERE "end" IS NULL AND deleted_at IS NULL;
@@ -0,0 +1,32 @@
+-- set_entries goes back to a plain table with the same constraints and
+-- indexes, the other way round from the up
+DO $$
+DECLARE
+	fk record;
+	idx record;
+BEGIN
+	IF (SELECT relkind FROM pg_class WHERE oid = to_regclass('set_entries')) <> 'p' THEN
+		RETURN;
+	END IF;
+
+	ALTER TABLE set_entries RENAME TO set_entries_partitioned;
+	EXECUTE format('ALTER TABLE set_entries_partitioned RENAME CONSTRAINT %I TO set_entries_partitioned_pkey', (SELECT conname FROM pg_constraint WHERE conrelid = 'set_entries_partitioned'::regclass AND contype = 'p'));
+	CREATE TABLE set_entries (LIKE set_entries_partitioned INCLUDING ALL EXCLUDING INDEXES);
+	ALTER TABLE set_entries ADD CONSTRAINT set_entries_pkey PRIMARY KEY (id);
+	-- it was only not null for the partitioned primary key
+	ALTER TABLE set_entries ALTER COLUMN created_at DROP NOT NULL;
+	FOR fk IN SELECT conname, pg_get_constraintdef(oid) AS def FROM pg_constraint WHERE conrelid = 'set_entries_partitioned'::regclass AND contype = 'f' LOOP
+		EXECUTE format('ALTER TABLE set_entries ADD CONSTRAINT %I %s', fk.conname, fk.def);
+	END LOOP;
+	-- dropping a partitioned index drops the partitions' ones with it
+	FOR idx IN SELECT indexrelid::regclass::text AS name, pg_get_indexdef(indexrelid) AS def FROM pg_index WHERE indrelid = 'set_entries_partitioned'::regclass AND NOT indisprimary LOOP
+		EXECUTE format('DROP INDEX %s', idx.name);
+		EXECUTE regexp_replace(idx.def, ' ON (ONLY )?(\S+\.)?set_entries_partitioned ', ' ON set_entries ');
+	END LOOP;
+
+	INSERT INTO set_entries SELECT * FROM set_entries_partitioned;
+	ALTER SEQUENCE set_entries_id_seq OWNED BY set_entries.id;
+	-- its partitions go with it
+	DROP TABLE set_entries_partitioned;
+END
+$$;
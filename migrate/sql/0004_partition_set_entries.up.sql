@@ -0,0 +1,62 @@
+-- set_entries becomes partitioned by month of created_at. it's rewritten in
+-- one go, so on a big table this holds a lock on it for a while. tables that
+-- are already partitioned are left alone. the server keeps making the months
+-- ahead as it goes, this makes the ones the rows need and the 3 after this
+-- one, like config.SET_ENTRY_PARTITION_MONTHS_AHEAD
+DO $$
+DECLARE
+	month timestamptz;
+	next timestamptz;
+	fk record;
+	idx record;
+BEGIN
+	IF (SELECT relkind FROM pg_class WHERE oid = to_regclass('set_entries')) <> 'r' THEN
+		RETURN;
+	END IF;
+
+	ALTER TABLE set_entries RENAME TO set_entries_unpartitioned;
+	-- the old primary key's name goes to the new one
+	EXECUTE format('ALTER TABLE set_entries_unpartitioned RENAME CONSTRAINT %I TO set_entries_unpartitioned_pkey', (SELECT conname FROM pg_constraint WHERE conrelid = 'set_entries_unpartitioned'::regclass AND contype = 'p'));
+	-- not null and check constraints and defaults come along with the
+	-- columns. the indexes can't, unique constraints on a partitioned table
+	-- have to include the partition key so the primary key changes
+	CREATE TABLE set_entries (LIKE set_entries_unpartitioned INCLUDING ALL EXCLUDING INDEXES) PARTITION BY RANGE (created_at);
+	ALTER TABLE set_entries ADD CONSTRAINT set_entries_pkey PRIMARY KEY (id, created_at);
+	-- foreign keys don't come with LIKE, so fk_exercises_sets and any others
+	-- are made again on the new table
+	FOR fk IN SELECT conname, pg_get_constraintdef(oid) AS def FROM pg_constraint WHERE conrelid = 'set_entries_unpartitioned'::regclass AND contype = 'f' LOOP
+		EXECUTE format('ALTER TABLE set_entries ADD CONSTRAINT %I %s', fk.conname, fk.def);
+	END LOOP;
+	-- and so are the other indexes. index names are shared across the schema,
+	-- so each old one is dropped before the new table takes its name
+	FOR idx IN SELECT indexrelid::regclass::text AS name, pg_get_indexdef(indexrelid) AS def FROM pg_index WHERE indrelid = 'set_entries_unpartitioned'::regclass AND NOT indisprimary LOOP
+		EXECUTE format('DROP INDEX %s', idx.name);
+		EXECUTE regexp_replace(idx.def, ' ON (\S+\.)?set_entries_unpartitioned ', ' ON set_entries ');
+	END LOOP;
+	-- the model indexes exercise_id, databases from the baseline alone don't
+	CREATE INDEX IF NOT EXISTS idx_set_entries_exercise_id ON set_entries (exercise_id);
+	CREATE INDEX IF NOT EXISTS idx_set_entries_deleted_at ON set_entries (deleted_at);
+	-- rows outside every month end up here
+	CREATE TABLE set_entries_default PARTITION OF set_entries DEFAULT;
+
+	-- months are in utc and named like set_entries_y2023m01, the same as
+	-- database.SetEntryPartitionName
+	month := date_trunc('month', LEAST(COALESCE((SELECT MIN(created_at) FROM set_entries_unpartitioned), now()), now()) AT TIME ZONE 'UTC') AT TIME ZONE 'UTC';
+	WHILE month <= now() + interval '3 months' LOOP
+		next := (month AT TIME ZONE 'UTC' + interval '1 month') AT TIME ZONE 'UTC';
+		EXECUTE format(
+			'CREATE TABLE IF NOT EXISTS %I PARTITION OF set_entries FOR VALUES FROM (%L) TO (%L)',
+			to_char(month AT TIME ZONE 'UTC', '"set_entries_y"YYYY"m"MM'), month, next
+		);
+		month := next;
+	END LOOP;
+
+	-- the partition key is part of the primary key, so it can't be null
+	UPDATE set_entries_unpartitioned SET created_at = COALESCE(updated_at, now()) WHERE created_at IS NULL;
+	INSERT INTO set_entries SELECT * FROM set_entries_unpartitioned;
+	-- the id sequence is owned by the old table, move it over before dropping
+	-- it so new sets keep counting up from the same place
+	ALTER SEQUENCE set_entries_id_seq OWNED BY set_entries.id;
+	DROP TABLE set_entries_unpartitioned;
+END
+$$;
@@ -0,0 +1 @@
+-- nothing to undo, the owners were always who the exercises belonged to
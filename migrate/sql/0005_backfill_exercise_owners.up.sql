@@ -0,0 +1,2 @@
+-- exercises logged before co-logging belong to the session's owner
+UPDATE exercises SET user_id = workout_sessions.user_id FROM workout_sessions WHERE exercises.workout_session_id = workout_sessions.id AND exercises.user_id IS NULL;
@@ -0,0 +1 @@
+ALTER TABLE "workout_session_share_links" DROP COLUMN IF EXISTS "invite";
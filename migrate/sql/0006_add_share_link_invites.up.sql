@@ -0,0 +1,3 @@
+-- share links were only for watching, invites are the ones that let someone
+-- join the session
+ALTER TABLE "workout_session_share_links" ADD COLUMN IF NOT EXISTS "invite" boolean NOT NULL DEFAULT false;
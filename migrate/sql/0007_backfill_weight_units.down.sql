@@ -0,0 +1 @@
+-- nothing to undo, the sets were always in the unit they're labelled with
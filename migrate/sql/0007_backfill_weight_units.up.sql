@@ -0,0 +1,18 @@
+-- sets, archived sessions and lift goals from before units were recorded are
+-- in whatever their owner logs in. that's their own pick, or else what their
+-- locale's region lifts in like format.NewHints works out: pounds in the US,
+-- Liberia and Myanmar, kilograms everywhere else, and locales that don't
+-- parse are taken as en-US
+CREATE TEMPORARY TABLE owner_weight_units ON COMMIT DROP AS
+SELECT id AS user_id, COALESCE(weight_unit, CASE
+		WHEN locale !~ '^[a-zA-Z]{2,3}([-_]([a-zA-Z]{2}|[0-9]{3}))?$' OR locale IS NULL THEN 'LB'
+		WHEN upper(substring(locale FROM '^[a-zA-Z]{2,3}[-_]([a-zA-Z]{2})$')) IN ('US', 'LR', 'MM') THEN 'LB'
+		ELSE 'KG'
+	END) AS unit
+FROM users;
+
+-- deleted rows are labelled too, they can still be restored. updated_at is
+-- left alone so clients' expected versions still match
+UPDATE set_entries SET unit = owner_weight_units.unit FROM exercises, owner_weight_units WHERE set_entries.exercise_id = exercises.id AND exercises.user_id = owner_weight_units.user_id AND COALESCE(set_entries.unit, '') = '';
+UPDATE archived_workout_sessions SET volume_unit = owner_weight_units.unit FROM owner_weight_units WHERE archived_workout_sessions.user_id = owner_weight_units.user_id AND COALESCE(archived_workout_sessions.volume_unit, '') = '';
+UPDATE goals SET unit = owner_weight_units.unit FROM owner_weight_units WHERE goals.user_id = owner_weight_units.user_id AND COALESCE(goals.unit, '') = '';
@@ -0,0 +1,100 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/database"
+	"gorm.io/gorm"
+)
+
+const FCMBaseURL = "https://fcm.googleapis.com"
+
+// FCM pushes notifications to every device the user registered through
+// Firebase Cloud Messaging's http api
+type FCM struct {
+	DB        *gorm.DB
+	BaseURL   string
+	ServerKey string
+	Client    *http.Client
+}
+
+func NewFCM(db *gorm.DB, serverKey string, timeout time.Duration) *FCM {
+	return &FCM{
+		DB:        db,
+		BaseURL:   FCMBaseURL,
+		ServerKey: serverKey,
+		Client:    &http.Client{Timeout: timeout},
+	}
+}
+
+func (*FCM) Channel() string {
+	return ChannelPush
+}
+
+type fcmMessage struct {
+	RegistrationIDs []string `json:"registration_ids"`
+	Notification    struct {
+		Title string `json:"title"`
+		Body  string `json:"body"`
+	} `json:"notification"`
+}
+
+// results line up with the registration ids they were sent to
+type fcmResponse struct {
+	Results []struct {
+		Error string `json:"error"`
+	} `json:"results"`
+}
+
+// Notify sends to all of the user's devices at once, nothing is sent when
+// they have none. Tokens FCM says are gone are dropped
+func (f *FCM) Notify(to *Recipient, n *Notification) error {
+	tokens, err := database.GetPushTokens(f.DB, to.UserID)
+	if err != nil || len(tokens) == 0 {
+		return err
+	}
+
+	msg := fcmMessage{RegistrationIDs: tokens}
+	msg.Notification.Title = n.Subject
+	msg.Notification.Body = n.Text
+	payload, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, f.BaseURL+"/fcm/send", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "key="+f.ServerKey)
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("fcm responded %s: %s", resp.Status, b)
+	}
+
+	var result fcmResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	gone := []string{}
+	for i, r := range result.Results {
+		if i < len(tokens) && (r.Error == "NotRegistered" || r.Error == "InvalidRegistration") {
+			gone = append(gone, tokens[i])
+		}
+	}
+	if len(gone) > 0 {
+		return database.DeletePushTokens(f.DB, gone)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package notify
+
+import (
+	"os"
+
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/mail"
+	"gorm.io/gorm"
+)
+
+// channels line up with how users opt out of notifications
+const (
+	ChannelEmail = "EMAIL"
+	ChannelPush  = "PUSH"
+)
+
+type Recipient struct {
+	UserID uint
+	Name   string
+	Email  string
+}
+
+// Notification is the same news for every channel, emails get HTML and push
+// notifications Subject as their title and Text as their body
+type Notification struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Notifier gets a notification to a user over one channel
+type Notifier interface {
+	Channel() string
+	Notify(to *Recipient, n *Notification) error
+}
+
+// Email sends notifications with the Mailer
+type Email struct {
+	Mailer mail.Mailer
+}
+
+func (Email) Channel() string {
+	return ChannelEmail
+}
+
+func (e Email) Notify(to *Recipient, n *Notification) error {
+	return e.Mailer.Send([]string{to.Email}, n.Subject, n.HTML)
+}
+
+// NewNotifiers is email through mail.NewMailer, and push when FCM_SERVER_KEY
+// is set
+func NewNotifiers(db *gorm.DB) []Notifier {
+	notifiers := []Notifier{Email{Mailer: mail.NewMailer()}}
+	if key := os.Getenv(config.FCM_SERVER_KEY); key != "" {
+		notifiers = append(notifiers, NewFCM(db, key, config.FCM_TIMEOUT))
+	}
+	return notifiers
+}
@@ -6,35 +6,33 @@ import (
 	"time"
 )
 
-const (
-	workoutSessionId = 0
-	workoutRoutineId = 1
-	date             = 2
-)
-
-// serializable struct that can be passed to the Prev Exercise loader function
+// serializable struct that can be passed to the Prev Exercise loader function.
+// sessions of the same routine that started together have the same previous
+// exercises, so they share a key
 type PrevExerciseArgs struct {
-	WorkoutSessionId string
 	WorkoutRoutineID string
-	Date             time.Time
+	Before           time.Time
 }
 
 func (p *PrevExerciseArgs) String() string {
-	dateString := p.Date.Format(time.RFC3339)
-	// serializes into comma separated string
-	return fmt.Sprintf("%s,%s,%s", p.WorkoutSessionId, p.WorkoutRoutineID, dateString)
+	// serializes into comma separated string, to the nanosecond so a session
+	// started within a second of the one before still finds it. in utc so the
+	// same time in two zones is one key
+	return fmt.Sprintf("%s,%s", p.WorkoutRoutineID, p.Before.UTC().Format(time.RFC3339Nano))
 }
 
 func BuildPrevExerciseArgs(s string) (*PrevExerciseArgs, error) {
 	args := strings.Split(s, ",")
-	date, err := time.Parse(time.RFC3339, args[date])
+	if len(args) != 2 {
+		return nil, fmt.Errorf("prev exercise key %q isn't a routine and a time", s)
+	}
+	before, err := time.Parse(time.RFC3339Nano, args[1])
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	return &PrevExerciseArgs{
-		WorkoutSessionId: args[workoutSessionId],
-		WorkoutRoutineID: args[workoutRoutineId],
-		Date:             date,
+		WorkoutRoutineID: args[0],
+		Before:           before,
 	}, nil
 }
@@ -39,21 +39,35 @@ type SetEntrySliceReader struct {
 	DB *gorm.DB
 }
 
+type CatalogExerciseReader struct {
+	DB *gorm.DB
+}
+
+type TagSliceReader struct {
+	DB *gorm.DB
+}
+
+// a batch's statements count towards the request that sent it
+func countedDB(ctx context.Context, db *gorm.DB) *gorm.DB {
+	return db.WithContext(database.QueryStatsContext(ctx))
+}
+
 func (w *WorkoutRoutineReader) GetWorkoutRoutines(ctx context.Context, keys dataloader.Keys) []*dataloader.Result {
 	workoutSessionIds := []string{}
 	for _, key := range keys {
 		workoutSessionIds = append(workoutSessionIds, key.String())
 	}
 
-	workoutSessions, _ := database.GetWorkoutSessionsById(w.DB, workoutSessionIds)
+	workoutSessions, _ := database.GetWorkoutSessionsById(countedDB(ctx, w.DB), workoutSessionIds)
 	workoutRoutineById := map[string]*model.WorkoutRoutine{}
 	for _, workoutSession := range *workoutSessions {
 		workoutSessionId := strconv.Itoa(int(workoutSession.ID))
 		workoutRoutineId := strconv.Itoa(int(workoutSession.WorkoutRoutine.ID))
 		workoutRoutineById[workoutSessionId] = &model.WorkoutRoutine{
-			ID:     workoutRoutineId,
-			Name:   workoutSession.WorkoutRoutine.Name,
-			Active: workoutSession.WorkoutRoutine.Active,
+			ID:        workoutRoutineId,
+			Name:      workoutSession.WorkoutRoutine.Name,
+			Active:    workoutSession.WorkoutRoutine.Active,
+			UpdatedAt: workoutSession.WorkoutRoutine.UpdatedAt,
 		}
 	}
 
@@ -75,7 +89,7 @@ func (e *ExerciseRoutineSliceReader) GetExerciseRoutineSlices(ctx context.Contex
 	for _, key := range keys {
 		workoutRoutineIds = append(workoutRoutineIds, key.String())
 	}
-	exerciseRoutines, _ := database.GetExerciseRoutinesByWorkoutRoutineId(e.DB, workoutRoutineIds)
+	exerciseRoutines, _ := database.GetExerciseRoutinesByWorkoutRoutineId(countedDB(ctx, e.DB), workoutRoutineIds)
 	exerciseRoutinesByWorkoutRoutineId := map[string][]*model.ExerciseRoutine{}
 	for _, exerciseRoutine := range *exerciseRoutines {
 		workoutRoutineId := utils.UIntToString(exerciseRoutine.WorkoutRoutineID)
@@ -119,7 +133,7 @@ func (e *ExerciseRoutineReader) GetExerciseRoutines(ctx context.Context, keys da
 		exerciseIds = append(exerciseIds, key.String())
 	}
 
-	exercises, _ := database.GetExercisesById(e.DB, exerciseIds)
+	exercises, _ := database.GetExercisesById(countedDB(ctx, e.DB), exerciseIds)
 
 	// convert to graphql models and store in a dict with exercise id as key
 	exerciseRoutineByExerciseId := map[string]*model.ExerciseRoutine{}
@@ -156,21 +170,25 @@ func (e *ExerciseSliceReader) GetExerciseSlices(ctx context.Context, keys datalo
 		workoutSessionIds = append(workoutSessionIds, key.String())
 	}
 
-	exercises, _ := database.GetExercisesByWorkoutSessionId(e.DB, workoutSessionIds)
+	exercises, _ := database.GetExercisesByWorkoutSessionId(countedDB(ctx, e.DB), workoutSessionIds)
 	exerciseSlicesByWorkoutSession := map[string][]*model.Exercise{}
 	for _, exercise := range *exercises {
 		workoutSessionId := utils.UIntToString(exercise.WorkoutSessionID)
 		exerciseId := utils.UIntToString(exercise.ID)
 		if _, ok := exerciseSlicesByWorkoutSession[workoutSessionId]; ok {
 			exerciseSlicesByWorkoutSession[workoutSessionId] = append(exerciseSlicesByWorkoutSession[workoutSessionId], &model.Exercise{
-				ID:    exerciseId,
-				Notes: exercise.Notes,
+				ID:        exerciseId,
+				Notes:     exercise.Notes,
+				LoggedBy:  utils.UIntToString(exercise.UserID),
+				UpdatedAt: exercise.UpdatedAt,
 			})
 		} else {
 			exerciseSlicesByWorkoutSession[workoutSessionId] = []*model.Exercise{
 				{
-					ID:    exerciseId,
-					Notes: exercise.Notes,
+					ID:        exerciseId,
+					Notes:     exercise.Notes,
+					LoggedBy:  utils.UIntToString(exercise.UserID),
+					UpdatedAt: exercise.UpdatedAt,
 				},
 			}
 		}
@@ -188,31 +206,84 @@ func (e *ExerciseSliceReader) GetExerciseSlices(ctx context.Context, keys datalo
 	return output
 }
 
+// keys are PrevExerciseArgs, all of a batch's are looked up in one statement
+func (p *PrevExerciseSliceReader) GetPrevExerciseSlices(ctx context.Context, keys dataloader.Keys) []*dataloader.Result {
+	output := make([]*dataloader.Result, len(keys))
+	of := make([]database.PrevExercisesOf, 0, len(keys))
+	// keys that parsed, by where they are in of
+	positions := make([]int, 0, len(keys))
+	for i, key := range keys {
+		args, err := BuildPrevExerciseArgs(key.String())
+		if err != nil {
+			output[i] = &dataloader.Result{Data: nil, Error: err}
+			continue
+		}
+		of = append(of, database.PrevExercisesOf{WorkoutRoutineID: args.WorkoutRoutineID, Before: args.Before})
+		positions = append(positions, i)
+	}
+
+	prev, err := database.GetPrevExercises(countedDB(ctx, p.DB), of)
+	for n, i := range positions {
+		if err != nil {
+			output[i] = &dataloader.Result{Data: nil, Error: err}
+			continue
+		}
+		exercises := make([]*model.Exercise, 0, len(prev[n]))
+		for _, e := range prev[n] {
+			exercises = append(exercises, &model.Exercise{
+				ID:        utils.UIntToString(e.ID),
+				Notes:     e.Notes,
+				LoggedBy:  utils.UIntToString(e.UserID),
+				UpdatedAt: e.UpdatedAt,
+			})
+		}
+		output[i] = &dataloader.Result{Data: exercises, Error: nil}
+	}
+
+	return output
+}
+
 func (s *SetEntrySliceReader) GetSetEntrySlices(ctx context.Context, keys dataloader.Keys) []*dataloader.Result {
 	exerciseIds := []string{}
 	for _, key := range keys {
 		exerciseIds = append(exerciseIds, key.String())
 	}
 
-	setEntries, _ := database.GetSetsByExerciseId(s.DB, exerciseIds)
+	setEntries, _ := database.GetSetsByExerciseId(countedDB(ctx, s.DB), exerciseIds)
 	setEntrySlicesByExerciseId := map[string][]*model.SetEntry{}
 	for _, setEntry := range *setEntries {
 		exerciseId := utils.UIntToString(setEntry.ExerciseID)
 		setEntryId := utils.UIntToString(setEntry.ID)
+		var restTimeSeconds *int
+		if setEntry.RestTimeSeconds != nil {
+			rest := int(*setEntry.RestTimeSeconds)
+			restTimeSeconds = &rest
+		}
+		var origin *model.ClientOrigin
+		if setEntry.Origin != (database.Origin{}) {
+			origin = &model.ClientOrigin{
+				Platform:   setEntry.Origin.Platform,
+				AppVersion: setEntry.Origin.AppVersion,
+				DeviceID:   setEntry.Origin.DeviceID,
+			}
+		}
+		set := &model.SetEntry{
+			ID:              setEntryId,
+			Weight:          float64(setEntry.Weight),
+			Unit:            model.WeightUnit(setEntry.Unit),
+			Reps:            int(setEntry.Reps),
+			SetOrder:        int(setEntry.SetOrder),
+			Type:            model.SetType(setEntry.Type),
+			RestTimeSeconds: restTimeSeconds,
+			CompletedAt:     setEntry.CompletedAt,
+			ClientMetadata:  setEntry.ClientMetadata,
+			Origin:          origin,
+			UpdatedAt:       setEntry.UpdatedAt,
+		}
 		if _, ok := setEntrySlicesByExerciseId[exerciseId]; ok {
-			setEntrySlicesByExerciseId[exerciseId] = append(setEntrySlicesByExerciseId[exerciseId], &model.SetEntry{
-				ID:     setEntryId,
-				Weight: float64(setEntry.Weight),
-				Reps:   int(setEntry.Reps),
-			})
+			setEntrySlicesByExerciseId[exerciseId] = append(setEntrySlicesByExerciseId[exerciseId], set)
 		} else {
-			setEntrySlicesByExerciseId[exerciseId] = []*model.SetEntry{
-				{
-					ID:     setEntryId,
-					Weight: float64(setEntry.Weight),
-					Reps:   int(setEntry.Reps),
-				},
-			}
+			setEntrySlicesByExerciseId[exerciseId] = []*model.SetEntry{set}
 		}
 	}
 
@@ -227,3 +298,73 @@ func (s *SetEntrySliceReader) GetSetEntrySlices(ctx context.Context, keys datalo
 
 	return output
 }
+
+// keys are exercise routine ids, routines that don't reference the catalog
+// load nil
+func (c *CatalogExerciseReader) GetCatalogExercises(ctx context.Context, keys dataloader.Keys) []*dataloader.Result {
+	exerciseRoutineIds := []string{}
+	for _, key := range keys {
+		exerciseRoutineIds = append(exerciseRoutineIds, key.String())
+	}
+
+	catalogExercisesByExerciseRoutineId := map[string]*model.CatalogExercise{}
+	references, _ := database.GetCatalogExerciseIdsByExerciseRoutineId(countedDB(ctx, c.DB), exerciseRoutineIds)
+	if len(references) > 0 {
+		catalogExerciseIds := []string{}
+		for _, reference := range references {
+			catalogExerciseIds = append(catalogExerciseIds, utils.UIntToString(reference.CatalogExerciseID))
+		}
+
+		catalogExercises, _ := database.GetCatalogExercisesById(countedDB(ctx, c.DB), catalogExerciseIds)
+		catalogExercisesById := map[uint]*model.CatalogExercise{}
+		for _, catalogExercise := range catalogExercises {
+			muscleGroups := []model.MuscleGroup{}
+			for _, muscleGroup := range catalogExercise.MuscleGroups {
+				muscleGroups = append(muscleGroups, model.MuscleGroup(muscleGroup.MuscleGroup))
+			}
+			catalogExercisesById[catalogExercise.ID] = &model.CatalogExercise{
+				ID:           utils.UIntToString(catalogExercise.ID),
+				Name:         catalogExercise.Name,
+				MuscleGroups: muscleGroups,
+				Equipment:    catalogExercise.Equipment,
+				Instructions: catalogExercise.Instructions,
+			}
+		}
+
+		for _, reference := range references {
+			catalogExercisesByExerciseRoutineId[utils.UIntToString(reference.ExerciseRoutineID)] = catalogExercisesById[reference.CatalogExerciseID]
+		}
+	}
+
+	var output []*dataloader.Result
+	for _, exerciseRoutineKey := range keys {
+		output = append(output, &dataloader.Result{Data: catalogExercisesByExerciseRoutineId[exerciseRoutineKey.String()], Error: nil})
+	}
+
+	return output
+}
+
+func (t *TagSliceReader) GetTagSlices(ctx context.Context, keys dataloader.Keys) []*dataloader.Result {
+	workoutSessionIds := []string{}
+	for _, key := range keys {
+		workoutSessionIds = append(workoutSessionIds, key.String())
+	}
+
+	tagNames, _ := database.GetTagNamesByWorkoutSessionId(countedDB(ctx, t.DB), workoutSessionIds)
+	tagsByWorkoutSession := map[string][]string{}
+	for _, tagName := range tagNames {
+		workoutSessionId := utils.UIntToString(tagName.WorkoutSessionID)
+		tagsByWorkoutSession[workoutSessionId] = append(tagsByWorkoutSession[workoutSessionId], tagName.Name)
+	}
+
+	var output []*dataloader.Result
+	for _, workoutSessionKey := range keys {
+		if tags, ok := tagsByWorkoutSession[workoutSessionKey.String()]; ok {
+			output = append(output, &dataloader.Result{Data: tags, Error: nil})
+		} else {
+			output = append(output, &dataloader.Result{Data: []string{}, Error: nil})
+		}
+	}
+
+	return output
+}
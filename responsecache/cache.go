@@ -0,0 +1,103 @@
+package responsecache
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/cache"
+	"github.com/neilZon/workout-logger-api/clock"
+)
+
+// Cache keeps query results for a short while so the app pulling to refresh
+// over and over doesn't read the same rows every time. Every result is in
+// one or more scopes, invalidating a scope drops everything in it
+type Cache struct {
+	store cache.Cache
+	ttl   time.Duration
+}
+
+// New keeps responses in store, which has to be shared when there's more than
+// one instance or a mutation on one won't invalidate what the others cached
+func New(store cache.Cache, ttl time.Duration) *Cache {
+	return &Cache{store: store, ttl: ttl}
+}
+
+// UserScope is everything cached for the user, it's invalidated by any of
+// their mutations
+func UserScope(userId string) string {
+	return "user:" + userId
+}
+
+// WorkoutRoutineScope is everything cached about the routine for anyone who
+// can read it, like the user's coaches
+func WorkoutRoutineScope(workoutRoutineId string) string {
+	return "workout_routine:" + workoutRoutineId
+}
+
+// Key is the key for a field's result for the user with the arguments
+func Key(field string, userId string, args ...interface{}) string {
+	b, err := json.Marshal(args)
+	if err != nil {
+		return ""
+	}
+	return field + ":" + userId + ":" + string(b)
+}
+
+// Get reads a cached result into into, false when there isn't one. A nil
+// cache never has anything
+func (c *Cache) Get(key string, scopes []string, into interface{}) bool {
+	if c == nil || key == "" {
+		return false
+	}
+	b, ok := c.store.Get(c.scopedKey(key, scopes))
+	if !ok {
+		return false
+	}
+	return json.Unmarshal(b, into) == nil
+}
+
+func (c *Cache) Set(key string, scopes []string, value interface{}) {
+	if c == nil || key == "" {
+		return
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	c.store.Set(c.scopedKey(key, scopes), b, c.ttl)
+}
+
+// Invalidate drops everything cached in the scope. Rather than finding
+// every key, which Redis can't do cheaply, the scope gets a new
+// version that's part of the key
+func (c *Cache) Invalidate(scope string) {
+	if c == nil {
+		return
+	}
+	// results cached before this expire before the version does, so it going
+	// away can't bring them back
+	version := fmt.Sprintf("%d", clock.Now().UnixNano())
+	c.store.Set(versionKey(scope), []byte(version), c.ttl)
+}
+
+func (c *Cache) scopedKey(key string, scopes []string) string {
+	var b strings.Builder
+	b.WriteString(key)
+	for _, scope := range scopes {
+		version, ok := c.store.Get(versionKey(scope))
+		if !ok {
+			version = []byte("0")
+		}
+		b.WriteString("|")
+		b.WriteString(scope)
+		b.WriteString("@")
+		b.Write(version)
+	}
+	return b.String()
+}
+
+func versionKey(scope string) string {
+	return "version:" + scope
+}
@@ -0,0 +1,165 @@
+package schedule
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/clock"
+)
+
+// Schedule is when a job runs, from a cron spec of minute, hour, day of month,
+// month and day of week. Fields take *, numbers, ranges like 1-5, steps like
+// */15 and lists of those like 1,3,5. Sunday is 0. Like cron, a day matches
+// either day field when both are restricted
+type Schedule struct {
+	minute, hour, dom, month, dow uint64 // bit n set when n matches
+	domAny, dowAny                bool
+}
+
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = []field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// Parse reads a spec like "0 8 * * 1", eight in the morning every monday
+func Parse(spec string) (*Schedule, error) {
+	parts := strings.Fields(spec)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("schedule %q needs %d fields, it has %d", spec, len(fields), len(parts))
+	}
+
+	bits := make([]uint64, len(fields))
+	for i, f := range fields {
+		b, err := parseField(parts[i], f)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %q: %w", spec, err)
+		}
+		bits[i] = b
+	}
+	return &Schedule{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: parts[2] == "*",
+		dowAny: parts[4] == "*",
+	}, nil
+}
+
+func parseField(s string, f field) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(s, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("bad step in %s %q", f.name, part)
+			}
+			step = n
+			part = part[:i]
+		}
+
+		lo, hi := f.min, f.max
+		if part != "*" {
+			var err error
+			bounds := strings.SplitN(part, "-", 2)
+			lo, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return 0, fmt.Errorf("bad %s %q", f.name, part)
+			}
+			hi = lo
+			if len(bounds) == 2 {
+				hi, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return 0, fmt.Errorf("bad %s %q", f.name, part)
+				}
+			} else if step > 1 {
+				// 5/15 is 5 and every 15 after
+				hi = f.max
+			}
+		}
+		if lo < f.min || hi > f.max || lo > hi {
+			return 0, fmt.Errorf("%s %q is out of %d-%d", f.name, part, f.min, f.max)
+		}
+		for n := lo; n <= hi; n += step {
+			bits |= 1 << uint(n)
+		}
+	}
+	return bits, nil
+}
+
+// Next is the first minute after t the schedule matches, in t's location. It's
+// the zero time when nothing matches in the next five years, like a 31st of
+// February
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) matchesDay(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domAny || s.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Start runs job at every time the schedule matches until stop is closed, it's
+// handed the time it was due. Runs missed while the server was down aren't
+// made up
+func Start(s *Schedule, name string, job func(at time.Time) error, stop <-chan struct{}) {
+	go func() {
+		for {
+			now := clock.Now()
+			at := s.Next(now)
+			if at.IsZero() {
+				log.Printf("%s never runs, its schedule doesn't match any time", name)
+				return
+			}
+
+			timer := time.NewTimer(at.Sub(now))
+			select {
+			case <-timer.C:
+				if err := job(at); err != nil {
+					log.Printf("error running %s: %v", name, err)
+				}
+			case <-stop:
+				timer.Stop()
+				return
+			}
+		}
+	}()
+}
@@ -6,17 +6,34 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/99designs/gqlgen/graphql/handler/extension"
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/joho/godotenv"
 	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/archive"
+	"github.com/neilZon/workout-logger-api/autofinish"
+	"github.com/neilZon/workout-logger-api/cache"
+	"github.com/neilZon/workout-logger-api/chaos"
+	"github.com/neilZon/workout-logger-api/clock"
 	"github.com/neilZon/workout-logger-api/config"
 	"github.com/neilZon/workout-logger-api/database"
 	db "github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/digest"
+	"github.com/neilZon/workout-logger-api/e2e"
+	"github.com/neilZon/workout-logger-api/export"
+	"github.com/neilZon/workout-logger-api/goal"
+	"github.com/neilZon/workout-logger-api/health"
 	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/integration"
+	"github.com/neilZon/workout-logger-api/live"
+	"github.com/neilZon/workout-logger-api/media"
 	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/notify"
+	"github.com/neilZon/workout-logger-api/tracing"
 	"github.com/rs/cors"
 	"github.com/vektah/gqlparser/v2/gqlerror"
 	"gorm.io/gorm"
@@ -30,19 +47,164 @@ func main() {
 		log.Fatalf("Error loading .env file")
 	}
 
+	// http.Dir("") would be the working directory, .env and all
+	mediaDir := os.Getenv(config.MEDIA_DIR)
+	if mediaDir == "" {
+		log.Fatalf("%s needs to be set to where form check videos are kept", config.MEDIA_DIR)
+	}
+	archiveDir := os.Getenv(config.ARCHIVE_DIR)
+	if archiveDir == "" {
+		log.Fatalf("%s needs to be set to where archived sessions, exports and snapshots are kept", config.ARCHIVE_DIR)
+	}
+
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = defaultPort
 	}
 
+	// before the database so its queries are traced too
+	shutdownTracing, err := tracing.Init(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer shutdownTracing(context.Background())
+
 	db, err := db.InitDb()
 	if err != nil {
 		log.Fatal(err)
 	}
+	// deferred first so it runs last, after the background jobs are told to
+	// stop
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer sqlDB.Close()
+
+	// faults are only for trying out retries, never leave them on in production
+	chaosConfig, err := config.ChaosFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if chaosConfig.DB.Enabled() {
+		log.Printf("chaos: slowing %.1f%% of database calls by %s and failing %.1f%%", chaosConfig.DB.LatencyPercent, chaosConfig.DB.Latency, chaosConfig.DB.ErrorPercent)
+		if err := db.Use(&database.ChaosPlugin{Injector: chaos.NewInjector(chaosConfig.DB)}); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// statements are only counted for requests that ask for their cost
+	if err := db.Use(&database.QueryStatsPlugin{}); err != nil {
+		log.Fatal(err)
+	}
+
+	stopPartitionMaintenance := make(chan struct{})
+	defer close(stopPartitionMaintenance)
+	database.StartSetEntryPartitionMaintenance(db, config.SET_ENTRY_PARTITION_INTERVAL, config.SET_ENTRY_PARTITION_MONTHS_AHEAD, stopPartitionMaintenance)
+
+	stopArchiver := make(chan struct{})
+	defer close(stopArchiver)
+	// the one store for archived sessions, account exports and snapshots, the
+	// resolvers and the export download get it too
+	archiveStore := media.NewLocalStore(archiveDir, "")
+	archiver := archive.NewArchiver(db, archiveStore, config.ARCHIVE_AFTER, config.ARCHIVE_BATCH_SIZE)
+	archiver.Start(config.ARCHIVE_INTERVAL, stopArchiver)
+
+	stopGoalChecker := make(chan struct{})
+	defer close(stopGoalChecker)
+	goalChecker := goal.NewChecker(db, config.GOAL_CHECK_BATCH_SIZE)
+	goalChecker.Start(config.GOAL_CHECK_INTERVAL, stopGoalChecker)
+
+	// spectators of a session are let go wherever it's finished from
+	liveBroker := live.NewBroker()
+
+	stopAutoFinisher := make(chan struct{})
+	defer close(stopAutoFinisher)
+	if openFor := autofinish.OpenFor(); openFor > 0 {
+		autoFinisher := autofinish.NewFinisher(db, openFor, config.AUTO_FINISH_BATCH_SIZE, liveBroker)
+		autoFinisher.Start(config.AUTO_FINISH_INTERVAL, stopAutoFinisher)
+	}
+
+	stopAccountExporter := make(chan struct{})
+	defer close(stopAccountExporter)
+	accountExporter := export.NewAccountExporter(db, archiveStore, config.ACCOUNT_EXPORT_BATCH_SIZE, config.ACCOUNT_EXPORT_TTL)
+	accountExporter.Start(config.ACCOUNT_EXPORT_INTERVAL, stopAccountExporter)
+
+	stopWebhookDeliverer := make(chan struct{})
+	defer close(stopWebhookDeliverer)
+	webhookDeliverer := integration.NewDeliverer(db, config.WEBHOOK_TIMEOUT, config.WEBHOOK_BATCH_SIZE, config.WEBHOOK_MAX_ATTEMPTS, config.WEBHOOK_BACKOFF)
+	webhookDeliverer.Start(config.WEBHOOK_INTERVAL, stopWebhookDeliverer)
+
+	stopStravaSyncer := make(chan struct{})
+	defer close(stopStravaSyncer)
+	strava := integration.NewStrava(os.Getenv(config.STRAVA_CLIENT_ID), os.Getenv(config.STRAVA_CLIENT_SECRET), config.STRAVA_TIMEOUT)
+	if strava.Enabled() {
+		stravaSyncer := integration.NewStravaSyncer(db, strava, config.STRAVA_BATCH_SIZE, config.STRAVA_MAX_ATTEMPTS, config.STRAVA_BACKOFF, config.STRAVA_REFRESH_BEFORE)
+		stravaSyncer.Start(config.STRAVA_INTERVAL, stopStravaSyncer)
+	}
+
+	stopDigester := make(chan struct{})
+	defer close(stopDigester)
+	digestSchedule, err := digest.Schedule()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if digestSchedule != nil {
+		digester := digest.NewDigester(db, notify.NewNotifiers(db), config.DIGEST_BATCH_SIZE)
+		digester.Start(digestSchedule, stopDigester)
+	}
+
+	// in memory unless REDIS_URL is set, which it has to be with more than one
+	// instance or they'll serve each other's stale loads and responses
+	sharedCache, err := cache.New(os.Getenv(config.REDIS_URL), config.CACHE_SWEEP_INTERVAL)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	acs := accesscontrol.NewAccessControllerService(db)
-	srv := helpers.NewGqlServer(db, acs)
+	acs := accesscontrol.NewSharedAccessControllerService(db, sharedCache)
+	srv := helpers.NewSharedGqlServer(db, acs, sharedCache, liveBroker, archiveStore)
 	srv.Use(extension.Introspection{})
+	srv.Use(middleware.Tracer{})
+	minClientVersion := middleware.MinClientVersion{
+		Minimum: os.Getenv(config.MIN_CLIENT_VERSION),
+		StoreURLs: map[string]string{
+			"ios":     os.Getenv(config.IOS_STORE_URL),
+			"android": os.Getenv(config.ANDROID_STORE_URL),
+		},
+	}
+	srv.Use(minClientVersion)
+	// sandbox devs get more room to experiment
+	quotaMultiplier := 1
+	if os.Getenv(config.SANDBOX) == "true" {
+		quotaMultiplier = config.SANDBOX_QUOTA_MULTIPLIER
+	}
+	// shared with the read only endpoint so it isn't a second allowance
+	rateLimit := middleware.RateLimit{
+		Operations: middleware.NewRateLimiter(config.RATE_LIMIT_PER_MINUTE*quotaMultiplier, config.RATE_LIMIT_BURST*quotaMultiplier),
+		Auth:       middleware.NewRateLimiter(config.AUTH_RATE_LIMIT_PER_MINUTE, config.AUTH_RATE_LIMIT_BURST),
+	}
+	srv.Use(rateLimit)
+	srv.Use(middleware.LowBandwidth{})
+	srv.Use(&middleware.QueryCost{})
+	responseQuota := middleware.ResponseQuota{
+		MaxBytes: config.MAX_RESPONSE_BYTES * quotaMultiplier,
+		MaxNodes: int64(config.MAX_RESPONSE_NODES * quotaMultiplier),
+	}
+	srv.Use(responseQuota)
+	srv.Use(middleware.RequestRecorder{
+		DB:        db,
+		RecordAll: os.Getenv(config.RECORD_ALL_REQUESTS) == "true",
+		Cache:     sharedCache,
+	})
+	auditLog := &middleware.AuditLog{DB: db}
+	srv.Use(auditLog)
+	if chaosConfig.Resolvers.Enabled() {
+		log.Printf("chaos: slowing %.1f%% of resolver calls by %s and failing %.1f%%, resolvers: %v", chaosConfig.Resolvers.LatencyPercent, chaosConfig.Resolvers.Latency, chaosConfig.Resolvers.ErrorPercent, chaosConfig.ResolverFields)
+		srv.Use(middleware.Chaos{
+			Injector: chaos.NewInjector(chaosConfig.Resolvers),
+			Fields:   chaosConfig.ResolverFields,
+		})
+	}
 	srv.SetRecoverFunc(func(ctx context.Context, err interface{}) error {
 		// notify bug tracker...maybe? idk too much money
 		if err != nil {
@@ -51,20 +213,97 @@ func main() {
 		return gqlerror.Errorf("Internal server error")
 	})
 
+	allowedOrigins, err := config.AllowedOriginsFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
 	c := cors.New(cors.Options{
-		AllowedOrigins:   []string{"http://127.0.0.1", "http://localhost:8080", "https://hoppscotch.io/"},
+		AllowedOrigins:   allowedOrigins,
 		AllowCredentials: true,
 		Debug:            false,
-		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization", middleware.ClientPlatformHeader, middleware.ClientAppVersionHeader, middleware.ClientDeviceIDHeader, middleware.LowBandwidthHeader, middleware.DebugCostHeader, "traceparent", "tracestate"},
 	})
 
-	loaders := helpers.NewLoaders(db)
+	// who's calling is only read from X-Forwarded-For when it was set by them
+	trustedProxies, err := config.TrustedProxiesFromEnv()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	authenticator, err := middleware.NewAuthenticator(os.Getenv(config.AUTH_PROVIDER), db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	authenticate := middleware.Authenticate(authenticator)
+
+	loaders := helpers.NewSharedLoaders(db, sharedCache)
 
 	dataloaderMiddleware := middleware.DataloaderMiddleware(loaders, srv)
-	authMiddleware := middleware.AuthMiddleware(dataloaderMiddleware)
+	clientMiddleware := middleware.ClientMiddleware(trustedProxies, dataloaderMiddleware)
+	authMiddleware := authenticate(clientMiddleware)
+	traceContextMiddleware := middleware.TraceContextMiddleware(authMiddleware)
+	websockets := middleware.NewWebsocketDrainer()
+
+	// probes stay out of cors and auth, load balancers don't send either
+	http.Handle("/healthz", &health.LivenessHandler{})
+	http.Handle("/readyz", &health.ReadinessHandler{DB: db, Timeout: config.READINESS_TIMEOUT})
 
 	http.Handle("/", playground.Handler("GraphQL playground", "/query"))
-	http.Handle("/query", c.Handler(authMiddleware))
+	http.Handle("/query", c.Handler(websockets.Middleware(traceContextMiddleware)))
+	http.Handle("/export/csv", c.Handler(authenticate(&export.CSVHandler{DB: db})))
+	http.Handle("/export/account", c.Handler(authenticate(&export.AccountExportHandler{DB: db, Store: archiveStore, TTL: config.ACCOUNT_EXPORT_TTL})))
+
+	// dashboards and reports read from the replica so they can't slow down
+	// or write to the primary
+	replica, err := database.InitReplicaDb()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if replica != nil {
+		replicaDB, err := replica.DB()
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer replicaDB.Close()
+		if err := replica.Use(&database.QueryStatsPlugin{}); err != nil {
+			log.Fatal(err)
+		}
+
+		readOnlySrv := helpers.NewReadOnlyGqlServer(replica, accesscontrol.NewAccessControllerService(replica), archiveStore)
+		readOnlySrv.Use(extension.Introspection{})
+		readOnlySrv.Use(middleware.Tracer{})
+		readOnlySrv.Use(minClientVersion)
+		readOnlySrv.Use(rateLimit)
+		readOnlySrv.Use(&middleware.QueryCost{})
+		readOnlySrv.Use(responseQuota)
+		readOnlySrv.SetRecoverFunc(func(ctx context.Context, err interface{}) error {
+			if err != nil {
+				log.Printf("panic in read only query: %v", err)
+			}
+			return gqlerror.Errorf("Internal server error")
+		})
+		readOnlyHandler := middleware.DataloaderMiddleware(helpers.NewLoaders(replica), readOnlySrv)
+		readOnlyHandler = middleware.TraceContextMiddleware(authenticate(middleware.ClientMiddleware(trustedProxies, readOnlyHandler)))
+
+		log.Println("read only endpoint is on /readonly/query")
+		http.Handle("/readonly", playground.Handler("GraphQL playground", "/readonly/query"))
+		http.Handle("/readonly/query", c.Handler(readOnlyHandler))
+	}
+
+	// never mounted on Cloud Run, see e2e.Enabled
+	if e2e.Enabled() {
+		log.Println("e2e test mode is on, /e2e/clock and /e2e/token are open")
+		http.Handle("/e2e/clock", &e2e.ClockHandler{})
+		http.Handle("/e2e/token", &e2e.TokenHandler{DB: db})
+	}
+
+	// videos are private, like photos they're only reachable through signed
+	// urls and never listed
+	videoStore := media.NewSignedLocalStore(mediaDir, os.Getenv(config.HOST), "/media", os.Getenv(config.MEDIA_SIGNING_SECRET))
+	http.Handle("/media", &media.SignedURLHandler{Store: videoStore})
+	// never under /media/, photos are only reachable through signed urls
+	photoStore := media.NewSignedLocalStore(os.Getenv(config.PHOTO_DIR), os.Getenv(config.HOST), "/photos", os.Getenv(config.PHOTO_SIGNING_SECRET))
+	http.Handle("/photos", &media.SignedURLHandler{Store: photoStore})
 
 	http.HandleFunc("/static/", func(w http.ResponseWriter, r *http.Request) {
 		// Open the file specified by the request path
@@ -94,8 +333,40 @@ func main() {
 	}
 	http.HandleFunc("/verify", basehandler.verify)
 
-	log.Printf("connect to http://localhost:%s/ for GraphQL playground", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// headers and body limits go on everything, probes and static pages too
+	limitRequestBody := middleware.LimitRequestBody(config.MAX_REQUEST_BYTES, config.MAX_UPLOAD_REQUEST_BYTES)
+	server := &http.Server{
+		Addr:    ":" + port,
+		Handler: middleware.SecurityHeaders(limitRequestBody(http.DefaultServeMux)),
+	}
+	server.RegisterOnShutdown(websockets.Close)
+	go func() {
+		log.Printf("connect to http://localhost:%s/ for GraphQL playground", port)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	// a second signal kills the process right away
+	stop()
+	log.Printf("shutting down, draining requests for up to %s", config.SHUTDOWN_TIMEOUT)
+
+	// stops accepting connections and waits for in-flight requests, the
+	// background jobs, database pool and tracing are closed by the defers
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), config.SHUTDOWN_TIMEOUT)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("error draining requests: %v", err)
+	}
+	if err := websockets.Wait(shutdownCtx); err != nil {
+		log.Printf("error draining websockets: %v", err)
+	}
+	// entries for the requests that were drained still need writing
+	auditLog.Wait()
 }
 
 type BaseHandler struct {
@@ -112,7 +383,7 @@ func (b *BaseHandler) verify(w http.ResponseWriter, r *http.Request) {
 			http.Redirect(w, r, fmt.Sprintf("%s/static/verification-failure.html", host), http.StatusSeeOther)
 		}
 
-		expiryTime := time.Now().Add(24 * time.Hour)
+		expiryTime := clock.Now().Add(24 * time.Hour)
 		user, err := database.GetUserByVerificationCode(b.DB, code)
 		if err != nil || user == nil || user.VerificationCode == nil || *user.VerificationCode != code || user.VerificationSentAt == nil || user.VerificationSentAt.After(expiryTime) {
 			http.Redirect(w, r, fmt.Sprintf("%s/static/verification-failure.html", host), http.StatusSeeOther)
@@ -0,0 +1,129 @@
+package snapshot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/media"
+	"github.com/neilZon/workout-logger-api/utils"
+	"gorm.io/gorm"
+)
+
+// Create copies userId's data into a compressed json blob in the archive
+// store. Archived sessions are already out of postgres and aren't part of it
+func Create(ctx context.Context, db *gorm.DB, store media.Store, userId uint, now time.Time) (*database.Snapshot, error) {
+	data, err := collect(db, userId, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(gz).Encode(data); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	key := StorageKey(userId, now)
+	if err := store.Save(ctx, key, &buf); err != nil {
+		return nil, err
+	}
+
+	snapshot := &database.Snapshot{
+		UserID:              userId,
+		StorageKey:          key,
+		WorkoutRoutineCount: len(data.WorkoutRoutines),
+		WorkoutSessionCount: len(data.WorkoutSessions),
+	}
+	if err := database.AddSnapshot(db, snapshot); err != nil {
+		// nothing points at the blob without the row
+		store.Delete(ctx, key)
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// Load reads a snapshot's data back out of its blob
+func Load(ctx context.Context, store media.Store, snapshot *database.Snapshot) (*database.SnapshotData, error) {
+	r, err := store.Open(ctx, snapshot.StorageKey)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	var data database.SnapshotData
+	if err := json.NewDecoder(gz).Decode(&data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// Restore puts a snapshot back into its account, see database.RestoreSnapshot
+// for how merging and replacing differ
+func Restore(ctx context.Context, db *gorm.DB, store media.Store, snapshot *database.Snapshot, replace bool, now time.Time) error {
+	data, err := Load(ctx, store, snapshot)
+	if err != nil {
+		return err
+	}
+	return database.RestoreSnapshot(db, snapshot, data, replace, now)
+}
+
+// Delete removes the snapshot and its blob
+func Delete(ctx context.Context, db *gorm.DB, store media.Store, snapshot *database.Snapshot) error {
+	if err := database.DeleteSnapshot(db, utils.UIntToString(snapshot.ID)); err != nil {
+		return err
+	}
+
+	// the row is gone, a leftover blob is only wasted space
+	if err := store.Delete(ctx, snapshot.StorageKey); err != nil {
+		log.Printf("error deleting snapshot %s: %v", snapshot.StorageKey, err)
+	}
+	return nil
+}
+
+func StorageKey(userId uint, takenAt time.Time) string {
+	return fmt.Sprintf("snapshots/%d/%d.json.gz", userId, takenAt.UnixNano())
+}
+
+func collect(db *gorm.DB, userId uint, now time.Time) (*database.SnapshotData, error) {
+	id := utils.UIntToString(userId)
+	data := database.SnapshotData{TakenAt: now}
+
+	var err error
+	if data.WorkoutRoutines, err = database.GetAccountWorkoutRoutines(db, id); err != nil {
+		return nil, err
+	}
+	if data.WorkoutSessions, err = database.GetSnapshotWorkoutSessions(db, id); err != nil {
+		return nil, err
+	}
+	if data.BodyWeightEntries, err = database.GetAllBodyWeightEntries(db, id); err != nil {
+		return nil, err
+	}
+	if data.Goals, err = database.GetGoals(db, id); err != nil {
+		return nil, err
+	}
+	if data.NutritionLogs, err = database.GetAllNutritionLogs(db, id); err != nil {
+		return nil, err
+	}
+	if data.SleepLogs, err = database.GetAllSleepLogs(db, id); err != nil {
+		return nil, err
+	}
+	if data.QuickPhrases, err = database.GetQuickPhrases(db, id); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
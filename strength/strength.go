@@ -0,0 +1,50 @@
+// Package strength places lifts against bodyweight strength standards
+package strength
+
+// levels line up with the graphql StrengthLevel enum, weakest first
+const (
+	LevelBeginner     = "BEGINNER"
+	LevelNovice       = "NOVICE"
+	LevelIntermediate = "INTERMEDIATE"
+	LevelAdvanced     = "ADVANCED"
+	LevelElite        = "ELITE"
+)
+
+var levels = []string{LevelNovice, LevelIntermediate, LevelAdvanced, LevelElite}
+
+// one rep max as a multiple of bodyweight it takes to reach novice,
+// intermediate, advanced and elite. they're the middle of commonly
+// published standards, the app doesn't know the lifter's sex or age to be
+// any more exact. exercises with bodyweight in the movement aren't here
+var standards = map[string][4]float64{
+	"Bench Press":         {0.75, 1.0, 1.5, 1.9},
+	"Incline Bench Press": {0.6, 0.9, 1.3, 1.65},
+	"Overhead Press":      {0.45, 0.65, 0.9, 1.15},
+	"Squat":               {1.0, 1.4, 2.0, 2.6},
+	"Front Squat":         {0.8, 1.15, 1.65, 2.1},
+	"Deadlift":            {1.2, 1.65, 2.3, 2.9},
+	"Romanian Deadlift":   {0.9, 1.3, 1.85, 2.4},
+	"Barbell Row":         {0.6, 0.9, 1.25, 1.6},
+	"Barbell Curl":        {0.35, 0.5, 0.75, 1.0},
+	"Hip Thrust":          {1.0, 1.6, 2.4, 3.2},
+}
+
+// Classify is the level a one rep max on the catalog exercise reaches at
+// bodyWeight, false when there are no standards for it. Both need to be in
+// the same unit
+func Classify(catalogExercise string, oneRepMax float64, bodyWeight float64) (string, bool) {
+	thresholds, ok := standards[catalogExercise]
+	if !ok || bodyWeight <= 0 {
+		return "", false
+	}
+
+	level := LevelBeginner
+	ratio := oneRepMax / bodyWeight
+	for i, threshold := range thresholds {
+		if ratio < threshold {
+			break
+		}
+		level = levels[i]
+	}
+	return level, true
+}
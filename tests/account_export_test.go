@@ -0,0 +1,307 @@
+package test
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/export"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/media"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type ExportAccountDataResp struct {
+	ExportAccountData struct {
+		ID          string
+		Status      string
+		DownloadUrl *string
+	}
+}
+
+type AccountExportResp struct {
+	AccountExport struct {
+		ID          string
+		Status      string
+		DownloadUrl *string
+	}
+}
+
+func TestAccountExportResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	now := time.Now()
+	accountExportColumns := []string{"id", "user_id", "status", "storage_key", "completed_at", "created_at", "updated_at"}
+	const unfinishedQuery = `SELECT * FROM "account_exports" WHERE (user_id = $1 AND status IN ($2,$3)) AND "account_exports"."deleted_at" IS NULL ORDER BY "account_exports"."id" LIMIT 1`
+
+	t.Run("Export Account Data", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		mock.ExpectQuery(regexp.QuoteMeta(unfinishedQuery)).
+			WithArgs(fmt.Sprintf("%d", u.ID), database.AccountExportStatusPending, database.AccountExportStatusRunning).
+			WillReturnRows(sqlmock.NewRows(accountExportColumns))
+
+		mock.ExpectBegin()
+		const addAccountExportStmt = `INSERT INTO "account_exports" ("created_at","updated_at","deleted_at","user_id","status","storage_key","completed_at") VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING "id"`
+		mock.ExpectQuery(regexp.QuoteMeta(addAccountExportStmt)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, u.ID, database.AccountExportStatusPending, "", nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectCommit()
+
+		var resp ExportAccountDataResp
+		c.MustPost(`
+			mutation ExportAccountData {
+				exportAccountData {
+					id
+					status
+					downloadUrl
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, "2", resp.ExportAccountData.ID)
+		require.Equal(t, database.AccountExportStatusPending, resp.ExportAccountData.Status)
+		require.Nil(t, resp.ExportAccountData.DownloadUrl)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Export Account Data Already Running", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		mock.ExpectQuery(regexp.QuoteMeta(unfinishedQuery)).
+			WithArgs(fmt.Sprintf("%d", u.ID), database.AccountExportStatusPending, database.AccountExportStatusRunning).
+			WillReturnRows(sqlmock.NewRows(accountExportColumns).AddRow(1, u.ID, database.AccountExportStatusRunning, "", nil, now, now))
+
+		var resp ExportAccountDataResp
+		c.MustPost(`
+			mutation ExportAccountData {
+				exportAccountData {
+					id
+					status
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, "1", resp.ExportAccountData.ID)
+		require.Equal(t, database.AccountExportStatusRunning, resp.ExportAccountData.Status)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Get Account Export Ready", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "account_exports" WHERE id = $1`)).
+			WithArgs("1").
+			WillReturnRows(sqlmock.NewRows(accountExportColumns).AddRow(1, u.ID, database.AccountExportStatusReady, export.AccountExportStorageKey(u.ID, 1), now, now, now))
+
+		var resp AccountExportResp
+		c.MustPost(`
+			query AccountExport {
+				accountExport(accountExportId: "1") {
+					id
+					status
+					downloadUrl
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, database.AccountExportStatusReady, resp.AccountExport.Status)
+		require.Contains(t, *resp.AccountExport.DownloadUrl, "/export/account?id=1")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Get Account Export Access Denied", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		incorrectUserId := 66
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "account_exports" WHERE id = $1`)).
+			WithArgs("1").
+			WillReturnRows(sqlmock.NewRows(accountExportColumns).AddRow(1, incorrectUserId, database.AccountExportStatusReady, "", now, now, now))
+
+		var resp AccountExportResp
+		err := c.Post(`
+			query AccountExport {
+				accountExport(accountExportId: "1") {
+					id
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Getting Account Export: Access Denied","path":["accountExport"],"extensions":{"code":"FORBIDDEN"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
+
+func TestAccountExporter(t *testing.T) {
+	t.Parallel()
+
+	u := testdata.User
+	wr := testdata.WorkoutRoutine
+	er := wr.ExerciseRoutines[0]
+	now := time.Now()
+	start := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	store := media.NewLocalStore(t.TempDir(), "")
+
+	mock, gormDB := helpers.SetupMockDB()
+	exporter := export.NewAccountExporter(gormDB, store, 10, config.ACCOUNT_EXPORT_TTL)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "account_exports" WHERE status = $1`)).
+		WithArgs(database.AccountExportStatusPending).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "status"}).AddRow(2, u.ID, database.AccountExportStatusPending))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "account_exports" SET "status"=$1,"updated_at"=$2 WHERE (id = $3 AND status = $4)`)).
+		WithArgs(database.AccountExportStatusRunning, sqlmock.AnyArg(), 2, database.AccountExportStatusPending).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE id = $1`)).
+		WithArgs(fmt.Sprintf("%d", u.ID)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "password", "created_at"}).AddRow(u.ID, u.Name, u.Subject, "hashed", start))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_routines" WHERE user_id = $1`)).
+		WithArgs(fmt.Sprintf("%d", u.ID)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "active", "user_id"}).AddRow(wr.ID, wr.Name, true, u.ID))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "exercise_routines" WHERE "exercise_routines"."workout_routine_id" = $1`)).
+		WithArgs(wr.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "sets", "reps", "active", "workout_routine_id"}).AddRow(er.ID, er.Name, er.Sets, er.Reps, true, wr.ID))
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_sessions" WHERE (user_id = $1 OR EXISTS`)).
+		WithArgs(fmt.Sprintf("%d", u.ID), fmt.Sprintf("%d", u.ID)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "start", "workout_routine_id", "user_id"}).AddRow(7, start, wr.ID, u.ID))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "exercises" WHERE user_id = $1 AND "exercises"."workout_session_id" = $2`)).
+		WithArgs(fmt.Sprintf("%d", u.ID), 7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "workout_session_id", "exercise_routine_id", "user_id", "notes"}).AddRow(9, 7, er.ID, u.ID, "felt good"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "exercise_routines" WHERE "exercise_routines"."id" = $1`)).
+		WithArgs(er.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(er.ID, er.Name))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "set_entries" WHERE "set_entries"."exercise_id" = $1`)).
+		WithArgs(9).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "exercise_id", "weight", "reps", "set_order", "type"}).AddRow(11, 9, 225, 5, 1, "WORKING"))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_routines" WHERE "workout_routines"."id" = $1`)).
+		WithArgs(wr.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(wr.ID, wr.Name))
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "archived_workout_sessions" WHERE user_id = $1`)).
+		WithArgs(fmt.Sprintf("%d", u.ID)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "body_weight_entries" WHERE user_id = $1`)).
+		WithArgs(fmt.Sprintf("%d", u.ID)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "weight", "logged_at"}).AddRow(1, u.ID, 180.5, start))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "goals" WHERE user_id = $1`)).
+		WithArgs(fmt.Sprintf("%d", u.ID)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "nutrition_logs" WHERE user_id = $1`)).
+		WithArgs(fmt.Sprintf("%d", u.ID)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "day", "calories", "protein_grams", "notes"}).AddRow(1, u.ID, start, 2500, 180, ""))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "sleep_logs" WHERE user_id = $1`)).
+		WithArgs(fmt.Sprintf("%d", u.ID)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "night", "hours"}).AddRow(1, u.ID, start, 7.5))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "quick_phrases" WHERE user_id = $1`)).
+		WithArgs(fmt.Sprintf("%d", u.ID)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "text", "position"}).AddRow(1, u.ID, "slow eccentric", 0))
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "account_exports" SET "completed_at"=$1,"status"=$2,"storage_key"=$3,"updated_at"=$4 WHERE id = $5`)).
+		WithArgs(now, database.AccountExportStatusReady, export.AccountExportStorageKey(u.ID, 2), sqlmock.AnyArg(), 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	built, err := exporter.RunPending(context.Background(), now)
+	require.NoError(t, err)
+	require.Equal(t, 1, built)
+
+	blob, err := store.Open(context.Background(), export.AccountExportStorageKey(u.ID, 2))
+	require.NoError(t, err)
+	defer blob.Close()
+	gz, err := gzip.NewReader(blob)
+	require.NoError(t, err)
+
+	var archive struct {
+		Profile struct {
+			Email string
+		}
+		WorkoutRoutines []struct {
+			Name             string
+			ExerciseRoutines []struct{ Name string }
+		}
+		WorkoutSessions []struct {
+			WorkoutRoutine string
+			Exercises      []struct {
+				Name  string
+				Notes string
+				Sets  []struct {
+					Weight float64
+					Reps   int
+				}
+			}
+		}
+		BodyWeightEntries []struct{ Weight float64 }
+		NutritionLogs     []struct {
+			Day      string
+			Calories int
+		}
+		SleepLogs    []struct{ Hours float64 }
+		QuickPhrases []string
+	}
+	require.NoError(t, json.NewDecoder(gz).Decode(&archive))
+	require.Equal(t, u.Subject, archive.Profile.Email)
+	require.Len(t, archive.WorkoutRoutines, 1)
+	require.Equal(t, er.Name, archive.WorkoutRoutines[0].ExerciseRoutines[0].Name)
+	require.Len(t, archive.WorkoutSessions, 1)
+	require.Equal(t, wr.Name, archive.WorkoutSessions[0].WorkoutRoutine)
+	require.Equal(t, er.Name, archive.WorkoutSessions[0].Exercises[0].Name)
+	require.Equal(t, float64(225), archive.WorkoutSessions[0].Exercises[0].Sets[0].Weight)
+	require.Equal(t, 180.5, archive.BodyWeightEntries[0].Weight)
+	require.Equal(t, "2022-10-01", archive.NutritionLogs[0].Day)
+	require.Equal(t, 2500, archive.NutritionLogs[0].Calories)
+	require.Equal(t, 7.5, archive.SleepLogs[0].Hours)
+	require.Equal(t, []string{"slow eccentric"}, archive.QuickPhrases)
+
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		panic(err)
+	}
+}
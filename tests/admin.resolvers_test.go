@@ -0,0 +1,181 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/neilZon/workout-logger-api/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	userId := fmt.Sprintf("%d", u.ID)
+
+	expectAdmin := func(mock sqlmock.Sqlmock, admin bool) {
+		userRow := sqlmock.NewRows([]string{"id", "verified", "admin"}).AddRow(u.ID, true, admin)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+	}
+
+	t.Run("Non Admin Is Denied", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectAdmin(mock, false)
+
+		var resp struct{}
+		err := c.Post(`query { usageStats { users } }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Access Denied","path":["usageStats"],"extensions":{"code":"FORBIDDEN"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Impersonating Admin Is Denied", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		impersonated := *u
+		impersonated.ImpersonatedBy = 1
+
+		var resp struct{}
+		err := c.Post(`query { usageStats { users } }`, &resp, helpers.AddContext(&impersonated, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Access Denied","path":["usageStats"],"extensions":{"code":"FORBIDDEN"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Admin Users", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectAdmin(mock, true)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE (name ILIKE $1 OR email ILIKE $2) AND id > $3 AND "users"."deleted_at" IS NULL ORDER BY id LIMIT 2`)).
+			WithArgs(`%100\%%`, `%100\%%`, "40").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "verified", "admin", "created_at"}).
+				AddRow(41, "Lifter", "100%@test.com", true, false, time.Now()))
+
+		var resp struct {
+			AdminUsers []struct {
+				ID       string
+				Email    string
+				Verified bool
+			}
+		}
+		c.MustPost(`query { adminUsers(query: "100%", limit: 2, after: "40") { id email verified } }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Len(t, resp.AdminUsers, 1)
+		require.Equal(t, "41", resp.AdminUsers[0].ID)
+		require.Equal(t, "100%@test.com", resp.AdminUsers[0].Email)
+		require.True(t, resp.AdminUsers[0].Verified)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Usage Stats", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectAdmin(mock, true)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT
+		(SELECT count(*) FROM users WHERE deleted_at IS NULL) AS users`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"users", "verified_users", "weekly_active_users", "monthly_active_users", "workout_sessions", "sets"}).
+				AddRow(120, 100, 30, 55, 900, 27000))
+
+		var resp struct {
+			UsageStats struct {
+				Users              int
+				WeeklyActiveUsers  int
+				MonthlyActiveUsers int
+				Sets               int
+			}
+		}
+		c.MustPost(`query { usageStats { users weeklyActiveUsers monthlyActiveUsers sets } }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, 120, resp.UsageStats.Users)
+		require.Equal(t, 30, resp.UsageStats.WeeklyActiveUsers)
+		require.Equal(t, 55, resp.UsageStats.MonthlyActiveUsers)
+		require.Equal(t, 27000, resp.UsageStats.Sets)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Impersonate User", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectAdmin(mock, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).
+			WithArgs("41").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email"}).AddRow(41, "Lifter", "lifter@test.com"))
+
+		var resp struct {
+			ImpersonateUser struct {
+				AccessToken string
+			}
+		}
+		c.MustPost(`mutation { impersonateUser(userId: "41", reason: "support ticket 1234") { accessToken } }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+
+		claims, err := token.Decode("Bearer "+resp.ImpersonateUser.AccessToken, []byte(os.Getenv(config.ACCESS_SECRET)))
+		require.Nil(t, err)
+		require.Equal(t, uint(41), claims.ID)
+		require.Equal(t, "lifter@test.com", claims.Subject)
+		require.Equal(t, u.ID, claims.ImpersonatedBy)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Force Delete Refuses Admins", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectAdmin(mock, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).
+			WithArgs("41").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "admin"}).AddRow(41, true))
+
+		var resp struct{}
+		err := c.Post(`mutation { forceDeleteUser(userId: "41", reason: "support ticket 1234") { dryRun } }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Deleting User: admins can't be force deleted","path":["forceDeleteUser"],"extensions":{"code":"FORBIDDEN"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutomaticPersistedQueries(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	userId := fmt.Sprintf("%d", u.ID)
+
+	const quickPhrasesQuery = `query QuickPhrases { quickPhrases { id text } }`
+	hash := sha256.Sum256([]byte(quickPhrasesQuery))
+	persistedQuery := client.Extensions(map[string]interface{}{
+		"persistedQuery": map[string]interface{}{
+			"version":    1,
+			"sha256Hash": hex.EncodeToString(hash[:]),
+		},
+	})
+
+	expectQuickPhrases := func(mock sqlmock.Sqlmock) {
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "quick_phrases" WHERE user_id = $1`)).
+			WithArgs(userId).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "text", "position"}).AddRow(1, u.ID, "felt heavy", 0))
+	}
+
+	t.Run("Hash Is Enough Once The Query Was Sent", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		c := helpers.NewGqlClient(gormDB, accesscontrol.NewAccessControllerService(gormDB))
+
+		var resp QuickPhrasesResp
+		err := c.Post("", &resp, persistedQuery, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"PersistedQueryNotFound","extensions":{"code":"PERSISTED_QUERY_NOT_FOUND"}}]`)
+
+		expectQuickPhrases(mock)
+		c.MustPost(quickPhrasesQuery, &resp, persistedQuery, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Len(t, resp.QuickPhrases, 1)
+
+		expectQuickPhrases(mock)
+		resp = QuickPhrasesResp{}
+		c.MustPost("", &resp, persistedQuery, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Len(t, resp.QuickPhrases, 1)
+		require.Equal(t, "felt heavy", resp.QuickPhrases[0].Text)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Hash Has To Match The Query", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		c := helpers.NewGqlClient(gormDB, accesscontrol.NewAccessControllerService(gormDB))
+
+		var resp QuickPhrasesResp
+		err := c.Post(`query QuickPhrases { quickPhrases { id } }`, &resp, persistedQuery, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"provided APQ hash does not match query","extensions":{"code":"INTERNAL"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
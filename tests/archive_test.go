@@ -0,0 +1,128 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/archive"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/media"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type RestoreArchivedWorkoutSessionResp struct {
+	RestoreArchivedWorkoutSession struct {
+		ID string
+	}
+}
+
+// not parallel since the archive dir comes from the environment
+func TestArchive(t *testing.T) {
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	ws := testdata.WorkoutSession
+	e := ws.Exercises[0]
+	dir := t.TempDir()
+	t.Setenv(config.ARCHIVE_DIR, dir)
+
+	start := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	workoutSessionColumns := []string{"id", "start", "end", "workout_routine_id", "user_id", "created_at", "updated_at"}
+	exerciseColumns := []string{"id", "workout_session_id", "exercise_routine_id", "user_id", "notes", "created_at", "updated_at"}
+	setColumns := []string{"id", "exercise_id", "weight", "unit", "reps", "set_order", "type", "created_at", "updated_at"}
+
+	t.Run("Archive Batch", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		archiver := archive.NewArchiver(gormDB, media.NewLocalStore(dir, ""), config.ARCHIVE_AFTER, 10)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_sessions" WHERE start < $1 AND (NOT EXISTS`)).
+			WillReturnRows(sqlmock.NewRows(workoutSessionColumns).AddRow(ws.ID, start, start.Add(time.Hour), ws.WorkoutRoutineID, u.ID, start, start))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "exercises" WHERE "exercises"."workout_session_id" = $1`)).
+			WithArgs(ws.ID).
+			WillReturnRows(sqlmock.NewRows(exerciseColumns).AddRow(e.ID, ws.ID, e.ExerciseRoutineID, u.ID, "", start, start))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "set_entries" WHERE "set_entries"."exercise_id" = $1`)).
+			WithArgs(e.ID).
+			WillReturnRows(sqlmock.NewRows(setColumns).
+				AddRow(30, e.ID, 135, "LB", 10, 1, "WARMUP", start, start).
+				AddRow(31, e.ID, 225, "LB", 5, 2, "WORKING", start, start))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "archived_workout_sessions"`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, ws.ID, u.ID, ws.WorkoutRoutineID, start, sqlmock.AnyArg(), 1, 1, float64(1125), "LB", archive.StorageKey(u.ID, ws.ID)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(6))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "set_entries" WHERE exercise_id IN ($1)`)).WithArgs(e.ID).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "exercises" WHERE id IN ($1)`)).WithArgs(e.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "workout_session_participants" WHERE workout_session_id = $1`)).WithArgs(ws.ID).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "workout_session_share_links" WHERE workout_session_id = $1`)).WithArgs(ws.ID).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "workout_sessions" WHERE id = $1`)).WithArgs(ws.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		archived, err := archiver.ArchiveBatch(context.Background(), time.Now())
+		require.NoError(t, err)
+		require.Equal(t, 1, archived)
+
+		_, err = os.Stat(filepath.Join(dir, filepath.FromSlash(archive.StorageKey(u.ID, ws.ID))))
+		require.NoError(t, err)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Restore Archived Workout Session", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		archivedRow := sqlmock.NewRows([]string{"id", "workout_session_id", "user_id", "workout_routine_id", "start", "storage_key"}).
+			AddRow(6, ws.ID, u.ID, ws.WorkoutRoutineID, start, archive.StorageKey(u.ID, ws.ID))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "archived_workout_sessions" WHERE id = $1`)).WithArgs("6").WillReturnRows(archivedRow)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "workout_sessions" ("created_at","updated_at","deleted_at","start","end","workout_routine_id","user_id","client_metadata","origin_platform","origin_app_version","origin_device_id","notes","id")`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(ws.ID))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "exercises"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(e.ID))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "set_entries"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(30).AddRow(31))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "archived_workout_sessions" WHERE "archived_workout_sessions"."id" = $1`)).
+			WithArgs(6).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		var resp RestoreArchivedWorkoutSessionResp
+		c.MustPost(`
+			mutation RestoreArchivedWorkoutSession {
+				restoreArchivedWorkoutSession(archivedWorkoutSessionId: "6") {
+					id
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, fmt.Sprintf("%d", ws.ID), resp.RestoreArchivedWorkoutSession.ID)
+
+		// blob is cleaned up once the session is back
+		_, err = os.Stat(filepath.Join(dir, filepath.FromSlash(archive.StorageKey(u.ID, ws.ID))))
+		require.True(t, os.IsNotExist(err))
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,241 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type AuditTrailResp struct {
+	AuditTrail []struct {
+		ID         string
+		Operation  string
+		EntityType string
+		EntityID   string
+		Before     map[string]interface{}
+		After      map[string]interface{}
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	userId := fmt.Sprintf("%d", u.ID)
+
+	loggedAt := time.Date(2022, 10, 1, 8, 0, 0, 0, time.UTC)
+	deletedAt := loggedAt.Add(time.Hour)
+	entryColumns := []string{"id", "user_id", "weight", "logged_at", "deleted_at"}
+	const snapshotQuery = `SELECT * FROM "body_weight_entries" WHERE id = $1 LIMIT 1`
+
+	t.Run("Mutation Is Recorded With The Row Before And After", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		srv := helpers.NewGqlServer(gormDB, accesscontrol.NewAccessControllerService(gormDB))
+		auditLog := &middleware.AuditLog{DB: gormDB}
+		srv.Use(auditLog)
+		c := client.New(srv)
+
+		mock.ExpectQuery(regexp.QuoteMeta(snapshotQuery)).
+			WithArgs(4).
+			WillReturnRows(sqlmock.NewRows(entryColumns).AddRow(4, u.ID, 180, loggedAt, nil))
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "body_weight_entries" WHERE id = $1 AND "body_weight_entries"."deleted_at" IS NULL`)).
+			WithArgs("4").
+			WillReturnRows(sqlmock.NewRows(entryColumns).AddRow(4, u.ID, 180, loggedAt, nil))
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "body_weight_entries" SET "deleted_at"=$1 WHERE id = $2`)).
+			WithArgs(sqlmock.AnyArg(), "4").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		mock.ExpectQuery(regexp.QuoteMeta(snapshotQuery)).
+			WithArgs(4).
+			WillReturnRows(sqlmock.NewRows(entryColumns).AddRow(4, u.ID, 180, loggedAt, deletedAt))
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "audit_logs" ("created_at","updated_at","deleted_at","user_id","operation","entity_type","entity_id","before","after","impersonated_by","reason")`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, u.ID, "deleteBodyWeight", "BODY_WEIGHT", 4, sqlmock.AnyArg(), sqlmock.AnyArg(), 0, "").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		var resp DeleteBodyWeightResp
+		c.MustPost(`
+			mutation DeleteBodyWeight {
+				deleteBodyWeight(bodyWeightEntryId: "4")
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, 1, resp.DeleteBodyWeight)
+		auditLog.Wait()
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Failed Mutation Isn't Recorded", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		srv := helpers.NewGqlServer(gormDB, accesscontrol.NewAccessControllerService(gormDB))
+		auditLog := &middleware.AuditLog{DB: gormDB}
+		srv.Use(auditLog)
+		c := client.New(srv)
+
+		mock.ExpectQuery(regexp.QuoteMeta(snapshotQuery)).
+			WithArgs(4).
+			WillReturnRows(sqlmock.NewRows(entryColumns).AddRow(4, u.ID+1, 180, loggedAt, nil))
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "body_weight_entries" WHERE id = $1 AND "body_weight_entries"."deleted_at" IS NULL`)).
+			WithArgs("4").
+			WillReturnRows(sqlmock.NewRows(entryColumns).AddRow(4, u.ID+1, 180, loggedAt, nil))
+
+		var resp DeleteBodyWeightResp
+		err := c.Post(`
+			mutation DeleteBodyWeight {
+				deleteBodyWeight(bodyWeightEntryId: "4")
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Deleting Body Weight: Access Denied","path":["deleteBodyWeight"],"extensions":{"code":"FORBIDDEN"}}]`)
+		auditLog.Wait()
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Impersonated Query Is Recorded", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		srv := helpers.NewGqlServer(gormDB, accesscontrol.NewAccessControllerService(gormDB))
+		auditLog := &middleware.AuditLog{DB: gormDB}
+		srv.Use(auditLog)
+		c := client.New(srv)
+
+		impersonated := *u
+		impersonated.ImpersonatedBy = 1
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "quick_phrases" WHERE user_id = $1`)).
+			WithArgs(userId).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "text", "position"}).AddRow(1, u.ID, "felt heavy", 0))
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "audit_logs" ("created_at","updated_at","deleted_at","user_id","operation","entity_type","entity_id","before","after","impersonated_by","reason")`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, u.ID, "quickPhrases", "", 0, sqlmock.AnyArg(), sqlmock.AnyArg(), 1, "").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		var resp QuickPhrasesResp
+		c.MustPost(`query QuickPhrases { quickPhrases { id text } }`, &resp, helpers.AddContext(&impersonated, helpers.NewLoaders(gormDB)))
+		require.Len(t, resp.QuickPhrases, 1)
+		auditLog.Wait()
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Impersonation Can't Delete The Account", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		srv := helpers.NewGqlServer(gormDB, accesscontrol.NewAccessControllerService(gormDB))
+		auditLog := &middleware.AuditLog{DB: gormDB}
+		srv.Use(auditLog)
+		c := client.New(srv)
+
+		impersonated := *u
+		impersonated.ImpersonatedBy = 1
+
+		var resp DeleteAccountResp
+		err := c.Post(`
+			mutation DeleteAccount {
+				deleteAccount {
+					dryRun
+				}
+			}`, &resp, helpers.AddContext(&impersonated, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Access Denied","path":["deleteAccount"],"extensions":{"code":"FORBIDDEN"}}]`)
+		auditLog.Wait()
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Audit Trail", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		adminRow := sqlmock.NewRows([]string{"id", "verified", "admin"}).AddRow(u.ID, true, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(adminRow)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "audit_logs" WHERE entity_id = $1 AND entity_type = $2 AND "audit_logs"."deleted_at" IS NULL ORDER BY id desc LIMIT 50`)).
+			WithArgs("4", "BODY_WEIGHT").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "operation", "entity_type", "entity_id", "before", "after", "created_at"}).
+				AddRow(2, u.ID, "deleteBodyWeight", "BODY_WEIGHT", 4, `{"id":4,"weight":180,"deleted_at":null}`, `{"id":4,"weight":180,"deleted_at":"2022-10-01T09:00:00Z"}`, deletedAt).
+				AddRow(1, u.ID, "logBodyWeight", "BODY_WEIGHT", 4, nil, `{"id":4,"weight":180,"deleted_at":null}`, loggedAt))
+
+		var resp AuditTrailResp
+		c.MustPost(`
+			query AuditTrail {
+				auditTrail(entityId: "4", entityType: BODY_WEIGHT) {
+					id
+					operation
+					entityType
+					entityId
+					before
+					after
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+
+		require.Len(t, resp.AuditTrail, 2)
+		require.Equal(t, "deleteBodyWeight", resp.AuditTrail[0].Operation)
+		require.Equal(t, "BODY_WEIGHT", resp.AuditTrail[0].EntityType)
+		require.Equal(t, "4", resp.AuditTrail[0].EntityID)
+		require.Nil(t, resp.AuditTrail[0].Before["deleted_at"])
+		require.Equal(t, "2022-10-01T09:00:00Z", resp.AuditTrail[0].After["deleted_at"])
+		require.Nil(t, resp.AuditTrail[1].Before)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Audit Trail Not Admin", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified", "admin"}).AddRow(u.ID, true, false)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+
+		var resp AuditTrailResp
+		err := c.Post(`
+			query AuditTrail {
+				auditTrail(entityId: "4") {
+					id
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Access Denied","path":["auditTrail"],"extensions":{"code":"FORBIDDEN"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -65,6 +65,22 @@ func TestAuthResolvers(t *testing.T) {
 		Password: "$2a$10$0EGP2OywIngzJKu.GoKS8eG/08tGSbZi5sMbDoJ..nWVgvQQlaDcC",
 	}
 
+	// logins without a client ip are only throttled by email
+	const throttlesQuery = `SELECT * FROM "login_throttles" WHERE key IN ($1)`
+	const addFailureStmt = `INSERT INTO "login_throttles" ("key","failures","last_failed_at") VALUES ($1,$2,$3) ON CONFLICT ("key") DO UPDATE SET "failures"=CASE WHEN login_throttles.last_failed_at < $4 THEN 1 ELSE login_throttles.failures + 1 END,"last_failed_at"=$5`
+	expectThrottles := func(mock sqlmock.Sqlmock, email string) {
+		mock.ExpectQuery(regexp.QuoteMeta(throttlesQuery)).
+			WithArgs("email:" + email).
+			WillReturnRows(sqlmock.NewRows([]string{"key", "failures", "last_failed_at"}))
+	}
+	expectFailure := func(mock sqlmock.Sqlmock, email string) {
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(addFailureStmt)).
+			WithArgs("email:"+email, 1, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+	}
+
 	t.Run("Login resolver success", func(t *testing.T) {
 		mock, gormDB := helpers.SetupMockDB()
 		acs := accesscontrol.NewAccessControllerService(gormDB)
@@ -74,8 +90,22 @@ func TestAuthResolvers(t *testing.T) {
 			NewRows([]string{"id", "name", "email", "password", "created_at", "deleted_at", "updated_at"}).
 			AddRow(u.ID, u.Name, u.Email, u.Password, u.CreatedAt, u.DeletedAt, u.UpdatedAt)
 
+		expectThrottles(mock, u.Email)
 		const userQuery = `SELECT * FROM "users" WHERE email = $1 AND "users"."deleted_at" IS NULL ORDER BY "users"."id" LIMIT 1`
 		mock.ExpectQuery(regexp.QuoteMeta(userQuery)).WithArgs(u.Email).WillReturnRows(userRow)
+		verifyRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(verifyRow)
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "login_throttles" WHERE key = $1`)).
+			WithArgs("email:" + u.Email).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "sessions"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "refresh_tokens"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
 
 		var resp LoginResp
 		c.MustPost(`mutation Login {
@@ -106,8 +136,12 @@ func TestAuthResolvers(t *testing.T) {
 			NewRows([]string{"id", "name", "email", "password", "created_at", "deleted_at", "updated_at"}).
 			AddRow(u.ID, u.Name, u.Email, u.Password, u.CreatedAt, u.DeletedAt, u.UpdatedAt)
 
+		expectThrottles(mock, u.Email)
 		const userQuery = `SELECT * FROM "users" WHERE email = $1 AND "users"."deleted_at" IS NULL ORDER BY "users"."id" LIMIT 1`
 		mock.ExpectQuery(regexp.QuoteMeta(userQuery)).WithArgs(u.Email).WillReturnRows(rows)
+		verifyRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(verifyRow)
+		expectFailure(mock, u.Email)
 
 		var resp struct {
 			Login struct {
@@ -124,7 +158,7 @@ func TestAuthResolvers(t *testing.T) {
 			  }
 		  }`,
 			&resp)
-		require.EqualError(t, err, "[{\"message\":\"Incorrect Password\",\"path\":[\"login\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Incorrect Password\",\"path\":[\"login\"],\"extensions\":{\"code\":\"INVALID_INPUT\"}}]")
 
 		err = mock.ExpectationsWereMet() // make sure all expectations were met
 		if err != nil {
@@ -137,8 +171,10 @@ func TestAuthResolvers(t *testing.T) {
 		acs := accesscontrol.NewAccessControllerService(gormDB)
 		c := helpers.NewGqlClient(gormDB, acs)
 
+		expectThrottles(mock, "notexistingemail@test.com")
 		const userQuery = `SELECT * FROM "users" WHERE email = $1 AND "users"."deleted_at" IS NULL ORDER BY "users"."id" LIMIT 1`
 		mock.ExpectQuery(regexp.QuoteMeta(userQuery)).WithArgs("notexistingemail@test.com").WillReturnError(gorm.ErrRecordNotFound)
+		expectFailure(mock, "notexistingemail@test.com")
 
 		// empty response struct since we know we are going to return an error
 		var resp struct{}
@@ -152,7 +188,7 @@ func TestAuthResolvers(t *testing.T) {
 			  }
 		  }`,
 			&resp)
-		require.EqualError(t, err, "[{\"message\":\"Email does not exist\",\"path\":[\"login\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Email does not exist\",\"path\":[\"login\"],\"extensions\":{\"code\":\"NOT_FOUND\"}}]")
 
 		err = mock.ExpectationsWereMet() // make sure all expectations were met
 		if err != nil {
@@ -177,7 +213,7 @@ func TestAuthResolvers(t *testing.T) {
 			  }
 		  }`,
 			&resp)
-		require.EqualError(t, err, "[{\"message\":\"Error Logging In\",\"path\":[\"login\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"invalid email\",\"path\":[\"login\"],\"extensions\":{\"code\":\"INVALID_INPUT\"}}]")
 
 		err = mock.ExpectationsWereMet() // make sure all expectations were met
 		if err != nil {
@@ -255,7 +291,7 @@ func TestAuthResolvers(t *testing.T) {
 			}
 		  }`,
 			&resp)
-		require.EqualError(t, err, "[{\"message\":\"Email already exists\",\"path\":[\"signup\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Email already exists\",\"path\":[\"signup\"],\"extensions\":{\"code\":\"INVALID_INPUT\"}}]")
 	})
 
 	t.Run("Signup resolver with invalid email", func(t *testing.T) {
@@ -277,7 +313,7 @@ func TestAuthResolvers(t *testing.T) {
 			}
 		  }`,
 			&resp)
-		require.EqualError(t, err, "[{\"message\":\"Not a valid email\",\"path\":[\"signup\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"not a valid email\",\"path\":[\"signup\"],\"extensions\":{\"code\":\"INVALID_INPUT\",\"fields\":[{\"field\":\"signupInput.email\",\"message\":\"not a valid email\"}]}}]")
 
 		err = mock.ExpectationsWereMet() // make sure all expectations were met
 		if err != nil {
@@ -304,7 +340,7 @@ func TestAuthResolvers(t *testing.T) {
 			}
 		  }`,
 			&resp)
-		require.EqualError(t, err, "[{\"message\":\"Passwords don't match\",\"path\":[\"signup\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"passwords don't match\",\"path\":[\"signup\"],\"extensions\":{\"code\":\"INVALID_INPUT\",\"fields\":[{\"field\":\"signupInput.confirmPassword\",\"message\":\"passwords don't match\"}]}}]")
 
 		err = mock.ExpectationsWereMet() // make sure all expectations were met
 		if err != nil {
@@ -331,7 +367,7 @@ func TestAuthResolvers(t *testing.T) {
 			}
 		  }`,
 			&resp)
-		require.EqualError(t, err, "[{\"message\":\"Password needs at least 1 number and 8 - 32 characters\",\"path\":[\"signup\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"password needs at least 1 number and 8 - 32 characters\",\"path\":[\"signup\"],\"extensions\":{\"code\":\"INVALID_INPUT\",\"fields\":[{\"field\":\"signupInput.password\",\"message\":\"password needs at least 1 number and 8 - 32 characters\"}]}}]")
 
 		err = mock.ExpectationsWereMet() // make sure all expectations were met
 		if err != nil {
@@ -358,7 +394,7 @@ func TestAuthResolvers(t *testing.T) {
 			}
 		  }`,
 			&resp)
-		require.EqualError(t, err, "[{\"message\":\"Password needs at least 1 number and 8 - 32 characters\",\"path\":[\"signup\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"password needs at least 1 number and 8 - 32 characters\",\"path\":[\"signup\"],\"extensions\":{\"code\":\"INVALID_INPUT\",\"fields\":[{\"field\":\"signupInput.password\",\"message\":\"password needs at least 1 number and 8 - 32 characters\"}]}}]")
 
 		err = mock.ExpectationsWereMet() // make sure all expectations were met
 		if err != nil {
@@ -378,7 +414,7 @@ func TestAuthResolvers(t *testing.T) {
 			Email: "test@test.com",
 		}
 
-		refreshToken := token.Sign(cred, REFRESH_SECRET, 5)
+		refreshToken := token.SignRefresh(cred, "family", "first", REFRESH_SECRET, 5)
 
 		// send request and get back refresh token
 		var resp struct {
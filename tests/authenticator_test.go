@@ -0,0 +1,120 @@
+package test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthenticator(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	// the user Authenticate put in the request's context
+	userOf := func(a middleware.Authenticator, authorization string) (*token.Claims, error) {
+		var claims *token.Claims
+		var err error
+		handler := middleware.Authenticate(a)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, err = middleware.GetUser(r.Context())
+		}))
+		r := httptest.NewRequest(http.MethodPost, "/query", nil)
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+		return claims, err
+	}
+
+	const sessionQuery = `SELECT * FROM "sessions" WHERE family_id = $1 AND "sessions"."deleted_at" IS NULL ORDER BY "sessions"."id" LIMIT 1`
+	secret := []byte(os.Getenv(config.ACCESS_SECRET))
+	c := &token.Credentials{ID: 12, Name: "testname", Email: "test@test.com"}
+
+	t.Run("JWT By Default", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		a, err := middleware.NewAuthenticator("", gormDB)
+		require.Nil(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(sessionQuery)).
+			WithArgs("family").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "family_id", "revoked_at"}).AddRow(1, 12, "family", nil))
+
+		claims, err := userOf(a, "Bearer "+token.SignAccess(c, "family", secret, config.ACCESS_TTL))
+		require.Nil(t, err)
+		require.Equal(t, uint(12), claims.ID)
+		require.Equal(t, "test@test.com", claims.Subject)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Revoked Session Has No User", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		a, err := middleware.NewAuthenticator("jwt", gormDB)
+		require.Nil(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(sessionQuery)).
+			WithArgs("family").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "family_id", "revoked_at"}).AddRow(1, 12, "family", time.Now()))
+
+		_, err = userOf(a, "Bearer "+token.SignAccess(c, "family", secret, config.ACCESS_TTL))
+		require.EqualError(t, err, "Unauthorized")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Token Without A Session Has No User", func(t *testing.T) {
+		_, gormDB := helpers.SetupMockDB()
+		a, err := middleware.NewAuthenticator("jwt", gormDB)
+		require.Nil(t, err)
+
+		_, err = userOf(a, "Bearer "+token.Sign(c, secret, config.ACCESS_TTL))
+		require.EqualError(t, err, "Unauthorized")
+	})
+
+	t.Run("Impersonation Token Needs No Session", func(t *testing.T) {
+		_, gormDB := helpers.SetupMockDB()
+		a, err := middleware.NewAuthenticator("jwt", gormDB)
+		require.Nil(t, err)
+
+		impersonation := &token.Credentials{ID: 12, Name: "testname", Email: "test@test.com", ImpersonatedBy: 1}
+		claims, err := userOf(a, "Bearer "+token.Sign(impersonation, secret, config.IMPERSONATION_TTL))
+		require.Nil(t, err)
+		require.Equal(t, uint(1), claims.ImpersonatedBy)
+	})
+
+	t.Run("Bad Or Missing Token Has No User", func(t *testing.T) {
+		a, err := middleware.NewAuthenticator("jwt", nil)
+		require.Nil(t, err)
+
+		_, err = userOf(a, "Bearer not.a.token")
+		require.EqualError(t, err, "Unauthorized")
+
+		_, err = userOf(a, "")
+		require.EqualError(t, err, "Unauthorized")
+	})
+
+	t.Run("Unknown Provider", func(t *testing.T) {
+		_, err := middleware.NewAuthenticator("clerk", nil)
+		require.EqualError(t, err, `AUTH_PROVIDER "clerk" isn't supported, the only provider is jwt`)
+	})
+}
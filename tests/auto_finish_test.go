@@ -0,0 +1,198 @@
+package test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/neilZon/workout-logger-api/autofinish"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/live"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoFinish(t *testing.T) {
+	t.Parallel()
+
+	u := testdata.User
+	now := time.Date(2022, 10, 2, 12, 0, 0, 0, time.UTC)
+	workoutSessionColumns := []string{"id", "user_id", "start", "end", "workout_routine_id"}
+	const snapshotQuery = `SELECT * FROM "workout_sessions" WHERE id = $1 LIMIT 1`
+	const auditLogStmt = `INSERT INTO "audit_logs" ("created_at","updated_at","deleted_at","user_id","operation","entity_type","entity_id","before","after","impersonated_by","reason")`
+
+	t.Run("Finishes At The Last Set", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		finisher := autofinish.NewFinisher(gormDB, 6*time.Hour, 10, live.NewBroker())
+
+		start := now.Add(-8 * time.Hour)
+		lastSet := start.Add(50 * time.Minute)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_sessions" WHERE id > $1 AND ("end" IS NULL AND start < $2) AND "workout_sessions"."deleted_at" IS NULL ORDER BY id LIMIT 10`)).
+			WithArgs(0, now.Add(-6*time.Hour)).
+			WillReturnRows(sqlmock.NewRows(workoutSessionColumns).AddRow(7, u.ID, start, nil, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT MAX(COALESCE(set_entries.completed_at, set_entries.created_at)) FROM "set_entries" JOIN exercises ON exercises.id = set_entries.exercise_id AND exercises.deleted_at IS NULL WHERE exercises.workout_session_id = $1 AND "set_entries"."deleted_at" IS NULL`)).
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(lastSet))
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(snapshotQuery)).
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows(workoutSessionColumns).AddRow(7, u.ID, start, nil, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "workout_sessions" SET "end"=$1,"updated_at"=$2 WHERE (id = $3 AND "end" IS NULL) AND "workout_sessions"."deleted_at" IS NULL RETURNING *`)).
+			WithArgs(lastSet, sqlmock.AnyArg(), "7").
+			WillReturnRows(sqlmock.NewRows(workoutSessionColumns).AddRow(7, u.ID, start, lastSet, 1))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.QueueWebhookDeliveriesStmt)).
+			WithArgs(7, u.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.QueueStravaUploadStmt)).
+			WithArgs(7, u.ID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "workout_session_auto_finishes" ("created_at","updated_at","deleted_at","workout_session_id","user_id","start","end") VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING "id"`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, 7, u.ID, start, lastSet).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta(snapshotQuery)).
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows(workoutSessionColumns).AddRow(7, u.ID, start, lastSet, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(auditLogStmt)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, u.ID, "autoFinishWorkoutSession", "WORKOUT_SESSION", 7, sqlmock.AnyArg(), sqlmock.AnyArg(), 0, "").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		finished, err := finisher.FinishStaleSessions(now)
+		require.Nil(t, err)
+		require.Equal(t, 1, finished)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Finishes At The Start Without Sets", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		finisher := autofinish.NewFinisher(gormDB, 6*time.Hour, 10, live.NewBroker())
+
+		start := now.Add(-7 * time.Hour)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_sessions" WHERE id > $1 AND ("end" IS NULL AND start < $2)`)).
+			WillReturnRows(sqlmock.NewRows(workoutSessionColumns).AddRow(8, u.ID, start, nil, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT MAX(COALESCE(set_entries.completed_at, set_entries.created_at)) FROM "set_entries"`)).
+			WithArgs(8).
+			WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(nil))
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(snapshotQuery)).
+			WithArgs(8).
+			WillReturnRows(sqlmock.NewRows(workoutSessionColumns).AddRow(8, u.ID, start, nil, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "workout_sessions" SET "end"=$1`)).
+			WithArgs(start, sqlmock.AnyArg(), "8").
+			WillReturnRows(sqlmock.NewRows(workoutSessionColumns).AddRow(8, u.ID, start, start, 1))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.QueueWebhookDeliveriesStmt)).
+			WithArgs(8, u.ID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.QueueStravaUploadStmt)).
+			WithArgs(8, u.ID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "workout_session_auto_finishes"`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, 8, u.ID, start, start).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectQuery(regexp.QuoteMeta(snapshotQuery)).
+			WithArgs(8).
+			WillReturnRows(sqlmock.NewRows(workoutSessionColumns).AddRow(8, u.ID, start, start, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(auditLogStmt)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, u.ID, "autoFinishWorkoutSession", "WORKOUT_SESSION", 8, sqlmock.AnyArg(), sqlmock.AnyArg(), 0, "").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectCommit()
+
+		finished, err := finisher.FinishStaleSessions(now)
+		require.Nil(t, err)
+		require.Equal(t, 1, finished)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Skips Sessions The User Finished", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		finisher := autofinish.NewFinisher(gormDB, 6*time.Hour, 10, live.NewBroker())
+
+		start := now.Add(-9 * time.Hour)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_sessions" WHERE id > $1 AND ("end" IS NULL AND start < $2)`)).
+			WillReturnRows(sqlmock.NewRows(workoutSessionColumns).AddRow(9, u.ID, start, nil, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT MAX(COALESCE(set_entries.completed_at, set_entries.created_at)) FROM "set_entries"`)).
+			WithArgs(9).
+			WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(nil))
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(snapshotQuery)).
+			WithArgs(9).
+			WillReturnRows(sqlmock.NewRows(workoutSessionColumns).AddRow(9, u.ID, start, now, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "workout_sessions" SET "end"=$1`)).
+			WillReturnRows(sqlmock.NewRows(workoutSessionColumns))
+		mock.ExpectRollback()
+
+		finished, err := finisher.FinishStaleSessions(now)
+		require.Nil(t, err)
+		require.Equal(t, 0, finished)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Pages By Id And Lets Spectators Go", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		broker := live.NewBroker()
+		finisher := autofinish.NewFinisher(gormDB, 6*time.Hour, 1, broker)
+		updates := broker.Subscribe(context.Background(), "7")
+
+		start := now.Add(-8 * time.Hour)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_sessions" WHERE id > $1 AND ("end" IS NULL AND start < $2) AND "workout_sessions"."deleted_at" IS NULL ORDER BY id LIMIT 1`)).
+			WithArgs(0, now.Add(-6*time.Hour)).
+			WillReturnRows(sqlmock.NewRows(workoutSessionColumns).AddRow(7, u.ID, start, nil, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT MAX(COALESCE(set_entries.completed_at, set_entries.created_at)) FROM "set_entries"`)).
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(nil))
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(snapshotQuery)).
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows(workoutSessionColumns).AddRow(7, u.ID, start, nil, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "workout_sessions" SET "end"=$1`)).
+			WithArgs(start, sqlmock.AnyArg(), "7").
+			WillReturnRows(sqlmock.NewRows(workoutSessionColumns).AddRow(7, u.ID, start, start, 1))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.QueueWebhookDeliveriesStmt)).
+			WithArgs(7, u.ID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.QueueStravaUploadStmt)).
+			WithArgs(7, u.ID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "workout_session_auto_finishes"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta(snapshotQuery)).
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows(workoutSessionColumns).AddRow(7, u.ID, start, start, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(auditLogStmt)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+		// the next page starts after the last session, wherever it started
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_sessions" WHERE id > $1 AND ("end" IS NULL AND start < $2) AND "workout_sessions"."deleted_at" IS NULL ORDER BY id LIMIT 1`)).
+			WithArgs(7, now.Add(-6*time.Hour)).
+			WillReturnRows(sqlmock.NewRows(workoutSessionColumns))
+
+		finished, err := finisher.FinishStaleSessions(now)
+		require.Nil(t, err)
+		require.Equal(t, 1, finished)
+		_, open := <-updates
+		require.False(t, open)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
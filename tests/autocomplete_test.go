@@ -0,0 +1,157 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type AutocompleteResp struct {
+	Autocomplete []struct {
+		Text string
+		Kind string
+		ID   *string
+	}
+}
+
+func TestAutocomplete(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+
+	const exerciseRoutinesQuery = `SELECT id, text, CASE WHEN text ILIKE $1 THEN 0 WHEN text ILIKE $2 THEN 1 ELSE 2 END AS rank FROM (`
+	const catalogExercisesQuery = `SELECT id, name AS text, CASE WHEN name ILIKE $1 THEN 0 WHEN name ILIKE $2 THEN 1 ELSE 2 END AS rank FROM catalog_exercises`
+	const equipmentQuery = `SELECT equipment AS text, CASE WHEN equipment ILIKE $1 THEN 0 WHEN equipment ILIKE $2 THEN 1 ELSE 2 END AS rank FROM catalog_exercises`
+
+	suggestionRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "text", "rank"})
+	}
+
+	t.Run("Autocomplete Ranks Own Exercises First", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		mock.ExpectQuery(regexp.QuoteMeta(exerciseRoutinesQuery)).
+			WithArgs("bench%", "% bench%", fmt.Sprintf("%d", u.ID), "%bench%", 5).
+			WillReturnRows(suggestionRows().AddRow(7, "Bench Press", 0).AddRow(9, "Incline Bench", 1))
+		mock.ExpectQuery(regexp.QuoteMeta(catalogExercisesQuery)).
+			WithArgs("bench%", "% bench%", "%bench%", 5).
+			WillReturnRows(suggestionRows().AddRow(1, "Bench Press", 0).AddRow(2, "Bench Dip", 0).AddRow(3, "Dumbbell Bench Press", 1))
+		mock.ExpectQuery(regexp.QuoteMeta(equipmentQuery)).
+			WithArgs("bench%", "% bench%", "%bench%", 5).
+			WillReturnRows(sqlmock.NewRows([]string{"text", "rank"}).AddRow("Bench", 0))
+
+		var resp AutocompleteResp
+		c.MustPost(`
+			query Autocomplete {
+				autocomplete(prefix: " bench ", limit: 5) {
+					text
+					kind
+					id
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+
+		// the catalog's Bench Press is left out since the user has their own
+		require.Len(t, resp.Autocomplete, 5)
+		require.Equal(t, "Bench Press", resp.Autocomplete[0].Text)
+		require.Equal(t, "EXERCISE", resp.Autocomplete[0].Kind)
+		require.Equal(t, "7", *resp.Autocomplete[0].ID)
+		require.Equal(t, "Bench Dip", resp.Autocomplete[1].Text)
+		require.Equal(t, "CATALOG_EXERCISE", resp.Autocomplete[1].Kind)
+		require.Equal(t, "Bench", resp.Autocomplete[2].Text)
+		require.Equal(t, "EQUIPMENT", resp.Autocomplete[2].Kind)
+		require.Nil(t, resp.Autocomplete[2].ID)
+		require.Equal(t, "Incline Bench", resp.Autocomplete[3].Text)
+		require.Equal(t, "Dumbbell Bench Press", resp.Autocomplete[4].Text)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Autocomplete Tags", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		mock.ExpectQuery(regexp.QuoteMeta(equipmentQuery)).
+			WithArgs("ca%", "% ca%", "%ca%", 10).
+			WillReturnRows(sqlmock.NewRows([]string{"text", "rank"}).AddRow("Cable", 0))
+
+		var resp AutocompleteResp
+		c.MustPost(`
+			query Autocomplete {
+				autocomplete(prefix: "ca", scope: TAGS) {
+					text
+					kind
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+
+		require.Len(t, resp.Autocomplete, 2)
+		require.Equal(t, "CALVES", resp.Autocomplete[0].Text)
+		require.Equal(t, "MUSCLE_GROUP", resp.Autocomplete[0].Kind)
+		require.Equal(t, "Cable", resp.Autocomplete[1].Text)
+		require.Equal(t, "EQUIPMENT", resp.Autocomplete[1].Kind)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Autocomplete Empty Prefix", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		var resp AutocompleteResp
+		err := c.Post(`query Autocomplete { autocomplete(prefix: "  ") { text } }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Autocompleting: prefix must have between 1 and 64 characters","path":["autocomplete"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Autocomplete Limit Too High", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		var resp AutocompleteResp
+		err := c.Post(`query Autocomplete { autocomplete(prefix: "squat", limit: 100) { text } }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Autocompleting: limit must be between 1 and 25","path":["autocomplete"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,205 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type LogBodyWeightResp struct {
+	LogBodyWeight struct {
+		ID       string
+		Weight   float64
+		LoggedAt string
+	}
+}
+
+type BodyWeightHistoryResp struct {
+	BodyWeightHistory struct {
+		Edges []struct {
+			Cursor string
+			Node   struct {
+				ID     string
+				Weight float64
+			}
+		}
+		PageInfo struct {
+			HasNextPage bool
+		}
+	}
+}
+
+type DeleteBodyWeightResp struct {
+	DeleteBodyWeight int
+}
+
+func TestBodyWeightResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	loggedAt := time.Date(2022, 10, 1, 8, 0, 0, 0, time.UTC)
+	entryColumns := []string{"id", "user_id", "weight", "logged_at", "created_at", "updated_at"}
+	const getEntryQuery = `SELECT * FROM "body_weight_entries" WHERE id = $1 AND "body_weight_entries"."deleted_at" IS NULL ORDER BY "body_weight_entries"."id" LIMIT 1`
+
+	t.Run("Log Body Weight", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		mock.ExpectBegin()
+		const addEntryStmt = `INSERT INTO "body_weight_entries" ("created_at","updated_at","deleted_at","user_id","weight","logged_at") VALUES ($1,$2,$3,$4,$5,$6) RETURNING "id"`
+		mock.ExpectQuery(regexp.QuoteMeta(addEntryStmt)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, u.ID, float32(180.5), loggedAt).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+		mock.ExpectCommit()
+
+		var resp LogBodyWeightResp
+		c.MustPost(`
+			mutation LogBodyWeight {
+				logBodyWeight(bodyWeight: { weight: 180.5, loggedAt: "2022-10-01T08:00:00Z" }) {
+					id
+					weight
+					loggedAt
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, "4", resp.LogBodyWeight.ID)
+		require.Equal(t, 180.5, resp.LogBodyWeight.Weight)
+		require.Equal(t, "2022-10-01T08:00:00Z", resp.LogBodyWeight.LoggedAt)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Log Body Weight Invalid Weight", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		var resp LogBodyWeightResp
+		err := c.Post(`
+			mutation LogBodyWeight {
+				logBodyWeight(bodyWeight: { weight: -3 }) {
+					id
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"weight needs to be between 0 and 9999","path":["logBodyWeight"],"extensions":{"code":"INVALID_INPUT","fields":[{"field":"bodyWeight.weight","message":"weight needs to be between 0 and 9999"}]}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Body Weight History Next Page", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		const historyQuery = `SELECT * FROM "body_weight_entries" WHERE (user_id = $1 AND (logged_at, id) < (SELECT logged_at, id FROM body_weight_entries WHERE id = $2)) AND "body_weight_entries"."deleted_at" IS NULL ORDER BY logged_at desc, id desc LIMIT 3`
+		entryRows := sqlmock.NewRows(entryColumns).
+			AddRow(7, u.ID, 181, loggedAt, loggedAt, loggedAt).
+			AddRow(5, u.ID, 180, loggedAt.AddDate(0, 0, -1), loggedAt, loggedAt).
+			AddRow(6, u.ID, 182, loggedAt.AddDate(0, 0, -2), loggedAt, loggedAt)
+		mock.ExpectQuery(regexp.QuoteMeta(historyQuery)).WithArgs(fmt.Sprintf("%d", u.ID), "9").WillReturnRows(entryRows)
+
+		var resp BodyWeightHistoryResp
+		c.MustPost(`
+			query BodyWeightHistory {
+				bodyWeightHistory(limit: 2, after: "9") {
+					edges {
+						cursor
+						node {
+							id
+							weight
+						}
+					}
+					pageInfo {
+						hasNextPage
+					}
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Len(t, resp.BodyWeightHistory.Edges, 2)
+		require.Equal(t, "5", resp.BodyWeightHistory.Edges[1].Cursor)
+		require.Equal(t, float64(180), resp.BodyWeightHistory.Edges[1].Node.Weight)
+		require.True(t, resp.BodyWeightHistory.PageInfo.HasNextPage)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Delete Body Weight Access Denied", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		entryRow := sqlmock.NewRows(entryColumns).AddRow(4, u.ID+1, 180, loggedAt, loggedAt, loggedAt)
+		mock.ExpectQuery(regexp.QuoteMeta(getEntryQuery)).WithArgs("4").WillReturnRows(entryRow)
+
+		var resp DeleteBodyWeightResp
+		err := c.Post(`
+			mutation DeleteBodyWeight {
+				deleteBodyWeight(bodyWeightEntryId: "4")
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Deleting Body Weight: Access Denied","path":["deleteBodyWeight"],"extensions":{"code":"FORBIDDEN"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Delete Body Weight", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		entryRow := sqlmock.NewRows(entryColumns).AddRow(4, u.ID, 180, loggedAt, loggedAt, loggedAt)
+		mock.ExpectQuery(regexp.QuoteMeta(getEntryQuery)).WithArgs("4").WillReturnRows(entryRow)
+
+		mock.ExpectBegin()
+		const deleteEntryStmt = `UPDATE "body_weight_entries" SET "deleted_at"=$1 WHERE id = $2 AND "body_weight_entries"."deleted_at" IS NULL`
+		mock.ExpectExec(regexp.QuoteMeta(deleteEntryStmt)).WithArgs(sqlmock.AnyArg(), "4").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		var resp DeleteBodyWeightResp
+		c.MustPost(`
+			mutation DeleteBodyWeight {
+				deleteBodyWeight(bodyWeightEntryId: "4")
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, 1, resp.DeleteBodyWeight)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
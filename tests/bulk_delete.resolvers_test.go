@@ -0,0 +1,148 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBulkDeleteResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+
+	expectUser := func(mock sqlmock.Sqlmock) {
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+	}
+
+	t.Run("Delete Workout Sessions", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectUser(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, user_id FROM "workout_sessions" WHERE id IN ($1,$2) AND "workout_sessions"."deleted_at" IS NULL`)).
+			WithArgs("3", "4").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(3, u.ID).AddRow(4, u.ID))
+		helpers.ExpectEditableWorkoutSession(mock, 3)
+		helpers.ExpectEditableWorkoutSession(mock, 4)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "workout_sessions" SET "deleted_at"=$1 WHERE id IN ($2,$3) AND "workout_sessions"."deleted_at" IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), "3", "4").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "exercises" SET "deleted_at"=$1 WHERE workout_session_id IN ($2,$3) AND "exercises"."deleted_at" IS NULL RETURNING *`)).
+			WithArgs(sqlmock.AnyArg(), "3", "4").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "workout_session_id"}).AddRow(10, 3).AddRow(11, 4))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "set_entries" SET "deleted_at"=$1 WHERE exercise_id IN ($2,$3) AND "set_entries"."deleted_at" IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), "10", "11").
+			WillReturnResult(sqlmock.NewResult(0, 6))
+		mock.ExpectCommit()
+
+		var resp struct {
+			DeleteWorkoutSessions DeleteResult
+		}
+		c.MustPost(`mutation { deleteWorkoutSessions(workoutSessionIds: ["3", "4"]) { dryRun counts { workoutSessions exercises sets } } }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, 2, resp.DeleteWorkoutSessions.Counts.WorkoutSessions)
+		require.Equal(t, 2, resp.DeleteWorkoutSessions.Counts.Exercises)
+		require.Equal(t, 6, resp.DeleteWorkoutSessions.Counts.Sets)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Delete Workout Sessions Someone Else's", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectUser(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, user_id FROM "workout_sessions" WHERE id IN ($1,$2) AND "workout_sessions"."deleted_at" IS NULL`)).
+			WithArgs("3", "4").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(3, u.ID).AddRow(4, u.ID+1))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "coaches"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		var resp struct{}
+		err := c.Post(`mutation { deleteWorkoutSessions(workoutSessionIds: ["3", "4"]) { dryRun } }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Deleting Workout Sessions: Access Denied","path":["deleteWorkoutSessions"],"extensions":{"code":"FORBIDDEN"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Delete Exercise Routines", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectUser(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT exercise_routines.id, workout_routines.user_id FROM "exercise_routines" JOIN workout_routines ON workout_routines.id = exercise_routines.workout_routine_id AND workout_routines.deleted_at IS NULL WHERE exercise_routines.id IN ($1,$2) AND "exercise_routines"."deleted_at" IS NULL`)).
+			WithArgs("5", "6").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(5, u.ID).AddRow(6, u.ID))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "exercise_routines" SET "deleted_at"=$1 WHERE id IN ($2,$3) AND "exercise_routines"."deleted_at" IS NULL RETURNING *`)).
+			WithArgs(sqlmock.AnyArg(), "5", "6").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "workout_routine_id"}).AddRow(5, 2).AddRow(6, 2))
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "exercises" SET "deleted_at"=$1 WHERE exercise_routine_id IN ($2,$3) AND "exercises"."deleted_at" IS NULL RETURNING *`)).
+			WithArgs(sqlmock.AnyArg(), "5", "6").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "workout_session_id"}).AddRow(10, 3))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "set_entries" SET "deleted_at"=$1 WHERE exercise_id IN ($2) AND "set_entries"."deleted_at" IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), "10").
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		// both were in the same routine, it's only touched once
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "workout_routines" SET "updated_at"=$1 WHERE id = $2`)).
+			WithArgs(sqlmock.AnyArg(), 2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "workout_sessions" SET "updated_at"=$1 WHERE id IN ($2)`)).
+			WithArgs(sqlmock.AnyArg(), 3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		var resp struct {
+			DeleteExerciseRoutines int
+		}
+		c.MustPost(`mutation { deleteExerciseRoutines(exerciseRoutineIds: ["5", "6"]) }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, 2, resp.DeleteExerciseRoutines)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Delete Exercise Routines Without Ids", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectUser(mock)
+
+		var resp struct{}
+		err := c.Post(`mutation { deleteExerciseRoutines(exerciseRoutineIds: []) }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Deleting Exercise Routines: between 1 and 100 ids are allowed","path":["deleteExerciseRoutines"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,231 @@
+package test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/graph-gophers/dataloader"
+	"github.com/neilZon/workout-logger-api/cache"
+	"github.com/neilZon/workout-logger-api/loader"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRedis answers the commands cache.Redis sends, over a real socket
+type fakeRedis struct {
+	password string
+
+	mu       sync.Mutex
+	values   map[string]string
+	commands []string
+}
+
+func startFakeRedis(t *testing.T, password string) (*fakeRedis, string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	f := &fakeRedis{password: password, values: map[string]string{}}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f, l.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+
+		f.mu.Lock()
+		f.commands = append(f.commands, args[0])
+		var reply string
+		switch {
+		case args[0] == "AUTH":
+			authed = args[len(args)-1] == f.password
+			reply = "+OK\r\n"
+			if !authed {
+				reply = "-WRONGPASS invalid password\r\n"
+			}
+		case !authed:
+			reply = "-NOAUTH Authentication required.\r\n"
+		case args[0] == "SET":
+			f.values[args[1]] = args[2]
+			reply = "+OK\r\n"
+		case args[0] == "GET":
+			v, ok := f.values[args[1]]
+			reply = "$-1\r\n"
+			if ok {
+				reply = fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+			}
+		case args[0] == "DEL":
+			_, ok := f.values[args[1]]
+			delete(f.values, args[1])
+			reply = ":0\r\n"
+			if ok {
+				reply = ":1\r\n"
+			}
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		f.mu.Unlock()
+
+		if _, err := conn.Write([]byte(reply)); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeRedis) seen() ([]string, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string{}, f.commands...), len(f.values)
+}
+
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "$")))
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, size+2)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return nil, err
+		}
+		args[i] = string(b[:size])
+	}
+	return args, nil
+}
+
+func TestCache(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Redis Get Set Delete", func(t *testing.T) {
+		f, addr := startFakeRedis(t, "hunter2")
+		r, err := cache.NewRedis("redis://:hunter2@" + addr)
+		require.Nil(t, err)
+
+		_, ok := r.Get("missing")
+		require.False(t, ok)
+
+		r.Set("greeting", []byte("hello\r\nworld"), time.Minute)
+		value, ok := r.Get("greeting")
+		require.True(t, ok)
+		require.Equal(t, "hello\r\nworld", string(value))
+
+		r.Delete("greeting")
+		_, ok = r.Get("greeting")
+		require.False(t, ok)
+
+		// one connection, authed once and reused
+		commands, _ := f.seen()
+		require.Equal(t, []string{"AUTH", "GET", "SET", "GET", "DEL", "GET"}, commands)
+	})
+
+	t.Run("Redis Wrong Password Is A Miss", func(t *testing.T) {
+		f, addr := startFakeRedis(t, "hunter2")
+		r, err := cache.NewRedis("redis://:wrong@" + addr)
+		require.Nil(t, err)
+
+		r.Set("greeting", []byte("hello"), time.Minute)
+		_, ok := r.Get("greeting")
+		require.False(t, ok)
+		_, stored := f.seen()
+		require.Equal(t, 0, stored)
+	})
+
+	t.Run("Redis Bad URL", func(t *testing.T) {
+		_, err := cache.NewRedis("http://localhost:6379")
+		require.EqualError(t, err, `REDIS_URL needs to start with redis:// or rediss://, got "http"`)
+
+		_, err = cache.NewRedis("redis://localhost:6379/zero")
+		require.EqualError(t, err, `REDIS_URL needs a database number after the host, got "zero"`)
+	})
+
+	t.Run("Memory Expires", func(t *testing.T) {
+		m := cache.NewMemory(time.Minute)
+		m.Set("greeting", []byte("hello"), time.Nanosecond)
+		time.Sleep(time.Millisecond)
+		_, ok := m.Get("greeting")
+		require.False(t, ok)
+	})
+
+	t.Run("Loaders Sharing A Cache See Each Other's Clears", func(t *testing.T) {
+		store := cache.NewMemory(time.Minute)
+		var mu sync.Mutex
+		reads := 0
+		tags := []string{"legs"}
+		batch := func(ctx context.Context, keys dataloader.Keys) []*dataloader.Result {
+			mu.Lock()
+			defer mu.Unlock()
+			reads++
+			results := make([]*dataloader.Result, len(keys))
+			for i := range keys {
+				results[i] = &dataloader.Result{Data: append([]string{}, tags...)}
+			}
+			return results
+		}
+		// one per instance
+		newLoader := func() *dataloader.Loader {
+			return dataloader.NewBatchedLoader(batch, dataloader.WithCache(loader.NewCache(store, "tag_slice", time.Minute, func() interface{} { return new([]string) })))
+		}
+		a, b := newLoader(), newLoader()
+		ctx := context.Background()
+		key := dataloader.StringKey("1")
+
+		loaded, err := a.Load(ctx, key)()
+		require.Nil(t, err)
+		require.Equal(t, []string{"legs"}, loaded)
+		// the result is stored once the thunk resolves
+		require.Eventually(t, func() bool {
+			_, ok := store.Get("loader:tag_slice:1")
+			return ok
+		}, time.Second, time.Millisecond)
+
+		loaded, err = b.Load(ctx, key)()
+		require.Nil(t, err)
+		require.Equal(t, []string{"legs"}, loaded)
+		require.Equal(t, 1, reads)
+
+		mu.Lock()
+		tags = []string{"legs", "deload"}
+		mu.Unlock()
+		b.Clear(ctx, key)
+
+		loaded, err = a.Load(ctx, key)()
+		require.Nil(t, err)
+		require.Equal(t, []string{"legs", "deload"}, loaded)
+		require.Equal(t, 2, reads)
+	})
+}
@@ -0,0 +1,121 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/chaos"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+// not parallel, the faults are read from the environment
+func TestChaos(t *testing.T) {
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+
+	// every call is picked
+	always := func(fault config.Fault) *chaos.Injector {
+		injector := chaos.NewInjector(fault)
+		injector.Percent = func() float64 { return 0 }
+		return injector
+	}
+
+	t.Run("Database Calls Fail Before Reaching Postgres", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		err := gormDB.Use(&database.ChaosPlugin{Injector: always(config.Fault{ErrorPercent: 100})})
+		require.NoError(t, err)
+
+		_, err = database.GetUserById(gormDB, "1")
+		require.ErrorIs(t, err, chaos.ErrInjected)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Only Targeted Resolvers Fail", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		srv := helpers.NewGqlServer(gormDB, accesscontrol.NewAccessControllerService(gormDB))
+		srv.Use(middleware.Chaos{
+			Injector: always(config.Fault{ErrorPercent: 100}),
+			Fields:   []string{"Query.currentWorkoutSession"},
+		})
+		c := client.New(srv)
+
+		var resp struct{}
+		err := c.Post(`
+			query CurrentWorkoutSession {
+				currentWorkoutSession {
+					id
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Internal server error","path":["currentWorkoutSession"],"extensions":{"code":"INTERNAL"}}]`)
+
+		// untargeted resolvers run as usual, this one stops at the token
+		err = c.Post(`
+			query QuickPhrases {
+				quickPhrases {
+					id
+				}
+			}`, &resp)
+		require.EqualError(t, err, `[{"message":"Unauthorized","path":["quickPhrases"],"extensions":{"code":"UNAUTHORIZED"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Latency Gives Up With The Request", func(t *testing.T) {
+		injector := always(config.Fault{Latency: time.Hour, LatencyPercent: 100})
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		err := injector.Inject(ctx)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("From Environment", func(t *testing.T) {
+		t.Setenv(config.CHAOS_LATENCY, "")
+		t.Setenv(config.CHAOS_DB_LATENCY_PERCENT, "")
+		t.Setenv(config.CHAOS_DB_ERROR_PERCENT, "2.5")
+		t.Setenv(config.CHAOS_RESOLVER_LATENCY_PERCENT, "50")
+		t.Setenv(config.CHAOS_RESOLVER_ERROR_PERCENT, "")
+		t.Setenv(config.CHAOS_RESOLVERS, "Mutation.addWorkoutSession, Query.workoutRoutines")
+
+		c, err := config.ChaosFromEnv()
+		require.NoError(t, err)
+		require.Equal(t, config.Chaos{
+			DB:             config.Fault{Latency: config.DefaultChaosLatency, ErrorPercent: 2.5},
+			Resolvers:      config.Fault{Latency: config.DefaultChaosLatency, LatencyPercent: 50},
+			ResolverFields: []string{"Mutation.addWorkoutSession", "Query.workoutRoutines"},
+		}, c)
+		require.True(t, c.DB.Enabled())
+		require.True(t, c.Resolvers.Enabled())
+	})
+
+	t.Run("Invalid Values", func(t *testing.T) {
+		t.Setenv(config.CHAOS_DB_ERROR_PERCENT, "150")
+		_, err := config.ChaosFromEnv()
+		require.EqualError(t, err, `CHAOS_DB_ERROR_PERCENT needs to be a percent between 0 and 100, got "150"`)
+
+		t.Setenv(config.CHAOS_DB_ERROR_PERCENT, "")
+		t.Setenv(config.CHAOS_RESOLVERS, "addWorkoutSession")
+		_, err = config.ChaosFromEnv()
+		require.EqualError(t, err, `CHAOS_RESOLVERS needs to be resolvers like Mutation.addWorkoutSession, got "addWorkoutSession"`)
+	})
+}
@@ -0,0 +1,143 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type AddSetWithMetadataResp struct {
+	AddSet struct {
+		ID             string
+		ClientMetadata map[string]interface{}
+	}
+}
+
+func TestClientMetadata(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	e := testdata.WorkoutSession.Exercises[0]
+	s := testdata.WorkoutSession.Exercises[0].Sets[0]
+
+	exerciseColumns := []string{"id", "created_at", "deleted_at", "updated_at", "workout_session_id", "exercise_routine_id", "user_id"}
+
+	const addSetMutation = `
+		mutation AddSet($exerciseId: ID!, $set: SetEntryInput!) {
+			addSet(exerciseId: $exerciseId, set: $set) {
+				id
+				clientMetadata
+			}
+		}`
+
+	t.Run("Add Set With Client Metadata", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		exerciseRow := sqlmock.NewRows(exerciseColumns).
+			AddRow(e.ID, e.CreatedAt, nil, e.UpdatedAt, e.WorkoutSessionID, e.ExerciseRoutineID, u.ID)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.ExerciseForUserQuery)).WithArgs(fmt.Sprintf("%d", e.ID), fmt.Sprintf("%d", u.ID)).WillReturnRows(exerciseRow)
+		helpers.ExpectEditableWorkoutSession(mock, e.WorkoutSessionID)
+
+		mock.ExpectBegin()
+		const addSetStmt = `INSERT INTO "set_entries" ("created_at","updated_at","deleted_at","weight","unit","reps","exercise_id","set_order","type","rest_time_seconds","completed_at","client_metadata","origin_platform","origin_app_version","origin_device_id") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15) RETURNING "id"`
+		mock.ExpectQuery(regexp.QuoteMeta(addSetStmt)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), s.Weight, "LB", s.Reps, e.ID, 1, "WORKING", nil, nil, `{"device":"watch","hr":{"avg":142}}`, "", "", "").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(s.ID))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.TouchExerciseStmt)).
+			WithArgs(sqlmock.AnyArg(), e.ID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.TouchExerciseSessionStmt)).
+			WithArgs(sqlmock.AnyArg(), e.ID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		var resp AddSetWithMetadataResp
+		c.MustPost(addSetMutation, &resp,
+			client.Var("exerciseId", fmt.Sprintf("%d", e.ID)),
+			client.Var("set", map[string]interface{}{
+				"weight":   s.Weight,
+				"reps":     s.Reps,
+				"setOrder": 1,
+				"clientMetadata": map[string]interface{}{
+					"device": "watch",
+					"hr":     map[string]interface{}{"avg": 142},
+				},
+			}),
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.Equal(t, fmt.Sprintf("%d", s.ID), resp.AddSet.ID)
+		require.Equal(t, "watch", resp.AddSet.ClientMetadata["device"])
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	rejected := []struct {
+		name     string
+		metadata map[string]interface{}
+		message  string
+	}{
+		{
+			"Add Set Rejects Oversized Client Metadata",
+			map[string]interface{}{"blob": strings.Repeat("a", 4096)},
+			"clientMetadata can't be more than 2048 bytes",
+		},
+		{
+			"Add Set Rejects Deep Client Metadata",
+			map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{"c": map[string]interface{}{"d": 1}}}},
+			"clientMetadata can't be nested more than 3 levels deep",
+		},
+		{
+			"Add Set Rejects Long Client Metadata Key",
+			map[string]interface{}{strings.Repeat("k", 65): true},
+			"clientMetadata keys need to be between 1 and 64 characters",
+		},
+	}
+	for _, r := range rejected {
+		r := r
+		t.Run(r.name, func(t *testing.T) {
+			mock, gormDB := helpers.SetupMockDB()
+			acs := accesscontrol.NewAccessControllerService(gormDB)
+			c := helpers.NewGqlClient(gormDB, acs)
+
+			// rejected before the resolver runs, so the db isn't touched
+			var resp AddSetWithMetadataResp
+			err := c.Post(addSetMutation, &resp,
+				client.Var("exerciseId", fmt.Sprintf("%d", e.ID)),
+				client.Var("set", map[string]interface{}{
+					"weight":         s.Weight,
+					"reps":           s.Reps,
+					"clientMetadata": r.metadata,
+				}),
+				helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+			)
+			require.EqualError(t, err, fmt.Sprintf(`[{"message":"%s","path":["addSet"],"extensions":{"code":"INVALID_INPUT","fields":[{"field":"set.clientMetadata","message":"%s"}]}}]`, r.message, r.message))
+
+			err = mock.ExpectationsWereMet()
+			if err != nil {
+				panic(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type GrantAccessResp struct {
+	GrantAccess struct {
+		CoachID string
+		Access  string
+	}
+}
+
+type RevokeAccessResp struct {
+	RevokeAccess int
+}
+
+func TestCoachAccessResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	coachId := uint(77)
+	coachEmail := "coach@gmail.com"
+
+	const userByEmailQuery = `SELECT * FROM "users" WHERE email = $1 AND "users"."deleted_at" IS NULL ORDER BY "users"."id" LIMIT 1`
+	const coachQuery = `SELECT * FROM "coaches" WHERE (user_id = $1 AND coach_id = $2) AND "coaches"."deleted_at" IS NULL ORDER BY "coaches"."id" LIMIT 1`
+
+	t.Run("Grant Access Links New Coach", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		coachRow := sqlmock.NewRows([]string{"id", "email", "verified"}).AddRow(coachId, coachEmail, true)
+		mock.ExpectQuery(regexp.QuoteMeta(userByEmailQuery)).WithArgs(coachEmail).WillReturnRows(coachRow)
+		mock.ExpectQuery(regexp.QuoteMeta(coachQuery)).WithArgs(fmt.Sprintf("%d", u.ID), fmt.Sprintf("%d", coachId)).WillReturnError(gorm.ErrRecordNotFound)
+
+		mock.ExpectBegin()
+		const addCoachStmt = `INSERT INTO "coaches" ("created_at","updated_at","deleted_at","user_id","coach_id","access") VALUES ($1,$2,$3,$4,$5,$6) RETURNING "id"`
+		mock.ExpectQuery(regexp.QuoteMeta(addCoachStmt)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, u.ID, coachId, "READ_WRITE").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		var resp GrantAccessResp
+		c.MustPost(`
+			mutation GrantAccess {
+				grantAccess(email: "coach@gmail.com", access: READ_WRITE) {
+					coachId
+					access
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, "77", resp.GrantAccess.CoachID)
+		require.Equal(t, "READ_WRITE", resp.GrantAccess.Access)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Grant Access Updates Existing Coach", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		coachRow := sqlmock.NewRows([]string{"id", "email", "verified"}).AddRow(coachId, coachEmail, true)
+		mock.ExpectQuery(regexp.QuoteMeta(userByEmailQuery)).WithArgs(coachEmail).WillReturnRows(coachRow)
+		linkRow := sqlmock.NewRows([]string{"id", "user_id", "coach_id", "access"}).AddRow(4, u.ID, coachId, "READ_WRITE")
+		mock.ExpectQuery(regexp.QuoteMeta(coachQuery)).WithArgs(fmt.Sprintf("%d", u.ID), fmt.Sprintf("%d", coachId)).WillReturnRows(linkRow)
+
+		mock.ExpectBegin()
+		const updateAccessStmt = `UPDATE "coaches" SET "access"=$1,"updated_at"=$2 WHERE "coaches"."deleted_at" IS NULL AND "id" = $3`
+		mock.ExpectExec(regexp.QuoteMeta(updateAccessStmt)).
+			WithArgs("READ", sqlmock.AnyArg(), 4).
+			WillReturnResult(sqlmock.NewResult(4, 1))
+		mock.ExpectCommit()
+
+		var resp GrantAccessResp
+		c.MustPost(`
+			mutation GrantAccess {
+				grantAccess(email: "coach@gmail.com", access: READ) {
+					coachId
+					access
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, "77", resp.GrantAccess.CoachID)
+		require.Equal(t, "READ", resp.GrantAccess.Access)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Grant Access To Yourself", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		selfRow := sqlmock.NewRows([]string{"id", "email", "verified"}).AddRow(u.ID, u.Subject, true)
+		mock.ExpectQuery(regexp.QuoteMeta(userByEmailQuery)).WithArgs(u.Subject).WillReturnRows(selfRow)
+
+		var resp GrantAccessResp
+		err := c.Post(fmt.Sprintf(`
+			mutation GrantAccess {
+				grantAccess(email: "%s", access: READ) {
+					coachId
+				}
+			}`, u.Subject), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.ErrorContains(t, err, "Error Granting Access: Can't Coach Yourself")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Revoke Access", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		mock.ExpectBegin()
+		const deleteCoachStmt = `DELETE FROM "coaches" WHERE user_id = $1 AND coach_id = $2`
+		mock.ExpectExec(regexp.QuoteMeta(deleteCoachStmt)).
+			WithArgs(fmt.Sprintf("%d", u.ID), "77").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		var resp RevokeAccessResp
+		c.MustPost(`
+			mutation RevokeAccess {
+				revokeAccess(coachId: "77")
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, 1, resp.RevokeAccess)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,276 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/cache"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type CurrentWorkoutSessionResp struct {
+	CurrentWorkoutSession *struct {
+		ID    string
+		Start string
+		End   *string
+	}
+}
+
+type FinishWorkoutSessionResp struct {
+	FinishWorkoutSession struct {
+		ID  string
+		End string
+	}
+}
+
+func TestCurrentWorkoutSessionResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	ws := testdata.WorkoutSession
+	wr := testdata.WorkoutRoutine
+
+	workoutSessionColumns := []string{"id", "user_id", "start", "end", "workout_routine_id"}
+	const openWorkoutSessionQuery = `SELECT * FROM "workout_sessions" WHERE (user_id = $1 AND "end" IS NULL) AND "workout_sessions"."deleted_at" IS NULL ORDER BY "workout_sessions"."id" LIMIT 1`
+
+	t.Run("Current Workout Session", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		workoutSessionRow := sqlmock.NewRows(workoutSessionColumns).AddRow(ws.ID, u.ID, ws.Start, nil, ws.WorkoutRoutineID)
+		mock.ExpectQuery(regexp.QuoteMeta(openWorkoutSessionQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(workoutSessionRow)
+
+		var resp CurrentWorkoutSessionResp
+		c.MustPost(`
+			query CurrentWorkoutSession {
+				currentWorkoutSession {
+					id
+					start
+					end
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.NotNil(t, resp.CurrentWorkoutSession)
+		require.Equal(t, fmt.Sprintf("%d", ws.ID), resp.CurrentWorkoutSession.ID)
+		require.Equal(t, "2022-10-30T12:34:00Z", resp.CurrentWorkoutSession.Start)
+		require.Nil(t, resp.CurrentWorkoutSession.End)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("No Current Workout Session", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		mock.ExpectQuery(regexp.QuoteMeta(openWorkoutSessionQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(sqlmock.NewRows(workoutSessionColumns))
+
+		var resp CurrentWorkoutSessionResp
+		c.MustPost(`
+			query CurrentWorkoutSession {
+				currentWorkoutSession {
+					id
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Nil(t, resp.CurrentWorkoutSession)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Second Open Workout Session Is Rejected", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		workoutRoutineRow := sqlmock.
+			NewRows([]string{"id", "name", "created_at", "deleted_at", "updated_at", "user_id", "active"}).
+			AddRow(wr.ID, wr.Name, wr.CreatedAt, wr.DeletedAt, wr.UpdatedAt, wr.UserID, wr.Active)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).WithArgs(fmt.Sprintf("%d", wr.ID)).WillReturnRows(workoutRoutineRow)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.LockOpenWorkoutSessionQuery)).
+			WithArgs(u.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(ws.ID))
+		mock.ExpectRollback()
+
+		var resp struct{}
+		err := c.Post(fmt.Sprintf(`
+			mutation AddWorkoutSession {
+				addWorkoutSession(workout: { start: "2022-10-31T12:00:00Z", workoutRoutineId: "%d", exercises: [] }) {
+					id
+				}
+			}`, wr.ID), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Adding Workout Session: Finish The Current Workout Session First","path":["addWorkoutSession"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Workout Session For A Deleted Routine Is Rejected", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		ac := &accesscontrol.AccessController{DB: gormDB, Cache: cache.NewMemory(time.Minute)}
+		c := helpers.NewGqlClient(gormDB, ac)
+
+		// the routine was deleted after its owner was cached
+		ac.Cache.Set(fmt.Sprintf("owner:workout_routines:%d", wr.ID), []byte(fmt.Sprintf("%d", u.ID)), time.Minute)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.LockOpenWorkoutSessionQuery)).
+			WithArgs(u.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "workout_routines" WHERE id = $1 AND "workout_routines"."deleted_at" IS NULL FOR SHARE`)).
+			WithArgs(wr.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
+
+		var resp struct{}
+		err := c.Post(fmt.Sprintf(`
+			mutation AddWorkoutSession {
+				addWorkoutSession(workout: { start: "2022-10-31T12:00:00Z", workoutRoutineId: "%d", exercises: [] }) {
+					id
+				}
+			}`, wr.ID), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Adding Workout Session","path":["addWorkoutSession"],"extensions":{"code":"NOT_FOUND"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Finish Workout Session", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		workoutSessionRow := sqlmock.NewRows(workoutSessionColumns).AddRow(ws.ID, u.ID, ws.Start, nil, ws.WorkoutRoutineID)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(workoutSessionRow)
+		workoutSessionRow = sqlmock.NewRows(workoutSessionColumns).AddRow(ws.ID, u.ID, ws.Start, nil, ws.WorkoutRoutineID)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(workoutSessionRow)
+
+		end := ws.Start.Add(time.Hour)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "workout_sessions" SET "end"=$1,"updated_at"=$2 WHERE (id = $3 AND "end" IS NULL) AND "workout_sessions"."deleted_at" IS NULL RETURNING *`)).
+			WithArgs(end, sqlmock.AnyArg(), fmt.Sprintf("%d", ws.ID)).
+			WillReturnRows(sqlmock.NewRows(workoutSessionColumns).AddRow(ws.ID, u.ID, ws.Start, end, ws.WorkoutRoutineID))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.QueueWebhookDeliveriesStmt)).
+			WithArgs(ws.ID, u.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.QueueStravaUploadStmt)).
+			WithArgs(ws.ID, u.ID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		var resp FinishWorkoutSessionResp
+		c.MustPost(fmt.Sprintf(`
+			mutation FinishWorkoutSession {
+				finishWorkoutSession(workoutSessionId: "%d", end: "2022-10-30T13:34:00Z") {
+					id
+					end
+				}
+			}`, ws.ID), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, fmt.Sprintf("%d", ws.ID), resp.FinishWorkoutSession.ID)
+		require.Equal(t, "2022-10-30T13:34:00Z", resp.FinishWorkoutSession.End)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Finish Workout Session Twice", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		workoutSessionRow := sqlmock.NewRows(workoutSessionColumns).AddRow(ws.ID, u.ID, ws.Start, ws.End, ws.WorkoutRoutineID)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(workoutSessionRow)
+		workoutSessionRow = sqlmock.NewRows(workoutSessionColumns).AddRow(ws.ID, u.ID, ws.Start, ws.End, ws.WorkoutRoutineID)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(workoutSessionRow)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "workout_sessions" SET "end"=$1,"updated_at"=$2 WHERE (id = $3 AND "end" IS NULL) AND "workout_sessions"."deleted_at" IS NULL RETURNING *`)).
+			WillReturnRows(sqlmock.NewRows(workoutSessionColumns))
+		mock.ExpectRollback()
+
+		var resp FinishWorkoutSessionResp
+		err := c.Post(fmt.Sprintf(`
+			mutation FinishWorkoutSession {
+				finishWorkoutSession(workoutSessionId: "%d", end: "2022-10-30T13:34:00Z") {
+					id
+				}
+			}`, ws.ID), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Finishing Workout Session: Already Finished","path":["finishWorkoutSession"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Finish Workout Session Before It Started", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		workoutSessionRow := sqlmock.NewRows(workoutSessionColumns).AddRow(ws.ID, u.ID, ws.Start, nil, ws.WorkoutRoutineID)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(workoutSessionRow)
+		workoutSessionRow = sqlmock.NewRows(workoutSessionColumns).AddRow(ws.ID, u.ID, ws.Start, nil, ws.WorkoutRoutineID)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(workoutSessionRow)
+
+		var resp FinishWorkoutSessionResp
+		err := c.Post(fmt.Sprintf(`
+			mutation FinishWorkoutSession {
+				finishWorkoutSession(workoutSessionId: "%d", end: "2022-10-30T11:00:00Z") {
+					id
+				}
+			}`, ws.ID), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Finishing Workout Session: End Can't Be Before Start","path":["finishWorkoutSession"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
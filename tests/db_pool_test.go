@@ -0,0 +1,55 @@
+package test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/stretchr/testify/require"
+)
+
+// not parallel, the pool is read from the environment
+func TestDBPool(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		for _, name := range []string{config.DB_MAX_OPEN_CONNS, config.DB_MAX_IDLE_CONNS, config.DB_CONN_MAX_LIFETIME, config.DB_CONN_MAX_IDLE_TIME, config.DB_STATEMENT_TIMEOUT} {
+			t.Setenv(name, "")
+		}
+
+		pool, err := config.DBPoolFromEnv()
+		require.NoError(t, err)
+		require.Equal(t, config.DefaultDBPool, pool)
+	})
+
+	t.Run("From Environment", func(t *testing.T) {
+		t.Setenv(config.DB_MAX_OPEN_CONNS, "40")
+		t.Setenv(config.DB_MAX_IDLE_CONNS, "60")
+		t.Setenv(config.DB_CONN_MAX_LIFETIME, "1h")
+		t.Setenv(config.DB_CONN_MAX_IDLE_TIME, "90s")
+		t.Setenv(config.DB_STATEMENT_TIMEOUT, "15s")
+
+		pool, err := config.DBPoolFromEnv()
+		require.NoError(t, err)
+		require.Equal(t, config.DBPool{
+			MaxOpenConns:     40,
+			MaxIdleConns:     40, // capped at open
+			ConnMaxLifetime:  time.Hour,
+			ConnMaxIdleTime:  90 * time.Second,
+			StatementTimeout: 15 * time.Second,
+		}, pool)
+	})
+
+	t.Run("Invalid Values", func(t *testing.T) {
+		t.Setenv(config.DB_MAX_OPEN_CONNS, "lots")
+		_, err := config.DBPoolFromEnv()
+		require.EqualError(t, err, `DB_MAX_OPEN_CONNS needs to be a whole number of connections, got "lots"`)
+
+		t.Setenv(config.DB_MAX_OPEN_CONNS, "1")
+		_, err = config.DBPoolFromEnv()
+		require.EqualError(t, err, "DB_MAX_OPEN_CONNS needs to be at least 2, migrations use two connections at once")
+
+		t.Setenv(config.DB_MAX_OPEN_CONNS, "")
+		t.Setenv(config.DB_STATEMENT_TIMEOUT, "30")
+		_, err = config.DBPoolFromEnv()
+		require.EqualError(t, err, `DB_STATEMENT_TIMEOUT needs to be a duration like 30s, got "30"`)
+	})
+}
@@ -0,0 +1,260 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type DeleteResult struct {
+	DryRun bool
+	Counts struct {
+		WorkoutRoutines  int
+		ExerciseRoutines int
+		WorkoutSessions  int
+		Exercises        int
+		Sets             int
+		ExerciseVideos   int
+		BodyWeights      int
+		Goals            int
+		NutritionLogs    int
+		SleepLogs        int
+	}
+}
+
+type DeleteAccountResp struct {
+	DeleteAccount DeleteResult
+}
+
+func TestDeleteAccountResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	userId := fmt.Sprintf("%d", u.ID)
+
+	// every table that can hold the user's rows, children first
+	purgedTables := []string{
+		"video_annotations",
+		"exercise_videos",
+		"set_entries",
+		"exercises",
+		"workout_session_participants",
+		"workout_session_share_links",
+		"workout_session_unlocks",
+		"workout_session_auto_finishes",
+		"webhook_deliveries",
+		"strava_uploads",
+		"workout_session_tags",
+		"workout_sessions",
+		"workout_routine_share_links",
+		"program_days",
+		"programs",
+		"exercise_routines",
+		"workout_routines",
+		"coaches",
+		"request_recordings",
+		"archived_workout_sessions",
+		"body_weight_entries",
+		"goals",
+		"nutrition_logs",
+		"sleep_logs",
+		"unit_conversions",
+		"quick_phrases",
+		"account_exports",
+		"snapshots",
+		"webhooks",
+		"strava_connections",
+		"progress_photos",
+		"sync_records",
+		"audit_logs",
+		"password_reset_tokens",
+		"refresh_tokens",
+		"sessions",
+		"user_preferences",
+		"push_tokens",
+		"weekly_digests",
+		"login_throttles",
+		"users",
+	}
+
+	t.Run("Delete Account", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "storage_key" FROM "exercise_videos" WHERE id IN (SELECT id FROM exercise_videos WHERE user_id = $1 OR exercise_id IN (SELECT id FROM exercises WHERE user_id = $2 OR workout_session_id IN (SELECT id FROM workout_sessions WHERE user_id = $3)))`)).
+			WithArgs(userId, userId, userId).
+			WillReturnRows(sqlmock.NewRows([]string{"storage_key"}).AddRow("videos/28/missing.mp4"))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "storage_key" FROM "archived_workout_sessions" WHERE user_id = $1`)).
+			WithArgs(userId).
+			WillReturnRows(sqlmock.NewRows([]string{"storage_key"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "storage_key" FROM "account_exports" WHERE user_id = $1 AND storage_key <> ''`)).
+			WithArgs(userId).
+			WillReturnRows(sqlmock.NewRows([]string{"storage_key"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "storage_key" FROM "snapshots" WHERE user_id = $1`)).
+			WithArgs(userId).
+			WillReturnRows(sqlmock.NewRows([]string{"storage_key"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "storage_key" FROM "progress_photos" WHERE user_id = $1`)).
+			WithArgs(userId).
+			WillReturnRows(sqlmock.NewRows([]string{"storage_key"}))
+		for _, table := range purgedTables {
+			mock.ExpectExec(regexp.QuoteMeta(fmt.Sprintf(`DELETE FROM "%s" WHERE`, table))).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		mock.ExpectCommit()
+
+		// a file that can't be removed doesn't fail the deletion
+		var resp DeleteAccountResp
+		c.MustPost(`
+			mutation DeleteAccount {
+				deleteAccount {
+					dryRun
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.False(t, resp.DeleteAccount.DryRun)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Delete Account Dry Run", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "storage_key" FROM "exercise_videos"`)).WillReturnRows(sqlmock.NewRows([]string{"storage_key"}).AddRow("videos/28/squat.mp4"))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "storage_key" FROM "archived_workout_sessions"`)).WillReturnRows(sqlmock.NewRows([]string{"storage_key"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "storage_key" FROM "account_exports"`)).WillReturnRows(sqlmock.NewRows([]string{"storage_key"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "storage_key" FROM "snapshots"`)).WillReturnRows(sqlmock.NewRows([]string{"storage_key"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "storage_key" FROM "progress_photos"`)).WillReturnRows(sqlmock.NewRows([]string{"storage_key"}))
+		affected := map[string]int64{
+			"exercise_videos":     1,
+			"set_entries":         42,
+			"exercises":           12,
+			"workout_sessions":    4,
+			"exercise_routines":   3,
+			"workout_routines":    1,
+			"body_weight_entries": 30,
+			"goals":               2,
+			"nutrition_logs":      7,
+			"sleep_logs":          5,
+		}
+		for _, table := range purgedTables {
+			mock.ExpectExec(regexp.QuoteMeta(fmt.Sprintf(`DELETE FROM "%s" WHERE`, table))).
+				WillReturnResult(sqlmock.NewResult(0, affected[table]))
+		}
+		mock.ExpectRollback()
+
+		// nothing is committed and no file is touched
+		var resp DeleteAccountResp
+		c.MustPost(`
+			mutation DeleteAccount {
+				deleteAccount(dryRun: true) {
+					dryRun
+					counts {
+						workoutRoutines
+						exerciseRoutines
+						workoutSessions
+						exercises
+						sets
+						exerciseVideos
+						bodyWeights
+						goals
+						nutritionLogs
+						sleepLogs
+					}
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.True(t, resp.DeleteAccount.DryRun)
+		counts := resp.DeleteAccount.Counts
+		require.Equal(t, 1, counts.WorkoutRoutines)
+		require.Equal(t, 3, counts.ExerciseRoutines)
+		require.Equal(t, 4, counts.WorkoutSessions)
+		require.Equal(t, 12, counts.Exercises)
+		require.Equal(t, 42, counts.Sets)
+		require.Equal(t, 1, counts.ExerciseVideos)
+		require.Equal(t, 30, counts.BodyWeights)
+		require.Equal(t, 2, counts.Goals)
+		require.Equal(t, 7, counts.NutritionLogs)
+		require.Equal(t, 5, counts.SleepLogs)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Delete Account Rolls Back", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "storage_key" FROM "exercise_videos"`)).WillReturnRows(sqlmock.NewRows([]string{"storage_key"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "storage_key" FROM "archived_workout_sessions"`)).WillReturnRows(sqlmock.NewRows([]string{"storage_key"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "storage_key" FROM "account_exports"`)).WillReturnRows(sqlmock.NewRows([]string{"storage_key"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "storage_key" FROM "snapshots"`)).WillReturnRows(sqlmock.NewRows([]string{"storage_key"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "storage_key" FROM "progress_photos"`)).WillReturnRows(sqlmock.NewRows([]string{"storage_key"}))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "video_annotations" WHERE`)).WillReturnError(fmt.Errorf("connection reset"))
+		mock.ExpectRollback()
+
+		var resp DeleteAccountResp
+		err := c.Post(`
+			mutation DeleteAccount {
+				deleteAccount {
+					dryRun
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Deleting Account","path":["deleteAccount"],"extensions":{"code":"INTERNAL"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Delete Account Invalid Token", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		var resp DeleteAccountResp
+		err := c.Post(`
+			mutation DeleteAccount {
+				deleteAccount {
+					dryRun
+				}
+			}`, &resp)
+		require.EqualError(t, err, "[{\"message\":\"Unauthorized\",\"path\":[\"deleteAccount\"],\"extensions\":{\"code\":\"UNAUTHORIZED\"}}]")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,221 @@
+package test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type AddSetWithOriginResp struct {
+	AddSet struct {
+		ID     string
+		Origin *struct {
+			Platform   string
+			AppVersion string
+			DeviceId   string
+		}
+	}
+}
+
+type DeviceOriginsResp struct {
+	DeviceOrigins []struct {
+		Platform   string
+		AppVersion string
+		Sessions   int
+		Sets       int
+		Devices    int
+		LastSeenAt string
+	}
+}
+
+func TestDeviceOrigin(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	e := testdata.WorkoutSession.Exercises[0]
+	s := testdata.WorkoutSession.Exercises[0].Sets[0]
+
+	exerciseColumns := []string{"id", "created_at", "deleted_at", "updated_at", "workout_session_id", "exercise_routine_id", "user_id"}
+
+	const deviceOriginsQuery = `COUNT(*) FILTER (WHERE kind = 'session') AS sessions`
+	start := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Client Middleware Reads Headers", func(t *testing.T) {
+		var origin database.Origin
+		h := middleware.ClientMiddleware(nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin = middleware.GetClient(r.Context())
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "/query", nil)
+		req.Header.Set(middleware.ClientPlatformHeader, " iOS ")
+		req.Header.Set(middleware.ClientAppVersionHeader, "2.3.1")
+		req.Header.Set(middleware.ClientDeviceIDHeader, strings.Repeat("d", 80))
+		h.ServeHTTP(httptest.NewRecorder(), req)
+
+		require.Equal(t, "ios", origin.Platform)
+		require.Equal(t, "2.3.1", origin.AppVersion)
+		require.Equal(t, strings.Repeat("d", 64), origin.DeviceID)
+	})
+
+	t.Run("Add Set Records Origin", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		exerciseRow := sqlmock.NewRows(exerciseColumns).
+			AddRow(e.ID, e.CreatedAt, nil, e.UpdatedAt, e.WorkoutSessionID, e.ExerciseRoutineID, u.ID)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.ExerciseForUserQuery)).WithArgs(fmt.Sprintf("%d", e.ID), fmt.Sprintf("%d", u.ID)).WillReturnRows(exerciseRow)
+		helpers.ExpectEditableWorkoutSession(mock, e.WorkoutSessionID)
+
+		mock.ExpectBegin()
+		const addSetStmt = `INSERT INTO "set_entries" ("created_at","updated_at","deleted_at","weight","unit","reps","exercise_id","set_order","type","rest_time_seconds","completed_at","client_metadata","origin_platform","origin_app_version","origin_device_id") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15) RETURNING "id"`
+		mock.ExpectQuery(regexp.QuoteMeta(addSetStmt)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), s.Weight, "LB", s.Reps, e.ID, 1, "WORKING", nil, nil, nil, "watchos", "2.3.1", "device-1").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(s.ID))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.TouchExerciseStmt)).
+			WithArgs(sqlmock.AnyArg(), e.ID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.TouchExerciseSessionStmt)).
+			WithArgs(sqlmock.AnyArg(), e.ID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		var resp AddSetWithOriginResp
+		mutation := fmt.Sprintf(`
+			mutation AddSet {
+				addSet(exerciseId: "%d", set: { weight: %f, reps: %d, setOrder: 1 }) {
+					id
+					origin {
+						platform
+						appVersion
+						deviceId
+					}
+				}
+			}`, e.ID, s.Weight, s.Reps)
+		c.MustPost(mutation, &resp,
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+			helpers.AddClient(database.Origin{Platform: "watchos", AppVersion: "2.3.1", DeviceID: "device-1"}),
+		)
+		require.Equal(t, fmt.Sprintf("%d", s.ID), resp.AddSet.ID)
+		require.NotNil(t, resp.AddSet.Origin)
+		require.Equal(t, "watchos", resp.AddSet.Origin.Platform)
+		require.Equal(t, "2.3.1", resp.AddSet.Origin.AppVersion)
+		require.Equal(t, "device-1", resp.AddSet.Origin.DeviceId)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Get Device Origins", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified", "admin"}).AddRow(u.ID, true, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		lastSeenAt := time.Date(2022, 10, 20, 18, 0, 0, 0, time.UTC)
+		statRows := sqlmock.NewRows([]string{"platform", "app_version", "sessions", "sets", "devices", "last_seen_at"}).
+			AddRow("ios", "2.3.1", 12, 140, 5, lastSeenAt).
+			AddRow("", "", 1, 9, 0, lastSeenAt)
+		mock.ExpectQuery(regexp.QuoteMeta(deviceOriginsQuery)).
+			WithArgs(start, end, start, end).
+			WillReturnRows(statRows)
+
+		var resp DeviceOriginsResp
+		c.MustPost(fmt.Sprintf(`
+			query DeviceOrigins {
+				deviceOrigins(range: { start: "%s", end: "%s" }) {
+					platform
+					appVersion
+					sessions
+					sets
+					devices
+					lastSeenAt
+				}
+			}`, start.Format(time.RFC3339), end.Format(time.RFC3339)), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+
+		require.Len(t, resp.DeviceOrigins, 2)
+		require.Equal(t, "ios", resp.DeviceOrigins[0].Platform)
+		require.Equal(t, "2.3.1", resp.DeviceOrigins[0].AppVersion)
+		require.Equal(t, 12, resp.DeviceOrigins[0].Sessions)
+		require.Equal(t, 140, resp.DeviceOrigins[0].Sets)
+		require.Equal(t, 5, resp.DeviceOrigins[0].Devices)
+		require.Equal(t, "", resp.DeviceOrigins[1].Platform)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Get Device Origins Range Too Long", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified", "admin"}).AddRow(u.ID, true, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		var resp DeviceOriginsResp
+		err := c.Post(fmt.Sprintf(`
+			query DeviceOrigins {
+				deviceOrigins(range: { start: "%s", end: "%s" }) {
+					platform
+				}
+			}`, start.Format(time.RFC3339), start.AddDate(1, 0, 0).Format(time.RFC3339)), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Getting Device Origins: range can be at most 92 days","path":["deviceOrigins"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Get Device Origins Not Admin", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified", "admin"}).AddRow(u.ID, true, false)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		var resp DeviceOriginsResp
+		err := c.Post(fmt.Sprintf(`
+			query DeviceOrigins {
+				deviceOrigins(range: { start: "%s", end: "%s" }) {
+					platform
+				}
+			}`, start.Format(time.RFC3339), end.Format(time.RFC3339)), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Access Denied","path":["deviceOrigins"],"extensions":{"code":"FORBIDDEN"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebsocketDrainer(t *testing.T) {
+	t.Parallel()
+
+	upgrade := func() *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/query", nil)
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		return req
+	}
+
+	t.Run("Websockets Close On Shutdown", func(t *testing.T) {
+		d := middleware.NewWebsocketDrainer()
+		started := make(chan struct{})
+		h := d.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			// like a subscription, open until its context is done
+			<-r.Context().Done()
+		}))
+		go h.ServeHTTP(httptest.NewRecorder(), upgrade())
+		<-started
+
+		d.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		require.NoError(t, d.Wait(ctx))
+
+		// new websockets are turned away once draining
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, upgrade())
+		require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	})
+
+	t.Run("Wait Gives Up After Timeout", func(t *testing.T) {
+		d := middleware.NewWebsocketDrainer()
+		started := make(chan struct{})
+		release := make(chan struct{})
+		defer close(release)
+		h := d.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			<-release
+		}))
+		go h.ServeHTTP(httptest.NewRecorder(), upgrade())
+		<-started
+
+		d.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		require.ErrorIs(t, d.Wait(ctx), context.DeadlineExceeded)
+	})
+
+	t.Run("Other Requests Pass Through", func(t *testing.T) {
+		d := middleware.NewWebsocketDrainer()
+		d.Close()
+
+		// http.Server.Shutdown drains plain requests itself
+		var cancelled bool
+		h := d.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cancelled = r.Context().Err() != nil
+		}))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/query", nil))
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.False(t, cancelled)
+	})
+}
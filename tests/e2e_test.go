@@ -0,0 +1,181 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/clock"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/e2e"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/neilZon/workout-logger-api/token"
+	"github.com/stretchr/testify/require"
+)
+
+// not parallel, the clock is shared by the whole process. top level tests that
+// don't call t.Parallel finish before the parallel ones start
+func TestE2ETestMode(t *testing.T) {
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	frozenAt := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	postClock := func(body string) time.Time {
+		req := httptest.NewRequest(http.MethodPost, "/e2e/clock", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		(&e2e.ClockHandler{}).ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var resp struct{ Now time.Time }
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+		return resp.Now
+	}
+
+	t.Run("Freeze And Advance The Clock", func(t *testing.T) {
+		defer clock.Reset()
+
+		require.Equal(t, frozenAt, postClock(`{"freeze": "2023-01-01T00:00:00Z"}`).UTC())
+		require.Equal(t, frozenAt.Add(25*time.Hour), postClock(`{"advance": "25h"}`).UTC())
+		require.Equal(t, frozenAt.Add(25*time.Hour), clock.Now().UTC())
+
+		now := postClock(`{"reset": true}`)
+		require.WithinDuration(t, time.Now(), now, time.Minute)
+	})
+
+	t.Run("Bad Advance Is Rejected", func(t *testing.T) {
+		defer clock.Reset()
+
+		req := httptest.NewRequest(http.MethodPost, "/e2e/clock", strings.NewReader(`{"advance": "-1h"}`))
+		rec := httptest.NewRecorder()
+		(&e2e.ClockHandler{}).ServeHTTP(rec, req)
+		require.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Minted Token Expires With The Clock", func(t *testing.T) {
+		defer clock.Reset()
+		t.Setenv(config.E2E_TEST_MODE, "true")
+		t.Setenv("K_SERVICE", "")
+		t.Setenv(config.E2E_USER_EMAILS, "someone@test.com, "+u.Subject)
+		mock, gormDB := helpers.SetupMockDB()
+
+		postClock(`{"freeze": "2023-01-01T00:00:00Z"}`)
+
+		userRow := sqlmock.NewRows([]string{"id", "name", "email"}).AddRow(u.ID, u.Name, u.Subject)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE email = $1 AND "users"."deleted_at" IS NULL ORDER BY "users"."id" LIMIT 1`)).
+			WithArgs(u.Subject).
+			WillReturnRows(userRow)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "sessions"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "refresh_tokens"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		req := httptest.NewRequest(http.MethodPost, "/e2e/token", strings.NewReader(fmt.Sprintf(`{"email": "%s"}`, u.Subject)))
+		rec := httptest.NewRecorder()
+		(&e2e.TokenHandler{DB: gormDB}).ServeHTTP(rec, req)
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var resp struct {
+			AccessToken  string
+			RefreshToken string
+		}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&resp))
+
+		secret := []byte(os.Getenv(config.ACCESS_SECRET))
+		claims, err := token.Decode("Bearer "+resp.AccessToken, secret)
+		require.NoError(t, err)
+		require.Equal(t, u.ID, claims.ID)
+		require.Equal(t, frozenAt.Unix(), claims.IssuedAt)
+		require.NotEmpty(t, claims.Family)
+
+		// refreshable, its jti is the token StartSession stored
+		refreshClaims, err := token.Decode("Bearer "+resp.RefreshToken, []byte(os.Getenv(config.REFRESH_SECRET)))
+		require.NoError(t, err)
+		require.Equal(t, claims.Family, refreshClaims.Family)
+		require.NotEmpty(t, refreshClaims.Id)
+
+		postClock(fmt.Sprintf(`{"advance": "%s"}`, config.ACCESS_TTL*time.Hour+time.Second))
+		_, err = token.Decode("Bearer "+resp.AccessToken, secret)
+		require.Error(t, err)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Token For Unknown User", func(t *testing.T) {
+		t.Setenv(config.E2E_TEST_MODE, "true")
+		t.Setenv("K_SERVICE", "")
+		t.Setenv(config.E2E_USER_EMAILS, "nobody@test.com")
+		mock, gormDB := helpers.SetupMockDB()
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE email = $1 AND "users"."deleted_at" IS NULL ORDER BY "users"."id" LIMIT 1`)).
+			WithArgs("nobody@test.com").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		req := httptest.NewRequest(http.MethodPost, "/e2e/token", strings.NewReader(`{"email": "nobody@test.com"}`))
+		rec := httptest.NewRecorder()
+		(&e2e.TokenHandler{DB: gormDB}).ServeHTTP(rec, req)
+		require.Equal(t, http.StatusNotFound, rec.Code)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Token Only For Seeded Users", func(t *testing.T) {
+		t.Setenv(config.E2E_TEST_MODE, "true")
+		t.Setenv("K_SERVICE", "")
+		t.Setenv(config.E2E_USER_EMAILS, "")
+		mock, gormDB := helpers.SetupMockDB()
+
+		req := httptest.NewRequest(http.MethodPost, "/e2e/token", strings.NewReader(fmt.Sprintf(`{"email": "%s"}`, u.Subject)))
+		rec := httptest.NewRecorder()
+		(&e2e.TokenHandler{DB: gormDB}).ServeHTTP(rec, req)
+		require.Equal(t, http.StatusForbidden, rec.Code)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Token Off Outside E2E Test Mode", func(t *testing.T) {
+		t.Setenv(config.E2E_TEST_MODE, "")
+		t.Setenv(config.E2E_USER_EMAILS, u.Subject)
+		mock, gormDB := helpers.SetupMockDB()
+
+		req := httptest.NewRequest(http.MethodPost, "/e2e/token", strings.NewReader(fmt.Sprintf(`{"email": "%s"}`, u.Subject)))
+		rec := httptest.NewRecorder()
+		(&e2e.TokenHandler{DB: gormDB}).ServeHTTP(rec, req)
+		require.Equal(t, http.StatusNotFound, rec.Code)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Off On Cloud Run", func(t *testing.T) {
+		t.Setenv(config.E2E_TEST_MODE, "true")
+		require.True(t, e2e.Enabled())
+		t.Setenv("K_SERVICE", "workout-logger-api")
+		require.False(t, e2e.Enabled())
+	})
+}
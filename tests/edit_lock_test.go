@@ -0,0 +1,129 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type UnlockWorkoutSessionResp struct {
+	UnlockWorkoutSession struct {
+		WorkoutSessionId string
+		UnlockedUntil    string
+	}
+}
+
+func TestEditLock(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	ws := testdata.WorkoutSession
+
+	workoutSessionColumns := []string{"id", "user_id", "start", "end", "workout_routine_id", "created_at", "deleted_at", "updated_at"}
+
+	t.Run("Update Locked Workout Session", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		workoutSessionRow := sqlmock.NewRows(workoutSessionColumns).
+			AddRow(ws.ID, u.ID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.CreatedAt, ws.DeletedAt, ws.UpdatedAt)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(workoutSessionRow)
+
+		// logged back in 2022 and never unlocked
+		lockRow := sqlmock.NewRows([]string{"start", "unlocked_until"}).AddRow(ws.Start, nil)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionEditLockQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(lockRow)
+
+		var resp struct{}
+		err := c.Post(fmt.Sprintf(`
+			mutation UpdateWorkoutSession {
+				updateWorkoutSession(workoutSessionId: "%d", updateWorkoutSessionInput: { end: "2022-10-30T14:00:00Z" }) {
+					id
+				}
+			}`, ws.ID), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Workout sessions older than 90 days are locked, unlock the session to edit it","path":["updateWorkoutSession"],"extensions":{"code":"SESSION_LOCKED","lockAfterDays":90}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Unlock Workout Session", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		workoutSessionRow := sqlmock.NewRows(workoutSessionColumns).
+			AddRow(ws.ID, u.ID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.CreatedAt, ws.DeletedAt, ws.UpdatedAt)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(workoutSessionRow)
+
+		mock.ExpectBegin()
+		const addUnlockStmt = `INSERT INTO "workout_session_unlocks" ("created_at","updated_at","deleted_at","workout_session_id","user_id","expires_at") VALUES ($1,$2,$3,$4,$5,$6) RETURNING "id"`
+		mock.ExpectQuery(regexp.QuoteMeta(addUnlockStmt)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, ws.ID, u.ID, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		var resp UnlockWorkoutSessionResp
+		c.MustPost(fmt.Sprintf(`
+			mutation UnlockWorkoutSession {
+				unlockWorkoutSession(workoutSessionId: "%d") {
+					workoutSessionId
+					unlockedUntil
+				}
+			}`, ws.ID), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, fmt.Sprintf("%d", ws.ID), resp.UnlockWorkoutSession.WorkoutSessionId)
+		require.NotEmpty(t, resp.UnlockWorkoutSession.UnlockedUntil)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Unlock Workout Session Not Owner", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		workoutSessionRow := sqlmock.NewRows(workoutSessionColumns).
+			AddRow(ws.ID, u.ID+1, ws.Start, ws.End, ws.WorkoutRoutineID, ws.CreatedAt, ws.DeletedAt, ws.UpdatedAt)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(workoutSessionRow)
+
+		var resp UnlockWorkoutSessionResp
+		err := c.Post(fmt.Sprintf(`
+			mutation UnlockWorkoutSession {
+				unlockWorkoutSession(workoutSessionId: "%d") {
+					workoutSessionId
+				}
+			}`, ws.ID), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Unlocking Workout Session: Access Denied","path":["unlockWorkoutSession"],"extensions":{"code":"FORBIDDEN"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
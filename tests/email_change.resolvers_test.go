@@ -0,0 +1,133 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type RequestEmailChangeResp struct {
+	RequestEmailChange bool
+}
+
+type ConfirmEmailChangeResp struct {
+	ConfirmEmailChange bool
+}
+
+func TestEmailChangeResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+
+	const userByEmailQuery = `SELECT * FROM "users" WHERE email = $1 AND "users"."deleted_at" IS NULL ORDER BY "users"."id" LIMIT 1`
+	const userByCodeQuery = `SELECT * FROM "users" WHERE email_change_code = $1 AND "users"."deleted_at" IS NULL ORDER BY "users"."id" LIMIT 1`
+
+	t.Run("Request Email Change Already Taken", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(userByEmailQuery)).
+			WithArgs("taken@test.com").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(7, "taken@test.com"))
+
+		var resp RequestEmailChangeResp
+		err := c.Post(`
+			mutation RequestEmailChange {
+				requestEmailChange(newEmail: "taken@test.com")
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"email already exists","path":["requestEmailChange"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Request Email Change Invalid Email", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		var resp RequestEmailChangeResp
+		err := c.Post(`
+			mutation RequestEmailChange {
+				requestEmailChange(newEmail: "not an email")
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"not a valid email","path":["requestEmailChange"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Confirm Email Change Expired", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		mock.ExpectQuery(regexp.QuoteMeta(userByCodeQuery)).
+			WithArgs("code").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "pending_email", "email_change_code", "email_change_sent_at"}).
+				AddRow(u.ID, u.Subject, "new@test.com", "code", time.Now().Add(-48*time.Hour)))
+
+		var resp ConfirmEmailChangeResp
+		err := c.Post(`
+			mutation ConfirmEmailChange {
+				confirmEmailChange(token: "code")
+			}`, &resp)
+		require.EqualError(t, err, `[{"message":"email change link expired","path":["confirmEmailChange"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Confirm Email Change Taken Since Request", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		mock.ExpectQuery(regexp.QuoteMeta(userByCodeQuery)).
+			WithArgs("code").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "pending_email", "email_change_code", "email_change_sent_at"}).
+				AddRow(u.ID, u.Subject, "new@test.com", "code", time.Now().Add(-time.Hour)))
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "email"=$1,"email_change_code"=$2,"email_change_sent_at"=$3,"pending_email"=$4,"updated_at"=$5 WHERE email_change_code = $6`)).
+			WithArgs("new@test.com", nil, nil, nil, sqlmock.AnyArg(), "code").
+			WillReturnError(fmt.Errorf(`duplicate key value violates unique constraint "users_email_key"`))
+		mock.ExpectRollback()
+
+		var resp ConfirmEmailChangeResp
+		err := c.Post(`
+			mutation ConfirmEmailChange {
+				confirmEmailChange(token: "code")
+			}`, &resp)
+		require.EqualError(t, err, `[{"message":"could not change email","path":["confirmEmailChange"],"extensions":{"code":"INTERNAL"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
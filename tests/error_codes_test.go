@@ -0,0 +1,84 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestErrorCodes(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+
+	t.Run("Missing Row Is Not Found", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "quick_phrases" WHERE id = $1`)).
+			WithArgs("404").
+			WillReturnError(gorm.ErrRecordNotFound)
+
+		var resp struct{}
+		err := c.Post(`
+			mutation UpdateQuickPhrase {
+				updateQuickPhrase(quickPhraseId: "404", text: "slow eccentric") {
+					id
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Updating Quick Phrase","path":["updateQuickPhrase"],"extensions":{"code":"NOT_FOUND"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Unverified User Is Forbidden", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, false)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		var resp struct{}
+		err := c.Post(`query QuickPhrases { quickPhrases { id } }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"user not verified","path":["quickPhrases"],"extensions":{"code":"FORBIDDEN"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Every Presented Error Has A Code", func(t *testing.T) {
+		ctx := context.Background()
+		require.Equal(t, "INVALID_INPUT", errors.Present(ctx, errors.InvalidInput("bad")).Extensions["code"])
+		require.Equal(t, "NOT_FOUND", errors.Present(ctx, fmt.Errorf("loading: %w", gorm.ErrRecordNotFound)).Extensions["code"])
+		require.Equal(t, "INTERNAL", errors.Present(ctx, fmt.Errorf("dataloader blew up")).Extensions["code"])
+
+		// wrapping keeps the code of the error underneath
+		wrapped := errors.From(errors.Forbidden("Access Denied"), "Error Adding Exercise: %s", "Access Denied")
+		require.Equal(t, errors.ForbiddenCode, wrapped.Code)
+		require.Equal(t, "Error Adding Exercise: Access Denied", wrapped.Message)
+	})
+}
@@ -75,20 +75,39 @@ func TestExerciseResolvers(t *testing.T) {
 			NewRows([]string{"id", "user_id", "start", "end", "workout_routine_id", "created_at", "deleted_at", "updated_at"}).
 			AddRow(ws.ID, ws.UserID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.CreatedAt, ws.DeletedAt, ws.UpdatedAt)
 		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(workoutSessionRow)
+		helpers.ExpectEditableWorkoutSession(mock, ws.ID)
+
+		workoutSessionRow = sqlmock.
+			NewRows([]string{"id", "user_id", "start", "end", "workout_routine_id", "created_at", "deleted_at", "updated_at"}).
+			AddRow(ws.ID, ws.UserID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.CreatedAt, ws.DeletedAt, ws.UpdatedAt)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(workoutSessionRow)
+
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.ExerciseRoutineCountQuery)).
+			WithArgs(utils.UIntToString(ws.WorkoutRoutineID), utils.UIntToString(e.ExerciseRoutineID)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
 
 		mock.ExpectBegin()
 
-		const createExerciseStmnt = `INSERT INTO "exercises" ("created_at","updated_at","deleted_at","notes","exercise_routine_id","workout_session_id") VALUES ($1,$2,$3,$4,$5,$6) RETURNING "id"`
-		mock.ExpectQuery(regexp.QuoteMeta(createExerciseStmnt)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), e.Notes, e.WorkoutSessionID, e.ExerciseRoutineID).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(e.ID))
+		const createExerciseStmnt = `INSERT INTO "exercises" ("created_at","updated_at","deleted_at","notes","exercise_routine_id","workout_session_id","user_id") VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING "id"`
+		mock.ExpectQuery(regexp.QuoteMeta(createExerciseStmnt)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), e.Notes, e.WorkoutSessionID, e.ExerciseRoutineID, u.ID).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(e.ID))
 
-		const creatSetStmnt = `INSERT INTO "set_entries" ("created_at","updated_at","deleted_at","weight","reps","exercise_id") VALUES ($1,$2,$3,$4,$5,$6) ON CONFLICT ("id") DO UPDATE SET "exercise_id"="excluded"."exercise_id" RETURNING "id"`
+		const creatSetStmnt = `INSERT INTO "set_entries" ("created_at","updated_at","deleted_at","weight","unit","reps","exercise_id","set_order","type","rest_time_seconds","completed_at","client_metadata","origin_platform","origin_app_version","origin_device_id") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15) RETURNING "id"`
 		mock.ExpectQuery(regexp.QuoteMeta(creatSetStmnt)).WithArgs(
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
 			e.Sets[0].Weight,
+			"LB",
 			e.Sets[0].Reps,
-			e.Sets[0].ExerciseID).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(e.Sets[0].ID))
+			e.Sets[0].ExerciseID,
+			1,
+			"WORKING",
+			nil,
+			nil,
+			nil,
+			"",
+			"",
+			"").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(e.Sets[0].ID))
 
 		mock.ExpectCommit()
 
@@ -167,7 +186,7 @@ func TestExerciseResolvers(t *testing.T) {
 			1233,
 		)
 		err = c.Post(gqlMutation, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
-		require.EqualError(t, err, "[{\"message\":\"Error Adding Exercise: Access Denied\",\"path\":[\"addExercise\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Adding Exercise: Access Denied\",\"path\":[\"addExercise\"],\"extensions\":{\"code\":\"FORBIDDEN\"}}]")
 	})
 
 	t.Run("Get Exercise Success", func(t *testing.T) {
@@ -269,7 +288,7 @@ func TestExerciseResolvers(t *testing.T) {
 			exerciseId,
 		)
 		err = c.Post(gqlQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
-		require.EqualError(t, err, "[{\"message\":\"Error Getting Exercise: Access Denied\",\"path\":[\"exercise\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Getting Exercise: Access Denied\",\"path\":[\"exercise\"],\"extensions\":{\"code\":\"FORBIDDEN\"}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -297,6 +316,7 @@ func TestExerciseResolvers(t *testing.T) {
 			AddRow(ws.ID, ws.UserID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.CreatedAt, ws.DeletedAt, ws.UpdatedAt)
 		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(workoutSessionRow)
 
+		helpers.ExpectEditableWorkoutSession(mock, ws.ID)
 		mock.ExpectBegin()
 		updateExerciseStmt := `UPDATE "exercises" SET "updated_at"=$1,"notes"=$2 WHERE id = $3 AND "exercises"."deleted_at" IS NULL RETURNING *`
 		mock.ExpectQuery(regexp.QuoteMeta(updateExerciseStmt)).
@@ -379,7 +399,7 @@ func TestExerciseResolvers(t *testing.T) {
 			updatedNote,
 		)
 		err := c.Post(gqlQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
-		require.EqualError(t, err, "[{\"message\":\"Error Updating Exercise: Access Denied\",\"path\":[\"updateExercise\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Updating Exercise: Access Denied\",\"path\":[\"updateExercise\"],\"extensions\":{\"code\":\"FORBIDDEN\"}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -407,6 +427,7 @@ func TestExerciseResolvers(t *testing.T) {
 			AddRow(ws.ID, ws.UserID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.CreatedAt, ws.DeletedAt, ws.UpdatedAt)
 		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(workoutSessionRow)
 
+		helpers.ExpectEditableWorkoutSession(mock, ws.ID)
 		mock.ExpectBegin()
 		updateExerciseStmt := `UPDATE "exercises" SET "updated_at"=$1,"notes"=$2 WHERE id = $3 AND "exercises"."deleted_at" IS NULL RETURNING *`
 		mock.ExpectQuery(regexp.QuoteMeta(updateExerciseStmt)).
@@ -426,7 +447,7 @@ func TestExerciseResolvers(t *testing.T) {
 			updatedNote,
 		)
 		err := c.Post(gqlQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
-		require.EqualError(t, err, "[{\"message\":\"Error Updating Exercise\",\"path\":[\"updateExercise\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Updating Exercise\",\"path\":[\"updateExercise\"],\"extensions\":{\"code\":\"INTERNAL\"}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -452,6 +473,7 @@ func TestExerciseResolvers(t *testing.T) {
 			AddRow(ws.ID, ws.UserID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.CreatedAt, ws.DeletedAt, ws.UpdatedAt)
 		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(workoutSessionRow)
 
+		helpers.ExpectEditableWorkoutSession(mock, ws.ID)
 		mock.ExpectBegin()
 		deleteExerciseQuery := `UPDATE "exercises" SET "deleted_at"=$1 WHERE id = $2 AND "exercises"."deleted_at" IS NULL`
 		mock.ExpectExec(regexp.QuoteMeta(deleteExerciseQuery)).
@@ -523,7 +545,7 @@ func TestExerciseResolvers(t *testing.T) {
 			e.ID,
 		)
 		err := c.Post(gqlQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
-		require.EqualError(t, err, "[{\"message\":\"Error Deleting Exercise: Access Denied\",\"path\":[\"deleteExercise\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Deleting Exercise: Access Denied\",\"path\":[\"deleteExercise\"],\"extensions\":{\"code\":\"FORBIDDEN\"}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -549,6 +571,7 @@ func TestExerciseResolvers(t *testing.T) {
 			AddRow(ws.ID, ws.UserID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.CreatedAt, ws.DeletedAt, ws.UpdatedAt)
 		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(workoutSessionRow)
 
+		helpers.ExpectEditableWorkoutSession(mock, ws.ID)
 		mock.ExpectBegin()
 		deleteExerciseQuery := `UPDATE "exercises" SET "deleted_at"=$1 WHERE id = $2 AND "exercises"."deleted_at" IS NULL`
 		mock.ExpectExec(regexp.QuoteMeta(deleteExerciseQuery)).
@@ -565,7 +588,7 @@ func TestExerciseResolvers(t *testing.T) {
 			e.ID,
 		)
 		err := c.Post(gqlQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
-		require.EqualError(t, err, "[{\"message\":\"Error Deleting Exercise\",\"path\":[\"deleteExercise\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Deleting Exercise\",\"path\":[\"deleteExercise\"],\"extensions\":{\"code\":\"INTERNAL\"}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -591,6 +614,7 @@ func TestExerciseResolvers(t *testing.T) {
 			AddRow(ws.ID, ws.UserID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.CreatedAt, ws.DeletedAt, ws.UpdatedAt)
 		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(workoutSessionRow)
 
+		helpers.ExpectEditableWorkoutSession(mock, ws.ID)
 		mock.ExpectBegin()
 		deleteExerciseQuery := `UPDATE "exercises" SET "deleted_at"=$1 WHERE id = $2 AND "exercises"."deleted_at" IS NULL`
 		mock.ExpectExec(regexp.QuoteMeta(deleteExerciseQuery)).
@@ -611,7 +635,7 @@ func TestExerciseResolvers(t *testing.T) {
 			e.ID,
 		)
 		err := c.Post(gqlQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
-		require.EqualError(t, err, "[{\"message\":\"Error Deleting Exercise\",\"path\":[\"deleteExercise\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Deleting Exercise\",\"path\":[\"deleteExercise\"],\"extensions\":{\"code\":\"INTERNAL\"}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -0,0 +1,144 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type SearchExerciseCatalogResp struct {
+	SearchExerciseCatalog []struct {
+		ID           string
+		Name         string
+		MuscleGroups []string
+		Equipment    string
+	}
+}
+
+type ExerciseRoutinesCatalogResp struct {
+	ExerciseRoutines []struct {
+		ID              string
+		CatalogExercise *struct {
+			ID           string
+			Name         string
+			MuscleGroups []string
+		}
+	}
+}
+
+func TestExerciseCatalogResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	wr := testdata.WorkoutRoutine
+	er0 := wr.ExerciseRoutines[0]
+	er1 := wr.ExerciseRoutines[1]
+	now := time.Now()
+	catalogExerciseColumns := []string{"id", "name", "equipment", "instructions", "created_at", "updated_at"}
+	muscleGroupColumns := []string{"id", "catalog_exercise_id", "muscle_group", "is_primary"}
+
+	t.Run("Search Exercise Catalog", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		const searchQuery = `SELECT * FROM "catalog_exercises" WHERE name ILIKE $1 AND (EXISTS (SELECT 1 FROM catalog_exercise_muscle_groups WHERE catalog_exercise_muscle_groups.catalog_exercise_id = catalog_exercises.id AND catalog_exercise_muscle_groups.muscle_group = $2 AND catalog_exercise_muscle_groups.deleted_at IS NULL)) AND "catalog_exercises"."deleted_at" IS NULL ORDER BY name LIMIT 50`
+		catalogExerciseRows := sqlmock.NewRows(catalogExerciseColumns).
+			AddRow(4, "Squat", "Barbell", "Sit down and stand up.", now, now)
+		mock.ExpectQuery(regexp.QuoteMeta(searchQuery)).WithArgs("%squat%", "QUADS").WillReturnRows(catalogExerciseRows)
+
+		muscleGroupRows := sqlmock.NewRows(muscleGroupColumns).
+			AddRow(10, 4, "QUADS", true).
+			AddRow(11, 4, "GLUTES", false)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "catalog_exercise_muscle_groups" WHERE "catalog_exercise_muscle_groups"."catalog_exercise_id" = $1 AND "catalog_exercise_muscle_groups"."deleted_at" IS NULL ORDER BY id`)).
+			WithArgs(4).
+			WillReturnRows(muscleGroupRows)
+
+		var resp SearchExerciseCatalogResp
+		c.MustPost(`
+			query SearchExerciseCatalog {
+				searchExerciseCatalog(query: "squat", muscleGroup: QUADS) {
+					id
+					name
+					muscleGroups
+					equipment
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Len(t, resp.SearchExerciseCatalog, 1)
+		require.Equal(t, "Squat", resp.SearchExerciseCatalog[0].Name)
+		require.Equal(t, []string{"QUADS", "GLUTES"}, resp.SearchExerciseCatalog[0].MuscleGroups)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Exercise Routines With Catalog Exercise", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		workoutRoutineRow := sqlmock.NewRows([]string{"id", "name", "created_at", "deleted_at", "updated_at", "user_id", "active"}).
+			AddRow(wr.ID, wr.Name, wr.CreatedAt, nil, wr.UpdatedAt, wr.UserID, wr.Active)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).WithArgs(fmt.Sprintf("%d", wr.ID)).WillReturnRows(workoutRoutineRow)
+
+		exerciseRoutineRows := sqlmock.NewRows([]string{"id", "name", "sets", "reps", "active", "workout_routine_id", "position", "catalog_exercise_id"}).
+			AddRow(er0.ID, er0.Name, er0.Sets, er0.Reps, true, wr.ID, 0, 4).
+			AddRow(er1.ID, er1.Name, er1.Sets, er1.Reps, true, wr.ID, 1, nil)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "exercise_routines" WHERE workout_routine_id = $1`)).WithArgs(fmt.Sprintf("%d", wr.ID)).WillReturnRows(exerciseRoutineRows)
+
+		// only the first routine references the catalog
+		referenceRows := sqlmock.NewRows([]string{"exercise_routine_id", "catalog_exercise_id"}).AddRow(er0.ID, 4)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT id AS exercise_routine_id, catalog_exercise_id FROM "exercise_routines" WHERE (id IN ($1,$2) AND catalog_exercise_id IS NOT NULL)`)).
+			WillReturnRows(referenceRows)
+		catalogExerciseRows := sqlmock.NewRows(catalogExerciseColumns).AddRow(4, "Squat", "Barbell", "Sit down and stand up.", now, now)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "catalog_exercises" WHERE id IN ($1)`)).WithArgs("4").WillReturnRows(catalogExerciseRows)
+		muscleGroupRows := sqlmock.NewRows(muscleGroupColumns).AddRow(10, 4, "QUADS", true)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "catalog_exercise_muscle_groups" WHERE "catalog_exercise_muscle_groups"."catalog_exercise_id" = $1`)).
+			WithArgs(4).
+			WillReturnRows(muscleGroupRows)
+
+		var resp ExerciseRoutinesCatalogResp
+		query := fmt.Sprintf(`
+			query ExerciseRoutines {
+				exerciseRoutines(workoutRoutineId: "%d") {
+					id
+					catalogExercise {
+						id
+						name
+						muscleGroups
+					}
+				}
+			}`, wr.ID)
+		c.MustPost(query, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Len(t, resp.ExerciseRoutines, 2)
+		require.Equal(t, "Squat", resp.ExerciseRoutines[0].CatalogExercise.Name)
+		require.Equal(t, []string{"QUADS"}, resp.ExerciseRoutines[0].CatalogExercise.MuscleGroups)
+		require.Nil(t, resp.ExerciseRoutines[1].CatalogExercise)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -64,10 +64,13 @@ func TestExerciseRoutineResolvers(t *testing.T) {
 			AddRow(wr.ID, wr.Name, wr.CreatedAt, wr.DeletedAt, wr.UpdatedAt, wr.UserID, wr.Active)
 		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).WithArgs(fmt.Sprintf("%d", wr.ID)).WillReturnRows(workoutRoutineRow)
 
+		const nextPositionQuery = `SELECT COALESCE(MAX(position) + 1, 0) FROM "exercise_routines" WHERE workout_routine_id = $1 AND "exercise_routines"."deleted_at" IS NULL`
+		mock.ExpectQuery(regexp.QuoteMeta(nextPositionQuery)).WithArgs(er.WorkoutRoutineID).WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(2))
+
 		mock.ExpectBegin()
-		createExerciseRoutineStmt := `INSERT INTO "exercise_routines" ("created_at","updated_at","deleted_at","name","sets","reps","active","workout_routine_id") VALUES ($1,$2,$3,$4,$5,$6,$7,$8) RETURNING "id"`
+		createExerciseRoutineStmt := `INSERT INTO "exercise_routines" ("created_at","updated_at","deleted_at","name","sets","reps","active","workout_routine_id","position","catalog_exercise_id") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10) RETURNING "id"`
 		mock.ExpectQuery(regexp.QuoteMeta(createExerciseRoutineStmt)).
-			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), er.Name, er.Sets, er.Reps, er.Active, er.WorkoutRoutineID).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), er.Name, er.Sets, er.Reps, er.Active, er.WorkoutRoutineID, 2, nil).
 			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(er.ID))
 		mock.ExpectCommit()
 
@@ -137,7 +140,7 @@ func TestExerciseRoutineResolvers(t *testing.T) {
 			er.WorkoutRoutineID, er.Sets, er.Reps, er.Name,
 		)
 		err := c.Post(mutation, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
-		require.EqualError(t, err, "[{\"message\":\"Error Adding Exercise Routine: Access Denied\",\"path\":[\"addExerciseRoutine\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Adding Exercise Routine: Access Denied\",\"path\":[\"addExerciseRoutine\"],\"extensions\":{\"code\":\"FORBIDDEN\"}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -231,7 +234,7 @@ func TestExerciseRoutineResolvers(t *testing.T) {
 			er.WorkoutRoutineID,
 		)
 		err := c.Post(query, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
-		require.EqualError(t, err, "[{\"message\":\"Error Getting Exercise Routine\",\"path\":[\"exerciseRoutines\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Getting Exercise Routine\",\"path\":[\"exerciseRoutines\"],\"extensions\":{\"code\":\"INTERNAL\"}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -338,7 +341,7 @@ func TestExerciseRoutineResolvers(t *testing.T) {
 			er.ID,
 		)
 		err := c.Post(gqlQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
-		require.EqualError(t, err, "[{\"message\":\"Error Deleting Exercise Routine: Access Denied\",\"path\":[\"deleteExerciseRoutine\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Deleting Exercise Routine: Access Denied\",\"path\":[\"deleteExerciseRoutine\"],\"extensions\":{\"code\":\"FORBIDDEN\"}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -387,7 +390,7 @@ func TestExerciseRoutineResolvers(t *testing.T) {
 			er.ID,
 		)
 		err := c.Post(gqlQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
-		require.EqualError(t, err, "[{\"message\":\"Error Deleting Exercise Routine\",\"path\":[\"deleteExerciseRoutine\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Deleting Exercise Routine\",\"path\":[\"deleteExerciseRoutine\"],\"extensions\":{\"code\":\"INTERNAL\"}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -0,0 +1,200 @@
+package test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/media"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type AddVideoAnnotationResp struct {
+	AddVideoAnnotation struct {
+		ID          string
+		CoachId     string
+		TimestampMs int
+		Note        string
+	}
+}
+
+func TestExerciseVideoResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	coach := *testdata.User
+	coach.ID = 77
+
+	const addVideoAnnotationMutation = `
+		mutation AddVideoAnnotation {
+			addVideoAnnotation(exerciseVideoId: "4", timestampMs: 1500, note: "knees caving in") {
+				id
+				coachId
+				timestampMs
+				note
+			}
+		}`
+	const exerciseVideoQuery = `SELECT * FROM "exercise_videos" WHERE id = $1 AND "exercise_videos"."deleted_at" IS NULL ORDER BY "exercise_videos"."id" LIMIT 1`
+	const coachQuery = `SELECT * FROM "coaches" WHERE (user_id = $1 AND coach_id = $2) AND "coaches"."deleted_at" IS NULL ORDER BY "coaches"."id" LIMIT 1`
+
+	t.Run("Add Video Annotation Success", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(coach.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", coach.ID)).WillReturnRows(userRow)
+
+		videoRow := sqlmock.NewRows([]string{"id", "exercise_id", "user_id", "storage_key", "content_type", "created_at"}).
+			AddRow(4, 1, u.ID, "videos/28/abc.mp4", "video/mp4", time.Now())
+		mock.ExpectQuery(regexp.QuoteMeta(exerciseVideoQuery)).WithArgs("4").WillReturnRows(videoRow)
+
+		coachRow := sqlmock.NewRows([]string{"id", "user_id", "coach_id"}).AddRow(1, u.ID, coach.ID)
+		mock.ExpectQuery(regexp.QuoteMeta(coachQuery)).WithArgs(fmt.Sprintf("%d", u.ID), fmt.Sprintf("%d", coach.ID)).WillReturnRows(coachRow)
+
+		mock.ExpectBegin()
+		const addAnnotationStmt = `INSERT INTO "video_annotations" ("created_at","updated_at","deleted_at","exercise_video_id","coach_id","timestamp_ms","note") VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING "id"`
+		mock.ExpectQuery(regexp.QuoteMeta(addAnnotationStmt)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, 4, coach.ID, 1500, "knees caving in").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+		mock.ExpectCommit()
+
+		var resp AddVideoAnnotationResp
+		c.MustPost(addVideoAnnotationMutation, &resp, helpers.AddContext(&coach, helpers.NewLoaders(gormDB)))
+		require.Equal(t, "9", resp.AddVideoAnnotation.ID)
+		require.Equal(t, fmt.Sprintf("%d", coach.ID), resp.AddVideoAnnotation.CoachId)
+		require.Equal(t, 1500, resp.AddVideoAnnotation.TimestampMs)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Add Video Annotation Not A Coach", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(coach.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", coach.ID)).WillReturnRows(userRow)
+
+		videoRow := sqlmock.NewRows([]string{"id", "exercise_id", "user_id", "storage_key", "content_type", "created_at"}).
+			AddRow(4, 1, u.ID, "videos/28/abc.mp4", "video/mp4", time.Now())
+		mock.ExpectQuery(regexp.QuoteMeta(exerciseVideoQuery)).WithArgs("4").WillReturnRows(videoRow)
+
+		mock.ExpectQuery(regexp.QuoteMeta(coachQuery)).WithArgs(fmt.Sprintf("%d", u.ID), fmt.Sprintf("%d", coach.ID)).WillReturnError(gorm.ErrRecordNotFound)
+
+		var resp AddVideoAnnotationResp
+		err := c.Post(addVideoAnnotationMutation, &resp, helpers.AddContext(&coach, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, "[{\"message\":\"Error Adding Annotation: Access Denied\",\"path\":[\"addVideoAnnotation\"],\"extensions\":{\"code\":\"FORBIDDEN\"}}]")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
+
+// not parallel since the media dir and signing secret come from the
+// environment
+func TestExerciseVideoURLs(t *testing.T) {
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	dir := t.TempDir()
+	t.Setenv(config.HOST, "")
+	t.Setenv(config.MEDIA_DIR, dir)
+	t.Setenv(config.MEDIA_SIGNING_SECRET, "media-secret")
+
+	key := fmt.Sprintf("videos/%d/abc.mp4", u.ID)
+	require.Nil(t, os.MkdirAll(filepath.Join(dir, "videos", fmt.Sprintf("%d", u.ID)), 0755))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, filepath.FromSlash(key)), []byte("not really a video"), 0644))
+
+	store := media.NewSignedLocalStore(dir, "", "/media", "media-secret")
+	mediaServer := httptest.NewServer(&media.SignedURLHandler{Store: store})
+	defer mediaServer.Close()
+
+	get := func(url string, header http.Header) (*http.Response, string) {
+		req, err := http.NewRequest(http.MethodGet, mediaServer.URL+url, nil)
+		require.Nil(t, err)
+		for k := range header {
+			req.Header.Set(k, header.Get(k))
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		require.Nil(t, err)
+		return resp, string(body)
+	}
+
+	t.Run("Videos Come With A Signed URL", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "exercises" WHERE`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(1, u.ID))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "exercise_videos" WHERE exercise_id = $1 AND "exercise_videos"."deleted_at" IS NULL ORDER BY id`)).
+			WithArgs("1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "exercise_id", "user_id", "storage_key", "content_type", "created_at"}).
+				AddRow(4, 1, u.ID, key, "video/mp4", time.Now()))
+
+		var resp struct {
+			ExerciseVideos []struct {
+				ID  string
+				Url string
+			}
+		}
+		c.MustPost(`query { exerciseVideos(exerciseId: "1") { id url } }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Len(t, resp.ExerciseVideos, 1)
+		url := resp.ExerciseVideos[0].Url
+		require.True(t, strings.HasPrefix(url, "/media?"))
+
+		r, body := get(url, nil)
+		require.Equal(t, http.StatusOK, r.StatusCode)
+		require.Equal(t, "not really a video", body)
+
+		// players seek with ranges
+		r, body = get(url, http.Header{"Range": {"bytes=0-2"}})
+		require.Equal(t, http.StatusPartialContent, r.StatusCode)
+		require.Equal(t, "not", body)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Unsigned Requests Get Nothing", func(t *testing.T) {
+		r, _ := get("/media?key="+key, nil)
+		require.Equal(t, http.StatusForbidden, r.StatusCode)
+		r, _ = get("/media/"+key, nil)
+		require.Equal(t, http.StatusForbidden, r.StatusCode)
+	})
+}
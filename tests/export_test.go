@@ -0,0 +1,81 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/export"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSVExport(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+
+	t.Run("Export Workout History", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		start := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+		end := start.Add(time.Hour)
+		historyRows := sqlmock.NewRows([]string{
+			"workout_session_id", "start", "end", "workout_routine", "exercise_id", "exercise", "notes",
+			"set_order", "set_type", "weight", "unit", "reps", "rest_time_seconds", "completed_at",
+		}).
+			AddRow(1, start, end, "Legs", 2, "Squat", "felt heavy, go lighter", 1, "WARMUP", 135, "LB", 10, nil, nil).
+			AddRow(1, start, end, "Legs", 2, "Squat", "felt heavy, go lighter", 2, "WORKING", 227.5, "LB", 5, 180, end).
+			AddRow(3, start.AddDate(0, 0, 2), nil, "Push", nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT workout_sessions.id AS workout_session_id")).
+			WithArgs(fmt.Sprintf("%d", u.ID), fmt.Sprintf("%d", u.ID)).
+			WillReturnRows(historyRows)
+
+		req := httptest.NewRequest(http.MethodGet, "/export/csv", nil)
+		req = req.WithContext(context.WithValue(req.Context(), middleware.UserCtxKey, u))
+		rec := httptest.NewRecorder()
+		(&export.CSVHandler{DB: gormDB}).ServeHTTP(rec, req)
+
+		require.Equal(t, http.StatusOK, rec.Code)
+		require.Equal(t, "text/csv", rec.Header().Get("Content-Type"))
+		require.Equal(t, "workout_session_id,start,end,workout_routine,exercise_id,exercise,notes,set_order,set_type,weight,unit,reps,rest_time_seconds,completed_at\n"+
+			"1,2022-10-01T12:00:00Z,2022-10-01T13:00:00Z,Legs,2,Squat,\"felt heavy, go lighter\",1,WARMUP,135,LB,10,,\n"+
+			"1,2022-10-01T12:00:00Z,2022-10-01T13:00:00Z,Legs,2,Squat,\"felt heavy, go lighter\",2,WORKING,227.5,LB,5,180,2022-10-01T13:00:00Z\n"+
+			"3,2022-10-03T12:00:00Z,,Push,,,,,,,,,,\n", rec.Body.String())
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Export Without Token", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+
+		req := httptest.NewRequest(http.MethodGet, "/export/csv", nil)
+		rec := httptest.NewRecorder()
+		(&export.CSVHandler{DB: gormDB}).ServeHTTP(rec, req)
+		require.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
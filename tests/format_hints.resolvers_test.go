@@ -0,0 +1,156 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type FormatHints struct {
+	Locale            string
+	DecimalSeparator  string
+	GroupingSeparator string
+	WeightUnit        string
+	WeightUnitLabel   string
+	WeightDecimals    int
+	VolumeDecimals    int
+}
+
+type GetFormatHintsResp struct {
+	User struct {
+		Locale      string
+		WeightUnit  *string
+		FormatHints FormatHints
+	}
+}
+
+type UpdateFormatPreferencesResp struct {
+	UpdateFormatPreferences struct {
+		Locale      string
+		WeightUnit  *string
+		FormatHints FormatHints
+	}
+}
+
+func TestFormatHintsResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+
+	const formatHintsFields = `
+		formatHints {
+			locale
+			decimalSeparator
+			groupingSeparator
+			weightUnit
+			weightUnitLabel
+			weightDecimals
+			volumeDecimals
+		}`
+
+	t.Run("Format Hints Follow Locale", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		profileRow := sqlmock.NewRows([]string{"id", "name", "email", "locale", "weight_unit"}).AddRow(u.ID, u.Name, u.Subject, "de-DE", nil)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE id = $1`)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(profileRow)
+
+		var resp GetFormatHintsResp
+		c.MustPost(`query User { user { locale weightUnit `+formatHintsFields+` } }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+
+		require.Equal(t, "de-DE", resp.User.Locale)
+		require.Nil(t, resp.User.WeightUnit)
+		require.Equal(t, FormatHints{
+			Locale:            "de-DE",
+			DecimalSeparator:  ",",
+			GroupingSeparator: ".",
+			WeightUnit:        "KG",
+			WeightUnitLabel:   "kg",
+			WeightDecimals:    2,
+			VolumeDecimals:    0,
+		}, resp.User.FormatHints)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Update Format Preferences", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "locale"=$1,"weight_unit"=$2,"updated_at"=$3 WHERE id = $4 AND "users"."deleted_at" IS NULL`)).
+			WithArgs("en-GB", "LB", sqlmock.AnyArg(), fmt.Sprintf("%d", u.ID)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		profileRow := sqlmock.NewRows([]string{"id", "name", "email", "locale", "weight_unit"}).AddRow(u.ID, u.Name, u.Subject, "en-GB", "LB")
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE id = $1`)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(profileRow)
+
+		// en-GB would be kilograms, the picked unit wins
+		var resp UpdateFormatPreferencesResp
+		c.MustPost(`
+			mutation UpdateFormatPreferences {
+				updateFormatPreferences(locale: "en_gb", weightUnit: LB) {
+					locale
+					weightUnit
+					`+formatHintsFields+`
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+
+		require.Equal(t, "en-GB", resp.UpdateFormatPreferences.Locale)
+		require.Equal(t, "LB", *resp.UpdateFormatPreferences.WeightUnit)
+		require.Equal(t, ".", resp.UpdateFormatPreferences.FormatHints.DecimalSeparator)
+		require.Equal(t, "lb", resp.UpdateFormatPreferences.FormatHints.WeightUnitLabel)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Update Format Preferences Invalid Locale", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		var resp UpdateFormatPreferencesResp
+		err := c.Post(`
+			mutation UpdateFormatPreferences {
+				updateFormatPreferences(locale: "english please") {
+					locale
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"locale needs to be a language with an optional region like en-US","path":["updateFormatPreferences"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,277 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/goal"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type CreateGoalResp struct {
+	CreateGoal struct {
+		ID           string
+		Type         string
+		TargetValue  float64
+		StartValue   *float64
+		CurrentValue *float64
+		Progress     float64
+		Status       string
+	}
+}
+
+type GoalsResp struct {
+	Goals []struct {
+		ID                string
+		Type              string
+		CatalogExerciseId *string
+		CurrentValue      *float64
+		Progress          float64
+		Status            string
+	}
+}
+
+func TestGoalResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	deadline := time.Now().Add(30 * 24 * time.Hour).UTC().Truncate(time.Second)
+	goalColumns := []string{"id", "user_id", "type", "target_value", "start_value", "unit", "catalog_exercise_id", "deadline", "completed_at", "created_at", "updated_at"}
+	const latestBodyWeightQuery = `SELECT "weight" FROM "body_weight_entries" WHERE user_id = $1 AND "body_weight_entries"."deleted_at" IS NULL ORDER BY logged_at desc, id desc LIMIT 1`
+	const bestE1RMQuery = `SELECT MAX(CASE WHEN set_entries.reps = 1 THEN (CASE WHEN set_entries.unit = 'KG' THEN set_entries.weight * 2.20462262185 ELSE set_entries.weight END) ELSE`
+
+	t.Run("Create Body Weight Goal", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		mock.ExpectQuery(regexp.QuoteMeta(latestBodyWeightQuery)).
+			WithArgs(fmt.Sprintf("%d", u.ID)).
+			WillReturnRows(sqlmock.NewRows([]string{"weight"}).AddRow(200))
+
+		mock.ExpectBegin()
+		const addGoalStmt = `INSERT INTO "goals" ("created_at","updated_at","deleted_at","user_id","type","target_value","start_value","unit","catalog_exercise_id","deadline","completed_at") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11) RETURNING "id"`
+		mock.ExpectQuery(regexp.QuoteMeta(addGoalStmt)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, u.ID, database.GoalTypeBodyWeight, float32(180), float32(200), "LB", nil, deadline, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectCommit()
+
+		var resp CreateGoalResp
+		c.MustPost(fmt.Sprintf(`
+			mutation CreateGoal {
+				createGoal(goal: { type: BODY_WEIGHT, targetValue: 180, deadline: "%s" }) {
+					id
+					type
+					targetValue
+					startValue
+					currentValue
+					progress
+					status
+				}
+			}`, deadline.Format(time.RFC3339)), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, "3", resp.CreateGoal.ID)
+		require.Equal(t, "BODY_WEIGHT", resp.CreateGoal.Type)
+		require.Equal(t, float64(200), *resp.CreateGoal.StartValue)
+		require.Equal(t, float64(0), resp.CreateGoal.Progress)
+		require.Equal(t, goal.StatusInProgress, resp.CreateGoal.Status)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Create Body Weight Goal Without Weigh In", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		mock.ExpectQuery(regexp.QuoteMeta(latestBodyWeightQuery)).
+			WithArgs(fmt.Sprintf("%d", u.ID)).
+			WillReturnRows(sqlmock.NewRows([]string{"weight"}))
+
+		var resp CreateGoalResp
+		err := c.Post(fmt.Sprintf(`
+			mutation CreateGoal {
+				createGoal(goal: { type: BODY_WEIGHT, targetValue: 180, deadline: "%s" }) {
+					id
+				}
+			}`, deadline.Format(time.RFC3339)), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Creating Goal: log your body weight first","path":["createGoal"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Create Lift Goal Without Catalog Exercise", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		var resp CreateGoalResp
+		err := c.Post(fmt.Sprintf(`
+			mutation CreateGoal {
+				createGoal(goal: { type: LIFT_E1RM, targetValue: 300, deadline: "%s" }) {
+					id
+				}
+			}`, deadline.Format(time.RFC3339)), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Creating Goal: lift goals need a catalog exercise","path":["createGoal"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Get Goals", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		now := time.Now()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "goals" WHERE user_id = $1 AND "goals"."deleted_at" IS NULL ORDER BY deadline, id`)).
+			WithArgs(fmt.Sprintf("%d", u.ID)).
+			WillReturnRows(sqlmock.NewRows(goalColumns).
+				AddRow(3, u.ID, database.GoalTypeBodyWeight, 180, 200, "LB", nil, deadline, nil, now, now).
+				AddRow(4, u.ID, database.GoalTypeLiftE1RM, 300, 250, "LB", 1, deadline, nil, now, now))
+
+		mock.ExpectQuery(regexp.QuoteMeta(latestBodyWeightQuery)).
+			WithArgs(fmt.Sprintf("%d", u.ID)).
+			WillReturnRows(sqlmock.NewRows([]string{"weight"}).AddRow(190))
+		mock.ExpectQuery(regexp.QuoteMeta(bestE1RMQuery)).
+			WithArgs(fmt.Sprintf("%d", u.ID), 1).
+			WillReturnRows(sqlmock.NewRows([]string{"e1rm"}).AddRow(300))
+
+		var resp GoalsResp
+		c.MustPost(`
+			query Goals {
+				goals {
+					id
+					type
+					catalogExerciseId
+					currentValue
+					progress
+					status
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Len(t, resp.Goals, 2)
+		require.Equal(t, 0.5, resp.Goals[0].Progress)
+		require.Equal(t, goal.StatusInProgress, resp.Goals[0].Status)
+		require.Equal(t, "1", *resp.Goals[1].CatalogExerciseId)
+		require.Equal(t, float64(1), resp.Goals[1].Progress)
+		require.Equal(t, goal.StatusCompleted, resp.Goals[1].Status)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Update Goal Access Denied", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		now := time.Now()
+		incorrectUserId := 66
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "goals" WHERE id = $1 AND "goals"."deleted_at" IS NULL ORDER BY "goals"."id" LIMIT 1`)).
+			WithArgs("3").
+			WillReturnRows(sqlmock.NewRows(goalColumns).AddRow(3, incorrectUserId, database.GoalTypeBodyWeight, 180, 200, "LB", nil, deadline, nil, now, now))
+
+		var resp struct{ UpdateGoal struct{ ID string } }
+		err := c.Post(`
+			mutation UpdateGoal {
+				updateGoal(goalId: "3", goal: { targetValue: 175 }) {
+					id
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Updating Goal: Access Denied","path":["updateGoal"],"extensions":{"code":"FORBIDDEN"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
+
+func TestGoalChecker(t *testing.T) {
+	t.Parallel()
+
+	u := testdata.User
+	now := time.Now()
+	deadline := now.Add(30 * 24 * time.Hour)
+	goalColumns := []string{"id", "user_id", "type", "target_value", "start_value", "unit", "catalog_exercise_id", "deadline", "completed_at", "created_at", "updated_at"}
+
+	mock, gormDB := helpers.SetupMockDB()
+	var notified []uint
+	checker := &goal.Checker{
+		DB:        gormDB,
+		BatchSize: 10,
+		Notify: func(user *database.User, g *database.Goal) error {
+			notified = append(notified, g.ID)
+			return nil
+		},
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "goals" WHERE id > $1 AND (completed_at IS NULL AND deadline > $2) AND "goals"."deleted_at" IS NULL ORDER BY id LIMIT 10`)).
+		WithArgs(0, now).
+		WillReturnRows(sqlmock.NewRows(goalColumns).
+			AddRow(3, u.ID, database.GoalTypeBodyWeight, 180, 200, "LB", nil, deadline, nil, now, now).
+			AddRow(4, u.ID, database.GoalTypeBodyWeight, 210, 200, "LB", nil, deadline, nil, now, now))
+
+	// down to 179 reaches the cut but not the bulk
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "weight" FROM "body_weight_entries"`)).
+		WithArgs(fmt.Sprintf("%d", u.ID)).
+		WillReturnRows(sqlmock.NewRows([]string{"weight"}).AddRow(179))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "goals" SET "completed_at"=$1,"updated_at"=$2 WHERE (id = $3 AND completed_at IS NULL) AND "goals"."deleted_at" IS NULL`)).
+		WithArgs(now, sqlmock.AnyArg(), 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE id = $1`)).
+		WithArgs(fmt.Sprintf("%d", u.ID)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email"}).AddRow(u.ID, u.Name, u.Subject))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "weight" FROM "body_weight_entries"`)).
+		WithArgs(fmt.Sprintf("%d", u.ID)).
+		WillReturnRows(sqlmock.NewRows([]string{"weight"}).AddRow(179))
+
+	completed, err := checker.CheckOpenGoals(now)
+	require.NoError(t, err)
+	require.Equal(t, 1, completed)
+	require.Equal(t, []uint{3}, notified)
+
+	err = mock.ExpectationsWereMet()
+	if err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,163 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/neilZon/workout-logger-api/health"
+	"github.com/neilZon/workout-logger-api/migrate"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type HealthResp struct {
+	Status string
+	Checks map[string]string
+}
+
+func TestHealth(t *testing.T) {
+	t.Parallel()
+
+	const migrationsTableQuery = `SELECT to_regclass('schema_migrations') IS NOT NULL`
+	const versionQuery = `SELECT version, dirty FROM schema_migrations LIMIT 1`
+
+	migrator, err := migrate.New(nil)
+	if err != nil {
+		panic(err)
+	}
+	migrations := migrator.Migrations()
+
+	// pings only go through the mock when they're monitored
+	setupDB := func() (sqlmock.Sqlmock, *gorm.DB) {
+		mockDb, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+		if err != nil {
+			panic(err)
+		}
+		mock.ExpectPing()
+		gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: mockDb}), &gorm.Config{})
+		if err != nil {
+			panic(err)
+		}
+		return mock, gormDB
+	}
+	latest := migrations[len(migrations)-1]
+	versionRow := func(version uint, dirty bool) *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"version", "dirty"}).AddRow(version, dirty)
+	}
+	probe := func(h http.Handler, path string) (int, HealthResp) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		var resp HealthResp
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			panic(err)
+		}
+		return rec.Code, resp
+	}
+
+	t.Run("Liveness", func(t *testing.T) {
+		code, resp := probe(&health.LivenessHandler{}, "/healthz")
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "ok", resp.Status)
+	})
+
+	t.Run("Ready", func(t *testing.T) {
+		mock, gormDB := setupDB()
+		h := &health.ReadinessHandler{DB: gormDB, Timeout: time.Second}
+
+		mock.ExpectPing()
+		mock.ExpectQuery(regexp.QuoteMeta(migrationsTableQuery)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(regexp.QuoteMeta(versionQuery)).WillReturnRows(versionRow(latest.Version, false))
+		code, resp := probe(h, "/readyz")
+		require.Equal(t, http.StatusOK, code)
+		require.Equal(t, "ok", resp.Status)
+
+		// migrations are only checked until they're seen applied
+		mock.ExpectPing()
+		code, _ = probe(h, "/readyz")
+		require.Equal(t, http.StatusOK, code)
+
+		err := mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Not Ready Without Migrations", func(t *testing.T) {
+		mock, gormDB := setupDB()
+		h := &health.ReadinessHandler{DB: gormDB, Timeout: time.Second}
+
+		mock.ExpectPing()
+		mock.ExpectQuery(regexp.QuoteMeta(migrationsTableQuery)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(regexp.QuoteMeta(versionQuery)).WillReturnRows(versionRow(migrations[len(migrations)-2].Version, false))
+		code, resp := probe(h, "/readyz")
+		require.Equal(t, http.StatusServiceUnavailable, code)
+		require.Equal(t, "unavailable", resp.Status)
+		require.Equal(t, "ok", resp.Checks["database"])
+		require.Equal(t, "pending: "+latest.String(), resp.Checks["migrations"])
+
+		err := mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Not Ready While The Latest Migration Is Dirty", func(t *testing.T) {
+		mock, gormDB := setupDB()
+		h := &health.ReadinessHandler{DB: gormDB, Timeout: time.Second}
+
+		mock.ExpectPing()
+		mock.ExpectQuery(regexp.QuoteMeta(migrationsTableQuery)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(regexp.QuoteMeta(versionQuery)).WillReturnRows(versionRow(latest.Version, true))
+		code, resp := probe(h, "/readyz")
+		require.Equal(t, http.StatusServiceUnavailable, code)
+		require.Equal(t, "pending: "+latest.String(), resp.Checks["migrations"])
+
+		err := mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Not Ready On A New Database", func(t *testing.T) {
+		mock, gormDB := setupDB()
+		h := &health.ReadinessHandler{DB: gormDB, Timeout: time.Second}
+
+		mock.ExpectPing()
+		mock.ExpectQuery(regexp.QuoteMeta(migrationsTableQuery)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		code, resp := probe(h, "/readyz")
+		require.Equal(t, http.StatusServiceUnavailable, code)
+		names := []string{}
+		for _, m := range migrations {
+			names = append(names, m.String())
+		}
+		require.Equal(t, "pending: "+strings.Join(names, ", "), resp.Checks["migrations"])
+
+		err := mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Not Ready Without Database", func(t *testing.T) {
+		mock, gormDB := setupDB()
+		h := &health.ReadinessHandler{DB: gormDB, Timeout: time.Second}
+
+		mock.ExpectPing().WillReturnError(fmt.Errorf("connection refused"))
+		code, resp := probe(h, "/readyz")
+		require.Equal(t, http.StatusServiceUnavailable, code)
+		require.Equal(t, "unreachable", resp.Checks["database"])
+
+		err := mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
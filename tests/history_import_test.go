@@ -0,0 +1,137 @@
+package test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/importer"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+const strongExport = `Date;Workout Name;Duration;Exercise Name;Set Order;Weight;Reps;Distance;Seconds;Notes;Workout Notes;RPE
+2022-10-30 12:00:00;Push;1h 5m;Bench Press (Barbell);W;60;10;0;0;;;
+2022-10-30 12:00:00;Push;1h 5m;Bench Press (Barbell);1;82,5;5;0;0;paused;;
+2022-10-30 12:00:00;Push;1h 5m;Bench Press (Barbell);Rest Timer;0;0;0;90;;;
+2022-10-30 12:00:00;Push;1h 5m;Bench Press (Dumbbell);1;30;8;0;0;;;
+2022-10-31 18:30:00;Legs;45m;Squat (Barbell);1;100;5;0;0;;;
+2022-10-31 18:30:00;Legs;45m;Treadmill;1;0;;1.5;600;;;
+`
+
+const hevyExport = `"title","start_time","end_time","description","exercise_title","superset_id","exercise_notes","set_index","set_type","weight_lbs","reps","distance_miles","duration_seconds","rpe"
+"Pull","30 Oct 2022, 12:00","30 Oct 2022, 13:00","","Lat Pulldown (Cable)","","","0","warmup","50","12","","",""
+"Pull","30 Oct 2022, 12:00","30 Oct 2022, 13:00","","Lat Pulldown (Cable)","","slow negatives","1","normal","100","10","","",""
+"Pull","30 Oct 2022, 12:00","30 Oct 2022, 13:00","","Lat Pulldown (Cable)","","","2","failure","100","7","","",""
+`
+
+func TestImportWorkoutHistory(t *testing.T) {
+	t.Parallel()
+
+	u := testdata.User
+	toronto, err := time.LoadLocation("America/Toronto")
+	require.Nil(t, err)
+
+	t.Run("Parse Strong Export", func(t *testing.T) {
+		format, workouts, err := importer.Parse(strings.NewReader(strongExport), toronto, database.WeightUnitKg)
+		require.Nil(t, err)
+		require.Equal(t, importer.FormatStrong, format)
+		require.Len(t, workouts, 2)
+
+		push := workouts[0]
+		require.Equal(t, "Push", push.Name)
+		require.Equal(t, time.Date(2022, 10, 30, 12, 0, 0, 0, toronto), push.Start)
+		require.Equal(t, push.Start.Add(65*time.Minute), push.End)
+		require.Len(t, push.Exercises, 2)
+		require.Equal(t, "Bench Press (Barbell)", push.Exercises[0].Name)
+		require.Equal(t, "paused", push.Exercises[0].Notes)
+		require.Equal(t, []database.ImportedSet{
+			{Weight: 60, Unit: database.WeightUnitKg, Reps: 10, Type: database.SetTypeWarmup},
+			{Weight: 82.5, Unit: database.WeightUnitKg, Reps: 5, Type: database.SetTypeWorking},
+		}, push.Exercises[0].Sets)
+
+		// the treadmill has no reps so only the squat is left
+		legs := workouts[1]
+		require.Len(t, legs.Exercises, 1)
+		require.Equal(t, "Squat (Barbell)", legs.Exercises[0].Name)
+	})
+
+	t.Run("Parse Hevy Export In Kilograms", func(t *testing.T) {
+		format, workouts, err := importer.Parse(strings.NewReader(hevyExport), time.UTC, database.WeightUnitKg)
+		require.Nil(t, err)
+		require.Equal(t, importer.FormatHevy, format)
+		require.Len(t, workouts, 1)
+		require.Equal(t, time.Date(2022, 10, 30, 13, 0, 0, 0, time.UTC), workouts[0].End)
+		require.Len(t, workouts[0].Exercises, 1)
+		require.Equal(t, "slow negatives", workouts[0].Exercises[0].Notes)
+		require.Equal(t, []database.ImportedSet{
+			{Weight: 22.68, Unit: database.WeightUnitKg, Reps: 12, Type: database.SetTypeWarmup},
+			{Weight: 45.36, Unit: database.WeightUnitKg, Reps: 10, Type: database.SetTypeWorking},
+			{Weight: 45.36, Unit: database.WeightUnitKg, Reps: 7, Type: database.SetTypeFailure},
+		}, workouts[0].Exercises[0].Sets)
+	})
+
+	t.Run("Parse Unknown Export", func(t *testing.T) {
+		_, _, err := importer.Parse(strings.NewReader("a,b,c\n1,2,3\n"), time.UTC, database.WeightUnitKg)
+		require.Equal(t, importer.ErrUnknownFormat, err)
+	})
+
+	t.Run("Dry Run Matches Catalog And Rolls Back", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+
+		start := time.Date(2022, 10, 30, 12, 0, 0, 0, time.UTC)
+		workouts := []database.ImportedWorkout{{
+			Name:  "Push",
+			Start: start,
+			End:   start.Add(time.Hour),
+			Exercises: []database.ImportedExercise{
+				{Name: "Bench Press (Dumbbell)", Sets: []database.ImportedSet{
+					{Weight: 30, Reps: 8, Type: database.SetTypeWorking},
+				}},
+				{Name: "Pec Deck", Sets: []database.ImportedSet{
+					{Weight: 40, Reps: 12, Type: database.SetTypeWorking},
+				}},
+			},
+		}}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_routines" WHERE user_id = $1 AND "workout_routines"."deleted_at" IS NULL ORDER BY id`)).
+			WithArgs(u.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "user_id"}).AddRow(2, "push", u.ID))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "exercise_routines" WHERE "exercise_routines"."workout_routine_id" = $1 AND "exercise_routines"."deleted_at" IS NULL`)).
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "workout_routine_id"}).AddRow(3, "Pec-Deck", 2))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "catalog_exercises" WHERE "catalog_exercises"."deleted_at" IS NULL`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "Bench Press").AddRow(3, "Dumbbell Bench Press"))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "workout_routine_id","start" FROM "workout_sessions" WHERE (user_id = $1 AND start >= $2 AND start <= $3) AND "workout_sessions"."deleted_at" IS NULL`)).
+			WithArgs(u.ID, start, start).
+			WillReturnRows(sqlmock.NewRows([]string{"workout_routine_id", "start"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "exercise_routines" ("created_at","updated_at","deleted_at","name","sets","reps","active","workout_routine_id","position","catalog_exercise_id") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10) RETURNING "id"`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, "Bench Press (Dumbbell)", 1, 8, true, 2, 1, 3).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "workout_sessions"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "exercises"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10).AddRow(11))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "set_entries"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(12).AddRow(13))
+		mock.ExpectRollback()
+
+		imported, err := database.ImportWorkoutHistory(gormDB, u.ID, workouts, true)
+		require.Nil(t, err)
+		require.Equal(t, 1, imported.WorkoutSessions)
+		require.Equal(t, 2, imported.Sets)
+		require.Empty(t, imported.NewWorkoutRoutines)
+		require.Len(t, imported.NewExerciseRoutines, 1)
+		require.Equal(t, "Dumbbell Bench Press", *imported.NewExerciseRoutines[0].CatalogExercise)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
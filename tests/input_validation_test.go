@@ -0,0 +1,149 @@
+package test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInputValidation(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	wr := testdata.WorkoutRoutine
+	er := testdata.WorkoutRoutine.ExerciseRoutines[0]
+
+	const addWorkoutSession = `
+		mutation AddWorkoutSession($workout: WorkoutSessionInput!) {
+			addWorkoutSession(workout: $workout) {
+				id
+			}
+		}`
+
+	exercise := func(sets ...map[string]interface{}) map[string]interface{} {
+		if sets == nil {
+			sets = []map[string]interface{}{}
+		}
+		return map[string]interface{}{
+			"exerciseRoutineId": fmt.Sprintf("%d", er.ID),
+			"notes":             "",
+			"setEntries":        sets,
+		}
+	}
+
+	// input is checked before the resolver runs, none of these touch the db
+	t.Run("Negative Reps Are Rejected", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		var resp struct{}
+		err := c.Post(addWorkoutSession, &resp,
+			client.Var("workout", map[string]interface{}{
+				"workoutRoutineId": fmt.Sprintf("%d", wr.ID),
+				"start":            "2022-10-01T10:00:00Z",
+				"exercises": []map[string]interface{}{
+					exercise(map[string]interface{}{"weight": 225, "reps": 5}, map[string]interface{}{"weight": 225, "reps": -1}),
+				},
+			}),
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.EqualError(t, err, `[{"message":"reps needs to be between 0 and 9999","path":["addWorkoutSession"],"extensions":{"code":"INVALID_INPUT","fields":[{"field":"workout.exercises.0.setEntries.1.reps","message":"reps needs to be between 0 and 9999"}]}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Every Invalid Field Is Listed", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		var resp struct{}
+		err := c.Post(addWorkoutSession, &resp,
+			client.Var("workout", map[string]interface{}{
+				"workoutRoutineId": fmt.Sprintf("%d", wr.ID),
+				"start":            "2022-10-01T10:00:00Z",
+				"exercises": []map[string]interface{}{
+					exercise(),
+					{
+						"exerciseRoutineId": fmt.Sprintf("%d", er.ID),
+						"notes":             strings.Repeat("a", 513),
+						"setEntries":        []map[string]interface{}{{"weight": -5, "reps": 10000}},
+					},
+				},
+			}),
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.EqualError(t, err, `[{"message":"max length of notes is 512 characters; weight needs to be between 0 and 9999; reps needs to be between 0 and 9999","path":["addWorkoutSession"],"extensions":{"code":"INVALID_INPUT","fields":[{"field":"workout.exercises.1.notes","message":"max length of notes is 512 characters"},{"field":"workout.exercises.1.setEntries.0.weight","message":"weight needs to be between 0 and 9999"},{"field":"workout.exercises.1.setEntries.0.reps","message":"reps needs to be between 0 and 9999"}]}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Too Many Exercises Are Rejected", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		exercises := []map[string]interface{}{}
+		for i := 0; i < 31; i++ {
+			exercises = append(exercises, exercise())
+		}
+
+		var resp struct{}
+		err := c.Post(addWorkoutSession, &resp,
+			client.Var("workout", map[string]interface{}{
+				"workoutRoutineId": fmt.Sprintf("%d", wr.ID),
+				"start":            "2022-10-01T10:00:00Z",
+				"exercises":        exercises,
+			}),
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.EqualError(t, err, `[{"message":"workout session cannot have more than 30 exercises","path":["addWorkoutSession"],"extensions":{"code":"INVALID_INPUT","fields":[{"field":"workout.exercises","message":"workout session cannot have more than 30 exercises"}]}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Exercise Routine Names Are Limited", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		var resp struct{}
+		err := c.Post(fmt.Sprintf(`
+			mutation AddExerciseRoutine {
+				addExerciseRoutine(workoutRoutineId: "%d", exerciseRoutine: { name: "%s", sets: -1, reps: 5 }) {
+					id
+				}
+			}`, wr.ID, strings.Repeat("a", 33)),
+			&resp,
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.EqualError(t, err, `[{"message":"name needs to be between 1 and 32 characters; sets needs to be between 0 and 20","path":["addExerciseRoutine"],"extensions":{"code":"INVALID_INPUT","fields":[{"field":"exerciseRoutine.name","message":"name needs to be between 1 and 32 characters"},{"field":"exerciseRoutine.sets","message":"sets needs to be between 0 and 20"}]}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,95 @@
+//go:build integration
+
+package integration
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/testsupport"
+	"gorm.io/gorm"
+)
+
+// small enough to seed in a few seconds, the load tests use DefaultSeedOptions
+var benchmarkSeed = testsupport.SeedOptions{
+	Users:           4,
+	SessionsPerUser: 60,
+	Exercises:       5,
+	SetsPerExercise: 4,
+	EmailPrefix:     "bench",
+}
+
+func seed(b *testing.B) (*gorm.DB, *client.Client, []testsupport.SeededUser) {
+	b.Helper()
+	_ = godotenv.Load("../../.env")
+	db := testsupport.NewDB(b)
+	users, err := testsupport.Seed(db, benchmarkSeed)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return db, helpers.NewGqlClient(db, accesscontrol.NewAccessControllerService(db)), users
+}
+
+func BenchmarkWorkoutSessions(b *testing.B) {
+	db, c, users := seed(b)
+	claims := testsupport.Claims(&users[0].User)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var resp struct {
+			WorkoutSessions struct {
+				Edges []struct{ Cursor string }
+			}
+		}
+		// loaders are per request, like the middleware makes them
+		c.MustPost(testsupport.WorkoutSessionsQuery, &resp, client.Var("limit", 20), helpers.AddContext(claims, helpers.NewLoaders(db)))
+		if len(resp.WorkoutSessions.Edges) != 20 {
+			b.Fatalf("got %d sessions", len(resp.WorkoutSessions.Edges))
+		}
+	}
+}
+
+func BenchmarkAddSetParallel(b *testing.B) {
+	db, c, users := seed(b)
+	var next int64
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		// each goroutine is a user, adding to their own session
+		user := users[int(atomic.AddInt64(&next, 1)-1)%len(users)]
+		claims := testsupport.Claims(&user.User)
+		for i := 0; pb.Next(); i++ {
+			var resp struct {
+				AddSet struct{ ID string }
+			}
+			exerciseID := user.ExerciseIDs[i%len(user.ExerciseIDs)]
+			err := c.Post(testsupport.AddSetMutation, &resp,
+				client.Var("exerciseId", fmt.Sprintf("%d", exerciseID)),
+				client.Var("set", map[string]interface{}{"weight": 135, "reps": 5}),
+				helpers.AddContext(claims, helpers.NewLoaders(db)),
+			)
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkLogin(b *testing.B) {
+	_, c, users := seed(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		var resp struct {
+			Login struct{ AccessToken string }
+		}
+		user := users[i%len(users)].User
+		c.MustPost(testsupport.LoginMutation, &resp, client.Var("loginInput", map[string]string{"email": user.Email, "password": testsupport.Password}))
+	}
+}
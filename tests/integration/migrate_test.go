@@ -0,0 +1,130 @@
+//go:build integration
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/neilZon/workout-logger-api/migrate"
+	"github.com/neilZon/workout-logger-api/testsupport"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrations(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Down And Back Up", func(t *testing.T) {
+		t.Parallel()
+		db := testsupport.NewDB(t)
+		migrator, err := migrate.New(db)
+		require.NoError(t, err)
+		all := migrator.Migrations()
+
+		undone, err := migrator.Down(len(all))
+		require.NoError(t, err)
+		require.Len(t, undone, len(all))
+		pending, err := migrator.Pending()
+		require.NoError(t, err)
+		require.Len(t, pending, len(all))
+
+		// downs that leave something behind make the ups fail here
+		applied, err := migrator.Up()
+		require.NoError(t, err)
+		require.Len(t, applied, len(all))
+		pending, err = migrator.Pending()
+		require.NoError(t, err)
+		require.Empty(t, pending)
+	})
+	t.Run("Adopts An AutoMigrate Database", func(t *testing.T) {
+		t.Parallel()
+		db := testsupport.NewDB(t)
+		migrator, err := migrate.New(db)
+		require.NoError(t, err)
+		all := migrator.Migrations()
+
+		// what AutoMigrate left behind, the baseline's tables and no versions
+		_, err = migrator.Down(len(all) - 1)
+		require.NoError(t, err)
+		require.NoError(t, db.Exec(`DROP TABLE schema_migrations`).Error)
+
+		applied, err := migrator.Up()
+		require.NoError(t, err)
+		require.Len(t, applied, len(all)-1)
+		require.Equal(t, all[1].String(), applied[0].String())
+	})
+
+	t.Run("Set Entries Keep Their Keys And Indexes Partitioned Or Not", func(t *testing.T) {
+		t.Parallel()
+		db := testsupport.NewDB(t)
+		migrator, err := migrate.New(db)
+		require.NoError(t, err)
+
+		check := func(kind string) {
+			var relkind string
+			require.NoError(t, db.Raw(`SELECT relkind FROM pg_class WHERE oid = to_regclass('set_entries')`).Scan(&relkind).Error)
+			require.Equal(t, kind, relkind)
+			var constraints []string
+			require.NoError(t, db.Raw(`SELECT conname FROM pg_constraint WHERE conrelid = to_regclass('set_entries') ORDER BY conname`).Scan(&constraints).Error)
+			require.Equal(t, []string{"fk_exercises_sets", "set_entries_pkey"}, constraints)
+			var indexes []string
+			require.NoError(t, db.Raw(`SELECT indexrelid::regclass::text FROM pg_index WHERE indrelid = to_regclass('set_entries') AND NOT indisprimary ORDER BY 1`).Scan(&indexes).Error)
+			require.Equal(t, []string{"idx_set_entries_deleted_at", "idx_set_entries_exercise_id"}, indexes)
+		}
+		check("p")
+
+		// back to before 0004_partition_set_entries
+		steps := 0
+		for _, m := range migrator.Migrations() {
+			if m.Version >= 4 {
+				steps++
+			}
+		}
+		_, err = migrator.Down(steps)
+		require.NoError(t, err)
+		check("r")
+	})
+
+	t.Run("Weight Units Are Labelled From The Owner", func(t *testing.T) {
+		t.Parallel()
+		db := testsupport.NewDB(t)
+		migrator, err := migrate.New(db)
+		require.NoError(t, err)
+		// back to before 0007_backfill_weight_units
+		steps := 0
+		for _, m := range migrator.Migrations() {
+			if m.Version >= 7 {
+				steps++
+			}
+		}
+		_, err = migrator.Down(steps)
+		require.NoError(t, err)
+
+		var pick, region, unparsed uint
+		for _, u := range []struct {
+			id         *uint
+			locale     string
+			weightUnit *string
+		}{
+			{&pick, "en-US", stringPtr("KG")},
+			{&region, "de_de", nil},
+			{&unparsed, "not a locale", nil},
+		} {
+			err := db.Raw(`INSERT INTO users (name, email, password, locale, weight_unit) VALUES ('lifter', ?, 'x', ?, ?) RETURNING id`, u.locale+"@example.com", u.locale, u.weightUnit).Scan(u.id).Error
+			require.NoError(t, err)
+			err = db.Exec(`INSERT INTO goals (user_id, type, unit) VALUES (?, 'ONE_REP_MAX', '')`, *u.id).Error
+			require.NoError(t, err)
+		}
+
+		_, err = migrator.Up()
+		require.NoError(t, err)
+		for id, unit := range map[uint]string{pick: "KG", region: "KG", unparsed: "LB"} {
+			var got string
+			require.NoError(t, db.Raw(`SELECT unit FROM goals WHERE user_id = ?`, id).Scan(&got).Error)
+			require.Equal(t, unit, got)
+		}
+	})
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
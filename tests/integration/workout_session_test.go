@@ -0,0 +1,212 @@
+//go:build integration
+
+package integration
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/testsupport"
+	"github.com/stretchr/testify/require"
+)
+
+type SetResp struct {
+	ID       string
+	Weight   float64
+	Unit     string
+	Reps     int
+	SetOrder int
+}
+
+type WorkoutSessionResp struct {
+	ID        string
+	Exercises []struct {
+		ID   string
+		Sets []SetResp
+	}
+	Summary *struct {
+		TotalVolume float64
+		TotalSets   int
+		WeightUnit  string
+	}
+}
+
+func TestWorkoutSessions(t *testing.T) {
+	t.Parallel()
+
+	// ci passes settings in the environment instead
+	_ = godotenv.Load("../../.env")
+
+	t.Run("Add Workout Session And Read It Back", func(t *testing.T) {
+		t.Parallel()
+		db := testsupport.NewDB(t)
+		f := testsupport.NewFixtures(t, db)
+		user := f.User("lifter")
+		routine := f.WorkoutRoutine(user, "Legs", "Squat", "Leg Press")
+		c := helpers.NewGqlClient(db, accesscontrol.NewAccessControllerService(db))
+		ctx := helpers.AddContext(testsupport.Claims(user), helpers.NewLoaders(db))
+
+		var added struct {
+			AddWorkoutSession WorkoutSessionResp
+		}
+		c.MustPost(fmt.Sprintf(`mutation {
+			addWorkoutSession(workout: {
+				workoutRoutineId: "%d"
+				start: "2023-03-01T17:00:00Z"
+				end: "2023-03-01T18:00:00Z"
+				exercises: [
+					{exerciseRoutineId: "%d", notes: "", setEntries: [{weight: 225.499998, reps: 5}, {weight: 100, unit: KG, reps: 5}]}
+					{exerciseRoutineId: "%d", notes: "", setEntries: [{weight: 300, reps: 10}]}
+				]
+			}) { id }
+		}`, routine.ID, routine.ExerciseRoutines[0].ID, routine.ExerciseRoutines[1].ID), &added, ctx)
+
+		var resp struct {
+			WorkoutSession WorkoutSessionResp
+		}
+		c.MustPost(fmt.Sprintf(`query {
+			workoutSession(workoutSessionId: "%s") {
+				id
+				exercises { id sets { id weight unit reps setOrder } }
+				summary { totalVolume totalSets weightUnit }
+			}
+		}`, added.AddWorkoutSession.ID), &resp, ctx)
+
+		require.Len(t, resp.WorkoutSession.Exercises, 2)
+		sets := resp.WorkoutSession.Exercises[0].Sets
+		require.Len(t, sets, 2)
+		// numeric(10,2) keeps what the api rounded to
+		require.Equal(t, 225.5, sets[0].Weight)
+		require.Equal(t, "LB", sets[0].Unit)
+		require.Equal(t, 1, sets[0].SetOrder)
+		require.Equal(t, 100.0, sets[1].Weight)
+		require.Equal(t, "KG", sets[1].Unit)
+
+		// kg sets are added up in the user's pounds
+		require.NotNil(t, resp.WorkoutSession.Summary)
+		require.Equal(t, 3, resp.WorkoutSession.Summary.TotalSets)
+		require.Equal(t, "LB", resp.WorkoutSession.Summary.WeightUnit)
+		require.InDelta(t, 225.5*5+100*2.20462262185*5+300*10, resp.WorkoutSession.Summary.TotalVolume, 0.01)
+	})
+
+	t.Run("Sets Of An Exercise", func(t *testing.T) {
+		t.Parallel()
+		db := testsupport.NewDB(t)
+		f := testsupport.NewFixtures(t, db)
+		user := f.User("lifter")
+		routine := f.WorkoutRoutine(user, "Push", "Bench Press")
+		session := f.WorkoutSession(user, routine, time.Now().Add(-2*time.Hour), 2, 135, 8)
+		exerciseId := session.Exercises[0].ID
+		c := helpers.NewGqlClient(db, accesscontrol.NewAccessControllerService(db))
+		ctx := helpers.AddContext(testsupport.Claims(user), helpers.NewLoaders(db))
+
+		var added struct {
+			AddSet SetResp
+		}
+		c.MustPost(fmt.Sprintf(`mutation { addSet(exerciseId: "%d", set: {weight: 145, reps: 6}) { id setOrder } }`, exerciseId), &added, ctx)
+		require.Equal(t, 3, added.AddSet.SetOrder)
+
+		var updated struct {
+			UpdateSet SetResp
+		}
+		c.MustPost(fmt.Sprintf(`mutation { updateSet(setId: "%s", set: {weight: 150, reps: 5}) { id weight reps } }`, added.AddSet.ID), &updated, ctx)
+		require.Equal(t, 150.0, updated.UpdateSet.Weight)
+		require.Equal(t, 5, updated.UpdateSet.Reps)
+
+		var deleted struct {
+			DeleteSet int
+		}
+		c.MustPost(fmt.Sprintf(`mutation { deleteSet(setId: "%d") }`, session.Exercises[0].Sets[0].ID), &deleted, ctx)
+		require.Equal(t, 1, deleted.DeleteSet)
+
+		var resp struct {
+			Sets []SetResp
+		}
+		c.MustPost(fmt.Sprintf(`query { sets(exerciseId: "%d") { id weight reps setOrder } }`, exerciseId), &resp, ctx)
+		require.Len(t, resp.Sets, 2)
+		require.Equal(t, 135.0, resp.Sets[0].Weight)
+		require.Equal(t, 150.0, resp.Sets[1].Weight)
+	})
+
+	t.Run("Previous Exercises", func(t *testing.T) {
+		t.Parallel()
+		db := testsupport.NewDB(t)
+		f := testsupport.NewFixtures(t, db)
+		user := f.User("lifter")
+		routine := f.WorkoutRoutine(user, "Legs", "Squat", "Leg Press")
+		first := f.WorkoutSession(user, routine, time.Now().AddDate(0, 0, -14), 1, 100, 5)
+		second := f.WorkoutSession(user, routine, time.Now().AddDate(0, 0, -7), 1, 105, 5)
+		c := helpers.NewGqlClient(db, accesscontrol.NewAccessControllerService(db))
+
+		var resp struct {
+			WorkoutSessions struct {
+				Edges []struct {
+					Node struct {
+						ID            string
+						PrevExercises []struct{ ID string }
+					}
+				}
+			}
+		}
+		c.MustPost(`query { workoutSessions(limit: 10) { edges { node { id prevExercises { id } } } } }`, &resp, helpers.AddContext(testsupport.Claims(user), helpers.NewLoaders(db)))
+
+		edges := resp.WorkoutSessions.Edges
+		require.Len(t, edges, 2)
+		require.Equal(t, fmt.Sprintf("%d", second.ID), edges[0].Node.ID)
+		require.ElementsMatch(t, []struct{ ID string }{
+			{fmt.Sprintf("%d", first.Exercises[0].ID)},
+			{fmt.Sprintf("%d", first.Exercises[1].ID)},
+		}, edges[0].Node.PrevExercises)
+		require.Empty(t, edges[1].Node.PrevExercises)
+	})
+
+	t.Run("Lifetime Stats", func(t *testing.T) {
+		t.Parallel()
+		db := testsupport.NewDB(t)
+		f := testsupport.NewFixtures(t, db)
+		user := f.User("lifter")
+		routine := f.WorkoutRoutine(user, "Legs", "Squat", "Leg Press")
+		for week := 0; week < 3; week++ {
+			f.WorkoutSession(user, routine, time.Now().AddDate(0, 0, -7*(week+1)), 3, 100, 5)
+		}
+		// someone else's sessions don't count
+		other := f.User("other")
+		f.WorkoutSession(other, f.WorkoutRoutine(other, "Legs", "Squat"), time.Now().AddDate(0, 0, -1), 3, 500, 5)
+		c := helpers.NewGqlClient(db, accesscontrol.NewAccessControllerService(db))
+
+		var resp struct {
+			User struct {
+				LifetimeStats struct {
+					TotalSessions int
+					TotalSets     int
+					TotalVolume   float64
+					WeightUnit    string
+				}
+			}
+		}
+		c.MustPost(`query { user { lifetimeStats { totalSessions totalSets totalVolume weightUnit } } }`, &resp, helpers.AddContext(testsupport.Claims(user), helpers.NewLoaders(db)))
+		stats := resp.User.LifetimeStats
+		require.Equal(t, 3, stats.TotalSessions)
+		require.Equal(t, 18, stats.TotalSets)
+		require.Equal(t, 9000.0, stats.TotalVolume)
+		require.Equal(t, "LB", stats.WeightUnit)
+	})
+
+	t.Run("Another User's Session Is Forbidden", func(t *testing.T) {
+		t.Parallel()
+		db := testsupport.NewDB(t)
+		f := testsupport.NewFixtures(t, db)
+		owner := f.User("owner")
+		session := f.WorkoutSession(owner, f.WorkoutRoutine(owner, "Legs", "Squat"), time.Now().Add(-time.Hour), 1, 100, 5)
+		stranger := f.User("stranger")
+		c := helpers.NewGqlClient(db, accesscontrol.NewAccessControllerService(db))
+
+		var resp struct{}
+		err := c.Post(fmt.Sprintf(`query { workoutSession(workoutSessionId: "%d") { id } }`, session.ID), &resp, helpers.AddContext(testsupport.Claims(stranger), helpers.NewLoaders(db)))
+		require.EqualError(t, err, `[{"message":"Error Getting Workout Session: Access Denied","path":["workoutSession"],"extensions":{"code":"FORBIDDEN"}}]`)
+	})
+}
@@ -0,0 +1,83 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type LifetimeStatsResp struct {
+	User struct {
+		ID            string
+		LifetimeStats struct {
+			TotalSessions int
+			TotalSets     int
+			TotalVolume   float64
+			MemberSince   string
+		}
+	}
+}
+
+func TestLifetimeStatsResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+
+	t.Run("Lifetime Stats Include Archived Sessions", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		memberSince := time.Date(2020, 1, 5, 0, 0, 0, 0, time.UTC)
+		profileRow := sqlmock.NewRows([]string{"id", "name", "email", "created_at"}).AddRow(u.ID, u.Name, u.Subject, memberSince)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE id = $1`)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(profileRow)
+
+		liveRow := sqlmock.NewRows([]string{"total_sessions", "total_sets", "total_volume"}).AddRow(3, 12, 5400)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(DISTINCT workout_sessions.id) AS total_sessions")).
+			WithArgs(fmt.Sprintf("%d", u.ID), fmt.Sprintf("%d", u.ID)).
+			WillReturnRows(liveRow)
+		archivedRow := sqlmock.NewRows([]string{"total_sessions", "total_sets", "total_volume"}).AddRow(10, 40, 20000)
+		mock.ExpectQuery(regexp.QuoteMeta("FROM archived_workout_sessions")).
+			WithArgs(fmt.Sprintf("%d", u.ID)).
+			WillReturnRows(archivedRow)
+
+		var resp LifetimeStatsResp
+		c.MustPost(`
+			query User {
+				user {
+					id
+					lifetimeStats {
+						totalSessions
+						totalSets
+						totalVolume
+						memberSince
+					}
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, 13, resp.User.LifetimeStats.TotalSessions)
+		require.Equal(t, 52, resp.User.LifetimeStats.TotalSets)
+		require.Equal(t, float64(25400), resp.User.LifetimeStats.TotalVolume)
+		require.Equal(t, "2020-01-05T00:00:00Z", resp.User.LifetimeStats.MemberSince)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/testsupport"
+	"github.com/neilZon/workout-logger-api/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadTestScenarios(t *testing.T) {
+	t.Parallel()
+
+	secret := []byte("load test secret")
+	users := []testsupport.SeededUser{
+		{User: database.User{Name: "Lifter 1", Email: "lifter1@loadtest.untilfailure.app"}, ExerciseIDs: []uint{11, 12}},
+		{User: database.User{Name: "Lifter 2", Email: "lifter2@loadtest.untilfailure.app"}, ExerciseIDs: []uint{21, 22}},
+	}
+	users[0].User.ID = 1
+	users[1].User.ID = 2
+
+	scenarios, err := testsupport.Scenarios("http://localhost:8080/query", users, secret)
+	require.NoError(t, err)
+	require.Len(t, scenarios, 3)
+	require.Equal(t, "workout_sessions", scenarios[0].Name)
+	require.Len(t, scenarios[0].Requests, 2)
+	require.Len(t, scenarios[1].Requests, 4)
+	require.Len(t, scenarios[2].Requests, 2)
+
+	t.Run("Vegeta Targets", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, testsupport.WriteVegeta(&buf, scenarios[1].Requests))
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		require.Len(t, lines, 4)
+
+		var target struct {
+			Method string
+			URL    string
+			Body   string
+			Header map[string][]string
+		}
+		require.NoError(t, json.Unmarshal([]byte(lines[3]), &target))
+		require.Equal(t, "POST", target.Method)
+		require.Equal(t, "http://localhost:8080/query", target.URL)
+
+		body, err := base64.StdEncoding.DecodeString(target.Body)
+		require.NoError(t, err)
+		var gql struct {
+			Query     string
+			Variables map[string]interface{}
+		}
+		require.NoError(t, json.Unmarshal(body, &gql))
+		require.Equal(t, testsupport.AddSetMutation, gql.Query)
+		require.Equal(t, "22", gql.Variables["exerciseId"])
+
+		// requests are for the user whose exercise it is
+		claims, err := token.Decode(target.Header["Authorization"][0], secret)
+		require.NoError(t, err)
+		require.Equal(t, uint(2), claims.ID)
+	})
+
+	t.Run("K6 Scenarios", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, testsupport.WriteK6(&buf, scenarios))
+
+		var out struct {
+			Scenarios map[string][]struct {
+				Method  string
+				Body    string
+				Headers map[string]string
+			}
+		}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &out))
+		require.Len(t, out.Scenarios["add_set"], 4)
+
+		login := out.Scenarios["login"][0]
+		require.Contains(t, login.Body, `"email":"lifter1@loadtest.untilfailure.app"`)
+		require.Contains(t, login.Body, `"password":"`+testsupport.Password+`"`)
+		require.Empty(t, login.Headers["Authorization"])
+		require.Equal(t, "application/json", login.Headers["Content-Type"])
+	})
+}
@@ -0,0 +1,143 @@
+package test
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLoginThrottle(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	const throttlesQuery = `SELECT * FROM "login_throttles" WHERE key IN ($1,$2)`
+	const userByEmailQuery = `SELECT * FROM "users" WHERE email = $1 AND "users"."deleted_at" IS NULL ORDER BY "users"."id" LIMIT 1`
+	const loginMutation = `mutation { login(loginInput: {email: "Lifter@test.com", password: "password1"}) { accessToken } }`
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("password1"), bcrypt.MinCost)
+	require.Nil(t, err)
+
+	expectUser := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(userByEmailQuery)).
+			WithArgs("Lifter@test.com").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password"}).AddRow(7, "Lifter@test.com", hashedPassword))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE id = $1`)).
+			WithArgs("7").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "verified"}).AddRow(7, true))
+	}
+
+	t.Run("Locked Email Is Refused Before Checking The Password", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		c := newRefreshTokenClient(gormDB)
+
+		mock.ExpectQuery(regexp.QuoteMeta(throttlesQuery)).
+			WithArgs("email:lifter@test.com", "ip:203.0.113.7").
+			WillReturnRows(sqlmock.NewRows([]string{"key", "failures", "last_failed_at"}).
+				AddRow("email:lifter@test.com", config.LOGIN_FREE_FAILURES, time.Now()).
+				AddRow("ip:203.0.113.7", 1, time.Now()))
+
+		var resp struct{}
+		err := c.Post(loginMutation, &resp, addDevice("203.0.113.7", ""))
+		require.EqualError(t, err, `[{"message":"Too many failed logins, try again later","path":["login"],"extensions":{"code":"LOGIN_LOCKED","retryAfterSeconds":30}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Wrong Password Counts Against The Email And Ip", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		c := newRefreshTokenClient(gormDB)
+
+		mock.ExpectQuery(regexp.QuoteMeta(throttlesQuery)).
+			WithArgs("email:lifter@test.com", "ip:203.0.113.7").
+			WillReturnRows(sqlmock.NewRows([]string{"key", "failures", "last_failed_at"}))
+		mock.ExpectQuery(regexp.QuoteMeta(userByEmailQuery)).
+			WithArgs("Lifter@test.com").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password"}).AddRow(7, "Lifter@test.com", "not the hash"))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE id = $1`)).
+			WithArgs("7").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "verified"}).AddRow(7, true))
+		for _, key := range []string{"email:lifter@test.com", "ip:203.0.113.7"} {
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "login_throttles" ("key","failures","last_failed_at") VALUES ($1,$2,$3) ON CONFLICT ("key") DO UPDATE SET "failures"=CASE WHEN login_throttles.last_failed_at < $4 THEN 1 ELSE login_throttles.failures + 1 END,"last_failed_at"=$5`)).
+				WithArgs(key, 1, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			mock.ExpectCommit()
+		}
+
+		var resp struct{}
+		err := c.Post(loginMutation, &resp, addDevice("203.0.113.7", ""))
+		require.EqualError(t, err, `[{"message":"Incorrect Password","path":["login"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Login Clears The Email's Failures", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		c := newRefreshTokenClient(gormDB)
+
+		mock.ExpectQuery(regexp.QuoteMeta(throttlesQuery)).
+			WithArgs("email:lifter@test.com", "ip:203.0.113.7").
+			WillReturnRows(sqlmock.NewRows([]string{"key", "failures", "last_failed_at"}).
+				AddRow("email:lifter@test.com", config.LOGIN_FREE_FAILURES, time.Now().Add(-time.Minute)))
+		expectUser(mock)
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "login_throttles" WHERE key = $1`)).
+			WithArgs("email:lifter@test.com").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "sessions"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "refresh_tokens"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		var resp struct {
+			Login struct {
+				AccessToken string
+			}
+		}
+		c.MustPost(loginMutation, &resp, addDevice("203.0.113.7", ""))
+		require.NotEmpty(t, resp.Login.AccessToken)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Lockout Doubles Up To The Max", func(t *testing.T) {
+		lastFailed := time.Date(2022, 10, 30, 12, 0, 0, 0, time.UTC)
+		lockout := func(failures int) time.Duration {
+			throttle := database.LoginThrottle{Failures: failures, LastFailedAt: lastFailed}
+			until := throttle.LockedUntil(config.LOGIN_FREE_FAILURES)
+			if until.IsZero() {
+				return 0
+			}
+			return until.Sub(lastFailed)
+		}
+
+		require.Equal(t, time.Duration(0), lockout(config.LOGIN_FREE_FAILURES-1))
+		require.Equal(t, config.LOGIN_LOCKOUT, lockout(config.LOGIN_FREE_FAILURES))
+		require.Equal(t, 2*config.LOGIN_LOCKOUT, lockout(config.LOGIN_FREE_FAILURES+1))
+		require.Equal(t, 4*config.LOGIN_LOCKOUT, lockout(config.LOGIN_FREE_FAILURES+2))
+		require.Equal(t, config.LOGIN_MAX_LOCKOUT, lockout(config.LOGIN_FREE_FAILURES+100))
+	})
+}
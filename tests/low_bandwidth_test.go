@@ -0,0 +1,142 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type LowBandwidthSessionsResp struct {
+	WorkoutSessions struct {
+		Edges []struct {
+			Node struct {
+				ID             string
+				ClientMetadata map[string]interface{}
+				Origin         *struct {
+					Platform string
+				}
+				PrevExercises []struct {
+					ID string
+				}
+			}
+		}
+	}
+}
+
+func TestLowBandwidth(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	ws := testdata.WorkoutSession
+
+	lowBandwidth := func(bd *client.Request) {
+		bd.HTTP = bd.HTTP.WithContext(context.WithValue(bd.HTTP.Context(), middleware.LowBandwidthCtxKey, true))
+	}
+
+	t.Run("Low Bandwidth Trims Workout Sessions", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		srv := helpers.NewGqlServer(gormDB, accesscontrol.NewAccessControllerService(gormDB))
+		srv.Use(middleware.LowBandwidth{})
+		c := client.New(srv)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		// asked for 30, got the low bandwidth page size
+		sessionRows := sqlmock.NewRows([]string{"id", "start", "workout_routine_id", "user_id", "client_metadata", "origin_platform", "origin_app_version", "origin_device_id"}).
+			AddRow(ws.ID, ws.Start, ws.WorkoutRoutineID, u.ID, `{"device":"watch"}`, "watchos", "2.3.1", "device-1")
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_sessions" WHERE user_id = $1 AND "workout_sessions"."deleted_at" IS NULL ORDER BY id desc LIMIT 10`)).
+			WithArgs(fmt.Sprintf("%d", u.ID)).
+			WillReturnRows(sessionRows)
+
+		// prevExercises doesn't touch the db
+		var resp LowBandwidthSessionsResp
+		c.MustPost(`
+			query WorkoutSessions {
+				workoutSessions(limit: 30) {
+					edges {
+						node {
+							id
+							clientMetadata
+							origin {
+								platform
+							}
+							prevExercises {
+								id
+							}
+						}
+					}
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)), lowBandwidth)
+
+		require.Len(t, resp.WorkoutSessions.Edges, 1)
+		node := resp.WorkoutSessions.Edges[0].Node
+		require.Equal(t, fmt.Sprintf("%d", ws.ID), node.ID)
+		require.Nil(t, node.ClientMetadata)
+		require.Nil(t, node.Origin)
+		require.Empty(t, node.PrevExercises)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Full Bandwidth Keeps Optional Fields", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		srv := helpers.NewGqlServer(gormDB, accesscontrol.NewAccessControllerService(gormDB))
+		srv.Use(middleware.LowBandwidth{})
+		c := client.New(srv)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		sessionRows := sqlmock.NewRows([]string{"id", "start", "workout_routine_id", "user_id", "client_metadata", "origin_platform", "origin_app_version", "origin_device_id"}).
+			AddRow(ws.ID, ws.Start, ws.WorkoutRoutineID, u.ID, `{"device":"watch"}`, "watchos", "2.3.1", "device-1")
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_sessions" WHERE user_id = $1 AND "workout_sessions"."deleted_at" IS NULL ORDER BY id desc LIMIT 30`)).
+			WithArgs(fmt.Sprintf("%d", u.ID)).
+			WillReturnRows(sessionRows)
+
+		var resp LowBandwidthSessionsResp
+		c.MustPost(`
+			query WorkoutSessions {
+				workoutSessions(limit: 30) {
+					edges {
+						node {
+							id
+							clientMetadata
+							origin {
+								platform
+							}
+						}
+					}
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+
+		require.Len(t, resp.WorkoutSessions.Edges, 1)
+		node := resp.WorkoutSessions.Edges[0].Node
+		require.Equal(t, "watch", node.ClientMetadata["device"])
+		require.NotNil(t, node.Origin)
+		require.Equal(t, "watchos", node.Origin.Platform)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,225 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type MergeUsersResp struct {
+	MergeUsers bool
+}
+
+func TestMergeUsersResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	adminId := fmt.Sprintf("%d", u.ID)
+	const sourceId = "41"
+	const targetId = "42"
+
+	// tables where both accounts can have the same row, in the order they're settled
+	duplicateTables := []string{
+		"workout_session_participants",
+		"workout_session_participants",
+		"coaches",
+		"coaches",
+		"coaches",
+		"nutrition_logs",
+		"sleep_logs",
+		"strava_connections",
+		"sync_records",
+		"user_preferences",
+		"weekly_digests",
+		"password_reset_tokens",
+		"refresh_tokens",
+		"sessions",
+	}
+
+	reparented := []struct {
+		table  string
+		column string
+	}{
+		{"workout_routines", "user_id"},
+		{"workout_sessions", "user_id"},
+		{"exercises", "user_id"},
+		{"exercise_videos", "user_id"},
+		{"video_annotations", "coach_id"},
+		{"workout_session_participants", "user_id"},
+		{"workout_session_share_links", "user_id"},
+		{"workout_session_unlocks", "user_id"},
+		{"workout_session_auto_finishes", "user_id"},
+		{"coaches", "user_id"},
+		{"coaches", "coach_id"},
+		{"request_recordings", "user_id"},
+		{"archived_workout_sessions", "user_id"},
+		{"body_weight_entries", "user_id"},
+		{"goals", "user_id"},
+		{"nutrition_logs", "user_id"},
+		{"sleep_logs", "user_id"},
+		{"unit_conversions", "user_id"},
+		{"quick_phrases", "user_id"},
+		{"account_exports", "user_id"},
+		{"snapshots", "user_id"},
+		{"programs", "user_id"},
+		{"workout_routine_share_links", "user_id"},
+		{"webhooks", "user_id"},
+		{"strava_connections", "user_id"},
+		{"strava_uploads", "user_id"},
+		{"progress_photos", "user_id"},
+		{"sync_records", "user_id"},
+		{"audit_logs", "user_id"},
+		{"user_preferences", "user_id"},
+		{"push_tokens", "user_id"},
+		{"weekly_digests", "user_id"},
+	}
+
+	const openWorkoutSessionsQuery = `SELECT "id" FROM "workout_sessions" WHERE (user_id IN ($1, $2) AND "end" IS NULL) AND "workout_sessions"."deleted_at" IS NULL FOR UPDATE`
+
+	const mergeUsersMutation = `
+		mutation MergeUsers {
+			mergeUsers(sourceUserId: "41", targetUserId: "42")
+		}`
+
+	t.Run("Merge Users", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		adminRow := sqlmock.NewRows([]string{"id", "verified", "admin"}).AddRow(u.ID, true, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(adminId).WillReturnRows(adminRow)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(sourceId).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(41))
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(targetId).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(openWorkoutSessionsQuery)).
+			WithArgs(sourceId, targetId).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		for _, table := range duplicateTables {
+			mock.ExpectExec(regexp.QuoteMeta(fmt.Sprintf(`DELETE FROM "%s" WHERE`, table))).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+		}
+		for _, r := range reparented {
+			mock.ExpectExec(regexp.QuoteMeta(fmt.Sprintf(`UPDATE "%s" SET "%s"=$1 WHERE %s = $2`, r.table, r.column, r.column))).
+				WithArgs(targetId, sourceId).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "users" WHERE id = $1`)).
+			WithArgs(sourceId).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		var resp MergeUsersResp
+		c.MustPost(mergeUsersMutation, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.True(t, resp.MergeUsers)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Merge Users Rolls Back", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		adminRow := sqlmock.NewRows([]string{"id", "verified", "admin"}).AddRow(u.ID, true, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(adminId).WillReturnRows(adminRow)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(sourceId).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(41))
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(targetId).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(openWorkoutSessionsQuery)).
+			WithArgs(sourceId, targetId).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "workout_session_participants" WHERE`)).WillReturnError(fmt.Errorf("connection reset"))
+		mock.ExpectRollback()
+
+		var resp MergeUsersResp
+		err := c.Post(mergeUsersMutation, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Merging Users","path":["mergeUsers"],"extensions":{"code":"INTERNAL"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Merge Users Both With Open Sessions", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		adminRow := sqlmock.NewRows([]string{"id", "verified", "admin"}).AddRow(u.ID, true, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(adminId).WillReturnRows(adminRow)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(sourceId).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(41))
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(targetId).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(openWorkoutSessionsQuery)).
+			WithArgs(sourceId, targetId).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7).AddRow(8))
+		mock.ExpectRollback()
+
+		var resp MergeUsersResp
+		err := c.Post(mergeUsersMutation, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Merging Users: Both Users Have An Open Workout Session, Finish One First","path":["mergeUsers"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Merge Users Not Admin", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified", "admin"}).AddRow(u.ID, true, false)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(adminId).WillReturnRows(userRow)
+
+		var resp MergeUsersResp
+		err := c.Post(mergeUsersMutation, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Access Denied","path":["mergeUsers"],"extensions":{"code":"FORBIDDEN"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Merge Users Into Itself", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		adminRow := sqlmock.NewRows([]string{"id", "verified", "admin"}).AddRow(u.ID, true, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(adminId).WillReturnRows(adminRow)
+
+		var resp MergeUsersResp
+		err := c.Post(`
+			mutation MergeUsers {
+				mergeUsers(sourceUserId: "41", targetUserId: "41")
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Merging Users: source and target need to be different users","path":["mergeUsers"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
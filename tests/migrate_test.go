@@ -0,0 +1,105 @@
+package test
+
+import (
+	"regexp"
+	"testing"
+	"testing/fstest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/migrate"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrate(t *testing.T) {
+	t.Parallel()
+
+	const migrationsTableQuery = `SELECT to_regclass('schema_migrations') IS NOT NULL`
+	const versionQuery = `SELECT version, dirty FROM schema_migrations LIMIT 1`
+
+	migrations := fstest.MapFS{
+		"sql/0001_baseline.up.sql":        {Data: []byte("CREATE TABLE users (id bigserial PRIMARY KEY, email text NOT NULL);")},
+		"sql/0001_baseline.down.sql":      {Data: []byte("DROP TABLE users;")},
+		"sql/0002_add_user_name.up.sql":   {Data: []byte("ALTER TABLE users ADD COLUMN name text;")},
+		"sql/0002_add_user_name.down.sql": {Data: []byte("ALTER TABLE users DROP COLUMN name;")},
+		"sql/0003_index_names.up.sql":     {Data: []byte("CREATE INDEX IF NOT EXISTS idx_users_name ON users (name);")},
+	}
+
+	expectVersion := func(mock sqlmock.Sqlmock, version uint, dirty bool) {
+		mock.ExpectQuery(regexp.QuoteMeta(migrationsTableQuery)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(regexp.QuoteMeta(versionQuery)).WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(version, dirty))
+	}
+
+	t.Run("Load", func(t *testing.T) {
+		migrator, err := migrate.NewFromFS(nil, migrations, "sql")
+		require.NoError(t, err)
+		all := migrator.Migrations()
+		require.Len(t, all, 3)
+		require.Equal(t, "0002_add_user_name", all[1].String())
+		require.Equal(t, "ALTER TABLE users DROP COLUMN name;", all[1].Down)
+		require.Empty(t, all[2].Down)
+	})
+
+	t.Run("Status", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		migrator, err := migrate.NewFromFS(gormDB, migrations, "sql")
+		require.NoError(t, err)
+
+		expectVersion(mock, 1, false)
+		pending, err := migrator.Pending()
+		require.NoError(t, err)
+		require.Len(t, pending, 2)
+		require.Equal(t, "0002_add_user_name", pending[0].String())
+		require.Equal(t, "0003_index_names", pending[1].String())
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Dirty Migration Isn't Applied", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		migrator, err := migrate.NewFromFS(gormDB, migrations, "sql")
+		require.NoError(t, err)
+
+		expectVersion(mock, 2, true)
+		statuses, err := migrator.Status()
+		require.NoError(t, err)
+		require.True(t, statuses[0].Applied)
+		require.False(t, statuses[1].Applied)
+		require.True(t, statuses[1].Dirty)
+		require.False(t, statuses[2].Applied)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Status On A New Database", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		migrator, err := migrate.NewFromFS(gormDB, migrations, "sql")
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(migrationsTableQuery)).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		pending, err := migrator.Pending()
+		require.NoError(t, err)
+		require.Len(t, pending, 3)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Built In Migrations Load", func(t *testing.T) {
+		migrator, err := migrate.New(nil)
+		require.NoError(t, err)
+		all := migrator.Migrations()
+		require.Equal(t, "0001_baseline", all[0].String())
+		for _, m := range all {
+			require.NotEmpty(t, m.Down, "%s has no down", m)
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMinClientVersion(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	newClient := func(minimum string) *client.Client {
+		_, gormDB := helpers.SetupMockDB()
+		srv := helpers.NewGqlServer(gormDB, accesscontrol.NewAccessControllerService(gormDB))
+		srv.Use(middleware.MinClientVersion{
+			Minimum: minimum,
+			StoreURLs: map[string]string{
+				"ios":     "https://apps.apple.com/app/id0000000000",
+				"android": "",
+			},
+		})
+		return client.New(srv)
+	}
+
+	// passing the check lands on the resolver, which wants a token
+	const query = `query User { user { id } }`
+	const unauthorized = `[{"message":"Unauthorized","path":["user"],"extensions":{"code":"UNAUTHORIZED"}}]`
+
+	t.Run("Old Version Is Rejected", func(t *testing.T) {
+		var resp struct{}
+		err := newClient("2.4.0").Post(query, &resp, helpers.AddClient(database.Origin{Platform: "ios", AppVersion: "2.3.9"}))
+		require.EqualError(t, err, `[{"message":"App version 2.3.9 is no longer supported, update to 2.4.0 or later","extensions":{"code":"UPGRADE_REQUIRED","minimumVersion":"2.4.0","storeUrls":{"ios":"https://apps.apple.com/app/id0000000000"}}}]`)
+	})
+
+	t.Run("Versions Compare Numerically", func(t *testing.T) {
+		var resp struct{}
+		err := newClient("2.4.0").Post(query, &resp, helpers.AddClient(database.Origin{Platform: "ios", AppVersion: "2.10"}))
+		require.EqualError(t, err, unauthorized)
+	})
+
+	t.Run("Minimum Version Is Allowed", func(t *testing.T) {
+		var resp struct{}
+		err := newClient("2.4").Post(query, &resp, helpers.AddClient(database.Origin{Platform: "android", AppVersion: "2.4.0-beta"}))
+		require.EqualError(t, err, unauthorized)
+	})
+
+	t.Run("Missing Version Is Allowed", func(t *testing.T) {
+		var resp struct{}
+		err := newClient("2.4.0").Post(query, &resp)
+		require.EqualError(t, err, unauthorized)
+	})
+
+	t.Run("No Minimum Allows Everything", func(t *testing.T) {
+		var resp struct{}
+		err := newClient("").Post(query, &resp, helpers.AddClient(database.Origin{Platform: "ios", AppVersion: "0.0.1"}))
+		require.EqualError(t, err, unauthorized)
+	})
+}
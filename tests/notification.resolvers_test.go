@@ -0,0 +1,111 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+
+	expectUser := func(mock sqlmock.Sqlmock) {
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+	}
+
+	t.Run("Defaults To Every Notification", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectUser(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user_preferences" WHERE user_id = $1 AND "user_preferences"."deleted_at" IS NULL LIMIT 1`)).
+			WithArgs(u.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		var resp struct {
+			NotificationPreferences struct {
+				WeeklyDigestEmail bool
+				WeeklyDigestPush  bool
+			}
+		}
+		c.MustPost(`query { notificationPreferences { weeklyDigestEmail weeklyDigestPush } }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.True(t, resp.NotificationPreferences.WeeklyDigestEmail)
+		require.True(t, resp.NotificationPreferences.WeeklyDigestPush)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Opt Out Of Digest Emails", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectUser(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user_preferences" WHERE user_id = $1`)).
+			WithArgs(u.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "user_preferences" ("created_at","updated_at","deleted_at","user_id","time_zone","weekly_digest_email","weekly_digest_push") VALUES ($1,$2,$3,$4,$5,$6,$7) ON CONFLICT ("user_id") DO UPDATE SET "updated_at"="excluded"."updated_at","time_zone"="excluded"."time_zone","weekly_digest_email"="excluded"."weekly_digest_email","weekly_digest_push"="excluded"."weekly_digest_push" RETURNING "id"`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, u.ID, "UTC", false, true).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		var resp struct {
+			UpdateNotificationPreferences struct {
+				WeeklyDigestEmail bool
+				WeeklyDigestPush  bool
+			}
+		}
+		c.MustPost(`mutation { updateNotificationPreferences(preferences: {weeklyDigestEmail: false}) { weeklyDigestEmail weeklyDigestPush } }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.False(t, resp.UpdateNotificationPreferences.WeeklyDigestEmail)
+		require.True(t, resp.UpdateNotificationPreferences.WeeklyDigestPush)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Register Push Token", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectUser(mock)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "push_tokens" ("created_at","updated_at","deleted_at","user_id","token") VALUES ($1,$2,$3,$4,$5) ON CONFLICT ("token") DO UPDATE SET "updated_at"="excluded"."updated_at","user_id"="excluded"."user_id" RETURNING "id"`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, u.ID, "device-token").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		var resp struct {
+			RegisterPushToken bool
+		}
+		c.MustPost(`mutation { registerPushToken(token: "device-token") }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.True(t, resp.RegisterPushToken)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
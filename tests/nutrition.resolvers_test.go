@@ -0,0 +1,163 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type LogNutritionResp struct {
+	LogNutrition struct {
+		ID       string
+		Day      string
+		Calories *int
+		Protein  *float64
+		Notes    string
+	}
+}
+
+type NutritionLogsResp struct {
+	NutritionLogs []struct {
+		ID       string
+		Day      string
+		Calories *int
+		Protein  *float64
+	}
+}
+
+func TestNutritionResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	day := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Log Nutrition", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		mock.ExpectBegin()
+		const upsertStmt = `INSERT INTO "nutrition_logs" ("created_at","updated_at","deleted_at","user_id","day","calories","protein_grams","notes") VALUES ($1,$2,$3,$4,$5,$6,$7,$8) ON CONFLICT ("user_id","day") DO UPDATE SET "calories"="excluded"."calories","protein_grams"="excluded"."protein_grams","notes"="excluded"."notes","updated_at"="excluded"."updated_at","deleted_at"="excluded"."deleted_at" RETURNING "id"`
+		mock.ExpectQuery(regexp.QuoteMeta(upsertStmt)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, u.ID, day, uint(2500), float32(180), "").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+		mock.ExpectCommit()
+
+		// local midnight west of UTC is still the 1st
+		var resp LogNutritionResp
+		c.MustPost(`
+			mutation LogNutrition {
+				logNutrition(nutrition: { day: "2022-10-01T00:00:00-07:00", calories: 2500, protein: 180 }) {
+					id
+					day
+					calories
+					protein
+					notes
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, "5", resp.LogNutrition.ID)
+		require.Equal(t, "2022-10-01T00:00:00Z", resp.LogNutrition.Day)
+		require.Equal(t, 2500, *resp.LogNutrition.Calories)
+		require.Equal(t, float64(180), *resp.LogNutrition.Protein)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Log Nutrition Nothing Logged", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		var resp LogNutritionResp
+		err := c.Post(`
+			mutation LogNutrition {
+				logNutrition(nutrition: { day: "2022-10-01T00:00:00Z" }) {
+					id
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"log at least one of calories, protein or notes","path":["logNutrition"],"extensions":{"code":"INVALID_INPUT","fields":[{"field":"nutrition","message":"log at least one of calories, protein or notes"}]}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Get Nutrition Logs", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		const nutritionLogsQuery = `SELECT * FROM "nutrition_logs" WHERE (user_id = $1 AND day >= $2 AND day < $3) AND "nutrition_logs"."deleted_at" IS NULL ORDER BY day`
+		mock.ExpectQuery(regexp.QuoteMeta(nutritionLogsQuery)).
+			WithArgs(fmt.Sprintf("%d", u.ID), day, day.AddDate(0, 0, 7)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "day", "calories", "protein_grams", "notes"}).
+				AddRow(5, u.ID, day, 2500, 180, "").
+				AddRow(6, u.ID, day.AddDate(0, 0, 1), nil, 150, "rest day"))
+
+		var resp NutritionLogsResp
+		c.MustPost(`
+			query NutritionLogs {
+				nutritionLogs(range: { start: "2022-10-01T00:00:00Z", end: "2022-10-08T00:00:00Z" }) {
+					id
+					day
+					calories
+					protein
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Len(t, resp.NutritionLogs, 2)
+		require.Equal(t, 2500, *resp.NutritionLogs[0].Calories)
+		require.Nil(t, resp.NutritionLogs[1].Calories)
+		require.Equal(t, float64(150), *resp.NutritionLogs[1].Protein)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Get Nutrition Logs Range Too Long", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		var resp NutritionLogsResp
+		err := c.Post(`
+			query NutritionLogs {
+				nutritionLogs(range: { start: "2020-10-01T00:00:00Z", end: "2022-10-08T00:00:00Z" }) {
+					id
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Getting Nutrition Logs: range can be at most 366 days","path":["nutritionLogs"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
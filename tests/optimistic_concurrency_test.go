@@ -0,0 +1,116 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type UpdateExerciseWithVersionResp struct {
+	UpdateExercise struct {
+		ID        string
+		Notes     string
+		UpdatedAt string
+	}
+}
+
+func TestOptimisticConcurrency(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	e := testdata.WorkoutSession.Exercises[0]
+	ws := testdata.WorkoutSession
+
+	readAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	changedAt := readAt.Add(time.Minute)
+
+	const getExerciseQuery = `SELECT * FROM "exercises" WHERE "exercises"."deleted_at" IS NULL AND "exercises"."id" = $1 ORDER BY "exercises"."id" LIMIT 1`
+	const updateExerciseStmt = `UPDATE "exercises" SET "updated_at"=$1,"notes"=$2 WHERE (id = $3 AND user_id = $4) AND exercises.updated_at = $5 AND "exercises"."deleted_at" IS NULL RETURNING *`
+
+	expectEditableExercise := func(mock sqlmock.Sqlmock, updatedAt time.Time) {
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		exerciseRow := sqlmock.
+			NewRows([]string{"id", "updated_at", "workout_session_id", "exercise_routine_id", "user_id", "notes"}).
+			AddRow(e.ID, updatedAt, ws.ID, e.ExerciseRoutineID, u.ID, "felt heavy")
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.ExerciseForUserQuery)).WithArgs(fmt.Sprintf("%d", e.ID), fmt.Sprintf("%d", u.ID)).WillReturnRows(exerciseRow)
+		helpers.ExpectEditableWorkoutSession(mock, ws.ID)
+	}
+
+	gqlQuery := fmt.Sprintf(`
+		mutation UpdateExercise {
+			updateExercise(exerciseId: "%d", exercise: { notes: "paused reps", expectedUpdatedAt: "%s" }) {
+				id
+				notes
+				updatedAt
+			}
+		}`,
+		e.ID,
+		readAt.Format(time.RFC3339Nano),
+	)
+
+	t.Run("Update Exercise Unchanged Since Read", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectEditableExercise(mock, readAt)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(updateExerciseStmt)).
+			WithArgs(sqlmock.AnyArg(), "paused reps", fmt.Sprintf("%d", e.ID), fmt.Sprintf("%d", u.ID), readAt).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "workout_session_id", "notes", "updated_at"}).AddRow(e.ID, ws.ID, "paused reps", changedAt))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "workout_sessions" SET "updated_at"=$1 WHERE id IN ($2) AND "workout_sessions"."deleted_at" IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), ws.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		var resp UpdateExerciseWithVersionResp
+		c.MustPost(gqlQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, "paused reps", resp.UpdateExercise.Notes)
+		require.Equal(t, changedAt.Format(time.RFC3339), resp.UpdateExercise.UpdatedAt)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Update Exercise Changed Since Read", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectEditableExercise(mock, changedAt)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(updateExerciseStmt)).
+			WithArgs(sqlmock.AnyArg(), "paused reps", fmt.Sprintf("%d", e.ID), fmt.Sprintf("%d", u.ID), readAt).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "notes", "updated_at"}))
+		mock.ExpectRollback()
+		mock.ExpectQuery(regexp.QuoteMeta(getExerciseQuery)).
+			WithArgs(e.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "notes", "updated_at"}).AddRow(e.ID, "felt heavy", changedAt))
+
+		var resp UpdateExerciseWithVersionResp
+		err := c.Post(gqlQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, fmt.Sprintf(`[{"message":"Error Updating Exercise: Changed Since It Was Read","path":["updateExercise"],"extensions":{"code":"CONFLICT","current":{"id":"%d","notes":"felt heavy","updatedAt":"%s"}}}]`, e.ID, changedAt.Format(time.RFC3339)))
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
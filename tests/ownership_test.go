@@ -0,0 +1,197 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+// reads and writes on exercises and sets are scoped to the user in the same
+// query, there's no separate access check to forget. Coaches are only let in
+// after that query comes up empty
+func TestOwnershipScopedQueries(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	userId := fmt.Sprintf("%d", u.ID)
+	const setForUserQuery = `SELECT set_entries.*, exercises.workout_session_id, exercises.user_id AS logged_by FROM "set_entries" JOIN exercises ON exercises.id = set_entries.exercise_id AND exercises.deleted_at IS NULL WHERE (set_entries.id = $1 AND exercises.user_id = $2) AND "set_entries"."deleted_at" IS NULL LIMIT 1`
+	const userSetQuery = `SELECT set_entries.*, exercises.workout_session_id, exercises.user_id AS logged_by FROM "set_entries" JOIN exercises ON exercises.id = set_entries.exercise_id AND exercises.deleted_at IS NULL WHERE set_entries.id = $1 AND "set_entries"."deleted_at" IS NULL LIMIT 1`
+	const exerciseQuery = `SELECT * FROM "exercises" WHERE "exercises"."deleted_at" IS NULL AND "exercises"."id" = $1 ORDER BY "exercises"."id" LIMIT 1`
+	const coachQuery = `SELECT * FROM "coaches" WHERE (user_id = $1 AND coach_id = $2) AND "coaches"."deleted_at" IS NULL ORDER BY "coaches"."id" LIMIT 1`
+	clientId := u.ID + 1
+
+	expectUser := func(mock sqlmock.Sqlmock) {
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+	}
+
+	t.Run("Delete Set", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectUser(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(setForUserQuery)).
+			WithArgs("9", userId).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "exercise_id", "reps", "workout_session_id", "logged_by"}).AddRow(9, 4, 5, 7, u.ID))
+		helpers.ExpectEditableWorkoutSession(mock, 7)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "set_entries" SET "deleted_at"=$1 WHERE (id = $2 AND exercise_id IN (SELECT id FROM exercises WHERE user_id = $3 AND deleted_at IS NULL)) AND "set_entries"."deleted_at" IS NULL RETURNING *`)).
+			WithArgs(sqlmock.AnyArg(), "9", userId).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "exercise_id"}).AddRow(9, 4))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.TouchExerciseStmt)).
+			WithArgs(sqlmock.AnyArg(), 4).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.TouchExerciseSessionStmt)).
+			WithArgs(sqlmock.AnyArg(), 4).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		var resp struct {
+			DeleteSet int
+		}
+		c.MustPost(`mutation { deleteSet(setId: "9") }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, 1, resp.DeleteSet)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Someone Else's Set Is Not Found", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectUser(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(setForUserQuery)).
+			WithArgs("9", userId).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery(regexp.QuoteMeta(userSetQuery)).
+			WithArgs("9").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		var resp struct{}
+		err := c.Post(`mutation { updateSet(setId: "9", set: {reps: 6}) { id } }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Updating Set","path":["updateSet"],"extensions":{"code":"NOT_FOUND"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Coach With Read Write Access Deletes A Client's Set", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectUser(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(setForUserQuery)).
+			WithArgs("9", userId).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery(regexp.QuoteMeta(userSetQuery)).
+			WithArgs("9").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "exercise_id", "reps", "workout_session_id", "logged_by"}).AddRow(9, 4, 5, 7, clientId))
+		mock.ExpectQuery(regexp.QuoteMeta(exerciseQuery)).
+			WithArgs(4).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "workout_session_id", "user_id"}).AddRow(4, 7, clientId))
+		mock.ExpectQuery(regexp.QuoteMeta(coachQuery)).
+			WithArgs(fmt.Sprintf("%d", clientId), userId).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "coach_id", "access"}).AddRow(1, clientId, u.ID, "READ_WRITE"))
+		helpers.ExpectEditableWorkoutSession(mock, 7)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "set_entries" SET "deleted_at"=$1 WHERE (id = $2 AND exercise_id IN (SELECT id FROM exercises WHERE user_id = $3 AND deleted_at IS NULL)) AND "set_entries"."deleted_at" IS NULL RETURNING *`)).
+			WithArgs(sqlmock.AnyArg(), "9", fmt.Sprintf("%d", clientId)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "exercise_id"}).AddRow(9, 4))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.TouchExerciseStmt)).
+			WithArgs(sqlmock.AnyArg(), 4).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.TouchExerciseSessionStmt)).
+			WithArgs(sqlmock.AnyArg(), 4).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		var resp struct {
+			DeleteSet int
+		}
+		c.MustPost(`mutation { deleteSet(setId: "9") }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, 1, resp.DeleteSet)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Coach With Read Access Can't Change A Client's Set", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectUser(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(setForUserQuery)).
+			WithArgs("9", userId).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery(regexp.QuoteMeta(userSetQuery)).
+			WithArgs("9").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "exercise_id", "reps", "workout_session_id", "logged_by"}).AddRow(9, 4, 5, 7, clientId))
+		mock.ExpectQuery(regexp.QuoteMeta(exerciseQuery)).
+			WithArgs(4).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "workout_session_id", "user_id"}).AddRow(4, 7, clientId))
+		mock.ExpectQuery(regexp.QuoteMeta(coachQuery)).
+			WithArgs(fmt.Sprintf("%d", clientId), userId).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "coach_id", "access"}).AddRow(1, clientId, u.ID, "READ"))
+
+		var resp struct{}
+		err := c.Post(`mutation { updateSet(setId: "9", set: {reps: 6}) { id } }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Updating Set","path":["updateSet"],"extensions":{"code":"NOT_FOUND"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Training Partner Reads Sets", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectUser(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "exercises"."id","exercises"."created_at"`)).
+			WithArgs("4", userId, userId).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "workout_session_id", "user_id"}).AddRow(4, 7, u.ID+1))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "set_entries" WHERE exercise_id = $1 AND "set_entries"."deleted_at" IS NULL ORDER BY set_order, id LIMIT 100`)).
+			WithArgs("4").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "exercise_id", "reps", "set_order"}).AddRow(9, 4, 5, 1))
+
+		var resp struct {
+			Sets []struct {
+				ID   string
+				Reps int
+			}
+		}
+		c.MustPost(`query { sets(exerciseId: "4") { id reps } }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Len(t, resp.Sets, 1)
+		require.Equal(t, 5, resp.Sets[0].Reps)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
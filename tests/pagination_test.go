@@ -0,0 +1,123 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExercisesAndSetsPagination(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	userId := fmt.Sprintf("%d", u.ID)
+
+	expectUser := func(mock sqlmock.Sqlmock) {
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+	}
+
+	t.Run("Exercises After A Cursor", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectUser(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_sessions" WHERE id = $1 AND "workout_sessions"."deleted_at" IS NULL ORDER BY "workout_sessions"."id" LIMIT 1`)).
+			WithArgs("7").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(7, u.ID))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "exercises" WHERE workout_session_id = $1 AND id > $2 AND "exercises"."deleted_at" IS NULL ORDER BY id LIMIT 2`)).
+			WithArgs("7", "4").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "workout_session_id", "user_id"}).AddRow(5, 7, u.ID).AddRow(6, 7, u.ID))
+
+		var resp struct {
+			Exercises []struct {
+				ID string
+			}
+		}
+		c.MustPost(`query { exercises(workoutSessionId: "7", limit: 2, after: "4") { id } }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Len(t, resp.Exercises, 2)
+		require.Equal(t, "5", resp.Exercises[0].ID)
+		require.Equal(t, "6", resp.Exercises[1].ID)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Sets After A Cursor", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectUser(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "exercises"."id","exercises"."created_at"`)).
+			WithArgs("4", userId, userId).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "workout_session_id", "user_id"}).AddRow(4, 7, u.ID))
+		// set order comes before id, sets added out of order still page in order
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "set_entries" WHERE exercise_id = $1 AND ((set_order, id) > (SELECT set_order, id FROM set_entries WHERE id = $2 AND exercise_id = $3)) AND "set_entries"."deleted_at" IS NULL ORDER BY set_order, id LIMIT 1`)).
+			WithArgs("4", "9", "4").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "exercise_id", "reps", "set_order"}).AddRow(8, 4, 5, 2))
+
+		var resp struct {
+			Sets []struct {
+				ID string
+			}
+		}
+		c.MustPost(`query { sets(exerciseId: "4", limit: 1, after: "9") { id } }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Len(t, resp.Sets, 1)
+		require.Equal(t, "8", resp.Sets[0].ID)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Limit Out Of Range", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectUser(mock)
+
+		var resp struct{}
+		err := c.Post(`query { sets(exerciseId: "4", limit: 201) { id } }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Getting Sets: limit needs to be between 1 and 200","path":["sets"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Invalid Cursor", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectUser(mock)
+
+		var resp struct{}
+		err := c.Post(`query { exercises(workoutSessionId: "7", after: "abc") { id } }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Getting Exercises: Invalid Cursor","path":["exercises"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,41 @@
+package test
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetEntryPartitions(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2022, 11, 15, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Ensure Partitions Creates Upcoming Months", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+
+		for _, month := range []string{"2022-11", "2022-12", "2023-01"} {
+			m, _ := time.Parse("2006-01", month)
+			stmt := "CREATE TABLE IF NOT EXISTS " + database.SetEntryPartitionName(m) + " PARTITION OF set_entries FOR VALUES FROM ('" +
+				m.Format(time.RFC3339) + "') TO ('" + m.AddDate(0, 1, 0).Format(time.RFC3339) + "')"
+			mock.ExpectExec(regexp.QuoteMeta(stmt)).WillReturnResult(sqlmock.NewResult(0, 0))
+		}
+
+		err := database.EnsureSetEntryPartitions(gormDB, now, 2)
+		require.NoError(t, err)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Partition Name", func(t *testing.T) {
+		require.Equal(t, "set_entries_y2022m03", database.SetEntryPartitionName(time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)))
+	})
+}
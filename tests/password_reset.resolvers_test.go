@@ -0,0 +1,239 @@
+package test
+
+import (
+	"database/sql/driver"
+	"net/url"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph"
+	"github.com/neilZon/workout-logger-api/graph/generated"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// outbox is a mail.Mailer that keeps what it was asked to send
+type outbox struct {
+	mu     sync.Mutex
+	bodies []string
+}
+
+func (o *outbox) Send(to []string, subject string, body string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.bodies = append(o.bodies, body)
+	return nil
+}
+
+// capturedArg matches anything and keeps it
+type capturedArg struct {
+	value driver.Value
+}
+
+func (a *capturedArg) Match(v driver.Value) bool {
+	a.value = v
+	return true
+}
+
+func newPasswordResetClient(gormDB *gorm.DB, mailer *outbox) *client.Client {
+	srv := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{Resolvers: &graph.Resolver{
+		DB:     gormDB,
+		Mailer: mailer,
+	}}))
+	srv.SetErrorPresenter(errors.Present)
+	return client.New(srv)
+}
+
+type RequestPasswordResetResp struct {
+	RequestPasswordReset bool
+}
+
+type ResetPasswordResp struct {
+	ResetPassword bool
+}
+
+func TestPasswordReset(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	const email = "lifter@test.com"
+	const userByEmailQuery = `SELECT * FROM "users" WHERE email = $1 AND "users"."deleted_at" IS NULL ORDER BY "users"."id" LIMIT 1`
+	const countQuery = `SELECT count(*) FROM "password_reset_tokens" WHERE (user_id = $1 AND created_at > $2) AND "password_reset_tokens"."deleted_at" IS NULL`
+	const tokenQuery = `SELECT * FROM "password_reset_tokens" WHERE (token_hash = $1 AND used_at IS NULL AND expires_at > $2) AND "password_reset_tokens"."deleted_at" IS NULL LIMIT 1 FOR UPDATE`
+
+	t.Run("Request Emails A Link With A Token Stored Hashed", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		mailer := &outbox{}
+		c := newPasswordResetClient(gormDB, mailer)
+
+		mock.ExpectQuery(regexp.QuoteMeta(userByEmailQuery)).
+			WithArgs(email).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(7, email))
+		mock.ExpectQuery(regexp.QuoteMeta(countQuery)).
+			WithArgs(7, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		tokenHash := &capturedArg{}
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "password_reset_tokens" ("created_at","updated_at","deleted_at","user_id","token_hash","expires_at","used_at")`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, 7, tokenHash, sqlmock.AnyArg(), nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		var resp RequestPasswordResetResp
+		c.MustPost(`mutation { requestPasswordReset(email: "`+email+`") }`, &resp)
+		require.True(t, resp.RequestPasswordReset)
+
+		require.Len(t, mailer.bodies, 1)
+		code := regexp.MustCompile(`password-redirect\.html\?code=([^"]+)"`).FindStringSubmatch(mailer.bodies[0])
+		require.Len(t, code, 2)
+		resetToken, err := url.QueryUnescape(code[1])
+		require.Nil(t, err)
+		require.NotEmpty(t, resetToken)
+		require.NotEqual(t, resetToken, tokenHash.value)
+		require.Equal(t, database.HashPasswordResetToken(resetToken), tokenHash.value)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Request For An Unknown Email Looks The Same", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		mailer := &outbox{}
+		c := newPasswordResetClient(gormDB, mailer)
+
+		mock.ExpectQuery(regexp.QuoteMeta(userByEmailQuery)).
+			WithArgs(email).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email"}))
+
+		var resp RequestPasswordResetResp
+		c.MustPost(`mutation { requestPasswordReset(email: "`+email+`") }`, &resp)
+		require.True(t, resp.RequestPasswordReset)
+		require.Empty(t, mailer.bodies)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Request Past The Hourly Limit Sends Nothing", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		mailer := &outbox{}
+		c := newPasswordResetClient(gormDB, mailer)
+
+		mock.ExpectQuery(regexp.QuoteMeta(userByEmailQuery)).
+			WithArgs(email).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(7, email))
+		mock.ExpectQuery(regexp.QuoteMeta(countQuery)).
+			WithArgs(7, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(config.MAX_PASSWORD_RESETS_PER_HOUR))
+
+		var resp RequestPasswordResetResp
+		c.MustPost(`mutation { requestPasswordReset(email: "`+email+`") }`, &resp)
+		require.True(t, resp.RequestPasswordReset)
+		require.Empty(t, mailer.bodies)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Reset Uses Up Every Link", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		c := newPasswordResetClient(gormDB, &outbox{})
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(tokenQuery)).
+			WithArgs(database.HashPasswordResetToken("the-token"), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(1, 7))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "password_reset_tokens" SET "used_at"=$1,"updated_at"=$2 WHERE (user_id = $3 AND used_at IS NULL) AND "password_reset_tokens"."deleted_at" IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 7).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "password"=$1,"updated_at"=$2 WHERE id = $3 AND "users"."deleted_at" IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 7).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		// and logs out everywhere
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "refresh_tokens" SET "revoked_at"=$1,"updated_at"=$2 WHERE (user_id = $3 AND revoked_at IS NULL) AND "refresh_tokens"."deleted_at" IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 7).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "sessions" SET "revoked_at"=$1,"updated_at"=$2 WHERE (user_id = $3 AND revoked_at IS NULL) AND "sessions"."deleted_at" IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 7).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		var resp ResetPasswordResp
+		c.MustPost(`mutation { resetPassword(token: "the-token", newPassword: "password1") }`, &resp)
+		require.True(t, resp.ResetPassword)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Reset With A Used Or Expired Token", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		c := newPasswordResetClient(gormDB, &outbox{})
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(tokenQuery)).
+			WithArgs(database.HashPasswordResetToken("the-token"), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}))
+		mock.ExpectRollback()
+
+		var resp ResetPasswordResp
+		err := c.Post(`mutation { resetPassword(token: "the-token", newPassword: "password1") }`, &resp)
+		require.EqualError(t, err, `[{"message":"Error Resetting Password: Invalid Or Expired Token","path":["resetPassword"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Reset With A Weak Password", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		c := newPasswordResetClient(gormDB, &outbox{})
+
+		var resp ResetPasswordResp
+		err := c.Post(`mutation { resetPassword(token: "the-token", newPassword: "password") }`, &resp)
+		require.EqualError(t, err, `[{"message":"password needs at least 1 number and 8 - 32 characters","path":["resetPassword"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Reset With Old Credentials Passwords Don't Match", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		c := newPasswordResetClient(gormDB, &outbox{})
+
+		var resp ResetPasswordResp
+		err := c.Post(`mutation {
+			resetPassword(passwordResetCredentials: {code: "the-token", password: "password1", confirmPassword: "password2"})
+		}`, &resp)
+		require.EqualError(t, err, `[{"message":"passwords don't match","path":["resetPassword"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
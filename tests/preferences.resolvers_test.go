@@ -0,0 +1,138 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type PreferencesResp struct {
+	WeightUnit    string
+	WeekStart     string
+	TimeZone      string
+	Notifications struct {
+		WeeklyDigestEmail bool
+		WeeklyDigestPush  bool
+	}
+}
+
+func TestPreferencesResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	userId := fmt.Sprintf("%d", u.ID)
+	userColumns := []string{"id", "verified", "locale", "weight_unit", "week_start"}
+	const preferencesQuery = `SELECT * FROM "user_preferences" WHERE user_id = $1 AND "user_preferences"."deleted_at" IS NULL LIMIT 1`
+	const preferencesFields = `{ weightUnit weekStart timeZone notifications { weeklyDigestEmail weeklyDigestPush } }`
+
+	t.Run("Preferences", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		for i := 0; i < 2; i++ {
+			mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).
+				WithArgs(userId).
+				WillReturnRows(sqlmock.NewRows(userColumns).AddRow(u.ID, true, "de-DE", nil, "SUNDAY"))
+		}
+		mock.ExpectQuery(regexp.QuoteMeta(preferencesQuery)).
+			WithArgs(u.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "time_zone", "weekly_digest_email", "weekly_digest_push"}).
+				AddRow(1, u.ID, "Europe/Berlin", true, false))
+
+		var resp struct {
+			Preferences PreferencesResp
+		}
+		c.MustPost(`query { preferences `+preferencesFields+` }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		// the locale picks the unit until one is set
+		require.Equal(t, "KG", resp.Preferences.WeightUnit)
+		require.Equal(t, "SUNDAY", resp.Preferences.WeekStart)
+		require.Equal(t, "Europe/Berlin", resp.Preferences.TimeZone)
+		require.True(t, resp.Preferences.Notifications.WeeklyDigestEmail)
+		require.False(t, resp.Preferences.Notifications.WeeklyDigestPush)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Update Preferences", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).
+			WithArgs(userId).
+			WillReturnRows(sqlmock.NewRows(userColumns).AddRow(u.ID, true, "en-US", nil, "MONDAY"))
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "updated_at"=$1,"week_start"=$2,"weight_unit"=$3 WHERE id = $4 AND "users"."deleted_at" IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), "SATURDAY", "KG", userId).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery(regexp.QuoteMeta(preferencesQuery)).
+			WithArgs(u.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "user_preferences"`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, u.ID, "Asia/Tokyo", true, false).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).
+			WithArgs(userId).
+			WillReturnRows(sqlmock.NewRows(userColumns).AddRow(u.ID, true, "en-US", "KG", "SATURDAY"))
+
+		var resp struct {
+			UpdatePreferences PreferencesResp
+		}
+		c.MustPost(`mutation {
+			updatePreferences(preferences: {
+				weightUnit: KG
+				weekStart: SATURDAY
+				timeZone: "Asia/Tokyo"
+				notifications: {weeklyDigestPush: false}
+			}) `+preferencesFields+`
+		}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, "KG", resp.UpdatePreferences.WeightUnit)
+		require.Equal(t, "SATURDAY", resp.UpdatePreferences.WeekStart)
+		require.Equal(t, "Asia/Tokyo", resp.UpdatePreferences.TimeZone)
+		require.True(t, resp.UpdatePreferences.Notifications.WeeklyDigestEmail)
+		require.False(t, resp.UpdatePreferences.Notifications.WeeklyDigestPush)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Unknown Time Zone", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).
+			WithArgs(userId).
+			WillReturnRows(sqlmock.NewRows(userColumns).AddRow(u.ID, true, "en-US", nil, "MONDAY"))
+
+		var resp struct{}
+		err := c.Post(`mutation { updatePreferences(preferences: {timeZone: "Mars/Olympus_Mons"}) { timeZone } }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Updating Preferences: Unknown Time Zone \"Mars/Olympus_Mons\"","path":["updatePreferences"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,435 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/clock"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type ProgramResp struct {
+	ID     string
+	Name   string
+	Active bool
+	Days   []struct {
+		Weekday        string
+		WorkoutRoutine struct {
+			ID   string
+			Name string
+		}
+	}
+}
+
+type TodaysWorkoutResp struct {
+	TodaysWorkout *struct {
+		Day            string
+		Weekday        string
+		WorkoutRoutine struct {
+			ID string
+		}
+		PrevExercises []struct {
+			ID string
+		}
+	}
+}
+
+const programQuery = `SELECT * FROM "programs" WHERE id = $1 AND "programs"."deleted_at" IS NULL ORDER BY "programs"."id" LIMIT 1`
+
+var (
+	programColumns        = []string{"id", "user_id", "name", "active"}
+	programDayColumns     = []string{"id", "program_id", "weekday", "workout_routine_id"}
+	workoutRoutineColumns = []string{"id", "name", "active", "user_id"}
+)
+
+func TestProgramResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	userId := fmt.Sprintf("%d", u.ID)
+	wr := testdata.WorkoutRoutine
+
+	const createProgram = `
+		mutation CreateProgram($program: ProgramInput!) {
+			createProgram(program: $program) {
+				id
+				name
+				active
+				days {
+					weekday
+					workoutRoutine {
+						id
+						name
+					}
+				}
+			}
+		}`
+
+	t.Run("Create Program", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+
+		// the routine is on two days but only checked once
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).
+			WithArgs(fmt.Sprintf("%d", wr.ID)).
+			WillReturnRows(sqlmock.NewRows(workoutRoutineColumns).AddRow(wr.ID, wr.Name, true, u.ID))
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "programs" SET "active"=$1,"updated_at"=$2 WHERE (user_id = $3 AND active) AND "programs"."deleted_at" IS NULL`)).
+			WithArgs(false, sqlmock.AnyArg(), u.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "programs" ("created_at","updated_at","deleted_at","user_id","name","active") VALUES ($1,$2,$3,$4,$5,$6) RETURNING "id"`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, u.ID, "PPL", true).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "program_days" ("created_at","updated_at","deleted_at","program_id","weekday","workout_routine_id") VALUES ($1,$2,$3,$4,$5,$6),($7,$8,$9,$10,$11,$12)`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, 2, "MONDAY", wr.ID, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, 2, "THURSDAY", wr.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+		mock.ExpectCommit()
+
+		mock.ExpectQuery(regexp.QuoteMeta(programQuery)).
+			WithArgs("2").
+			WillReturnRows(sqlmock.NewRows(programColumns).AddRow(2, u.ID, "PPL", true))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "program_days" WHERE "program_days"."program_id" = $1 AND "program_days"."deleted_at" IS NULL ORDER BY CASE weekday`)).
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows(programDayColumns).AddRow(1, 2, "MONDAY", wr.ID).AddRow(2, 2, "THURSDAY", wr.ID))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_routines" WHERE "workout_routines"."id" = $1 AND "workout_routines"."deleted_at" IS NULL`)).
+			WithArgs(wr.ID).
+			WillReturnRows(sqlmock.NewRows(workoutRoutineColumns).AddRow(wr.ID, wr.Name, true, u.ID))
+
+		var resp struct{ CreateProgram ProgramResp }
+		c.MustPost(createProgram, &resp,
+			client.Var("program", map[string]interface{}{
+				"name": "PPL",
+				"days": []map[string]interface{}{
+					{"weekday": "MONDAY", "workoutRoutineId": fmt.Sprintf("%d", wr.ID)},
+					{"weekday": "THURSDAY", "workoutRoutineId": fmt.Sprintf("%d", wr.ID)},
+				},
+			}),
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.Equal(t, "2", resp.CreateProgram.ID)
+		require.True(t, resp.CreateProgram.Active)
+		require.Len(t, resp.CreateProgram.Days, 2)
+		require.Equal(t, "THURSDAY", resp.CreateProgram.Days[1].Weekday)
+		require.Equal(t, wr.Name, resp.CreateProgram.Days[1].WorkoutRoutine.Name)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("A Day Only Has One Routine", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		var resp struct{ CreateProgram ProgramResp }
+		err := c.Post(createProgram, &resp,
+			client.Var("program", map[string]interface{}{
+				"name": "PPL",
+				"days": []map[string]interface{}{
+					{"weekday": "MONDAY", "workoutRoutineId": "8"},
+					{"weekday": "MONDAY", "workoutRoutineId": "9"},
+				},
+			}),
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.EqualError(t, err, `[{"message":"monday already has a workout routine","path":["createProgram"],"extensions":{"code":"INVALID_INPUT","fields":[{"field":"program.days.1.weekday","message":"monday already has a workout routine"}]}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Cannot Schedule Another Users Routine", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).
+			WithArgs("9").
+			WillReturnRows(sqlmock.NewRows(workoutRoutineColumns).AddRow(9, "Pull", true, u.ID+1))
+
+		var resp struct{ CreateProgram ProgramResp }
+		err := c.Post(createProgram, &resp,
+			client.Var("program", map[string]interface{}{
+				"name": "PPL",
+				"days": []map[string]interface{}{
+					{"weekday": "MONDAY", "workoutRoutineId": "9"},
+				},
+			}),
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.EqualError(t, err, `[{"message":"Error Creating Program: Access Denied","path":["createProgram"],"extensions":{"code":"FORBIDDEN"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Update Program Replaces Its Days", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(programQuery)).
+			WithArgs("2").
+			WillReturnRows(sqlmock.NewRows(programColumns).AddRow(2, u.ID, "PPL", true))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "program_days"`)).
+			WillReturnRows(sqlmock.NewRows(programDayColumns))
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).
+			WithArgs(fmt.Sprintf("%d", wr.ID)).
+			WillReturnRows(sqlmock.NewRows(workoutRoutineColumns).AddRow(wr.ID, wr.Name, true, u.ID))
+
+		// made inactive so the others are left alone
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "programs" SET "active"=$1,"name"=$2,"updated_at"=$3 WHERE id = $4 AND "programs"."deleted_at" IS NULL`)).
+			WithArgs(false, "Upper Lower", sqlmock.AnyArg(), "2").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "program_days" WHERE program_id = $1`)).
+			WithArgs("2").
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "program_days"`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, 2, "FRIDAY", wr.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectCommit()
+
+		mock.ExpectQuery(regexp.QuoteMeta(programQuery)).
+			WithArgs("2").
+			WillReturnRows(sqlmock.NewRows(programColumns).AddRow(2, u.ID, "Upper Lower", false))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "program_days"`)).
+			WillReturnRows(sqlmock.NewRows(programDayColumns).AddRow(3, 2, "FRIDAY", wr.ID))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_routines"`)).
+			WillReturnRows(sqlmock.NewRows(workoutRoutineColumns).AddRow(wr.ID, wr.Name, true, u.ID))
+
+		var resp struct{ UpdateProgram ProgramResp }
+		c.MustPost(`
+			mutation UpdateProgram($program: ProgramInput!) {
+				updateProgram(programId: "2", program: $program) {
+					id
+					name
+					active
+					days {
+						weekday
+					}
+				}
+			}`, &resp,
+			client.Var("program", map[string]interface{}{
+				"name":   "Upper Lower",
+				"active": false,
+				"days": []map[string]interface{}{
+					{"weekday": "FRIDAY", "workoutRoutineId": fmt.Sprintf("%d", wr.ID)},
+				},
+			}),
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.Equal(t, "Upper Lower", resp.UpdateProgram.Name)
+		require.False(t, resp.UpdateProgram.Active)
+		require.Len(t, resp.UpdateProgram.Days, 1)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Deleted Routines Drop Off The Schedule", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "programs" WHERE user_id = $1 AND "programs"."deleted_at" IS NULL ORDER BY active DESC, id DESC`)).
+			WithArgs(userId).
+			WillReturnRows(sqlmock.NewRows(programColumns).AddRow(2, u.ID, "PPL", true))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "program_days"`)).
+			WillReturnRows(sqlmock.NewRows(programDayColumns).AddRow(1, 2, "MONDAY", wr.ID).AddRow(2, 2, "TUESDAY", 9))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_routines" WHERE "workout_routines"."id" IN ($1,$2)`)).
+			WillReturnRows(sqlmock.NewRows(workoutRoutineColumns).AddRow(wr.ID, wr.Name, true, u.ID))
+
+		var resp struct{ Programs []ProgramResp }
+		c.MustPost(`
+			query Programs {
+				programs {
+					id
+					days {
+						weekday
+						workoutRoutine {
+							id
+						}
+					}
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Len(t, resp.Programs, 1)
+		require.Len(t, resp.Programs[0].Days, 1)
+		require.Equal(t, "MONDAY", resp.Programs[0].Days[0].Weekday)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
+
+// not parallel, the clock is frozen
+func TestTodaysWorkout(t *testing.T) {
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	userId := fmt.Sprintf("%d", u.ID)
+	wr := testdata.WorkoutRoutine
+
+	// wednesday in UTC, still tuesday in los angeles
+	clock.Freeze(time.Date(2022, 11, 2, 3, 0, 0, 0, time.UTC))
+	defer clock.Reset()
+
+	const todaysWorkout = `
+		query TodaysWorkout($timeZone: String) {
+			todaysWorkout(timeZone: $timeZone) {
+				day
+				weekday
+				workoutRoutine {
+					id
+				}
+				prevExercises {
+					id
+				}
+			}
+		}`
+
+	// preferredTimeZone is looked up when the query doesn't send one
+	expectActiveProgram := func(mock sqlmock.Sqlmock, preferredTimeZone string) {
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		if preferredTimeZone != "" {
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "user_preferences" WHERE user_id = $1`)).
+				WithArgs(u.ID).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "time_zone"}).AddRow(1, u.ID, preferredTimeZone))
+		}
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "programs" WHERE (user_id = $1 AND active) AND "programs"."deleted_at" IS NULL ORDER BY "programs"."id" LIMIT 1`)).
+			WithArgs(userId).
+			WillReturnRows(sqlmock.NewRows(programColumns).AddRow(2, u.ID, "PPL", true))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "program_days"`)).
+			WillReturnRows(sqlmock.NewRows(programDayColumns).AddRow(1, 2, "TUESDAY", wr.ID))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_routines"`)).
+			WillReturnRows(sqlmock.NewRows(workoutRoutineColumns).AddRow(wr.ID, wr.Name, true, u.ID))
+	}
+
+	t.Run("Scheduled Routine In The Users Time Zone", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectActiveProgram(mock, "")
+		la, err := time.LoadLocation("America/Los_Angeles")
+		require.NoError(t, err)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * from (`)).
+			WithArgs(0, fmt.Sprintf("%d", wr.ID), time.Date(2022, 11, 1, 0, 0, 0, 0, la).UTC()).
+			WillReturnRows(sqlmock.NewRows([]string{"n", "id", "notes", "user_id"}).AddRow(0, 44, "", u.ID))
+
+		var resp TodaysWorkoutResp
+		c.MustPost(todaysWorkout, &resp,
+			client.Var("timeZone", "America/Los_Angeles"),
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.NotNil(t, resp.TodaysWorkout)
+		require.Equal(t, "TUESDAY", resp.TodaysWorkout.Weekday)
+		require.Equal(t, "2022-11-01T00:00:00-07:00", resp.TodaysWorkout.Day)
+		require.Equal(t, fmt.Sprintf("%d", wr.ID), resp.TodaysWorkout.WorkoutRoutine.ID)
+		require.Len(t, resp.TodaysWorkout.PrevExercises, 1)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Scheduled Routine In The Preferred Time Zone", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectActiveProgram(mock, "America/Los_Angeles")
+		la, err := time.LoadLocation("America/Los_Angeles")
+		require.NoError(t, err)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * from (`)).
+			WithArgs(0, fmt.Sprintf("%d", wr.ID), time.Date(2022, 11, 1, 0, 0, 0, 0, la).UTC()).
+			WillReturnRows(sqlmock.NewRows([]string{"n", "id", "notes", "user_id"}).AddRow(0, 44, "", u.ID))
+
+		var resp TodaysWorkoutResp
+		c.MustPost(todaysWorkout, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.NotNil(t, resp.TodaysWorkout)
+		require.Equal(t, "TUESDAY", resp.TodaysWorkout.Weekday)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Rest Day", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectActiveProgram(mock, "UTC")
+
+		var resp TodaysWorkoutResp
+		c.MustPost(todaysWorkout, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Nil(t, resp.TodaysWorkout)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Unknown Time Zone", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+
+		var resp TodaysWorkoutResp
+		err := c.Post(todaysWorkout, &resp,
+			client.Var("timeZone", "Mars/Olympus_Mons"),
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.EqualError(t, err, `[{"message":"Error Getting Today's Workout: Unknown Time Zone \"Mars/Olympus_Mons\"","path":["todaysWorkout"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,259 @@
+package test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/media"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type CreateProgressPhotoUploadResp struct {
+	CreateProgressPhotoUpload struct {
+		ProgressPhotoId string
+		UploadUrl       string
+		ContentType     string
+		MaxBytes        int
+	}
+}
+
+type CompleteProgressPhotoUploadResp struct {
+	CompleteProgressPhotoUpload struct {
+		ID         string
+		TakenOn    string
+		BodyWeight *float64
+		Url        string
+	}
+}
+
+// not parallel since the photo dir and signing secret come from the
+// environment
+func TestProgressPhotoResolvers(t *testing.T) {
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	userId := fmt.Sprintf("%d", u.ID)
+	dir := t.TempDir()
+	t.Setenv(config.HOST, "")
+	t.Setenv(config.PHOTO_DIR, dir)
+	t.Setenv(config.PHOTO_SIGNING_SECRET, "photo-secret")
+
+	// the resolvers sign with the same store the handler checks against
+	store := media.NewSignedLocalStore(dir, "", "/photos", "photo-secret")
+	photoServer := httptest.NewServer(&media.SignedURLHandler{Store: store})
+	defer photoServer.Close()
+
+	takenOn := time.Date(2022, 10, 30, 0, 0, 0, 0, time.UTC)
+	photoColumns := []string{"id", "user_id", "storage_key", "content_type", "taken_on", "body_weight", "uploaded_at"}
+	const getPhotoQuery = `SELECT * FROM "progress_photos" WHERE id = $1 AND "progress_photos"."deleted_at" IS NULL ORDER BY "progress_photos"."id" LIMIT 1`
+
+	put := func(url string, contentType string, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPut, photoServer.URL+url, strings.NewReader(body))
+		require.Nil(t, err)
+		req.Header.Set("Content-Type", contentType)
+		resp, err := http.DefaultClient.Do(req)
+		require.Nil(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	t.Run("Upload Complete And List", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "progress_photos" ("created_at","updated_at","deleted_at","user_id","storage_key","content_type","taken_on","body_weight","uploaded_at") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9) RETURNING "id"`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, u.ID, sqlmock.AnyArg(), "image/jpeg", takenOn, 81.5, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+		mock.ExpectCommit()
+
+		var createResp CreateProgressPhotoUploadResp
+		c.MustPost(`
+			mutation CreateProgressPhotoUpload {
+				createProgressPhotoUpload(photo: { contentType: "image/jpeg", takenOn: "2022-10-30T18:00:00Z", bodyWeight: 81.5 }) {
+					progressPhotoId
+					uploadUrl
+					contentType
+					maxBytes
+				}
+			}`, &createResp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		upload := createResp.CreateProgressPhotoUpload
+		require.Equal(t, "5", upload.ProgressPhotoId)
+		require.Equal(t, config.MAX_PHOTO_BYTES, upload.MaxBytes)
+
+		// the signed url is only good for what it was signed for
+		require.Equal(t, http.StatusBadRequest, put(upload.UploadUrl, "image/png", "photo").StatusCode)
+		require.Equal(t, http.StatusForbidden, put(strings.Replace(upload.UploadUrl, "photos%2F28", "photos%2F29", 1), "image/jpeg", "photo").StatusCode)
+		require.Equal(t, http.StatusNoContent, put(upload.UploadUrl, "image/jpeg", "photo").StatusCode)
+
+		key := regexp.MustCompile(`key=([^&]+)`).FindStringSubmatch(upload.UploadUrl)[1]
+		key = strings.ReplaceAll(key, "%2F", "/")
+		require.True(t, strings.HasPrefix(key, "photos/"+userId+"/"))
+		require.True(t, strings.HasSuffix(key, ".jpg"))
+
+		userRow = sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(getPhotoQuery)).
+			WithArgs("5").
+			WillReturnRows(sqlmock.NewRows(photoColumns).AddRow(5, u.ID, key, "image/jpeg", takenOn, 81.5, nil))
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "progress_photos" SET "uploaded_at"=$1,"updated_at"=$2 WHERE "progress_photos"."deleted_at" IS NULL AND "id" = $3`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 5).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		var completeResp CompleteProgressPhotoUploadResp
+		c.MustPost(`
+			mutation CompleteProgressPhotoUpload {
+				completeProgressPhotoUpload(progressPhotoId: "5") {
+					id
+					takenOn
+					bodyWeight
+					url
+				}
+			}`, &completeResp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		photo := completeResp.CompleteProgressPhotoUpload
+		require.Equal(t, "2022-10-30T00:00:00Z", photo.TakenOn)
+		require.Equal(t, 81.5, *photo.BodyWeight)
+
+		downloadResp, err := http.Get(photoServer.URL + photo.Url)
+		require.Nil(t, err)
+		defer downloadResp.Body.Close()
+		require.Equal(t, http.StatusOK, downloadResp.StatusCode)
+
+		// an upload url can't be used to read the photo back
+		getWithUploadURL, err := http.Get(photoServer.URL + upload.UploadUrl)
+		require.Nil(t, err)
+		getWithUploadURL.Body.Close()
+		require.Equal(t, http.StatusForbidden, getWithUploadURL.StatusCode)
+
+		userRow = sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "progress_photos" WHERE (user_id = $1 AND uploaded_at IS NOT NULL) AND "progress_photos"."deleted_at" IS NULL ORDER BY taken_on desc, id desc`)).
+			WithArgs(userId).
+			WillReturnRows(sqlmock.NewRows(photoColumns).AddRow(5, u.ID, key, "image/jpeg", takenOn, 81.5, takenOn))
+
+		var listResp struct {
+			ProgressPhotos []struct {
+				ID  string
+				Url string
+			}
+		}
+		c.MustPost(`
+			query ProgressPhotos {
+				progressPhotos {
+					id
+					url
+				}
+			}`, &listResp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Len(t, listResp.ProgressPhotos, 1)
+		require.Contains(t, listResp.ProgressPhotos[0].Url, "signature=")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Rejects Non Image Uploads", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+
+		var resp CreateProgressPhotoUploadResp
+		err := c.Post(`
+			mutation CreateProgressPhotoUpload {
+				createProgressPhotoUpload(photo: { contentType: "video/mp4" }) {
+					progressPhotoId
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Creating Photo Upload: Photo Needs To Be A JPEG, PNG, HEIC Or WebP","path":["createProgressPhotoUpload"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Complete Before Upload", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(getPhotoQuery)).
+			WithArgs("6").
+			WillReturnRows(sqlmock.NewRows(photoColumns).AddRow(6, u.ID, "photos/"+userId+"/missing.jpg", "image/jpeg", takenOn, nil, nil))
+
+		var resp CompleteProgressPhotoUploadResp
+		err := c.Post(`
+			mutation CompleteProgressPhotoUpload {
+				completeProgressPhotoUpload(progressPhotoId: "6") {
+					id
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Completing Photo Upload: Photo Hasn't Been Uploaded","path":["completeProgressPhotoUpload"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Delete Someone Else's Photo", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(getPhotoQuery)).
+			WithArgs("7").
+			WillReturnRows(sqlmock.NewRows(photoColumns).AddRow(7, 99, "photos/99/theirs.jpg", "image/jpeg", takenOn, nil, takenOn))
+
+		var resp struct{ DeleteProgressPhoto int }
+		err := c.Post(`
+			mutation DeleteProgressPhoto {
+				deleteProgressPhoto(progressPhotoId: "7")
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Deleting Photo: Access Denied","path":["deleteProgressPhoto"],"extensions":{"code":"FORBIDDEN"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Expired Upload URL", func(t *testing.T) {
+		url, err := store.SignedUploadURL("photos/28/late.jpg", "image/jpeg", config.MAX_PHOTO_BYTES, time.Now().Add(-time.Minute))
+		require.Nil(t, err)
+		require.Equal(t, http.StatusForbidden, put(url, "image/jpeg", "photo").StatusCode)
+	})
+
+	t.Run("Upload Too Large", func(t *testing.T) {
+		url, err := store.SignedUploadURL("photos/28/big.jpg", "image/jpeg", 4, time.Now().Add(time.Minute))
+		require.Nil(t, err)
+		require.Equal(t, http.StatusRequestEntityTooLarge, put(url, "image/jpeg", "too big").StatusCode)
+	})
+}
@@ -0,0 +1,99 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryCost(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	userId := fmt.Sprintf("%d", u.ID)
+
+	const quickPhrasesQuery = `
+		query QuickPhrases {
+			quickPhrases {
+				id
+				text
+				position
+			}
+		}`
+
+	debugCost := func(bd *client.Request) {
+		bd.HTTP = bd.HTTP.WithContext(context.WithValue(bd.HTTP.Context(), middleware.DebugCostCtxKey, true))
+	}
+
+	expectQuickPhrases := func(mock sqlmock.Sqlmock) {
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "quick_phrases" WHERE user_id = $1 AND "quick_phrases"."deleted_at" IS NULL ORDER BY position, id`)).
+			WithArgs(userId).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "text", "position"}).AddRow(1, u.ID, "felt heavy", 0))
+	}
+
+	t.Run("Cost Is Reported When Asked For", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		err := gormDB.Use(&database.QueryStatsPlugin{})
+		require.NoError(t, err)
+		srv := helpers.NewGqlServer(gormDB, accesscontrol.NewAccessControllerService(gormDB))
+		srv.Use(&middleware.QueryCost{})
+		c := client.New(srv)
+
+		expectQuickPhrases(mock)
+
+		resp, err := c.RawPost(quickPhrasesQuery, debugCost, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.NoError(t, err)
+		require.Empty(t, resp.Errors)
+
+		cost, ok := resp.Extensions["cost"].(map[string]interface{})
+		require.True(t, ok, "cost extension is missing")
+		require.Equal(t, float64(4), cost["complexity"])
+		require.Equal(t, float64(2), cost["dbQueries"])
+		require.Contains(t, cost, "dbDurationMs")
+		require.Contains(t, cost, "durationMs")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Cost Is Left Out Without The Header", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		err := gormDB.Use(&database.QueryStatsPlugin{})
+		require.NoError(t, err)
+		srv := helpers.NewGqlServer(gormDB, accesscontrol.NewAccessControllerService(gormDB))
+		srv.Use(&middleware.QueryCost{})
+		c := client.New(srv)
+
+		expectQuickPhrases(mock)
+
+		resp, err := c.RawPost(quickPhrasesQuery, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.NoError(t, err)
+		require.Empty(t, resp.Errors)
+		require.NotContains(t, resp.Extensions, "cost")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,70 @@
+package test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/logger"
+)
+
+func TestPlanLogger(t *testing.T) {
+	t.Parallel()
+
+	const exerciseRoutinesQuery = `SELECT * FROM "exercise_routines" WHERE workout_routine_id = $1 AND "exercise_routines"."deleted_at" IS NULL ORDER BY position, id`
+
+	t.Run("Slow Query Gets Explained", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		sqlDB, err := gormDB.DB()
+		require.NoError(t, err)
+		// every query counts as slow
+		gormDB.Logger = database.NewPlanLogger(logger.Discard, sqlDB, 0)
+
+		mock.ExpectQuery(regexp.QuoteMeta(exerciseRoutinesQuery)).WithArgs("8").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("^" + regexp.QuoteMeta(`EXPLAIN SELECT * FROM "exercise_routines" WHERE workout_routine_id = '8'`)).
+			WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).AddRow("Seq Scan on exercise_routines"))
+
+		_, err = database.GetExerciseRoutines(gormDB, "8")
+		require.NoError(t, err)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Fast Query Is Not Explained", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		sqlDB, err := gormDB.DB()
+		require.NoError(t, err)
+		gormDB.Logger = database.NewPlanLogger(logger.Discard, sqlDB, time.Hour)
+
+		mock.ExpectQuery(regexp.QuoteMeta(exerciseRoutinesQuery)).WithArgs("8").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		_, err = database.GetExerciseRoutines(gormDB, "8")
+		require.NoError(t, err)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Explain Query", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		sqlDB, err := gormDB.DB()
+		require.NoError(t, err)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`EXPLAIN SELECT 1`)).
+			WillReturnRows(sqlmock.NewRows([]string{"QUERY PLAN"}).AddRow("Result").AddRow("  (cost=0.00..0.01 rows=1 width=4)"))
+
+		plan, err := database.ExplainQuery(context.Background(), sqlDB, "SELECT 1")
+		require.NoError(t, err)
+		require.Equal(t, "Result\n  (cost=0.00..0.01 rows=1 width=4)", plan)
+	})
+}
@@ -0,0 +1,274 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type QuickPhrase struct {
+	ID       string
+	Text     string
+	Position int
+}
+
+type AddQuickPhraseResp struct {
+	AddQuickPhrase QuickPhrase
+}
+
+type QuickPhrasesResp struct {
+	QuickPhrases []QuickPhrase
+}
+
+type ReorderQuickPhrasesResp struct {
+	ReorderQuickPhrases []QuickPhrase
+}
+
+func TestQuickPhraseResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	userId := fmt.Sprintf("%d", u.ID)
+
+	const countQuery = `SELECT count(*) FROM "quick_phrases" WHERE user_id = $1 AND "quick_phrases"."deleted_at" IS NULL`
+	const getQuery = `SELECT * FROM "quick_phrases" WHERE id = $1 AND "quick_phrases"."deleted_at" IS NULL ORDER BY "quick_phrases"."id" LIMIT 1`
+	const listQuery = `SELECT * FROM "quick_phrases" WHERE user_id = $1 AND "quick_phrases"."deleted_at" IS NULL ORDER BY position, id`
+
+	expectUser := func(mock sqlmock.Sqlmock) {
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+	}
+	quickPhraseRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "user_id", "text", "position"}).
+			AddRow(1, u.ID, "felt heavy", 0).
+			AddRow(2, u.ID, "slow eccentric", 1)
+	}
+
+	t.Run("Add Quick Phrase", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectUser(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(countQuery)).WithArgs(userId).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(MAX(position) + 1, 0) FROM "quick_phrases" WHERE user_id = $1`)).
+			WithArgs(u.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"position"}).AddRow(2))
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "quick_phrases" ("created_at","updated_at","deleted_at","user_id","text","position") VALUES ($1,$2,$3,$4,$5,$6) RETURNING "id"`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, u.ID, "paused reps", 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectCommit()
+
+		var resp AddQuickPhraseResp
+		c.MustPost(`
+			mutation AddQuickPhrase {
+				addQuickPhrase(text: "paused reps") {
+					id
+					text
+					position
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, QuickPhrase{ID: "3", Text: "paused reps", Position: 2}, resp.AddQuickPhrase)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Add Quick Phrase Empty", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectUser(mock)
+
+		var resp AddQuickPhraseResp
+		err := c.Post(`
+			mutation AddQuickPhrase {
+				addQuickPhrase(text: "  ") {
+					id
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Adding Quick Phrase: text can't be empty","path":["addQuickPhrase"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Add Quick Phrase Too Many", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectUser(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(countQuery)).WithArgs(userId).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(config.MAX_QUICK_PHRASES))
+
+		var resp AddQuickPhraseResp
+		err := c.Post(`
+			mutation AddQuickPhrase {
+				addQuickPhrase(text: "paused reps") {
+					id
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, fmt.Sprintf(`[{"message":"Error Adding Quick Phrase: at most %d quick phrases","path":["addQuickPhrase"],"extensions":{"code":"INVALID_INPUT"}}]`, config.MAX_QUICK_PHRASES))
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Quick Phrases", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectUser(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(listQuery)).WithArgs(userId).WillReturnRows(quickPhraseRows())
+
+		var resp QuickPhrasesResp
+		c.MustPost(`
+			query QuickPhrases {
+				quickPhrases {
+					id
+					text
+					position
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, []QuickPhrase{
+			{ID: "1", Text: "felt heavy", Position: 0},
+			{ID: "2", Text: "slow eccentric", Position: 1},
+		}, resp.QuickPhrases)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Update Quick Phrase Access Denied", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectUser(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(getQuery)).
+			WithArgs("7").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "text", "position"}).AddRow(7, u.ID+1, "someone else's", 0))
+
+		var resp struct{}
+		err := c.Post(`
+			mutation UpdateQuickPhrase {
+				updateQuickPhrase(quickPhraseId: "7", text: "mine now") {
+					id
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Updating Quick Phrase: Access Denied","path":["updateQuickPhrase"],"extensions":{"code":"FORBIDDEN"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Delete Quick Phrase", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectUser(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(getQuery)).
+			WithArgs("1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "text", "position"}).AddRow(1, u.ID, "felt heavy", 0))
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "quick_phrases" SET "deleted_at"=$1 WHERE id = $2 AND "quick_phrases"."deleted_at" IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), "1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		var resp struct{ DeleteQuickPhrase int }
+		c.MustPost(`
+			mutation DeleteQuickPhrase {
+				deleteQuickPhrase(quickPhraseId: "1")
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, 1, resp.DeleteQuickPhrase)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Reorder Quick Phrases", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectUser(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(listQuery)).WithArgs(userId).WillReturnRows(quickPhraseRows())
+		mock.ExpectBegin()
+		const positionStmt = `UPDATE "quick_phrases" SET "position"=$1,"updated_at"=$2 WHERE (id = $3 AND user_id = $4) AND "quick_phrases"."deleted_at" IS NULL`
+		mock.ExpectExec(regexp.QuoteMeta(positionStmt)).WithArgs(0, sqlmock.AnyArg(), "2", userId).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(positionStmt)).WithArgs(1, sqlmock.AnyArg(), "1", userId).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		var resp ReorderQuickPhrasesResp
+		c.MustPost(`
+			mutation ReorderQuickPhrases {
+				reorderQuickPhrases(orderedIds: ["2", "1"]) {
+					id
+					text
+					position
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, []QuickPhrase{
+			{ID: "2", Text: "slow eccentric", Position: 0},
+			{ID: "1", Text: "felt heavy", Position: 1},
+		}, resp.ReorderQuickPhrases)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Reorder Quick Phrases Missing One", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectUser(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(listQuery)).WithArgs(userId).WillReturnRows(quickPhraseRows())
+
+		var resp ReorderQuickPhrasesResp
+		err := c.Post(`
+			mutation ReorderQuickPhrases {
+				reorderQuickPhrases(orderedIds: ["2", "2"]) {
+					id
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Reordering Quick Phrases: Order Must Include Every Quick Phrase","path":["reorderQuickPhrases"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/neilZon/workout-logger-api/token"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+
+	newClient := func(operations *middleware.RateLimiter, auth *middleware.RateLimiter) *client.Client {
+		_, gormDB := helpers.SetupMockDB()
+		srv := helpers.NewGqlServer(gormDB, accesscontrol.NewAccessControllerService(gormDB))
+		srv.Use(middleware.RateLimit{Operations: operations, Auth: auth})
+		return client.New(srv)
+	}
+	fromIP := func(ip string) client.Option {
+		return func(bd *client.Request) {
+			bd.HTTP = bd.HTTP.WithContext(context.WithValue(bd.HTTP.Context(), middleware.ClientIPCtxKey, ip))
+		}
+	}
+	isRateLimited := func(err error) bool {
+		return err != nil && strings.Contains(err.Error(), `"code":"RATE_LIMITED"`)
+	}
+
+	t.Run("Operations Are Limited Per User", func(t *testing.T) {
+		c := newClient(middleware.NewRateLimiter(1, 2), middleware.NewRateLimiter(60, 60))
+
+		// the resolvers fail on the empty mock db, only the limit matters here
+		var resp struct{}
+		for i := 0; i < 2; i++ {
+			err := c.Post(`query User { user { id } }`, &resp, helpers.AddContext(u, nil), fromIP("203.0.113.7"))
+			require.False(t, isRateLimited(err))
+		}
+		err := c.Post(`query User { user { id } }`, &resp, helpers.AddContext(u, nil), fromIP("203.0.113.7"))
+		require.EqualError(t, err, `[{"message":"Too many requests, try again later","extensions":{"code":"RATE_LIMITED","retryAfterSeconds":60}}]`)
+
+		// someone else on the same network has their own bucket
+		other := &token.Claims{ID: u.ID + 1, Name: u.Name}
+		other.Subject = "other@test.com"
+		err = c.Post(`query User { user { id } }`, &resp, helpers.AddContext(other, nil), fromIP("203.0.113.7"))
+		require.False(t, isRateLimited(err))
+	})
+
+	t.Run("Operations Without A Token Are Limited Per IP", func(t *testing.T) {
+		c := newClient(middleware.NewRateLimiter(1, 1), middleware.NewRateLimiter(60, 60))
+
+		var resp struct{}
+		err := c.Post(`query User { user { id } }`, &resp, fromIP("203.0.113.7"))
+		require.False(t, isRateLimited(err))
+		err = c.Post(`query User { user { id } }`, &resp, fromIP("203.0.113.7"))
+		require.True(t, isRateLimited(err))
+		err = c.Post(`query User { user { id } }`, &resp, fromIP("198.51.100.2"))
+		require.False(t, isRateLimited(err))
+	})
+
+	t.Run("Auth Mutations Are Limited Per IP", func(t *testing.T) {
+		c := newClient(middleware.NewRateLimiter(60, 60), middleware.NewRateLimiter(1, 2))
+
+		const login = `mutation Login { login(loginInput: { email: "not an email", password: "password1" }) { accessToken } }`
+		var resp struct{}
+		for i := 0; i < 2; i++ {
+			err := c.Post(login, &resp, fromIP("203.0.113.7"))
+			require.EqualError(t, err, `[{"message":"invalid email","path":["login"],"extensions":{"code":"INVALID_INPUT"}}]`)
+		}
+		err := c.Post(login, &resp, fromIP("203.0.113.7"))
+		require.EqualError(t, err, `[{"message":"Too many requests, try again later","path":["login"],"extensions":{"code":"RATE_LIMITED","retryAfterSeconds":60}}]`)
+
+		// every login in one operation takes a token
+		err = c.Post(`mutation Login {
+			a: login(loginInput: { email: "not an email", password: "password1" }) { accessToken }
+			b: login(loginInput: { email: "not an email", password: "password1" }) { accessToken }
+			c: login(loginInput: { email: "not an email", password: "password1" }) { accessToken }
+		}`, &resp, fromIP("198.51.100.2"))
+		require.True(t, isRateLimited(err))
+	})
+
+	t.Run("Client IP Comes From The Proxy", func(t *testing.T) {
+		_, proxies, err := net.ParseCIDR("10.1.0.0/16")
+		require.Nil(t, err)
+		var ip string
+		h := middleware.ClientMiddleware([]*net.IPNet{proxies}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip = middleware.GetClientIP(r.Context())
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "/query", nil)
+		req.RemoteAddr = "10.1.0.4:5123"
+		req.Header.Set("X-Forwarded-For", "10.0.0.1, 203.0.113.7, 10.1.0.9")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		require.Equal(t, "203.0.113.7", ip)
+
+		req = httptest.NewRequest(http.MethodPost, "/query", nil)
+		req.RemoteAddr = "198.51.100.2:5123"
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		require.Equal(t, "198.51.100.2", ip)
+	})
+
+	t.Run("Client IP Ignores X-Forwarded-For From Anyone Else", func(t *testing.T) {
+		var ip string
+		h := middleware.ClientMiddleware(nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip = middleware.GetClientIP(r.Context())
+		}))
+
+		req := httptest.NewRequest(http.MethodPost, "/query", nil)
+		req.RemoteAddr = "198.51.100.2:5123"
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+		require.Equal(t, "198.51.100.2", ip)
+	})
+}
@@ -0,0 +1,105 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/media"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOnly(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+
+	t.Run("Queries Are Answered", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		c := client.New(helpers.NewReadOnlyGqlServer(gormDB, accesscontrol.NewAccessControllerService(gormDB), media.NewLocalStore(t.TempDir(), "")))
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_sessions" WHERE (user_id = $1 AND "end" IS NULL)`)).
+			WithArgs(fmt.Sprintf("%d", u.ID)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		var resp CurrentWorkoutSessionResp
+		c.MustPost(`
+			query CurrentWorkoutSession {
+				currentWorkoutSession {
+					id
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Nil(t, resp.CurrentWorkoutSession)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Mutations Are Rejected", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		c := client.New(helpers.NewReadOnlyGqlServer(gormDB, accesscontrol.NewAccessControllerService(gormDB), media.NewLocalStore(t.TempDir(), "")))
+
+		var resp struct{}
+		err := c.Post(`
+			mutation AddQuickPhrase {
+				addQuickPhrase(text: "slow eccentric") {
+					id
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"This endpoint is read only, send mutations to /query","extensions":{"code":"FORBIDDEN"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Introspection Leaves Out Mutations", func(t *testing.T) {
+		_, gormDB := helpers.SetupMockDB()
+		c := client.New(helpers.NewReadOnlyGqlServer(gormDB, accesscontrol.NewAccessControllerService(gormDB), media.NewLocalStore(t.TempDir(), "")))
+
+		var resp struct {
+			Schema struct {
+				QueryType    struct{ Name string }
+				MutationType *struct{ Name string }
+				Types        []struct{ Name string }
+			} `json:"__schema"`
+			Type *struct{ Name string } `json:"__type"`
+		}
+		c.MustPost(`
+			query Introspection {
+				__schema {
+					queryType { name }
+					mutationType { name }
+					types { name }
+				}
+				__type(name: "Mutation") { name }
+			}`, &resp)
+		require.Equal(t, "Query", resp.Schema.QueryType.Name)
+		require.Nil(t, resp.Schema.MutationType)
+		require.Nil(t, resp.Type)
+
+		names := []string{}
+		for _, typ := range resp.Schema.Types {
+			names = append(names, typ.Name)
+		}
+		require.Contains(t, names, "WorkoutSession")
+		require.NotContains(t, names, "Mutation")
+		require.NotContains(t, names, "Subscription")
+	})
+}
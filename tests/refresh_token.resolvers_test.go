@@ -0,0 +1,206 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph"
+	"github.com/neilZon/workout-logger-api/graph/generated"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/token"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// addDevice stands in for the ip and user agent ClientMiddleware reads
+func addDevice(ip string, userAgent string) client.Option {
+	return func(bd *client.Request) {
+		ctx := context.WithValue(bd.HTTP.Context(), middleware.ClientIPCtxKey, ip)
+		ctx = context.WithValue(ctx, middleware.UserAgentCtxKey, userAgent)
+		bd.HTTP = bd.HTTP.WithContext(ctx)
+	}
+}
+
+// expectRevokedFamily is user 12's family being logged out
+func expectRevokedFamily(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "refresh_tokens" SET "revoked_at"=$1,"updated_at"=$2 WHERE (user_id = $3 AND family_id = $4 AND revoked_at IS NULL) AND "refresh_tokens"."deleted_at" IS NULL`)).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 12, "family").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "sessions" SET "revoked_at"=$1,"updated_at"=$2 WHERE (user_id = $3 AND family_id = $4 AND revoked_at IS NULL) AND "sessions"."deleted_at" IS NULL`)).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 12, "family").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func newRefreshTokenClient(gormDB *gorm.DB) *client.Client {
+	srv := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{Resolvers: &graph.Resolver{DB: gormDB}}))
+	srv.SetErrorPresenter(errors.Present)
+	return client.New(srv)
+}
+
+type RefreshAccessTokenResp struct {
+	RefreshAccessToken struct {
+		AccessToken  string
+		RefreshToken string
+	}
+}
+
+func TestRefreshTokens(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	REFRESH_SECRET := []byte(os.Getenv(config.REFRESH_SECRET))
+	ACCESS_SECRET := []byte(os.Getenv(config.ACCESS_SECRET))
+	cred := &token.Credentials{
+		ID:    12,
+		Name:  "testname",
+		Email: "test@test.com",
+	}
+	const tokenQuery = `SELECT * FROM "refresh_tokens" WHERE token_id = $1 AND "refresh_tokens"."deleted_at" IS NULL LIMIT 1 FOR UPDATE`
+	refreshMutation := func(refreshToken string) string {
+		return fmt.Sprintf(`mutation {
+			refreshAccessToken(refreshToken: "Bearer %s") {
+				accessToken
+				refreshToken
+			}
+		}`, refreshToken)
+	}
+	expectVerifiedUser := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE id = $1`)).
+			WithArgs("12").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "verified"}).AddRow(12, true))
+	}
+
+	t.Run("Refresh Swaps The Token For One In The Same Family", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		c := newRefreshTokenClient(gormDB)
+
+		expectVerifiedUser(mock)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(tokenQuery)).
+			WithArgs("first").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "family_id", "token_id"}).AddRow(1, 12, "family", "first"))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "refresh_tokens" SET "used_at"=$1,"updated_at"=$2 WHERE "refresh_tokens"."deleted_at" IS NULL AND "id" = $3`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "refresh_tokens" ("created_at","updated_at","deleted_at","user_id","family_id","token_id","used_at","revoked_at")`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, 12, "family", sqlmock.AnyArg(), nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "sessions" SET "ip"=$1,"last_used_at"=$2,"origin_app_version"=$3,"origin_device_id"=$4,"origin_platform"=$5,"user_agent"=$6,"updated_at"=$7 WHERE family_id = $8 AND "sessions"."deleted_at" IS NULL`)).
+			WithArgs("203.0.113.7", sqlmock.AnyArg(), "2.4.0", "device-1", "ios", "TilFailure/2.4.0 CFNetwork", sqlmock.AnyArg(), "family").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		var resp RefreshAccessTokenResp
+		c.MustPost(
+			refreshMutation(token.SignRefresh(cred, "family", "first", REFRESH_SECRET, 5)),
+			&resp,
+			helpers.AddClient(database.Origin{Platform: "ios", AppVersion: "2.4.0", DeviceID: "device-1"}),
+			addDevice("203.0.113.7", "TilFailure/2.4.0 CFNetwork"),
+		)
+
+		claims, err := token.Decode("Bearer "+resp.RefreshAccessToken.RefreshToken, REFRESH_SECRET)
+		require.Nil(t, err)
+		require.Equal(t, "family", claims.Family)
+		require.NotEqual(t, "first", claims.Id)
+		require.Equal(t, uint(12), claims.ID)
+		require.True(t, token.Validate(resp.RefreshAccessToken.AccessToken, ACCESS_SECRET))
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Reusing A Swapped Token Revokes The Family", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		c := newRefreshTokenClient(gormDB)
+
+		expectVerifiedUser(mock)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(tokenQuery)).
+			WithArgs("first").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "family_id", "token_id", "used_at"}).AddRow(1, 12, "family", "first", time.Now()))
+		expectRevokedFamily(mock)
+		mock.ExpectCommit()
+
+		var resp RefreshAccessTokenResp
+		err := c.Post(refreshMutation(token.SignRefresh(cred, "family", "first", REFRESH_SECRET, 5)), &resp)
+		require.EqualError(t, err, `[{"message":"Refresh token invalid","path":["refreshAccessToken"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Revoked Family Can't Refresh", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		c := newRefreshTokenClient(gormDB)
+
+		expectVerifiedUser(mock)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(tokenQuery)).
+			WithArgs("second").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "family_id", "token_id", "revoked_at"}).AddRow(2, 12, "family", "second", time.Now()))
+		mock.ExpectRollback()
+
+		var resp RefreshAccessTokenResp
+		err := c.Post(refreshMutation(token.SignRefresh(cred, "family", "second", REFRESH_SECRET, 5)), &resp)
+		require.EqualError(t, err, `[{"message":"Refresh token invalid","path":["refreshAccessToken"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Token From Before Rotation Can't Refresh", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		c := newRefreshTokenClient(gormDB)
+
+		var resp RefreshAccessTokenResp
+		err := c.Post(refreshMutation(token.Sign(cred, REFRESH_SECRET, 5)), &resp)
+		require.EqualError(t, err, `[{"message":"Refresh token invalid","path":["refreshAccessToken"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Logout Revokes The Family", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		c := newRefreshTokenClient(gormDB)
+
+		mock.ExpectBegin()
+		expectRevokedFamily(mock)
+		mock.ExpectCommit()
+
+		var resp struct {
+			Logout bool
+		}
+		c.MustPost(fmt.Sprintf(`mutation { logout(refreshToken: "Bearer %s") }`, token.SignRefresh(cred, "family", "second", REFRESH_SECRET, 5)), &resp)
+		require.True(t, resp.Logout)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
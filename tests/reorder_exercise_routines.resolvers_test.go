@@ -0,0 +1,121 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type ReorderExerciseRoutinesResp struct {
+	ReorderExerciseRoutines []struct {
+		ID   string
+		Name string
+	}
+}
+
+func TestReorderExerciseRoutinesResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	wr := testdata.WorkoutRoutine
+	er0 := wr.ExerciseRoutines[0]
+	er1 := wr.ExerciseRoutines[1]
+
+	const exerciseRoutinesQuery = `SELECT * FROM "exercise_routines" WHERE workout_routine_id = $1 AND "exercise_routines"."deleted_at" IS NULL ORDER BY position, id`
+	workoutRoutineColumns := []string{"id", "name", "created_at", "deleted_at", "updated_at", "user_id", "active"}
+	exerciseRoutineColumns := []string{"id", "name", "sets", "reps", "active", "workout_routine_id", "position"}
+
+	t.Run("Reorder Exercise Routines Success", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		workoutRoutineRow := sqlmock.NewRows(workoutRoutineColumns).
+			AddRow(wr.ID, wr.Name, wr.CreatedAt, nil, wr.UpdatedAt, wr.UserID, wr.Active)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).WithArgs(fmt.Sprintf("%d", wr.ID)).WillReturnRows(workoutRoutineRow)
+
+		exerciseRoutineRows := sqlmock.NewRows(exerciseRoutineColumns).
+			AddRow(er0.ID, er0.Name, er0.Sets, er0.Reps, true, wr.ID, 0).
+			AddRow(er1.ID, er1.Name, er1.Sets, er1.Reps, true, wr.ID, 1)
+		mock.ExpectQuery(regexp.QuoteMeta(exerciseRoutinesQuery)).WithArgs(fmt.Sprintf("%d", wr.ID)).WillReturnRows(exerciseRoutineRows)
+
+		const updatePositionStmt = `UPDATE "exercise_routines" SET "position"=$1,"updated_at"=$2 WHERE (id = $3 AND workout_routine_id = $4) AND "exercise_routines"."deleted_at" IS NULL`
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(updatePositionStmt)).
+			WithArgs(0, sqlmock.AnyArg(), fmt.Sprintf("%d", er1.ID), fmt.Sprintf("%d", wr.ID)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(updatePositionStmt)).
+			WithArgs(1, sqlmock.AnyArg(), fmt.Sprintf("%d", er0.ID), fmt.Sprintf("%d", wr.ID)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.TouchWorkoutRoutineStmt)).
+			WithArgs(sqlmock.AnyArg(), fmt.Sprintf("%d", wr.ID)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		var resp ReorderExerciseRoutinesResp
+		mutation := fmt.Sprintf(`
+			mutation ReorderExerciseRoutines {
+				reorderExerciseRoutines(workoutRoutineId: "%d", orderedIds: ["%d", "%d"]) {
+					id
+					name
+				}
+			}`, wr.ID, er1.ID, er0.ID)
+		c.MustPost(mutation, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Len(t, resp.ReorderExerciseRoutines, 2)
+		require.Equal(t, fmt.Sprintf("%d", er1.ID), resp.ReorderExerciseRoutines[0].ID)
+		require.Equal(t, fmt.Sprintf("%d", er0.ID), resp.ReorderExerciseRoutines[1].ID)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Reorder Exercise Routines Missing Exercise Routine", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		workoutRoutineRow := sqlmock.NewRows(workoutRoutineColumns).
+			AddRow(wr.ID, wr.Name, wr.CreatedAt, nil, wr.UpdatedAt, wr.UserID, wr.Active)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).WithArgs(fmt.Sprintf("%d", wr.ID)).WillReturnRows(workoutRoutineRow)
+
+		exerciseRoutineRows := sqlmock.NewRows(exerciseRoutineColumns).
+			AddRow(er0.ID, er0.Name, er0.Sets, er0.Reps, true, wr.ID, 0).
+			AddRow(er1.ID, er1.Name, er1.Sets, er1.Reps, true, wr.ID, 1)
+		mock.ExpectQuery(regexp.QuoteMeta(exerciseRoutinesQuery)).WithArgs(fmt.Sprintf("%d", wr.ID)).WillReturnRows(exerciseRoutineRows)
+
+		var resp ReorderExerciseRoutinesResp
+		mutation := fmt.Sprintf(`
+			mutation ReorderExerciseRoutines {
+				reorderExerciseRoutines(workoutRoutineId: "%d", orderedIds: ["%d", "%d"]) {
+					id
+				}
+			}`, wr.ID, er1.ID, er1.ID)
+		err := c.Post(mutation, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, "[{\"message\":\"Error Reordering Exercise Routines: Order Must Include Every Exercise Routine\",\"path\":[\"reorderExerciseRoutines\"],\"extensions\":{\"code\":\"INVALID_INPUT\"}}]")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
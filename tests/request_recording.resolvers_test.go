@@ -0,0 +1,233 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/cache"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type StartRequestRecordingResp struct {
+	StartRequestRecording string
+}
+
+type GetRequestRecordingsResp struct {
+	RequestRecordings []struct {
+		ID            string
+		OperationName string
+		Query         string
+		Variables     string
+		DurationMs    int
+	}
+}
+
+func TestRequestRecordingResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+
+	t.Run("Start Request Recording Success", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		mock.ExpectBegin()
+		const updateUserStmt = `UPDATE "users" SET "record_requests_until"=$1,"updated_at"=$2 WHERE id = $3 AND "users"."deleted_at" IS NULL`
+		mock.ExpectExec(regexp.QuoteMeta(updateUserStmt)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), fmt.Sprintf("%d", u.ID)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		var resp StartRequestRecordingResp
+		c.MustPost(`
+			mutation StartRequestRecording {
+				startRequestRecording(minutes: 30)
+			}`,
+			&resp,
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.NotEmpty(t, resp.StartRequestRecording)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Start Request Recording Window Too Long", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		var resp StartRequestRecordingResp
+		err := c.Post(`
+			mutation StartRequestRecording {
+				startRequestRecording(minutes: 100000)
+			}`,
+			&resp,
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.EqualError(t, err, "[{\"message\":\"minutes needs to be between 1 and 1440\",\"path\":[\"startRequestRecording\"],\"extensions\":{\"code\":\"INVALID_INPUT\"}}]")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Recording Window Is Read Once", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		srv := helpers.NewGqlServer(gormDB, accesscontrol.NewAccessControllerService(gormDB))
+		store := cache.NewMemory(time.Minute)
+		srv.Use(middleware.RequestRecorder{DB: gormDB, Cache: store})
+		c := client.New(srv)
+
+		for i := 0; i < 2; i++ {
+			userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+			mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "quick_phrases" WHERE user_id = $1`)).
+				WithArgs(fmt.Sprintf("%d", u.ID)).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "text", "position"}))
+			// only the first operation reads the recording window
+			if i == 0 {
+				recorderRow := sqlmock.NewRows([]string{"id", "record_requests_until"}).AddRow(u.ID, nil)
+				mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(recorderRow)
+			}
+
+			var resp QuickPhrasesResp
+			c.MustPost(`query QuickPhrases { quickPhrases { id text } }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		}
+
+		until, ok := store.Get(fmt.Sprintf("recording:%d", u.ID))
+		require.True(t, ok)
+		require.Equal(t, "0", string(until))
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Start Request Recording Reads The Window Again", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		srv := helpers.NewGqlServer(gormDB, accesscontrol.NewAccessControllerService(gormDB))
+		store := cache.NewMemory(time.Minute)
+		store.Set(fmt.Sprintf("recording:%d", u.ID), []byte("0"), time.Minute)
+		srv.Use(middleware.RequestRecorder{DB: gormDB, Cache: store})
+		c := client.New(srv)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "record_requests_until"=$1`)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		// already over by the time it's read, so nothing gets recorded
+		ended := time.Now().Add(-time.Minute).Truncate(time.Microsecond)
+		recorderRow := sqlmock.NewRows([]string{"id", "record_requests_until"}).AddRow(u.ID, ended)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(recorderRow)
+
+		var resp StartRequestRecordingResp
+		c.MustPost(`
+			mutation StartRequestRecording {
+				startRequestRecording(minutes: 30)
+			}`,
+			&resp,
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+
+		until, ok := store.Get(fmt.Sprintf("recording:%d", u.ID))
+		require.True(t, ok)
+		require.Equal(t, strconv.FormatInt(ended.UnixNano(), 10), string(until))
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Get Request Recordings Success", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified", "admin"}).AddRow(u.ID, true, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		recordingRows := sqlmock.
+			NewRows([]string{"id", "user_id", "operation_name", "query", "variables", "duration_ms"}).
+			AddRow(2, 55, "Login", `mutation Login { login(loginInput: {email: "[REDACTED]"}) }`, "{}", 12)
+		const recordingsQuery = `SELECT * FROM "request_recordings" WHERE user_id = $1 AND "request_recordings"."deleted_at" IS NULL ORDER BY id desc LIMIT 10`
+		mock.ExpectQuery(regexp.QuoteMeta(recordingsQuery)).WithArgs("55").WillReturnRows(recordingRows)
+
+		var resp GetRequestRecordingsResp
+		c.MustPost(`
+			query RequestRecordings {
+				requestRecordings(userId: "55", limit: 10) {
+					id
+					operationName
+					query
+					variables
+					durationMs
+				}
+			}`,
+			&resp,
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.Len(t, resp.RequestRecordings, 1)
+		require.Equal(t, "Login", resp.RequestRecordings[0].OperationName)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Get Request Recordings Not Admin", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified", "admin"}).AddRow(u.ID, true, false)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		var resp GetRequestRecordingsResp
+		err := c.Post(`
+			query RequestRecordings {
+				requestRecordings(userId: "55", limit: 10) {
+					id
+				}
+			}`,
+			&resp,
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.EqualError(t, err, "[{\"message\":\"Access Denied\",\"path\":[\"requestRecordings\"],\"extensions\":{\"code\":\"FORBIDDEN\"}}]")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
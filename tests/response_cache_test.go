@@ -0,0 +1,148 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type CachedWorkoutRoutinesResp struct {
+	WorkoutRoutines struct {
+		Edges []struct {
+			Node struct {
+				ID   string
+				Name string
+			}
+		}
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	userId := fmt.Sprintf("%d", u.ID)
+
+	const workoutRoutinesQuery = `
+		query WorkoutRoutines {
+			workoutRoutines(first: 10) {
+				edges {
+					node {
+						id
+						name
+					}
+				}
+			}
+		}`
+
+	expectVerifyUser := func(mock sqlmock.Sqlmock) {
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+	}
+	expectWorkoutRoutines := func(mock sqlmock.Sqlmock, name string) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_routines" WHERE workout_routines.user_id = $1`)).
+			WithArgs(userId).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "user_id"}).AddRow(1, name, u.ID))
+	}
+
+	t.Run("Same Query Is Answered From The Cache", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		c := helpers.NewGqlClient(gormDB, accesscontrol.NewAccessControllerService(gormDB))
+
+		expectVerifyUser(mock)
+		expectWorkoutRoutines(mock, "Push")
+		var resp CachedWorkoutRoutinesResp
+		c.MustPost(workoutRoutinesQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, "Push", resp.WorkoutRoutines.Edges[0].Node.Name)
+
+		// only the user is checked the second time
+		expectVerifyUser(mock)
+		resp = CachedWorkoutRoutinesResp{}
+		c.MustPost(workoutRoutinesQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Len(t, resp.WorkoutRoutines.Edges, 1)
+		require.Equal(t, "1", resp.WorkoutRoutines.Edges[0].Node.ID)
+		require.Equal(t, "Push", resp.WorkoutRoutines.Edges[0].Node.Name)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Mutation Drops The User's Cached Responses", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		c := helpers.NewGqlClient(gormDB, accesscontrol.NewAccessControllerService(gormDB))
+
+		expectVerifyUser(mock)
+		expectWorkoutRoutines(mock, "Push")
+		var resp CachedWorkoutRoutinesResp
+		c.MustPost(workoutRoutinesQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+
+		loggedAt := time.Date(2022, 10, 1, 8, 0, 0, 0, time.UTC)
+		expectVerifyUser(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "body_weight_entries" WHERE id = $1 AND "body_weight_entries"."deleted_at" IS NULL`)).
+			WithArgs("4").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "weight", "logged_at"}).AddRow(4, u.ID, 180, loggedAt))
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "body_weight_entries" SET "deleted_at"=$1 WHERE id = $2`)).
+			WithArgs(sqlmock.AnyArg(), "4").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		var deleteResp DeleteBodyWeightResp
+		c.MustPost(`
+			mutation DeleteBodyWeight {
+				deleteBodyWeight(bodyWeightEntryId: "4")
+			}`, &deleteResp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+
+		expectVerifyUser(mock)
+		expectWorkoutRoutines(mock, "Pull")
+		resp = CachedWorkoutRoutinesResp{}
+		c.MustPost(workoutRoutinesQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, "Pull", resp.WorkoutRoutines.Edges[0].Node.Name)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Other Users Don't Share Cached Responses", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		c := helpers.NewGqlClient(gormDB, accesscontrol.NewAccessControllerService(gormDB))
+
+		expectVerifyUser(mock)
+		expectWorkoutRoutines(mock, "Push")
+		var resp CachedWorkoutRoutinesResp
+		c.MustPost(workoutRoutinesQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+
+		other := *u
+		other.ID = u.ID + 1
+		otherId := fmt.Sprintf("%d", other.ID)
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(other.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(otherId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_routines" WHERE workout_routines.user_id = $1`)).
+			WithArgs(otherId).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "user_id"}))
+		resp = CachedWorkoutRoutinesResp{}
+		c.MustPost(workoutRoutinesQuery, &resp, helpers.AddContext(&other, helpers.NewLoaders(gormDB)))
+		require.Len(t, resp.WorkoutRoutines.Edges, 0)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,121 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseQuota(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	userId := fmt.Sprintf("%d", u.ID)
+
+	const quickPhrasesQuery = `
+		query QuickPhrases {
+			quickPhrases {
+				id
+				text
+				position
+			}
+		}`
+	const tooLarge = `[{"message":"Response too large, use pagination (limit/after) or request fewer fields","extensions":{"code":"RESPONSE_TOO_LARGE"}}]`
+
+	expectQuickPhrases := func(mock sqlmock.Sqlmock) {
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "quick_phrases" WHERE user_id = $1 AND "quick_phrases"."deleted_at" IS NULL ORDER BY position, id`)).
+			WithArgs(userId).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "text", "position"}).
+				AddRow(1, u.ID, "felt heavy", 0).
+				AddRow(2, u.ID, "easy", 1))
+	}
+
+	t.Run("Under The Caps Passes Through", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		srv := helpers.NewGqlServer(gormDB, accesscontrol.NewAccessControllerService(gormDB))
+		srv.Use(middleware.ResponseQuota{MaxBytes: 1 << 10, MaxNodes: 7})
+		c := client.New(srv)
+
+		expectQuickPhrases(mock)
+
+		var resp QuickPhrasesResp
+		c.MustPost(quickPhrasesQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Len(t, resp.QuickPhrases, 2)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Too Many Bytes", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		srv := helpers.NewGqlServer(gormDB, accesscontrol.NewAccessControllerService(gormDB))
+		srv.Use(middleware.ResponseQuota{MaxBytes: 32, MaxNodes: 100})
+		c := client.New(srv)
+
+		expectQuickPhrases(mock)
+
+		var resp QuickPhrasesResp
+		err := c.Post(quickPhrasesQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, tooLarge)
+		require.Empty(t, resp.QuickPhrases)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Too Many Nodes", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		srv := helpers.NewGqlServer(gormDB, accesscontrol.NewAccessControllerService(gormDB))
+		// the list and one phrase's fields, the second phrase goes over
+		srv.Use(middleware.ResponseQuota{MaxBytes: 1 << 10, MaxNodes: 6})
+		c := client.New(srv)
+
+		expectQuickPhrases(mock)
+
+		var resp QuickPhrasesResp
+		err := c.Post(quickPhrasesQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, tooLarge)
+		require.Empty(t, resp.QuickPhrases)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Nothing Is Resolved Once The Cap Is Hit", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		srv := helpers.NewGqlServer(gormDB, accesscontrol.NewAccessControllerService(gormDB))
+		srv.Use(middleware.ResponseQuota{MaxBytes: 1 << 10, MaxNodes: 0})
+		c := client.New(srv)
+
+		var resp QuickPhrasesResp
+		err := c.Post(quickPhrasesQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, tooLarge)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,218 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/cache"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type RestoreWorkoutSessionResp struct {
+	RestoreWorkoutSession struct {
+		ID string
+	}
+}
+
+func TestRestoreResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	ws := testdata.WorkoutSession
+	wr := testdata.WorkoutRoutine
+
+	const restoreWorkoutSessionMutation = `
+		mutation RestoreWorkoutSession {
+			restoreWorkoutSession(workoutSessionId: "%d") {
+				id
+			}
+		}`
+	const deletedWorkoutSessionQuery = `SELECT * FROM "workout_sessions" WHERE id = $1 AND deleted_at IS NOT NULL ORDER BY "workout_sessions"."id" LIMIT 1`
+
+	const workoutRoutineNotDeletedQuery = `SELECT "id" FROM "workout_routines" WHERE id = (SELECT workout_routine_id FROM workout_sessions WHERE id = $1) AND "workout_routines"."deleted_at" IS NULL FOR SHARE`
+	const restoredOpenWorkoutSessionQuery = `SELECT "user_id" FROM "workout_sessions" WHERE id = $1 AND "end" IS NULL LIMIT 1`
+
+	workoutSessionColumns := []string{"id", "start", "end", "workout_routine_id", "user_id", "created_at", "deleted_at", "updated_at"}
+
+	t.Run("Restore Workout Session Success", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		deletedAt := time.Now().Add(-time.Hour)
+		workoutSessionRow := sqlmock.
+			NewRows(workoutSessionColumns).
+			AddRow(ws.ID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.UserID, ws.CreatedAt, deletedAt, ws.UpdatedAt)
+		mock.ExpectQuery(regexp.QuoteMeta(deletedWorkoutSessionQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(workoutSessionRow)
+
+		workoutRoutineRow := sqlmock.
+			NewRows([]string{"id", "name", "created_at", "deleted_at", "updated_at", "user_id", "active"}).
+			AddRow(wr.ID, wr.Name, wr.CreatedAt, nil, wr.UpdatedAt, wr.UserID, wr.Active)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).WithArgs(fmt.Sprintf("%d", wr.ID)).WillReturnRows(workoutRoutineRow)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(workoutRoutineNotDeletedQuery)).
+			WithArgs(fmt.Sprintf("%d", ws.ID)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(wr.ID))
+		mock.ExpectQuery(regexp.QuoteMeta(restoredOpenWorkoutSessionQuery)).
+			WithArgs(fmt.Sprintf("%d", ws.ID)).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+		const restoreWorkoutSessionStmt = `UPDATE "workout_sessions" SET "deleted_at"=$1,"updated_at"=$2 WHERE id = $3`
+		mock.ExpectExec(regexp.QuoteMeta(restoreWorkoutSessionStmt)).
+			WithArgs(nil, sqlmock.AnyArg(), fmt.Sprintf("%d", ws.ID)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		const restoreExercisesStmt = `UPDATE "exercises" SET "deleted_at"=$1,"updated_at"=$2 WHERE workout_session_id = $3 AND deleted_at = $4 RETURNING *`
+		exerciseRows := sqlmock.NewRows([]string{"id"}).AddRow(ws.Exercises[0].ID).AddRow(ws.Exercises[1].ID)
+		mock.ExpectQuery(regexp.QuoteMeta(restoreExercisesStmt)).
+			WithArgs(nil, sqlmock.AnyArg(), fmt.Sprintf("%d", ws.ID), sqlmock.AnyArg()).
+			WillReturnRows(exerciseRows)
+
+		const restoreSetsStmt = `UPDATE "set_entries" SET "deleted_at"=$1,"updated_at"=$2 WHERE exercise_id IN ($3,$4) AND deleted_at = $5`
+		mock.ExpectExec(regexp.QuoteMeta(restoreSetsStmt)).
+			WithArgs(nil, sqlmock.AnyArg(), fmt.Sprintf("%d", ws.Exercises[0].ID), fmt.Sprintf("%d", ws.Exercises[1].ID), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 4))
+		mock.ExpectCommit()
+
+		var resp RestoreWorkoutSessionResp
+		c.MustPost(
+			fmt.Sprintf(restoreWorkoutSessionMutation, ws.ID),
+			&resp,
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.Equal(t, fmt.Sprintf("%d", ws.ID), resp.RestoreWorkoutSession.ID)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Restore Workout Session Grace Period Expired", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		deletedAt := time.Now().Add(-30 * 24 * time.Hour)
+		workoutSessionRow := sqlmock.
+			NewRows(workoutSessionColumns).
+			AddRow(ws.ID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.UserID, ws.CreatedAt, deletedAt, ws.UpdatedAt)
+		mock.ExpectQuery(regexp.QuoteMeta(deletedWorkoutSessionQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(workoutSessionRow)
+
+		workoutRoutineRow := sqlmock.
+			NewRows([]string{"id", "name", "created_at", "deleted_at", "updated_at", "user_id", "active"}).
+			AddRow(wr.ID, wr.Name, wr.CreatedAt, nil, wr.UpdatedAt, wr.UserID, wr.Active)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).WithArgs(fmt.Sprintf("%d", wr.ID)).WillReturnRows(workoutRoutineRow)
+
+		var resp RestoreWorkoutSessionResp
+		err := c.Post(
+			fmt.Sprintf(restoreWorkoutSessionMutation, ws.ID),
+			&resp,
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.EqualError(t, err, "[{\"message\":\"Error Restoring Workout Session: Grace Period Expired\",\"path\":[\"restoreWorkoutSession\"],\"extensions\":{\"code\":\"INVALID_INPUT\"}}]")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+	t.Run("Restore Open Workout Session While Another Is Open", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		deletedAt := time.Now().Add(-time.Hour)
+		workoutSessionRow := sqlmock.
+			NewRows(workoutSessionColumns).
+			AddRow(ws.ID, ws.Start, nil, ws.WorkoutRoutineID, ws.UserID, ws.CreatedAt, deletedAt, ws.UpdatedAt)
+		mock.ExpectQuery(regexp.QuoteMeta(deletedWorkoutSessionQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(workoutSessionRow)
+
+		workoutRoutineRow := sqlmock.
+			NewRows([]string{"id", "name", "created_at", "deleted_at", "updated_at", "user_id", "active"}).
+			AddRow(wr.ID, wr.Name, wr.CreatedAt, nil, wr.UpdatedAt, wr.UserID, wr.Active)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).WithArgs(fmt.Sprintf("%d", wr.ID)).WillReturnRows(workoutRoutineRow)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(workoutRoutineNotDeletedQuery)).
+			WithArgs(fmt.Sprintf("%d", ws.ID)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(wr.ID))
+		mock.ExpectQuery(regexp.QuoteMeta(restoredOpenWorkoutSessionQuery)).
+			WithArgs(fmt.Sprintf("%d", ws.ID)).
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow(u.ID))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "workout_sessions" WHERE (user_id = $1 AND "end" IS NULL) AND "workout_sessions"."deleted_at" IS NULL LIMIT 1 FOR UPDATE`)).
+			WithArgs(u.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(ws.ID + 1))
+		mock.ExpectRollback()
+
+		var resp RestoreWorkoutSessionResp
+		err := c.Post(
+			fmt.Sprintf(restoreWorkoutSessionMutation, ws.ID),
+			&resp,
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.EqualError(t, err, "[{\"message\":\"Error Restoring Workout Session: Finish The Current Workout Session First\",\"path\":[\"restoreWorkoutSession\"],\"extensions\":{\"code\":\"INVALID_INPUT\"}}]")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+	t.Run("Restore Workout Session Under A Deleted Routine", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		ac := &accesscontrol.AccessController{DB: gormDB, Cache: cache.NewMemory(time.Minute)}
+		c := helpers.NewGqlClient(gormDB, ac)
+
+		// the routine was deleted after its owner was cached
+		ac.Cache.Set(fmt.Sprintf("owner:workout_routines:%d", wr.ID), []byte(fmt.Sprintf("%d", u.ID)), time.Minute)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		deletedAt := time.Now().Add(-time.Hour)
+		workoutSessionRow := sqlmock.
+			NewRows(workoutSessionColumns).
+			AddRow(ws.ID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.UserID, ws.CreatedAt, deletedAt, ws.UpdatedAt)
+		mock.ExpectQuery(regexp.QuoteMeta(deletedWorkoutSessionQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(workoutSessionRow)
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(workoutRoutineNotDeletedQuery)).
+			WithArgs(fmt.Sprintf("%d", ws.ID)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectRollback()
+
+		var resp RestoreWorkoutSessionResp
+		err := c.Post(
+			fmt.Sprintf(restoreWorkoutSessionMutation, ws.ID),
+			&resp,
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.EqualError(t, err, "[{\"message\":\"Error Restoring Workout Session: Restore Its Workout Routine First\",\"path\":[\"restoreWorkoutSession\"],\"extensions\":{\"code\":\"NOT_FOUND\"}}]")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
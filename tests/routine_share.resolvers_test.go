@@ -0,0 +1,291 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type SharedWorkoutRoutineResp struct {
+	SharedWorkoutRoutine struct {
+		Name             string
+		ExerciseRoutines []struct {
+			Name            string
+			Sets            int
+			Reps            int
+			CatalogExercise *struct {
+				Name         string
+				MuscleGroups []string
+			}
+		}
+	}
+}
+
+const (
+	shareLinkByRoutineQuery = `SELECT * FROM "workout_routine_share_links" WHERE workout_routine_id = $1 AND "workout_routine_share_links"."deleted_at" IS NULL ORDER BY "workout_routine_share_links"."id" LIMIT 1`
+	shareLinkByTokenQuery   = `SELECT * FROM "workout_routine_share_links" WHERE token = $1 AND "workout_routine_share_links"."deleted_at" IS NULL ORDER BY "workout_routine_share_links"."id" LIMIT 1`
+	sharedRoutineQuery      = `SELECT * FROM "workout_routines" WHERE "workout_routines"."id" = $1 AND "workout_routines"."deleted_at" IS NULL`
+	sharedExerciseRoutines  = `SELECT * FROM "exercise_routines" WHERE "exercise_routines"."workout_routine_id" = $1 AND "exercise_routines"."deleted_at" IS NULL ORDER BY position, id`
+)
+
+var shareLinkColumns = []string{"id", "token", "workout_routine_id", "user_id"}
+
+func TestRoutineShareResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	userId := fmt.Sprintf("%d", u.ID)
+	wr := testdata.WorkoutRoutine
+	routineId := fmt.Sprintf("%d", wr.ID)
+	const token = "abcdefghijklmnopqrstuvwxyz012345"
+	const ownerId = 99
+
+	const shareWorkoutRoutine = `
+		mutation ShareWorkoutRoutine($workoutRoutineId: ID!) {
+			shareWorkoutRoutine(workoutRoutineId: $workoutRoutineId) {
+				token
+				workoutRoutineId
+			}
+		}`
+
+	expectSharedRoutine := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(shareLinkByTokenQuery)).
+			WithArgs(token).
+			WillReturnRows(sqlmock.NewRows(shareLinkColumns).AddRow(1, token, wr.ID, ownerId))
+		mock.ExpectQuery(regexp.QuoteMeta(sharedRoutineQuery)).
+			WithArgs(wr.ID).
+			WillReturnRows(sqlmock.NewRows(workoutRoutineColumns).AddRow(wr.ID, "Push", true, ownerId))
+		mock.ExpectQuery(regexp.QuoteMeta(sharedExerciseRoutines)).
+			WithArgs(wr.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "sets", "reps", "active", "workout_routine_id", "position", "catalog_exercise_id"}).
+				AddRow(5, "bench", 3, 8, true, wr.ID, 0, 4).
+				AddRow(6, "dips", 3, 12, true, wr.ID, 1, nil))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "catalog_exercises" WHERE "catalog_exercises"."id" = $1 AND "catalog_exercises"."deleted_at" IS NULL`)).
+			WithArgs(4).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "equipment", "instructions"}).AddRow(4, "Bench Press", "barbell", ""))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "catalog_exercise_muscle_groups" WHERE "catalog_exercise_muscle_groups"."catalog_exercise_id" = $1 AND "catalog_exercise_muscle_groups"."deleted_at" IS NULL ORDER BY id`)).
+			WithArgs(4).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "catalog_exercise_id", "muscle_group", "is_primary"}).AddRow(1, 4, "CHEST", true))
+	}
+
+	t.Run("Share Workout Routine", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).
+			WithArgs(routineId).
+			WillReturnRows(sqlmock.NewRows(workoutRoutineColumns).AddRow(wr.ID, wr.Name, true, u.ID))
+		mock.ExpectQuery(regexp.QuoteMeta(shareLinkByRoutineQuery)).
+			WithArgs(routineId).
+			WillReturnRows(sqlmock.NewRows(shareLinkColumns))
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).
+			WithArgs(routineId).
+			WillReturnRows(sqlmock.NewRows(workoutRoutineColumns).AddRow(wr.ID, wr.Name, true, u.ID))
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "workout_routine_share_links" ("created_at","updated_at","deleted_at","token","workout_routine_id","user_id") VALUES ($1,$2,$3,$4,$5,$6) RETURNING "id"`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, sqlmock.AnyArg(), wr.ID, u.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		var resp struct {
+			ShareWorkoutRoutine struct {
+				Token            string
+				WorkoutRoutineId string
+			}
+		}
+		c.MustPost(shareWorkoutRoutine, &resp,
+			client.Var("workoutRoutineId", routineId),
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.NotEmpty(t, resp.ShareWorkoutRoutine.Token)
+		require.Equal(t, routineId, resp.ShareWorkoutRoutine.WorkoutRoutineId)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Share Workout Routine Again Reuses Link", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).
+			WithArgs(routineId).
+			WillReturnRows(sqlmock.NewRows(workoutRoutineColumns).AddRow(wr.ID, wr.Name, true, u.ID))
+		mock.ExpectQuery(regexp.QuoteMeta(shareLinkByRoutineQuery)).
+			WithArgs(routineId).
+			WillReturnRows(sqlmock.NewRows(shareLinkColumns).AddRow(1, token, wr.ID, u.ID))
+
+		var resp struct {
+			ShareWorkoutRoutine struct {
+				Token            string
+				WorkoutRoutineId string
+			}
+		}
+		c.MustPost(shareWorkoutRoutine, &resp,
+			client.Var("workoutRoutineId", routineId),
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.Equal(t, token, resp.ShareWorkoutRoutine.Token)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Share Workout Routine Access Denied", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).
+			WithArgs(routineId).
+			WillReturnRows(sqlmock.NewRows(workoutRoutineColumns).AddRow(wr.ID, wr.Name, true, ownerId))
+
+		var resp struct{}
+		err := c.Post(shareWorkoutRoutine, &resp,
+			client.Var("workoutRoutineId", routineId),
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.EqualError(t, err, `[{"message":"Error Sharing Workout Routine: Access Denied","path":["shareWorkoutRoutine"],"extensions":{"code":"FORBIDDEN"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Shared Workout Routine Without Account", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectSharedRoutine(mock)
+
+		var resp SharedWorkoutRoutineResp
+		c.MustPost(fmt.Sprintf(`
+			query SharedWorkoutRoutine {
+				sharedWorkoutRoutine(token: "%s") {
+					name
+					exerciseRoutines {
+						name
+						sets
+						reps
+						catalogExercise {
+							name
+							muscleGroups
+						}
+					}
+				}
+			}`, token), &resp)
+		require.Equal(t, "Push", resp.SharedWorkoutRoutine.Name)
+		require.Len(t, resp.SharedWorkoutRoutine.ExerciseRoutines, 2)
+		require.Equal(t, "bench", resp.SharedWorkoutRoutine.ExerciseRoutines[0].Name)
+		require.Equal(t, 8, resp.SharedWorkoutRoutine.ExerciseRoutines[0].Reps)
+		require.Equal(t, "Bench Press", resp.SharedWorkoutRoutine.ExerciseRoutines[0].CatalogExercise.Name)
+		require.Equal(t, []string{"CHEST"}, resp.SharedWorkoutRoutine.ExerciseRoutines[0].CatalogExercise.MuscleGroups)
+		require.Nil(t, resp.SharedWorkoutRoutine.ExerciseRoutines[1].CatalogExercise)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Shared Workout Routine Deleted", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		mock.ExpectQuery(regexp.QuoteMeta(shareLinkByTokenQuery)).
+			WithArgs(token).
+			WillReturnRows(sqlmock.NewRows(shareLinkColumns).AddRow(1, token, wr.ID, ownerId))
+		mock.ExpectQuery(regexp.QuoteMeta(sharedRoutineQuery)).
+			WithArgs(wr.ID).
+			WillReturnRows(sqlmock.NewRows(workoutRoutineColumns))
+
+		var resp struct{}
+		err := c.Post(fmt.Sprintf(`
+			query SharedWorkoutRoutine {
+				sharedWorkoutRoutine(token: "%s") {
+					name
+				}
+			}`, token), &resp)
+		require.EqualError(t, err, `[{"message":"Error Getting Shared Workout Routine: Invalid Share Link","path":["sharedWorkoutRoutine"],"extensions":{"code":"NOT_FOUND"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Import Shared Routine", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		expectSharedRoutine(mock)
+
+		// only the routine and its exercise routines are copied
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "workout_routines" ("created_at","updated_at","deleted_at","name","active","user_id") VALUES ($1,$2,$3,$4,$5,$6) RETURNING "id"`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, "Push", true, u.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(20))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "exercise_routines" ("created_at","updated_at","deleted_at","name","sets","reps","active","workout_routine_id","position","catalog_exercise_id") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10),($11,$12,$13,$14,$15,$16,$17,$18,$19,$20)`)).
+			WithArgs(
+				sqlmock.AnyArg(), sqlmock.AnyArg(), nil, "bench", 3, 8, true, 20, 0, 4,
+				sqlmock.AnyArg(), sqlmock.AnyArg(), nil, "dips", 3, 12, true, 20, 1, nil,
+			).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(21).AddRow(22))
+		mock.ExpectCommit()
+
+		var resp struct {
+			ImportSharedRoutine struct {
+				ID   string
+				Name string
+			}
+		}
+		c.MustPost(`
+			mutation ImportSharedRoutine($token: String!) {
+				importSharedRoutine(token: $token) {
+					id
+					name
+				}
+			}`, &resp,
+			client.Var("token", token),
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.Equal(t, "20", resp.ImportSharedRoutine.ID)
+		require.Equal(t, "Push", resp.ImportSharedRoutine.Name)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
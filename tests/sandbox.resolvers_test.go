@@ -0,0 +1,93 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type ResetSandboxResp struct {
+	ResetSandbox bool
+}
+
+// not parallel since the sandbox flag comes from the environment
+func TestSandboxResolvers(t *testing.T) {
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+
+	const resetSandboxMutation = `
+		mutation ResetSandbox {
+			resetSandbox
+		}`
+
+	t.Run("Reset Sandbox Not In Sandbox Mode", func(t *testing.T) {
+		t.Setenv(config.SANDBOX, "")
+
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		adminRow := sqlmock.NewRows([]string{"id", "verified", "admin"}).AddRow(u.ID, true, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(adminRow)
+
+		var resp ResetSandboxResp
+		err := c.Post(resetSandboxMutation, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, "[{\"message\":\"Error Resetting Sandbox: Server Is Not In Sandbox Mode\",\"path\":[\"resetSandbox\"],\"extensions\":{\"code\":\"FORBIDDEN\"}}]")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Reset Sandbox Not Admin", func(t *testing.T) {
+		t.Setenv(config.SANDBOX, "true")
+
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified", "admin"}).AddRow(u.ID, true, false)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		var resp ResetSandboxResp
+		err := c.Post(resetSandboxMutation, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, "[{\"message\":\"Access Denied\",\"path\":[\"resetSandbox\"],\"extensions\":{\"code\":\"FORBIDDEN\"}}]")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+	t.Run("Reset Wipes Every Table But Users And The Catalog", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+
+		// every model's table, but the admins in users and the catalog stay
+		const truncateStmt = `TRUNCATE "workout_routines", "exercise_routines", "workout_sessions", "exercises", "set_entries", "request_recordings", "workout_session_share_links", "workout_session_participants", "coaches", "exercise_videos", "video_annotations", "archived_workout_sessions", "body_weight_entries", "goals", "account_exports", "nutrition_logs", "sleep_logs", "unit_conversions", "workout_session_unlocks", "quick_phrases", "workout_session_auto_finishes", "snapshots", "programs", "program_days", "workout_routine_share_links", "webhooks", "webhook_deliveries", "strava_connections", "strava_uploads", "progress_photos", "tags", "workout_session_tags", "sync_records", "audit_logs", "password_reset_tokens", "refresh_tokens", "sessions", "login_throttles", "user_preferences", "push_tokens", "weekly_digests" CASCADE`
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(truncateStmt)).
+			WillReturnError(sqlmock.ErrCancelled)
+		mock.ExpectRollback()
+
+		err := database.ResetSandbox(gormDB)
+		require.ErrorIs(t, err, sqlmock.ErrCancelled)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
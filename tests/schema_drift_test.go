@@ -0,0 +1,61 @@
+package test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaDrift(t *testing.T) {
+	t.Parallel()
+
+	const columnsQuery = `SELECT table_name, column_name, is_nullable, column_default AS "default" FROM information_schema.columns WHERE table_schema = CURRENT_SCHEMA()`
+	const indexesQuery = `SELECT tablename AS table_name, indexname AS index_name, indexdef AS index_def FROM pg_indexes WHERE schemaname = CURRENT_SCHEMA()`
+
+	t.Run("Schema Drift", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+
+		columns := sqlmock.NewRows([]string{"table_name", "column_name", "is_nullable", "default"})
+		for _, c := range []string{"id", "created_at", "updated_at", "deleted_at", "user_id", "night"} {
+			columns.AddRow("sleep_logs", c, "YES", nil)
+		}
+		columns.AddRow("sleep_logs", "source", "NO", nil)
+		columns.AddRow("sleep_logs", "quality", "NO", "0")
+		for _, c := range []string{"id", "created_at", "updated_at", "deleted_at", "name", "email", "password", "verified", "verification_code", "verification_sent_at", "password_reset_code", "password_reset_sent_at", "pending_email", "email_change_code", "email_change_sent_at", "admin", "record_requests_until", "week_start", "locale", "weight_unit"} {
+			columns.AddRow("users", c, "YES", nil)
+		}
+		mock.ExpectQuery(regexp.QuoteMeta(columnsQuery)).WillReturnRows(columns)
+
+		indexes := sqlmock.NewRows([]string{"table_name", "index_name", "index_def"}).
+			AddRow("sleep_logs", "idx_sleep_user_night", "CREATE UNIQUE INDEX idx_sleep_user_night ON public.sleep_logs USING btree (user_id, night)").
+			AddRow("users", "idx_users_deleted_at", "CREATE INDEX idx_users_deleted_at ON public.users USING btree (deleted_at)").
+			AddRow("users", "users_email_key", "CREATE UNIQUE INDEX users_email_key ON public.users USING btree (email)").
+			AddRow("users", "users_verification_code_key", "CREATE UNIQUE INDEX users_verification_code_key ON public.users USING btree (verification_code)").
+			AddRow("users", "users_password_reset_code_key", "CREATE UNIQUE INDEX users_password_reset_code_key ON public.users USING btree (password_reset_code)")
+		mock.ExpectQuery(regexp.QuoteMeta(indexesQuery)).WillReturnRows(indexes)
+
+		drift, err := database.CheckSchemaDrift(gormDB, database.SleepLog{}, database.User{}, database.Goal{})
+		require.NoError(t, err)
+
+		problems := []string{}
+		for _, d := range drift {
+			problems = append(problems, d.String())
+		}
+		require.Equal(t, []string{
+			"sleep_logs: column hours is missing, try ALTER TABLE sleep_logs ADD COLUMN hours decimal",
+			"sleep_logs: column source isn't on the model and is NOT NULL without a default, try ALTER TABLE sleep_logs ALTER COLUMN source DROP NOT NULL",
+			"sleep_logs: index idx_sleep_logs_deleted_at is missing",
+			"users: column email_change_code is not unique, try ALTER TABLE users ADD UNIQUE (email_change_code)",
+			"goals: table is missing",
+		}, problems)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,143 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type SearchResp struct {
+	Search []struct {
+		Typename string `json:"__typename"`
+		ID       string
+		Name     string
+		Notes    string
+	}
+}
+
+func TestSearch(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	userId := fmt.Sprintf("%d", u.ID)
+	ws := testdata.WorkoutSession
+
+	const searchQuery = `WITH q AS (SELECT plainto_tsquery('english', $1) AS query)`
+
+	t.Run("Search Orders Results By Relevance", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+
+		mock.ExpectQuery(regexp.QuoteMeta(searchQuery)).
+			WithArgs("pressing", userId, userId, userId, userId, 5).
+			WillReturnRows(sqlmock.NewRows([]string{"kind", "id", "rank"}).
+				AddRow("exercise_routine", 7, 0.9).
+				AddRow("workout_session", ws.ID, 0.6).
+				AddRow("exercise", 12, 0.4).
+				AddRow("workout_routine", 3, 0.1))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_routines" WHERE id IN ($1) AND "workout_routines"."deleted_at" IS NULL`)).
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "active", "user_id"}).AddRow(3, "Press Day", true, u.ID))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "exercise_routines" WHERE id IN ($1) AND "exercise_routines"."deleted_at" IS NULL`)).
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "sets", "reps", "active"}).AddRow(7, "Bench Press", 3, 8, true))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "exercises" WHERE id IN ($1) AND "exercises"."deleted_at" IS NULL`)).
+			WithArgs(12).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "notes", "user_id"}).AddRow(12, "pressed with a pause", u.ID))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_sessions" WHERE id IN ($1) AND "workout_sessions"."deleted_at" IS NULL`)).
+			WithArgs(ws.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "start", "workout_routine_id", "notes"}).AddRow(ws.ID, u.ID, ws.Start, ws.WorkoutRoutineID, "pressing felt good"))
+
+		var resp SearchResp
+		c.MustPost(`
+			query Search {
+				search(query: " pressing ", limit: 5) {
+					__typename
+					... on WorkoutRoutine { id name }
+					... on ExerciseRoutine { id name }
+					... on Exercise { id notes }
+					... on WorkoutSession { id notes }
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+
+		require.Len(t, resp.Search, 4)
+		require.Equal(t, "ExerciseRoutine", resp.Search[0].Typename)
+		require.Equal(t, "Bench Press", resp.Search[0].Name)
+		require.Equal(t, "WorkoutSession", resp.Search[1].Typename)
+		require.Equal(t, "pressing felt good", resp.Search[1].Notes)
+		require.Equal(t, "Exercise", resp.Search[2].Typename)
+		require.Equal(t, "12", resp.Search[2].ID)
+		require.Equal(t, "WorkoutRoutine", resp.Search[3].Typename)
+		require.Equal(t, "Press Day", resp.Search[3].Name)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Search Without Hits", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(searchQuery)).
+			WithArgs("zercher", userId, userId, userId, userId, 20).
+			WillReturnRows(sqlmock.NewRows([]string{"kind", "id", "rank"}))
+
+		var resp SearchResp
+		c.MustPost(`
+			query Search {
+				search(query: "zercher") {
+					__typename
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Len(t, resp.Search, 0)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Search Limit Too High", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+
+		var resp SearchResp
+		err := c.Post(`
+			query Search {
+				search(query: "bench", limit: 51) {
+					__typename
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Searching: limit must be between 1 and 50","path":["search"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
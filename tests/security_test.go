@@ -0,0 +1,156 @@
+package test
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/stretchr/testify/require"
+)
+
+// not parallel, origins are read from the environment
+func TestAllowedOrigins(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		t.Setenv(config.CORS_ALLOWED_ORIGINS, "")
+		t.Setenv("K_SERVICE", "")
+
+		origins, err := config.AllowedOriginsFromEnv()
+		require.NoError(t, err)
+		require.Equal(t, config.DefaultAllowedOrigins, origins)
+	})
+
+	t.Run("Required On Cloud Run", func(t *testing.T) {
+		t.Setenv(config.CORS_ALLOWED_ORIGINS, "")
+		t.Setenv("K_SERVICE", "workout-logger-api")
+
+		_, err := config.AllowedOriginsFromEnv()
+		require.EqualError(t, err, `CORS_ALLOWED_ORIGINS needs to be set on Cloud Run`)
+
+		t.Setenv(config.CORS_ALLOWED_ORIGINS, "https://app.untilfailure.app")
+		origins, err := config.AllowedOriginsFromEnv()
+		require.NoError(t, err)
+		require.Equal(t, []string{"https://app.untilfailure.app"}, origins)
+	})
+
+	t.Run("From Environment", func(t *testing.T) {
+		t.Setenv(config.CORS_ALLOWED_ORIGINS, "https://App.untilfailure.app, https://*.untilfailure.app,,http://localhost:19006")
+
+		origins, err := config.AllowedOriginsFromEnv()
+		require.NoError(t, err)
+		require.Equal(t, []string{"https://app.untilfailure.app", "https://*.untilfailure.app", "http://localhost:19006"}, origins)
+	})
+
+	t.Run("Invalid Origins", func(t *testing.T) {
+		for _, origin := range []string{"*", "untilfailure.app", "https://untilfailure.app/", "ftp://untilfailure.app", "https://*.*.untilfailure.app"} {
+			t.Setenv(config.CORS_ALLOWED_ORIGINS, origin)
+			_, err := config.AllowedOriginsFromEnv()
+			require.EqualError(t, err, `CORS_ALLOWED_ORIGINS needs to be origins like https://app.untilfailure.app, got "`+origin+`"`)
+		}
+
+		t.Setenv(config.CORS_ALLOWED_ORIGINS, " , ")
+		_, err := config.AllowedOriginsFromEnv()
+		require.EqualError(t, err, `CORS_ALLOWED_ORIGINS needs at least one origin`)
+	})
+}
+
+// not parallel, proxies are read from the environment
+func TestTrustedProxies(t *testing.T) {
+	t.Run("None By Default", func(t *testing.T) {
+		t.Setenv(config.TRUSTED_PROXIES, "")
+
+		proxies, err := config.TrustedProxiesFromEnv()
+		require.NoError(t, err)
+		require.Empty(t, proxies)
+	})
+
+	t.Run("Addresses And Ranges", func(t *testing.T) {
+		t.Setenv(config.TRUSTED_PROXIES, "10.0.0.0/8, 169.254.1.1,,")
+
+		proxies, err := config.TrustedProxiesFromEnv()
+		require.NoError(t, err)
+		require.Len(t, proxies, 2)
+		require.Equal(t, "10.0.0.0/8", proxies[0].String())
+		require.Equal(t, "169.254.1.1/32", proxies[1].String())
+	})
+
+	t.Run("Invalid Proxies", func(t *testing.T) {
+		for _, proxy := range []string{"proxy.internal", "10.0.0.0/33"} {
+			t.Setenv(config.TRUSTED_PROXIES, proxy)
+			_, err := config.TrustedProxiesFromEnv()
+			require.EqualError(t, err, `TRUSTED_PROXIES needs to be addresses or ranges like 10.0.0.0/8, got "`+proxy+`"`)
+		}
+	})
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	t.Parallel()
+
+	handler := middleware.SecurityHeaders(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	t.Run("Plain Http", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/query", nil))
+
+		require.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+		require.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+		require.Equal(t, "frame-ancestors 'none'", w.Header().Get("Content-Security-Policy"))
+		require.Equal(t, "no-referrer", w.Header().Get("Referrer-Policy"))
+		require.Empty(t, w.Header().Get("Strict-Transport-Security"))
+	})
+
+	t.Run("Https Gets Hsts", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodPost, "/query", nil)
+		r.Header.Set("X-Forwarded-Proto", "https")
+		handler.ServeHTTP(w, r)
+		require.Equal(t, "max-age=63072000; includeSubDomains", w.Header().Get("Strict-Transport-Security"))
+
+		w = httptest.NewRecorder()
+		r = httptest.NewRequest(http.MethodPost, "/query", nil)
+		r.TLS = &tls.ConnectionState{}
+		handler.ServeHTTP(w, r)
+		require.Equal(t, "max-age=63072000; includeSubDomains", w.Header().Get("Strict-Transport-Security"))
+	})
+}
+
+func TestLimitRequestBody(t *testing.T) {
+	t.Parallel()
+
+	// reads the whole body like the graphql transports do
+	handler := middleware.LimitRequestBody(10, 20)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		}
+	}))
+
+	post := func(body string, contentType string, chunked bool) int {
+		r := httptest.NewRequest(http.MethodPost, "/query", strings.NewReader(body))
+		r.Header.Set("Content-Type", contentType)
+		if chunked {
+			r.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	t.Run("Json", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, post(`{"a":"bc"}`, "application/json", false))
+		require.Equal(t, http.StatusRequestEntityTooLarge, post(`{"a":"bcd"}`, "application/json", false))
+	})
+
+	t.Run("Body Without A Length Stops At The Limit", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, post(`{"a":"bc"}`, "application/json", true))
+		require.Equal(t, http.StatusRequestEntityTooLarge, post(`{"a":"bcd"}`, "application/json", true))
+	})
+
+	t.Run("Uploads Get More Room", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, post(strings.Repeat("x", 20), "multipart/form-data; boundary=x", false))
+		require.Equal(t, http.StatusRequestEntityTooLarge, post(strings.Repeat("x", 21), "multipart/form-data; boundary=x", false))
+	})
+}
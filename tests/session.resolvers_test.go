@@ -0,0 +1,137 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type ActiveSessionsResp struct {
+	ActiveSessions []struct {
+		ID         string
+		UserAgent  string
+		IP         string
+		LastUsedAt string
+		Origin     *struct {
+			Platform string
+		}
+	}
+}
+
+func TestSessions(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	lastUsed := time.Date(2022, 10, 30, 12, 0, 0, 0, time.UTC)
+	const sessionQuery = `SELECT * FROM "sessions" WHERE id = $1 AND "sessions"."deleted_at" IS NULL ORDER BY "sessions"."id" LIMIT 1`
+
+	t.Run("Active Sessions", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "sessions" WHERE (user_id = $1 AND revoked_at IS NULL AND last_used_at > $2) AND "sessions"."deleted_at" IS NULL ORDER BY last_used_at DESC`)).
+			WithArgs(u.ID, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "user_agent", "ip", "origin_platform", "last_used_at"}).
+				AddRow(3, u.ID, "TilFailure/2.4.0 CFNetwork", "203.0.113.7", "ios", lastUsed).
+				AddRow(1, u.ID, "Mozilla/5.0", "198.51.100.2", "", lastUsed.Add(-time.Hour)))
+
+		var resp ActiveSessionsResp
+		c.MustPost(`query {
+			activeSessions {
+				id
+				userAgent
+				ip
+				lastUsedAt
+				origin {
+					platform
+				}
+			}
+		}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+
+		require.Len(t, resp.ActiveSessions, 2)
+		require.Equal(t, "3", resp.ActiveSessions[0].ID)
+		require.Equal(t, "TilFailure/2.4.0 CFNetwork", resp.ActiveSessions[0].UserAgent)
+		require.Equal(t, "203.0.113.7", resp.ActiveSessions[0].IP)
+		require.Equal(t, "ios", resp.ActiveSessions[0].Origin.Platform)
+		require.Equal(t, "2022-10-30T12:00:00Z", resp.ActiveSessions[0].LastUsedAt)
+		require.Nil(t, resp.ActiveSessions[1].Origin)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Revoke Session", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		mock.ExpectQuery(regexp.QuoteMeta(sessionQuery)).
+			WithArgs("3").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "family_id"}).AddRow(3, u.ID, "stolen-phone"))
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "refresh_tokens" SET "revoked_at"=$1,"updated_at"=$2 WHERE (user_id = $3 AND family_id = $4 AND revoked_at IS NULL) AND "refresh_tokens"."deleted_at" IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), u.ID, "stolen-phone").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "sessions" SET "revoked_at"=$1,"updated_at"=$2 WHERE (user_id = $3 AND family_id = $4 AND revoked_at IS NULL) AND "sessions"."deleted_at" IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), u.ID, "stolen-phone").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		var resp struct {
+			RevokeSession int
+		}
+		c.MustPost(`mutation { revokeSession(sessionId: "3") }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, 1, resp.RevokeSession)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Revoke Someone Else's Session", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		mock.ExpectQuery(regexp.QuoteMeta(sessionQuery)).
+			WithArgs("3").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "family_id"}).AddRow(3, u.ID+1, "family"))
+
+		var resp struct {
+			RevokeSession int
+		}
+		err := c.Post(`mutation { revokeSession(sessionId: "3") }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Revoking Session: Access Denied","path":["revokeSession"],"extensions":{"code":"FORBIDDEN"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
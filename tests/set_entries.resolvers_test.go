@@ -70,10 +70,14 @@ func TestSetEntryResolvers(t *testing.T) {
 			AddRow(ws.ID, ws.UserID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.CreatedAt, ws.DeletedAt, ws.UpdatedAt)
 		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(workoutSessionRow)
 
+		nextSetOrderQuery := `SELECT COALESCE(MAX(set_order), 0) + 1 FROM "set_entries" WHERE exercise_id = $1 AND "set_entries"."deleted_at" IS NULL`
+		mock.ExpectQuery(regexp.QuoteMeta(nextSetOrderQuery)).WithArgs(fmt.Sprintf("%d", s.ExerciseID)).WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(1))
+
+		helpers.ExpectEditableWorkoutSession(mock, ws.ID)
 		mock.ExpectBegin()
-		addSetEntriesQuery := `INSERT INTO "set_entries" ("created_at","updated_at","deleted_at","weight","reps","exercise_id") VALUES ($1,$2,$3,$4,$5,$6) RETURNING "id"`
+		addSetEntriesQuery := `INSERT INTO "set_entries" ("created_at","updated_at","deleted_at","weight","unit","reps","exercise_id","set_order","type","rest_time_seconds","completed_at","client_metadata","origin_platform","origin_app_version","origin_device_id") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15) RETURNING "id"`
 		mock.ExpectQuery(regexp.QuoteMeta(addSetEntriesQuery)).
-			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), s.Weight, s.Reps, s.ExerciseID).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), s.Weight, "LB", s.Reps, s.ExerciseID, 1, "WORKING", nil, nil, nil, "", "", "").
 			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(s.ID))
 		mock.ExpectCommit()
 
@@ -138,7 +142,7 @@ func TestSetEntryResolvers(t *testing.T) {
 			&resp,
 			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
 		)
-		require.EqualError(t, err, "[{\"message\":\"Error Adding Set\",\"path\":[\"addSet\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Adding Set\",\"path\":[\"addSet\"],\"extensions\":{\"code\":\"INTERNAL\"}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -160,7 +164,7 @@ func TestSetEntryResolvers(t *testing.T) {
 			&resp,
 			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
 		)
-		require.EqualError(t, err, "[{\"message\":\"Reps needs to be between 0 and 9999\",\"path\":[\"addSet\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"reps needs to be between 0 and 9999\",\"path\":[\"addSet\"],\"extensions\":{\"code\":\"INVALID_INPUT\",\"fields\":[{\"field\":\"set.reps\",\"message\":\"reps needs to be between 0 and 9999\"}]}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -182,7 +186,7 @@ func TestSetEntryResolvers(t *testing.T) {
 			&resp,
 			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
 		)
-		require.EqualError(t, err, "[{\"message\":\"Reps needs to be between 0 and 9999\",\"path\":[\"addSet\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"reps needs to be between 0 and 9999\",\"path\":[\"addSet\"],\"extensions\":{\"code\":\"INVALID_INPUT\",\"fields\":[{\"field\":\"set.reps\",\"message\":\"reps needs to be between 0 and 9999\"}]}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -204,7 +208,7 @@ func TestSetEntryResolvers(t *testing.T) {
 			&resp,
 			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
 		)
-		require.EqualError(t, err, "[{\"message\":\"Weight needs to be between 0 and 9999\",\"path\":[\"addSet\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"weight needs to be between 0 and 9999\",\"path\":[\"addSet\"],\"extensions\":{\"code\":\"INVALID_INPUT\",\"fields\":[{\"field\":\"set.weight\",\"message\":\"weight needs to be between 0 and 9999\"}]}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -226,7 +230,7 @@ func TestSetEntryResolvers(t *testing.T) {
 			&resp,
 			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
 		)
-		require.EqualError(t, err, "[{\"message\":\"Weight needs to be between 0 and 9999\",\"path\":[\"addSet\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"weight needs to be between 0 and 9999\",\"path\":[\"addSet\"],\"extensions\":{\"code\":\"INVALID_INPUT\",\"fields\":[{\"field\":\"set.weight\",\"message\":\"weight needs to be between 0 and 9999\"}]}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -253,7 +257,7 @@ func TestSetEntryResolvers(t *testing.T) {
 			&resp,
 			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
 		)
-		require.EqualError(t, err, "[{\"message\":\"Error Adding Set: record not found\",\"path\":[\"addSet\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Adding Set: record not found\",\"path\":[\"addSet\"],\"extensions\":{\"code\":\"NOT_FOUND\"}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -279,10 +283,14 @@ func TestSetEntryResolvers(t *testing.T) {
 			AddRow(ws.ID, ws.UserID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.CreatedAt, ws.DeletedAt, ws.UpdatedAt)
 		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(workoutSessionRow)
 
+		nextSetOrderQuery := `SELECT COALESCE(MAX(set_order), 0) + 1 FROM "set_entries" WHERE exercise_id = $1 AND "set_entries"."deleted_at" IS NULL`
+		mock.ExpectQuery(regexp.QuoteMeta(nextSetOrderQuery)).WithArgs(fmt.Sprintf("%d", s.ExerciseID)).WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(1))
+
+		helpers.ExpectEditableWorkoutSession(mock, ws.ID)
 		mock.ExpectBegin()
-		addSetEntriesQuery := `INSERT INTO "set_entries" ("created_at","updated_at","deleted_at","weight","reps","exercise_id") VALUES ($1,$2,$3,$4,$5,$6) RETURNING "id"`
+		addSetEntriesQuery := `INSERT INTO "set_entries" ("created_at","updated_at","deleted_at","weight","unit","reps","exercise_id","set_order","type","rest_time_seconds","completed_at","client_metadata","origin_platform","origin_app_version","origin_device_id") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15) RETURNING "id"`
 		mock.ExpectQuery(regexp.QuoteMeta(addSetEntriesQuery)).
-			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), s.Weight, s.Reps, s.ExerciseID).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), s.Weight, "LB", s.Reps, s.ExerciseID, 1, "WORKING", nil, nil, nil, "", "", "").
 			WillReturnError(gorm.ErrInvalidTransaction)
 		mock.ExpectRollback()
 
@@ -295,7 +303,7 @@ func TestSetEntryResolvers(t *testing.T) {
 			&resp,
 			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
 		)
-		require.EqualError(t, err, "[{\"message\":\"Error Adding Set\",\"path\":[\"addSet\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Adding Set\",\"path\":[\"addSet\"],\"extensions\":{\"code\":\"INTERNAL\"}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -320,7 +328,7 @@ func TestSetEntryResolvers(t *testing.T) {
 		for _, s := range e.Sets {
 			setEntryRows.AddRow(s.ID, s.CreatedAt, s.DeletedAt, s.UpdatedAt, s.Weight, s.Reps, s.ExerciseID)
 		}
-		const getSetEntries = `SELECT * FROM "set_entries" WHERE "set_entries"."exercise_id" = $1 AND "set_entries"."deleted_at" IS NULL`
+		const getSetEntries = `SELECT * FROM "set_entries" WHERE "set_entries"."exercise_id" = $1 AND "set_entries"."deleted_at" IS NULL ORDER BY set_order, id`
 		mock.ExpectQuery(regexp.QuoteMeta(getSetEntries)).
 			WithArgs(e.ID).
 			WillReturnRows(setEntryRows)
@@ -387,7 +395,7 @@ func TestSetEntryResolvers(t *testing.T) {
 		for _, s := range e.Sets {
 			setEntryRows.AddRow(s.ID, s.CreatedAt, s.DeletedAt, s.UpdatedAt, s.Weight, s.Reps, s.ExerciseID)
 		}
-		const getSetEntries = `SELECT * FROM "set_entries" WHERE "set_entries"."exercise_id" = $1 AND "set_entries"."deleted_at" IS NULL`
+		const getSetEntries = `SELECT * FROM "set_entries" WHERE "set_entries"."exercise_id" = $1 AND "set_entries"."deleted_at" IS NULL ORDER BY set_order, id`
 		mock.ExpectQuery(regexp.QuoteMeta(getSetEntries)).
 			WithArgs(e.ID).
 			WillReturnRows(setEntryRows)
@@ -411,7 +419,7 @@ func TestSetEntryResolvers(t *testing.T) {
 			&resp,
 			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
 		)
-		require.EqualError(t, err, "[{\"message\":\"Error Getting Sets: Access Denied\",\"path\":[\"sets\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Getting Sets: Access Denied\",\"path\":[\"sets\"],\"extensions\":{\"code\":\"FORBIDDEN\"}}]")
 	})
 
 	t.Run("Update Set Success", func(t *testing.T) {
@@ -441,6 +449,7 @@ func TestSetEntryResolvers(t *testing.T) {
 		setEntryRow := sqlmock.NewRows([]string{"id", "created_at", "deleted_at", "updated_at", "weight", "reps", "exercise_id"}).
 			AddRow(s.ID, s.CreatedAt, s.DeletedAt, s.UpdatedAt, s.Weight, s.Reps, s.ExerciseID)
 
+		helpers.ExpectEditableWorkoutSession(mock, ws.ID)
 		mock.ExpectBegin()
 		updateSetQuery := `UPDATE "set_entries" SET "updated_at"=$1,"weight"=$2 WHERE id = $3 AND "set_entries"."deleted_at" IS NULL RETURNING *`
 		mock.ExpectQuery(regexp.QuoteMeta(updateSetQuery)).
@@ -527,7 +536,7 @@ func TestSetEntryResolvers(t *testing.T) {
 			&resp,
 			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
 		)
-		require.EqualError(t, err, "[{\"message\":\"Error Updating Set: Access Denied\",\"path\":[\"updateSet\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Updating Set: Access Denied\",\"path\":[\"updateSet\"],\"extensions\":{\"code\":\"FORBIDDEN\"}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -554,7 +563,7 @@ func TestSetEntryResolvers(t *testing.T) {
 			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
 		)
 
-		require.EqualError(t, err, "[{\"message\":\"Reps needs to be between 0 and 9999\",\"path\":[\"updateSet\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"reps needs to be between 0 and 9999\",\"path\":[\"updateSet\"],\"extensions\":{\"code\":\"INVALID_INPUT\",\"fields\":[{\"field\":\"set.reps\",\"message\":\"reps needs to be between 0 and 9999\"}]}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -581,7 +590,7 @@ func TestSetEntryResolvers(t *testing.T) {
 			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
 		)
 
-		require.EqualError(t, err, "[{\"message\":\"Reps needs to be between 0 and 9999\",\"path\":[\"updateSet\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"reps needs to be between 0 and 9999\",\"path\":[\"updateSet\"],\"extensions\":{\"code\":\"INVALID_INPUT\",\"fields\":[{\"field\":\"set.reps\",\"message\":\"reps needs to be between 0 and 9999\"}]}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -608,7 +617,7 @@ func TestSetEntryResolvers(t *testing.T) {
 			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
 		)
 
-		require.EqualError(t, err, "[{\"message\":\"Weight needs to be between 0 and 9999\",\"path\":[\"updateSet\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"weight needs to be between 0 and 9999\",\"path\":[\"updateSet\"],\"extensions\":{\"code\":\"INVALID_INPUT\",\"fields\":[{\"field\":\"set.weight\",\"message\":\"weight needs to be between 0 and 9999\"}]}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -635,7 +644,7 @@ func TestSetEntryResolvers(t *testing.T) {
 			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
 		)
 
-		require.EqualError(t, err, "[{\"message\":\"Weight needs to be between 0 and 9999\",\"path\":[\"updateSet\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"weight needs to be between 0 and 9999\",\"path\":[\"updateSet\"],\"extensions\":{\"code\":\"INVALID_INPUT\",\"fields\":[{\"field\":\"set.weight\",\"message\":\"weight needs to be between 0 and 9999\"}]}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -667,6 +676,7 @@ func TestSetEntryResolvers(t *testing.T) {
 			AddRow(ws.ID, ws.UserID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.CreatedAt, ws.DeletedAt, ws.UpdatedAt)
 		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(workoutSessionRow)
 
+		helpers.ExpectEditableWorkoutSession(mock, ws.ID)
 		mock.ExpectBegin()
 		updateSetQuery := `UPDATE "set_entries" SET "updated_at"=$1,"weight"=$2 WHERE id = $3 AND "set_entries"."deleted_at" IS NULL RETURNING *`
 		mock.ExpectQuery(regexp.QuoteMeta(updateSetQuery)).
@@ -687,7 +697,7 @@ func TestSetEntryResolvers(t *testing.T) {
 			&resp,
 			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
 		)
-		require.EqualError(t, err, "[{\"message\":\"Error Updating Set\",\"path\":[\"updateSet\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Updating Set\",\"path\":[\"updateSet\"],\"extensions\":{\"code\":\"INTERNAL\"}}]")
 
 		err := mock.ExpectationsWereMet()
 		if err != nil {
@@ -719,6 +729,7 @@ func TestSetEntryResolvers(t *testing.T) {
 			AddRow(ws.ID, ws.UserID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.CreatedAt, ws.DeletedAt, ws.UpdatedAt)
 		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(workoutSessionRow)
 
+		helpers.ExpectEditableWorkoutSession(mock, ws.ID)
 		mock.ExpectBegin()
 		deleteSetQuery := `UPDATE "set_entries" SET "deleted_at"=$1 WHERE id = $2 AND "set_entries"."deleted_at" IS NULL`
 		mock.ExpectExec(regexp.QuoteMeta(deleteSetQuery)).
@@ -793,7 +804,7 @@ func TestSetEntryResolvers(t *testing.T) {
 			&resp,
 			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
 		)
-		require.EqualError(t, err, "[{\"message\":\"Error Deleting Set: Access Denied\",\"path\":[\"deleteSet\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Deleting Set: Access Denied\",\"path\":[\"deleteSet\"],\"extensions\":{\"code\":\"FORBIDDEN\"}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -0,0 +1,213 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type AddTypedSetResp struct {
+	AddSet struct {
+		ID       string
+		SetOrder int
+		Type     string
+	}
+}
+
+type AddWeightedSetResp struct {
+	AddSet struct {
+		ID     string
+		Weight float64
+		Unit   string
+	}
+}
+
+type AddTimedSetResp struct {
+	AddSet struct {
+		ID              string
+		RestTimeSeconds *int
+		CompletedAt     string
+	}
+}
+
+func TestSetTypeResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	e := testdata.WorkoutSession.Exercises[0]
+	s := testdata.WorkoutSession.Exercises[0].Sets[0]
+
+	exerciseColumns := []string{"id", "created_at", "deleted_at", "updated_at", "workout_session_id", "exercise_routine_id", "user_id"}
+
+	t.Run("Add Warmup Set With Order", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		exerciseRow := sqlmock.NewRows(exerciseColumns).
+			AddRow(e.ID, e.CreatedAt, nil, e.UpdatedAt, e.WorkoutSessionID, e.ExerciseRoutineID, u.ID)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.ExerciseForUserQuery)).WithArgs(fmt.Sprintf("%d", e.ID), fmt.Sprintf("%d", u.ID)).WillReturnRows(exerciseRow)
+		helpers.ExpectEditableWorkoutSession(mock, e.WorkoutSessionID)
+
+		mock.ExpectBegin()
+		const addSetStmt = `INSERT INTO "set_entries" ("created_at","updated_at","deleted_at","weight","unit","reps","exercise_id","set_order","type","rest_time_seconds","completed_at","client_metadata","origin_platform","origin_app_version","origin_device_id") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15) RETURNING "id"`
+		mock.ExpectQuery(regexp.QuoteMeta(addSetStmt)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), s.Weight, "LB", s.Reps, e.ID, 2, "WARMUP", nil, nil, nil, "", "", "").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(s.ID))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.TouchExerciseStmt)).
+			WithArgs(sqlmock.AnyArg(), e.ID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.TouchExerciseSessionStmt)).
+			WithArgs(sqlmock.AnyArg(), e.ID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		var resp AddTypedSetResp
+		mutation := fmt.Sprintf(`
+			mutation AddSet {
+				addSet(exerciseId: "%d", set: { weight: %f, reps: %d, setOrder: 2, type: WARMUP }) {
+					id
+					setOrder
+					type
+				}
+			}`, e.ID, s.Weight, s.Reps)
+		c.MustPost(mutation, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, fmt.Sprintf("%d", s.ID), resp.AddSet.ID)
+		require.Equal(t, 2, resp.AddSet.SetOrder)
+		require.Equal(t, "WARMUP", resp.AddSet.Type)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Add Set With Rest Time", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		exerciseRow := sqlmock.NewRows(exerciseColumns).
+			AddRow(e.ID, e.CreatedAt, nil, e.UpdatedAt, e.WorkoutSessionID, e.ExerciseRoutineID, u.ID)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.ExerciseForUserQuery)).WithArgs(fmt.Sprintf("%d", e.ID), fmt.Sprintf("%d", u.ID)).WillReturnRows(exerciseRow)
+		helpers.ExpectEditableWorkoutSession(mock, e.WorkoutSessionID)
+
+		completedAt := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+		mock.ExpectBegin()
+		const addSetStmt = `INSERT INTO "set_entries" ("created_at","updated_at","deleted_at","weight","unit","reps","exercise_id","set_order","type","rest_time_seconds","completed_at","client_metadata","origin_platform","origin_app_version","origin_device_id") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15) RETURNING "id"`
+		mock.ExpectQuery(regexp.QuoteMeta(addSetStmt)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), s.Weight, "LB", s.Reps, e.ID, 3, "WORKING", 120, completedAt, nil, "", "", "").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(s.ID))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.TouchExerciseStmt)).
+			WithArgs(sqlmock.AnyArg(), e.ID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.TouchExerciseSessionStmt)).
+			WithArgs(sqlmock.AnyArg(), e.ID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		var resp AddTimedSetResp
+		mutation := fmt.Sprintf(`
+			mutation AddSet {
+				addSet(exerciseId: "%d", set: { weight: %f, reps: %d, setOrder: 3, restTimeSeconds: 120, completedAt: "2022-10-01T12:00:00Z" }) {
+					id
+					restTimeSeconds
+					completedAt
+				}
+			}`, e.ID, s.Weight, s.Reps)
+		c.MustPost(mutation, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, 120, *resp.AddSet.RestTimeSeconds)
+		require.Equal(t, "2022-10-01T12:00:00Z", resp.AddSet.CompletedAt)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Add Set Rounds Weight", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		exerciseRow := sqlmock.NewRows(exerciseColumns).
+			AddRow(e.ID, e.CreatedAt, nil, e.UpdatedAt, e.WorkoutSessionID, e.ExerciseRoutineID, u.ID)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.ExerciseForUserQuery)).WithArgs(fmt.Sprintf("%d", e.ID), fmt.Sprintf("%d", u.ID)).WillReturnRows(exerciseRow)
+		helpers.ExpectEditableWorkoutSession(mock, e.WorkoutSessionID)
+
+		mock.ExpectBegin()
+		const addSetStmt = `INSERT INTO "set_entries" ("created_at","updated_at","deleted_at","weight","unit","reps","exercise_id","set_order","type","rest_time_seconds","completed_at","client_metadata","origin_platform","origin_app_version","origin_device_id") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15) RETURNING "id"`
+		mock.ExpectQuery(regexp.QuoteMeta(addSetStmt)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), 22.5, "KG", s.Reps, e.ID, 1, "WORKING", nil, nil, nil, "", "", "").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(s.ID))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.TouchExerciseStmt)).
+			WithArgs(sqlmock.AnyArg(), e.ID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.TouchExerciseSessionStmt)).
+			WithArgs(sqlmock.AnyArg(), e.ID).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		var resp AddWeightedSetResp
+		// a float32 22.5 that went through a client as 22.499998
+		mutation := fmt.Sprintf(`
+			mutation AddSet {
+				addSet(exerciseId: "%d", set: { weight: 22.499998, unit: KG, reps: %d, setOrder: 1 }) {
+					id
+					weight
+					unit
+				}
+			}`, e.ID, s.Reps)
+		c.MustPost(mutation, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, 22.5, resp.AddSet.Weight)
+		require.Equal(t, "KG", resp.AddSet.Unit)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Add Set Invalid Order", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		var resp AddTypedSetResp
+		mutation := fmt.Sprintf(`
+			mutation AddSet {
+				addSet(exerciseId: "%d", set: { weight: %f, reps: %d, setOrder: 0 }) {
+					id
+				}
+			}`, e.ID, s.Weight, s.Reps)
+		err := c.Post(mutation, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, "[{\"message\":\"setOrder needs to be between 1 and 99\",\"path\":[\"addSet\"],\"extensions\":{\"code\":\"INVALID_INPUT\",\"fields\":[{\"field\":\"set.setOrder\",\"message\":\"setOrder needs to be between 1 and 99\"}]}}]")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
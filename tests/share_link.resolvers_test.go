@@ -0,0 +1,137 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type CreateWorkoutSessionShareLinkResp struct {
+	CreateWorkoutSessionShareLink struct {
+		Token            string
+		WorkoutSessionId string
+	}
+}
+
+func TestShareLinkResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	ws := testdata.WorkoutSession
+
+	const createShareLinkMutation = `
+		mutation CreateWorkoutSessionShareLink {
+			createWorkoutSessionShareLink(workoutSessionId: "%d") {
+				token
+				workoutSessionId
+			}
+		}`
+
+	workoutSessionColumns := []string{"id", "start", "end", "workout_routine_id", "user_id", "created_at", "deleted_at", "updated_at"}
+
+	t.Run("Create Share Link Success", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		for i := 0; i < 2; i++ {
+			workoutSessionRow := sqlmock.
+				NewRows(workoutSessionColumns).
+				AddRow(ws.ID, ws.Start, nil, ws.WorkoutRoutineID, ws.UserID, ws.CreatedAt, nil, ws.UpdatedAt)
+			mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(workoutSessionRow)
+		}
+
+		mock.ExpectBegin()
+		const addShareLinkStmt = `INSERT INTO "workout_session_share_links" ("created_at","updated_at","deleted_at","token","workout_session_id","user_id","invite") VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING "id"`
+		mock.ExpectQuery(regexp.QuoteMeta(addShareLinkStmt)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, sqlmock.AnyArg(), ws.ID, u.ID, false).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		var resp CreateWorkoutSessionShareLinkResp
+		c.MustPost(
+			fmt.Sprintf(createShareLinkMutation, ws.ID),
+			&resp,
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.NotEmpty(t, resp.CreateWorkoutSessionShareLink.Token)
+		require.Equal(t, fmt.Sprintf("%d", ws.ID), resp.CreateWorkoutSessionShareLink.WorkoutSessionId)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Create Share Link Session Ended", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		for i := 0; i < 2; i++ {
+			workoutSessionRow := sqlmock.
+				NewRows(workoutSessionColumns).
+				AddRow(ws.ID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.UserID, ws.CreatedAt, nil, ws.UpdatedAt)
+			mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(workoutSessionRow)
+		}
+
+		var resp CreateWorkoutSessionShareLinkResp
+		err := c.Post(
+			fmt.Sprintf(createShareLinkMutation, ws.ID),
+			&resp,
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.EqualError(t, err, "[{\"message\":\"Error Creating Share Link: Workout Session Already Ended\",\"path\":[\"createWorkoutSessionShareLink\"],\"extensions\":{\"code\":\"INVALID_INPUT\"}}]")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Create Share Link Access Denied", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		incorrectUserId := 66
+		workoutSessionRow := sqlmock.
+			NewRows(workoutSessionColumns).
+			AddRow(ws.ID, ws.Start, nil, ws.WorkoutRoutineID, incorrectUserId, ws.CreatedAt, nil, ws.UpdatedAt)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(fmt.Sprintf("%d", ws.ID)).WillReturnRows(workoutSessionRow)
+
+		var resp CreateWorkoutSessionShareLinkResp
+		err := c.Post(
+			fmt.Sprintf(createShareLinkMutation, ws.ID),
+			&resp,
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.EqualError(t, err, "[{\"message\":\"Error Creating Share Link: Access Denied\",\"path\":[\"createWorkoutSessionShareLink\"],\"extensions\":{\"code\":\"FORBIDDEN\"}}]")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
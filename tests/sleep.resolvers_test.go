@@ -0,0 +1,131 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type LogSleepResp struct {
+	LogSleep struct {
+		ID    string
+		Night string
+		Hours float64
+	}
+}
+
+type SleepLogsResp struct {
+	SleepLogs []struct {
+		ID    string
+		Night string
+		Hours float64
+	}
+}
+
+func TestSleepResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	night := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Log Sleep", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		mock.ExpectBegin()
+		const upsertStmt = `INSERT INTO "sleep_logs" ("created_at","updated_at","deleted_at","user_id","night","hours") VALUES ($1,$2,$3,$4,$5,$6) ON CONFLICT ("user_id","night") DO UPDATE SET "hours"="excluded"."hours","updated_at"="excluded"."updated_at","deleted_at"="excluded"."deleted_at" RETURNING "id"`
+		mock.ExpectQuery(regexp.QuoteMeta(upsertStmt)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, u.ID, night, float32(7.5)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectCommit()
+
+		var resp LogSleepResp
+		c.MustPost(`
+			mutation LogSleep {
+				logSleep(sleep: { night: "2022-10-01T22:30:00-07:00", hours: 7.5 }) {
+					id
+					night
+					hours
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, "3", resp.LogSleep.ID)
+		require.Equal(t, "2022-10-01T00:00:00Z", resp.LogSleep.Night)
+		require.Equal(t, 7.5, resp.LogSleep.Hours)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Log Sleep Invalid Hours", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		var resp LogSleepResp
+		err := c.Post(`
+			mutation LogSleep {
+				logSleep(sleep: { night: "2022-10-01T00:00:00Z", hours: 25 }) {
+					id
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"hours needs to be between 0 and 24","path":["logSleep"],"extensions":{"code":"INVALID_INPUT","fields":[{"field":"sleep.hours","message":"hours needs to be between 0 and 24"}]}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Get Sleep Logs", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		const sleepLogsQuery = `SELECT * FROM "sleep_logs" WHERE (user_id = $1 AND night >= $2 AND night < $3) AND "sleep_logs"."deleted_at" IS NULL ORDER BY night`
+		mock.ExpectQuery(regexp.QuoteMeta(sleepLogsQuery)).
+			WithArgs(fmt.Sprintf("%d", u.ID), night, night.AddDate(0, 0, 7)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "night", "hours"}).
+				AddRow(3, u.ID, night, 7.5).
+				AddRow(4, u.ID, night.AddDate(0, 0, 1), 6))
+
+		var resp SleepLogsResp
+		c.MustPost(`
+			query SleepLogs {
+				sleepLogs(range: { start: "2022-10-01T00:00:00Z", end: "2022-10-08T00:00:00Z" }) {
+					id
+					night
+					hours
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Len(t, resp.SleepLogs, 2)
+		require.Equal(t, 7.5, resp.SleepLogs[0].Hours)
+		require.Equal(t, "2022-10-02T00:00:00Z", resp.SleepLogs[1].Night)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,384 @@
+package test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/media"
+	"github.com/neilZon/workout-logger-api/snapshot"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type CreateSnapshotResp struct {
+	CreateSnapshot struct {
+		ID                  string
+		WorkoutRoutineCount int
+		WorkoutSessionCount int
+	}
+}
+
+type RestoreSnapshotResp struct {
+	RestoreSnapshot struct {
+		ID         string
+		RestoredAt *string
+	}
+}
+
+// not parallel since the archive dir comes from the environment
+func TestSnapshots(t *testing.T) {
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	userId := fmt.Sprintf("%d", u.ID)
+	dir := t.TempDir()
+	t.Setenv(config.ARCHIVE_DIR, dir)
+
+	takenAt := time.Date(2022, 11, 1, 9, 0, 0, 0, time.UTC)
+	start := time.Date(2022, 10, 30, 12, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	snapshotColumns := []string{"id", "user_id", "storage_key", "workout_routine_count", "workout_session_count", "created_at"}
+
+	// one routine with one exercise routine and one session logged against it
+	data := &database.SnapshotData{
+		TakenAt: takenAt,
+		WorkoutRoutines: []database.WorkoutRoutine{{
+			Model:  gorm.Model{ID: 8, CreatedAt: start},
+			Name:   "Legs",
+			Active: true,
+			UserID: u.ID,
+			ExerciseRoutines: []database.ExerciseRoutine{{
+				Model:            gorm.Model{ID: 3, CreatedAt: start},
+				Name:             "squat",
+				Sets:             4,
+				Reps:             6,
+				Active:           true,
+				WorkoutRoutineID: 8,
+			}},
+		}},
+		WorkoutSessions: []database.WorkoutSession{{
+			Model:            gorm.Model{ID: 5, CreatedAt: start},
+			Start:            start,
+			End:              &end,
+			WorkoutRoutineID: 8,
+			UserID:           u.ID,
+			Exercises: []database.Exercise{{
+				Model:             gorm.Model{ID: 44, CreatedAt: start},
+				ExerciseRoutineID: 3,
+				WorkoutSessionID:  5,
+				UserID:            u.ID,
+				Sets: []database.SetEntry{{
+					Model:      gorm.Model{ID: 30, CreatedAt: start},
+					Weight:     225,
+					Reps:       5,
+					ExerciseID: 44,
+					SetOrder:   1,
+					Type:       database.SetTypeWorking,
+				}},
+			}},
+		}},
+		QuickPhrases: []database.QuickPhrase{{
+			Model:  gorm.Model{ID: 2, CreatedAt: start},
+			UserID: u.ID,
+			Text:   "felt strong",
+		}},
+	}
+	key := snapshot.StorageKey(u.ID, takenAt)
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	require.NoError(t, json.NewEncoder(gz).Encode(data))
+	require.NoError(t, gz.Close())
+	require.NoError(t, media.NewLocalStore(dir, "").Save(context.Background(), key, &buf))
+
+	snapshotRow := func(userId uint) *sqlmock.Rows {
+		return sqlmock.NewRows(snapshotColumns).AddRow(6, userId, key, 1, 1, takenAt)
+	}
+
+	t.Run("Create Snapshot", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "snapshots" WHERE user_id = $1`)).
+			WithArgs(userId).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_routines" WHERE user_id = $1`)).
+			WithArgs(userId).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "active", "user_id"}).AddRow(8, "Legs", true, u.ID))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "exercise_routines" WHERE "exercise_routines"."workout_routine_id" = $1`)).
+			WithArgs(8).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "sets", "reps", "workout_routine_id"}).AddRow(3, "squat", 4, 6, 8))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_sessions" WHERE user_id = $1 AND "workout_sessions"."deleted_at" IS NULL ORDER BY start, id`)).
+			WithArgs(userId).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "start", "end", "workout_routine_id", "user_id"}).AddRow(5, start, end, 8, u.ID))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "exercises" WHERE "exercises"."workout_session_id" = $1`)).
+			WithArgs(5).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "workout_session_id", "exercise_routine_id", "user_id"}).AddRow(44, 5, 3, u.ID))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "set_entries" WHERE "set_entries"."exercise_id" = $1`)).
+			WithArgs(44).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "exercise_id", "weight", "reps"}).AddRow(30, 44, 225, 5))
+		for _, table := range []string{"body_weight_entries", "goals", "nutrition_logs", "sleep_logs", "quick_phrases"} {
+			mock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(`SELECT * FROM "%s" WHERE user_id = $1`, table))).
+				WithArgs(userId).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		}
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "snapshots" ("created_at","updated_at","deleted_at","user_id","storage_key","workout_routine_count","workout_session_count","restored_at") VALUES ($1,$2,$3,$4,$5,$6,$7,$8) RETURNING "id"`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, u.ID, sqlmock.AnyArg(), 1, 1, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+		mock.ExpectCommit()
+
+		var resp CreateSnapshotResp
+		c.MustPost(`
+			mutation CreateSnapshot {
+				createSnapshot {
+					id
+					workoutRoutineCount
+					workoutSessionCount
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, "7", resp.CreateSnapshot.ID)
+		require.Equal(t, 1, resp.CreateSnapshot.WorkoutRoutineCount)
+		require.Equal(t, 1, resp.CreateSnapshot.WorkoutSessionCount)
+
+		blobs, err := filepath.Glob(filepath.Join(dir, "snapshots", userId, "*.json.gz"))
+		require.NoError(t, err)
+		require.Len(t, blobs, 2)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Snapshots Are Limited", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "snapshots" WHERE user_id = $1`)).
+			WithArgs(userId).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(config.MAX_SNAPSHOTS))
+
+		var resp CreateSnapshotResp
+		err := c.Post(`
+			mutation CreateSnapshot {
+				createSnapshot {
+					id
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Creating Snapshot: at most 10 snapshots, delete one first","path":["createSnapshot"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Merge Only Adds What Is Gone", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "snapshots" WHERE id = $1`)).WithArgs("6").WillReturnRows(snapshotRow(u.ID))
+
+		mock.ExpectBegin()
+		// the routine is still there, the session was deleted since
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "workout_routines" WHERE user_id = $1`)).
+			WithArgs(u.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "exercise_routines" WHERE (workout_routine_id IN (SELECT id FROM workout_routines WHERE user_id = $1 AND deleted_at IS NULL))`)).
+			WithArgs(u.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "workout_sessions" WHERE user_id = $1`)).
+			WithArgs(u.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "workout_sessions" ("created_at","updated_at","deleted_at","start","end","workout_routine_id","user_id","client_metadata","origin_platform","origin_app_version","origin_device_id","notes")`)).
+			WithArgs(start, sqlmock.AnyArg(), nil, start, end, 8, u.ID, nil, "", "", "", "").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "exercises"`)).
+			WithArgs(start, sqlmock.AnyArg(), nil, "", 3, 9, u.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(50))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "set_entries"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(60))
+		for _, table := range []string{"body_weight_entries", "goals"} {
+			mock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(`SELECT "id" FROM "%s" WHERE user_id = $1`, table))).
+				WithArgs(u.ID).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		}
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "day" FROM "nutrition_logs" WHERE user_id = $1`)).
+			WithArgs(u.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"day"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "night" FROM "sleep_logs" WHERE user_id = $1`)).
+			WithArgs(u.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"night"}))
+		// already has the phrase so it isn't doubled up
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "text" FROM "quick_phrases" WHERE user_id = $1`)).
+			WithArgs(u.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"text"}).AddRow("felt strong"))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "snapshots" SET "restored_at"=$1,"updated_at"=$2 WHERE "snapshots"."deleted_at" IS NULL AND "id" = $3`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 6).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		var resp RestoreSnapshotResp
+		c.MustPost(`
+			mutation RestoreSnapshot {
+				restoreSnapshot(snapshotId: "6") {
+					id
+					restoredAt
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, "6", resp.RestoreSnapshot.ID)
+		require.NotNil(t, resp.RestoreSnapshot.RestoredAt)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Replace Deletes The Account First", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "snapshots" WHERE id = $1`)).WithArgs("6").WillReturnRows(snapshotRow(u.ID))
+
+		mock.ExpectBegin()
+		for _, table := range []string{"set_entries", "exercises", "workout_sessions", "exercise_routines", "workout_routines", "body_weight_entries", "goals", "nutrition_logs", "sleep_logs", "quick_phrases"} {
+			mock.ExpectExec(regexp.QuoteMeta(fmt.Sprintf(`UPDATE "%s" SET "deleted_at"=$1 WHERE`, table))).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "workout_routines" WHERE user_id = $1`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "exercise_routines"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "workout_routines" ("created_at","updated_at","deleted_at","name","active","user_id")`)).
+			WithArgs(start, sqlmock.AnyArg(), nil, "Legs", true, u.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "exercise_routines" ("created_at","updated_at","deleted_at","name","sets","reps","active","workout_routine_id","position","catalog_exercise_id")`)).
+			WithArgs(start, sqlmock.AnyArg(), nil, "squat", 4, 6, true, 10, 0, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "workout_sessions" WHERE user_id = $1`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		// the session points at the routines it was restored with
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "workout_sessions"`)).
+			WithArgs(start, sqlmock.AnyArg(), nil, start, end, 10, u.ID, nil, "", "", "", "").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "exercises"`)).
+			WithArgs(start, sqlmock.AnyArg(), nil, "", 11, 9, u.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(50))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "set_entries"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(60))
+		for _, table := range []string{"body_weight_entries", "goals"} {
+			mock.ExpectQuery(regexp.QuoteMeta(fmt.Sprintf(`SELECT "id" FROM "%s"`, table))).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		}
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "day" FROM "nutrition_logs"`)).WillReturnRows(sqlmock.NewRows([]string{"day"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "night" FROM "sleep_logs"`)).WillReturnRows(sqlmock.NewRows([]string{"night"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "text" FROM "quick_phrases"`)).WillReturnRows(sqlmock.NewRows([]string{"text"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "quick_phrases" ("created_at","updated_at","deleted_at","user_id","text","position")`)).
+			WithArgs(start, sqlmock.AnyArg(), nil, u.ID, "felt strong", 0).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(12))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "snapshots" SET "restored_at"=$1`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		var resp RestoreSnapshotResp
+		c.MustPost(`
+			mutation RestoreSnapshot {
+				restoreSnapshot(snapshotId: "6", mode: REPLACE) {
+					id
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, "6", resp.RestoreSnapshot.ID)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Other Users Snapshots Are Off Limits", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "snapshots" WHERE id = $1`)).WithArgs("6").WillReturnRows(snapshotRow(u.ID + 1))
+
+		var resp RestoreSnapshotResp
+		err := c.Post(`
+			mutation RestoreSnapshot {
+				restoreSnapshot(snapshotId: "6") {
+					id
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Restoring Snapshot: Access Denied","path":["restoreSnapshot"],"extensions":{"code":"FORBIDDEN"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Delete Snapshot", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "snapshots" WHERE id = $1`)).WithArgs("6").WillReturnRows(snapshotRow(u.ID))
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "snapshots" WHERE id = $1`)).
+			WithArgs("6").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		var resp struct{ DeleteSnapshot int }
+		c.MustPost(`
+			mutation DeleteSnapshot {
+				deleteSnapshot(snapshotId: "6")
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, 1, resp.DeleteSnapshot)
+
+		_, err = os.Stat(filepath.Join(dir, filepath.FromSlash(key)))
+		require.True(t, os.IsNotExist(err))
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,183 @@
+package test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/integration"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStrava(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	userId := fmt.Sprintf("%d", u.ID)
+	now := time.Date(2022, 10, 30, 14, 0, 0, 0, time.UTC)
+	start := time.Date(2022, 10, 30, 12, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	const dueUploadsQuery = `SELECT * FROM "strava_uploads" WHERE next_attempt_at <= $1 AND "strava_uploads"."deleted_at" IS NULL ORDER BY next_attempt_at, id LIMIT 10`
+	const connectionQuery = `SELECT * FROM "strava_connections" WHERE user_id = $1 AND "strava_connections"."deleted_at" IS NULL ORDER BY "strava_connections"."id" LIMIT 1`
+	uploadColumns := []string{"id", "user_id", "workout_session_id", "attempts"}
+	connectionColumns := []string{"id", "user_id", "athlete_id", "access_token", "refresh_token", "expires_at"}
+
+	expectFinishedSession := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_sessions" WHERE (id = $1 AND "end" IS NOT NULL)`)).
+			WithArgs(9).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "start", "end", "workout_routine_id"}).AddRow(9, u.ID, start, end, 2))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "exercises" WHERE "exercises"."workout_session_id" = $1`)).
+			WithArgs(9).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "exercise_routine_id", "workout_session_id"}).AddRow(4, 3, 9))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "exercise_routines" WHERE "exercise_routines"."id" = $1`)).
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "Bench"))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "set_entries" WHERE "set_entries"."exercise_id" = $1`)).
+			WithArgs(4).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "exercise_id", "weight", "reps", "type"}).
+				AddRow(1, 4, 95, 10, "WARMUP").
+				AddRow(2, 4, 185, 5, "WORKING"))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_routines" WHERE "workout_routines"."id" = $1`)).
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "Push"))
+	}
+
+	t.Run("Uploads With Refreshed Token", func(t *testing.T) {
+		var activity *http.Request
+		stravaApi := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r.ParseForm()
+			switch r.URL.Path {
+			case "/oauth/token":
+				require.Equal(t, "refresh_token", r.PostForm.Get("grant_type"))
+				require.Equal(t, "old-refresh", r.PostForm.Get("refresh_token"))
+				fmt.Fprintf(w, `{"access_token":"new-access","refresh_token":"new-refresh","expires_at":%d}`, now.Add(6*time.Hour).Unix())
+			case "/api/v3/activities":
+				activity = r
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprint(w, `{"id":77}`)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+		defer stravaApi.Close()
+
+		mock, gormDB := helpers.SetupMockDB()
+		strava := &integration.Strava{BaseURL: stravaApi.URL, ClientID: "1", ClientSecret: "secret", Client: stravaApi.Client()}
+		syncer := integration.NewStravaSyncer(gormDB, strava, 10, 3, time.Minute, 5*time.Minute)
+
+		mock.ExpectQuery(regexp.QuoteMeta(dueUploadsQuery)).
+			WithArgs(now).
+			WillReturnRows(sqlmock.NewRows(uploadColumns).AddRow(1, u.ID, 9, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(connectionQuery)).
+			WithArgs(userId).
+			WillReturnRows(sqlmock.NewRows(connectionColumns).AddRow(5, u.ID, 123, "old-access", "old-refresh", now.Add(time.Minute)))
+		expectFinishedSession(mock)
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "strava_connections" SET "access_token"=$1,"expires_at"=$2,"refresh_token"=$3,"updated_at"=$4 WHERE id = $5 AND "strava_connections"."deleted_at" IS NULL`)).
+			WithArgs("new-access", now.Add(6*time.Hour), "new-refresh", sqlmock.AnyArg(), 5).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "strava_uploads" SET "activity_id"=$1,"attempts"=attempts + 1,"last_error"=$2,"next_attempt_at"=$3,"uploaded_at"=$4,"updated_at"=$5 WHERE id = $6 AND "strava_uploads"."deleted_at" IS NULL`)).
+			WithArgs(77, "", nil, now, sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		uploaded, err := syncer.SyncDue(now)
+		require.Nil(t, err)
+		require.Equal(t, 1, uploaded)
+		require.Equal(t, "Bearer new-access", activity.Header.Get("Authorization"))
+		require.Equal(t, "WeightTraining", activity.PostForm.Get("sport_type"))
+		require.Equal(t, "Push", activity.PostForm.Get("name"))
+		require.Equal(t, "3600", activity.PostForm.Get("elapsed_time"))
+		require.Equal(t, "Bench: 185x5\nTotal volume: 925", activity.PostForm.Get("description"))
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Retries When Token Refresh Fails", func(t *testing.T) {
+		stravaApi := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer stravaApi.Close()
+
+		mock, gormDB := helpers.SetupMockDB()
+		strava := &integration.Strava{BaseURL: stravaApi.URL, ClientID: "1", ClientSecret: "secret", Client: stravaApi.Client()}
+		syncer := integration.NewStravaSyncer(gormDB, strava, 10, 3, time.Minute, 5*time.Minute)
+
+		mock.ExpectQuery(regexp.QuoteMeta(dueUploadsQuery)).
+			WithArgs(now).
+			WillReturnRows(sqlmock.NewRows(uploadColumns).AddRow(1, u.ID, 9, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(connectionQuery)).
+			WithArgs(userId).
+			WillReturnRows(sqlmock.NewRows(connectionColumns).AddRow(5, u.ID, 123, "old-access", "old-refresh", now.Add(-time.Hour)))
+		expectFinishedSession(mock)
+		mock.ExpectBegin()
+		// second failure waits twice as long as the first
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "strava_uploads" SET "attempts"=attempts + 1,"last_error"=$1,"next_attempt_at"=$2,"updated_at"=$3 WHERE id = $4 AND "strava_uploads"."deleted_at" IS NULL`)).
+			WithArgs("refreshing strava token: strava responded 503 Service Unavailable: ", now.Add(2*time.Minute), sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		uploaded, err := syncer.SyncDue(now)
+		require.Nil(t, err)
+		require.Equal(t, 0, uploaded)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Disconnect Strava", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+
+		mock.ExpectQuery(regexp.QuoteMeta(connectionQuery)).
+			WithArgs(userId).
+			WillReturnRows(sqlmock.NewRows(connectionColumns).AddRow(5, u.ID, 123, "access", "refresh", now))
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "strava_uploads" SET "next_attempt_at"=$1,"updated_at"=$2 WHERE (user_id = $3 AND next_attempt_at IS NOT NULL) AND "strava_uploads"."deleted_at" IS NULL`)).
+			WithArgs(nil, sqlmock.AnyArg(), userId).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "strava_connections" WHERE user_id = $1`)).
+			WithArgs(userId).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		var resp struct {
+			DisconnectStrava int
+		}
+		c.MustPost(`
+			mutation DisconnectStrava {
+				disconnectStrava
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, 1, resp.DisconnectStrava)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
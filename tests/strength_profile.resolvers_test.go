@@ -0,0 +1,143 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/strength"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type StrengthProfileResp struct {
+	StrengthProfile struct {
+		Formula    string
+		BodyWeight *float64
+		Exercises  []struct {
+			ExerciseRoutineId  string
+			Name               string
+			EstimatedOneRepMax float64
+			Reps               int
+			BodyWeightRatio    *float64
+			Level              *string
+		}
+	}
+}
+
+func TestStrengthProfileResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	userId := fmt.Sprintf("%d", u.ID)
+	performedAt := time.Date(2022, 10, 30, 12, 0, 0, 0, time.UTC)
+
+	const latestBodyWeightQuery = `SELECT "weight" FROM "body_weight_entries" WHERE user_id = $1 AND "body_weight_entries"."deleted_at" IS NULL ORDER BY logged_at desc, id desc LIMIT 1`
+	oneRepMaxColumns := []string{"exercise_routine_id", "name", "catalog_exercise_id", "catalog_exercise_name", "estimated_one_rep_max", "weight", "reps", "performed_at"}
+
+	const strengthProfileQuery = `
+		query StrengthProfile {
+			strengthProfile(formula: BRZYCKI) {
+				formula
+				bodyWeight
+				exercises {
+					exerciseRoutineId
+					name
+					estimatedOneRepMax
+					reps
+					bodyWeightRatio
+					level
+				}
+			}
+		}`
+
+	t.Run("Classifies Against Bodyweight", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT ON (exercise_routines.id)`)).
+			WithArgs(userId, 12).
+			WillReturnRows(sqlmock.NewRows(oneRepMaxColumns).
+				AddRow(3, "Squat", 25, "Squat", 180, 160, 4, performedAt).
+				AddRow(1, "Bench", 1, "Bench Press", 90, 80, 4, performedAt).
+				AddRow(7, "Pec Deck", nil, nil, 60, 50, 6, performedAt))
+		mock.ExpectQuery(regexp.QuoteMeta(latestBodyWeightQuery)).
+			WithArgs(userId).
+			WillReturnRows(sqlmock.NewRows([]string{"weight"}).AddRow(90))
+
+		var resp StrengthProfileResp
+		c.MustPost(strengthProfileQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+
+		profile := resp.StrengthProfile
+		require.Equal(t, "BRZYCKI", profile.Formula)
+		require.Equal(t, float64(90), *profile.BodyWeight)
+		require.Len(t, profile.Exercises, 3)
+		require.Equal(t, float64(2), *profile.Exercises[0].BodyWeightRatio)
+		require.Equal(t, strength.LevelAdvanced, *profile.Exercises[0].Level)
+		require.Equal(t, strength.LevelIntermediate, *profile.Exercises[1].Level)
+		// not in the catalog so there's nothing to compare it to
+		require.Nil(t, profile.Exercises[2].Level)
+		require.NotNil(t, profile.Exercises[2].BodyWeightRatio)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("No Levels Without Bodyweight", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT DISTINCT ON (exercise_routines.id)`)).
+			WithArgs(userId, 12).
+			WillReturnRows(sqlmock.NewRows(oneRepMaxColumns).AddRow(1, "Bench", 1, "Bench Press", 90, 80, 4, performedAt))
+		mock.ExpectQuery(regexp.QuoteMeta(latestBodyWeightQuery)).
+			WithArgs(userId).
+			WillReturnRows(sqlmock.NewRows([]string{"weight"}))
+
+		var resp StrengthProfileResp
+		c.MustPost(strengthProfileQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+
+		require.Nil(t, resp.StrengthProfile.BodyWeight)
+		require.Len(t, resp.StrengthProfile.Exercises, 1)
+		require.Nil(t, resp.StrengthProfile.Exercises[0].BodyWeightRatio)
+		require.Nil(t, resp.StrengthProfile.Exercises[0].Level)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Classify", func(t *testing.T) {
+		level, ok := strength.Classify("Deadlift", 100, 100)
+		require.True(t, ok)
+		require.Equal(t, strength.LevelBeginner, level)
+
+		level, ok = strength.Classify("Deadlift", 300, 100)
+		require.True(t, ok)
+		require.Equal(t, strength.LevelElite, level)
+
+		_, ok = strength.Classify("Pull Up", 100, 100)
+		require.False(t, ok)
+	})
+}
@@ -0,0 +1,340 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/cache"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/live"
+	"github.com/neilZon/workout-logger-api/media"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type SyncWorkoutDataResp struct {
+	SyncWorkoutData []struct {
+		Entity   string
+		ClientID string
+		ID       *string
+		Status   string
+		Message  *string
+	}
+}
+
+func TestSyncWorkoutData(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	userId := fmt.Sprintf("%d", u.ID)
+
+	const (
+		sessionClientId  = "0b7e6c1e-5f4a-4a55-9d0e-2a1f3c4d5e6f"
+		exerciseClientId = "1c8f7d2f-6a5b-4b66-8e1f-3b2a4d5e6f70"
+		setClientId      = "2d9a8e3a-7b6c-4c77-9f2a-4c3b5e6f7081"
+	)
+	syncRecordColumns := []string{"id", "user_id", "client_id", "entity", "entity_id", "last_modified", "deleted"}
+	lastModified := time.Date(2022, 10, 30, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Sync Creates A Session With Its Exercises And Sets", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "sync_records" WHERE (user_id = $1 AND client_id IN ($2,$3,$4,$5,$6)) AND "sync_records"."deleted_at" IS NULL`)).
+			WithArgs(u.ID, sessionClientId, exerciseClientId, sessionClientId, setClientId, exerciseClientId).
+			WillReturnRows(sqlmock.NewRows(syncRecordColumns))
+
+		// the session was finished offline so it goes out to webhooks and Strava
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "workout_routines" WHERE (id = $1 AND user_id = $2)`)).
+			WithArgs(3, u.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "workout_sessions"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(20))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO webhook_deliveries`)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO strava_uploads`)).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "sync_records"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		helpers.ExpectEditableWorkoutSession(mock, 20)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id","workout_routine_id" FROM "workout_sessions" WHERE id = $1`)).
+			WithArgs(20).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "workout_routine_id"}).AddRow(20, 3))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "exercise_routines" WHERE (workout_routine_id = $1 AND id IN ($2))`)).
+			WithArgs("3", "7").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "exercises"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(30))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "workout_sessions" SET "updated_at"=$1 WHERE id IN ($2)`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "sync_records"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		mock.ExpectCommit()
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "workout_session_id" FROM "exercises" WHERE id = $1 AND "exercises"."deleted_at" IS NULL`)).
+			WithArgs(30).
+			WillReturnRows(sqlmock.NewRows([]string{"workout_session_id"}).AddRow(20))
+		helpers.ExpectEditableWorkoutSession(mock, 20)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "exercises" WHERE id = $1`)).
+			WithArgs(30).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(MAX(set_order), 0) + 1 FROM "set_entries" WHERE exercise_id = $1`)).
+			WithArgs("30").
+			WillReturnRows(sqlmock.NewRows([]string{"set_order"}).AddRow(1))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "set_entries"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(40))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "exercises" SET "updated_at"=$1 WHERE id = $2`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "workout_sessions" SET "updated_at"=$1 WHERE id = (SELECT workout_session_id FROM exercises WHERE id = $2)`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "sync_records"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectCommit()
+
+		var resp SyncWorkoutDataResp
+		c.MustPost(fmt.Sprintf(`
+			mutation SyncWorkoutData {
+				syncWorkoutData(changes: {
+					workoutSessions: [{clientId: "%s", operation: CREATE, lastModified: "2022-10-30T12:00:00Z", workoutRoutineId: "3", start: "2022-10-30T11:00:00Z", end: "2022-10-30T12:00:00Z"}]
+					exercises: [{clientId: "%s", operation: CREATE, lastModified: "2022-10-30T12:00:00Z", workoutSessionClientId: "%s", exerciseRoutineId: "7"}]
+					sets: [{clientId: "%s", operation: CREATE, lastModified: "2022-10-30T12:00:00Z", exerciseClientId: "%s", weight: 100, reps: 5}]
+				}) {
+					entity
+					clientId
+					id
+					status
+				}
+			}`, sessionClientId, exerciseClientId, sessionClientId, setClientId, exerciseClientId), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+
+		require.Len(t, resp.SyncWorkoutData, 3)
+		require.Equal(t, "WORKOUT_SESSION", resp.SyncWorkoutData[0].Entity)
+		require.Equal(t, "APPLIED", resp.SyncWorkoutData[0].Status)
+		require.Equal(t, "20", *resp.SyncWorkoutData[0].ID)
+		require.Equal(t, "EXERCISE", resp.SyncWorkoutData[1].Entity)
+		require.Equal(t, "30", *resp.SyncWorkoutData[1].ID)
+		require.Equal(t, "SET_ENTRY", resp.SyncWorkoutData[2].Entity)
+		require.Equal(t, setClientId, resp.SyncWorkoutData[2].ClientID)
+		require.Equal(t, "40", *resp.SyncWorkoutData[2].ID)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Sync Skips Replayed And Older Changes", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "sync_records" WHERE (user_id = $1 AND client_id IN ($2,$3,$4,$5))`)).
+			WithArgs(u.ID, sessionClientId, exerciseClientId, setClientId, exerciseClientId).
+			WillReturnRows(sqlmock.NewRows(syncRecordColumns).
+				AddRow(1, u.ID, sessionClientId, "WORKOUT_SESSION", 20, lastModified, false).
+				AddRow(2, u.ID, exerciseClientId, "EXERCISE", 30, lastModified.Add(time.Hour), false))
+
+		var resp SyncWorkoutDataResp
+		c.MustPost(fmt.Sprintf(`
+			mutation SyncWorkoutData {
+				syncWorkoutData(changes: {
+					workoutSessions: [{clientId: "%s", operation: CREATE, lastModified: "2022-10-30T12:00:00Z", workoutRoutineId: "3", start: "2022-10-30T11:00:00Z"}]
+					exercises: [{clientId: "%s", operation: UPDATE, lastModified: "2022-10-30T12:30:00Z", notes: "older"}]
+					sets: [{clientId: "%s", operation: UPDATE, lastModified: "2022-10-30T12:00:00Z", exerciseClientId: "%s", reps: 6}]
+				}) {
+					id
+					status
+					message
+				}
+			}`, sessionClientId, exerciseClientId, setClientId, exerciseClientId), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+
+		require.Len(t, resp.SyncWorkoutData, 3)
+		require.Equal(t, "DUPLICATE", resp.SyncWorkoutData[0].Status)
+		require.Equal(t, "20", *resp.SyncWorkoutData[0].ID)
+		require.Equal(t, "STALE", resp.SyncWorkoutData[1].Status)
+		require.Equal(t, "30", *resp.SyncWorkoutData[1].ID)
+		require.Equal(t, "REJECTED", resp.SyncWorkoutData[2].Status)
+		require.Nil(t, resp.SyncWorkoutData[2].ID)
+		require.Equal(t, "it hasn't been created", *resp.SyncWorkoutData[2].Message)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Sync Newer Change Wins", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "sync_records" WHERE (user_id = $1 AND client_id IN ($2))`)).
+			WithArgs(u.ID, sessionClientId).
+			WillReturnRows(sqlmock.NewRows(syncRecordColumns).
+				AddRow(1, u.ID, sessionClientId, "WORKOUT_SESSION", 20, lastModified, false))
+
+		helpers.ExpectEditableWorkoutSession(mock, 20)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_sessions" WHERE id = $1 AND "workout_sessions"."deleted_at" IS NULL LIMIT 1 FOR UPDATE`)).
+			WithArgs(20).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "start"}).AddRow(20, u.ID, lastModified.Add(-time.Hour)))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "workout_sessions" SET "notes"=$1,"updated_at"=$2 WHERE id = $3`)).
+			WithArgs("felt strong", sqlmock.AnyArg(), 20).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "sync_records" SET`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		var resp SyncWorkoutDataResp
+		c.MustPost(fmt.Sprintf(`
+			mutation SyncWorkoutData {
+				syncWorkoutData(changes: {
+					workoutSessions: [{clientId: "%s", operation: UPDATE, lastModified: "2022-10-30T12:05:00Z", notes: "felt strong"}]
+				}) {
+					id
+					status
+				}
+			}`, sessionClientId), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+
+		require.Len(t, resp.SyncWorkoutData, 1)
+		require.Equal(t, "APPLIED", resp.SyncWorkoutData[0].Status)
+		require.Equal(t, "20", *resp.SyncWorkoutData[0].ID)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Sync Finishing A Session Lets Spectators Go", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		broker := live.NewBroker()
+		c := client.New(helpers.NewSharedGqlServer(gormDB, acs, cache.NewMemory(time.Minute), broker, media.NewLocalStore(t.TempDir(), "")))
+		updates := broker.Subscribe(context.Background(), "20")
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "sync_records" WHERE (user_id = $1 AND client_id IN ($2))`)).
+			WithArgs(u.ID, sessionClientId).
+			WillReturnRows(sqlmock.NewRows(syncRecordColumns).
+				AddRow(1, u.ID, sessionClientId, "WORKOUT_SESSION", 20, lastModified, false))
+
+		helpers.ExpectEditableWorkoutSession(mock, 20)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_sessions" WHERE id = $1 AND "workout_sessions"."deleted_at" IS NULL LIMIT 1 FOR UPDATE`)).
+			WithArgs(20).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "start"}).AddRow(20, u.ID, lastModified.Add(-time.Hour)))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "workout_sessions" SET "end"=$1,"updated_at"=$2 WHERE id = $3`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), 20).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.QueueWebhookDeliveriesStmt)).
+			WithArgs(20, u.ID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.QueueStravaUploadStmt)).
+			WithArgs(20, u.ID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "sync_records" SET`)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		var resp SyncWorkoutDataResp
+		c.MustPost(fmt.Sprintf(`
+			mutation SyncWorkoutData {
+				syncWorkoutData(changes: {
+					workoutSessions: [{clientId: "%s", operation: UPDATE, lastModified: "2022-10-30T12:05:00Z", end: "2022-10-30T12:00:00Z"}]
+				}) {
+					status
+				}
+			}`, sessionClientId), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+
+		require.Len(t, resp.SyncWorkoutData, 1)
+		require.Equal(t, "APPLIED", resp.SyncWorkoutData[0].Status)
+		_, open := <-updates
+		require.False(t, open)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Sync Rejects Changes To Locked Sessions", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "sync_records" WHERE (user_id = $1 AND client_id IN ($2))`)).
+			WithArgs(u.ID, sessionClientId).
+			WillReturnRows(sqlmock.NewRows(syncRecordColumns).
+				AddRow(1, u.ID, sessionClientId, "WORKOUT_SESSION", 20, lastModified, false))
+		lockRow := sqlmock.NewRows([]string{"start", "unlocked_until"}).AddRow(time.Now().AddDate(0, 0, -91), nil)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionEditLockQuery)).WithArgs("20").WillReturnRows(lockRow)
+
+		var resp SyncWorkoutDataResp
+		c.MustPost(fmt.Sprintf(`
+			mutation SyncWorkoutData {
+				syncWorkoutData(changes: {
+					workoutSessions: [{clientId: "%s", operation: UPDATE, lastModified: "2022-10-30T12:05:00Z", notes: "felt strong"}]
+				}) {
+					id
+					status
+					message
+				}
+			}`, sessionClientId), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+
+		require.Len(t, resp.SyncWorkoutData, 1)
+		require.Equal(t, "REJECTED", resp.SyncWorkoutData[0].Status)
+		require.Equal(t, "Workout sessions older than 90 days are locked, unlock the session to edit it", *resp.SyncWorkoutData[0].Message)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Sync Create Missing Fields", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		var resp SyncWorkoutDataResp
+		err := c.Post(`
+			mutation SyncWorkoutData {
+				syncWorkoutData(changes: {
+					sets: [{clientId: "not-a-uuid", operation: CREATE, lastModified: "2022-10-30T12:00:00Z", weight: 100, reps: 5}]
+				}) {
+					id
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"client ids need to be UUIDs; needed to create a set","path":["syncWorkoutData"],"extensions":{"code":"INVALID_INPUT","fields":[{"field":"changes.sets.0.clientId","message":"client ids need to be UUIDs"},{"field":"changes.sets.0.exerciseClientId","message":"needed to create a set"}]}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
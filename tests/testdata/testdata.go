@@ -116,7 +116,7 @@ var WorkoutSession = &database.WorkoutSession{
 						},
 						UpdatedAt: time.Now(),
 					},
-					Weight:     float32(225),
+					Weight:     float64(225),
 					Reps:       uint(8),
 					ExerciseID: 44,
 				},
@@ -130,7 +130,7 @@ var WorkoutSession = &database.WorkoutSession{
 						},
 						UpdatedAt: time.Now(),
 					},
-					Weight:     float32(225),
+					Weight:     float64(225),
 					Reps:       uint(7),
 					ExerciseID: 44,
 				},
@@ -160,7 +160,7 @@ var WorkoutSession = &database.WorkoutSession{
 						},
 						UpdatedAt: time.Now(),
 					},
-					Weight:     float32(225),
+					Weight:     float64(225),
 					Reps:       uint(8),
 					ExerciseID: 45,
 				},
@@ -174,7 +174,7 @@ var WorkoutSession = &database.WorkoutSession{
 						},
 						UpdatedAt: time.Now(),
 					},
-					Weight:     float32(225),
+					Weight:     float64(225),
 					Reps:       uint(7),
 					ExerciseID: 45,
 				},
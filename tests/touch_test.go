@@ -0,0 +1,98 @@
+package test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/helpers"
+)
+
+func TestTouchParents(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Delete Set Touches Exercise And Session", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "set_entries" SET "deleted_at"=$1 WHERE (id = $2 AND exercise_id IN (SELECT id FROM exercises WHERE user_id = $3 AND deleted_at IS NULL)) AND "set_entries"."deleted_at" IS NULL RETURNING *`)).
+			WithArgs(sqlmock.AnyArg(), "9", "28").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "exercise_id"}).AddRow(9, 4))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.TouchExerciseStmt)).
+			WithArgs(sqlmock.AnyArg(), 4).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.TouchExerciseSessionStmt)).
+			WithArgs(sqlmock.AnyArg(), 4).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		err := database.DeleteSet(gormDB, "28", "9")
+		if err != nil {
+			panic(err)
+		}
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Delete Exercise Routine Touches Routine And Sessions", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "exercise_routines" SET "deleted_at"=$1 WHERE id = $2 AND "exercise_routines"."deleted_at" IS NULL RETURNING *`)).
+			WithArgs(sqlmock.AnyArg(), "3").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "workout_routine_id"}).AddRow(3, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "exercises" SET "deleted_at"=$1 WHERE exercise_routine_id = $2 AND "exercises"."deleted_at" IS NULL RETURNING *`)).
+			WithArgs(sqlmock.AnyArg(), "3").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "workout_session_id"}).AddRow(4, 7).AddRow(5, 8))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "set_entries" SET "deleted_at"=$1 WHERE exercise_id IN ($2,$3) AND "set_entries"."deleted_at" IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), "4", "5").
+			WillReturnResult(sqlmock.NewResult(0, 6))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.TouchWorkoutRoutineStmt)).
+			WithArgs(sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "workout_sessions" SET "updated_at"=$1 WHERE id IN ($2,$3) AND "workout_sessions"."deleted_at" IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), 7, 8).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		err := database.DeleteExerciseRoutine(gormDB, "3")
+		if err != nil {
+			panic(err)
+		}
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Touch Failure Rolls Back", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "exercises" SET "deleted_at"=$1 WHERE (id = $2 AND user_id = $3) AND "exercises"."deleted_at" IS NULL RETURNING *`)).
+			WithArgs(sqlmock.AnyArg(), "4", "28").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "workout_session_id"}).AddRow(4, 7))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "set_entries" SET "deleted_at"=$1 WHERE exercise_id = $2 AND "set_entries"."deleted_at" IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), "4").
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "workout_sessions" SET "updated_at"=$1 WHERE id IN ($2) AND "workout_sessions"."deleted_at" IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), 7).
+			WillReturnError(sqlmock.ErrCancelled)
+		mock.ExpectRollback()
+
+		err := database.DeleteExercise(gormDB, "28", "4")
+		if err == nil {
+			panic("expected touch failure to fail the delete")
+		}
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
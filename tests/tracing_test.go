@@ -0,0 +1,84 @@
+package test
+
+import (
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/middleware"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracing(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+
+	newClient := func(recorder *tracetest.SpanRecorder) *client.Client {
+		_, gormDB := helpers.SetupMockDB()
+		srv := helpers.NewGqlServer(gormDB, accesscontrol.NewAccessControllerService(gormDB))
+		srv.Use(middleware.Tracer{Provider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))})
+		return client.New(srv)
+	}
+	spansByName := func(recorder *tracetest.SpanRecorder) map[string]sdktrace.ReadOnlySpan {
+		spans := map[string]sdktrace.ReadOnlySpan{}
+		for _, s := range recorder.Ended() {
+			spans[s.Name()] = s
+		}
+		return spans
+	}
+
+	t.Run("Resolvers Get Spans Under The Operation", func(t *testing.T) {
+		recorder := tracetest.NewSpanRecorder()
+		c := newClient(recorder)
+
+		// the resolver fails on the empty mock db, the spans are still there
+		var resp struct{}
+		err := c.Post(`query User { user { id name } }`, &resp, helpers.AddContext(u, nil))
+		require.Error(t, err)
+
+		spans := spansByName(recorder)
+		require.Len(t, spans, 2)
+
+		operation, ok := spans["query User"]
+		require.True(t, ok)
+		require.Contains(t, operation.Attributes(), attribute.Int64("enduser.id", int64(u.ID)))
+		require.Equal(t, codes.Error, operation.Status().Code)
+
+		// id and name are plain struct fields and don't get spans
+		resolver, ok := spans["Query.user"]
+		require.True(t, ok)
+		require.Equal(t, operation.SpanContext().SpanID(), resolver.Parent().SpanID())
+		require.Equal(t, operation.SpanContext().TraceID(), resolver.SpanContext().TraceID())
+		require.Contains(t, resolver.Attributes(), attribute.String("graphql.field.path", "user"))
+		require.Equal(t, codes.Error, resolver.Status().Code)
+	})
+
+	t.Run("Anonymous Operations Are Named By Type", func(t *testing.T) {
+		recorder := tracetest.NewSpanRecorder()
+		c := newClient(recorder)
+
+		var resp struct{}
+		c.Post(`mutation { login(loginInput: { email: "not an email", password: "password1" }) { accessToken } }`, &resp)
+
+		spans := spansByName(recorder)
+		operation, ok := spans["mutation anonymous"]
+		require.True(t, ok)
+		for _, a := range operation.Attributes() {
+			require.NotEqual(t, attribute.Key("enduser.id"), a.Key)
+		}
+		require.Contains(t, spans, "Mutation.login")
+	})
+}
@@ -0,0 +1,182 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type ConvertHistoricalUnitsResp struct {
+	ConvertHistoricalUnits struct {
+		ID                string
+		ExerciseRoutineId string
+		From              string
+		To                string
+		SetsConverted     int
+	}
+}
+
+func TestUnitConversion(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	wr := testdata.WorkoutRoutine
+	er := testdata.WorkoutRoutine.ExerciseRoutines[0]
+	e := testdata.WorkoutSession.Exercises[0]
+
+	start := time.Date(2022, 10, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2022, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	const getExerciseRoutineQuery = `SELECT * FROM "exercise_routines" WHERE id = $1 AND "exercise_routines"."deleted_at" IS NULL ORDER BY "exercise_routines"."id" LIMIT 1`
+	const overlapQuery = `SELECT count(*) FROM "unit_conversions" WHERE (user_id = $1 AND exercise_routine_id = $2 AND from_unit = $3 AND to_unit = $4 AND range_start < $5 AND range_end > $6) AND "unit_conversions"."deleted_at" IS NULL`
+	const maxWeightQuery = `SELECT COALESCE(MAX(weight), 0) FROM set_entries WHERE exercise_id IN (`
+
+	mutation := func(from string, to string) string {
+		return fmt.Sprintf(`
+			mutation ConvertHistoricalUnits {
+				convertHistoricalUnits(exerciseRoutineId: "%d", from: %s, to: %s, range: { start: "%s", end: "%s" }) {
+					id
+					exerciseRoutineId
+					from
+					to
+					setsConverted
+				}
+			}`, er.ID, from, to, start.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+
+	// everything up to the overlap check
+	expectOwnership := func(mock sqlmock.Sqlmock) {
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		exerciseRoutineRow := sqlmock.NewRows([]string{"id", "name", "sets", "reps", "workout_routine_id"}).
+			AddRow(er.ID, er.Name, er.Sets, er.Reps, wr.ID)
+		mock.ExpectQuery(regexp.QuoteMeta(getExerciseRoutineQuery)).WithArgs(fmt.Sprintf("%d", er.ID)).WillReturnRows(exerciseRoutineRow)
+
+		workoutRoutineRow := sqlmock.
+			NewRows([]string{"id", "name", "created_at", "deleted_at", "updated_at", "user_id", "active"}).
+			AddRow(wr.ID, wr.Name, wr.CreatedAt, wr.DeletedAt, wr.UpdatedAt, wr.UserID, wr.Active)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).WithArgs(fmt.Sprintf("%d", wr.ID)).WillReturnRows(workoutRoutineRow)
+	}
+
+	t.Run("Convert Historical Units", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectOwnership(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(overlapQuery)).
+			WithArgs(u.ID, er.ID, "LB", "KG", end, start).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(regexp.QuoteMeta(maxWeightQuery)).
+			WithArgs(er.ID, u.ID, start, end, "LB").
+			WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(225))
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT exercises.id FROM exercises`)).
+			WithArgs(er.ID, u.ID, start, end).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(e.ID))
+		// only the sets logged in pounds are converted
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "set_entries" SET "unit"=$1,"weight"=ROUND((weight * $2)::numeric, 2),"updated_at"=$3 WHERE (exercise_id IN ($4) AND unit = $5) AND "set_entries"."deleted_at" IS NULL`)).
+			WithArgs("KG", 0.45359237, sqlmock.AnyArg(), e.ID, "LB").
+			WillReturnResult(sqlmock.NewResult(0, 4))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "exercises" SET "updated_at"=$1 WHERE id IN ($2) AND "exercises"."deleted_at" IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), e.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "workout_sessions" SET "updated_at"=$1 WHERE id IN (SELECT workout_session_id FROM exercises WHERE id IN ($2)) AND "workout_sessions"."deleted_at" IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), e.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "unit_conversions" ("created_at","updated_at","deleted_at","user_id","exercise_routine_id","from_unit","to_unit","factor","range_start","range_end","sets_converted") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11) RETURNING "id"`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, u.ID, er.ID, "LB", "KG", 0.45359237, start, end, 4).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		var resp ConvertHistoricalUnitsResp
+		c.MustPost(mutation("LB", "KG"), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+
+		require.Equal(t, "1", resp.ConvertHistoricalUnits.ID)
+		require.Equal(t, fmt.Sprintf("%d", er.ID), resp.ConvertHistoricalUnits.ExerciseRoutineId)
+		require.Equal(t, "LB", resp.ConvertHistoricalUnits.From)
+		require.Equal(t, "KG", resp.ConvertHistoricalUnits.To)
+		require.Equal(t, 4, resp.ConvertHistoricalUnits.SetsConverted)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Convert Historical Units Same Unit", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		var resp ConvertHistoricalUnitsResp
+		err := c.Post(mutation("KG", "KG"), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Converting Units: from and to need to be different units","path":["convertHistoricalUnits"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Convert Historical Units Already Converted", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectOwnership(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(overlapQuery)).
+			WithArgs(u.ID, er.ID, "LB", "KG", end, start).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+		var resp ConvertHistoricalUnitsResp
+		err := c.Post(mutation("LB", "KG"), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Converting Units: part of this range was already converted from LB to KG","path":["convertHistoricalUnits"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Convert Historical Units Too Heavy", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		expectOwnership(mock)
+		mock.ExpectQuery(regexp.QuoteMeta(overlapQuery)).
+			WithArgs(u.ID, er.ID, "KG", "LB", end, start).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectQuery(regexp.QuoteMeta(maxWeightQuery)).
+			WithArgs(er.ID, u.ID, start, end, "KG").
+			WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(5000))
+
+		var resp ConvertHistoricalUnitsResp
+		err := c.Post(mutation("KG", "LB"), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Converting Units: a set would weigh more than 9999","path":["convertHistoricalUnits"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,309 @@
+package test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/integration"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type RegisterWebhookResp struct {
+	RegisterWebhook struct {
+		ID     string
+		URL    string
+		Secret string
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	now := time.Date(2022, 10, 30, 14, 0, 0, 0, time.UTC)
+	start := time.Date(2022, 10, 30, 12, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	const dueDeliveriesQuery = `SELECT "webhook_deliveries"."id","webhook_deliveries"."created_at","webhook_deliveries"."updated_at","webhook_deliveries"."deleted_at","webhook_deliveries"."webhook_id","webhook_deliveries"."workout_session_id","webhook_deliveries"."attempts","webhook_deliveries"."next_attempt_at","webhook_deliveries"."delivered_at","webhook_deliveries"."last_error","Webhook"."id" AS "Webhook__id","Webhook"."created_at" AS "Webhook__created_at","Webhook"."updated_at" AS "Webhook__updated_at","Webhook"."deleted_at" AS "Webhook__deleted_at","Webhook"."user_id" AS "Webhook__user_id","Webhook"."url" AS "Webhook__url","Webhook"."secret" AS "Webhook__secret" FROM "webhook_deliveries" LEFT JOIN "webhooks" "Webhook" ON "webhook_deliveries"."webhook_id" = "Webhook"."id" AND "Webhook"."deleted_at" IS NULL WHERE (webhook_deliveries.next_attempt_at <= $1 AND "Webhook"."id" IS NOT NULL) AND "webhook_deliveries"."deleted_at" IS NULL ORDER BY webhook_deliveries.next_attempt_at, webhook_deliveries.id LIMIT 10`
+	deliveryColumns := []string{"id", "webhook_id", "workout_session_id", "attempts", "Webhook__id", "Webhook__user_id", "Webhook__url", "Webhook__secret"}
+
+	expectFinishedSession := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_sessions" WHERE (id = $1 AND "end" IS NOT NULL) AND "workout_sessions"."deleted_at" IS NULL ORDER BY "workout_sessions"."id" LIMIT 1`)).
+			WithArgs(9).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "start", "end", "workout_routine_id"}).AddRow(9, u.ID, start, end, 2))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "exercises" WHERE "exercises"."workout_session_id" = $1 AND "exercises"."deleted_at" IS NULL ORDER BY id`)).
+			WithArgs(9).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "exercise_routine_id", "workout_session_id"}).AddRow(4, 3, 9))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "exercise_routines" WHERE "exercise_routines"."id" = $1`)).
+			WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "Bench"))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "set_entries" WHERE "set_entries"."exercise_id" = $1 AND "set_entries"."deleted_at" IS NULL ORDER BY set_order, id`)).
+			WithArgs(4).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "exercise_id", "weight", "reps", "type"}).
+				AddRow(1, 4, 95, 10, "WARMUP").
+				AddRow(2, 4, 185, 5, "WORKING").
+				AddRow(3, 4, 185, 5, "WORKING"))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_routines" WHERE "workout_routines"."id" = $1`)).
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "Push"))
+	}
+
+	t.Run("Register Webhook Needs Https", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		var resp RegisterWebhookResp
+		err := c.Post(`
+			mutation RegisterWebhook {
+				registerWebhook(url: "http://example.com/hook") {
+					id
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Registering Webhook: url needs to be an https url","path":["registerWebhook"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Register Webhook Needs A Public Host", func(t *testing.T) {
+		for _, url := range []string{"https://localhost/hook", "https://127.0.0.1:8080/hook", "https://169.254.169.254/latest/meta-data", "https://[::1]/hook", "https://10.0.0.4/hook", "https://db.internal/hook"} {
+			mock, gormDB := helpers.SetupMockDB()
+			acs := accesscontrol.NewAccessControllerService(gormDB)
+			c := helpers.NewGqlClient(gormDB, acs)
+
+			userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+			mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+			var resp RegisterWebhookResp
+			err := c.Post(`
+				mutation RegisterWebhook($url: String!) {
+					registerWebhook(url: $url) {
+						id
+					}
+				}`, &resp, client.Var("url", url), helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+			require.EqualError(t, err, `[{"message":"Error Registering Webhook: url needs to be on the public internet","path":["registerWebhook"],"extensions":{"code":"INVALID_INPUT"}}]`, url)
+
+			err = mock.ExpectationsWereMet()
+			if err != nil {
+				panic(err)
+			}
+		}
+	})
+
+	t.Run("Register Webhook", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "webhooks" WHERE user_id = $1 AND "webhooks"."deleted_at" IS NULL`)).
+			WithArgs(fmt.Sprintf("%d", u.ID)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "webhooks" ("created_at","updated_at","deleted_at","user_id","url","secret") VALUES ($1,$2,$3,$4,$5,$6) RETURNING "id"`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, u.ID, "https://example.com/hook", sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(6))
+		mock.ExpectCommit()
+
+		var resp RegisterWebhookResp
+		c.MustPost(`
+			mutation RegisterWebhook {
+				registerWebhook(url: "https://example.com/hook") {
+					id
+					url
+					secret
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, "6", resp.RegisterWebhook.ID)
+		require.Equal(t, "https://example.com/hook", resp.RegisterWebhook.URL)
+		require.NotEmpty(t, resp.RegisterWebhook.Secret)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Ending A Session With An Update Queues It", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+
+		end := now
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "workout_sessions" WHERE (id = $1 AND "end" IS NULL) AND "workout_sessions"."deleted_at" IS NULL FOR UPDATE`)).
+			WithArgs("9").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(9))
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "workout_sessions" SET "updated_at"=$1,"end"=$2 WHERE id = $3 AND "workout_sessions"."deleted_at" IS NULL RETURNING *`)).
+			WithArgs(sqlmock.AnyArg(), end, "9").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "end"}).AddRow(9, u.ID, end))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO webhook_deliveries`)).WithArgs(9, u.ID).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO strava_uploads`)).WithArgs(9, u.ID).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err := database.UpdateWorkoutSession(gormDB, "9", &database.WorkoutSession{End: &end}, nil)
+		require.Nil(t, err)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Updating A Finished Session Doesn't Queue It Again", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+
+		end := now
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "workout_sessions" WHERE (id = $1 AND "end" IS NULL) AND "workout_sessions"."deleted_at" IS NULL FOR UPDATE`)).
+			WithArgs("9").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "workout_sessions" SET "updated_at"=$1,"end"=$2 WHERE id = $3 AND "workout_sessions"."deleted_at" IS NULL RETURNING *`)).
+			WithArgs(sqlmock.AnyArg(), end, "9").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "end"}).AddRow(9, u.ID, end))
+		mock.ExpectCommit()
+
+		err := database.UpdateWorkoutSession(gormDB, "9", &database.WorkoutSession{End: &end}, nil)
+		require.Nil(t, err)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Delivers Signed Finished Session", func(t *testing.T) {
+		var body []byte
+		var signature string
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ = io.ReadAll(r.Body)
+			signature = r.Header.Get(integration.SignatureHeader)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer receiver.Close()
+
+		mock, gormDB := helpers.SetupMockDB()
+		deliverer := integration.NewDeliverer(gormDB, time.Second, 10, 3, time.Minute)
+		// the receiver is on loopback, which the deliverer's own client won't dial
+		deliverer.Client = receiver.Client()
+
+		mock.ExpectQuery(regexp.QuoteMeta(dueDeliveriesQuery)).
+			WithArgs(now).
+			WillReturnRows(sqlmock.NewRows(deliveryColumns).AddRow(1, 6, 9, 0, 6, u.ID, receiver.URL, "secret"))
+		expectFinishedSession(mock)
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "webhook_deliveries" SET "attempts"=attempts + 1,"delivered_at"=$1,"last_error"=$2,"next_attempt_at"=$3,"updated_at"=$4 WHERE id = $5 AND "webhook_deliveries"."deleted_at" IS NULL`)).
+			WithArgs(now, "", nil, sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		delivered, err := deliverer.DeliverDue(now)
+		require.Nil(t, err)
+		require.Equal(t, 1, delivered)
+		require.Equal(t, integration.Sign("secret", now, body), signature)
+
+		var payload integration.WorkoutSessionFinished
+		require.Nil(t, json.Unmarshal(body, &payload))
+		require.Equal(t, "workout_session.finished", payload.Event)
+		require.Equal(t, "9", payload.WorkoutSessionID)
+		require.Equal(t, "Push", payload.WorkoutRoutine)
+		require.Equal(t, int64(3600), payload.DurationSeconds)
+		require.Equal(t, float64(1850), payload.TotalVolume)
+		require.Len(t, payload.Exercises, 1)
+		require.Equal(t, "Bench", payload.Exercises[0].Name)
+		require.Len(t, payload.Exercises[0].Sets, 3)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Won't Deliver To A Private Address", func(t *testing.T) {
+		called := false
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer receiver.Close()
+
+		mock, gormDB := helpers.SetupMockDB()
+		deliverer := integration.NewDeliverer(gormDB, time.Second, 10, 3, time.Minute)
+
+		mock.ExpectQuery(regexp.QuoteMeta(dueDeliveriesQuery)).
+			WithArgs(now).
+			WillReturnRows(sqlmock.NewRows(deliveryColumns).AddRow(1, 6, 9, 0, 6, u.ID, receiver.URL, "secret"))
+		expectFinishedSession(mock)
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "webhook_deliveries" SET "attempts"=attempts + 1,"last_error"=$1,"next_attempt_at"=$2,"updated_at"=$3 WHERE id = $4 AND "webhook_deliveries"."deleted_at" IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), now.Add(time.Minute), sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		delivered, err := deliverer.DeliverDue(now)
+		require.Nil(t, err)
+		require.Equal(t, 0, delivered)
+		require.False(t, called)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Retries Failed Delivery Later", func(t *testing.T) {
+		receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer receiver.Close()
+
+		mock, gormDB := helpers.SetupMockDB()
+		deliverer := integration.NewDeliverer(gormDB, time.Second, 10, 3, time.Minute)
+		// the receiver is on loopback, which the deliverer's own client won't dial
+		deliverer.Client = receiver.Client()
+
+		mock.ExpectQuery(regexp.QuoteMeta(dueDeliveriesQuery)).
+			WithArgs(now).
+			WillReturnRows(sqlmock.NewRows(deliveryColumns).AddRow(1, 6, 9, 1, 6, u.ID, receiver.URL, "secret"))
+		expectFinishedSession(mock)
+		mock.ExpectBegin()
+		// second failure waits twice as long as the first
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "webhook_deliveries" SET "attempts"=attempts + 1,"last_error"=$1,"next_attempt_at"=$2,"updated_at"=$3 WHERE id = $4 AND "webhook_deliveries"."deleted_at" IS NULL`)).
+			WithArgs("webhook responded 502 Bad Gateway", now.Add(2*time.Minute), sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		delivered, err := deliverer.DeliverDue(now)
+		require.Nil(t, err)
+		require.Equal(t, 0, delivered)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,133 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type UpdateWeekStartResp struct {
+	UpdateWeekStart struct {
+		ID        string
+		WeekStart string
+	}
+}
+
+type GetWeeklyStatsResp struct {
+	WorkoutStats struct {
+		Weeks []struct {
+			WeekStart    string
+			SessionCount int
+			TotalSets    int
+			TotalVolume  float64
+		}
+	}
+}
+
+func TestWeekStartResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	wr := testdata.WorkoutRoutine
+
+	t.Run("Update Week Start Success", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "updated_at"=$1,"week_start"=$2 WHERE id = $3 AND "users"."deleted_at" IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), "SUNDAY", fmt.Sprintf("%d", u.ID)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		profileRow := sqlmock.NewRows([]string{"id", "name", "email", "week_start"}).AddRow(u.ID, u.Name, u.Subject, "SUNDAY")
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE id = $1`)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(profileRow)
+
+		var resp UpdateWeekStartResp
+		c.MustPost(`
+			mutation UpdateWeekStart {
+				updateWeekStart(weekStart: SUNDAY) {
+					id
+					weekStart
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, fmt.Sprintf("%d", u.ID), resp.UpdateWeekStart.ID)
+		require.Equal(t, "SUNDAY", resp.UpdateWeekStart.WeekStart)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Weekly Stats Follow Week Start", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		workoutRoutineRow := sqlmock.
+			NewRows([]string{"id", "name", "created_at", "deleted_at", "updated_at", "user_id", "active"}).
+			AddRow(wr.ID, wr.Name, wr.CreatedAt, wr.DeletedAt, wr.UpdatedAt, wr.UserID, wr.Active)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).WithArgs(fmt.Sprintf("%d", wr.ID)).WillReturnRows(workoutRoutineRow)
+
+		statsRow := sqlmock.NewRows([]string{"session_count", "total_sets", "total_volume"}).AddRow(2, 4, 3600)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(DISTINCT workout_sessions.id) AS session_count")).WillReturnRows(statsRow)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT exercise_routines.id AS exercise_routine_id")).
+			WillReturnRows(sqlmock.NewRows([]string{"exercise_routine_id", "name", "sets", "volume", "average_rest_seconds"}))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT catalog_exercise_muscle_groups.muscle_group AS muscle_group")).
+			WillReturnRows(sqlmock.NewRows([]string{"muscle_group", "sets", "volume"}))
+
+		profileRow := sqlmock.NewRows([]string{"id", "name", "email", "week_start"}).AddRow(u.ID, u.Name, u.Subject, "SUNDAY")
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE id = $1`)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(profileRow)
+
+		// sunday weeks shift sessions a day forward before truncating
+		weekRows := sqlmock.NewRows([]string{"week_start", "session_count", "total_sets", "total_volume"}).
+			AddRow(time.Date(2022, 10, 2, 0, 0, 0, 0, time.UTC), 1, 2, 1800).
+			AddRow(time.Date(2022, 10, 16, 0, 0, 0, 0, time.UTC), 1, 2, 1800)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT date_trunc('week', workout_sessions.start + $1 * interval '1 day') - $2 * interval '1 day' AS week_start")).
+			WithArgs(1, 1, fmt.Sprintf("%d", u.ID), sqlmock.AnyArg(), fmt.Sprintf("%d", wr.ID), sqlmock.AnyArg(), sqlmock.AnyArg(), fmt.Sprintf("%d", u.ID)).
+			WillReturnRows(weekRows)
+
+		var resp GetWeeklyStatsResp
+		c.MustPost(fmt.Sprintf(`
+			query WorkoutStats {
+				workoutStats(workoutRoutineId: "%d", range: { start: "2022-10-01T00:00:00Z", end: "2022-11-01T00:00:00Z" }) {
+					weeks {
+						weekStart
+						sessionCount
+						totalSets
+						totalVolume
+					}
+				}
+			}`, wr.ID), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Len(t, resp.WorkoutStats.Weeks, 2)
+		require.Equal(t, "2022-10-02T00:00:00Z", resp.WorkoutStats.Weeks[0].WeekStart)
+		require.Equal(t, 1, resp.WorkoutStats.Weeks[1].SessionCount)
+		require.Equal(t, float64(1800), resp.WorkoutStats.Weeks[1].TotalVolume)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,159 @@
+package test
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/neilZon/workout-logger-api/digest"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/notify"
+	"github.com/neilZon/workout-logger-api/schedule"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingNotifier struct {
+	channel string
+	sent    []*notify.Notification
+}
+
+func (n *recordingNotifier) Channel() string {
+	return n.channel
+}
+
+func (n *recordingNotifier) Notify(to *notify.Recipient, notification *notify.Notification) error {
+	n.sent = append(n.sent, notification)
+	return nil
+}
+
+func TestSchedule(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Next Monday Morning", func(t *testing.T) {
+		s, err := schedule.Parse("0 8 * * 1")
+		require.Nil(t, err)
+		// a wednesday
+		next := s.Next(time.Date(2022, 10, 5, 12, 0, 0, 0, time.UTC))
+		require.Equal(t, time.Date(2022, 10, 10, 8, 0, 0, 0, time.UTC), next)
+		require.Equal(t, time.Date(2022, 10, 17, 8, 0, 0, 0, time.UTC), s.Next(next))
+	})
+
+	t.Run("Steps Ranges And Lists", func(t *testing.T) {
+		s, err := schedule.Parse("*/20 9-10 1,15 * *")
+		require.Nil(t, err)
+		next := s.Next(time.Date(2022, 10, 1, 10, 45, 0, 0, time.UTC))
+		require.Equal(t, time.Date(2022, 10, 15, 9, 0, 0, 0, time.UTC), next)
+	})
+
+	t.Run("Bad Specs", func(t *testing.T) {
+		for _, spec := range []string{"0 8 * *", "60 8 * * 1", "0 8 * * 7", "0 8 */0 * *", "x 8 * * 1"} {
+			_, err := schedule.Parse(spec)
+			require.NotNil(t, err, spec)
+		}
+	})
+}
+
+func TestWeeklyDigest(t *testing.T) {
+	t.Parallel()
+
+	weekEnd := time.Date(2022, 10, 10, 8, 0, 0, 0, time.UTC)
+	weekStart := weekEnd.AddDate(0, 0, -7)
+	recipientColumns := []string{"id", "name", "email", "locale", "weekly_digest_email", "weekly_digest_push"}
+	totalsColumns := []string{"session_count", "total_sets", "total_volume"}
+	const recipientsQuery = `FROM users
+			LEFT JOIN user_preferences`
+	const totalsQuery = `SELECT COUNT(DISTINCT workout_sessions.id) AS session_count`
+	const claimStmt = `INSERT INTO "weekly_digests" ("user_id","week_end","created_at") VALUES ($1,$2,$3) ON CONFLICT DO NOTHING RETURNING "id"`
+
+	t.Run("Sends On The Channels Not Opted Out Of", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		email := &recordingNotifier{channel: notify.ChannelEmail}
+		push := &recordingNotifier{channel: notify.ChannelPush}
+		digester := digest.NewDigester(gormDB, []notify.Notifier{email, push}, 10)
+
+		mock.ExpectQuery(regexp.QuoteMeta(recipientsQuery)).
+			WithArgs(0, weekEnd, weekEnd, 10).
+			WillReturnRows(sqlmock.NewRows(recipientColumns).AddRow(41, "Lifter", "lifter@test.com", "en-GB", true, false))
+		mock.ExpectQuery(regexp.QuoteMeta(totalsQuery)).
+			WithArgs("41", weekStart, weekStart, weekEnd, "41").
+			WillReturnRows(sqlmock.NewRows(totalsColumns).AddRow(3, 45, 11000))
+		mock.ExpectQuery(regexp.QuoteMeta(totalsQuery)).
+			WithArgs("41", weekStart.AddDate(0, 0, -7), weekStart.AddDate(0, 0, -7), weekStart, "41").
+			WillReturnRows(sqlmock.NewRows(totalsColumns).AddRow(2, 40, 10000))
+		mock.ExpectQuery(regexp.QuoteMeta(`WITH session_best AS`)).
+			WithArgs("41", weekStart, weekEnd).
+			WillReturnRows(sqlmock.NewRows([]string{"exercise_routine_id", "name", "weight", "reps", "previous_weight"}).
+				AddRow(5, "Squat", 140, 3, 135))
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(claimStmt)).
+			WithArgs(41, weekEnd, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		sent, err := digester.SendWeek(weekEnd)
+		require.Nil(t, err)
+		require.Equal(t, 1, sent)
+		require.Len(t, email.sent, 1)
+		require.Len(t, push.sent, 0)
+		require.Equal(t, "3 sessions, 45 working sets and 11000 kg of volume, up 10% on the week before. New personal records on Squat.", email.sent[0].Text)
+		require.Contains(t, email.sent[0].HTML, "Squat: 140 kg x 3, up from 135")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Skips Users Who Didn't Train", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		email := &recordingNotifier{channel: notify.ChannelEmail}
+		digester := digest.NewDigester(gormDB, []notify.Notifier{email}, 10)
+
+		mock.ExpectQuery(regexp.QuoteMeta(recipientsQuery)).
+			WillReturnRows(sqlmock.NewRows(recipientColumns).AddRow(41, "Lifter", "lifter@test.com", "en-GB", true, true))
+		mock.ExpectQuery(regexp.QuoteMeta(totalsQuery)).
+			WillReturnRows(sqlmock.NewRows(totalsColumns).AddRow(0, 0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(totalsQuery)).
+			WillReturnRows(sqlmock.NewRows(totalsColumns).AddRow(0, 0, 0))
+
+		sent, err := digester.SendWeek(weekEnd)
+		require.Nil(t, err)
+		require.Equal(t, 0, sent)
+		require.Len(t, email.sent, 0)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Already Sent By Another Instance", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		email := &recordingNotifier{channel: notify.ChannelEmail}
+		digester := digest.NewDigester(gormDB, []notify.Notifier{email}, 10)
+
+		mock.ExpectQuery(regexp.QuoteMeta(recipientsQuery)).
+			WillReturnRows(sqlmock.NewRows(recipientColumns).AddRow(41, "Lifter", "lifter@test.com", "en-GB", true, true))
+		mock.ExpectQuery(regexp.QuoteMeta(totalsQuery)).
+			WillReturnRows(sqlmock.NewRows(totalsColumns).AddRow(1, 10, 2000))
+		mock.ExpectQuery(regexp.QuoteMeta(totalsQuery)).
+			WillReturnRows(sqlmock.NewRows(totalsColumns).AddRow(0, 0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(`WITH session_best AS`)).
+			WillReturnRows(sqlmock.NewRows([]string{"exercise_routine_id"}))
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(claimStmt)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectCommit()
+
+		sent, err := digester.SendWeek(weekEnd)
+		require.Nil(t, err)
+		require.Equal(t, 0, sent)
+		require.Len(t, email.sent, 0)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
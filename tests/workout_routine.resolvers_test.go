@@ -64,7 +64,7 @@ type UpdateWorkoutRoutine struct {
 }
 
 type DeleteWorkoutRoutineResp struct {
-	DeleteWorkoutRoutine int
+	DeleteWorkoutRoutine DeleteResult
 }
 
 func TestWorkoutRoutineResolvers(t *testing.T) {
@@ -87,7 +87,7 @@ func TestWorkoutRoutineResolvers(t *testing.T) {
 		mock.ExpectBegin()
 		const createWorkoutRoutineStmnt = `INSERT INTO "workout_routines" ("created_at","updated_at","deleted_at","name","active","user_id") VALUES ($1,$2,$3,$4,$5,$6) RETURNING "id"`
 		mock.ExpectQuery(regexp.QuoteMeta(createWorkoutRoutineStmnt)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), wr.Name, wr.Active, wr.UserID).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(wr.ID))
-		const createExerciseRoutineStmt = `INSERT INTO "exercise_routines" ("created_at","updated_at","deleted_at","name","sets","reps","active","workout_routine_id") VALUES ($1,$2,$3,$4,$5,$6,$7,$8),($9,$10,$11,$12,$13,$14,$15,$16) ON CONFLICT ("id") DO UPDATE SET "workout_routine_id"="excluded"."workout_routine_id" RETURNING "id"`
+		const createExerciseRoutineStmt = `INSERT INTO "exercise_routines" ("created_at","updated_at","deleted_at","name","sets","reps","active","workout_routine_id","position","catalog_exercise_id") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10),($11,$12,$13,$14,$15,$16,$17,$18,$19,$20) ON CONFLICT ("id") DO UPDATE SET "workout_routine_id"="excluded"."workout_routine_id" RETURNING "id"`
 		mock.ExpectQuery(regexp.QuoteMeta(createExerciseRoutineStmt)).WithArgs(
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
@@ -97,6 +97,8 @@ func TestWorkoutRoutineResolvers(t *testing.T) {
 			wr.ExerciseRoutines[0].Reps,
 			wr.ExerciseRoutines[0].Active,
 			wr.ExerciseRoutines[0].WorkoutRoutineID,
+			0,
+			nil,
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
@@ -104,7 +106,9 @@ func TestWorkoutRoutineResolvers(t *testing.T) {
 			wr.ExerciseRoutines[1].Sets,
 			wr.ExerciseRoutines[1].Reps,
 			wr.ExerciseRoutines[1].Active,
-			wr.ExerciseRoutines[1].WorkoutRoutineID).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(wr.ExerciseRoutines[0].ID).AddRow(wr.ExerciseRoutines[1].ID))
+			wr.ExerciseRoutines[1].WorkoutRoutineID,
+			1,
+			nil).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(wr.ExerciseRoutines[0].ID).AddRow(wr.ExerciseRoutines[1].ID))
 		mock.ExpectCommit()
 
 		var resp WorkoutRoutineResp
@@ -163,7 +167,7 @@ func TestWorkoutRoutineResolvers(t *testing.T) {
 		  }`,
 			&resp,
 			helpers.AddContext(u, helpers.NewLoaders(gormDB)))
-		require.EqualError(t, err, "[{\"message\":\"Invalid Routine Name Length\",\"path\":[\"createWorkoutRoutine\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"name needs to be between 3 and 32 characters\",\"path\":[\"createWorkoutRoutine\"],\"extensions\":{\"code\":\"INVALID_INPUT\",\"fields\":[{\"field\":\"routine.name\",\"message\":\"name needs to be between 3 and 32 characters\"}]}}]")
 	})
 
 	t.Run("Create workout routine no token", func(t *testing.T) {
@@ -199,7 +203,7 @@ func TestWorkoutRoutineResolvers(t *testing.T) {
 			NewRows([]string{"id", "name", "created_at", "deleted_at", "updated_at"}).
 			AddRow(wr.ID, wr.Name, wr.CreatedAt, wr.DeletedAt, wr.UpdatedAt)
 
-		const workoutRoutineQuery = `SELECT * FROM "workout_routines" WHERE user_id = $1 AND "workout_routines"."deleted_at" IS NULL`
+		const workoutRoutineQuery = `SELECT * FROM "workout_routines" WHERE workout_routines.user_id = $1 AND "workout_routines"."deleted_at" IS NULL`
 		mock.ExpectQuery(regexp.QuoteMeta(workoutRoutineQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(workoutRoutineRow)
 
 		exerciseRoutineRows := sqlmock.NewRows([]string{"id", "created_at", "deleted_at", "updated_at", "name", "sets", "reps", "workout_routine_id"})
@@ -358,7 +362,7 @@ func TestWorkoutRoutineResolvers(t *testing.T) {
 				wr.ExerciseRoutines[0].DeletedAt,
 				wr.ExerciseRoutines[0].UpdatedAt,
 			)
-		updateExerciseRoutineStmt := `INSERT INTO "exercise_routines" ("created_at","updated_at","deleted_at","name","sets","reps","active","workout_routine_id","id") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9) ON CONFLICT ("id") DO UPDATE SET "reps"="excluded"."reps","sets"="excluded"."sets","name"="excluded"."name","active"="excluded"."active" RETURNING *`
+		updateExerciseRoutineStmt := `INSERT INTO "exercise_routines" ("created_at","updated_at","deleted_at","name","sets","reps","active","workout_routine_id","position","catalog_exercise_id","id") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11) ON CONFLICT ("id") DO UPDATE SET "reps"="excluded"."reps","sets"="excluded"."sets","name"="excluded"."name","active"="excluded"."active","position"="excluded"."position","catalog_exercise_id"="excluded"."catalog_exercise_id" RETURNING *`
 		mock.ExpectQuery(regexp.QuoteMeta(updateExerciseRoutineStmt)).
 			WithArgs(
 				sqlmock.AnyArg(),
@@ -369,6 +373,8 @@ func TestWorkoutRoutineResolvers(t *testing.T) {
 				wr.ExerciseRoutines[0].Reps,
 				wr.Active,
 				wr.ID,
+				0,
+				nil,
 				wr.ExerciseRoutines[0].ID,
 			).WillReturnRows(exerciseRoutineRow)
 
@@ -509,7 +515,7 @@ func TestWorkoutRoutineResolvers(t *testing.T) {
 			wr.ExerciseRoutines[0].Sets, wr.ExerciseRoutines[0].Reps,
 		)
 		err := c.Post(mutation, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
-		require.EqualError(t, err, "[{\"message\":\"Error Updating Workout Routine: Access Denied\",\"path\":[\"updateWorkoutRoutine\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Updating Workout Routine: Access Denied\",\"path\":[\"updateWorkoutRoutine\"],\"extensions\":{\"code\":\"FORBIDDEN\"}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -570,7 +576,7 @@ func TestWorkoutRoutineResolvers(t *testing.T) {
 			wr.ExerciseRoutines[0].Sets, wr.ExerciseRoutines[0].Reps,
 		)
 		err := c.Post(mutation, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
-		require.EqualError(t, err, "[{\"message\":\"Error Updating Workout Routine\",\"path\":[\"updateWorkoutRoutine\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Updating Workout Routine\",\"path\":[\"updateWorkoutRoutine\"],\"extensions\":{\"code\":\"INTERNAL\"}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -628,7 +634,9 @@ func TestWorkoutRoutineResolvers(t *testing.T) {
 		var resp DeleteWorkoutRoutineResp
 		gqlQuery := fmt.Sprintf(`
 			mutation DeleteWorkoutRoutine {
-				deleteWorkoutRoutine(workoutRoutineId: "%d")
+				deleteWorkoutRoutine(workoutRoutineId: "%d") {
+					dryRun
+				}
 			}`,
 			wr.ID,
 		)
@@ -640,6 +648,79 @@ func TestWorkoutRoutineResolvers(t *testing.T) {
 		}
 	})
 
+	t.Run("Delete Workout Routine Dry Run", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		workoutRoutineRow := sqlmock.
+			NewRows([]string{"id", "name", "created_at", "deleted_at", "updated_at", "user_id", "active"}).
+			AddRow(wr.ID, wr.Name, wr.CreatedAt, wr.DeletedAt, wr.UpdatedAt, wr.UserID, wr.Active)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).WithArgs(fmt.Sprintf("%d", wr.ID)).WillReturnRows(workoutRoutineRow)
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "workout_routines" SET "deleted_at"=$1 WHERE id = $2 AND "workout_routines"."deleted_at" IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), utils.UIntToString(wr.ID)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "exercise_routines" SET "deleted_at"=$1 WHERE workout_routine_id = $2 AND "exercise_routines"."deleted_at" IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), utils.UIntToString(wr.ID)).
+			WillReturnResult(sqlmock.NewResult(1, 2))
+
+		workoutSessionRows := sqlmock.
+			NewRows([]string{"id", "user_id", "start", "end", "workout_routine_id", "created_at", "deleted_at", "updated_at"}).
+			AddRow(ws.ID, ws.UserID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.CreatedAt, ws.DeletedAt, ws.UpdatedAt)
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "workout_sessions" SET "deleted_at"=$1 WHERE workout_routine_id = $2 AND "workout_sessions"."deleted_at" IS NULL RETURNING *`)).
+			WithArgs(sqlmock.AnyArg(), utils.UIntToString(wr.ID)).
+			WillReturnRows(workoutSessionRows)
+
+		exerciseRows := sqlmock.NewRows([]string{"id", "created_at", "deleted_at", "updated_at", "workout_session_id", "exercise_routine_id"})
+		for _, e := range ws.Exercises {
+			exerciseRows.AddRow(e.ID, e.CreatedAt, e.DeletedAt, e.UpdatedAt, e.WorkoutSessionID, e.ExerciseRoutineID)
+		}
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "exercises" SET "deleted_at"=$1 WHERE workout_session_id IN ($2) AND "exercises"."deleted_at" IS NULL RETURNING *`)).
+			WithArgs(sqlmock.AnyArg(), utils.UIntToString(ws.ID)).
+			WillReturnRows(exerciseRows)
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "set_entries" SET "deleted_at"=$1 WHERE exercise_id IN ($2,$3) AND "set_entries"."deleted_at" IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), utils.UIntToString(ws.Exercises[0].ID), utils.UIntToString(ws.Exercises[1].ID)).
+			WillReturnResult(sqlmock.NewResult(1, 6))
+
+		mock.ExpectRollback()
+
+		var resp DeleteWorkoutRoutineResp
+		gqlQuery := fmt.Sprintf(`
+			mutation DeleteWorkoutRoutine {
+				deleteWorkoutRoutine(workoutRoutineId: "%d", dryRun: true) {
+					dryRun
+					counts {
+						workoutRoutines
+						exerciseRoutines
+						workoutSessions
+						exercises
+						sets
+					}
+				}
+			}`,
+			wr.ID,
+		)
+		c.MustPost(gqlQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+
+		require.True(t, resp.DeleteWorkoutRoutine.DryRun)
+		counts := resp.DeleteWorkoutRoutine.Counts
+		require.Equal(t, 1, counts.WorkoutRoutines)
+		require.Equal(t, 2, counts.ExerciseRoutines)
+		require.Equal(t, 1, counts.WorkoutSessions)
+		require.Equal(t, len(ws.Exercises), counts.Exercises)
+		require.Equal(t, 6, counts.Sets)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
 	t.Run("Delete Workout Invalid Token", func(t *testing.T) {
 		mock, gormDB := helpers.SetupMockDB()
 		acs := accesscontrol.NewAccessControllerService(gormDB)
@@ -648,7 +729,9 @@ func TestWorkoutRoutineResolvers(t *testing.T) {
 		var resp DeleteWorkoutRoutineResp
 		gqlQuery := fmt.Sprintf(`
 			mutation DeleteWorkoutRoutine {
-				deleteWorkoutRoutine(workoutRoutineId: "%d")
+				deleteWorkoutRoutine(workoutRoutineId: "%d") {
+					dryRun
+				}
 			}`,
 			wr.ID,
 		)
@@ -675,13 +758,15 @@ func TestWorkoutRoutineResolvers(t *testing.T) {
 		var resp DeleteWorkoutRoutineResp
 		gqlQuery := fmt.Sprintf(`
 			mutation DeleteWorkoutRoutine {
-				deleteWorkoutRoutine(workoutRoutineId: "%d")
+				deleteWorkoutRoutine(workoutRoutineId: "%d") {
+					dryRun
+				}
 			}`,
 			wr.ID,
 		)
 
 		err := c.Post(gqlQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
-		require.EqualError(t, err, "[{\"message\":\"Error Deleting Workout Routine: Access Denied\",\"path\":[\"deleteWorkoutRoutine\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Deleting Workout Routine: Access Denied\",\"path\":[\"deleteWorkoutRoutine\"],\"extensions\":{\"code\":\"FORBIDDEN\"}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -711,12 +796,14 @@ func TestWorkoutRoutineResolvers(t *testing.T) {
 		var resp DeleteWorkoutRoutineResp
 		gqlQuery := fmt.Sprintf(`
 			mutation DeleteWorkoutRoutine {
-				deleteWorkoutRoutine(workoutRoutineId: "%d")
+				deleteWorkoutRoutine(workoutRoutineId: "%d") {
+					dryRun
+				}
 			}`,
 			wr.ID,
 		)
 		err := c.Post(gqlQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
-		require.EqualError(t, err, "[{\"message\":\"Error Deleting Workout Routine\",\"path\":[\"deleteWorkoutRoutine\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Deleting Workout Routine\",\"path\":[\"deleteWorkoutRoutine\"],\"extensions\":{\"code\":\"INTERNAL\"}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -0,0 +1,123 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type WorkoutRoutinesPageResp struct {
+	WorkoutRoutines struct {
+		Edges []struct {
+			Cursor string
+			Node   struct {
+				ID   string
+				Name string
+			}
+		}
+		PageInfo struct {
+			HasNextPage bool
+		}
+	}
+}
+
+func TestWorkoutRoutinesPageResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	now := time.Now()
+	workoutRoutineColumns := []string{"id", "name", "active", "user_id", "created_at", "updated_at"}
+
+	t.Run("Order By Name With Prefix After Cursor", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		const workoutRoutinesQuery = `SELECT * FROM "workout_routines" WHERE workout_routines.user_id = $1 AND workout_routines.name ILIKE $2 AND (LOWER(workout_routines.name), workout_routines.id) > (SELECT LOWER(workout_routines.name), workout_routines.id FROM workout_routines WHERE workout_routines.id = $3) AND "workout_routines"."deleted_at" IS NULL ORDER BY LOWER(workout_routines.name) ASC, workout_routines.id ASC LIMIT 3`
+		workoutRoutineRows := sqlmock.NewRows(workoutRoutineColumns).
+			AddRow(4, "Legs 100%", true, u.ID, now, now).
+			AddRow(2, "Legs B", true, u.ID, now, now).
+			AddRow(9, "Legs C", true, u.ID, now, now)
+		mock.ExpectQuery(regexp.QuoteMeta(workoutRoutinesQuery)).
+			WithArgs(fmt.Sprintf("%d", u.ID), `Legs\_%`, "7").
+			WillReturnRows(workoutRoutineRows)
+
+		var resp WorkoutRoutinesPageResp
+		c.MustPost(`
+			query WorkoutRoutines {
+				workoutRoutines(first: 2, after: "7", orderBy: { field: NAME }, filter: { namePrefix: "Legs_" }) {
+					edges {
+						cursor
+						node {
+							id
+							name
+						}
+					}
+					pageInfo {
+						hasNextPage
+					}
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Len(t, resp.WorkoutRoutines.Edges, 2)
+		require.Equal(t, "2", resp.WorkoutRoutines.Edges[1].Cursor)
+		require.True(t, resp.WorkoutRoutines.PageInfo.HasNextPage)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Order By Last Performed Defaults To Most Recent", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		const workoutRoutinesQuery = `SELECT * FROM "workout_routines" WHERE workout_routines.user_id = $1 AND "workout_routines"."deleted_at" IS NULL ORDER BY COALESCE((SELECT MAX(workout_sessions.start) FROM workout_sessions WHERE workout_sessions.workout_routine_id = workout_routines.id AND workout_sessions.deleted_at IS NULL), '-infinity') DESC, workout_routines.id DESC LIMIT 6`
+		workoutRoutineRows := sqlmock.NewRows(workoutRoutineColumns).AddRow(4, "Legs", true, u.ID, now, now)
+		mock.ExpectQuery(regexp.QuoteMeta(workoutRoutinesQuery)).
+			WithArgs(fmt.Sprintf("%d", u.ID)).
+			WillReturnRows(workoutRoutineRows)
+
+		var resp WorkoutRoutinesPageResp
+		c.MustPost(`
+			query WorkoutRoutines {
+				workoutRoutines(limit: 5, orderBy: { field: LAST_PERFORMED_AT }) {
+					edges {
+						node {
+							id
+						}
+					}
+					pageInfo {
+						hasNextPage
+					}
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Len(t, resp.WorkoutRoutines.Edges, 1)
+		require.False(t, resp.WorkoutRoutines.PageInfo.HasNextPage)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -74,7 +74,7 @@ type UpdateWorkoutSession struct {
 }
 
 type DeleteWorkoutSessionResp struct {
-	DeleteWorkoutSession int
+	DeleteWorkoutSession DeleteResult
 }
 
 func TestWorkoutSessionResolvers(t *testing.T) {
@@ -86,20 +86,33 @@ func TestWorkoutSessionResolvers(t *testing.T) {
 	}
 
 	ws := testdata.WorkoutSession
-	// wr := testdata.WorkoutRoutine
+	wr := testdata.WorkoutRoutine
 	u := testdata.User
 
 	t.Run("Add Workout Session success", func(t *testing.T) {
 		mock, gormDB := helpers.SetupMockDB()
-		acs := accesscontrol.NewAccessControllerService(db)
+		acs := accesscontrol.NewAccessControllerService(gormDB)
 		c := helpers.NewGqlClient(gormDB, acs)
 
+		workoutRoutineRow := sqlmock.
+			NewRows([]string{"id", "name", "created_at", "deleted_at", "updated_at", "user_id", "active"}).
+			AddRow(wr.ID, wr.Name, wr.CreatedAt, wr.DeletedAt, wr.UpdatedAt, wr.UserID, wr.Active)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).WithArgs(utils.UIntToString(wr.ID)).WillReturnRows(workoutRoutineRow)
+
 		mock.ExpectBegin()
 
-		const addWorkoutSessionStmnt = `INSERT INTO "workout_sessions" ("created_at","updated_at","deleted_at","start","end","workout_routine_id","user_id") VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING "id"`
-		mock.ExpectQuery(regexp.QuoteMeta(addWorkoutSessionStmnt)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), ws.Start, nil, ws.WorkoutRoutineID, ws.UserID).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(ws.ID))
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.LockOpenWorkoutSessionQuery)).
+			WithArgs(u.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.LockExerciseRoutinesQuery)).
+			WithArgs(ws.WorkoutRoutineID, ws.Exercises[0].ExerciseRoutineID, ws.Exercises[1].ExerciseRoutineID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(ws.Exercises[0].ExerciseRoutineID).AddRow(ws.Exercises[1].ExerciseRoutineID))
 
-		const addExerciseStmt = `INSERT INTO "exercises" ("created_at","updated_at","deleted_at","notes","exercise_routine_id","workout_session_id") VALUES ($1,$2,$3,$4,$5,$6),($7,$8,$9,$10,$11,$12) ON CONFLICT ("id") DO UPDATE SET "workout_session_id"="excluded"."workout_session_id" RETURNING "id"`
+		const addWorkoutSessionStmnt = `INSERT INTO "workout_sessions" ("created_at","updated_at","deleted_at","start","end","workout_routine_id","user_id","client_metadata","origin_platform","origin_app_version","origin_device_id","notes") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12) RETURNING "id"`
+		mock.ExpectQuery(regexp.QuoteMeta(addWorkoutSessionStmnt)).WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), ws.Start, nil, ws.WorkoutRoutineID, ws.UserID, nil, "", "", "", "").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(ws.ID))
+
+		const addExerciseStmt = `INSERT INTO "exercises" ("created_at","updated_at","deleted_at","notes","exercise_routine_id","workout_session_id","user_id") VALUES ($1,$2,$3,$4,$5,$6,$7),($8,$9,$10,$11,$12,$13,$14) ON CONFLICT ("id") DO UPDATE SET "workout_session_id"="excluded"."workout_session_id" RETURNING "id"`
 		mock.ExpectQuery(regexp.QuoteMeta(addExerciseStmt)).WithArgs(
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
@@ -107,41 +120,82 @@ func TestWorkoutSessionResolvers(t *testing.T) {
 			ws.Exercises[0].Notes,
 			ws.Exercises[0].ExerciseRoutineID,
 			ws.Exercises[0].WorkoutSessionID,
+			ws.UserID,
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
 			ws.Exercises[1].Notes,
 			ws.Exercises[1].ExerciseRoutineID,
 			ws.Exercises[1].WorkoutSessionID,
+			ws.UserID,
 		).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(ws.Exercises[0].ID).AddRow(ws.Exercises[1].ID))
 
-		const addSetEntries = `INSERT INTO "set_entries" ("created_at","updated_at","deleted_at","weight","reps","exercise_id") VALUES ($1,$2,$3,$4,$5,$6),($7,$8,$9,$10,$11,$12),($13,$14,$15,$16,$17,$18),($19,$20,$21,$22,$23,$24) ON CONFLICT ("id") DO UPDATE SET "exercise_id"="excluded"."exercise_id" RETURNING "id"`
+		// sets are inserted per exercise since set_entries is partitioned
+		const addSetEntries = `INSERT INTO "set_entries" ("created_at","updated_at","deleted_at","weight","unit","reps","exercise_id","set_order","type","rest_time_seconds","completed_at","client_metadata","origin_platform","origin_app_version","origin_device_id") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12,$13,$14,$15),($16,$17,$18,$19,$20,$21,$22,$23,$24,$25,$26,$27,$28,$29,$30) RETURNING "id"`
 		mock.ExpectQuery(regexp.QuoteMeta(addSetEntries)).WithArgs(
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
 			ws.Exercises[0].Sets[0].Weight,
+			"LB",
 			ws.Exercises[0].Sets[0].Reps,
 			ws.Exercises[0].ID,
+			1,
+			"WORKING",
+			nil,
+			nil,
+			nil,
+			"",
+			"",
+			"",
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
 			ws.Exercises[0].Sets[1].Weight,
+			"LB",
 			ws.Exercises[0].Sets[1].Reps,
 			ws.Exercises[0].ID,
+			2,
+			"WORKING",
+			nil,
+			nil,
+			nil,
+			"",
+			"",
+			"",
+		).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(ws.Exercises[0].Sets[0].ID).AddRow(ws.Exercises[0].Sets[1].ID))
+		mock.ExpectQuery(regexp.QuoteMeta(addSetEntries)).WithArgs(
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
 			ws.Exercises[1].Sets[0].Weight,
+			"LB",
 			ws.Exercises[1].Sets[0].Reps,
 			ws.Exercises[1].ID,
+			1,
+			"WORKING",
+			nil,
+			nil,
+			nil,
+			"",
+			"",
+			"",
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
 			ws.Exercises[1].Sets[1].Weight,
+			"LB",
 			ws.Exercises[1].Sets[1].Reps,
 			ws.Exercises[1].ID,
-		).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(ws.Exercises[0].Sets[0].ID).AddRow(ws.Exercises[0].Sets[1].ID).AddRow(ws.Exercises[1].Sets[0].ID))
+			2,
+			"WORKING",
+			nil,
+			nil,
+			nil,
+			"",
+			"",
+			"",
+		).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(ws.Exercises[1].Sets[0].ID).AddRow(ws.Exercises[1].Sets[1].ID))
 
 		mock.ExpectCommit()
 
@@ -181,6 +235,132 @@ func TestWorkoutSessionResolvers(t *testing.T) {
 		}
 	})
 
+	t.Run("Add Workout Session Exercise Routine Not In Workout Routine", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(utils.UIntToString(u.ID)).WillReturnRows(userRow)
+
+		workoutRoutineRow := sqlmock.
+			NewRows([]string{"id", "name", "created_at", "deleted_at", "updated_at", "user_id", "active"}).
+			AddRow(wr.ID, wr.Name, wr.CreatedAt, wr.DeletedAt, wr.UpdatedAt, wr.UserID, wr.Active)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).WithArgs(utils.UIntToString(wr.ID)).WillReturnRows(workoutRoutineRow)
+
+		mock.ExpectBegin()
+
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.LockOpenWorkoutSessionQuery)).
+			WithArgs(u.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.LockExerciseRoutinesQuery)).
+			WithArgs(ws.WorkoutRoutineID, ws.Exercises[0].ExerciseRoutineID, ws.Exercises[1].ExerciseRoutineID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(ws.Exercises[0].ExerciseRoutineID))
+
+		mock.ExpectRollback()
+
+		var resp AddWorkoutSessionResp
+		err := c.Post(`
+			mutation AddWorkoutSession {
+				addWorkoutSession(workout: {
+					start: "2022-10-30T12:34:00Z",
+					workoutRoutineId: "8",
+					exercises: [
+						{
+							exerciseRoutineId: "3",
+							setEntries: [{ weight: 225, reps: 8 }],
+							notes: "This is a note"
+						},
+						{
+							exerciseRoutineId: "4",
+							setEntries: [{ weight: 225, reps: 8 }],
+							notes: "This is another note"
+						}
+					],
+				}) {
+					id
+				}
+			}`,
+			&resp,
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.EqualError(t, err, `[{"message":"Error Adding Workout Session: Exercise 1's Routine Must Belong To Workout Routine","path":["addWorkoutSession"],"extensions":{"code":"INVALID_INPUT","fields":[{"field":"workout.exercises.1.exerciseRoutineId","message":"exercise routine must belong to the workout routine"}]}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Add Workout Session Rolls Back A Failed Exercise", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(utils.UIntToString(u.ID)).WillReturnRows(userRow)
+
+		workoutRoutineRow := sqlmock.
+			NewRows([]string{"id", "name", "created_at", "deleted_at", "updated_at", "user_id", "active"}).
+			AddRow(wr.ID, wr.Name, wr.CreatedAt, wr.DeletedAt, wr.UpdatedAt, wr.UserID, wr.Active)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).WithArgs(utils.UIntToString(wr.ID)).WillReturnRows(workoutRoutineRow)
+
+		mock.ExpectBegin()
+
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.LockOpenWorkoutSessionQuery)).
+			WithArgs(u.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.LockExerciseRoutinesQuery)).
+			WithArgs(ws.WorkoutRoutineID, ws.Exercises[0].ExerciseRoutineID, ws.Exercises[1].ExerciseRoutineID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(ws.Exercises[0].ExerciseRoutineID).AddRow(ws.Exercises[1].ExerciseRoutineID))
+
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "workout_sessions"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(ws.ID))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "exercises"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(ws.Exercises[0].ID).AddRow(ws.Exercises[1].ID))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "set_entries"`)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(ws.Exercises[0].Sets[0].ID))
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "set_entries"`)).
+			WillReturnError(gorm.ErrInvalidValue)
+
+		// nothing from the first exercise or the session is left behind
+		mock.ExpectRollback()
+
+		var resp AddWorkoutSessionResp
+		err := c.Post(`
+			mutation AddWorkoutSession {
+				addWorkoutSession(workout: {
+					start: "2022-10-30T12:34:00Z",
+					workoutRoutineId: "8",
+					exercises: [
+						{
+							exerciseRoutineId: "3",
+							setEntries: [{ weight: 225, reps: 8 }],
+							notes: "This is a note"
+						},
+						{
+							exerciseRoutineId: "4",
+							setEntries: [{ weight: 225, reps: 8 }],
+							notes: "This is another note"
+						}
+					],
+				}) {
+					id
+				}
+			}`,
+			&resp,
+			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
+		)
+		require.EqualError(t, err, `[{"message":"Error Adding Workout Session: Could Not Add Exercise 1","path":["addWorkoutSession"],"extensions":{"code":"INTERNAL"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
 	t.Run("Add Workout Session Access Invalid Token", func(t *testing.T) {
 		_, gormDB := helpers.SetupMockDB()
 		acs := accesscontrol.NewAccessControllerService(gormDB)
@@ -222,14 +402,7 @@ func TestWorkoutSessionResolvers(t *testing.T) {
 		acs := accesscontrol.NewAccessControllerService(gormDB)
 		c := helpers.NewGqlClient(gormDB, acs)
 
-		mock.ExpectBegin()
-
-		const addWorkoutSessionStmnt = `INSERT INTO "workout_sessions" ("created_at","updated_at","deleted_at","start","end","workout_routine_id","user_id") VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING "id"`
-		mock.ExpectQuery(regexp.QuoteMeta(addWorkoutSessionStmnt)).
-			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), ws.Start, nil, 8789, ws.UserID).
-			WillReturnError(gorm.ErrInvalidValue)
-
-		mock.ExpectRollback()
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).WithArgs("8789").WillReturnError(gorm.ErrRecordNotFound)
 
 		var resp AddWorkoutSessionResp
 		err := c.Post(`
@@ -260,7 +433,7 @@ func TestWorkoutSessionResolvers(t *testing.T) {
 			&resp,
 			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
 		)
-		require.EqualError(t, err, "[{\"message\":\"Error Adding Workout Session\",\"path\":[\"addWorkoutSession\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Adding Workout Session: Access Denied\",\"path\":[\"addWorkoutSession\"],\"extensions\":{\"code\":\"FORBIDDEN\"}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -273,14 +446,27 @@ func TestWorkoutSessionResolvers(t *testing.T) {
 		acs := accesscontrol.NewAccessControllerService(gormDB)
 		c := helpers.NewGqlClient(gormDB, acs)
 
+		workoutRoutineRow := sqlmock.
+			NewRows([]string{"id", "name", "created_at", "deleted_at", "updated_at", "user_id", "active"}).
+			AddRow(wr.ID, wr.Name, wr.CreatedAt, wr.DeletedAt, wr.UpdatedAt, wr.UserID, wr.Active)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).WithArgs(utils.UIntToString(wr.ID)).WillReturnRows(workoutRoutineRow)
+
 		mock.ExpectBegin()
 
-		const addWorkoutSessionStmnt = `INSERT INTO "workout_sessions" ("created_at","updated_at","deleted_at","start","end","workout_routine_id","user_id") VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING "id"`
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.LockOpenWorkoutSessionQuery)).
+			WithArgs(u.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.LockExerciseRoutinesQuery)).
+			WithArgs(ws.WorkoutRoutineID, ws.Exercises[0].ExerciseRoutineID, ws.Exercises[1].ExerciseRoutineID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(ws.Exercises[0].ExerciseRoutineID).AddRow(ws.Exercises[1].ExerciseRoutineID))
+
+		const addWorkoutSessionStmnt = `INSERT INTO "workout_sessions" ("created_at","updated_at","deleted_at","start","end","workout_routine_id","user_id","client_metadata","origin_platform","origin_app_version","origin_device_id","notes") VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11,$12) RETURNING "id"`
 		mock.ExpectQuery(regexp.QuoteMeta(addWorkoutSessionStmnt)).
-			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), ws.Start, nil, ws.WorkoutRoutineID, ws.UserID).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), ws.Start, nil, ws.WorkoutRoutineID, ws.UserID, nil, "", "", "", "").
 			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(ws.ID))
 
-		const addExerciseStmt = `INSERT INTO "exercises" ("created_at","updated_at","deleted_at","notes","exercise_routine_id","workout_session_id") VALUES ($1,$2,$3,$4,$5,$6),($7,$8,$9,$10,$11,$12) ON CONFLICT ("id") DO UPDATE SET "workout_session_id"="excluded"."workout_session_id" RETURNING "id"`
+		const addExerciseStmt = `INSERT INTO "exercises" ("created_at","updated_at","deleted_at","notes","exercise_routine_id","workout_session_id","user_id") VALUES ($1,$2,$3,$4,$5,$6,$7),($8,$9,$10,$11,$12,$13,$14) ON CONFLICT ("id") DO UPDATE SET "workout_session_id"="excluded"."workout_session_id" RETURNING "id"`
 		mock.ExpectQuery(regexp.QuoteMeta(addExerciseStmt)).WithArgs(
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
@@ -288,12 +474,14 @@ func TestWorkoutSessionResolvers(t *testing.T) {
 			ws.Exercises[0].Notes,
 			ws.Exercises[0].ExerciseRoutineID,
 			ws.Exercises[0].WorkoutSessionID,
+			ws.UserID,
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
 			sqlmock.AnyArg(),
 			ws.Exercises[1].Notes,
 			ws.Exercises[1].ExerciseRoutineID,
 			ws.Exercises[1].WorkoutSessionID,
+			ws.UserID,
 		).WillReturnError(gorm.ErrInvalidValue)
 
 		mock.ExpectRollback()
@@ -327,7 +515,7 @@ func TestWorkoutSessionResolvers(t *testing.T) {
 			&resp,
 			helpers.AddContext(u, helpers.NewLoaders(gormDB)),
 		)
-		require.EqualError(t, err, "[{\"message\":\"Error Adding Workout Session\",\"path\":[\"addWorkoutSession\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Adding Workout Session\",\"path\":[\"addWorkoutSession\"],\"extensions\":{\"code\":\"INTERNAL\"}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -345,7 +533,11 @@ func TestWorkoutSessionResolvers(t *testing.T) {
 			AddRow(ws.ID, ws.UserID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.CreatedAt, ws.DeletedAt, ws.UpdatedAt)
 		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(utils.UIntToString(ws.ID)).WillReturnRows(workoutSessionRow)
 
+		helpers.ExpectEditableWorkoutSession(mock, ws.ID)
 		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "workout_sessions" WHERE (id = $1 AND "end" IS NULL) AND "workout_sessions"."deleted_at" IS NULL FOR UPDATE`)).
+			WithArgs(utils.UIntToString(ws.ID)).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(ws.ID))
 
 		updatedWorkoutSessionRow := sqlmock.
 			NewRows([]string{"id", "user_id", "start", "end", "workout_routine_id", "created_at", "deleted_at", "updated_at"}).
@@ -354,6 +546,9 @@ func TestWorkoutSessionResolvers(t *testing.T) {
 		mock.ExpectQuery(regexp.QuoteMeta(updateWorkoutSessionStmt)).
 			WithArgs(sqlmock.AnyArg(), ws.End, utils.UIntToString(ws.ID)).
 			WillReturnRows(updatedWorkoutSessionRow)
+		// ending it finishes it
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO webhook_deliveries`)).WithArgs(ws.ID, ws.UserID).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO strava_uploads`)).WithArgs(ws.ID, ws.UserID).WillReturnResult(sqlmock.NewResult(0, 0))
 
 		mock.ExpectCommit()
 
@@ -424,7 +619,7 @@ func TestWorkoutSessionResolvers(t *testing.T) {
 			}`, ws.ID, ws.End.Format(time.RFC3339))
 		var resp UpdateWorkoutSession
 		err := c.Post(gqlQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
-		require.EqualError(t, err, "[{\"message\":\"Error Updating Workout Session: Access Denied\",\"path\":[\"updateWorkoutSession\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Updating Workout Session: Access Denied\",\"path\":[\"updateWorkoutSession\"],\"extensions\":{\"code\":\"FORBIDDEN\"}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -442,6 +637,7 @@ func TestWorkoutSessionResolvers(t *testing.T) {
 			AddRow(ws.ID, ws.UserID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.CreatedAt, ws.DeletedAt, ws.UpdatedAt)
 		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(utils.UIntToString(ws.ID)).WillReturnRows(workoutSessionRow)
 
+		helpers.ExpectEditableWorkoutSession(mock, ws.ID)
 		mock.ExpectBegin()
 
 		updateWorkoutSessionStmt := `UPDATE "workout_sessions" SET "updated_at"=$1,"end"=$2 WHERE id = $3 AND "workout_sessions"."deleted_at" IS NULL RETURNING *`
@@ -463,7 +659,7 @@ func TestWorkoutSessionResolvers(t *testing.T) {
 			}`, ws.ID, ws.End.Format(time.RFC3339))
 		var resp UpdateWorkoutSession
 		err := c.Post(gqlQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
-		require.EqualError(t, err, "[{\"message\":\"Error Updating Workout Session\",\"path\":[\"updateWorkoutSession\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Updating Workout Session\",\"path\":[\"updateWorkoutSession\"],\"extensions\":{\"code\":\"INTERNAL\"}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -481,6 +677,7 @@ func TestWorkoutSessionResolvers(t *testing.T) {
 			AddRow(ws.ID, ws.UserID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.CreatedAt, ws.DeletedAt, ws.UpdatedAt)
 		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(utils.UIntToString(ws.ID)).WillReturnRows(workoutSessionRow)
 
+		helpers.ExpectEditableWorkoutSession(mock, ws.ID)
 		mock.ExpectBegin()
 		deleteWorkoutSessionQuery := `UPDATE "workout_sessions" SET "deleted_at"=$1 WHERE id = $2 AND "workout_sessions"."deleted_at" IS NULL`
 		mock.ExpectExec(regexp.QuoteMeta(deleteWorkoutSessionQuery)).WithArgs(sqlmock.AnyArg(), utils.UIntToString(ws.ID)).WillReturnResult(sqlmock.NewResult(1, 1))
@@ -500,11 +697,69 @@ func TestWorkoutSessionResolvers(t *testing.T) {
 		mock.ExpectCommit()
 
 		gqlQuery := fmt.Sprintf(`mutation DeleteWorkoutSession {
-			deleteWorkoutSession(workoutSessionId: "%d")
+			deleteWorkoutSession(workoutSessionId: "%d") {
+				dryRun
+			}
+		}`, ws.ID)
+		var resp DeleteWorkoutSessionResp
+		c.MustPost(gqlQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Delete Workout Session Dry Run", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		workoutSessionRow := sqlmock.
+			NewRows([]string{"id", "user_id", "start", "end", "workout_routine_id", "created_at", "deleted_at", "updated_at"}).
+			AddRow(ws.ID, ws.UserID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.CreatedAt, ws.DeletedAt, ws.UpdatedAt)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(utils.UIntToString(ws.ID)).WillReturnRows(workoutSessionRow)
+
+		helpers.ExpectEditableWorkoutSession(mock, ws.ID)
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "workout_sessions" SET "deleted_at"=$1 WHERE id = $2 AND "workout_sessions"."deleted_at" IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), utils.UIntToString(ws.ID)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		exerciseRows := sqlmock.NewRows([]string{"id", "created_at", "deleted_at", "updated_at", "workout_session_id", "exercise_routine_id"})
+		for _, e := range ws.Exercises {
+			exerciseRows.AddRow(e.ID, e.CreatedAt, e.DeletedAt, e.UpdatedAt, e.WorkoutSessionID, e.ExerciseRoutineID)
+		}
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "exercises" SET "deleted_at"=$1 WHERE workout_session_id = $2 AND "exercises"."deleted_at" IS NULL RETURNING *`)).
+			WithArgs(sqlmock.AnyArg(), utils.UIntToString(ws.ID)).
+			WillReturnRows(exerciseRows)
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "set_entries" SET "deleted_at"=$1 WHERE exercise_id IN ($2,$3) AND "set_entries"."deleted_at" IS NULL`)).
+			WithArgs(sqlmock.AnyArg(), utils.UIntToString(ws.Exercises[0].ID), utils.UIntToString(ws.Exercises[1].ID)).
+			WillReturnResult(sqlmock.NewResult(1, 6))
+
+		mock.ExpectRollback()
+
+		gqlQuery := fmt.Sprintf(`mutation DeleteWorkoutSession {
+			deleteWorkoutSession(workoutSessionId: "%d", dryRun: true) {
+				dryRun
+				counts {
+					workoutSessions
+					exercises
+					sets
+				}
+			}
 		}`, ws.ID)
 		var resp DeleteWorkoutSessionResp
 		c.MustPost(gqlQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
 
+		require.True(t, resp.DeleteWorkoutSession.DryRun)
+		require.Equal(t, 1, resp.DeleteWorkoutSession.Counts.WorkoutSessions)
+		require.Equal(t, len(ws.Exercises), resp.DeleteWorkoutSession.Counts.Exercises)
+		require.Equal(t, 6, resp.DeleteWorkoutSession.Counts.Sets)
+
 		err = mock.ExpectationsWereMet()
 		if err != nil {
 			panic(err)
@@ -517,7 +772,9 @@ func TestWorkoutSessionResolvers(t *testing.T) {
 		c := helpers.NewGqlClient(gormDB, acs)
 
 		gqlQuery := fmt.Sprintf(`mutation DeleteWorkoutSession {
-			deleteWorkoutSession(workoutSessionId: "%d")
+			deleteWorkoutSession(workoutSessionId: "%d") {
+				dryRun
+			}
 		}`, ws.ID)
 		var resp DeleteWorkoutSessionResp
 		err := c.Post(gqlQuery, &resp)
@@ -541,11 +798,13 @@ func TestWorkoutSessionResolvers(t *testing.T) {
 		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(utils.UIntToString(ws.ID)).WillReturnRows(workoutSessionRow)
 
 		gqlQuery := fmt.Sprintf(`mutation DeleteWorkoutSession {
-			deleteWorkoutSession(workoutSessionId: "%d")
+			deleteWorkoutSession(workoutSessionId: "%d") {
+				dryRun
+			}
 		}`, ws.ID)
 		var resp DeleteWorkoutSessionResp
 		err := c.Post(gqlQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
-		require.EqualError(t, err, "[{\"message\":\"Error Deleting Workout Session: Access Denied\",\"path\":[\"deleteWorkoutSession\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Deleting Workout Session: Access Denied\",\"path\":[\"deleteWorkoutSession\"],\"extensions\":{\"code\":\"FORBIDDEN\"}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
@@ -563,17 +822,20 @@ func TestWorkoutSessionResolvers(t *testing.T) {
 			AddRow(ws.ID, ws.UserID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.CreatedAt, ws.DeletedAt, ws.UpdatedAt)
 		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(utils.UIntToString(ws.ID)).WillReturnRows(workoutSessionRow)
 
+		helpers.ExpectEditableWorkoutSession(mock, ws.ID)
 		mock.ExpectBegin()
 		deleteWorkoutSessionQuery := `UPDATE "workout_sessions" SET "deleted_at"=$1 WHERE id = $2 AND "workout_sessions"."deleted_at" IS NULL`
 		mock.ExpectExec(regexp.QuoteMeta(deleteWorkoutSessionQuery)).WithArgs(sqlmock.AnyArg(), utils.UIntToString(ws.ID)).WillReturnError(gorm.ErrInvalidTransaction)
 		mock.ExpectRollback()
 
 		gqlQuery := fmt.Sprintf(`mutation DeleteWorkoutSession {
-			deleteWorkoutSession(workoutSessionId: "%d")
+			deleteWorkoutSession(workoutSessionId: "%d") {
+				dryRun
+			}
 		}`, ws.ID)
 		var resp DeleteWorkoutSessionResp
 		err := c.Post(gqlQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
-		require.EqualError(t, err, "[{\"message\":\"Error Deleting Workout Session\",\"path\":[\"deleteWorkoutSession\"]}]")
+		require.EqualError(t, err, "[{\"message\":\"Error Deleting Workout Session\",\"path\":[\"deleteWorkoutSession\"],\"extensions\":{\"code\":\"INTERNAL\"}}]")
 
 		err = mock.ExpectationsWereMet()
 		if err != nil {
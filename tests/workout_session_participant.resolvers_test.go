@@ -0,0 +1,132 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type JoinWorkoutSessionResp struct {
+	JoinWorkoutSession struct {
+		ID string
+	}
+}
+
+func TestWorkoutSessionParticipantResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	ws := testdata.WorkoutSession
+	partner := *testdata.User
+	partner.ID = 55
+
+	const joinWorkoutSessionMutation = `
+		mutation JoinWorkoutSession {
+			joinWorkoutSession(inviteToken: "share-token") {
+				id
+			}
+		}`
+	const shareLinkQuery = `SELECT * FROM "workout_session_share_links" WHERE token = $1 AND "workout_session_share_links"."deleted_at" IS NULL ORDER BY "workout_session_share_links"."id" LIMIT 1`
+	const shareLinkSessionQuery = `SELECT * FROM "workout_sessions" WHERE "workout_sessions"."id" = $1 AND "workout_sessions"."deleted_at" IS NULL`
+	const participantQuery = `SELECT * FROM "workout_session_participants" WHERE (workout_session_id = $1 AND user_id = $2) AND "workout_session_participants"."deleted_at" IS NULL ORDER BY "workout_session_participants"."id" LIMIT 1`
+
+	workoutSessionColumns := []string{"id", "start", "end", "workout_routine_id", "user_id", "created_at", "deleted_at", "updated_at"}
+
+	t.Run("Join Workout Session Success", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(partner.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", partner.ID)).WillReturnRows(userRow)
+
+		shareLinkRow := sqlmock.NewRows([]string{"id", "token", "workout_session_id", "user_id", "invite"}).AddRow(1, "share-token", ws.ID, ws.UserID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(shareLinkQuery)).WithArgs("share-token").WillReturnRows(shareLinkRow)
+		workoutSessionRow := sqlmock.
+			NewRows(workoutSessionColumns).
+			AddRow(ws.ID, ws.Start, nil, ws.WorkoutRoutineID, ws.UserID, ws.CreatedAt, nil, ws.UpdatedAt)
+		mock.ExpectQuery(regexp.QuoteMeta(shareLinkSessionQuery)).WithArgs(ws.ID).WillReturnRows(workoutSessionRow)
+
+		mock.ExpectQuery(regexp.QuoteMeta(participantQuery)).
+			WithArgs(fmt.Sprintf("%d", ws.ID), fmt.Sprintf("%d", partner.ID)).
+			WillReturnError(gorm.ErrRecordNotFound)
+
+		mock.ExpectBegin()
+		const addParticipantStmt = `INSERT INTO "workout_session_participants" ("created_at","updated_at","deleted_at","workout_session_id","user_id") VALUES ($1,$2,$3,$4,$5) RETURNING "id"`
+		mock.ExpectQuery(regexp.QuoteMeta(addParticipantStmt)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, ws.ID, partner.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectCommit()
+
+		var resp JoinWorkoutSessionResp
+		c.MustPost(joinWorkoutSessionMutation, &resp, helpers.AddContext(&partner, helpers.NewLoaders(gormDB)))
+		require.Equal(t, fmt.Sprintf("%d", ws.ID), resp.JoinWorkoutSession.ID)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Join Workout Session Already Ended", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(partner.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", partner.ID)).WillReturnRows(userRow)
+
+		shareLinkRow := sqlmock.NewRows([]string{"id", "token", "workout_session_id", "user_id", "invite"}).AddRow(1, "share-token", ws.ID, ws.UserID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(shareLinkQuery)).WithArgs("share-token").WillReturnRows(shareLinkRow)
+		workoutSessionRow := sqlmock.
+			NewRows(workoutSessionColumns).
+			AddRow(ws.ID, ws.Start, ws.End, ws.WorkoutRoutineID, ws.UserID, ws.CreatedAt, nil, ws.UpdatedAt)
+		mock.ExpectQuery(regexp.QuoteMeta(shareLinkSessionQuery)).WithArgs(ws.ID).WillReturnRows(workoutSessionRow)
+
+		var resp JoinWorkoutSessionResp
+		err := c.Post(joinWorkoutSessionMutation, &resp, helpers.AddContext(&partner, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, "[{\"message\":\"Error Joining Workout Session: Invite Expired\",\"path\":[\"joinWorkoutSession\"],\"extensions\":{\"code\":\"INVALID_INPUT\"}}]")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Join Workout Session With Spectator Link", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(partner.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", partner.ID)).WillReturnRows(userRow)
+
+		shareLinkRow := sqlmock.NewRows([]string{"id", "token", "workout_session_id", "user_id", "invite"}).AddRow(1, "share-token", ws.ID, ws.UserID, false)
+		mock.ExpectQuery(regexp.QuoteMeta(shareLinkQuery)).WithArgs("share-token").WillReturnRows(shareLinkRow)
+		workoutSessionRow := sqlmock.
+			NewRows(workoutSessionColumns).
+			AddRow(ws.ID, ws.Start, nil, ws.WorkoutRoutineID, ws.UserID, ws.CreatedAt, nil, ws.UpdatedAt)
+		mock.ExpectQuery(regexp.QuoteMeta(shareLinkSessionQuery)).WithArgs(ws.ID).WillReturnRows(workoutSessionRow)
+
+		var resp JoinWorkoutSessionResp
+		err := c.Post(joinWorkoutSessionMutation, &resp, helpers.AddContext(&partner, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, "[{\"message\":\"Error Joining Workout Session: Share Link Isn't An Invite\",\"path\":[\"joinWorkoutSession\"],\"extensions\":{\"code\":\"FORBIDDEN\"}}]")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,186 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/neilZon/workout-logger-api/utils"
+	"github.com/stretchr/testify/require"
+)
+
+type SetWorkoutSessionTagsResp struct {
+	SetWorkoutSessionTags struct {
+		ID    string
+		Notes string
+		Tags  []string
+	}
+}
+
+func TestWorkoutSessionTagResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	ws := testdata.WorkoutSession
+	userId := fmt.Sprintf("%d", u.ID)
+	workoutSessionId := utils.UIntToString(ws.ID)
+	workoutSessionColumns := []string{"id", "user_id", "start", "end", "workout_routine_id", "notes"}
+
+	t.Run("Set Tags", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).
+			WithArgs(workoutSessionId).
+			WillReturnRows(sqlmock.NewRows(workoutSessionColumns).AddRow(ws.ID, u.ID, ws.Start, ws.End, ws.WorkoutRoutineID, "felt heavy"))
+		helpers.ExpectEditableWorkoutSession(mock, ws.ID)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).
+			WithArgs(workoutSessionId).
+			WillReturnRows(sqlmock.NewRows(workoutSessionColumns).AddRow(ws.ID, u.ID, ws.Start, ws.End, ws.WorkoutRoutineID, "felt heavy"))
+
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "workout_session_tags" WHERE workout_session_id = $1`)).
+			WithArgs(ws.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		// "Deload" and "deload " are the same tag
+		mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "tags" ("created_at","updated_at","deleted_at","name") VALUES ($1,$2,$3,$4),($5,$6,$7,$8) ON CONFLICT DO NOTHING RETURNING "id"`)).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), nil, "deload", sqlmock.AnyArg(), sqlmock.AnyArg(), nil, "competition prep").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "tags" WHERE name IN ($1,$2) AND "tags"."deleted_at" IS NULL`)).
+			WithArgs("deload", "competition prep").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(4))
+		mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "workout_session_tags" ("workout_session_id","tag_id") VALUES ($1,$2),($3,$4)`)).
+			WithArgs(ws.ID, 1, ws.ID, 4).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT workout_session_tags.workout_session_id, tags.name FROM "workout_session_tags" JOIN tags ON tags.id = workout_session_tags.tag_id WHERE workout_session_tags.workout_session_id IN ($1) ORDER BY tags.name`)).
+			WithArgs(workoutSessionId).
+			WillReturnRows(sqlmock.NewRows([]string{"workout_session_id", "name"}).AddRow(ws.ID, "competition prep").AddRow(ws.ID, "deload"))
+
+		var resp SetWorkoutSessionTagsResp
+		c.MustPost(fmt.Sprintf(`
+			mutation SetWorkoutSessionTags {
+				setWorkoutSessionTags(workoutSessionId: "%d", tags: ["Deload", "deload ", "competition prep"]) {
+					id
+					notes
+					tags
+				}
+			}`, ws.ID), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, "felt heavy", resp.SetWorkoutSessionTags.Notes)
+		require.Equal(t, []string{"competition prep", "deload"}, resp.SetWorkoutSessionTags.Tags)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Too Many Tags", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+
+		tags := make([]string, 11)
+		for i := range tags {
+			tags[i] = fmt.Sprintf(`"tag %d"`, i)
+		}
+		var resp SetWorkoutSessionTagsResp
+		err := c.Post(fmt.Sprintf(`
+			mutation SetWorkoutSessionTags {
+				setWorkoutSessionTags(workoutSessionId: "%d", tags: [%s]) {
+					id
+				}
+			}`, ws.ID, strings.Join(tags, ", ")), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"a session can have at most 10 tags","path":["setWorkoutSessionTags"],"extensions":{"code":"INVALID_INPUT"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Set Notes On Someone Else's Session", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).
+			WithArgs(workoutSessionId).
+			WillReturnRows(sqlmock.NewRows(workoutSessionColumns).AddRow(ws.ID, 99, ws.Start, ws.End, ws.WorkoutRoutineID, ""))
+
+		var resp struct{ SetWorkoutSessionNotes struct{ ID string } }
+		err := c.Post(fmt.Sprintf(`
+			mutation SetWorkoutSessionNotes {
+				setWorkoutSessionNotes(workoutSessionId: "%d", notes: "mine now") {
+					id
+				}
+			}`, ws.ID), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, `[{"message":"Error Setting Workout Session Notes: Access Denied","path":["setWorkoutSessionNotes"],"extensions":{"code":"FORBIDDEN"}}]`)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Filter Workout Sessions By Tag", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_sessions" WHERE user_id = $1 AND id IN (SELECT workout_session_tags.workout_session_id FROM workout_session_tags JOIN tags ON tags.id = workout_session_tags.tag_id WHERE tags.name = $2) AND "workout_sessions"."deleted_at" IS NULL ORDER BY id desc LIMIT 10`)).
+			WithArgs(userId, "deload").
+			WillReturnRows(sqlmock.NewRows(workoutSessionColumns).AddRow(ws.ID, u.ID, ws.Start, ws.End, ws.WorkoutRoutineID, "easy week"))
+
+		var resp struct {
+			WorkoutSessions struct {
+				Edges []struct {
+					Node struct {
+						ID    string
+						Notes string
+					}
+				}
+			}
+		}
+		c.MustPost(`
+			query WorkoutSessions {
+				workoutSessions(limit: 10, tag: " Deload") {
+					edges {
+						node {
+							id
+							notes
+						}
+					}
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Len(t, resp.WorkoutSessions.Edges, 1)
+		require.Equal(t, "easy week", resp.WorkoutSessions.Edges[0].Node.Notes)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,213 @@
+package test
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type NestedWorkoutSessionsResp struct {
+	WorkoutSessions struct {
+		Edges []struct {
+			Node struct {
+				ID        string
+				Exercises []struct {
+					ID   string
+					Sets []struct {
+						ID     string
+						Weight float64
+					}
+				}
+			}
+		}
+	}
+}
+
+// sqlmock fails on any statement it wasn't told to expect, so these pin down
+// exactly what a page of sessions costs
+func TestWorkoutSessionsQueryCount(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	userId := fmt.Sprintf("%d", u.ID)
+	const sessionsQuery = `SELECT * FROM "workout_sessions" WHERE user_id = $1 AND "workout_sessions"."deleted_at" IS NULL ORDER BY id desc LIMIT 10`
+	const exercisesQuery = `SELECT * FROM "exercises" WHERE "exercises"."workout_session_id" IN ($1,$2,$3) AND "exercises"."deleted_at" IS NULL ORDER BY id`
+	const setsQuery = `SELECT * FROM "set_entries" WHERE "set_entries"."exercise_id" IN ($1,$2,$3,$4,$5,$6) AND "set_entries"."deleted_at" IS NULL ORDER BY set_order, id`
+
+	// 3 sessions of 2 exercises of 2 sets
+	expectPage := func(mock sqlmock.Sqlmock, withSets bool) {
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+
+		sessionRows := sqlmock.NewRows([]string{"id", "start", "workout_routine_id", "user_id"})
+		exerciseRows := sqlmock.NewRows([]string{"id", "workout_session_id", "exercise_routine_id", "user_id", "notes"})
+		setRows := sqlmock.NewRows([]string{"id", "exercise_id", "weight", "unit", "reps", "set_order", "type"})
+		for s := uint(3); s >= 1; s-- {
+			sessionRows.AddRow(s, time.Now(), 1, u.ID)
+		}
+		for e := uint(1); e <= 6; e++ {
+			exerciseRows.AddRow(e, (e+1)/2, 1, u.ID, "")
+			for set := uint(1); set <= 2; set++ {
+				setRows.AddRow(10*e+set, e, 100+set, "LB", 5, set, "WORKING")
+			}
+		}
+		mock.ExpectQuery(regexp.QuoteMeta(sessionsQuery)).WithArgs(userId).WillReturnRows(sessionRows)
+		mock.ExpectQuery(regexp.QuoteMeta(exercisesQuery)).WithArgs(3, 2, 1).WillReturnRows(exerciseRows)
+		if withSets {
+			mock.ExpectQuery(regexp.QuoteMeta(setsQuery)).WithArgs(1, 2, 3, 4, 5, 6).WillReturnRows(setRows)
+		}
+	}
+
+	t.Run("Exercises And Sets Take A Query Each", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		c := helpers.NewGqlClient(gormDB, accesscontrol.NewAccessControllerService(gormDB))
+		expectPage(mock, true)
+
+		var resp NestedWorkoutSessionsResp
+		c.MustPost(`
+			query WorkoutSessions {
+				workoutSessions(limit: 10) {
+					edges {
+						node {
+							id
+							exercises {
+								id
+								sets {
+									id
+									weight
+								}
+							}
+						}
+					}
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+
+		edges := resp.WorkoutSessions.Edges
+		require.Len(t, edges, 3)
+		require.Equal(t, "3", edges[0].Node.ID)
+		require.Len(t, edges[0].Node.Exercises, 2)
+		require.Equal(t, "5", edges[0].Node.Exercises[0].ID)
+		require.Len(t, edges[0].Node.Exercises[0].Sets, 2)
+		require.Equal(t, "51", edges[0].Node.Exercises[0].Sets[0].ID)
+		require.Equal(t, 101.0, edges[0].Node.Exercises[0].Sets[0].Weight)
+		require.Equal(t, "12", edges[2].Node.Exercises[0].Sets[1].ID)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Sets Aren't Loaded Unless Asked For", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		c := helpers.NewGqlClient(gormDB, accesscontrol.NewAccessControllerService(gormDB))
+		expectPage(mock, false)
+
+		// an alias in a fragment is still the exercises field
+		var resp struct {
+			WorkoutSessions struct {
+				Edges []struct {
+					Node struct {
+						ID    string
+						Moves []struct{ ID string }
+					}
+				}
+			}
+		}
+		c.MustPost(`
+			query WorkoutSessions {
+				workoutSessions(limit: 10) {
+					edges {
+						node {
+							id
+							... on WorkoutSession {
+								moves: exercises {
+									id
+								}
+							}
+						}
+					}
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Len(t, resp.WorkoutSessions.Edges, 3)
+		require.Len(t, resp.WorkoutSessions.Edges[1].Node.Moves, 2)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+	t.Run("Previous Exercises Take One Query", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		c := helpers.NewGqlClient(gormDB, accesscontrol.NewAccessControllerService(gormDB))
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(userId).WillReturnRows(userRow)
+		sessionRows := sqlmock.NewRows([]string{"id", "start", "workout_routine_id", "user_id"})
+		for s := 3; s >= 1; s-- {
+			sessionRows.AddRow(s, time.Now().AddDate(0, 0, -7*(4-s)), 1, u.ID)
+		}
+		mock.ExpectQuery(regexp.QuoteMeta(sessionsQuery)).WithArgs(userId).WillReturnRows(sessionRows)
+
+		// a routine and a start a session, in whatever order the loader got them
+		args := make([]driver.Value, 9)
+		for i := range args {
+			args[i] = sqlmock.AnyArg()
+		}
+		prevRows := sqlmock.NewRows([]string{"n", "id", "exercise_routine_id", "user_id", "notes"}).
+			AddRow(0, 41, 1, u.ID, "").
+			AddRow(1, 42, 1, u.ID, "").
+			AddRow(2, 43, 1, u.ID, "")
+		mock.ExpectQuery(regexp.QuoteMeta(`FROM (VALUES ($1::int, $2::bigint, $3::timestamptz), ($4::int, $5::bigint, $6::timestamptz), ($7::int, $8::bigint, $9::timestamptz)) AS prev`)).
+			WithArgs(args...).
+			WillReturnRows(prevRows)
+
+		var resp struct {
+			WorkoutSessions struct {
+				Edges []struct {
+					Node struct {
+						ID            string
+						PrevExercises []struct{ ID string }
+					}
+				}
+			}
+		}
+		c.MustPost(`
+			query WorkoutSessions {
+				workoutSessions(limit: 10) {
+					edges {
+						node {
+							id
+							prevExercises {
+								id
+							}
+						}
+					}
+				}
+			}`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+
+		require.Len(t, resp.WorkoutSessions.Edges, 3)
+		for _, edge := range resp.WorkoutSessions.Edges {
+			require.Len(t, edge.Node.PrevExercises, 1)
+		}
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
@@ -0,0 +1,183 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+type GetWorkoutStatsResp struct {
+	WorkoutStats struct {
+		TotalVolume          float64
+		TotalSets            int
+		SessionCount         int
+		ExerciseRoutineStats []struct {
+			ExerciseRoutineId  string
+			Name               string
+			Sets               int
+			Volume             float64
+			AverageRestSeconds *float64
+		}
+		MuscleGroupStats []struct {
+			MuscleGroup string
+			Sets        int
+			Volume      float64
+		}
+	}
+}
+
+func TestWorkoutStatsResolvers(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	wr := testdata.WorkoutRoutine
+
+	const workoutStatsQuery = `
+		query WorkoutStats {
+			workoutStats(workoutRoutineId: "%d", range: { start: "%s", end: "%s" }) {
+				totalVolume
+				totalSets
+				sessionCount
+				exerciseRoutineStats {
+					exerciseRoutineId
+					name
+					sets
+					volume
+					averageRestSeconds
+				}
+				muscleGroupStats {
+					muscleGroup
+					sets
+					volume
+				}
+			}
+		}`
+
+	t.Run("Get Workout Stats Success", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		workoutRoutineRow := sqlmock.
+			NewRows([]string{"id", "name", "created_at", "deleted_at", "updated_at", "user_id", "active"}).
+			AddRow(wr.ID, wr.Name, wr.CreatedAt, wr.DeletedAt, wr.UpdatedAt, wr.UserID, wr.Active)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).WithArgs(fmt.Sprintf("%d", wr.ID)).WillReturnRows(workoutRoutineRow)
+
+		statsRow := sqlmock.NewRows([]string{"session_count", "total_sets", "total_volume"}).AddRow(2, 4, 3600)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT COUNT(DISTINCT workout_sessions.id) AS session_count")).
+			WithArgs(fmt.Sprintf("%d", u.ID), sqlmock.AnyArg(), fmt.Sprintf("%d", wr.ID), sqlmock.AnyArg(), sqlmock.AnyArg(), fmt.Sprintf("%d", u.ID)).
+			WillReturnRows(statsRow)
+
+		exerciseRoutineStatsRow := sqlmock.
+			NewRows([]string{"exercise_routine_id", "name", "sets", "volume", "average_rest_seconds"}).
+			AddRow(wr.ExerciseRoutines[0].ID, wr.ExerciseRoutines[0].Name, 2, 1800, 90).
+			AddRow(wr.ExerciseRoutines[1].ID, wr.ExerciseRoutines[1].Name, 2, 1800, nil)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT exercise_routines.id AS exercise_routine_id")).
+			WithArgs(fmt.Sprintf("%d", u.ID), sqlmock.AnyArg(), fmt.Sprintf("%d", wr.ID), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(exerciseRoutineStatsRow)
+
+		muscleGroupStatsRow := sqlmock.NewRows([]string{"muscle_group", "sets", "volume"}).AddRow("QUADS", 2, 1800)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT catalog_exercise_muscle_groups.muscle_group AS muscle_group")).
+			WithArgs(fmt.Sprintf("%d", u.ID), sqlmock.AnyArg(), fmt.Sprintf("%d", wr.ID), sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(muscleGroupStatsRow)
+
+		var resp GetWorkoutStatsResp
+		gqlQuery := fmt.Sprintf(workoutStatsQuery, wr.ID, "2022-10-01T00:00:00Z", "2022-11-01T00:00:00Z")
+		c.MustPost(gqlQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+
+		require.Equal(t, float64(3600), resp.WorkoutStats.TotalVolume)
+		require.Equal(t, 4, resp.WorkoutStats.TotalSets)
+		require.Equal(t, 2, resp.WorkoutStats.SessionCount)
+		require.Len(t, resp.WorkoutStats.ExerciseRoutineStats, 2)
+		require.Equal(t, wr.ExerciseRoutines[0].Name, resp.WorkoutStats.ExerciseRoutineStats[0].Name)
+		require.Equal(t, float64(90), *resp.WorkoutStats.ExerciseRoutineStats[0].AverageRestSeconds)
+		require.Nil(t, resp.WorkoutStats.ExerciseRoutineStats[1].AverageRestSeconds)
+		require.Len(t, resp.WorkoutStats.MuscleGroupStats, 1)
+		require.Equal(t, "QUADS", resp.WorkoutStats.MuscleGroupStats[0].MuscleGroup)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Get Workout Stats Invalid Token", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		var resp GetWorkoutStatsResp
+		gqlQuery := fmt.Sprintf(workoutStatsQuery, wr.ID, "2022-10-01T00:00:00Z", "2022-11-01T00:00:00Z")
+		err := c.Post(gqlQuery, &resp)
+		require.EqualError(t, err, "[{\"message\":\"Unauthorized\",\"path\":[\"workoutStats\"],\"extensions\":{\"code\":\"UNAUTHORIZED\"}}]")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Get Workout Stats Invalid Range", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		var resp GetWorkoutStatsResp
+		gqlQuery := fmt.Sprintf(workoutStatsQuery, wr.ID, "2022-11-01T00:00:00Z", "2022-10-01T00:00:00Z")
+		err := c.Post(gqlQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, "[{\"message\":\"Could not get workout stats, range start needs to be before range end\",\"path\":[\"workoutStats\"],\"extensions\":{\"code\":\"INVALID_INPUT\"}}]")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Get Workout Stats Access Denied", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+
+		incorrectUserId := 66
+		workoutRoutineRow := sqlmock.
+			NewRows([]string{"id", "name", "created_at", "deleted_at", "updated_at", "user_id", "active"}).
+			AddRow(wr.ID, wr.Name, wr.CreatedAt, wr.DeletedAt, wr.UpdatedAt, incorrectUserId, wr.Active)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutRoutineAccessQuery)).WithArgs(fmt.Sprintf("%d", wr.ID)).WillReturnRows(workoutRoutineRow)
+
+		const participationCountQuery = `SELECT count(*) FROM "workout_session_participants" JOIN workout_sessions ON workout_sessions.id = workout_session_participants.workout_session_id AND workout_sessions.deleted_at IS NULL WHERE (workout_sessions.workout_routine_id = $1 AND workout_session_participants.user_id = $2) AND "workout_session_participants"."deleted_at" IS NULL`
+		mock.ExpectQuery(regexp.QuoteMeta(participationCountQuery)).
+			WithArgs(fmt.Sprintf("%d", wr.ID), fmt.Sprintf("%d", u.ID)).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+		var resp GetWorkoutStatsResp
+		gqlQuery := fmt.Sprintf(workoutStatsQuery, wr.ID, "2022-10-01T00:00:00Z", "2022-11-01T00:00:00Z")
+		err := c.Post(gqlQuery, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.EqualError(t, err, "[{\"message\":\"Error Getting Workout Stats: Access Denied\",\"path\":[\"workoutStats\"],\"extensions\":{\"code\":\"FORBIDDEN\"}}]")
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
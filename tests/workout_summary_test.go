@@ -0,0 +1,149 @@
+package test
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/joho/godotenv"
+	"github.com/neilZon/workout-logger-api/accesscontroller/accesscontrol"
+	"github.com/neilZon/workout-logger-api/helpers"
+	"github.com/neilZon/workout-logger-api/tests/testdata"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkoutSummary(t *testing.T) {
+	t.Parallel()
+
+	err := godotenv.Load("../.env")
+	if err != nil {
+		panic("Error loading .env file")
+	}
+
+	u := testdata.User
+	ws := testdata.WorkoutSession
+	wsId := fmt.Sprintf("%d", ws.ID)
+
+	workoutSessionColumns := []string{"id", "user_id", "start", "end", "workout_routine_id"}
+
+	t.Run("Finish Workout Session With Summary", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+		for i := 0; i < 2; i++ {
+			workoutSessionRow := sqlmock.NewRows(workoutSessionColumns).AddRow(ws.ID, u.ID, ws.Start, nil, ws.WorkoutRoutineID)
+			mock.ExpectQuery(regexp.QuoteMeta(helpers.WorkoutSessionAccessQuery)).WithArgs(wsId).WillReturnRows(workoutSessionRow)
+		}
+
+		end := ws.Start.Add(time.Hour)
+		mock.ExpectBegin()
+		mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "workout_sessions" SET "end"=$1`)).
+			WithArgs(end, sqlmock.AnyArg(), wsId).
+			WillReturnRows(sqlmock.NewRows(workoutSessionColumns).AddRow(ws.ID, u.ID, ws.Start, end, ws.WorkoutRoutineID))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.QueueWebhookDeliveriesStmt)).
+			WithArgs(ws.ID, u.ID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta(helpers.QueueStravaUploadStmt)).
+			WithArgs(ws.ID, u.ID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		// sets logged in pounds are added up in the viewer's kilograms
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).
+			WithArgs(fmt.Sprintf("%d", u.ID)).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "verified", "locale"}).AddRow(u.ID, true, "de-DE"))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT COALESCE(SUM((CASE WHEN set_entries.unit = 'LB' THEN set_entries.weight * 0.45359237 ELSE set_entries.weight END) * set_entries.reps), 0) AS total_volume`)).
+			WithArgs(wsId).
+			WillReturnRows(sqlmock.NewRows([]string{"total_volume", "total_sets", "exercises_completed", "exercises_planned"}).
+				AddRow(4200.5, 12, 3, 4))
+		mock.ExpectQuery(regexp.QuoteMeta(`WITH session_best AS`)).
+			WithArgs(wsId).
+			WillReturnRows(sqlmock.NewRows([]string{"exercise_routine_id", "name", "weight", "reps", "previous_weight"}).
+				AddRow(5, "Squat", 140, 3, 135))
+
+		var resp struct {
+			FinishWorkoutSession struct {
+				Summary *struct {
+					DurationSeconds    int
+					TotalVolume        float64
+					WeightUnit         string
+					TotalSets          int
+					ExercisesCompleted int
+					ExercisesPlanned   int
+					PersonalRecords    []struct {
+						ExerciseRoutineID string
+						Name              string
+						Weight            float64
+						PreviousWeight    float64
+					}
+				}
+			}
+		}
+		c.MustPost(fmt.Sprintf(`
+			mutation {
+				finishWorkoutSession(workoutSessionId: "%d", end: "2022-10-30T13:34:00Z") {
+					summary {
+						durationSeconds
+						totalVolume
+						weightUnit
+						totalSets
+						exercisesCompleted
+						exercisesPlanned
+						personalRecords { exerciseRoutineId name weight previousWeight }
+					}
+				}
+			}`, ws.ID), &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+
+		summary := resp.FinishWorkoutSession.Summary
+		require.NotNil(t, summary)
+		require.Equal(t, 3600, summary.DurationSeconds)
+		require.Equal(t, 4200.5, summary.TotalVolume)
+		require.Equal(t, "KG", summary.WeightUnit)
+		require.Equal(t, 12, summary.TotalSets)
+		require.Equal(t, 3, summary.ExercisesCompleted)
+		require.Equal(t, 4, summary.ExercisesPlanned)
+		require.Len(t, summary.PersonalRecords, 1)
+		require.Equal(t, "5", summary.PersonalRecords[0].ExerciseRoutineID)
+		require.Equal(t, float64(140), summary.PersonalRecords[0].Weight)
+		require.Equal(t, float64(135), summary.PersonalRecords[0].PreviousWeight)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+
+	t.Run("Open Workout Session Has No Summary", func(t *testing.T) {
+		mock, gormDB := helpers.SetupMockDB()
+		acs := accesscontrol.NewAccessControllerService(gormDB)
+		c := helpers.NewGqlClient(gormDB, acs)
+
+		userRow := sqlmock.NewRows([]string{"id", "verified"}).AddRow(u.ID, true)
+		mock.ExpectQuery(regexp.QuoteMeta(helpers.VerifyUserQuery)).WithArgs(fmt.Sprintf("%d", u.ID)).WillReturnRows(userRow)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "workout_sessions" WHERE (user_id = $1 AND "end" IS NULL)`)).
+			WithArgs(fmt.Sprintf("%d", u.ID)).
+			WillReturnRows(sqlmock.NewRows(workoutSessionColumns).AddRow(ws.ID, u.ID, ws.Start, nil, ws.WorkoutRoutineID))
+
+		var resp struct {
+			CurrentWorkoutSession struct {
+				ID      string
+				Summary *struct {
+					TotalSets int
+				}
+			}
+		}
+		c.MustPost(`query { currentWorkoutSession { id summary { totalSets } } }`, &resp, helpers.AddContext(u, helpers.NewLoaders(gormDB)))
+		require.Equal(t, wsId, resp.CurrentWorkoutSession.ID)
+		require.Nil(t, resp.CurrentWorkoutSession.Summary)
+
+		err = mock.ExpectationsWereMet()
+		if err != nil {
+			panic(err)
+		}
+	})
+}
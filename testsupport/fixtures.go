@@ -0,0 +1,115 @@
+package testsupport
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/token"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// Password is every fixture user's password
+const Password = "password123"
+
+// hashed once, bcrypt is slow on purpose
+var passwordHash = func() string {
+	hash, err := bcrypt.GenerateFromPassword([]byte(Password), bcrypt.MinCost)
+	if err != nil {
+		panic(err)
+	}
+	return string(hash)
+}()
+
+var emails int64
+
+// Fixtures creates rows for a test, failing it on the first error
+type Fixtures struct {
+	t  testing.TB
+	db *gorm.DB
+}
+
+func NewFixtures(t testing.TB, db *gorm.DB) *Fixtures {
+	return &Fixtures{t: t, db: db}
+}
+
+func (f *Fixtures) create(value interface{}) {
+	f.t.Helper()
+	if err := f.db.Create(value).Error; err != nil {
+		f.t.Fatal(err)
+	}
+}
+
+// User is a verified user with Password and an email no other fixture has
+func (f *Fixtures) User(name string) *database.User {
+	f.t.Helper()
+	user := &database.User{
+		Name:     name,
+		Email:    fmt.Sprintf("lifter%d@fixtures.untilfailure.app", atomic.AddInt64(&emails, 1)),
+		Password: passwordHash,
+		Verified: true,
+	}
+	f.create(user)
+	return user
+}
+
+// Claims are what the auth middleware would put in the context for user
+func Claims(user *database.User) *token.Claims {
+	claims := token.Claims{}
+	claims.Name = user.Name
+	claims.ID = user.ID
+	claims.Subject = user.Email
+	claims.ExpiresAt = time.Now().Add(config.ACCESS_TTL * time.Hour).Unix()
+	return &claims
+}
+
+// WorkoutRoutine is a routine of user's with an exercise routine of 3 sets
+// of 8 for each name
+func (f *Fixtures) WorkoutRoutine(user *database.User, name string, exercises ...string) *database.WorkoutRoutine {
+	f.t.Helper()
+	routine := &database.WorkoutRoutine{Name: name, UserID: user.ID, Active: true}
+	for i, exercise := range exercises {
+		routine.ExerciseRoutines = append(routine.ExerciseRoutines, database.ExerciseRoutine{
+			Name:     exercise,
+			Sets:     3,
+			Reps:     8,
+			Active:   true,
+			Position: uint(i),
+		})
+	}
+	f.create(routine)
+	return routine
+}
+
+// WorkoutSession is a finished hour long session of routine starting at
+// start, with setsPerExercise working sets of weight lb for reps on every
+// exercise routine
+func (f *Fixtures) WorkoutSession(user *database.User, routine *database.WorkoutRoutine, start time.Time, setsPerExercise int, weight float64, reps uint) *database.WorkoutSession {
+	f.t.Helper()
+	end := start.Add(time.Hour)
+	session := &database.WorkoutSession{
+		Start:            start,
+		End:              &end,
+		WorkoutRoutineID: routine.ID,
+		UserID:           user.ID,
+	}
+	for _, er := range routine.ExerciseRoutines {
+		exercise := database.Exercise{ExerciseRoutineID: er.ID, UserID: user.ID}
+		for s := 0; s < setsPerExercise; s++ {
+			exercise.Sets = append(exercise.Sets, database.SetEntry{
+				Weight:   weight,
+				Unit:     database.WeightUnitLb,
+				Reps:     reps,
+				SetOrder: uint(s + 1),
+				Type:     database.SetTypeWorking,
+			})
+		}
+		session.Exercises = append(session.Exercises, exercise)
+	}
+	f.create(session)
+	return session
+}
@@ -0,0 +1,141 @@
+package testsupport
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/token"
+)
+
+// the hot paths, as the app sends them
+const (
+	WorkoutSessionsQuery = `query WorkoutSessions($limit: Int!, $after: String) {
+  workoutSessions(limit: $limit, after: $after) {
+    edges { cursor node { id start end exercises { id notes sets { id weight unit reps setOrder } } } }
+    pageInfo { hasNextPage }
+  }
+}`
+	AddSetMutation = `mutation AddSet($exerciseId: ID!, $set: SetEntryInput!) {
+  addSet(exerciseId: $exerciseId, set: $set) { id weight unit reps setOrder }
+}`
+	LoginMutation = `mutation Login($loginInput: LoginInput!) {
+  login(loginInput: $loginInput) { accessToken refreshToken }
+}`
+)
+
+// Request is one graphql request of a scenario
+type Request struct {
+	Method string
+	URL    string
+	Header http.Header
+	Body   []byte
+}
+
+// Scenario is the requests of one hot path, spread over the seeded users so
+// no one user's rate limit is what gets measured
+type Scenario struct {
+	Name     string
+	Requests []Request
+}
+
+// Scenarios are workoutSessions, addSet and login for every seeded user,
+// against the graphql endpoint at url. Access tokens are signed with secret,
+// the server's ACCESS_SECRET
+func Scenarios(url string, users []SeededUser, secret []byte) ([]Scenario, error) {
+	sessions := Scenario{Name: "workout_sessions"}
+	addSet := Scenario{Name: "add_set"}
+	login := Scenario{Name: "login"}
+
+	for _, u := range users {
+		header := http.Header{}
+		header.Set("Content-Type", "application/json")
+		authed := header.Clone()
+		c := &token.Credentials{ID: u.User.ID, Name: u.User.Name, Email: u.User.Email}
+		authed.Set("Authorization", "Bearer "+token.SignAccess(c, u.FamilyID, secret, config.ACCESS_TTL))
+
+		r, err := newRequest(url, authed, WorkoutSessionsQuery, map[string]interface{}{"limit": 20})
+		if err != nil {
+			return nil, err
+		}
+		sessions.Requests = append(sessions.Requests, r)
+
+		for i, exerciseID := range u.ExerciseIDs {
+			r, err := newRequest(url, authed, AddSetMutation, map[string]interface{}{
+				"exerciseId": fmt.Sprintf("%d", exerciseID),
+				"set":        map[string]interface{}{"weight": 135 + 5*i, "reps": 5},
+			})
+			if err != nil {
+				return nil, err
+			}
+			addSet.Requests = append(addSet.Requests, r)
+		}
+
+		r, err = newRequest(url, header, LoginMutation, map[string]interface{}{
+			"loginInput": map[string]string{"email": u.User.Email, "password": Password},
+		})
+		if err != nil {
+			return nil, err
+		}
+		login.Requests = append(login.Requests, r)
+	}
+	return []Scenario{sessions, addSet, login}, nil
+}
+
+func newRequest(url string, header http.Header, query string, variables map[string]interface{}) (Request, error) {
+	body, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return Request{}, err
+	}
+	return Request{Method: http.MethodPost, URL: url, Header: header, Body: body}, nil
+}
+
+// WriteVegeta writes the requests as vegeta's json targets, one a line, for
+// `vegeta attack -format=json`
+func WriteVegeta(w io.Writer, requests []Request) error {
+	enc := json.NewEncoder(w)
+	for _, r := range requests {
+		// []byte marshals as base64, which is what vegeta wants for bodies
+		err := enc.Encode(struct {
+			Method string      `json:"method"`
+			URL    string      `json:"url"`
+			Body   []byte      `json:"body"`
+			Header http.Header `json:"header"`
+		}{r.Method, r.URL, r.Body, r.Header})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteK6 writes the scenarios as json for loadtest/k6.js, which reads it
+// with open() and runs each scenario as its own k6 scenario
+func WriteK6(w io.Writer, scenarios []Scenario) error {
+	type k6Request struct {
+		Method  string            `json:"method"`
+		URL     string            `json:"url"`
+		Body    string            `json:"body"`
+		Headers map[string]string `json:"headers"`
+	}
+	out := map[string][]k6Request{}
+	for _, s := range scenarios {
+		requests := make([]k6Request, 0, len(s.Requests))
+		for _, r := range s.Requests {
+			headers := map[string]string{}
+			for k := range r.Header {
+				headers[k] = strings.Join(r.Header.Values(k), ", ")
+			}
+			requests = append(requests, k6Request{r.Method, r.URL, string(r.Body), headers})
+		}
+		out[s.Name] = requests
+	}
+	return json.NewEncoder(w).Encode(map[string]interface{}{
+		"generatedAt": time.Now().UTC(),
+		"scenarios":   out,
+	})
+}
@@ -0,0 +1,131 @@
+package testsupport
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/neilZon/workout-logger-api/database"
+	"github.com/neilZon/workout-logger-api/utils"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// SeedOptions size a load test's data
+type SeedOptions struct {
+	Users           int
+	SessionsPerUser int
+	// exercises in every user's routine, and so in every session
+	Exercises       int
+	SetsPerExercise int
+	// the seeded users' emails are <EmailPrefix><n>@loadtest.untilfailure.app,
+	// so a database can be seeded more than once
+	EmailPrefix string
+}
+
+// DefaultSeedOptions is about a year of training each for 50 users
+var DefaultSeedOptions = SeedOptions{
+	Users:           50,
+	SessionsPerUser: 150,
+	Exercises:       5,
+	SetsPerExercise: 4,
+	EmailPrefix:     "lifter",
+}
+
+// SeededUser is a seeded user and what scenarios need of their data
+type SeededUser struct {
+	User database.User
+	// the login their access tokens are for
+	FamilyID string
+	// the exercises of their latest session, recent enough to add sets to
+	ExerciseIDs []uint
+}
+
+var seededExercises = []string{"Squat", "Bench Press", "Deadlift", "Overhead Press", "Barbell Row", "Pull Up", "Dips", "Lunge"}
+
+// Seed creates opts.Users verified users with Password and a routine each,
+// with opts.SessionsPerUser finished sessions every other day back from now.
+// Unlike fixtures their password is hashed at the cost signup uses, so login
+// costs what it does in production
+func Seed(db *gorm.DB, opts SeedOptions) ([]SeededUser, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(Password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+
+	seeded := make([]SeededUser, 0, opts.Users)
+	for u := 0; u < opts.Users; u++ {
+		user := database.User{
+			Name:     fmt.Sprintf("Load Test Lifter %d", u+1),
+			Email:    fmt.Sprintf("%s%d@loadtest.untilfailure.app", opts.EmailPrefix, u+1),
+			Password: string(hash),
+			Verified: true,
+		}
+		if err := db.Create(&user).Error; err != nil {
+			return nil, err
+		}
+
+		routine := database.WorkoutRoutine{Name: "Full Body", UserID: user.ID, Active: true}
+		for e := 0; e < opts.Exercises; e++ {
+			routine.ExerciseRoutines = append(routine.ExerciseRoutines, database.ExerciseRoutine{
+				Name:     seededExercises[e%len(seededExercises)],
+				Sets:     uint(opts.SetsPerExercise),
+				Reps:     5,
+				Active:   true,
+				Position: uint(e),
+			})
+		}
+		if err := db.Create(&routine).Error; err != nil {
+			return nil, err
+		}
+
+		sessions := make([]database.WorkoutSession, 0, opts.SessionsPerUser)
+		for s := opts.SessionsPerUser; s > 0; s-- {
+			start := time.Now().AddDate(0, 0, -2*s)
+			end := start.Add(time.Hour)
+			session := database.WorkoutSession{
+				Start:            start,
+				End:              &end,
+				WorkoutRoutineID: routine.ID,
+				UserID:           user.ID,
+			}
+			for _, er := range routine.ExerciseRoutines {
+				exercise := database.Exercise{ExerciseRoutineID: er.ID, UserID: user.ID}
+				for set := 0; set < opts.SetsPerExercise; set++ {
+					exercise.Sets = append(exercise.Sets, database.SetEntry{
+						// a pound a session, like someone making progress
+						Weight:   float64(95 + opts.SessionsPerUser - s),
+						Unit:     database.WeightUnitLb,
+						Reps:     5,
+						SetOrder: uint(set + 1),
+						Type:     database.SetTypeWorking,
+					})
+				}
+				session.Exercises = append(session.Exercises, exercise)
+			}
+			sessions = append(sessions, session)
+		}
+		if len(sessions) > 0 {
+			if err := db.CreateInBatches(&sessions, 50).Error; err != nil {
+				return nil, err
+			}
+		}
+
+		familyId, err := utils.GenerateSecureToken(16)
+		if err != nil {
+			return nil, err
+		}
+		session := database.Session{UserID: user.ID, FamilyID: familyId, UserAgent: "loadtest", LastUsedAt: time.Now()}
+		if err := db.Create(&session).Error; err != nil {
+			return nil, err
+		}
+
+		s := SeededUser{User: user, FamilyID: familyId}
+		if len(sessions) > 0 {
+			for _, exercise := range sessions[len(sessions)-1].Exercises {
+				s.ExerciseIDs = append(s.ExerciseIDs, exercise.ID)
+			}
+		}
+		seeded = append(seeded, s)
+	}
+	return seeded, nil
+}
@@ -7,34 +7,69 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/neilZon/workout-logger-api/clock"
 )
 
+func init() {
+	// expiry is checked against the same clock tokens are signed with
+	jwt.TimeFunc = clock.Now
+}
+
 type Credentials struct {
 	ID    uint
 	Name  string
 	Email string
+	// the admin a support token is for, they act as ID
+	ImpersonatedBy uint
 }
 
 type Claims struct {
 	Name string
 	ID   uint
+	// the login the token was issued for. Refresh tokens are rotated within
+	// it, and access tokens carry it so revoking the login stops them too
+	Family         string `json:",omitempty"`
+	ImpersonatedBy uint   `json:",omitempty"`
 	jwt.StandardClaims
 }
 
 // signs a token
 func Sign(c *Credentials, secret []byte, ttl time.Duration) string {
-	claims := Claims{
-		c.Name,
-		c.ID,
-		jwt.StandardClaims{
-			ExpiresAt: time.Now().Add(ttl * time.Hour).Unix(),
-			IssuedAt:  time.Now().Unix(),
-			NotBefore: time.Now().Unix(),
+	return sign(newClaims(c, ttl), secret)
+}
+
+// SignAccess signs an access token for the login family belongs to
+func SignAccess(c *Credentials, family string, secret []byte, ttl time.Duration) string {
+	claims := newClaims(c, ttl)
+	claims.Family = family
+	return sign(claims, secret)
+}
+
+// SignRefresh signs a refresh token. id is unique to the token and family is
+// shared by every token rotated from the same login
+func SignRefresh(c *Credentials, family string, id string, secret []byte, ttl time.Duration) string {
+	claims := newClaims(c, ttl)
+	claims.Family = family
+	claims.Id = id
+	return sign(claims, secret)
+}
+
+func newClaims(c *Credentials, ttl time.Duration) Claims {
+	return Claims{
+		Name:           c.Name,
+		ID:             c.ID,
+		ImpersonatedBy: c.ImpersonatedBy,
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: clock.Now().Add(ttl * time.Hour).Unix(),
+			IssuedAt:  clock.Now().Unix(),
+			NotBefore: clock.Now().Unix(),
 			Issuer:    "neil:)",
 			Subject:   c.Email,
 		},
 	}
+}
 
+func sign(claims Claims, secret []byte) string {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
 	// Sign and get the complete encoded token as a string using the secret
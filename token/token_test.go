@@ -28,6 +28,17 @@ func TestToken(t *testing.T) {
 		assert.Equal(t, claims.Name, "testname")
 	})
 
+	t.Run("Refresh token carries its family and id", func(t *testing.T) {
+		tkn := SignRefresh(&c, "family", "id", []byte(secret), ttl)
+
+		claims, err := Decode("Bearer "+tkn, []byte(secret))
+
+		assert.Nil(t, err, "Error decoding token")
+		assert.Equal(t, claims.Family, "family")
+		assert.Equal(t, claims.Id, "id")
+		assert.Equal(t, claims.ID, uint(12))
+	})
+
 	t.Run("Fail to decode a tampered token", func(t *testing.T) {
 		tkn := Sign(&c, []byte(secret), ttl)
 		tamperedToken := tkn + "hehehe"
@@ -0,0 +1,49 @@
+package tracing
+
+import (
+	"context"
+	"os"
+
+	"github.com/neilZon/workout-logger-api/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+)
+
+// Init installs the global tracer provider, exporting over OTLP/HTTP. The
+// exporter, sampler and resource are configured by the standard OTEL_* env
+// vars and tracing stays off unless OTEL_EXPORTER_OTLP_ENDPOINT is set.
+// Call the returned func before exiting to flush buffered spans
+func Init(ctx context.Context) (func(context.Context) error, error) {
+	if os.Getenv(config.OTEL_EXPORTER_OTLP_ENDPOINT) == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES win over the default name
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(config.SERVICE_NAME)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+	// pick up traces started by the apps or a load balancer
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return tp.Shutdown, nil
+}
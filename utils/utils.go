@@ -1,6 +1,7 @@
 package utils
 
 import (
+	cryptorand "crypto/rand"
 	"encoding/base64"
 	"fmt"
 	"math/rand"
@@ -34,3 +35,13 @@ func GenerateVerificationCode(length int) (string, error) {
 	// Encode the random byte slice using base64.URLEncoding, which produces a URL-safe string
 	return base64.URLEncoding.EncodeToString(randomBytes), nil
 }
+
+// GenerateSecureToken is length random bytes from crypto/rand, url safe. For
+// anything that grants access where a guessable token would be a way in
+func GenerateSecureToken(length int) (string, error) {
+	randomBytes := make([]byte, length)
+	if _, err := cryptorand.Read(randomBytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(randomBytes), nil
+}
@@ -0,0 +1,262 @@
+package validator
+
+import (
+	"fmt"
+	"net/mail"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/graph/model"
+)
+
+// Input checks the input object arguments of a mutation, keyed by argument
+// name, and lists every field that's wrong instead of stopping at the first.
+// Scalar arguments and checks that need the database are left to resolvers
+func Input(args map[string]interface{}) error {
+	names := make([]string, 0, len(args))
+	for name := range args {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	f := &fields{}
+	for _, name := range names {
+		switch arg := args[name].(type) {
+		case model.SignupInput:
+			f.signup(name, &arg)
+		case model.WorkoutRoutineInput:
+			f.workoutRoutine(name, arg.Name, len(arg.ExerciseRoutines))
+			for i, er := range arg.ExerciseRoutines {
+				f.exerciseRoutine(index(name+".exerciseRoutines", i), er.Name, er.Sets, er.Reps)
+			}
+		case model.UpdateWorkoutRoutineInput:
+			f.workoutRoutine(name, arg.Name, len(arg.ExerciseRoutines))
+			for i, er := range arg.ExerciseRoutines {
+				f.exerciseRoutine(index(name+".exerciseRoutines", i), er.Name, er.Sets, er.Reps)
+			}
+		case model.ExerciseRoutineInput:
+			f.exerciseRoutine(name, arg.Name, arg.Sets, arg.Reps)
+		case model.WorkoutSessionInput:
+			f.workoutSession(name, &arg)
+		case model.UpdateWorkoutSessionInput:
+			f.add(name+".clientMetadata", ClientMetadataIsValid(arg.ClientMetadata))
+		case model.ExerciseInput:
+			f.exercise(name, &arg)
+		case model.UpdateExerciseInput:
+			f.add(name+".notes", notesIsValid(arg.Notes))
+		case model.SetEntryInput:
+			f.setEntry(name, &arg)
+		case model.UpdateSetEntryInput:
+			f.updateSetEntry(name, &arg)
+		case model.BodyWeightInput:
+			f.add(name+".weight", BodyWeightIsValid(arg.Weight))
+		case model.UpdateBodyWeightInput:
+			if arg.Weight != nil {
+				f.add(name+".weight", BodyWeightIsValid(*arg.Weight))
+			}
+		case model.NutritionInput:
+			f.nutrition(name, &arg)
+		case model.SleepInput:
+			f.add(name+".hours", SleepHoursIsValid(arg.Hours))
+		case model.ProgressPhotoInput:
+			if arg.BodyWeight != nil {
+				f.add(name+".bodyWeight", BodyWeightIsValid(*arg.BodyWeight))
+			}
+		case model.ProgramInput:
+			f.program(name, &arg)
+		case model.SyncWorkoutDataInput:
+			f.sync(name, &arg)
+		case model.GoalInput:
+			f.add(name+".targetValue", GoalTargetIsValid(arg.TargetValue))
+		case model.UpdateGoalInput:
+			if arg.TargetValue != nil {
+				f.add(name+".targetValue", GoalTargetIsValid(*arg.TargetValue))
+			}
+		}
+	}
+
+	if len(*f) == 0 {
+		return nil
+	}
+	return errors.InvalidFields(*f)
+}
+
+type fields []errors.FieldError
+
+func (f *fields) add(field string, err error) {
+	if err != nil {
+		*f = append(*f, errors.FieldError{Field: field, Message: err.Error()})
+	}
+}
+
+func index(path string, i int) string {
+	return fmt.Sprintf("%s.%d", path, i)
+}
+
+func (f *fields) signup(path string, s *model.SignupInput) {
+	if _, err := mail.ParseAddress(s.Email); err != nil {
+		f.add(path+".email", errors.InvalidInput("not a valid email"))
+	}
+	if len(s.Name) < 2 || len(s.Name) > 50 {
+		f.add(path+".name", errors.InvalidInput("name needs to be between 2 and 50 characters"))
+	}
+	if !passwordLongEnough(s.Password) || !hasNumber(s.Password) {
+		f.add(path+".password", errors.InvalidInput("password needs at least 1 number and 8 - 32 characters"))
+	}
+	if s.Password != s.ConfirmPassword {
+		f.add(path+".confirmPassword", errors.InvalidInput("passwords don't match"))
+	}
+}
+
+func (f *fields) workoutRoutine(path string, name string, exerciseRoutines int) {
+	if n := utf8.RuneCountInString(name); n < 3 || n > 32 {
+		f.add(path+".name", errors.InvalidInput("name needs to be between 3 and 32 characters"))
+	}
+	if exerciseRoutines > 20 {
+		f.add(path+".exerciseRoutines", errors.InvalidInput("workout routine cannot have more than 20 exercise routines"))
+	}
+}
+
+func (f *fields) exerciseRoutine(path string, name string, sets int, reps int) {
+	if n := utf8.RuneCountInString(name); n < 1 || n > 32 {
+		f.add(path+".name", errors.InvalidInput("name needs to be between 1 and 32 characters"))
+	}
+	if sets < 0 || sets > 20 {
+		f.add(path+".sets", errors.InvalidInput("sets needs to be between 0 and 20"))
+	}
+	if reps < 0 || reps > 99 {
+		f.add(path+".reps", errors.InvalidInput("reps needs to be between 0 and 99"))
+	}
+}
+
+func (f *fields) workoutSession(path string, ws *model.WorkoutSessionInput) {
+	if len(ws.Exercises) > config.MAX_EXERCISES_PER_SESSION {
+		f.add(path+".exercises", errors.InvalidInput("workout session cannot have more than %d exercises", config.MAX_EXERCISES_PER_SESSION))
+	}
+	for i, e := range ws.Exercises {
+		f.exercise(index(path+".exercises", i), e)
+	}
+	f.add(path+".clientMetadata", ClientMetadataIsValid(ws.ClientMetadata))
+}
+
+func (f *fields) exercise(path string, e *model.ExerciseInput) {
+	f.add(path+".notes", notesIsValid(e.Notes))
+	if len(e.SetEntries) > 20 {
+		f.add(path+".setEntries", errors.InvalidInput("exercise cannot have more than 20 sets"))
+	}
+	for i, s := range e.SetEntries {
+		f.setEntry(index(path+".setEntries", i), s)
+	}
+}
+
+func (f *fields) setEntry(path string, s *model.SetEntryInput) {
+	f.add(path+".weight", WeightIsValid(s.Weight))
+	f.add(path+".reps", RepsIsValid(s.Reps))
+	f.add(path+".setOrder", SetOrderIsValid(s.SetOrder))
+	f.add(path+".restTimeSeconds", RestTimeIsValid(s.RestTimeSeconds))
+	f.add(path+".clientMetadata", ClientMetadataIsValid(s.ClientMetadata))
+}
+
+func (f *fields) updateSetEntry(path string, s *model.UpdateSetEntryInput) {
+	if s.Weight != nil {
+		f.add(path+".weight", WeightIsValid(*s.Weight))
+	}
+	if s.Reps != nil {
+		f.add(path+".reps", RepsIsValid(*s.Reps))
+	}
+	f.add(path+".setOrder", SetOrderIsValid(s.SetOrder))
+	f.add(path+".restTimeSeconds", RestTimeIsValid(s.RestTimeSeconds))
+	f.add(path+".clientMetadata", ClientMetadataIsValid(s.ClientMetadata))
+}
+
+func (f *fields) nutrition(path string, n *model.NutritionInput) {
+	if n.Calories == nil && n.Protein == nil && (n.Notes == nil || *n.Notes == "") {
+		f.add(path, errors.InvalidInput("log at least one of calories, protein or notes"))
+	}
+	if n.Calories != nil && (*n.Calories < 0 || *n.Calories > 20000) {
+		f.add(path+".calories", errors.InvalidInput("calories needs to be between 0 and 20000"))
+	}
+	if n.Protein != nil && (*n.Protein < 0 || *n.Protein > 1000) {
+		f.add(path+".protein", errors.InvalidInput("protein needs to be between 0 and 1000"))
+	}
+	if n.Notes != nil && len(*n.Notes) > 256 {
+		f.add(path+".notes", errors.InvalidInput("max length of notes is 256 characters"))
+	}
+}
+
+func (f *fields) program(path string, p *model.ProgramInput) {
+	if n := utf8.RuneCountInString(p.Name); n < 1 || n > 32 {
+		f.add(path+".name", errors.InvalidInput("name needs to be between 1 and 32 characters"))
+	}
+	scheduled := make(map[model.Weekday]bool)
+	for i, d := range p.Days {
+		if scheduled[d.Weekday] {
+			f.add(index(path+".days", i)+".weekday", errors.InvalidInput("%s already has a workout routine", strings.ToLower(d.Weekday.String())))
+		}
+		scheduled[d.Weekday] = true
+	}
+}
+
+func (f *fields) sync(path string, s *model.SyncWorkoutDataInput) {
+	if n := len(s.WorkoutSessions) + len(s.Exercises) + len(s.Sets); n > config.MAX_SYNC_CHANGES {
+		f.add(path, errors.InvalidInput("can't sync more than %d changes at once", config.MAX_SYNC_CHANGES))
+	}
+	for i, ws := range s.WorkoutSessions {
+		p := index(path+".workoutSessions", i)
+		f.add(p+".clientId", ClientIDIsValid(ws.ClientID))
+		if ws.Operation == model.SyncOperationCreate {
+			f.add(p+".workoutRoutineId", neededToCreate(ws.WorkoutRoutineID != nil, "workout session"))
+			f.add(p+".start", neededToCreate(ws.Start != nil, "workout session"))
+		}
+		if ws.Start != nil && ws.End != nil && ws.End.Before(*ws.Start) {
+			f.add(p+".end", errors.InvalidInput("end can't be before start"))
+		}
+		if ws.Notes != nil {
+			f.add(p+".notes", WorkoutSessionNotesIsValid(*ws.Notes))
+		}
+	}
+	for i, e := range s.Exercises {
+		p := index(path+".exercises", i)
+		f.add(p+".clientId", ClientIDIsValid(e.ClientID))
+		if e.Operation == model.SyncOperationCreate {
+			f.add(p+".workoutSessionClientId", neededToCreate(e.WorkoutSessionClientID != nil, "exercise"))
+			f.add(p+".exerciseRoutineId", neededToCreate(e.ExerciseRoutineID != nil, "exercise"))
+		}
+		if e.Notes != nil {
+			f.add(p+".notes", notesIsValid(*e.Notes))
+		}
+	}
+	for i, set := range s.Sets {
+		p := index(path+".sets", i)
+		f.add(p+".clientId", ClientIDIsValid(set.ClientID))
+		if set.Operation == model.SyncOperationCreate {
+			f.add(p+".exerciseClientId", neededToCreate(set.ExerciseClientID != nil, "set"))
+			f.add(p+".weight", neededToCreate(set.Weight != nil, "set"))
+			f.add(p+".reps", neededToCreate(set.Reps != nil, "set"))
+		}
+		if set.Weight != nil {
+			f.add(p+".weight", WeightIsValid(*set.Weight))
+		}
+		if set.Reps != nil {
+			f.add(p+".reps", RepsIsValid(*set.Reps))
+		}
+	}
+}
+
+func neededToCreate(present bool, entity string) error {
+	if !present {
+		return errors.InvalidInput("needed to create a %s", entity)
+	}
+	return nil
+}
+
+func notesIsValid(notes string) error {
+	if len(notes) > 512 {
+		return errors.InvalidInput("max length of notes is 512 characters")
+	}
+
+	return nil
+}
@@ -1,98 +1,270 @@
 package validator
 
 import (
-	"errors"
-	"fmt"
+	"context"
+	"encoding/json"
+	"net"
 	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
 
-	"github.com/neilZon/workout-logger-api/graph/model"
+	"github.com/neilZon/workout-logger-api/config"
+	"github.com/neilZon/workout-logger-api/errors"
+	"github.com/neilZon/workout-logger-api/format"
 )
 
-func SignupInputIsValid(s *model.SignupInput) error {
-	if _, err := mail.ParseAddress(s.Email); err != nil {
-		return errors.New("not a valid email")
+func ValidateEmail(email string) error {
+	if _, err := mail.ParseAddress(email); err != nil {
+		return errors.InvalidInput("not a valid email")
 	}
+	return nil
+}
 
-	if len(s.Name) < 2 || len(s.Name) > 50 {
-		return errors.New("name needs to be between 2 and 50 characters")
+// PasswordIsValid is the same rule signup has
+func PasswordIsValid(password string) error {
+	if !passwordLongEnough(password) || !hasNumber(password) {
+		return errors.InvalidInput("password needs at least 1 number and 8 - 32 characters")
 	}
 
-	if !passwordLongEnough(s.Password) || !hasNumber(s.Password) {
-		return errors.New("password needs at least 1 number and 8 - 32 characters")
+	return nil
+}
+
+func LocaleIsValid(locale string) error {
+	if _, ok := format.Canonical(locale); !ok {
+		return errors.InvalidInput("locale needs to be a language with an optional region like en-US")
 	}
 
-	if s.Password != s.ConfirmPassword {
-		return errors.New("passwords don't match")
+	return nil
+}
+
+func RepsIsValid(reps int) error {
+	if reps < 0 || reps > 9999 {
+		return errors.InvalidInput("reps needs to be between 0 and 9999")
 	}
 
 	return nil
 }
 
-func ValidateEmail(email string) error {
-	if _, err := mail.ParseAddress(email); err != nil {
-		return errors.New("not a valid email")
+func WeightIsValid(weight float64) error {
+	if weight < 0 || weight > 9999 {
+		return errors.InvalidInput("weight needs to be between 0 and 9999")
 	}
+
 	return nil
 }
 
-func UpdateSetEntryInputIsValid(u *model.UpdateSetEntryInput) error {
-	if u.Reps != nil && (*u.Reps > 9999 || *u.Reps < 0) {
-		return errors.New("reps needs to be between 0 and 9999")
+func RestTimeIsValid(restTimeSeconds *int) error {
+	if restTimeSeconds != nil && (*restTimeSeconds < 0 || *restTimeSeconds > 3600) {
+		return errors.InvalidInput("restTimeSeconds needs to be between 0 and 3600")
 	}
 
-	if u.Weight != nil && (*u.Weight > 9999 || *u.Weight < 0) {
-		return errors.New("weight needs to be between 0 and 9999")
+	return nil
+}
+
+// set order is optional, when it's missing the set goes after the last one
+func SetOrderIsValid(setOrder *int) error {
+	if setOrder != nil && (*setOrder < 1 || *setOrder > 99) {
+		return errors.InvalidInput("setOrder needs to be between 1 and 99")
 	}
 
 	return nil
 }
 
-func SetEntryInputIsValid(s *model.SetEntry) error {
-	if s.Reps < 0 || s.Reps > 9999 {
-		return errors.New("reps needs to be between 0 and 9999")
+func BodyWeightIsValid(weight float64) error {
+	if weight <= 0 || weight > 9999 {
+		return errors.InvalidInput("weight needs to be between 0 and 9999")
 	}
 
-	if s.Weight < 0 || s.Weight > 9999 {
-		return errors.New("weight needs to be between 0 and 9999")
+	return nil
+}
+
+func SleepHoursIsValid(hours float64) error {
+	if hours < 0 || hours > 24 {
+		return errors.InvalidInput("hours needs to be between 0 and 24")
 	}
 
 	return nil
 }
 
-func ExerciseIsVaid(exercise *model.Exercise) error {
-	if len(exercise.Sets) > 20 {
-		return errors.New("exercise cannot have more than 20 sets")
+func QuickPhraseIsValid(text string) error {
+	if strings.TrimSpace(text) == "" {
+		return errors.InvalidInput("text can't be empty")
+	}
+	if len(text) > 256 {
+		return errors.InvalidInput("max length of text is 256 characters")
 	}
 
-	for _, set := range exercise.Sets {
-		if err := SetEntryInputIsValid(set); err != nil {
-			return err
+	return nil
+}
+
+func WorkoutSessionNotesIsValid(notes string) error {
+	if len(notes) > config.MAX_SESSION_NOTES_LEN {
+		return errors.InvalidInput("max length of notes is %d characters", config.MAX_SESSION_NOTES_LEN)
+	}
+
+	return nil
+}
+
+func TagsAreValid(tags []string) error {
+	if len(tags) > config.MAX_WORKOUT_SESSION_TAGS {
+		return errors.InvalidInput("a session can have at most %d tags", config.MAX_WORKOUT_SESSION_TAGS)
+	}
+	for _, tag := range tags {
+		if strings.TrimSpace(tag) == "" {
+			return errors.InvalidInput("tags can't be empty")
+		}
+		if len(tag) > config.MAX_TAG_LEN {
+			return errors.InvalidInput("max length of a tag is %d characters", config.MAX_TAG_LEN)
 		}
 	}
 
-	if len(exercise.Notes) > 512 {
-		return errors.New("max length of notes is 512 character")
+	return nil
+}
+
+var clientIDPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// ids clients make up offline are UUIDs so two devices can't pick the same one
+func ClientIDIsValid(clientID string) error {
+	if !clientIDPattern.MatchString(clientID) {
+		return errors.InvalidInput("client ids need to be UUIDs")
 	}
 
 	return nil
 }
 
-func ExerciseRoutineIsValid(exerciseRoutine *model.ExerciseRoutine) error {
-	if exerciseRoutine.Sets > 20 {
-		return errors.New("you cannot have more than 20 sets")
+// webhooks carry workout data so they only go out over https, and only to
+// the public internet. The host is checked again on every delivery, where it
+// counts, this just turns away urls that can't work up front. A host that
+// doesn't resolve yet is let through, its deliveries fail until it does
+func WebhookURLIsValid(rawURL string) error {
+	if len(rawURL) > 2048 {
+		return errors.InvalidInput("max length of url is 2048 characters")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return errors.InvalidInput("url needs to be an https url")
 	}
 
-	if len(exerciseRoutine.Name) > 32 {
-		return errors.New("exercise routine names must have less than 32 characters")
+	host := strings.ToLower(u.Hostname())
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") || strings.HasSuffix(host, ".local") || strings.HasSuffix(host, ".internal") {
+		return errors.InvalidInput("url needs to be on the public internet")
 	}
+	ips := []net.IP{net.ParseIP(host)}
+	if ips[0] == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		addrs, _ := net.DefaultResolver.LookupIPAddr(ctx, host)
+		ips = ips[:0]
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+	for _, ip := range ips {
+		if !IsPublicIP(ip) {
+			return errors.InvalidInput("url needs to be on the public internet")
+		}
+	}
+
+	return nil
+}
+
+// ranges that aren't loopback, private or link local but still aren't the
+// public internet
+var nonPublicNets = []*net.IPNet{
+	mustParseCIDR("0.0.0.0/8"),
+	mustParseCIDR("100.64.0.0/10"), // carrier grade nat
+	mustParseCIDR("192.0.0.0/24"),
+	mustParseCIDR("198.18.0.0/15"), // benchmarking
+	mustParseCIDR("64:ff9b::/96"),  // nat64, can reach any ipv4 address
+}
+
+// IsPublicIP is false for addresses servers shouldn't be made to call, like
+// loopback, private networks and the cloud metadata service
+func IsPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, n := range nonPublicNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, n, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
 
-	if exerciseRoutine.Reps > 99 {
-		return fmt.Errorf("wtf you doing with %d reps??", exerciseRoutine.Reps)
+func GoalTargetIsValid(target float64) error {
+	if target <= 0 || target > 9999 {
+		return errors.InvalidInput("targetValue needs to be between 0 and 9999")
 	}
 
 	return nil
 }
 
-func WorkoutSessionIsValid(workoutSession *model.WorkoutSession) error { return nil }
+func GoalDeadlineIsValid(deadline time.Time, now time.Time) error {
+	if !deadline.After(now) {
+		return errors.InvalidInput("deadline needs to be in the future")
+	}
 
-func WorkoutRoutineIsValid(workoutRoutine *model.WorkoutRoutine) error { return nil }
+	return nil
+}
+
+// ClientMetadataIsValid keeps clientMetadata to a small json object of plain
+// values, nil is fine and clears it
+func ClientMetadataIsValid(m map[string]interface{}) error {
+	if m == nil {
+		return nil
+	}
+	if err := clientMetadataValueIsValid(m, 1); err != nil {
+		return err
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return errors.InvalidInput("clientMetadata needs to be a json object")
+	}
+	if len(b) > config.MAX_CLIENT_METADATA_BYTES {
+		return errors.InvalidInput("clientMetadata can't be more than %d bytes", config.MAX_CLIENT_METADATA_BYTES)
+	}
+	return nil
+}
+
+func clientMetadataValueIsValid(v interface{}, depth int) error {
+	switch v := v.(type) {
+	case nil, string, bool, json.Number, float64, float32, int, int32, int64:
+		return nil
+	case map[string]interface{}:
+		if depth > config.MAX_CLIENT_METADATA_DEPTH {
+			return errors.InvalidInput("clientMetadata can't be nested more than %d levels deep", config.MAX_CLIENT_METADATA_DEPTH)
+		}
+		for key, value := range v {
+			if key == "" || len(key) > config.MAX_CLIENT_METADATA_KEY_LEN {
+				return errors.InvalidInput("clientMetadata keys need to be between 1 and %d characters", config.MAX_CLIENT_METADATA_KEY_LEN)
+			}
+			if err := clientMetadataValueIsValid(value, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	case []interface{}:
+		if depth > config.MAX_CLIENT_METADATA_DEPTH {
+			return errors.InvalidInput("clientMetadata can't be nested more than %d levels deep", config.MAX_CLIENT_METADATA_DEPTH)
+		}
+		for _, value := range v {
+			if err := clientMetadataValueIsValid(value, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return errors.InvalidInput("clientMetadata can't hold a %T", v)
+}